@@ -0,0 +1,40 @@
+package main
+
+import (
+	"cakestore/internal/bootstrap"
+	configs "cakestore/internal/config"
+	"cakestore/internal/database"
+	"context"
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+// securityKeyPrefixes hold revoked sessions, login lockouts, two-factor
+// challenges and API key rate limits. Flushing them would undo logouts and
+// lift lockouts, so cache flush keeps them unless --all is given.
+var securityKeyPrefixes = []string{"auth:", "apikey:rate:"}
+
+func runCache(cfg *configs.Config, args []string) error {
+	if len(args) == 0 || args[0] != "flush" {
+		return fmt.Errorf("expected cache flush")
+	}
+
+	flags := pflag.NewFlagSet("cache flush", pflag.ContinueOnError)
+	all := flags.Bool("all", false, "also drop revoked sessions, lockouts and rate limits")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	keep := securityKeyPrefixes
+	if *all {
+		keep = nil
+	}
+	app := bootstrap.Connect(cfg)
+	defer app.Close()
+	if err := database.Flush(context.Background(), app.Cache, keep...); err != nil {
+		return err
+	}
+	fmt.Println("Cache flushed")
+	return nil
+}
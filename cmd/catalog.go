@@ -0,0 +1,86 @@
+package main
+
+import (
+	"cakestore/internal/bootstrap"
+	configs "cakestore/internal/config"
+	"cakestore/internal/domain/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+func runExport(cfg *configs.Config, args []string) error {
+	flags := pflag.NewFlagSet("export", pflag.ContinueOnError)
+	out := flags.String("out", "-", "file to write the catalog to, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	app := bootstrap.Connect(cfg)
+	defer app.Close()
+	catalog, err := app.Wire().CatalogUseCase.Export(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(catalog); err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Printf("Exported %d menus, %d inventory items and %d tables to %s\n", len(catalog.Menus), len(catalog.Inventory), len(catalog.Tables), *out)
+	}
+	return nil
+}
+
+func runImport(cfg *configs.Config, args []string) error {
+	flags := pflag.NewFlagSet("import", pflag.ContinueOnError)
+	in := flags.String("in", "", "catalog file written by export, - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("--in is required")
+	}
+
+	r := io.Reader(os.Stdin)
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	var catalog model.CatalogExport
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&catalog); err != nil {
+		return fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	app := bootstrap.Connect(cfg)
+	defer app.Close()
+	result, err := app.Wire().CatalogUseCase.Import(context.Background(), &catalog)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Menus: %d created, %d updated\n", result.MenusCreated, result.MenusUpdated)
+	fmt.Printf("Inventory: %d created, %d updated\n", result.InventoryCreated, result.InventoryUpdated)
+	fmt.Printf("Tables: %d created, %d updated\n", result.TablesCreated, result.TablesUpdated)
+	return nil
+}
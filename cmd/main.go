@@ -1,9 +1,66 @@
 package main
 
-import "cakestore/internal/bootstrap"
+import (
+	"cakestore/internal/bootstrap"
+	configs "cakestore/internal/config"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `Usage: main [--config file] [--port port] [--env env] [command]
+
+Commands:
+  serve                                   start the API (default)
+  migrate up [--dry-run]                  apply pending migrations
+  migrate down [--steps n] [--dry-run]    revert the latest migrations
+  migrate status                          list migrations and when they ran
+  seed [--profile minimal|demo]           create the admin, or demo data too
+  user create-admin --email e [--name n]  add an admin, password from --password or ADMIN_PASSWORD
+  cache flush [--all]                     drop cached data, keeping sessions and lockouts unless --all
+  orders expire-pending [--older-than d]  cancel unpaid orders older than d (default 24h)
+  export [--out file]                     write menus, inventory and tables as JSON
+  import --in file                        create or update the catalog from an export
+
+Every command except serve runs once and exits without starting the HTTP server.
+`
 
 func main() {
-	app := bootstrap.NewApplication()
-	app.Bootstrap()
-	app.Start()
+	cfg, args, err := configs.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration:\n%v", err)
+	}
+
+	command := "serve"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "serve" {
+		app := bootstrap.NewApplication(cfg)
+		app.Bootstrap()
+		if err := app.Run(); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Println("👋 Server stopped")
+		return
+	}
+
+	commands := map[string]func(*configs.Config, []string) error{
+		"migrate": runMigrate,
+		"seed":    runSeed,
+		"user":    runUser,
+		"cache":   runCache,
+		"orders":  runOrders,
+		"export":  runExport,
+		"import":  runImport,
+	}
+	run, ok := commands[command]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := run(cfg, args); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	configs "cakestore/internal/config"
+	"cakestore/internal/database"
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+)
+
+func runMigrate(cfg *configs.Config, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	flags := pflag.NewFlagSet("migrate "+action, pflag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print the SQL instead of running it")
+	steps := flags.Int("steps", 1, "how many migrations to revert")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	db := database.ConnectPostgres(cfg)
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	ctx := context.Background()
+	opts := database.MigrateOptions{DryRun: *dryRun, Out: os.Stdout}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx, opts)
+		printMigrations("Applied", applied, *dryRun)
+		return err
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("--steps must be at least 1")
+		}
+		reverted, err := migrator.Down(ctx, *steps, opts)
+		printMigrations("Reverted", reverted, *dryRun)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(out, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Modified {
+				appliedAt += " (file changed since)"
+			}
+			fmt.Fprintf(out, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return out.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", action)
+	}
+}
+
+func printMigrations(verb string, migrations []database.Migration, dryRun bool) {
+	if dryRun {
+		return
+	}
+	if len(migrations) == 0 {
+		fmt.Println("Nothing to do")
+	}
+	for _, migration := range migrations {
+		fmt.Printf("%s %04d %s\n", verb, migration.Version, migration.Name)
+	}
+}
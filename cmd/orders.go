@@ -0,0 +1,35 @@
+package main
+
+import (
+	"cakestore/internal/bootstrap"
+	configs "cakestore/internal/config"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func runOrders(cfg *configs.Config, args []string) error {
+	if len(args) == 0 || args[0] != "expire-pending" {
+		return fmt.Errorf("expected orders expire-pending")
+	}
+
+	flags := pflag.NewFlagSet("orders expire-pending", pflag.ContinueOnError)
+	olderThan := flags.Duration("older-than", 24*time.Hour, "cancel unpaid orders created longer ago than this")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *olderThan <= 0 {
+		return fmt.Errorf("--older-than must be positive")
+	}
+
+	app := bootstrap.Connect(cfg)
+	defer app.Close()
+	count, err := app.Wire().OrderUseCase.ExpirePending(context.Background(), time.Now().Add(-*olderThan))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Cancelled %d unpaid orders\n", count)
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"cakestore/internal/bootstrap"
+	configs "cakestore/internal/config"
+	"cakestore/internal/seeder"
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+func runSeed(cfg *configs.Config, args []string) error {
+	flags := pflag.NewFlagSet("seed", pflag.ContinueOnError)
+	name := flags.String("profile", string(seeder.ProfileMinimal), "minimal (admin only) or demo (sample accounts, menus, inventory and tables)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	profile, err := seeder.ParseProfile(*name)
+	if err != nil {
+		return err
+	}
+	if profile == seeder.ProfileDemo && cfg.SERVER_ENV == configs.EnvProduction {
+		return fmt.Errorf("the demo profile creates accounts with a known password and is refused in production")
+	}
+
+	app := bootstrap.Connect(cfg)
+	defer app.Close()
+	return app.Seed(app.Wire(), profile)
+}
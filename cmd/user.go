@@ -0,0 +1,40 @@
+package main
+
+import (
+	"cakestore/internal/bootstrap"
+	configs "cakestore/internal/config"
+	"cakestore/internal/seeder"
+	"fmt"
+
+	"github.com/spf13/pflag"
+)
+
+func runUser(cfg *configs.Config, args []string) error {
+	if len(args) == 0 || args[0] != "create-admin" {
+		return fmt.Errorf("expected user create-admin")
+	}
+
+	flags := pflag.NewFlagSet("user create-admin", pflag.ContinueOnError)
+	email := flags.String("email", "", "email of the new admin")
+	name := flags.String("name", "Admin", "display name of the new admin")
+	password := flags.String("password", "", "password of the new admin (default ADMIN_PASSWORD, which stays out of shell history)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *email == "" {
+		return fmt.Errorf("--email is required")
+	}
+	if *password == "" {
+		*password = cfg.ADMIN_PASSWORD
+	}
+
+	app := bootstrap.Connect(cfg)
+	defer app.Close()
+	deps := app.Wire()
+	admin, err := seeder.NewCustomerSeeder(deps.CustomerRepository, app.Logger).CreateAdmin(*name, *email, *password)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created admin %s with ID %d\n", admin.Email, admin.ID)
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package auth issues and verifies the access tokens used by the API. It is
+// the only place in the service that knows about JWT.
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrUnknownKey   = errors.New("token signed with an unknown key")
+)
+
+// Claims is the payload of every access token.
+type Claims struct {
+	CustomerID int64  `json:"customer_id"`
+	Email      string `json:"email"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	// IssuedAtMillis is iat in Unix milliseconds; the registered iat only
+	// keeps whole seconds
+	IssuedAtMillis int64 `json:"iat_ms,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// IssuedAtTime returns when the token was issued, to the millisecond when the
+// token carries it.
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAtMillis != 0 {
+		return time.UnixMilli(c.IssuedAtMillis)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// Subject is the account a token is issued for.
+type Subject struct {
+	CustomerID int64
+	Email      string
+	Name       string
+	Role       string
+}
+
+type TokenIssuer interface {
+	// Issue signs a new access token with the active key.
+	Issue(subject Subject) (string, *Claims, error)
+	// TTL is how long issued tokens stay valid.
+	TTL() time.Duration
+}
+
+type TokenVerifier interface {
+	// Verify checks the signature, issuer, audience and expiry of a token.
+	Verify(token string) (*Claims, error)
+}
+
+// Options configure the registered claims shared by every token.
+type Options struct {
+	Issuer    string
+	Audience  string
+	TTL       time.Duration
+	ClockSkew time.Duration
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var subject = Subject{CustomerID: 7, Email: "a@example.com", Name: "Alice", Role: "admin"}
+
+func hmacKeySet(t *testing.T, id, secret string, previous ...*Key) *KeySet {
+	key, err := NewHMACKey(id, []byte(secret))
+	require.NoError(t, err)
+	keys, err := NewKeySet(key, previous...)
+	require.NoError(t, err)
+	return keys
+}
+
+func TestJWTService_IssueAndVerify(t *testing.T) {
+	service := NewJWTService(hmacKeySet(t, "primary", "secret"), Options{TTL: time.Minute})
+
+	token, issued, err := service.Issue(subject)
+	require.NoError(t, err)
+
+	claims, err := service.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), claims.CustomerID)
+	assert.Equal(t, "Alice", claims.Name)
+	assert.Equal(t, "admin", claims.Role)
+	assert.Equal(t, issued.ID, claims.ID)
+	assert.Equal(t, "cakestore", claims.Issuer)
+}
+
+func TestJWTService_IssuedAtKeepsMilliseconds(t *testing.T) {
+	service := NewJWTService(hmacKeySet(t, "primary", "secret"), Options{TTL: time.Minute})
+	issuedAt := time.Now().Truncate(time.Second).Add(250 * time.Millisecond)
+	service.now = func() time.Time { return issuedAt }
+
+	token, _, err := service.Issue(subject)
+	require.NoError(t, err)
+
+	claims, err := service.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, issuedAt.UnixMilli(), claims.IssuedAtTime().UnixMilli())
+	// the registered claim keeps the library's default precision
+	assert.Equal(t, issuedAt.Unix(), claims.IssuedAt.Time.Unix())
+	assert.Zero(t, claims.IssuedAt.Time.Nanosecond())
+}
+
+func TestJWTService_KeyRotation(t *testing.T) {
+	old := NewJWTService(hmacKeySet(t, "2025-01", "old-secret"), Options{})
+	oldToken, _, err := old.Issue(subject)
+	require.NoError(t, err)
+
+	previous, err := NewHMACKey("2025-01", []byte("old-secret"))
+	require.NoError(t, err)
+	rotated := NewJWTService(hmacKeySet(t, "2025-06", "new-secret", previous), Options{})
+
+	_, err = rotated.Verify(oldToken)
+	assert.NoError(t, err, "tokens signed with a previous key stay valid")
+
+	retired := NewJWTService(hmacKeySet(t, "2025-06", "new-secret"), Options{})
+	_, err = retired.Verify(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestJWTService_AsymmetricKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, private := range []interface{}{rsaKey, edKey} {
+		key, err := NewPrivateKey("signing", private)
+		require.NoError(t, err)
+		keys, err := NewKeySet(key)
+		require.NoError(t, err)
+		service := NewJWTService(keys, Options{})
+
+		t.Run(key.Algorithm, func(t *testing.T) {
+			token, _, err := service.Issue(subject)
+			require.NoError(t, err)
+
+			_, err = service.Verify(token)
+			assert.NoError(t, err)
+
+			jwks := keys.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "signing", jwks.Keys[0].Kid)
+			assert.Equal(t, key.Algorithm, jwks.Keys[0].Alg)
+		})
+	}
+}
+
+func TestJWTService_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	key, err := NewPrivateKey("signing", rsaKey)
+	require.NoError(t, err)
+	keys, err := NewKeySet(key)
+	require.NoError(t, err)
+	service := NewJWTService(keys, Options{})
+
+	// An attacker signs an HS256 token using the published public key as the secret
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		CustomerID: 1,
+		Role:       "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "cakestore",
+			Audience:  jwt.ClaimStrings{"cakestore-api"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	forged.Header["kid"] = "signing"
+	token, err := forged.SignedString(publicPEM)
+	require.NoError(t, err)
+
+	_, err = service.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWTService_ClaimsValidation(t *testing.T) {
+	keys := hmacKeySet(t, "primary", "secret")
+	issuer := NewJWTService(keys, Options{TTL: time.Minute})
+	token, _, err := issuer.Issue(subject)
+	require.NoError(t, err)
+
+	t.Run("expired within clock skew", func(t *testing.T) {
+		verifier := NewJWTService(keys, Options{ClockSkew: 2 * time.Minute})
+		verifier.now = func() time.Time { return time.Now().Add(90 * time.Second) }
+
+		_, err := verifier.Verify(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		verifier := NewJWTService(keys, Options{})
+		verifier.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+		_, err := verifier.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		verifier := NewJWTService(keys, Options{Audience: "another-service"})
+
+		_, err := verifier.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Key is one signing key. Keys loaded from a public key can only verify.
+type Key struct {
+	ID        string
+	Algorithm string
+	signing   interface{}
+	verifying interface{}
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// NewHMACKey builds a symmetric HS256 key. HMAC keys are never published in the JWKS.
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("key %q: empty secret", id)
+	}
+	return &Key{ID: id, Algorithm: AlgorithmHS256, signing: secret, verifying: secret}, nil
+}
+
+// NewPrivateKey wraps an RSA or Ed25519 private key.
+func NewPrivateKey(id string, key crypto.PrivateKey) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Algorithm: AlgorithmRS256, signing: k, verifying: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Algorithm: AlgorithmEdDSA, signing: k, verifying: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported private key type %T", id, key)
+	}
+}
+
+// NewPublicKey wraps an RSA or Ed25519 public key that only verifies tokens.
+func NewPublicKey(id string, key crypto.PublicKey) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: id, Algorithm: AlgorithmRS256, verifying: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Algorithm: AlgorithmEdDSA, verifying: k}, nil
+	default:
+		return nil, fmt.Errorf("key %q: unsupported public key type %T", id, key)
+	}
+}
+
+// ParsePEMKey reads a PKCS#1, PKCS#8 or PKIX encoded key.
+func ParsePEMKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewPrivateKey(id, key)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewPrivateKey(id, key)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewPublicKey(id, key)
+	default:
+		return nil, fmt.Errorf("key %q: unsupported PEM block %q", id, block.Type)
+	}
+}
+
+// KeySet holds the active signing key and every key still accepted for
+// verification, indexed by kid. Rotating keys means making a new key active
+// and keeping the old one in the set until the tokens it signed have expired.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeySet(active *Key, previous ...*Key) (*KeySet, error) {
+	if active == nil || active.signing == nil {
+		return nil, errors.New("active key must be able to sign")
+	}
+
+	set := &KeySet{active: active, keys: map[string]*Key{}}
+	for _, key := range append([]*Key{active}, previous...) {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+func (s *KeySet) Active() *Key {
+	return s.active
+}
+
+func (s *KeySet) Get(id string) (*Key, bool) {
+	key, ok := s.keys[id]
+	return key, ok
+}
+
+func (s *KeySet) algorithms() []string {
+	seen := map[string]bool{}
+	var algorithms []string
+	for _, key := range s.keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
+
+// JWK is a public key in RFC 7517 format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publishes the public half of every asymmetric key so other services
+// can verify our tokens. HMAC secrets are left out.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		switch pub := key.verifying.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Algorithm,
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Algorithm,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return jwks
+}
+
+// KeyConfig describes where the keys come from.
+type KeyConfig struct {
+	// Algorithm of the active key: HS256 (default), RS256 or EdDSA
+	Algorithm string
+	// KeyID is the kid of the active key
+	KeyID string
+	// Secret is the active HS256 secret
+	Secret string
+	// PrivateKeyFile is the PEM file of the active RS256 or EdDSA key
+	PrivateKeyFile string
+	// PreviousKeys are still accepted for verification, as "kid=secret" for
+	// HS256 or "kid=/path/to/key.pem" for RS256 and EdDSA
+	PreviousKeys []string
+}
+
+func LoadKeySet(cfg KeyConfig) (*KeySet, error) {
+	if cfg.KeyID == "" {
+		cfg.KeyID = "primary"
+	}
+
+	var active *Key
+	var err error
+	switch cfg.Algorithm {
+	case "", AlgorithmHS256:
+		active, err = NewHMACKey(cfg.KeyID, []byte(cfg.Secret))
+	case AlgorithmRS256, AlgorithmEdDSA:
+		active, err = loadPEMFile(cfg.KeyID, cfg.PrivateKeyFile)
+		if err == nil && active.Algorithm != cfg.Algorithm {
+			err = fmt.Errorf("key %q is %s, expected %s", cfg.KeyID, active.Algorithm, cfg.Algorithm)
+		}
+	default:
+		err = fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []*Key
+	for _, entry := range cfg.PreviousKeys {
+		id, value, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || value == "" {
+			return nil, fmt.Errorf("previous key %q must be kid=value", entry)
+		}
+
+		var key *Key
+		if active.Algorithm == AlgorithmHS256 {
+			key, err = NewHMACKey(id, []byte(value))
+		} else {
+			key, err = loadPEMFile(id, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, key)
+	}
+
+	return NewKeySet(active, previous...)
+}
+
+func loadPEMFile(id, path string) (*Key, error) {
+	if path == "" {
+		return nil, fmt.Errorf("key %q: no key file configured", id)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+	return ParsePEMKey(id, data)
+}
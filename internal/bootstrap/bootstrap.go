@@ -2,18 +2,31 @@
 package bootstrap
 
 import (
+	"cakestore/internal/auth"
 	configs "cakestore/internal/config"
 	"cakestore/internal/database"
 	controller "cakestore/internal/delivery/http"
 	"cakestore/internal/delivery/http/route"
 	"cakestore/internal/health"
+	"cakestore/internal/lifecycle"
+	"cakestore/internal/metrics"
+	"cakestore/internal/notification"
 	"cakestore/internal/repository"
+	"cakestore/internal/scheduler"
 	"cakestore/internal/seeder"
+	"cakestore/internal/tracing"
 	"cakestore/internal/usecase"
 	"cakestore/utils"
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"log"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/gofiber/contrib/otelfiber/v2"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -22,70 +35,172 @@ import (
 )
 
 type Application struct {
-	App    *fiber.App
-	Config *configs.Config
-	DB     *gorm.DB
-	Logger *logrus.Logger
-	Cache  *database.RedisCacheService
+	App       *fiber.App
+	Config    *configs.Config
+	DB        *gorm.DB
+	Logger    *logrus.Logger
+	Cache     database.RedisCache
+	Scheduler *scheduler.Scheduler
+	// Metrics records business events, or nothing when METRICS_ENABLED is off
+	Metrics metrics.Business
+
+	shutdownTracing func(context.Context) error
 }
 
 type Dependencies struct {
 	// Repositories
-	MenuRepository        repository.MenuRepository
-	CustomerRepository    repository.CustomerRepository
-	CartRepository        repository.CartRepository
-	OrderRepository       repository.OrderRepository
-	PaymentRepository     repository.PaymentRepository
-	WishlistRepository    repository.WishListRepository
-	ReservationRepository repository.ReservationRepository
-	InventoryRepository   repository.InventoryRepository
-	TableRepository       repository.TableRepository
+	MenuRepository         repository.MenuRepository
+	CustomerRepository     repository.CustomerRepository
+	CartRepository         repository.CartRepository
+	OrderRepository        repository.OrderRepository
+	PaymentRepository      repository.PaymentRepository
+	WishlistRepository     repository.WishListRepository
+	ReservationRepository  repository.ReservationRepository
+	InventoryRepository    repository.InventoryRepository
+	TableRepository        repository.TableRepository
+	TableSessionRepository repository.TableSessionRepository
+	ReceiptRepository      repository.ReceiptRepository
+	ShiftRepository        repository.ShiftRepository
+	DepositRepository      repository.DepositRepository
+	RefreshTokenRepository repository.RefreshTokenRepository
+	AccountTokenRepository repository.AccountTokenRepository
+	TwoFactorRepository    repository.TwoFactorRepository
+	RoleRepository         repository.RoleRepository
+	APIKeyRepository       repository.APIKeyRepository
+	CustomerDataRepository repository.CustomerDataRepository
+	CatalogRepository      repository.CatalogRepository
+
+	// Notifications
+	NotificationSender notification.Sender
 
 	// Use Cases
-	MenuUseCase        usecase.MenuUseCase
-	CustomerUseCase    usecase.CustomerUseCase
-	CartUseCase        usecase.CartUseCase
-	OrderUseCase       usecase.OrderUseCase
-	PaymentUseCase     usecase.PaymentUseCase
-	WishlistUseCase    usecase.WishListUseCase
-	ReservationUseCase usecase.ReservationUseCase
-	InventoryUseCase   usecase.InventoryUseCase
-	TableUseCase       usecase.TableUseCase
+	MenuUseCase         usecase.MenuUseCase
+	CustomerUseCase     usecase.CustomerUseCase
+	CartUseCase         usecase.CartUseCase
+	OrderUseCase        usecase.OrderUseCase
+	PaymentUseCase      usecase.PaymentUseCase
+	WishlistUseCase     usecase.WishListUseCase
+	ReservationUseCase  usecase.ReservationUseCase
+	InventoryUseCase    usecase.InventoryUseCase
+	TableUseCase        usecase.TableUseCase
+	TableSessionUseCase usecase.TableSessionUseCase
+	POSUseCase          usecase.POSUseCase
+	ShiftUseCase        usecase.ShiftUseCase
+	DepositUseCase      usecase.DepositUseCase
+	SessionUseCase      usecase.SessionUseCase
+	AccountUseCase      usecase.AccountUseCase
+	LoginAttemptUseCase usecase.LoginAttemptUseCase
+	TwoFactorUseCase    usecase.TwoFactorUseCase
+	RoleUseCase         usecase.RoleUseCase
+	APIKeyUseCase       usecase.APIKeyUseCase
+	CustomerDataUseCase usecase.CustomerDataUseCase
+	CatalogUseCase      usecase.CatalogUseCase
 
 	// Controllers
-	MenuController        *controller.MenuController
-	CustomerController    *controller.CustomerController
-	OrderController       *controller.OrderController
-	CartController        *controller.CartController
-	PaymentController     controller.PaymentController
-	WishlistController    *controller.WishListController
-	ReservationController *controller.ReservationController
-	InventoryController   *controller.InventoryController
-	TableController       *controller.TableController
+	MenuController         *controller.MenuController
+	CustomerController     *controller.CustomerController
+	OrderController        *controller.OrderController
+	CartController         *controller.CartController
+	PaymentController      controller.PaymentController
+	WishlistController     *controller.WishListController
+	ReservationController  *controller.ReservationController
+	InventoryController    *controller.InventoryController
+	TableController        *controller.TableController
+	TableSessionController *controller.TableSessionController
+	POSController          *controller.POSController
+	ShiftController        *controller.ShiftController
+	AuthController         *controller.AuthController
+	TwoFactorController    *controller.TwoFactorController
+	RoleController         *controller.RoleController
+	APIKeyController       *controller.APIKeyController
+
+	// Access token signing and verification
+	Tokens *auth.JWTService
 
 	// Cache
-	Cache *database.RedisCacheService
+	Cache database.RedisCache
 }
 
-func NewApplication() *Application {
+// Connect opens the database and cache described by cfg, without the HTTP
+// server. Commands that only need the wiring start here. The config is
+// loaded once by the caller and shared from here on.
+func Connect(cfg *configs.Config) *Application {
 	logger := utils.NewLogger()
-	cfg := configs.LoadConfig()
+	// Tracing goes first so the Postgres and Redis clients pick it up
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up tracing: %v", err)
+	}
 	db := database.ConnectPostgres(cfg)
-	cache := database.NewRedisCacheService(context.Background(), "redis://dragonfly:6379/0")
-
-	if err := database.RunMigrations(db); err != nil {
-		log.Fatalf("❌ Failed to run database migrations: %v", err)
+	cache, err := database.NewCache(context.Background(), database.CacheConfig{
+		Driver:           cfg.CACHE_DRIVER,
+		RedisURL:         cfg.REDIS_ADDR,
+		MemoryMaxEntries: cfg.CACHE_MEMORY_MAX_ENTRIES,
+		BreakerThreshold: cfg.CACHE_BREAKER_THRESHOLD,
+		BreakerCooldown:  time.Duration(cfg.CACHE_BREAKER_COOLDOWN_SECONDS) * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to set up the cache: %v", err)
 	}
 
-	app := fiber.New()
+	var recorder metrics.Business = metrics.Noop{}
+	if cfg.METRICS_ENABLED {
+		recorder = metrics.Prometheus{}
+	}
 
 	return &Application{
-		App:    app,
-		Config: cfg,
-		DB:     db,
-		Logger: logger,
-		Cache:  cache,
+		Config:    cfg,
+		DB:        db,
+		Logger:    logger,
+		Cache:     cache,
+		Scheduler: scheduler.NewScheduler(logger),
+		Metrics:   recorder,
+
+		shutdownTracing: shutdownTracing,
+	}
+}
+
+// NewApplication connects like Connect, migrates when MIGRATE_ON_START is
+// set and creates the HTTP server.
+func NewApplication(cfg *configs.Config) *Application {
+	a := Connect(cfg)
+
+	if cfg.MIGRATE_ON_START {
+		if err := database.RunMigrations(a.DB); err != nil {
+			log.Fatalf("❌ Failed to run database migrations: %v", err)
+		}
 	}
+
+	// Behind a load balancer the client address comes from a header such as
+	// X-Forwarded-For; login throttling counts failures per address.
+	a.App = fiber.New(fiber.Config{
+		ProxyHeader:  cfg.PROXY_HEADER,
+		ReadTimeout:  time.Duration(cfg.SERVER_READ_TIMEOUT_SECONDS) * time.Second,
+		WriteTimeout: time.Duration(cfg.SERVER_WRITE_TIMEOUT_SECONDS) * time.Second,
+		IdleTimeout:  time.Duration(cfg.SERVER_IDLE_TIMEOUT_SECONDS) * time.Second,
+		BodyLimit:    cfg.SERVER_BODY_LIMIT_MB * 1024 * 1024,
+	})
+
+	// Every request gets a server span, continuing the caller's trace when
+	// it sends a traceparent header. Probes and scrapes are left out.
+	a.App.Use(otelfiber.Middleware(
+		otelfiber.WithoutMetrics(true),
+		otelfiber.WithNext(func(ctx *fiber.Ctx) bool {
+			switch ctx.Path() {
+			case "/livez", "/readyz", "/health", "/metrics":
+				return true
+			}
+			return false
+		}),
+	))
+	return a
+}
+
+// Wire builds the repositories and use cases, but no controllers or routes.
+func (a *Application) Wire() *Dependencies {
+	deps := a.initializeRepositories()
+	a.initializeUseCases(&deps)
+	return &deps
 }
 
 func (a *Application) initializeRepositories() Dependencies {
@@ -94,6 +209,13 @@ func (a *Application) initializeRepositories() Dependencies {
 	// Initialize repositories
 	deps.MenuRepository = repository.NewMenuRepository(a.DB, a.Logger)
 	deps.CustomerRepository = repository.NewCustomerRepository(a.DB, a.Logger)
+	deps.RefreshTokenRepository = repository.NewRefreshTokenRepository(a.DB, a.Logger)
+	deps.AccountTokenRepository = repository.NewAccountTokenRepository(a.DB, a.Logger)
+	deps.TwoFactorRepository = repository.NewTwoFactorRepository(a.DB, a.Logger)
+	deps.RoleRepository = repository.NewRoleRepository(a.DB, a.Logger)
+	deps.APIKeyRepository = repository.NewAPIKeyRepository(a.DB, a.Logger)
+	deps.CustomerDataRepository = repository.NewCustomerDataRepository(a.DB, a.Logger)
+	deps.CatalogRepository = repository.NewCatalogRepository(a.DB, a.Logger)
 	deps.CartRepository = repository.NewCartRepository(a.DB, a.Logger)
 	deps.OrderRepository = repository.NewOrderRepository(a.DB, a.Logger)
 	deps.PaymentRepository = repository.NewPaymentRepository(a.DB, a.Logger)
@@ -101,53 +223,198 @@ func (a *Application) initializeRepositories() Dependencies {
 	deps.ReservationRepository = repository.NewReservationRepository(a.DB, a.Logger)
 	deps.InventoryRepository = repository.NewInventoryRepository(a.DB, a.Logger)
 	deps.TableRepository = repository.NewTableRepository(a.DB, a.Logger)
+	deps.TableSessionRepository = repository.NewTableSessionRepository(a.DB, a.Logger)
+	deps.ReceiptRepository = repository.NewReceiptRepository(a.DB, a.Logger)
+	deps.ShiftRepository = repository.NewShiftRepository(a.DB, a.Logger)
+	deps.DepositRepository = repository.NewDepositRepository(a.DB, a.Logger)
+
+	sender, err := notification.NewSender(notification.Config{
+		Driver:   a.Config.NOTIFICATION_DRIVER,
+		FilePath: a.Config.NOTIFICATION_FILE,
+		SMTP: notification.SMTPConfig{
+			Host:     a.Config.SMTP_HOST,
+			Port:     a.Config.SMTP_PORT,
+			Username: a.Config.SMTP_USERNAME,
+			Password: a.Config.SMTP_PASSWORD,
+			From:     a.Config.SMTP_FROM,
+		},
+	}, a.Logger)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up notifications: %v", err)
+	}
+	deps.NotificationSender = sender
 
 	return deps
 }
 
 func (a *Application) initializeUseCases(deps *Dependencies) {
+	keys, err := auth.LoadKeySet(auth.KeyConfig{
+		Algorithm:      a.Config.JWT_ALGORITHM,
+		KeyID:          a.Config.JWT_KEY_ID,
+		Secret:         a.Config.JWT_SECRET,
+		PrivateKeyFile: a.Config.JWT_PRIVATE_KEY_FILE,
+		PreviousKeys:   a.Config.JWT_PREVIOUS_KEYS,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to load JWT keys: %v", err)
+	}
+	deps.Tokens = auth.NewJWTService(keys, auth.Options{
+		Issuer:    a.Config.JWT_ISSUER,
+		Audience:  a.Config.JWT_AUDIENCE,
+		TTL:       time.Duration(a.Config.ACCESS_TOKEN_TTL_MINUTES) * time.Minute,
+		ClockSkew: time.Duration(a.Config.JWT_CLOCK_SKEW_SECONDS) * time.Second,
+	})
+
 	// Initialize use cases
 	deps.MenuUseCase = usecase.NewMenuUseCase(deps.MenuRepository, a.Logger, a.Cache)
-	deps.CustomerUseCase = usecase.NewCustomerUseCase(deps.CustomerRepository, a.Logger, a.Config.JWT_SECRET, a.Cache)
-	deps.CartUseCase = usecase.NewCartUseCase(deps.CartRepository, deps.MenuRepository, a.Logger, a.Cache)
-	deps.OrderUseCase = usecase.NewOrderUseCase(deps.OrderRepository, deps.MenuRepository, deps.CustomerRepository, a.Logger, a.Config.SERVER_ENV, a.Cache)
-	deps.PaymentUseCase = usecase.NewPaymentUseCase(a.Config.MIDTRANS_ENDPOINT, deps.PaymentRepository, a.Logger, a.Config.SERVER_ENV, a.Cache)
+	deps.SessionUseCase = usecase.NewSessionUseCase(deps.RefreshTokenRepository, deps.CustomerRepository, a.Logger, a.Cache, deps.Tokens, usecase.SessionPolicy{
+		RefreshTTL: time.Duration(a.Config.REFRESH_TOKEN_TTL_HOURS) * time.Hour,
+	})
+	deps.AccountUseCase = usecase.NewAccountUseCase(deps.AccountTokenRepository, deps.CustomerRepository, deps.SessionUseCase, deps.NotificationSender, a.Logger, a.Cache, usecase.AccountPolicy{
+		PasswordResetURL: a.Config.PASSWORD_RESET_URL,
+		VerificationURL:  a.Config.EMAIL_VERIFICATION_URL,
+		InviteURL:        a.Config.INVITE_URL,
+		PasswordResetTTL: time.Duration(a.Config.PASSWORD_RESET_TTL_MINUTES) * time.Minute,
+		VerificationTTL:  time.Duration(a.Config.EMAIL_VERIFICATION_TTL_HOURS) * time.Hour,
+		InviteTTL:        time.Duration(a.Config.INVITE_TTL_HOURS) * time.Hour,
+	})
+	deps.LoginAttemptUseCase = usecase.NewLoginAttemptUseCase(a.Cache, a.Logger, usecase.LoginPolicy{
+		MaxAttempts:     a.Config.LOGIN_MAX_ATTEMPTS,
+		IPMaxAttempts:   a.Config.LOGIN_IP_MAX_ATTEMPTS,
+		Window:          time.Duration(a.Config.LOGIN_ATTEMPT_WINDOW_MINUTES) * time.Minute,
+		LockoutDuration: time.Duration(a.Config.LOGIN_LOCKOUT_MINUTES) * time.Minute,
+	})
+	twoFactorKey, err := a.twoFactorKey()
+	if err != nil {
+		log.Fatalf("❌ Failed to load two-factor encryption key: %v", err)
+	}
+	deps.TwoFactorUseCase = usecase.NewTwoFactorUseCase(deps.TwoFactorRepository, deps.CustomerRepository, deps.SessionUseCase, deps.LoginAttemptUseCase, a.Logger, a.Cache, usecase.TwoFactorPolicy{
+		Issuer:        a.Config.TWO_FACTOR_ISSUER,
+		EncryptionKey: twoFactorKey,
+		RequiredRoles: a.Config.TWO_FACTOR_REQUIRED_ROLES,
+	})
+	deps.RoleUseCase = usecase.NewRoleUseCase(deps.RoleRepository, a.Logger, a.Cache)
+	deps.APIKeyUseCase = usecase.NewAPIKeyUseCase(deps.APIKeyRepository, a.Logger, a.Cache, usecase.APIKeyPolicy{
+		RateLimit: a.Config.API_KEY_RATE_LIMIT,
+	})
+	deps.CustomerUseCase = usecase.NewCustomerUseCase(deps.CustomerRepository, a.Logger, deps.SessionUseCase, deps.AccountUseCase, deps.LoginAttemptUseCase, deps.TwoFactorUseCase, deps.RoleUseCase, a.Cache)
+	deps.CustomerDataUseCase = usecase.NewCustomerDataUseCase(deps.CustomerRepository, deps.CustomerDataRepository, a.Logger, deps.SessionUseCase, a.Cache)
+	deps.CartUseCase = usecase.NewCartUseCase(deps.CartRepository, deps.MenuRepository, a.Logger, a.Cache, a.Metrics)
+	deps.OrderUseCase = usecase.NewOrderUseCase(deps.OrderRepository, deps.MenuRepository, deps.CustomerRepository, a.Logger, a.Config.SERVER_ENV, a.Cache, a.Metrics)
+	deps.PaymentUseCase = usecase.NewPaymentUseCase(a.midtrans(), deps.PaymentRepository, a.Logger, a.Config.SERVER_ENV, a.Cache, a.Metrics)
 	deps.WishlistUseCase = usecase.NewWishListUseCase(deps.WishlistRepository, deps.MenuRepository, a.Logger, a.Cache)
-	deps.ReservationUseCase = usecase.NewReservationUseCase(deps.ReservationRepository, a.Logger, deps.TableRepository, a.Cache)
-	deps.InventoryUseCase = usecase.NewInventoryUseCase(deps.InventoryRepository, a.Logger, a.Cache)
+	deps.DepositUseCase = usecase.NewDepositUseCase(deps.DepositRepository, deps.ReservationRepository, deps.PaymentRepository, deps.OrderRepository, a.midtrans(), a.Logger, a.Cache, a.Metrics, usecase.DepositPolicy{
+		GuestThreshold:  a.Config.RESERVATION_DEPOSIT_GUEST_THRESHOLD,
+		Dates:           a.Config.RESERVATION_DEPOSIT_DATES,
+		NoShowThreshold: a.Config.RESERVATION_DEPOSIT_NO_SHOWS,
+		AmountPerGuest:  a.Config.RESERVATION_DEPOSIT_PER_GUEST,
+		PaymentWindow:   time.Duration(a.Config.RESERVATION_DEPOSIT_PAYMENT_HOURS) * time.Hour,
+		RefundCutoff:    time.Duration(a.Config.RESERVATION_DEPOSIT_REFUND_HOURS) * time.Hour,
+	})
+	deps.ReservationUseCase = usecase.NewReservationUseCase(deps.ReservationRepository, a.Logger, deps.TableRepository, a.Cache, deps.NotificationSender, deps.DepositUseCase, a.Metrics, usecase.ReservationPolicy{
+		ReminderLead: time.Duration(a.Config.RESERVATION_REMINDER_HOURS) * time.Hour,
+		NoShowGrace:  time.Duration(a.Config.RESERVATION_NO_SHOW_GRACE_MINUTES) * time.Minute,
+		NoShowLimit:  a.Config.RESERVATION_NO_SHOW_LIMIT,
+		LinkURL:      a.Config.RESERVATION_LINK_URL,
+		Secret:       a.Config.JWT_SECRET,
+	})
+	deps.InventoryUseCase = usecase.NewInventoryUseCase(deps.InventoryRepository, a.Logger, a.Cache, a.Metrics)
+	deps.CatalogUseCase = usecase.NewCatalogUseCase(deps.CatalogRepository, a.Logger, a.Cache)
 	deps.TableUseCase = usecase.NewTableUseCase(deps.TableRepository, a.Logger, a.Cache)
+	deps.TableSessionUseCase = usecase.NewTableSessionUseCase(deps.TableSessionRepository, deps.TableRepository, deps.CustomerRepository, a.Logger, a.Cache, a.Config.JWT_SECRET, a.Config.GUEST_ORDER_URL)
+	deps.POSUseCase = usecase.NewPOSUseCase(deps.ReceiptRepository, deps.ShiftRepository, deps.PaymentRepository, deps.OrderRepository, deps.CustomerRepository, a.Logger, a.Cache, a.Metrics)
+	deps.ShiftUseCase = usecase.NewShiftUseCase(deps.ShiftRepository, deps.ReceiptRepository, deps.PaymentRepository, a.Logger, a.Config.TAX_RATE)
 }
 
 func (a *Application) initializeControllers(deps *Dependencies) {
 	// Initialize controllers
 	deps.MenuController = controller.NewMenuController(deps.MenuUseCase, a.Logger)
-	deps.CustomerController = controller.NewCustomerController(deps.CustomerUseCase, a.Logger)
+	deps.CustomerController = controller.NewCustomerController(deps.CustomerUseCase, deps.CustomerDataUseCase, deps.SessionUseCase, a.Logger)
+	deps.TwoFactorController = controller.NewTwoFactorController(deps.TwoFactorUseCase, a.Logger)
+	deps.AuthController = controller.NewAuthController(deps.SessionUseCase, deps.AccountUseCase, deps.LoginAttemptUseCase, deps.Tokens.Keys(), a.Logger)
 	deps.OrderController = controller.NewOrderController(deps.OrderUseCase, deps.PaymentUseCase, a.Logger)
 	deps.CartController = controller.NewCartController(deps.CartUseCase, a.Logger)
-	deps.PaymentController = controller.NewPaymentController(a.Logger, a.Config.MIDTRANS_SERVER_KEY, deps.OrderUseCase, deps.PaymentUseCase)
+	deps.PaymentController = controller.NewPaymentController(a.Logger, a.Config.MIDTRANS_SERVER_KEY, deps.OrderUseCase, deps.PaymentUseCase, deps.DepositUseCase, deps.RoleUseCase, a.Metrics)
 	deps.WishlistController = controller.NewWishListController(deps.WishlistUseCase, a.Logger)
 	deps.ReservationController = controller.NewReservationController(deps.ReservationUseCase, a.Logger)
 	deps.InventoryController = controller.NewInventoryController(deps.InventoryUseCase, a.Logger)
 	deps.TableController = controller.NewTableController(deps.TableUseCase, a.Logger)
+	deps.TableSessionController = controller.NewTableSessionController(deps.TableSessionUseCase, deps.OrderUseCase, deps.PaymentUseCase, a.Logger)
+	deps.POSController = controller.NewPOSController(deps.POSUseCase, a.Logger)
+	deps.ShiftController = controller.NewShiftController(deps.ShiftUseCase, a.Logger)
+	deps.RoleController = controller.NewRoleController(deps.RoleUseCase, a.Logger)
+	deps.APIKeyController = controller.NewAPIKeyController(deps.APIKeyUseCase, a.Logger)
 }
 
-func (a *Application) seedDatabase(deps *Dependencies) {
-	// Initialize and run seeder
-	dbSeeder := seeder.NewSeeder(deps.CustomerRepository, deps.MenuRepository, a.Logger, deps.InventoryRepository, deps.TableRepository)
-	if err := dbSeeder.SeedAll(); err != nil {
-		log.Printf("⚠️ Warning: Failed to seed database: %v", err)
-	}
+// Seed creates the records of a seeder profile, see seeder.Profile.
+func (a *Application) Seed(deps *Dependencies, profile seeder.Profile) error {
+	dbSeeder := seeder.NewSeeder(deps.CustomerRepository, deps.MenuRepository, a.Logger, deps.InventoryRepository, deps.TableRepository, seeder.Options{
+		AdminEmail:    a.Config.ADMIN_EMAIL,
+		AdminPassword: a.Config.ADMIN_PASSWORD,
+	})
+	return dbSeeder.Seed(profile)
 }
 
+// setupHealthCheck serves /livez, which only shows the process answers, and
+// /readyz, which checks the dependencies and answers 503 while a critical
+// one fails. /health is kept as an alias of /readyz for existing probes.
 func (a *Application) setupHealthCheck() {
-	healthChecker := health.NewHealthChecker(a.DB)
-	a.App.Get("/health", func(c *fiber.Ctx) error {
-		health := healthChecker.Check()
-		if health.Status != "healthy" {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(health)
-		}
-		return c.JSON(health)
+	checks := health.NewRegistry(health.Options{
+		Timeout:  time.Duration(a.Config.HEALTH_CHECK_TIMEOUT_SECONDS) * time.Second,
+		CacheTTL: time.Duration(a.Config.HEALTH_CHECK_CACHE_SECONDS) * time.Second,
 	})
+	checks.Register(database.PostgresChecker(a.DB), true)
+	migrator, err := database.NewMigrator(a.DB)
+	if err != nil {
+		log.Fatalf("❌ Failed to load migrations: %v", err)
+	}
+	checks.Register(database.MigrationChecker(migrator), true)
+	checks.Register(database.PoolChecker(a.DB), false)
+	// The API keeps serving from Postgres while the cache is down
+	checks.Register(database.CacheChecker(a.Cache), false)
+	checks.Register(health.CheckerFunc("payment_gateway", a.midtrans().Check), false)
+
+	a.App.Get("/livez", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"status": "alive", "timestamp": time.Now()})
+	})
+	ready := func(c *fiber.Ctx) error {
+		report := checks.Check(c.UserContext())
+		if report.Status == health.StatusUnhealthy {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(report)
+		}
+		return c.JSON(report)
+	}
+	a.App.Get("/readyz", ready)
+	a.App.Get("/health", ready)
+}
+
+func (a *Application) midtrans() usecase.MidtransConfig {
+	return usecase.MidtransConfig{
+		Endpoint:  a.Config.MIDTRANS_ENDPOINT,
+		ServerKey: a.Config.MIDTRANS_SERVER_KEY,
+		Timeout:   time.Duration(a.Config.MIDTRANS_TIMEOUT_SECONDS) * time.Second,
+	}
+}
+
+// twoFactorKey decodes TWO_FACTOR_ENCRYPTION_KEY, a base64 encoded 32 byte
+// AES-256 key. Without a key two-factor authentication stays unavailable,
+// which is only allowed when no role requires it.
+func (a *Application) twoFactorKey() ([]byte, error) {
+	if a.Config.TWO_FACTOR_ENCRYPTION_KEY == "" {
+		if len(a.Config.TWO_FACTOR_REQUIRED_ROLES) > 0 {
+			return nil, errors.New("TWO_FACTOR_REQUIRED_ROLES is set but TWO_FACTOR_ENCRYPTION_KEY is empty")
+		}
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(a.Config.TWO_FACTOR_ENCRYPTION_KEY)
+	if err != nil {
+		return nil, fmt.Errorf("TWO_FACTOR_ENCRYPTION_KEY is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("TWO_FACTOR_ENCRYPTION_KEY must decode to 32 bytes, got %d", len(key))
+	}
+	return key, nil
 }
 
 // New function to set up Prometheus metrics
@@ -165,51 +432,168 @@ func (a *Application) setupPrometheus() {
 	// You can change this path using prometheus.RegisterAt(a.App, "/your-custom-metrics-path")
 	prometheus.RegisterAt(a.App, "/metrics")
 
+	// Connection pool figures, as go_sql_* metrics
+	if sqlDB, err := a.DB.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB, a.Config.DBName); err != nil {
+			a.Logger.Warnf("Failed to export database pool metrics: %v", err)
+		}
+	}
+
 	a.Logger.Info("Prometheus metrics exposed at /metrics")
 }
 
 func (a *Application) setupRoutes(deps *Dependencies) {
 	routeConfig := route.RouteConfig{
-		App:                   a.App,
-		MenuController:        deps.MenuController,
-		CustomerController:    deps.CustomerController,
-		CartController:        deps.CartController,
-		OrderController:       deps.OrderController,
-		PaymentController:     deps.PaymentController,
-		WishlistController:    deps.WishlistController,
-		ReservationController: deps.ReservationController,
-		InventoryController:   deps.InventoryController,
-		TableController:       deps.TableController,
-		JWTSecret:             a.Config.JWT_SECRET,
-		Log:                   a.Logger,
+		App:                    a.App,
+		MenuController:         deps.MenuController,
+		CustomerController:     deps.CustomerController,
+		AuthController:         deps.AuthController,
+		TwoFactorController:    deps.TwoFactorController,
+		CartController:         deps.CartController,
+		OrderController:        deps.OrderController,
+		PaymentController:      deps.PaymentController,
+		WishlistController:     deps.WishlistController,
+		ReservationController:  deps.ReservationController,
+		InventoryController:    deps.InventoryController,
+		TableController:        deps.TableController,
+		TableSessionController: deps.TableSessionController,
+		POSController:          deps.POSController,
+		ShiftController:        deps.ShiftController,
+		RoleController:         deps.RoleController,
+		APIKeyController:       deps.APIKeyController,
+		TableSessionUseCase:    deps.TableSessionUseCase,
+		SessionUseCase:         deps.SessionUseCase,
+		RoleUseCase:            deps.RoleUseCase,
+		APIKeyUseCase:          deps.APIKeyUseCase,
+		TokenVerifier:          deps.Tokens,
+		Log:                    a.Logger,
+		AllowOrigins:           a.Config.CORS_ALLOWED_ORIGINS,
+		EnableSwagger:          a.Config.SWAGGER_ENABLED,
+		EnablePprof:            a.Config.PPROF_ENABLED,
 	}
 	routeConfig.Setup()
 }
 
 func (a *Application) Bootstrap() {
 	// Initialize all dependencies in order
-	deps := a.initializeRepositories()
-	a.initializeUseCases(&deps)
-	a.initializeControllers(&deps)
+	deps := a.Wire()
+	a.initializeControllers(deps)
 
-	// Seed database
-	a.seedDatabase(&deps)
+	// Seeding at start is opt in, see the seed command
+	if a.Config.SEED_PROFILE != "" {
+		if err := a.Seed(deps, seeder.Profile(a.Config.SEED_PROFILE)); err != nil {
+			log.Printf("⚠️ Warning: Failed to seed database: %v", err)
+		}
+	}
 
 	// Setup health check
 	a.setupHealthCheck()
 
 	// set up prometheus
-	a.setupPrometheus()
+	if a.Config.METRICS_ENABLED {
+		a.setupPrometheus()
+	}
 
 	// Setup routes
-	a.setupRoutes(&deps)
+	a.setupRoutes(deps)
+
+	// Register background jobs, started by Run
+	if a.Config.JOBS_ENABLED {
+		a.setupJobs(deps)
+	}
+}
+
+func (a *Application) setupJobs(deps *Dependencies) {
+	a.Scheduler.Add(scheduler.Job{
+		Name:     "reservation-reminders",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := deps.ReservationUseCase.SendReminders(ctx, time.Now())
+			return err
+		},
+	})
+	a.Scheduler.Add(scheduler.Job{
+		Name:     "reservation-no-shows",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := deps.ReservationUseCase.MarkNoShows(ctx, time.Now())
+			return err
+		},
+	})
+	a.Scheduler.Add(scheduler.Job{
+		Name:     "reservation-deposits",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := deps.DepositUseCase.ExpireUnpaid(ctx, time.Now())
+			return err
+		},
+	})
+	if a.Config.METRICS_ENABLED {
+		a.Scheduler.Add(scheduler.Job{
+			Name:     "business-gauges",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				return errors.Join(
+					deps.OrderUseCase.RecordKitchenQueue(ctx),
+					deps.InventoryUseCase.RecordLowStock(ctx),
+				)
+			},
+		})
+	}
+}
+
+// Run serves HTTP and runs the background jobs until SIGINT or SIGTERM. It
+// then stops accepting connections, lets in-flight requests and jobs finish
+// within SHUTDOWN_TIMEOUT_SECONDS, and closes Postgres and the cache.
+func (a *Application) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	manager := lifecycle.NewManager(a.Logger, time.Duration(a.Config.SHUTDOWN_TIMEOUT_SECONDS)*time.Second)
+	manager.Add(lifecycle.Service{
+		Name: "http server",
+		Run: func() error {
+			port := a.Config.SERVER_PORT
+			log.Printf("🚀 Server running on port %s", port)
+			return a.App.Listen("0.0.0.0:" + port)
+		},
+		Stop: a.App.ShutdownWithContext,
+	})
+	if a.Config.JOBS_ENABLED {
+		manager.Add(lifecycle.Service{
+			Name: "background jobs",
+			Run: func() error {
+				a.Scheduler.Start(context.Background())
+				a.Scheduler.Wait()
+				return nil
+			},
+			Stop: a.Scheduler.Stop,
+		})
+	}
+	manager.OnClose("postgres", a.closeDB)
+	manager.OnClose("cache", func() error { return database.CloseCache(a.Cache) })
+	manager.OnClose("tracing", a.closeTracing)
+	return manager.Run(ctx)
+}
+
+// Close closes Postgres and then the cache, and flushes the buffered spans.
+// Run does this itself; commands that only Connect call it when they are done.
+func (a *Application) Close() error {
+	return errors.Join(a.closeDB(), database.CloseCache(a.Cache), a.closeTracing())
+}
+
+// closeTracing gives the exporter a few seconds to send the last spans, so
+// an unreachable collector cannot hold up the exit.
+func (a *Application) closeTracing() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return a.shutdownTracing(ctx)
 }
 
-func (a *Application) Start() {
-	port := a.Config.SERVER_PORT
-	if port == "" {
-		port = "8080"
+func (a *Application) closeDB() error {
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		return err
 	}
-	log.Printf("🚀 Server running on port %s", port)
-	log.Fatal(a.App.Listen("0.0.0.0:" + port))
+	return sqlDB.Close()
 }
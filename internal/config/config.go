@@ -1,5 +1,30 @@
 package configs
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Trace exporters
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// Config holds every setting. It is loaded once at startup by Load and
+// passed to whatever needs it.
 type Config struct {
 	DBName               string
 	DBPassword           string
@@ -14,12 +39,100 @@ type Config struct {
 	SERVER_ENV           string
 	SERVER_PORT          string
 	REDIS_ADDR           string
+	GUEST_ORDER_URL      string
+	TAX_RATE             float64
+	DBSSLMode            string
+
+	SERVER_READ_TIMEOUT_SECONDS  int
+	SERVER_WRITE_TIMEOUT_SECONDS int
+	SERVER_IDLE_TIMEOUT_SECONDS  int
+	SERVER_BODY_LIMIT_MB         int
+	// SHUTDOWN_TIMEOUT_SECONDS bounds draining requests and jobs on SIGTERM
+	SHUTDOWN_TIMEOUT_SECONDS     int
+	HEALTH_CHECK_TIMEOUT_SECONDS int
+	HEALTH_CHECK_CACHE_SECONDS   int
+	CORS_ALLOWED_ORIGINS         []string
+	DB_MAX_OPEN_CONNS            int
+	DB_MAX_IDLE_CONNS            int
+	DB_CONN_MAX_LIFETIME_MINUTES int
+	MIDTRANS_TIMEOUT_SECONDS     int
+
+	SWAGGER_ENABLED bool
+	METRICS_ENABLED bool
+	JOBS_ENABLED    bool
+	PPROF_ENABLED   bool
+	// MIGRATE_ON_START applies pending migrations when the server starts
+	MIGRATE_ON_START bool
+
+	// TRACING_EXPORTER is none, stdout or otlp
+	TRACING_EXPORTER      string
+	TRACING_SERVICE_NAME  string
+	TRACING_SAMPLE_RATIO  float64
+	TRACING_OTLP_ENDPOINT string
+
+	CACHE_DRIVER                   string
+	CACHE_MEMORY_MAX_ENTRIES       int
+	CACHE_BREAKER_THRESHOLD        int
+	CACHE_BREAKER_COOLDOWN_SECONDS int
+
+	ACCESS_TOKEN_TTL_MINUTES  int
+	REFRESH_TOKEN_TTL_HOURS   int
+	JWT_ALGORITHM             string
+	JWT_KEY_ID                string
+	JWT_PRIVATE_KEY_FILE      string
+	JWT_PREVIOUS_KEYS         []string
+	JWT_ISSUER                string
+	JWT_AUDIENCE              string
+	JWT_CLOCK_SKEW_SECONDS    int
+	TWO_FACTOR_ISSUER         string
+	TWO_FACTOR_ENCRYPTION_KEY string
+	TWO_FACTOR_REQUIRED_ROLES []string
+
+	NOTIFICATION_DRIVER               string
+	NOTIFICATION_FILE                 string
+	SMTP_HOST                         string
+	SMTP_PORT                         int
+	SMTP_USERNAME                     string
+	SMTP_PASSWORD                     string
+	SMTP_FROM                         string
+	PASSWORD_RESET_URL                string
+	PASSWORD_RESET_TTL_MINUTES        int
+	EMAIL_VERIFICATION_URL            string
+	EMAIL_VERIFICATION_TTL_HOURS      int
+	INVITE_URL                        string
+	INVITE_TTL_HOURS                  int
+	SEED_PROFILE                      string
+	ADMIN_EMAIL                       string
+	ADMIN_PASSWORD                    string
+	LOGIN_MAX_ATTEMPTS                int
+	LOGIN_IP_MAX_ATTEMPTS             int
+	LOGIN_ATTEMPT_WINDOW_MINUTES      int
+	LOGIN_LOCKOUT_MINUTES             int
+	PROXY_HEADER                      string
+	API_KEY_RATE_LIMIT                int
+	RESERVATION_LINK_URL              string
+	RESERVATION_REMINDER_HOURS        int
+	RESERVATION_NO_SHOW_GRACE_MINUTES int
+	RESERVATION_NO_SHOW_LIMIT         int
+
+	RESERVATION_DEPOSIT_GUEST_THRESHOLD int
+	RESERVATION_DEPOSIT_DATES           []string
+	RESERVATION_DEPOSIT_NO_SHOWS        int
+	RESERVATION_DEPOSIT_PER_GUEST       float64
+	RESERVATION_DEPOSIT_PAYMENT_HOURS   int
+	RESERVATION_DEPOSIT_REFUND_HOURS    int
 }
 
-func LoadConfig() *Config {
-	viper := NewViper()
+// Load reads the configuration from args (without the program name), the
+// environment and an optional env file, then validates it. The arguments
+// left after the flags are returned, starting with the command if any.
+func Load(args []string) (*Config, []string, error) {
+	viper, rest, err := NewViper(args)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	return &Config{
+	cfg := &Config{
 		DBName:               viper.GetString("POSTGRES_DB"),
 		DBPassword:           viper.GetString("POSTGRES_PASSWORD"),
 		DBUser:               viper.GetString("POSTGRES_USER"),
@@ -33,5 +146,229 @@ func LoadConfig() *Config {
 		SERVER_ENV:           viper.GetString("SERVER_ENV"),
 		SERVER_PORT:          viper.GetString("SERVER_PORT"),
 		REDIS_ADDR:           viper.GetString("REDIS_URL"),
+		GUEST_ORDER_URL:      viper.GetString("GUEST_ORDER_URL"),
+		TAX_RATE:             viper.GetFloat64("TAX_RATE"),
+		DBSSLMode:            viper.GetString("POSTGRES_SSLMODE"),
+
+		SERVER_READ_TIMEOUT_SECONDS:  viper.GetInt("SERVER_READ_TIMEOUT_SECONDS"),
+		SERVER_WRITE_TIMEOUT_SECONDS: viper.GetInt("SERVER_WRITE_TIMEOUT_SECONDS"),
+		SERVER_IDLE_TIMEOUT_SECONDS:  viper.GetInt("SERVER_IDLE_TIMEOUT_SECONDS"),
+		SERVER_BODY_LIMIT_MB:         viper.GetInt("SERVER_BODY_LIMIT_MB"),
+		SHUTDOWN_TIMEOUT_SECONDS:     viper.GetInt("SHUTDOWN_TIMEOUT_SECONDS"),
+		HEALTH_CHECK_TIMEOUT_SECONDS: viper.GetInt("HEALTH_CHECK_TIMEOUT_SECONDS"),
+		HEALTH_CHECK_CACHE_SECONDS:   viper.GetInt("HEALTH_CHECK_CACHE_SECONDS"),
+		CORS_ALLOWED_ORIGINS:         splitList(viper.GetString("CORS_ALLOWED_ORIGINS")),
+		DB_MAX_OPEN_CONNS:            viper.GetInt("DB_MAX_OPEN_CONNS"),
+		DB_MAX_IDLE_CONNS:            viper.GetInt("DB_MAX_IDLE_CONNS"),
+		DB_CONN_MAX_LIFETIME_MINUTES: viper.GetInt("DB_CONN_MAX_LIFETIME_MINUTES"),
+		MIDTRANS_TIMEOUT_SECONDS:     viper.GetInt("MIDTRANS_TIMEOUT_SECONDS"),
+
+		SWAGGER_ENABLED:  viper.GetBool("SWAGGER_ENABLED"),
+		METRICS_ENABLED:  viper.GetBool("METRICS_ENABLED"),
+		JOBS_ENABLED:     viper.GetBool("JOBS_ENABLED"),
+		PPROF_ENABLED:    viper.GetBool("PPROF_ENABLED"),
+		MIGRATE_ON_START: viper.GetBool("MIGRATE_ON_START"),
+
+		TRACING_EXPORTER:      viper.GetString("TRACING_EXPORTER"),
+		TRACING_SERVICE_NAME:  viper.GetString("TRACING_SERVICE_NAME"),
+		TRACING_SAMPLE_RATIO:  viper.GetFloat64("TRACING_SAMPLE_RATIO"),
+		TRACING_OTLP_ENDPOINT: viper.GetString("TRACING_OTLP_ENDPOINT"),
+
+		CACHE_DRIVER:                   viper.GetString("CACHE_DRIVER"),
+		CACHE_MEMORY_MAX_ENTRIES:       viper.GetInt("CACHE_MEMORY_MAX_ENTRIES"),
+		CACHE_BREAKER_THRESHOLD:        viper.GetInt("CACHE_BREAKER_THRESHOLD"),
+		CACHE_BREAKER_COOLDOWN_SECONDS: viper.GetInt("CACHE_BREAKER_COOLDOWN_SECONDS"),
+
+		ACCESS_TOKEN_TTL_MINUTES:  viper.GetInt("ACCESS_TOKEN_TTL_MINUTES"),
+		REFRESH_TOKEN_TTL_HOURS:   viper.GetInt("REFRESH_TOKEN_TTL_HOURS"),
+		JWT_ALGORITHM:             viper.GetString("JWT_ALGORITHM"),
+		JWT_KEY_ID:                viper.GetString("JWT_KEY_ID"),
+		JWT_PRIVATE_KEY_FILE:      viper.GetString("JWT_PRIVATE_KEY_FILE"),
+		JWT_PREVIOUS_KEYS:         splitList(viper.GetString("JWT_PREVIOUS_KEYS")),
+		JWT_ISSUER:                viper.GetString("JWT_ISSUER"),
+		JWT_AUDIENCE:              viper.GetString("JWT_AUDIENCE"),
+		JWT_CLOCK_SKEW_SECONDS:    viper.GetInt("JWT_CLOCK_SKEW_SECONDS"),
+		TWO_FACTOR_ISSUER:         viper.GetString("TWO_FACTOR_ISSUER"),
+		TWO_FACTOR_ENCRYPTION_KEY: viper.GetString("TWO_FACTOR_ENCRYPTION_KEY"),
+		TWO_FACTOR_REQUIRED_ROLES: splitList(viper.GetString("TWO_FACTOR_REQUIRED_ROLES")),
+
+		NOTIFICATION_DRIVER:               viper.GetString("NOTIFICATION_DRIVER"),
+		NOTIFICATION_FILE:                 viper.GetString("NOTIFICATION_FILE"),
+		SMTP_HOST:                         viper.GetString("SMTP_HOST"),
+		SMTP_PORT:                         viper.GetInt("SMTP_PORT"),
+		SMTP_USERNAME:                     viper.GetString("SMTP_USERNAME"),
+		SMTP_PASSWORD:                     viper.GetString("SMTP_PASSWORD"),
+		SMTP_FROM:                         viper.GetString("SMTP_FROM"),
+		PASSWORD_RESET_URL:                viper.GetString("PASSWORD_RESET_URL"),
+		PASSWORD_RESET_TTL_MINUTES:        viper.GetInt("PASSWORD_RESET_TTL_MINUTES"),
+		EMAIL_VERIFICATION_URL:            viper.GetString("EMAIL_VERIFICATION_URL"),
+		EMAIL_VERIFICATION_TTL_HOURS:      viper.GetInt("EMAIL_VERIFICATION_TTL_HOURS"),
+		INVITE_URL:                        viper.GetString("INVITE_URL"),
+		INVITE_TTL_HOURS:                  viper.GetInt("INVITE_TTL_HOURS"),
+		SEED_PROFILE:                      viper.GetString("SEED_PROFILE"),
+		ADMIN_EMAIL:                       viper.GetString("ADMIN_EMAIL"),
+		ADMIN_PASSWORD:                    viper.GetString("ADMIN_PASSWORD"),
+		LOGIN_MAX_ATTEMPTS:                viper.GetInt("LOGIN_MAX_ATTEMPTS"),
+		LOGIN_IP_MAX_ATTEMPTS:             viper.GetInt("LOGIN_IP_MAX_ATTEMPTS"),
+		LOGIN_ATTEMPT_WINDOW_MINUTES:      viper.GetInt("LOGIN_ATTEMPT_WINDOW_MINUTES"),
+		LOGIN_LOCKOUT_MINUTES:             viper.GetInt("LOGIN_LOCKOUT_MINUTES"),
+		PROXY_HEADER:                      viper.GetString("PROXY_HEADER"),
+		API_KEY_RATE_LIMIT:                viper.GetInt("API_KEY_RATE_LIMIT"),
+		RESERVATION_LINK_URL:              viper.GetString("RESERVATION_LINK_URL"),
+		RESERVATION_REMINDER_HOURS:        viper.GetInt("RESERVATION_REMINDER_HOURS"),
+		RESERVATION_NO_SHOW_GRACE_MINUTES: viper.GetInt("RESERVATION_NO_SHOW_GRACE_MINUTES"),
+		RESERVATION_NO_SHOW_LIMIT:         viper.GetInt("RESERVATION_NO_SHOW_LIMIT"),
+
+		RESERVATION_DEPOSIT_GUEST_THRESHOLD: viper.GetInt("RESERVATION_DEPOSIT_GUEST_THRESHOLD"),
+		RESERVATION_DEPOSIT_DATES:           splitList(viper.GetString("RESERVATION_DEPOSIT_DATES")),
+		RESERVATION_DEPOSIT_NO_SHOWS:        viper.GetInt("RESERVATION_DEPOSIT_NO_SHOWS"),
+		RESERVATION_DEPOSIT_PER_GUEST:       viper.GetFloat64("RESERVATION_DEPOSIT_PER_GUEST"),
+		RESERVATION_DEPOSIT_PAYMENT_HOURS:   viper.GetInt("RESERVATION_DEPOSIT_PAYMENT_HOURS"),
+		RESERVATION_DEPOSIT_REFUND_HOURS:    viper.GetInt("RESERVATION_DEPOSIT_REFUND_HOURS"),
+	}
+
+	// Development runs without a secret get a throwaway one, so tokens stop
+	// working on every restart but nothing has to be set up first
+	if cfg.JWT_SECRET == "" && cfg.SERVER_ENV == EnvDevelopment {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate a development JWT_SECRET: %w", err)
+		}
+		cfg.JWT_SECRET = hex.EncodeToString(secret)
+		log.Println("⚠️ JWT_SECRET is empty, using a random secret until the next restart")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, rest, nil
+}
+
+// Validate reports every invalid setting at once, so a bad deployment can
+// be fixed in one go.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.SERVER_ENV != EnvDevelopment && c.SERVER_ENV != EnvProduction {
+		fail("SERVER_ENV must be %s or %s, got %q", EnvDevelopment, EnvProduction, c.SERVER_ENV)
+	}
+	if port, err := strconv.Atoi(c.SERVER_PORT); err != nil || port < 1 || port > 65535 {
+		fail("SERVER_PORT must be a port number, got %q", c.SERVER_PORT)
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"POSTGRES_HOST", c.DBHost},
+		{"POSTGRES_PORT", c.DBPort},
+		{"POSTGRES_USER", c.DBUser},
+		{"POSTGRES_DB", c.DBName},
+	} {
+		if setting.value == "" {
+			fail("%s is required", setting.name)
+		}
+	}
+
+	// The secret also signs table session and reservation link tokens, so it
+	// is needed whatever JWT_ALGORITHM is
+	if c.JWT_SECRET == "" {
+		fail("JWT_SECRET is required")
+	} else if c.SERVER_ENV == EnvProduction && len(c.JWT_SECRET) < 32 {
+		fail("JWT_SECRET must be at least 32 characters in production")
+	}
+	// Seeding at start is opt in; the seed command does the same on demand
+	switch c.SEED_PROFILE {
+	case "", "minimal":
+	case "demo":
+		if c.SERVER_ENV == EnvProduction {
+			fail("SEED_PROFILE must not be demo in production")
+		}
+	default:
+		fail("SEED_PROFILE must be empty, minimal or demo, got %q", c.SEED_PROFILE)
+	}
+
+	// Revoked sessions and lockouts live in the cache, so production needs
+	// one that every instance shares and that reports failed writes
+	switch c.CACHE_DRIVER {
+	case "redis":
+	case "memory", "none":
+		if c.SERVER_ENV == EnvProduction {
+			fail("CACHE_DRIVER must be redis in production, got %q", c.CACHE_DRIVER)
+		}
+	default:
+		fail("CACHE_DRIVER must be redis, memory or none, got %q", c.CACHE_DRIVER)
+	}
+
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"SERVER_READ_TIMEOUT_SECONDS", c.SERVER_READ_TIMEOUT_SECONDS},
+		{"SERVER_WRITE_TIMEOUT_SECONDS", c.SERVER_WRITE_TIMEOUT_SECONDS},
+		{"SERVER_IDLE_TIMEOUT_SECONDS", c.SERVER_IDLE_TIMEOUT_SECONDS},
+		{"SERVER_BODY_LIMIT_MB", c.SERVER_BODY_LIMIT_MB},
+		{"SHUTDOWN_TIMEOUT_SECONDS", c.SHUTDOWN_TIMEOUT_SECONDS},
+		{"HEALTH_CHECK_TIMEOUT_SECONDS", c.HEALTH_CHECK_TIMEOUT_SECONDS},
+		{"HEALTH_CHECK_CACHE_SECONDS", c.HEALTH_CHECK_CACHE_SECONDS},
+		{"DB_MAX_OPEN_CONNS", c.DB_MAX_OPEN_CONNS},
+		{"MIDTRANS_TIMEOUT_SECONDS", c.MIDTRANS_TIMEOUT_SECONDS},
+	} {
+		if setting.value <= 0 {
+			fail("%s must be positive, got %d", setting.name, setting.value)
+		}
+	}
+	if c.DB_MAX_IDLE_CONNS < 0 || c.DB_MAX_IDLE_CONNS > c.DB_MAX_OPEN_CONNS {
+		fail("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS, got %d", c.DB_MAX_IDLE_CONNS)
+	}
+	if c.DB_CONN_MAX_LIFETIME_MINUTES < 0 {
+		fail("DB_CONN_MAX_LIFETIME_MINUTES must not be negative, got %d", c.DB_CONN_MAX_LIFETIME_MINUTES)
+	}
+	if c.RESERVATION_NO_SHOW_GRACE_MINUTES < 0 {
+		fail("RESERVATION_NO_SHOW_GRACE_MINUTES must not be negative, got %d", c.RESERVATION_NO_SHOW_GRACE_MINUTES)
+	}
+	if c.TAX_RATE < 0 || c.TAX_RATE >= 100 {
+		fail("TAX_RATE must be a percentage between 0 and 100, got %v", c.TAX_RATE)
+	}
+
+	switch c.TRACING_EXPORTER {
+	case TracingNone, TracingStdout:
+	case TracingOTLP:
+		// Without an endpoint the exporter falls back to the standard
+		// OTEL_EXPORTER_OTLP_ENDPOINT variable, then to localhost:4318
+		if c.TRACING_OTLP_ENDPOINT != "" {
+			if parsed, err := url.Parse(c.TRACING_OTLP_ENDPOINT); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				fail("TRACING_OTLP_ENDPOINT must look like http://otel-collector:4318, got %q", c.TRACING_OTLP_ENDPOINT)
+			}
+		}
+	default:
+		fail("TRACING_EXPORTER must be %s, %s or %s, got %q", TracingNone, TracingStdout, TracingOTLP, c.TRACING_EXPORTER)
+	}
+	if c.TRACING_SAMPLE_RATIO < 0 || c.TRACING_SAMPLE_RATIO > 1 {
+		fail("TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.TRACING_SAMPLE_RATIO)
+	}
+
+	if len(c.CORS_ALLOWED_ORIGINS) == 0 {
+		fail("CORS_ALLOWED_ORIGINS is required, use * to allow any origin")
+	}
+	for _, origin := range c.CORS_ALLOWED_ORIGINS {
+		if origin == "*" {
+			continue
+		}
+		if parsed, err := url.Parse(origin); err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" {
+			fail("CORS_ALLOWED_ORIGINS entry %q must look like https://example.com", origin)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// splitList turns a comma separated value such as "2025-12-24,2025-12-31" into its trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
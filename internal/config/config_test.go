@@ -0,0 +1,147 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadFromEnv loads with an empty config file, so a developer's .env cannot
+// leak into the test.
+func loadFromEnv(t *testing.T, env map[string]string, args ...string) (*Config, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "empty.env")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	base := map[string]string{
+		"SERVER_ENV":    "",
+		"SERVER_PORT":   "",
+		"POSTGRES_USER": "postgres",
+		"POSTGRES_DB":   "cakestore",
+		"JWT_SECRET":    "0123456789abcdef0123456789abcdef",
+		"SEED_PROFILE":  "",
+	}
+	for key, value := range env {
+		base[key] = value
+	}
+	for key, value := range base {
+		t.Setenv(key, value)
+	}
+	cfg, _, err := Load(append([]string{"--config", file}, args...))
+	return cfg, err
+}
+
+func TestLoad_FromEnvironmentWithDefaults(t *testing.T) {
+	cfg, err := loadFromEnv(t, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, EnvProduction, cfg.SERVER_ENV)
+	assert.Equal(t, "8080", cfg.SERVER_PORT)
+	assert.Equal(t, "localhost", cfg.DBHost)
+	assert.Equal(t, "cakestore", cfg.DBName)
+	assert.Equal(t, 25, cfg.DB_MAX_OPEN_CONNS)
+	assert.Equal(t, []string{"*"}, cfg.CORS_ALLOWED_ORIGINS)
+	assert.True(t, cfg.SWAGGER_ENABLED)
+	assert.False(t, cfg.PPROF_ENABLED)
+}
+
+func TestLoad_FlagsOverrideEnvironment(t *testing.T) {
+	cfg, err := loadFromEnv(t, map[string]string{"SERVER_PORT": "7000"}, "--port", "9090", "--env", EnvDevelopment)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9090", cfg.SERVER_PORT)
+	assert.Equal(t, EnvDevelopment, cfg.SERVER_ENV)
+}
+
+func TestLoad_ReportsEveryInvalidSetting(t *testing.T) {
+	_, err := loadFromEnv(t, map[string]string{
+		"JWT_SECRET":                        "",
+		"POSTGRES_DB":                       "",
+		"SERVER_PORT":                       "http",
+		"CORS_ALLOWED_ORIGINS":              "https://cakeville.dewanto.dev,cakeville.dewanto.dev",
+		"RESERVATION_NO_SHOW_GRACE_MINUTES": "-5",
+	})
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "JWT_SECRET is required")
+	assert.Contains(t, err.Error(), "POSTGRES_DB is required")
+	assert.Contains(t, err.Error(), "SERVER_PORT must be a port number")
+	assert.Contains(t, err.Error(), `CORS_ALLOWED_ORIGINS entry "cakeville.dewanto.dev"`)
+	assert.Contains(t, err.Error(), "RESERVATION_NO_SHOW_GRACE_MINUTES must not be negative")
+	assert.NotContains(t, err.Error(), `"https://cakeville.dewanto.dev"`)
+}
+
+func TestLoad_ProductionRejectsWeakSecretAndDemoSeed(t *testing.T) {
+	_, err := loadFromEnv(t, map[string]string{"JWT_SECRET": "secret", "SEED_PROFILE": "demo"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "JWT_SECRET must be at least 32 characters")
+	assert.Contains(t, err.Error(), "SEED_PROFILE must not be demo")
+
+	cfg, err := loadFromEnv(t, map[string]string{"JWT_SECRET": "secret", "SERVER_ENV": EnvDevelopment, "SEED_PROFILE": "demo"})
+	require.NoError(t, err)
+	assert.Equal(t, "secret", cfg.JWT_SECRET)
+	assert.Equal(t, "demo", cfg.SEED_PROFILE)
+
+	_, err = loadFromEnv(t, map[string]string{"SEED_PROFILE": "everything"})
+	assert.ErrorContains(t, err, "SEED_PROFILE must be empty, minimal or demo")
+}
+
+func TestLoad_ProductionRequiresRedisCache(t *testing.T) {
+	for _, driver := range []string{"memory", "none"} {
+		_, err := loadFromEnv(t, map[string]string{"CACHE_DRIVER": driver})
+		assert.ErrorContains(t, err, "CACHE_DRIVER must be redis in production")
+
+		cfg, err := loadFromEnv(t, map[string]string{"CACHE_DRIVER": driver, "SERVER_ENV": EnvDevelopment})
+		require.NoError(t, err)
+		assert.Equal(t, driver, cfg.CACHE_DRIVER)
+	}
+
+	_, err := loadFromEnv(t, map[string]string{"CACHE_DRIVER": "memcached", "SERVER_ENV": EnvDevelopment})
+	assert.ErrorContains(t, err, "CACHE_DRIVER must be redis, memory or none")
+}
+
+func TestLoad_ValidatesTracing(t *testing.T) {
+	cfg, err := loadFromEnv(t, nil)
+	require.NoError(t, err)
+	assert.Equal(t, TracingNone, cfg.TRACING_EXPORTER)
+	assert.Equal(t, 1.0, cfg.TRACING_SAMPLE_RATIO)
+
+	_, err = loadFromEnv(t, map[string]string{
+		"TRACING_EXPORTER":      TracingOTLP,
+		"TRACING_OTLP_ENDPOINT": "otel-collector:4318",
+		"TRACING_SAMPLE_RATIO":  "2",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TRACING_OTLP_ENDPOINT must look like")
+	assert.Contains(t, err.Error(), "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	_, err = loadFromEnv(t, map[string]string{"TRACING_EXPORTER": "jaeger"})
+	assert.ErrorContains(t, err, "TRACING_EXPORTER must be none, stdout or otlp")
+}
+
+func TestLoad_DevelopmentGeneratesMissingSecret(t *testing.T) {
+	cfg, err := loadFromEnv(t, map[string]string{"JWT_SECRET": "", "SERVER_ENV": EnvDevelopment})
+	require.NoError(t, err)
+	assert.Len(t, cfg.JWT_SECRET, 64)
+}
+
+func TestLoad_ReturnsCommandArguments(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "empty.env")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_DB", "cakestore")
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+
+	cfg, args, err := Load([]string{"--config", file, "--port", "9090", "migrate", "down", "--steps", "2"})
+	require.NoError(t, err)
+	assert.Equal(t, "9090", cfg.SERVER_PORT)
+	assert.Equal(t, []string{"migrate", "down", "--steps", "2"}, args)
+}
+
+func TestLoad_MissingConfigFileIsAnError(t *testing.T) {
+	_, _, err := Load([]string{"--config", filepath.Join(t.TempDir(), "missing.env")})
+	assert.Error(t, err)
+}
@@ -1,25 +1,97 @@
 package configs
 
 import (
+	"errors"
 	"fmt"
-	"log"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-func NewViper() *viper.Viper {
+// NewViper reads settings from, highest precedence first, command line flags,
+// environment variables, an env file and the defaults in setDefaults. The
+// file is the one named by --config, or else a .env found next to the binary
+// or in a parent directory. Without either, the environment alone is used.
+//
+// Flags are read up to the first argument that is not a flag; that argument
+// and everything after it are returned for the command to handle.
+func NewViper(args []string) (*viper.Viper, []string, error) {
+	flags := pflag.NewFlagSet("cakestore", pflag.ContinueOnError)
+	flags.SetInterspersed(false)
+	configFile := flags.String("config", "", "env file to read settings from (default .env when present)")
+	flags.String("port", "", "port to listen on, overrides SERVER_PORT")
+	flags.String("env", "", "development or production, overrides SERVER_ENV")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
 	config := viper.New()
-	config.SetConfigName(".env")   
-	config.SetConfigType("env")    
-	config.AddConfigPath(".")      
-	config.AddConfigPath("..")     
-	config.AddConfigPath("../../") 
-
-	err := config.ReadInConfig()
-	if err != nil {
-		log.Printf("No .env file found, using system environment variables %v", err.Error())
-		panic(fmt.Errorf("fatal error config file: %v", err.Error()))
+	setDefaults(config)
+	config.AutomaticEnv()
+	if err := config.BindPFlag("SERVER_PORT", flags.Lookup("port")); err != nil {
+		return nil, nil, err
+	}
+	if err := config.BindPFlag("SERVER_ENV", flags.Lookup("env")); err != nil {
+		return nil, nil, err
+	}
+
+	config.SetConfigType("env")
+	if *configFile != "" {
+		config.SetConfigFile(*configFile)
+		if err := config.ReadInConfig(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read config file %s: %w", *configFile, err)
+		}
+		return config, flags.Args(), nil
+	}
+
+	config.SetConfigName(".env")
+	config.AddConfigPath(".")
+	config.AddConfigPath("..")
+	config.AddConfigPath("../../")
+	if err := config.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, nil, fmt.Errorf("failed to read .env: %w", err)
+		}
 	}
+	return config, flags.Args(), nil
+}
+
+// setDefaults covers the server, database and cache basics plus the knobs
+// added with them. Policy settings such as token TTLs or login limits keep
+// their defaults in the use case that applies them.
+func setDefaults(config *viper.Viper) {
+	config.SetDefault("SERVER_ENV", EnvProduction)
+	config.SetDefault("SERVER_PORT", "8080")
+	config.SetDefault("SERVER_READ_TIMEOUT_SECONDS", 15)
+	config.SetDefault("SERVER_WRITE_TIMEOUT_SECONDS", 15)
+	config.SetDefault("SERVER_IDLE_TIMEOUT_SECONDS", 60)
+	config.SetDefault("SERVER_BODY_LIMIT_MB", 4)
+	config.SetDefault("SHUTDOWN_TIMEOUT_SECONDS", 20)
+	config.SetDefault("HEALTH_CHECK_TIMEOUT_SECONDS", 2)
+	config.SetDefault("HEALTH_CHECK_CACHE_SECONDS", 5)
+	config.SetDefault("CORS_ALLOWED_ORIGINS", "*")
+
+	config.SetDefault("POSTGRES_HOST", "localhost")
+	config.SetDefault("POSTGRES_PORT", "5432")
+	config.SetDefault("POSTGRES_SSLMODE", "disable")
+	config.SetDefault("DB_MAX_OPEN_CONNS", 25)
+	config.SetDefault("DB_MAX_IDLE_CONNS", 5)
+	config.SetDefault("DB_CONN_MAX_LIFETIME_MINUTES", 30)
+
+	config.SetDefault("CACHE_DRIVER", "redis")
+	config.SetDefault("REDIS_URL", "redis://dragonfly:6379/0")
+
+	config.SetDefault("TRACING_EXPORTER", TracingNone)
+	config.SetDefault("TRACING_SERVICE_NAME", "cakestore")
+	config.SetDefault("TRACING_SAMPLE_RATIO", 1)
+
+	config.SetDefault("MIDTRANS_TIMEOUT_SECONDS", 10)
+	config.SetDefault("NOTIFICATION_DRIVER", "log")
 
-	return config
+	config.SetDefault("SWAGGER_ENABLED", true)
+	config.SetDefault("METRICS_ENABLED", true)
+	config.SetDefault("JOBS_ENABLED", true)
+	config.SetDefault("PPROF_ENABLED", false)
+	config.SetDefault("MIGRATE_ON_START", true)
 }
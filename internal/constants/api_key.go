@@ -0,0 +1,10 @@
+package constants
+
+const (
+	// APIKeyHeader carries a partner API key instead of a bearer token
+	APIKeyHeader = "X-API-Key"
+	// LocalsKeyAPIKey holds the *model.APIKeyPrincipal for requests made with an API key
+	LocalsKeyAPIKey = "api_key"
+	// LocalsKeyAPIKeyAdmitted is set once a permission check has let the API key through
+	LocalsKeyAPIKeyAdmitted = "api_key_admitted"
+)
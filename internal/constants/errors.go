@@ -23,4 +23,48 @@ var (
 	ErrNotFound                   = errors.New("not found")
 	ErrInvalidInterfaceConversion = errors.New("invalid data type for interface conversion")
 	ErrMenuAlreadyInWishlist      = errors.New("menu already in wishlist")
+	ErrInvalidTableToken          = errors.New("invalid or expired table token")
+	ErrTableSessionClosed         = errors.New("table session is closed")
+	ErrOrderNotPayable            = errors.New("order can no longer be paid")
+	ErrOrderAlreadyPaid           = errors.New("order is already fully paid")
+	ErrSplitExceedsOutstanding    = errors.New("split amounts exceed the outstanding balance")
+	ErrInvalidSplit               = errors.New("invalid bill split")
+	ErrPaymentIDRequired          = errors.New("order has several pending payments, pass payment_id")
+	ErrAmountExceedsOutstanding   = errors.New("amount exceeds the outstanding balance")
+	ErrBalanceAwaitingSplits      = errors.New("the remaining balance is awaiting pending split payments")
+	ErrInsufficientTender         = errors.New("amount tendered is less than the amount due")
+	ErrNoOpenShift                = errors.New("no open cashier shift")
+	ErrShiftAlreadyOpen           = errors.New("cashier already has an open shift")
+	ErrInvalidReservationToken    = errors.New("invalid or expired reservation link")
+	ErrReservationNotChangeable   = errors.New("reservation can no longer be changed")
+	ErrReservationBlocked         = errors.New("booking is blocked after too many no-shows")
+	ErrDepositUnpaid              = errors.New("reservation deposit has not been paid")
+	ErrDepositNotApplicable       = errors.New("reservation deposit can no longer be applied")
+	ErrInvalidRefreshToken        = errors.New("invalid or expired refresh token")
+	ErrInvalidAccountToken        = errors.New("invalid or expired link")
+	ErrEmailNotVerified           = errors.New("email address has not been verified")
+	ErrEmailAlreadyVerified       = errors.New("email address is already verified")
+	ErrInvalidCredentials         = errors.New("invalid email or password")
+	ErrLoginLocked                = errors.New("too many failed login attempts, try again later")
+	ErrLoginThrottled             = errors.New("please wait before trying to log in again")
+	ErrTwoFactorNotConfigured     = errors.New("two-factor authentication is not configured")
+	ErrTwoFactorNotSetUp          = errors.New("two-factor authentication has not been set up")
+	ErrTwoFactorAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorRequired          = errors.New("two-factor authentication is required for this account")
+	ErrInvalidTwoFactorCode       = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorToken      = errors.New("invalid or expired two-factor token")
+	ErrUnknownRole                = errors.New("role does not exist")
+	ErrUnknownPermission          = errors.New("unknown permission")
+	ErrRoleAlreadyExists          = errors.New("role already exists")
+	ErrRoleInUse                  = errors.New("role is still assigned to accounts")
+	ErrBuiltInRole                = errors.New("built-in roles cannot be deleted")
+	ErrAdminRoleFixed             = errors.New("the admin role always has every permission")
+	ErrEmailAlreadyRegistered     = errors.New("email already registered")
+	ErrInvalidEmployeeRole        = errors.New("employees cannot have the customer or guest role")
+	ErrInviteAlreadyAccepted      = errors.New("invite has already been accepted")
+	ErrInvalidAPIKey              = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyRateLimited          = errors.New("API key rate limit exceeded")
+	ErrPermissionNotForAPIKeys    = errors.New("permission cannot be given to an API key")
+	ErrStaffAccountDeletion       = errors.New("staff accounts are deleted by an administrator")
+	ErrInvalidCatalog             = errors.New("invalid catalog")
 )
@@ -5,4 +5,8 @@ const (
 	ClaimsKeyName  = "name"
 	ClaimsKeyID    = "customer_id"
 	ClaimsKeyRole  = "role"
-)
\ No newline at end of file
+	// ClaimsKeyTokenID holds the access token jti so logout can revoke it
+	ClaimsKeyTokenID = "jti"
+	// ClaimsKeyTokenExpiry holds the access token expiry as a time.Time
+	ClaimsKeyTokenExpiry = "token_expires_at"
+)
@@ -9,3 +9,21 @@ const (
 	PaymentStatusExpired   PaymentStatus = "expired"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
 )
+
+type PaymentMethod string
+
+const (
+	PaymentMethodMidtrans PaymentMethod = "midtrans"
+	PaymentMethodCash     PaymentMethod = "cash"
+	PaymentMethodCard     PaymentMethod = "card"
+	// PaymentMethodDeposit credits a paid reservation deposit to the bill
+	PaymentMethodDeposit PaymentMethod = "deposit"
+)
+
+type SplitMode string
+
+const (
+	SplitModeEven   SplitMode = "even"
+	SplitModeItem   SplitMode = "item"
+	SplitModeCustom SplitMode = "custom"
+)
@@ -0,0 +1,83 @@
+package constants
+
+// Permissions guard staff endpoints. Roles are stored in the database and map
+// to a set of these; routes check a permission, never a role name.
+const (
+	PermissionMenuWrite          = "menu:write"
+	PermissionOrderReadAll       = "order:read_all"
+	PermissionOrderUpdateStatus  = "order:update_status"
+	PermissionPaymentSettle      = "payment:settle"
+	PermissionPOSOperate         = "pos:operate"
+	PermissionReportRead         = "report:read"
+	PermissionReservationReadAll = "reservation:read_all"
+	PermissionReservationManage  = "reservation:manage"
+	PermissionDepositApply       = "deposit:apply"
+	PermissionInventoryRead      = "inventory:read"
+	PermissionInventoryWrite     = "inventory:write"
+	PermissionInventoryAdjust    = "inventory:adjust"
+	PermissionTableManage        = "table:manage"
+	PermissionTableSessionClose  = "table:close_session"
+	PermissionEmployeeRead       = "employee:read"
+	PermissionEmployeeManage     = "employee:manage"
+	PermissionLoginUnlock        = "auth:unlock"
+	PermissionRoleManage         = "role:manage"
+	PermissionAPIKeyManage       = "api_key:manage"
+)
+
+// Permissions lists every permission a role can be granted
+var Permissions = []string{
+	PermissionMenuWrite,
+	PermissionOrderReadAll,
+	PermissionOrderUpdateStatus,
+	PermissionPaymentSettle,
+	PermissionPOSOperate,
+	PermissionReportRead,
+	PermissionReservationReadAll,
+	PermissionReservationManage,
+	PermissionDepositApply,
+	PermissionInventoryRead,
+	PermissionInventoryWrite,
+	PermissionInventoryAdjust,
+	PermissionTableManage,
+	PermissionTableSessionClose,
+	PermissionEmployeeRead,
+	PermissionEmployeeManage,
+	PermissionLoginUnlock,
+	PermissionRoleManage,
+	PermissionAPIKeyManage,
+}
+
+// DefaultRolePermissions is what the built-in roles are created with. They
+// match the access the roles had before permissions existed, except that menu
+// changes are limited to admins and the kitchen. The admin role always holds
+// every permission, including ones added later.
+var DefaultRolePermissions = map[string][]string{
+	RoleAdmin: Permissions,
+	RoleKitchen: {
+		PermissionMenuWrite,
+		PermissionOrderReadAll,
+		PermissionOrderUpdateStatus,
+		PermissionInventoryRead,
+		PermissionInventoryWrite,
+		PermissionInventoryAdjust,
+	},
+	RoleWaitress: {
+		PermissionOrderReadAll,
+		PermissionReservationManage,
+		PermissionTableSessionClose,
+	},
+	RoleCashier: {
+		PermissionOrderReadAll,
+		PermissionPaymentSettle,
+		PermissionPOSOperate,
+		PermissionDepositApply,
+		PermissionTableManage,
+		PermissionTableSessionClose,
+	},
+	RoleCustomer: {},
+	RoleGuest:    {},
+}
+
+// BuiltInRoles cannot be deleted: customers, guests and the admin account
+// depend on them
+var BuiltInRoles = []string{RoleAdmin, RoleCustomer, RoleGuest}
@@ -6,4 +6,5 @@ const (
 	RoleKitchen  = "kitchen_staff"
 	RoleWaitress = "waitress"
 	RoleCashier  = "cashier"
+	RoleGuest    = "guest"
 )
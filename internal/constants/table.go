@@ -0,0 +1,10 @@
+package constants
+
+const (
+	// TableTokenHeader carries the signed QR token for guest (dine-in) requests
+	TableTokenHeader = "X-Table-Token"
+	// TableTokenQuery is the query parameter fallback used by the printed QR link
+	TableTokenQuery = "token"
+	// LocalsKeyTableSession holds the resolved *entity.TableSession for guest requests
+	LocalsKeyTableSession = "table_session"
+)
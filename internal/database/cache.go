@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrCacheMiss is returned by Get when the key is not cached.
+	ErrCacheMiss = errors.New("key not found in cache")
+	// ErrCacheUnavailable is returned while the cache cannot be reached, or
+	// by the none driver for operations that need real storage.
+	ErrCacheUnavailable = errors.New("cache unavailable")
+)
+
+const (
+	CacheDriverRedis  = "redis"
+	CacheDriverMemory = "memory"
+	CacheDriverNone   = "none"
+
+	// DefaultRedisURL points at the Dragonfly service from docker-compose.
+	DefaultRedisURL = "redis://dragonfly:6379/0"
+)
+
+// Flusher is implemented by caches that can drop every key at once. It is
+// kept out of RedisCache so test doubles need not implement it.
+type Flusher interface {
+	// Flush deletes every key except those starting with one of
+	// keepPrefixes.
+	Flush(ctx context.Context, keepPrefixes ...string) error
+}
+
+// Flush empties cache through Flusher, keeping keys that start with one of
+// keepPrefixes.
+func Flush(ctx context.Context, cache RedisCache, keepPrefixes ...string) error {
+	flusher, ok := cache.(Flusher)
+	if !ok {
+		return fmt.Errorf("cache %T cannot be flushed", cache)
+	}
+	return flusher.Flush(ctx, keepPrefixes...)
+}
+
+// Pinger is implemented by caches backed by a server.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCache checks that the cache server answers. Caches inside the process
+// have nothing to ping and always succeed.
+func PingCache(ctx context.Context, cache RedisCache) error {
+	if pinger, ok := cache.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// CloseCache releases the connections of cache, if it holds any.
+func CloseCache(cache RedisCache) error {
+	if closer, ok := cache.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// hasAnyPrefix reports whether key starts with one of prefixes.
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheConfig selects and tunes the cache backend.
+type CacheConfig struct {
+	Driver   string
+	RedisURL string
+	// MemoryMaxEntries bounds the memory driver, which evicts the least
+	// recently used key once it is full.
+	MemoryMaxEntries int
+	// BreakerThreshold consecutive Redis failures open the circuit, and the
+	// cache is bypassed for BreakerCooldown before Redis is tried again.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// NewCache builds the cache chosen by cfg.Driver. Redis is the default.
+//
+// Redis sits behind a circuit breaker, so during an outage reads pass through
+// to the database instead of waiting on Redis, while writes fail. The memory
+// driver lives inside the process: each instance has its own copy, so it only
+// suits a single instance. The none driver caches nothing and refuses writes.
+// Lockouts, revoked sessions and two-factor challenges are kept in the cache
+// as well, so production requires Redis.
+func NewCache(ctx context.Context, cfg CacheConfig) (RedisCache, error) {
+	switch cfg.Driver {
+	case "", CacheDriverRedis:
+		url := cfg.RedisURL
+		if url == "" {
+			url = DefaultRedisURL
+		}
+		redis, err := NewRedisCacheService(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		return newInstrumentedCache(CacheDriverRedis, NewCircuitBreaker(redis, cfg.BreakerThreshold, cfg.BreakerCooldown)), nil
+	case CacheDriverMemory:
+		return newInstrumentedCache(CacheDriverMemory, NewMemoryCache(cfg.MemoryMaxEntries)), nil
+	case CacheDriverNone:
+		return newInstrumentedCache(CacheDriverNone, NoopCache{}), nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver %q", cfg.Driver)
+	}
+}
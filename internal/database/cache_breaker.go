@@ -0,0 +1,183 @@
+package database
+
+import (
+	"cakestore/internal/metrics"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker stops calling a failing cache. After threshold consecutive
+// errors it opens: every call returns ErrCacheUnavailable without reaching
+// Redis, so requests go straight to the database instead of waiting on Redis
+// timeouts. GetOrLoad treats that as a miss. Writes fail as they would against
+// a down Redis, so callers storing revocations or lockouts see the error
+// instead of losing them. Once the cooldown has passed a single call is let
+// through, and its result closes or reopens the circuit.
+//
+// Deletes refused while open are not replayed. A key written before the
+// outage can therefore be served stale for up to its TTL after Redis is back.
+type CircuitBreaker struct {
+	next      RedisCache
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker wraps next. A threshold or cooldown that is not positive
+// falls back to 5 failures and 30 seconds.
+func NewCircuitBreaker(next RedisCache, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &CircuitBreaker{next: next, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+func (b *CircuitBreaker) Get(ctx context.Context, key string, dest interface{}) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := b.next.Get(ctx, key, dest)
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := b.next.Set(ctx, key, value, expiration)
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) Delete(ctx context.Context, key string) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := b.next.Delete(ctx, key)
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if !b.allow() {
+		return 0, ErrCacheUnavailable
+	}
+	count, err := b.next.Increment(ctx, key, ttl)
+	b.record(err)
+	return count, err
+}
+
+func (b *CircuitBreaker) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := b.next.SetWithTags(ctx, key, value, expiration, tags...)
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) InvalidateTags(ctx context.Context, tags ...string) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := b.next.InvalidateTags(ctx, tags...)
+	b.record(err)
+	return err
+}
+
+// Flush is refused while the circuit is open, since the keys it should drop
+// would otherwise silently survive.
+func (b *CircuitBreaker) Flush(ctx context.Context, keepPrefixes ...string) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := Flush(ctx, b.next, keepPrefixes...)
+	b.record(err)
+	return err
+}
+
+// Ping reports ErrCacheUnavailable while the circuit is open. Otherwise it
+// counts like any other call, so a successful ping can close the circuit.
+func (b *CircuitBreaker) Ping(ctx context.Context) error {
+	if !b.allow() {
+		return ErrCacheUnavailable
+	}
+	err := PingCache(ctx, b.next)
+	b.record(err)
+	return err
+}
+
+func (b *CircuitBreaker) Close() error {
+	return CloseCache(b.next)
+}
+
+// allow reports whether a call may reach the cache. Only one call probes a
+// half-open circuit; the rest keep bypassing it until that call returns.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// record counts a call's outcome. A miss is a normal answer, not a failure.
+func (b *CircuitBreaker) record(err error) {
+	failed := err != nil && !errors.Is(err, ErrCacheMiss)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		if b.state == breakerHalfOpen {
+			log.Println("Cache: Redis is reachable again, closing the circuit")
+			metrics.CacheCircuitOpen.Set(0)
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		if b.state == breakerClosed {
+			log.Printf("Cache: %d consecutive Redis errors, bypassing the cache for %s: %v", b.failures, b.cooldown, err)
+			metrics.CacheCircuitOpen.Set(1)
+		}
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyCache wraps a mapCache and fails every call while down is set.
+type flakyCache struct {
+	*mapCache
+	down  bool
+	calls int
+}
+
+func (c *flakyCache) Get(ctx context.Context, key string, dest interface{}) error {
+	c.calls++
+	if c.down {
+		return errors.New("connection refused")
+	}
+	return c.mapCache.Get(ctx, key, dest)
+}
+
+func (c *flakyCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.calls++
+	if c.down {
+		return errors.New("connection refused")
+	}
+	return c.mapCache.Set(ctx, key, value, expiration)
+}
+
+func TestCircuitBreaker_OpensAfterThresholdAndRecovers(t *testing.T) {
+	redis := &flakyCache{mapCache: newMapCache(), down: true}
+	breaker := NewCircuitBreaker(redis, 3, time.Minute)
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	var value string
+	for i := 0; i < 3; i++ {
+		assert.Error(t, breaker.Get(ctx, "menu:1", &value))
+	}
+	require.Equal(t, 3, redis.calls)
+
+	// Open: Redis is no longer called, and reads and writes fail fast
+	assert.ErrorIs(t, breaker.Get(ctx, "menu:1", &value), ErrCacheUnavailable)
+	assert.ErrorIs(t, breaker.Set(ctx, "auth:revoked:abc", true, time.Minute), ErrCacheUnavailable)
+	assert.ErrorIs(t, breaker.Delete(ctx, "menu:1"), ErrCacheUnavailable)
+	assert.Equal(t, 3, redis.calls)
+
+	// A failed probe after the cooldown opens the circuit again
+	now = now.Add(time.Minute)
+	assert.Error(t, breaker.Get(ctx, "menu:1", &value))
+	assert.ErrorIs(t, breaker.Get(ctx, "menu:1", &value), ErrCacheUnavailable)
+	assert.Equal(t, 4, redis.calls)
+
+	// A successful probe closes it
+	redis.down = false
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, breaker.Get(ctx, "menu:1", &value), ErrCacheMiss)
+	require.NoError(t, breaker.Set(ctx, "menu:1", "cake", time.Minute))
+	require.NoError(t, breaker.Get(ctx, "menu:1", &value))
+	assert.Equal(t, "cake", value)
+}
+
+func TestCircuitBreaker_MissesAreNotFailures(t *testing.T) {
+	redis := &flakyCache{mapCache: newMapCache()}
+	breaker := NewCircuitBreaker(redis, 2, time.Minute)
+	ctx := context.Background()
+
+	var value string
+	for i := 0; i < 5; i++ {
+		assert.ErrorIs(t, breaker.Get(ctx, "menu:1", &value), ErrCacheMiss)
+	}
+	assert.Equal(t, 5, redis.calls)
+}
+
+func TestGetOrLoad_PassesThroughOpenCircuit(t *testing.T) {
+	redis := &flakyCache{mapCache: newMapCache(), down: true}
+	breaker := NewCircuitBreaker(redis, 1, time.Minute)
+	calls := 0
+	load := func() (string, error) {
+		calls++
+		return "cake", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := GetOrLoad(context.Background(), breaker, "menu:1", LoadOptions{TTL: time.Minute}, load)
+		require.NoError(t, err)
+		assert.Equal(t, "cake", value)
+	}
+	assert.Equal(t, 3, calls)
+}
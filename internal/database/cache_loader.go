@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// LoadOptions tunes a GetOrLoad read-through.
+type LoadOptions struct {
+	// TTL is how long a loaded value is served as fresh.
+	TTL time.Duration
+	// Jitter spreads the TTL by up to this fraction either way, so keys
+	// filled at the same moment do not all expire at the same moment.
+	Jitter float64
+	// StaleFor keeps a value this long past its TTL. A read in that window
+	// gets the stale value straight away while one reload runs in the
+	// background. Zero turns stale-while-revalidate off.
+	StaleFor time.Duration
+	// NotFound is the error the loader returns for a missing record. When it
+	// is set and NegativeTTL is positive, that miss is cached too, and reads
+	// keep getting NotFound until it expires or a tag drops it.
+	NotFound    error
+	NegativeTTL time.Duration
+	// Tags are recorded with SetWithTags so writes can drop the key.
+	Tags []string
+}
+
+// cacheEntry is what GetOrLoad stores: the value plus when it goes stale.
+type cacheEntry[T any] struct {
+	Value      T         `json:"value"`
+	NotFound   bool      `json:"not_found,omitempty"`
+	FreshUntil time.Time `json:"fresh_until"`
+}
+
+// loads coalesces concurrent misses of the same key into one load.
+var loads singleflight.Group
+
+// GetOrLoad returns the value cached under key, or calls load and caches the
+// result. Concurrent misses of a key share a single load call, so a hot key
+// expiring costs one query rather than one per request. Callers share the
+// loaded value and must not modify it. A key must always hold the same T.
+//
+// Cache errors are treated as misses: the value is then loaded and returned
+// as if nothing was cached.
+func GetOrLoad[T any](ctx context.Context, cache RedisCache, key string, opts LoadOptions, load func() (T, error)) (T, error) {
+	var entry cacheEntry[T]
+	if err := cache.Get(ctx, key, &entry); err == nil {
+		if time.Now().Before(entry.FreshUntil) {
+			if entry.NotFound {
+				var zero T
+				return zero, opts.NotFound
+			}
+			return entry.Value, nil
+		}
+		// Only values are kept past their TTL, never cached misses
+		if !entry.NotFound {
+			go func() {
+				_, _, _ = loads.Do(key, func() (interface{}, error) {
+					return loadAndStore(context.WithoutCancel(ctx), cache, key, opts, load)
+				})
+			}()
+			return entry.Value, nil
+		}
+	}
+
+	value, err, _ := loads.Do(key, func() (interface{}, error) {
+		return loadAndStore(ctx, cache, key, opts, load)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+func loadAndStore[T any](ctx context.Context, cache RedisCache, key string, opts LoadOptions, load func() (T, error)) (T, error) {
+	value, err := load()
+	if err != nil {
+		if opts.NotFound != nil && opts.NegativeTTL > 0 && errors.Is(err, opts.NotFound) {
+			ttl := jitter(opts.NegativeTTL, opts.Jitter)
+			store(ctx, cache, key, cacheEntry[T]{NotFound: true, FreshUntil: time.Now().Add(ttl)}, ttl, opts.Tags)
+		}
+		return value, err
+	}
+
+	ttl := jitter(opts.TTL, opts.Jitter)
+	store(ctx, cache, key, cacheEntry[T]{Value: value, FreshUntil: time.Now().Add(ttl)}, ttl+opts.StaleFor, opts.Tags)
+	return value, nil
+}
+
+// store writes the entry and only logs a failure, since the caller already
+// has the loaded value. ErrCacheUnavailable is not logged: an open circuit or
+// the none driver refuses every write, and that is reported elsewhere.
+func store[T any](ctx context.Context, cache RedisCache, key string, entry cacheEntry[T], expiration time.Duration, tags []string) {
+	var err error
+	if len(tags) > 0 {
+		err = cache.SetWithTags(ctx, key, entry, expiration, tags...)
+	} else {
+		err = cache.Set(ctx, key, entry, expiration)
+	}
+	if err != nil && !errors.Is(err, ErrCacheUnavailable) {
+		log.Printf("Cache: failed to store key %s: %v", key, err)
+	}
+}
+
+// jitter returns ttl moved by a random amount of up to fraction*ttl either way.
+func jitter(ttl time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || ttl <= 0 {
+		return ttl
+	}
+	spread := (rand.Float64()*2 - 1) * fraction * float64(ttl)
+	return ttl + time.Duration(spread)
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapCache is a RedisCache over a map. Expiration is ignored, which is enough
+// here because GetOrLoad decides freshness from the stored entry.
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{values: make(map[string][]byte)}
+}
+
+func (c *mapCache) Get(_ context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.values[key]
+	if !ok {
+		return ErrCacheMiss
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *mapCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = data
+	return nil
+}
+
+func (c *mapCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *mapCache) Increment(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (c *mapCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, _ ...string) error {
+	return c.Set(ctx, key, value, expiration)
+}
+
+func (c *mapCache) InvalidateTags(context.Context, ...string) error {
+	return nil
+}
+
+func TestGetOrLoad_CachesLoadedValue(t *testing.T) {
+	cache := newMapCache()
+	var calls int32
+	load := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "cake", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := GetOrLoad(context.Background(), cache, "test:cached", LoadOptions{TTL: time.Minute}, load)
+		require.NoError(t, err)
+		assert.Equal(t, "cake", value)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestGetOrLoad_CoalescesConcurrentMisses(t *testing.T) {
+	cache := newMapCache()
+	var calls int32
+	release := make(chan struct{})
+	load := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 20
+	var started, done sync.WaitGroup
+	started.Add(callers)
+	done.Add(callers)
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			results[i], _ = GetOrLoad(context.Background(), cache, "test:herd", LoadOptions{TTL: time.Minute}, load)
+		}(i)
+	}
+	started.Wait()
+	// Give every caller time to join the flight before the load finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	done.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, result := range results {
+		assert.Equal(t, 42, result)
+	}
+}
+
+func TestGetOrLoad_CachesNotFound(t *testing.T) {
+	cache := newMapCache()
+	errMissing := errors.New("missing")
+	var calls int32
+	load := func() (*string, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errMissing
+	}
+	opts := LoadOptions{TTL: time.Minute, NotFound: errMissing, NegativeTTL: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		_, err := GetOrLoad(context.Background(), cache, "test:missing", opts, load)
+		assert.ErrorIs(t, err, errMissing)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Other errors are never cached
+	errDown := errors.New("database down")
+	for i := 0; i < 2; i++ {
+		_, err := GetOrLoad(context.Background(), cache, "test:down", opts, func() (*string, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errDown
+		})
+		assert.ErrorIs(t, err, errDown)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestGetOrLoad_ServesStaleWhileRevalidating(t *testing.T) {
+	cache := newMapCache()
+	opts := LoadOptions{TTL: 10 * time.Millisecond, StaleFor: time.Hour}
+
+	value, err := GetOrLoad(context.Background(), cache, "test:stale", opts, func() (string, error) {
+		return "old", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "old", value)
+
+	time.Sleep(20 * time.Millisecond)
+	reloaded := make(chan struct{})
+	value, err = GetOrLoad(context.Background(), cache, "test:stale", opts, func() (string, error) {
+		defer close(reloaded)
+		return "new", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "old", value)
+
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("stale value was not reloaded in the background")
+	}
+	assert.Eventually(t, func() bool {
+		value, err := GetOrLoad(context.Background(), cache, "test:stale", opts, func() (string, error) {
+			return "unexpected", nil
+		})
+		return err == nil && value == "new"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestJitter_StaysWithinFraction(t *testing.T) {
+	ttl := 10 * time.Minute
+	for i := 0; i < 1000; i++ {
+		got := jitter(ttl, 0.1)
+		assert.GreaterOrEqual(t, got, 9*time.Minute)
+		assert.LessOrEqual(t, got, 11*time.Minute)
+	}
+	assert.Equal(t, ttl, jitter(ttl, 0))
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultMemoryMaxEntries = 10000
+
+// MemoryCache is an in-process LRU cache with per-key expiry. Values are
+// stored as JSON, like in Redis, so callers never share mutable state with
+// the cache and a value reads back the same whichever driver is in use.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+	tags       map[string]map[string]struct{}
+	now        func() time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	tags      []string
+}
+
+// NewMemoryCache returns a cache holding at most maxEntries keys, 10000 when
+// maxEntries is not positive.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryMaxEntries
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	entry := c.lookup(key)
+	c.mu.Unlock()
+	if entry == nil {
+		return fmt.Errorf("%w: %s", ErrCacheMiss, key)
+	}
+	return json.Unmarshal(entry.value, dest)
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return c.SetWithTags(ctx, key, value, expiration)
+}
+
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	return nil
+}
+
+// Increment counts like Redis INCR: the expiry is set by the first increment
+// and later increments keep it.
+func (c *MemoryCache) Increment(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry := c.lookup(key); entry != nil {
+		count, err := strconv.ParseInt(string(entry.value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cache key %s does not hold a counter", key)
+		}
+		count++
+		entry.value = []byte(strconv.FormatInt(count, 10))
+		return count, nil
+	}
+	c.store(key, []byte("1"), ttl, nil)
+	return 1, nil
+}
+
+func (c *MemoryCache) SetWithTags(_ context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value for cache: %w", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(key, data, expiration, tags)
+	return nil
+}
+
+func (c *MemoryCache) InvalidateTags(_ context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if element, ok := c.entries[key]; ok {
+				c.remove(element)
+			}
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Flush(_ context.Context, keepPrefixes ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, element := range c.entries {
+		if !hasAnyPrefix(key, keepPrefixes) {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+// lookup returns the live entry for key and marks it as recently used. An
+// expired entry is dropped on the way.
+func (c *MemoryCache) lookup(key string) *memoryEntry {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return nil
+	}
+	c.order.MoveToFront(element)
+	return entry
+}
+
+// store replaces key and evicts the least recently used keys beyond the
+// limit. A zero expiration keeps the key until it is deleted or evicted.
+func (c *MemoryCache) store(key string, value []byte, expiration time.Duration, tags []string) {
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	entry := &memoryEntry{key: key, value: value, tags: tags}
+	if expiration > 0 {
+		entry.expiresAt = c.now().Add(expiration)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+
+	for c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*memoryEntry)
+	delete(c.entries, entry.key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], entry.key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache_ExpiresKeys(t *testing.T) {
+	cache := NewMemoryCache(10)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "menu:1", "cake", time.Minute))
+	var value string
+	require.NoError(t, cache.Get(ctx, "menu:1", &value))
+	assert.Equal(t, "cake", value)
+
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, cache.Get(ctx, "menu:1", &value), ErrCacheMiss)
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCache(2)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "a", 1, time.Minute))
+	require.NoError(t, cache.Set(ctx, "b", 2, time.Minute))
+	var value int
+	// Reading a makes b the least recently used key
+	require.NoError(t, cache.Get(ctx, "a", &value))
+	require.NoError(t, cache.Set(ctx, "c", 3, time.Minute))
+
+	assert.ErrorIs(t, cache.Get(ctx, "b", &value), ErrCacheMiss)
+	assert.NoError(t, cache.Get(ctx, "a", &value))
+	assert.NoError(t, cache.Get(ctx, "c", &value))
+}
+
+func TestMemoryCache_InvalidateTags(t *testing.T) {
+	cache := NewMemoryCache(10)
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetWithTags(ctx, "menus:page:1", 1, time.Minute, "menus:list"))
+	require.NoError(t, cache.SetWithTags(ctx, "menus:page:2", 2, time.Minute, "menus:list"))
+	require.NoError(t, cache.Set(ctx, "menu:1", 1, time.Minute))
+
+	require.NoError(t, cache.InvalidateTags(ctx, "menus:list"))
+
+	var value int
+	assert.ErrorIs(t, cache.Get(ctx, "menus:page:1", &value), ErrCacheMiss)
+	assert.ErrorIs(t, cache.Get(ctx, "menus:page:2", &value), ErrCacheMiss)
+	assert.NoError(t, cache.Get(ctx, "menu:1", &value))
+	assert.Empty(t, cache.tags)
+}
+
+func TestMemoryCache_IncrementKeepsFirstExpiry(t *testing.T) {
+	cache := NewMemoryCache(10)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		count, err := cache.Increment(ctx, "login:failures", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, want, count)
+		now = now.Add(20 * time.Second)
+	}
+
+	// The window started with the first increment, so it has now run out
+	count, err := cache.Increment(ctx, "login:failures", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryCache_FlushKeepsPrefixes(t *testing.T) {
+	cache := NewMemoryCache(10)
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetWithTags(ctx, "menus:page:1", 1, time.Minute, "menus:list"))
+	require.NoError(t, cache.Set(ctx, "menu:1", 1, time.Minute))
+	require.NoError(t, cache.Set(ctx, "auth:revoked:abc", true, time.Minute))
+
+	require.NoError(t, Flush(ctx, newInstrumentedCache(CacheDriverMemory, cache), "auth:"))
+
+	var value int
+	assert.ErrorIs(t, cache.Get(ctx, "menus:page:1", &value), ErrCacheMiss)
+	assert.ErrorIs(t, cache.Get(ctx, "menu:1", &value), ErrCacheMiss)
+	var revoked bool
+	assert.NoError(t, cache.Get(ctx, "auth:revoked:abc", &revoked))
+	assert.Empty(t, cache.tags)
+
+	require.NoError(t, cache.Flush(ctx))
+	assert.ErrorIs(t, cache.Get(ctx, "auth:revoked:abc", &revoked), ErrCacheMiss)
+}
+
+func TestNoopCache_NeverStores(t *testing.T) {
+	cache := NoopCache{}
+	ctx := context.Background()
+
+	assert.ErrorIs(t, cache.Set(ctx, "auth:revoked:abc", true, time.Minute), ErrCacheUnavailable)
+	var value string
+	assert.ErrorIs(t, cache.Get(ctx, "menu:1", &value), ErrCacheMiss)
+	assert.NoError(t, cache.Delete(ctx, "menu:1"))
+	_, err := cache.Increment(ctx, "login:failures", time.Minute)
+	assert.ErrorIs(t, err, ErrCacheUnavailable)
+}
+
+func TestNewCache_RejectsUnknownDriver(t *testing.T) {
+	_, err := NewCache(context.Background(), CacheConfig{Driver: "memcached"})
+	assert.Error(t, err)
+
+	cache, err := NewCache(context.Background(), CacheConfig{Driver: CacheDriverMemory})
+	require.NoError(t, err)
+	value, err := GetOrLoad(context.Background(), cache, "menu:1", LoadOptions{TTL: time.Minute}, func() (string, error) {
+		return "cake", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cake", value)
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"cakestore/internal/metrics"
+	"context"
+	"errors"
+	"time"
+)
+
+// instrumentedCache counts lookups and errors of the cache it wraps.
+type instrumentedCache struct {
+	next    RedisCache
+	backend string
+}
+
+func newInstrumentedCache(backend string, next RedisCache) RedisCache {
+	return &instrumentedCache{next: next, backend: backend}
+}
+
+func (c *instrumentedCache) Get(ctx context.Context, key string, dest interface{}) error {
+	err := c.next.Get(ctx, key, dest)
+	switch {
+	case err == nil:
+		metrics.CacheLookups.WithLabelValues(c.backend, "hit").Inc()
+	case errors.Is(err, ErrCacheMiss):
+		metrics.CacheLookups.WithLabelValues(c.backend, "miss").Inc()
+	default:
+		metrics.CacheLookups.WithLabelValues(c.backend, "error").Inc()
+		c.countError("get")
+	}
+	return err
+}
+
+func (c *instrumentedCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return c.observe("set", c.next.Set(ctx, key, value, expiration))
+}
+
+func (c *instrumentedCache) Delete(ctx context.Context, key string) error {
+	return c.observe("delete", c.next.Delete(ctx, key))
+}
+
+func (c *instrumentedCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	count, err := c.next.Increment(ctx, key, ttl)
+	return count, c.observe("increment", err)
+}
+
+func (c *instrumentedCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	return c.observe("set", c.next.SetWithTags(ctx, key, value, expiration, tags...))
+}
+
+func (c *instrumentedCache) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.observe("invalidate", c.next.InvalidateTags(ctx, tags...))
+}
+
+func (c *instrumentedCache) Flush(ctx context.Context, keepPrefixes ...string) error {
+	return c.observe("flush", Flush(ctx, c.next, keepPrefixes...))
+}
+
+func (c *instrumentedCache) Ping(ctx context.Context) error {
+	return PingCache(ctx, c.next)
+}
+
+func (c *instrumentedCache) Close() error {
+	return CloseCache(c.next)
+}
+
+func (c *instrumentedCache) observe(operation string, err error) error {
+	if err != nil {
+		c.countError(operation)
+	}
+	return err
+}
+
+func (c *instrumentedCache) countError(operation string) {
+	metrics.CacheErrors.WithLabelValues(c.backend, operation).Inc()
+}
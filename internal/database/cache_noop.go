@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// NoopCache caches nothing: every read is a miss. Set and Increment fail
+// with ErrCacheUnavailable, since a revoked session, lockout or rate limit
+// that is silently dropped would look like it was applied. Deletes succeed,
+// as there is nothing to delete.
+type NoopCache struct{}
+
+func (NoopCache) Get(context.Context, string, interface{}) error {
+	return ErrCacheMiss
+}
+
+func (NoopCache) Set(context.Context, string, interface{}, time.Duration) error {
+	return ErrCacheUnavailable
+}
+
+func (NoopCache) Delete(context.Context, string) error {
+	return nil
+}
+
+func (NoopCache) Increment(context.Context, string, time.Duration) (int64, error) {
+	return 0, ErrCacheUnavailable
+}
+
+func (NoopCache) SetWithTags(context.Context, string, interface{}, time.Duration, ...string) error {
+	return ErrCacheUnavailable
+}
+
+func (NoopCache) InvalidateTags(context.Context, ...string) error {
+	return nil
+}
+
+func (NoopCache) Flush(context.Context, ...string) error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"cakestore/internal/health"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// PostgresChecker pings the database.
+func PostgresChecker(db *gorm.DB) health.Checker {
+	return health.CheckerFunc("postgres", func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// CacheChecker pings the cache server. An open circuit breaker reports the
+// cache as unavailable without waiting on Redis.
+func CacheChecker(cache RedisCache) health.Checker {
+	return health.CheckerFunc("cache", func(ctx context.Context) error {
+		return PingCache(ctx, cache)
+	})
+}
+
+// MigrationChecker fails while embedded migrations are still pending, for
+// example when MIGRATE_ON_START is off and the new release has not been
+// migrated yet.
+func MigrationChecker(migrator *Migrator) health.Checker {
+	return health.CheckerFunc("migrations", func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending, starting with %04d %s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	})
+}
+
+// poolChecker reports the connection pool figures and fails while the pool
+// is saturated: every connection is in use and requests had to wait for one
+// since the previous check.
+type poolChecker struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastWaits int64
+}
+
+func PoolChecker(db *gorm.DB) health.Checker {
+	return &poolChecker{db: db}
+}
+
+func (c *poolChecker) Name() string {
+	return "postgres_pool"
+}
+
+func (c *poolChecker) Check(context.Context) (map[string]interface{}, error) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	stats := sqlDB.Stats()
+	details := poolDetails(stats)
+
+	c.mu.Lock()
+	waited := stats.WaitCount - c.lastWaits
+	c.lastWaits = stats.WaitCount
+	c.mu.Unlock()
+
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections && waited > 0 {
+		return details, fmt.Errorf("all %d connections in use, %d requests waited since the last check", stats.MaxOpenConnections, waited)
+	}
+	return details, nil
+}
+
+func poolDetails(stats sql.DBStats) map[string]interface{} {
+	return map[string]interface{}{
+		"max_open":      stats.MaxOpenConnections,
+		"open":          stats.OpenConnections,
+		"in_use":        stats.InUse,
+		"idle":          stats.Idle,
+		"wait_count":    stats.WaitCount,
+		"wait_duration": stats.WaitDuration.String(),
+	}
+}
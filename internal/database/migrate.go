@@ -1,29 +1,97 @@
 package database
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
+	"context"
 	"log"
+	"slices"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// RunMigrations applies every pending migration and makes sure the built-in
+// roles exist.
 func RunMigrations(db *gorm.DB) error {
 	log.Println("🔄 Running database migrations...")
-	err := db.AutoMigrate(
-		&entity.Menu{},
-		&entity.Customer{},
-		&entity.Order{},
-		&entity.OrderItem{},
-		&entity.Payment{},
-		&entity.Cart{},
-		&entity.WishList{},
-		&entity.Reservation{},
-		&entity.Inventory{},
-		&entity.Table{},
-	)
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background(), MigrateOptions{})
 	if err != nil {
 		return err
 	}
+	for _, migration := range applied {
+		log.Printf("Applied migration %04d %s", migration.Version, migration.Name)
+	}
+
+	if err := seedRoles(db); err != nil {
+		return err
+	}
 	log.Println("✅ Database migrations completed successfully")
 	return nil
 }
+
+// seedRoles creates the built-in roles with their default permissions, and an
+// empty role for any other role name already held by an account so it can be
+// granted permissions. Existing roles are left alone to keep admin edits,
+// except that the admin role is given any permission it is missing.
+func seedRoles(db *gorm.DB) error {
+	var existing []string
+	if err := db.Model(&entity.Role{}).Pluck("name", &existing).Error; err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		known[name] = true
+	}
+
+	now := time.Now()
+	for name, permissions := range constants.DefaultRolePermissions {
+		if known[name] {
+			continue
+		}
+		role := entity.Role{Name: name, CreatedAt: now, UpdatedAt: now}
+		for _, permission := range permissions {
+			role.Permissions = append(role.Permissions, entity.RolePermission{RoleName: name, Permission: permission})
+		}
+		if err := db.Create(&role).Error; err != nil {
+			return err
+		}
+		known[name] = true
+		log.Printf("Created role %s with %d permissions", name, len(permissions))
+	}
+
+	var assigned []string
+	if err := db.Model(&entity.Customer{}).Distinct().Pluck("role", &assigned).Error; err != nil {
+		return err
+	}
+	for _, name := range assigned {
+		if name == "" || known[name] {
+			continue
+		}
+		if err := db.Create(&entity.Role{Name: name, CreatedAt: now, UpdatedAt: now}).Error; err != nil {
+			return err
+		}
+		known[name] = true
+		log.Printf("⚠️ Created role %s without permissions for existing accounts, grant them through /api/v1/roles", name)
+	}
+
+	var granted []string
+	if err := db.Model(&entity.RolePermission{}).Where("role_name = ?", constants.RoleAdmin).Pluck("permission", &granted).Error; err != nil {
+		return err
+	}
+	for _, permission := range constants.Permissions {
+		if slices.Contains(granted, permission) {
+			continue
+		}
+		if err := db.Create(&entity.RolePermission{RoleName: constants.RoleAdmin, Permission: permission}).Error; err != nil {
+			return err
+		}
+		log.Printf("Granted %s to the admin role", permission)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+DROP TABLE inventories;
+DROP TABLE reservations;
+DROP TABLE wishlists;
+DROP TABLE carts;
+DROP TABLE payments;
+DROP TABLE order_items;
+DROP TABLE orders;
+DROP TABLE tables;
+DROP TABLE customers;
+DROP TABLE menus;
@@ -0,0 +1,135 @@
+-- Schema as created by GORM AutoMigrate before versioned migrations existed.
+-- Databases that already have it are recorded at this version without
+-- running it, so it must not change.
+
+CREATE TABLE menus (
+    id bigserial PRIMARY KEY,
+    title text,
+    description text,
+    price decimal,
+    quantity bigint,
+    category text,
+    rating decimal,
+    image text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE TABLE customers (
+    id bigserial PRIMARY KEY,
+    name text,
+    email text,
+    password text,
+    address text,
+    role text DEFAULT 'customer',
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_customers_email UNIQUE (email)
+);
+
+CREATE TABLE tables (
+    id bigserial PRIMARY KEY,
+    table_number bigint NOT NULL,
+    capacity bigint NOT NULL,
+    is_available boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT uni_tables_table_number UNIQUE (table_number)
+);
+
+CREATE TABLE orders (
+    id bigserial PRIMARY KEY,
+    customer_id bigint,
+    status text,
+    food_status text,
+    total_price decimal,
+    delivery_address text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_orders_customer FOREIGN KEY (customer_id) REFERENCES customers (id)
+);
+
+CREATE TABLE order_items (
+    id bigserial PRIMARY KEY,
+    order_id bigint,
+    menu_id bigint,
+    quantity bigint,
+    price decimal,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_order_items_menu FOREIGN KEY (menu_id) REFERENCES menus (id),
+    CONSTRAINT fk_orders_items FOREIGN KEY (order_id) REFERENCES orders (id)
+);
+
+CREATE TABLE payments (
+    id bigserial PRIMARY KEY,
+    order_id bigint,
+    amount decimal,
+    status text,
+    payment_token text,
+    payment_url text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_payments_order FOREIGN KEY (order_id) REFERENCES orders (id)
+);
+
+CREATE TABLE carts (
+    id bigserial PRIMARY KEY,
+    customer_id bigint,
+    menu_id bigint,
+    quantity bigint,
+    price decimal,
+    subtotal decimal,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+
+CREATE TABLE wishlists (
+    id bigserial PRIMARY KEY,
+    customer_id bigint NOT NULL,
+    menu_id bigint,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_wishlists_customer FOREIGN KEY (customer_id) REFERENCES customers (id),
+    CONSTRAINT fk_wishlists_menu FOREIGN KEY (menu_id) REFERENCES menus (id)
+);
+
+CREATE TABLE reservations (
+    id bigserial PRIMARY KEY,
+    customer_id bigint,
+    table_id bigint,
+    table_number bigint,
+    guest_count bigint,
+    reserve_date timestamptz,
+    status text,
+    special_notes text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_reservations_customer FOREIGN KEY (customer_id) REFERENCES customers (id),
+    CONSTRAINT fk_tables_reservations FOREIGN KEY (table_id) REFERENCES tables (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_reservations_deleted_at ON reservations (deleted_at);
+
+CREATE TABLE inventories (
+    id bigserial PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    quantity decimal NOT NULL,
+    unit varchar(50) NOT NULL,
+    minimum_stock decimal NOT NULL,
+    reorder_point decimal NOT NULL,
+    unit_price decimal NOT NULL,
+    last_restock_date timestamptz NOT NULL,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL,
+    deleted_at timestamptz
+);
+CREATE INDEX idx_inventories_deleted_at ON inventories (deleted_at);
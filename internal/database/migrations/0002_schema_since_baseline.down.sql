@@ -0,0 +1,28 @@
+DROP TABLE receipts;
+DROP TABLE shift_transactions;
+DROP TABLE cashier_shifts;
+DROP TABLE reservation_deposits;
+ALTER TABLE reservations DROP COLUMN reminder_sent_at;
+ALTER TABLE reservations DROP COLUMN confirmed_at;
+DROP INDEX idx_payments_order_id;
+DROP INDEX idx_payments_gateway_order_id;
+DROP INDEX idx_payments_receipt_id;
+ALTER TABLE payments DROP COLUMN reference;
+ALTER TABLE payments DROP COLUMN receipt_id;
+ALTER TABLE payments DROP COLUMN description;
+ALTER TABLE payments DROP COLUMN gateway_order_id;
+ALTER TABLE payments DROP COLUMN method;
+DROP INDEX idx_orders_table_session_id;
+ALTER TABLE orders DROP COLUMN table_session_id;
+ALTER TABLE orders DROP COLUMN table_id;
+DROP TABLE table_sessions;
+ALTER TABLE tables DROP COLUMN qr_token_version;
+DROP TABLE two_factor_recovery_codes;
+DROP TABLE two_factors;
+DROP TABLE account_tokens;
+DROP TABLE refresh_tokens;
+DROP TABLE api_key_permissions;
+DROP TABLE api_keys;
+DROP TABLE role_permissions;
+DROP TABLE roles;
+ALTER TABLE customers DROP COLUMN email_verified_at;
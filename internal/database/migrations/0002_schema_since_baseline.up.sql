@@ -0,0 +1,202 @@
+-- Tables and columns added by AutoMigrate after the baseline, up to when
+-- versioned migrations replaced it. Everything is created only if missing,
+-- so a database that AutoMigrate had already moved past the baseline can be
+-- brought up to date as well.
+
+-- Accounts that existed before email verification are trusted as verified
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'customers' AND column_name = 'email_verified_at'
+    ) THEN
+        ALTER TABLE customers ADD COLUMN email_verified_at timestamptz;
+        UPDATE customers SET email_verified_at = created_at;
+    END IF;
+END $$;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS role text DEFAULT 'customer';
+
+CREATE TABLE IF NOT EXISTS roles (
+    name text PRIMARY KEY,
+    description text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_name text,
+    permission text,
+    PRIMARY KEY (role_name, permission),
+    CONSTRAINT fk_roles_permissions FOREIGN KEY (role_name) REFERENCES roles (name)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    prefix text,
+    key_hash text,
+    rate_limit bigint,
+    expires_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_by bigint,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys (key_hash);
+
+CREATE TABLE IF NOT EXISTS api_key_permissions (
+    api_key_id bigint,
+    permission text,
+    PRIMARY KEY (api_key_id, permission),
+    CONSTRAINT fk_api_keys_permissions FOREIGN KEY (api_key_id) REFERENCES api_keys (id)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial PRIMARY KEY,
+    customer_id bigint,
+    family_id text,
+    token_hash text,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    replaced_by_id bigint,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_customer_id ON refresh_tokens (customer_id);
+
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id bigserial PRIMARY KEY,
+    customer_id bigint,
+    purpose text,
+    token_hash text,
+    expires_at timestamptz,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_tokens_token_hash ON account_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_account_tokens_customer_id ON account_tokens (customer_id);
+
+CREATE TABLE IF NOT EXISTS two_factors (
+    customer_id bigint PRIMARY KEY,
+    secret text NOT NULL,
+    enabled_at timestamptz,
+    last_used_step bigint,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    id bigserial PRIMARY KEY,
+    customer_id bigint,
+    code_hash text,
+    used_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_customer_id ON two_factor_recovery_codes (customer_id);
+
+ALTER TABLE tables ADD COLUMN IF NOT EXISTS qr_token_version bigint NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS table_sessions (
+    id bigserial PRIMARY KEY,
+    table_id bigint,
+    guest_customer_id bigint,
+    token_version bigint,
+    status text,
+    opened_at timestamptz,
+    closed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_table_sessions_table FOREIGN KEY (table_id) REFERENCES tables (id),
+    CONSTRAINT fk_table_sessions_guest_customer FOREIGN KEY (guest_customer_id) REFERENCES customers (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_table_sessions_open ON table_sessions (table_id) WHERE status = 'open';
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS table_id bigint;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS table_session_id bigint;
+CREATE INDEX IF NOT EXISTS idx_orders_table_session_id ON orders (table_session_id);
+
+-- Every payment before the point of sale went through Midtrans
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS method text DEFAULT 'midtrans';
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS gateway_order_id text;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS description text;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS receipt_id bigint;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS reference text;
+CREATE INDEX IF NOT EXISTS idx_payments_receipt_id ON payments (receipt_id);
+CREATE INDEX IF NOT EXISTS idx_payments_gateway_order_id ON payments (gateway_order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS confirmed_at timestamptz;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS reminder_sent_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS reservation_deposits (
+    id bigserial PRIMARY KEY,
+    reservation_id bigint,
+    amount decimal,
+    status text,
+    gateway_order_id text,
+    payment_token text,
+    payment_url text,
+    due_at timestamptz,
+    paid_at timestamptz,
+    applied_order_id bigint,
+    applied_amount decimal,
+    refund_amount decimal,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_reservations_deposit FOREIGN KEY (reservation_id) REFERENCES reservations (id)
+);
+CREATE INDEX IF NOT EXISTS idx_reservation_deposits_due_at ON reservation_deposits (due_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservation_deposits_gateway_order_id ON reservation_deposits (gateway_order_id);
+CREATE INDEX IF NOT EXISTS idx_reservation_deposits_status ON reservation_deposits (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservation_deposits_reservation_id ON reservation_deposits (reservation_id);
+
+CREATE TABLE IF NOT EXISTS cashier_shifts (
+    id bigserial PRIMARY KEY,
+    cashier_id bigint,
+    status text,
+    opening_float decimal,
+    expected_amount decimal,
+    counted_amount decimal,
+    variance decimal,
+    notes text,
+    opened_at timestamptz,
+    closed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_cashier_shifts_cashier FOREIGN KEY (cashier_id) REFERENCES customers (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cashier_shifts_open ON cashier_shifts (cashier_id) WHERE status = 'open';
+
+CREATE TABLE IF NOT EXISTS shift_transactions (
+    id bigserial PRIMARY KEY,
+    shift_id bigint,
+    type text,
+    method text,
+    amount decimal,
+    order_id bigint,
+    reason text,
+    created_at timestamptz,
+    CONSTRAINT fk_cashier_shifts_transactions FOREIGN KEY (shift_id) REFERENCES cashier_shifts (id)
+);
+CREATE INDEX IF NOT EXISTS idx_shift_transactions_created_at ON shift_transactions (created_at);
+CREATE INDEX IF NOT EXISTS idx_shift_transactions_shift_id ON shift_transactions (shift_id);
+
+CREATE TABLE IF NOT EXISTS receipts (
+    id bigserial PRIMARY KEY,
+    number text,
+    cashier_id bigint,
+    shift_id bigint,
+    table_session_id bigint,
+    method text,
+    amount decimal,
+    amount_tendered decimal,
+    change_given decimal,
+    reference text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_receipts_cashier FOREIGN KEY (cashier_id) REFERENCES customers (id)
+);
+CREATE INDEX IF NOT EXISTS idx_receipts_shift_id ON receipts (shift_id);
+CREATE INDEX IF NOT EXISTS idx_receipts_cashier_id ON receipts (cashier_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_receipts_number ON receipts (number);
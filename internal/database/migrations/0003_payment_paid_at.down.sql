@@ -0,0 +1,2 @@
+DROP INDEX idx_payments_paid_at;
+ALTER TABLE payments DROP COLUMN paid_at;
@@ -0,0 +1,7 @@
+-- paid_at is when a payment was received, so reports no longer read the
+-- settlement time from updated_at. Payments that succeeded before this
+-- migration keep their last update as the best estimate.
+
+ALTER TABLE payments ADD COLUMN paid_at timestamptz;
+UPDATE payments SET paid_at = updated_at WHERE status = 'success';
+CREATE INDEX idx_payments_paid_at ON payments (paid_at);
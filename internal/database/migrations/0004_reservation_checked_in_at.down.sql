@@ -0,0 +1 @@
+ALTER TABLE reservations DROP COLUMN checked_in_at;
@@ -0,0 +1,4 @@
+-- checked_in_at is when staff seated the party; the no-show job skips
+-- reservations that have it.
+
+ALTER TABLE reservations ADD COLUMN checked_in_at timestamptz;
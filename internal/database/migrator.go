@@ -0,0 +1,338 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so
+// replicas starting together apply each migration once.
+const migrationLockKey int64 = 0x63616b65 // "cake"
+
+// baselineTable marks a database created by GORM AutoMigrate before
+// versioned migrations existed.
+const baselineTable = "customers"
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change, read from
+// migrations/<version>_<name>.up.sql and the matching .down.sql.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus is a migration and whether it has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	// Modified is set when the file changed after it was applied.
+	Modified bool
+}
+
+// MigrateOptions tunes Up and Down. With DryRun the SQL that would run is
+// written to Out and the database is left untouched.
+type MigrateOptions struct {
+	DryRun bool
+	Out    io.Writer
+}
+
+func (o MigrateOptions) out() io.Writer {
+	if o.Out == nil {
+		return io.Discard
+	}
+	return o.Out
+}
+
+// Migrator applies the SQL migrations embedded in the binary and records
+// them in schema_migrations. Each migration runs in its own transaction
+// together with its schema_migrations row.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: sqlDB, migrations: migrations}, nil
+}
+
+// loadMigrations reads every migrations/*.sql file in version order.
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(files, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+			sum := sha256.Sum256(body)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d %s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every migration that has not run yet, oldest first, and
+// returns them.
+//
+// A database that has tables but no schema_migrations was created by GORM
+// AutoMigrate. It has at least the baseline schema of version 1, which is
+// recorded without running it; version 2 then adds whatever is missing.
+func (m *Migrator) Up(ctx context.Context, opts MigrateOptions) ([]Migration, error) {
+	var applied []Migration
+	err := m.withConn(ctx, opts, func(conn *sql.Conn, done map[int64]appliedMigration) error {
+		if len(done) == 0 && len(m.migrations) > 0 {
+			legacy, err := tableExists(ctx, conn, baselineTable)
+			if err != nil {
+				return err
+			}
+			if legacy {
+				first := m.migrations[0]
+				if opts.DryRun {
+					fmt.Fprintf(opts.out(), "-- existing schema would be recorded as %04d %s\n", first.Version, first.Name)
+				} else {
+					log.Printf("Existing schema recorded as migration %04d %s", first.Version, first.Name)
+					if err := record(ctx, conn, first); err != nil {
+						return err
+					}
+				}
+				done[first.Version] = appliedMigration{}
+			}
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, opts, migration, migration.Up, func(tx *sql.Tx) error {
+				return record(ctx, tx, migration)
+			}); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns them.
+func (m *Migrator) Down(ctx context.Context, steps int, opts MigrateOptions) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withConn(ctx, opts, func(conn *sql.Conn, done map[int64]appliedMigration) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %04d %s has no down file", migration.Version, migration.Name)
+			}
+			if err := apply(ctx, conn, opts, migration, migration.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			}); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withConn(ctx, MigrateOptions{DryRun: true, Out: io.Discard}, func(_ *sql.Conn, done map[int64]appliedMigration) error {
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if row, ok := done[migration.Version]; ok {
+				appliedAt := row.appliedAt
+				status.AppliedAt = &appliedAt
+				status.Modified = row.checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending lists the migrations that have not been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// withConn runs fn on one connection holding the advisory lock, with the
+// applied migrations read under the lock. A dry run, and Status, take no
+// lock and never create schema_migrations.
+func (m *Migrator) withConn(ctx context.Context, opts MigrateOptions, fn func(*sql.Conn, map[int64]appliedMigration) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if !opts.DryRun {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return fmt.Errorf("failed to take the migration lock: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+				log.Printf("Failed to release the migration lock: %v", err)
+			}
+		}()
+
+		if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			checksum text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`); err != nil {
+			return fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+	}
+
+	done, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	done := make(map[int64]appliedMigration)
+	exists, err := tableExists(ctx, conn, "schema_migrations")
+	if err != nil || !exists {
+		return done, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var row appliedMigration
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = row
+	}
+	return done, rows.Err()
+}
+
+// apply runs one migration's SQL and its bookkeeping in a transaction, or
+// only prints the SQL in a dry run.
+func apply(ctx context.Context, conn *sql.Conn, opts MigrateOptions, migration Migration, statements string, bookkeeping func(*sql.Tx) error) error {
+	if opts.DryRun {
+		fmt.Fprintf(opts.out(), "-- %04d %s\n%s\n", migration.Version, migration.Name, statements)
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return fmt.Errorf("migration %04d %s failed: %w", migration.Version, migration.Name, err)
+	}
+	if err := bookkeeping(tx); err != nil {
+		return fmt.Errorf("failed to record migration %04d %s: %w", migration.Version, migration.Name, err)
+	}
+	return tx.Commit()
+}
+
+// execer is a *sql.Conn or a *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func record(ctx context.Context, db execer, migration Migration) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+		migration.Version, migration.Name, migration.Checksum)
+	return err
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var exists bool
+	err := conn.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"cakestore/internal/domain/entity"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func TestLoadMigrations_OrdersByVersion(t *testing.T) {
+	files := fstest.MapFS{
+		"migrations/0010_add_tips.up.sql":           {Data: []byte("ALTER TABLE orders ADD COLUMN tip decimal;")},
+		"migrations/0010_add_tips.down.sql":         {Data: []byte("ALTER TABLE orders DROP COLUMN tip;")},
+		"migrations/0002_reservation_ranges.up.sql": {Data: []byte("SELECT 1;")},
+	}
+
+	migrations, err := loadMigrations(files)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(2), migrations[0].Version)
+	assert.Equal(t, "reservation_ranges", migrations[0].Name)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, int64(10), migrations[1].Version)
+	assert.Equal(t, "ALTER TABLE orders DROP COLUMN tip;", migrations[1].Down)
+	assert.Len(t, migrations[1].Checksum, 64)
+}
+
+func TestLoadMigrations_RejectsBadFiles(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{"migrations/add_tips.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "add_tips.sql")
+
+	_, err = loadMigrations(fstest.MapFS{"migrations/0003_add_tips.down.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "no up file")
+
+	_, err = loadMigrations(fstest.MapFS{
+		"migrations/0003_add_tips.up.sql":  {Data: []byte("SELECT 1;")},
+		"migrations/0003_add_notes.up.sql": {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "named both")
+}
+
+func TestEmbeddedMigrations_CoverEveryEntity(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for _, migration := range migrations {
+		assert.NotEmpty(t, migration.Down, "migration %04d %s has no down file", migration.Version, migration.Name)
+	}
+
+	models := []interface{}{
+		&entity.Menu{}, &entity.Customer{}, &entity.Role{}, &entity.RolePermission{},
+		&entity.APIKey{}, &entity.APIKeyPermission{}, &entity.RefreshToken{}, &entity.AccountToken{},
+		&entity.TwoFactor{}, &entity.RecoveryCode{}, &entity.Order{}, &entity.OrderItem{},
+		&entity.Payment{}, &entity.Cart{}, &entity.WishList{}, &entity.Reservation{},
+		&entity.ReservationDeposit{}, &entity.Inventory{}, &entity.Table{}, &entity.TableSession{},
+		&entity.CashierShift{}, &entity.ShiftTransaction{}, &entity.Receipt{},
+	}
+	var schemaSQL strings.Builder
+	for _, migration := range migrations {
+		schemaSQL.WriteString(migration.Up)
+	}
+	for _, model := range models {
+		parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+		created := strings.Contains(schemaSQL.String(), "CREATE TABLE "+parsed.Table+" (") ||
+			strings.Contains(schemaSQL.String(), "CREATE TABLE IF NOT EXISTS "+parsed.Table+" (")
+		assert.True(t, created, "no migration creates table %s", parsed.Table)
+	}
+}
+
+// Legacy databases are recorded at version 1 without running it, so it must
+// stay the schema of the ten tables AutoMigrate created at the time, and
+// version 2 must only add what is missing.
+func TestEmbeddedMigrations_BaselineThenIdempotentUpgrade(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(migrations), 2)
+
+	baseline, upgrade := migrations[0], migrations[1]
+	assert.Equal(t, int64(1), baseline.Version)
+	assert.Equal(t, 10, strings.Count(baseline.Up, "CREATE TABLE "))
+	assert.NotContains(t, baseline.Up, "email_verified_at")
+
+	assert.Equal(t, int64(2), upgrade.Version)
+	assert.Equal(t, strings.Count(upgrade.Up, "CREATE TABLE "), strings.Count(upgrade.Up, "CREATE TABLE IF NOT EXISTS "))
+	// email_verified_at is added, with its backfill, only when it is missing
+	assert.Equal(t, strings.Count(upgrade.Up, "ADD COLUMN "), strings.Count(upgrade.Up, "ADD COLUMN IF NOT EXISTS ")+1)
+	assert.Contains(t, upgrade.Up, "UPDATE customers SET email_verified_at = created_at")
+}
@@ -4,21 +4,36 @@ import (
 	configs "cakestore/internal/config"
 	"fmt"
 	"log"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/plugin/opentelemetry/tracing"
 )
 
 func ConnectPostgres(cfg *configs.Config) *gorm.DB {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort, cfg.DBSSLMode)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		log.Println(dsn)
-		log.Fatalf("❌ Failed to connect to database: %v", err)
+		log.Fatalf("❌ Failed to connect to database %s on %s:%s as %s: %v", cfg.DBName, cfg.DBHost, cfg.DBPort, cfg.DBUser, err)
 	}
 
+	// One span per query, without the bound values so passwords and tokens
+	// stay out of the traces
+	if err := db.Use(tracing.NewPlugin(tracing.WithoutMetrics(), tracing.WithoutQueryVariables())); err != nil {
+		log.Fatalf("❌ Failed to trace database queries: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("❌ Failed to configure the database pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.DB_MAX_OPEN_CONNS)
+	sqlDB.SetMaxIdleConns(cfg.DB_MAX_IDLE_CONNS)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.DB_CONN_MAX_LIFETIME_MINUTES) * time.Minute)
+
 	log.Println("✅ Connected to PostgreSQL database")
 	return db
 }
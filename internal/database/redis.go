@@ -7,6 +7,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -15,22 +16,42 @@ type RedisCache interface {
 	Get(ctx context.Context, key string, dest interface{}) error
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Delete(ctx context.Context, key string) error
+	// Increment atomically adds one to a counter and returns the new value.
+	// The TTL starts with the first increment, so counters reset after a
+	// fixed window instead of living as long as they keep being hit.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// SetWithTags stores the value like Set and records the key under each
+	// tag, so InvalidateTags can drop a whole family of keys at once, such as
+	// every page of a list. Tagged keys must have an expiration.
+	SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error
+	// InvalidateTags deletes every key stored under any of the tags.
+	InvalidateTags(ctx context.Context, tags ...string) error
 }
 
 type RedisCacheService struct {
 	client *redis.Client
 }
 
-func NewRedisCacheService(ctx context.Context, redisURI string) *RedisCacheService {
-	opt, _ := redis.ParseURL(redisURI)
+// NewRedisCacheService connects to redisURI. An unreachable server is only
+// logged: go-redis reconnects on its own, and until then callers get errors
+// that the circuit breaker in NewCache turns into cache misses.
+func NewRedisCacheService(ctx context.Context, redisURI string) (*RedisCacheService, error) {
+	opt, err := redis.ParseURL(redisURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
 	rdb := redis.NewClient(opt)
+	// Commands are traced without their arguments, which hold cached values
+	if err := redisotel.InstrumentTracing(rdb, redisotel.WithDBStatement(false)); err != nil {
+		return nil, fmt.Errorf("failed to trace Redis commands: %w", err)
+	}
 
-	_, err := rdb.Ping(ctx).Result()
-	if err != nil {
-		log.Fatalf("Could not connect to Redis: %v", err)
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		log.Printf("Could not connect to Redis, continuing without cache until it is reachable: %v", err)
+	} else {
+		log.Println("Connected to Redis successfully!")
 	}
-	log.Println("Connected to Redis successfully!")
-	return &RedisCacheService{client: rdb}
+	return &RedisCacheService{client: rdb}, nil
 }
 
 // Get retrieves data from Redis and unmarshals it into dest.
@@ -38,7 +59,7 @@ func (s *RedisCacheService) Get(ctx context.Context, key string, dest interface{
 	val, err := s.client.Get(ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return fmt.Errorf("key not found in cache: %s", key)
+			return fmt.Errorf("%w: %s", ErrCacheMiss, key)
 		}
 		return fmt.Errorf("failed to get from Redis: %w", err)
 	}
@@ -59,3 +80,126 @@ func (s *RedisCacheService) Delete(ctx context.Context, key string) error {
 	log.Printf("Cache: Invalidating key: %s", key)
 	return s.client.Del(ctx, key).Err()
 }
+
+var incrementScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
+// Increment runs INCR and sets the expiry in one script, so a counter can
+// never be left behind without a TTL.
+func (s *RedisCacheService) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrementScript.Run(ctx, s.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+// tagKey names the set that holds the keys stored under a tag
+func tagKey(tag string) string {
+	return "tag:" + tag
+}
+
+// setWithTagsScript stores the value and adds its key to every tag set. A tag
+// set lives at least as long as its longest-lived key, so it cannot expire
+// while a key it should invalidate is still cached.
+var setWithTagsScript = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+for i = 2, #KEYS do
+	redis.call("SADD", KEYS[i], KEYS[1])
+	if redis.call("PTTL", KEYS[i]) < tonumber(ARGV[2]) then
+		redis.call("PEXPIRE", KEYS[i], ARGV[2])
+	end
+end
+return 1`)
+
+// SetWithTags stores the value and tags it in one script, so a key can never
+// be cached without being reachable from its tags.
+func (s *RedisCacheService) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	if expiration <= 0 {
+		return fmt.Errorf("tagged cache key %s needs an expiration", key)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value for Redis: %w", err)
+	}
+
+	keys := make([]string, 0, len(tags)+1)
+	keys = append(keys, key)
+	for _, tag := range tags {
+		keys = append(keys, tagKey(tag))
+	}
+	return setWithTagsScript.Run(ctx, s.client, keys, data, expiration.Milliseconds()).Err()
+}
+
+// InvalidateTags reads each tag set and deletes its keys. Only the members
+// that were read are removed from the set, so a key tagged concurrently
+// stays reachable for the next invalidation.
+func (s *RedisCacheService) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		log.Printf("Cache: Invalidating tag: %s", tag)
+		members, err := s.client.SMembers(ctx, tagKey(tag)).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read cache tag %s: %w", tag, err)
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		pipe := s.client.TxPipeline()
+		pipe.Del(ctx, members...)
+		pipe.SRem(ctx, tagKey(tag), toInterfaces(members)...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to invalidate cache tag %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisCacheService) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the connection pool
+func (s *RedisCacheService) Close() error {
+	return s.client.Close()
+}
+
+// flushBatch is how many keys Flush asks SCAN for at a time.
+const flushBatch = 500
+
+// Flush walks the keyspace with SCAN rather than FLUSHDB, so keys kept by
+// keepPrefixes survive and the server is never blocked on one large call.
+func (s *RedisCacheService) Flush(ctx context.Context, keepPrefixes ...string) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, "*", flushBatch).Result()
+		if err != nil {
+			return fmt.Errorf("failed to scan cache keys: %w", err)
+		}
+
+		doomed := keys[:0]
+		for _, key := range keys {
+			if !hasAnyPrefix(key, keepPrefixes) {
+				doomed = append(doomed, key)
+			}
+		}
+		if len(doomed) > 0 {
+			if err := s.client.Del(ctx, doomed...).Err(); err != nil {
+				return fmt.Errorf("failed to delete cache keys: %w", err)
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
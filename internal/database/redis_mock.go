@@ -25,3 +25,18 @@ func (m *MockRedisCacheService) Delete(ctx context.Context, key string) error {
 	args := m.Called(ctx, key)
 	return args.Error(0)
 }
+
+func (m *MockRedisCacheService) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRedisCacheService) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	args := m.Called(ctx, key, value, expiration, tags)
+	return args.Error(0)
+}
+
+func (m *MockRedisCacheService) InvalidateTags(ctx context.Context, tags ...string) error {
+	args := m.Called(ctx, tags)
+	return args.Error(0)
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type APIKeyController struct {
+	apiKeyUseCase usecase.APIKeyUseCase
+	logger        *logrus.Logger
+	validator     *validator.Validate
+}
+
+func NewAPIKeyController(apiKeyUseCase usecase.APIKeyUseCase, logger *logrus.Logger) *APIKeyController {
+	return &APIKeyController{
+		apiKeyUseCase: apiKeyUseCase,
+		logger:        logger,
+		validator:     validator.New(),
+	}
+}
+
+func (c *APIKeyController) GetAllAPIKeys(ctx *fiber.Ctx) error {
+	keys, err := c.apiKeyUseCase.GetAll(ctx.UserContext())
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to get API keys")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, keys, "API keys fetched successfully", nil)
+}
+
+// CreateAPIKey returns the key once; only its hash is stored
+func (c *APIKeyController) CreateAPIKey(ctx *fiber.Ctx) error {
+	var request model.CreateAPIKeyRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	createdBy, _ := ctx.Locals(constants.ClaimsKeyID).(int64)
+	key, err := c.apiKeyUseCase.Create(ctx.UserContext(), &request, createdBy)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to create API key")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, key, "API key created successfully", nil)
+}
+
+func (c *APIKeyController) RevokeAPIKey(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	if err := c.apiKeyUseCase.Revoke(ctx.UserContext(), id); err != nil {
+		return c.writeError(ctx, err, "Failed to revoke API key")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "API key revoked successfully", nil)
+}
+
+func (c *APIKeyController) writeError(ctx *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "API key not found")
+	case errors.Is(err, constants.ErrUnknownPermission),
+		errors.Is(err, constants.ErrPermissionNotForAPIKeys):
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	c.logger.Error(message+": ", err)
+	return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, message)
+}
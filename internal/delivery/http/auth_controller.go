@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"cakestore/internal/auth"
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type AuthController struct {
+	sessionUseCase usecase.SessionUseCase
+	accountUseCase usecase.AccountUseCase
+	loginAttempts  usecase.LoginAttemptUseCase
+	keys           *auth.KeySet
+	logger         *logrus.Logger
+	validator      *validator.Validate
+}
+
+func NewAuthController(sessionUseCase usecase.SessionUseCase, accountUseCase usecase.AccountUseCase, loginAttempts usecase.LoginAttemptUseCase, keys *auth.KeySet, logger *logrus.Logger) *AuthController {
+	return &AuthController{
+		sessionUseCase: sessionUseCase,
+		accountUseCase: accountUseCase,
+		loginAttempts:  loginAttempts,
+		keys:           keys,
+		logger:         logger,
+		validator:      validator.New(),
+	}
+}
+
+func (c *AuthController) Refresh(ctx *fiber.Ctx) error {
+	var request model.RefreshTokenRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := c.sessionUseCase.Refresh(ctx.UserContext(), request.RefreshToken)
+	if err != nil {
+		if errors.Is(err, constants.ErrInvalidRefreshToken) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, err.Error())
+		}
+		c.logger.Error("Failed to refresh token: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to refresh token")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, tokens, "Token refreshed successfully", nil)
+}
+
+func (c *AuthController) Logout(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	tokenID, _ := ctx.Locals(constants.ClaimsKeyTokenID).(string)
+	expiresAt, _ := ctx.Locals(constants.ClaimsKeyTokenExpiry).(time.Time)
+
+	// The body is optional; without it only the presented access token is revoked
+	var request model.LogoutRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			c.logger.Error("Failed to parse body: ", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	if err := c.sessionUseCase.Logout(ctx.UserContext(), customerID, tokenID, expiresAt, &request); err != nil {
+		c.logger.Error("Failed to logout: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to logout")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Logged out successfully", nil)
+}
+
+// JWKS publishes the public signing keys in the standard JWK Set format, not
+// wrapped in the usual response envelope, so off-the-shelf JWT libraries can
+// consume it directly. It is empty while tokens are signed with HS256.
+func (c *AuthController) JWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.Status(fiber.StatusOK).JSON(c.keys.JWKS())
+}
+
+func (c *AuthController) ForgotPassword(ctx *fiber.Ctx) error {
+	var request model.ForgotPasswordRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.accountUseCase.ForgotPassword(ctx.UserContext(), &request); err != nil {
+		c.logger.Error("Failed to send password reset email: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send password reset email")
+	}
+
+	// Same answer whether or not the address has an account
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "If the email is registered, a reset link has been sent", nil)
+}
+
+func (c *AuthController) ResetPassword(ctx *fiber.Ctx) error {
+	var request model.ResetPasswordRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.accountUseCase.ResetPassword(ctx.UserContext(), &request); err != nil {
+		if errors.Is(err, constants.ErrInvalidAccountToken) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		c.logger.Error("Failed to reset password: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to reset password")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Password reset, please log in again", nil)
+}
+
+// AcceptInvite sets the password of a staff account created by an admin
+func (c *AuthController) AcceptInvite(ctx *fiber.Ctx) error {
+	var request model.AcceptInviteRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.accountUseCase.AcceptInvite(ctx.UserContext(), &request); err != nil {
+		if errors.Is(err, constants.ErrInvalidAccountToken) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		c.logger.Error("Failed to accept invite: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to accept invite")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Password set, you can now log in", nil)
+}
+
+func (c *AuthController) VerifyEmail(ctx *fiber.Ctx) error {
+	token := ctx.Query("token")
+	if token == "" {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Missing token")
+	}
+
+	if err := c.accountUseCase.VerifyEmail(ctx.UserContext(), token); err != nil {
+		if errors.Is(err, constants.ErrInvalidAccountToken) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		c.logger.Error("Failed to verify email: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to verify email")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Email verified successfully", nil)
+}
+
+func (c *AuthController) ResendVerification(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if err := c.accountUseCase.ResendVerification(ctx.UserContext(), customerID); err != nil {
+		if errors.Is(err, constants.ErrEmailAlreadyVerified) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+		}
+		c.logger.Error("Failed to send verification email: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send verification email")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Verification email sent", nil)
+}
+
+func (c *AuthController) UnlockLogin(ctx *fiber.Ctx) error {
+	var request model.UnlockLoginRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if request.Email == "" && request.IP == "" {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Email or IP is required")
+	}
+
+	if err := c.loginAttempts.Unlock(ctx.UserContext(), &request); err != nil {
+		c.logger.Error("Failed to unlock login: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to unlock login")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Login unlocked", nil)
+}
@@ -24,7 +24,10 @@ func NewCartController(cartUseCase usecase.CartUseCase, logger *logrus.Logger) *
 }
 
 func (c *CartController) AddCart(ctx *fiber.Ctx) error {
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 	var req model.AddCart
 
 	if err := ctx.BodyParser(&req); err != nil {
@@ -32,7 +35,7 @@ func (c *CartController) AddCart(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	err := c.cartUseCase.CreateCart(customerID, &req)
+	err := c.cartUseCase.CreateCart(ctx.UserContext(), customerID, &req)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to create cart: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -49,7 +52,7 @@ func (c *CartController) GetCartByID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	cart, err := c.cartUseCase.GetCartByID(cartID)
+	cart, err := c.cartUseCase.GetCartByID(ctx.UserContext(), cartID)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to fetch cart: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, err.Error())
@@ -59,7 +62,10 @@ func (c *CartController) GetCartByID(ctx *fiber.Ctx) error {
 }
 
 func (c *CartController) GetCartByCustomerID(ctx *fiber.Ctx) error {
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
 	params := new(model.PaginationQuery)
 	if err := ctx.QueryParser(params); err != nil {
@@ -67,7 +73,7 @@ func (c *CartController) GetCartByCustomerID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	data, meta, err := c.cartUseCase.GetCartByCustomerID(customerID, params)
+	data, meta, err := c.cartUseCase.GetCartByCustomerID(ctx.UserContext(), customerID, params)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to fetch carts: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -77,14 +83,17 @@ func (c *CartController) GetCartByCustomerID(ctx *fiber.Ctx) error {
 }
 
 func (c *CartController) RemoveCart(ctx *fiber.Ctx) error {
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 	cartID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to parse cart ID: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	err = c.cartUseCase.RemoveCart(customerID, cartID)
+	err = c.cartUseCase.RemoveCart(ctx.UserContext(), customerID, cartID)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to remove cart: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -94,9 +103,12 @@ func (c *CartController) RemoveCart(ctx *fiber.Ctx) error {
 }
 
 func (c *CartController) ClearCart(ctx *fiber.Ctx) error {
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
-	err := c.cartUseCase.ClearCart(customerID)
+	err := c.cartUseCase.ClearCart(ctx.UserContext(), customerID)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to clear cart: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -106,7 +118,10 @@ func (c *CartController) ClearCart(ctx *fiber.Ctx) error {
 }
 
 func (c *CartController) BulkDeleteCart(ctx *fiber.Ctx) error {
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
 	var req struct {
 		CartIDs []int64 `json:"cart_ids"`
@@ -117,7 +132,7 @@ func (c *CartController) BulkDeleteCart(ctx *fiber.Ctx) error {
 	}
 
 	c.logger.Info("Cart IDs to delete: ", req.CartIDs)
-	err := c.cartUseCase.BulkDeleteCart(customerID, req.CartIDs)
+	err := c.cartUseCase.BulkDeleteCart(ctx.UserContext(), customerID, req.CartIDs)
 	if err != nil {
 		c.logger.Errorf("❌ Failed to bulk delete carts: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -5,6 +5,9 @@ import (
 	"cakestore/internal/domain/model"
 	"cakestore/internal/usecase"
 	"cakestore/utils"
+	"errors"
+	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
@@ -13,16 +16,20 @@ import (
 )
 
 type CustomerController struct {
-	customerUseCase usecase.CustomerUseCase
-	logger          *logrus.Logger
-	validator       *validator.Validate
+	customerUseCase     usecase.CustomerUseCase
+	customerDataUseCase usecase.CustomerDataUseCase
+	sessionUseCase      usecase.SessionUseCase
+	logger              *logrus.Logger
+	validator           *validator.Validate
 }
 
-func NewCustomerController(customerUseCase usecase.CustomerUseCase, logger *logrus.Logger) *CustomerController {
+func NewCustomerController(customerUseCase usecase.CustomerUseCase, customerDataUseCase usecase.CustomerDataUseCase, sessionUseCase usecase.SessionUseCase, logger *logrus.Logger) *CustomerController {
 	return &CustomerController{
-		customerUseCase: customerUseCase,
-		logger:          logger,
-		validator:       validator.New(),
+		customerUseCase:     customerUseCase,
+		customerDataUseCase: customerDataUseCase,
+		sessionUseCase:      sessionUseCase,
+		logger:              logger,
+		validator:           validator.New(),
 	}
 }
 
@@ -38,28 +45,28 @@ func (c *CustomerController) Register(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	// get header role
-	role := ctx.Get("x-app-role")
-
 	if err := c.validator.Struct(request); err != nil {
 		c.logger.Error("Validation failed: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	customer, err := c.customerUseCase.Register(&request, role)
+	customer, err := c.customerUseCase.Register(ctx.UserContext(), &request)
 	if err != nil {
+		if errors.Is(err, constants.ErrEmailAlreadyRegistered) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+		}
 		c.logger.Error("Failed to register customer: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(customer.ID, customer.Email, customer.Name, customer.Role)
+	// Start a session right away so the client does not have to log in again
+	tokens, err := c.sessionUseCase.Issue(ctx.UserContext(), customer)
 	if err != nil {
 		c.logger.Error("Failed to generate token: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to generate token")
 	}
 
-	return utils.WriteResponse(ctx, fiber.StatusCreated, token, "Customer registered successfully", nil)
+	return utils.WriteResponse(ctx, fiber.StatusCreated, tokens, "Customer registered successfully", nil)
 }
 
 func (c *CustomerController) Login(ctx *fiber.Ctx) error {
@@ -74,20 +81,45 @@ func (c *CustomerController) Login(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	token, err := c.customerUseCase.Login(&request)
+	result, err := c.customerUseCase.Login(ctx.UserContext(), &request, ctx.IP())
 	if err != nil {
+		var blocked *usecase.LoginBlockedError
+		switch {
+		case errors.As(err, &blocked):
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+			return utils.WriteErrorResponse(ctx, fiber.StatusTooManyRequests, blocked.Error())
+		case errors.Is(err, constants.ErrInvalidCredentials):
+			return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, err.Error())
+		}
 		c.logger.Error("Failed to login: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to login")
 	}
 
-	return utils.WriteResponse(ctx, fiber.StatusOK, token, "Login successful", nil)
+	if result.Challenge != nil {
+		return utils.WriteResponse(ctx, fiber.StatusOK, result.Challenge, "Two-factor authentication required", nil)
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, result.Tokens, "Login successful", nil)
 }
 
+// UpdateProfile serves PUT /customers/me and the older PUT /customers/:id,
+// which only accepts the caller's own ID
 func (c *CustomerController) UpdateProfile(ctx *fiber.Ctx) error {
-	customerID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
-	if err != nil {
-		c.logger.Error("Failed to parse customer ID: ", err)
-		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid customer ID")
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	if param := ctx.Params("id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			c.logger.Error("Failed to parse customer ID: ", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid customer ID")
+		}
+		if id != customerID {
+			return utils.WriteErrorResponse(ctx, fiber.StatusForbidden, "You can only update your own profile")
+		}
 	}
 
 	var request model.UpdateUserRequest
@@ -101,7 +133,7 @@ func (c *CustomerController) UpdateProfile(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := c.customerUseCase.UpdateCustomer(customerID, &request); err != nil {
+	if err := c.customerUseCase.UpdateCustomer(ctx.UserContext(), customerID, &request); err != nil {
 		c.logger.Error("Failed to update profile: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update profile")
 	}
@@ -109,6 +141,86 @@ func (c *CustomerController) UpdateProfile(ctx *fiber.Ctx) error {
 	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Profile updated successfully", nil)
 }
 
+// ExportData returns every piece of personal data held about the caller as a
+// JSON download
+func (c *CustomerController) ExportData(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	export, err := c.customerDataUseCase.Export(ctx.UserContext(), customerID)
+	if err != nil {
+		c.logger.Error("Failed to export customer data: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to export data")
+	}
+
+	ctx.Attachment(fmt.Sprintf("cakestore-data-%d.json", customerID))
+	return ctx.Status(fiber.StatusOK).JSON(export)
+}
+
+func (c *CustomerController) DeleteAccount(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var request model.DeleteAccountRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.customerDataUseCase.DeleteAccount(ctx.UserContext(), customerID, &request); err != nil {
+		switch {
+		case errors.Is(err, constants.ErrInvalidPassword):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Password is incorrect")
+		case errors.Is(err, constants.ErrStaffAccountDeletion):
+			return utils.WriteErrorResponse(ctx, fiber.StatusForbidden, err.Error())
+		}
+		c.logger.Error("Failed to delete account: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete account")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Account deleted", nil)
+}
+
+func (c *CustomerController) ChangePassword(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var request model.ChangePasswordRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.customerUseCase.ChangePassword(ctx.UserContext(), customerID, &request); err != nil {
+		if errors.Is(err, constants.ErrInvalidPassword) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Current password is incorrect")
+		}
+		c.logger.Error("Failed to change password: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to change password")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Password changed, please log in again", nil)
+}
+
 func (c *CustomerController) GetCustomerByID(ctx *fiber.Ctx) error {
 	customerIDStr := ctx.Locals(constants.ClaimsKeyID)
 
@@ -119,7 +231,7 @@ func (c *CustomerController) GetCustomerByID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid customer ID")
 	}
 
-	customer, err := c.customerUseCase.GetCustomerByID(customerID)
+	customer, err := c.customerUseCase.GetCustomerByID(ctx.UserContext(), customerID)
 	if err != nil {
 		c.logger.Error("Failed to get customer: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get customer")
@@ -129,7 +241,7 @@ func (c *CustomerController) GetCustomerByID(ctx *fiber.Ctx) error {
 }
 
 func (c *CustomerController) GetEmployees(ctx *fiber.Ctx) error {
-	employees, err := c.customerUseCase.GetEmployees()
+	employees, err := c.customerUseCase.GetEmployees(ctx.UserContext())
 	if err != nil {
 		c.logger.Error("Failed to get employees: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get employees")
@@ -149,7 +261,7 @@ func (c *CustomerController) GetEmployeeByID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID")
 	}
 
-	employee, err := c.customerUseCase.GetEmployeeByID(employeeId)
+	employee, err := c.customerUseCase.GetEmployeeByID(ctx.UserContext(), employeeId)
 	if err != nil {
 		c.logger.Error("Failed to get employee: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
@@ -158,6 +270,57 @@ func (c *CustomerController) GetEmployeeByID(ctx *fiber.Ctx) error {
 	return utils.WriteResponse(ctx, fiber.StatusOK, model.ToEmployeeResponse(employee), "Employee fetched successfully", nil)
 }
 
+// CreateEmployee creates a staff account and emails the employee an invite
+// link to choose their password
+func (c *CustomerController) CreateEmployee(ctx *fiber.Ctx) error {
+	var request model.CreateEmployeeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	employee, err := c.customerUseCase.CreateEmployee(ctx.UserContext(), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, constants.ErrUnknownRole),
+			errors.Is(err, constants.ErrInvalidEmployeeRole):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		case errors.Is(err, constants.ErrEmailAlreadyRegistered):
+			return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+		}
+		c.logger.Error("Failed to create employee: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create employee")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, model.ToEmployeeResponse(employee), "Employee created, an invite has been emailed", nil)
+}
+
+func (c *CustomerController) InviteEmployee(ctx *fiber.Ctx) error {
+	employeeID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Error("Failed to parse employee ID: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID")
+	}
+
+	if err := c.customerUseCase.InviteEmployee(ctx.UserContext(), employeeID); err != nil {
+		switch {
+		case errors.Is(err, constants.ErrNotFound):
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Employee not found")
+		case errors.Is(err, constants.ErrInviteAlreadyAccepted):
+			return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+		}
+		c.logger.Error("Failed to send invite: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to send invite")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Invite sent", nil)
+}
+
 func (c *CustomerController) UpdateEmployee(ctx *fiber.Ctx) error {
 	employeeID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
 	if err != nil {
@@ -179,7 +342,10 @@ func (c *CustomerController) UpdateEmployee(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := c.customerUseCase.UpdateEmployee(employeeID, &request, role); err != nil {
+	if err := c.customerUseCase.UpdateEmployee(ctx.UserContext(), employeeID, &request, role); err != nil {
+		if errors.Is(err, constants.ErrUnknownRole) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
 		c.logger.Error("Failed to update employee: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update employee")
 	}
@@ -194,7 +360,7 @@ func (c *CustomerController) DeleteEmployee(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid employee ID")
 	}
 
-	if err := c.customerUseCase.DeleteEmployee(employeeID); err != nil {
+	if err := c.customerUseCase.DeleteEmployee(ctx.UserContext(), employeeID); err != nil {
 		c.logger.Error("Failed to delete employee: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete employee")
 	}
@@ -1,10 +1,12 @@
 package controller
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
 	"cakestore/internal/usecase"
 	"cakestore/utils"
+	"errors"
 	"strconv"
 
 	"github.com/go-playground/validator/v10"
@@ -30,7 +32,10 @@ func NewOrderController(orderUseCase usecase.OrderUseCase, paymentUseCase usecas
 
 func (c *OrderController) CreateOrder(ctx *fiber.Ctx) error {
 	// Get customer ID from JWT token
-	customerID := ctx.Locals("customer_id").(int64)
+	customerID, ok := ctx.Locals("customer_id").(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
 	var request model.CreateOrderRequest
 	if err := ctx.BodyParser(&request); err != nil {
@@ -43,37 +48,33 @@ func (c *OrderController) CreateOrder(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	order, err := c.orderUseCase.CreateOrder(customerID, &request)
+	order, err := c.orderUseCase.CreateOrder(ctx.UserContext(), customerID, &request)
 	if err != nil {
+		if errors.Is(err, constants.ErrEmailNotVerified) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusForbidden, "Please verify your email address before ordering")
+		}
 		c.logger.Error("Failed to create order: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create order")
 	}
 
-	_, err = c.orderUseCase.GetOrderByID(order.ID)
+	_, err = c.orderUseCase.GetOrderByID(ctx.UserContext(), order.ID)
 	if err != nil {
 		c.logger.Error("Failed to get order details: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get order details")
 	}
 
 	// make payment link from midtrans
-	paymentURL, err := c.paymentUseCase.CreatePaymentURL(order)
+	paymentURL, err := c.paymentUseCase.CreatePaymentURL(ctx.UserContext(), order)
 	if err != nil {
 		c.logger.Error("Failed to create payment URL: ", err.Error())
 		// if error delete previous order
-		if err := c.orderUseCase.DeleteOrder(order.ID); err != nil {
+		if err := c.orderUseCase.DeleteOrder(ctx.UserContext(), order.ID); err != nil {
 			c.logger.Error("Failed to delete order: ", err)
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete order")
 		}
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create payment URL")
 	}
 
-	// FIXME force update order status due to midtrans webhook delay
-	orderIDStr := strconv.Itoa(int(order.ID))
-	if err := c.orderUseCase.UpdateOrderStatus(orderIDStr, string(entity.OrderStatusPaid)); err != nil {
-		c.logger.Error("Failed to update order status: ", err)
-		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
-	}
-
 	return utils.WriteResponse(ctx, fiber.StatusCreated, paymentURL, "Order created successfully", nil)
 }
 
@@ -84,7 +85,7 @@ func (c *OrderController) GetOrderByID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid order ID")
 	}
 
-	order, err := c.orderUseCase.GetOrderByID(orderID)
+	order, err := c.orderUseCase.GetOrderByID(ctx.UserContext(), orderID)
 	if err != nil {
 		c.logger.Error("Failed to get order: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get order")
@@ -95,9 +96,12 @@ func (c *OrderController) GetOrderByID(ctx *fiber.Ctx) error {
 
 func (c *OrderController) GetCustomerOrders(ctx *fiber.Ctx) error {
 	// Get customer ID from JWT token
-	customerID := ctx.Locals("customer_id").(int64)
+	customerID, ok := ctx.Locals("customer_id").(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
-	orders, err := c.orderUseCase.GetCustomerOrders(customerID)
+	orders, err := c.orderUseCase.GetCustomerOrders(ctx.UserContext(), customerID)
 	if err != nil {
 		c.logger.Error("Failed to get customer orders: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get customer orders")
@@ -115,7 +119,7 @@ func (c *OrderController) GetAllOrders(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid pagination query")
 	}
 
-	orders, meta, err := c.orderUseCase.GetAllOrders(&params)
+	orders, meta, err := c.orderUseCase.GetAllOrders(ctx.UserContext(), &params)
 	if err != nil {
 		c.logger.Error("Failed to get all orders: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get all orders")
@@ -142,7 +146,7 @@ func (c *OrderController) UpdateFoodStatus(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := c.orderUseCase.UpdateFoodStatus(orderID, entity.FoodStatus(req.FoodStatus)); err != nil {
+	if err := c.orderUseCase.UpdateFoodStatus(ctx.UserContext(), orderID, entity.FoodStatus(req.FoodStatus)); err != nil {
 		c.logger.Error("Failed to update food status: ", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update food status")
 	}
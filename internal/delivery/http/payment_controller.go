@@ -4,13 +4,17 @@ import (
 	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
 	"cakestore/internal/usecase"
 	"cakestore/utils"
+	"context"
 	"crypto/sha512"
 	"encoding/hex"
+	"errors"
 	"strconv"
 	"strings"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
@@ -18,6 +22,8 @@ import (
 type PaymentController interface {
 	GetTransactionStatus(ctx *fiber.Ctx) error
 	GetPaymentURL(ctx *fiber.Ctx) error
+	SplitPayment(ctx *fiber.Ctx) error
+	GetOrderPayments(ctx *fiber.Ctx) error
 }
 
 type PaymentControllerImpl struct {
@@ -25,21 +31,32 @@ type PaymentControllerImpl struct {
 	midtransServerKey string
 	orderUseCase      usecase.OrderUseCase
 	paymentUseCase    usecase.PaymentUseCase
+	depositUseCase    usecase.DepositUseCase
+	roleUseCase       usecase.RoleUseCase
+	metrics           metrics.Business
+	validator         *validator.Validate
 }
 
-func NewPaymentController(logger *logrus.Logger, midtransServerKey string, orderUseCase usecase.OrderUseCase, paymentUseCase usecase.PaymentUseCase) PaymentController {
+func NewPaymentController(logger *logrus.Logger, midtransServerKey string, orderUseCase usecase.OrderUseCase, paymentUseCase usecase.PaymentUseCase, depositUseCase usecase.DepositUseCase, roleUseCase usecase.RoleUseCase, recorder metrics.Business) PaymentController {
 	return &PaymentControllerImpl{
 		logger:            logger,
 		midtransServerKey: midtransServerKey,
 		orderUseCase:      orderUseCase,
 		paymentUseCase:    paymentUseCase,
+		depositUseCase:    depositUseCase,
+		roleUseCase:       roleUseCase,
+		metrics:           recorder,
+		validator:         validator.New(),
 	}
 }
 
 func (c *PaymentControllerImpl) GetPaymentURL(ctx *fiber.Ctx) error {
 	// returns paymentURL from orderID where status is pending
 	c.logger.Trace("GetPendingTransaction called")
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
 	orderID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
 	if err != nil {
@@ -47,33 +64,90 @@ func (c *PaymentControllerImpl) GetPaymentURL(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid orderID")
 	}
 
-	order, err := c.orderUseCase.GetPendingOrder(customerID, orderID)
+	order, err := c.orderUseCase.GetPendingOrder(ctx.UserContext(), customerID, orderID)
 	if err != nil {
 		c.logger.Errorf("Failed to get order: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get order")
 	}
 
-	payment, err := c.paymentUseCase.GetPaymentByOrderID(model.ToOrderEntity(order))
+	// Split bills have one link per split, picked with ?payment_id=
+	paymentID := int64(ctx.QueryInt("payment_id"))
+	payment, err := c.paymentUseCase.GetPendingPayment(ctx.UserContext(), model.ToOrderEntity(order), paymentID)
 	if err != nil {
+		switch {
+		case errors.Is(err, constants.ErrNotFound):
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Payment not found")
+		case errors.Is(err, constants.ErrPaymentIDRequired):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
 		c.logger.Errorf("Failed to get payment: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get payment")
 	}
 
-	if payment.Status != constants.PaymentStatusPending {
-		c.logger.Errorf("Payment not found")
-		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Payment not found")
+	return utils.WriteResponse(ctx, fiber.StatusOK, payment.PaymentURL, "Success Get Payment URL", nil)
+}
+
+// SplitPayment splits the outstanding balance of an order into several payments
+func (c *PaymentControllerImpl) SplitPayment(ctx *fiber.Ctx) error {
+	order, err := c.getAccessibleOrder(ctx)
+	if err != nil {
+		return c.writeOrderAccessError(ctx, err)
 	}
 
-	return utils.WriteResponse(ctx, fiber.StatusOK, payment.PaymentURL, "Success Get Payment URL", nil)
+	var request model.SplitPaymentRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Errorf("Failed to parse body: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Errorf("Validation failed: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	summary, err := c.paymentUseCase.CreateSplitPayments(ctx.UserContext(), model.ToOrderEntity(order), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, constants.ErrInvalidSplit),
+			errors.Is(err, constants.ErrSplitExceedsOutstanding),
+			errors.Is(err, constants.ErrOrderAlreadyPaid),
+			errors.Is(err, constants.ErrOrderNotPayable):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		c.logger.Errorf("Failed to split payment: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to split payment")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, summary, "Payment split successfully", nil)
+}
+
+func (c *PaymentControllerImpl) GetOrderPayments(ctx *fiber.Ctx) error {
+	order, err := c.getAccessibleOrder(ctx)
+	if err != nil {
+		return c.writeOrderAccessError(ctx, err)
+	}
+
+	summary, err := c.paymentUseCase.GetPaymentSummary(ctx.UserContext(), model.ToOrderEntity(order))
+	if err != nil {
+		c.logger.Errorf("Failed to get payments: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get payments")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, summary, "Success Get Payments", nil)
 }
 
 func (c *PaymentControllerImpl) GetTransactionStatus(ctx *fiber.Ctx) error {
+	defer func() {
+		c.metrics.Webhook(webhookOutcome(ctx.Response().StatusCode()))
+	}()
+
 	var notif model.MidtransNotification
 	if err := ctx.BodyParser(&notif); err != nil {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
 	rawSignature := notif.OrderID + notif.StatusCode + notif.GrossAmount + c.midtransServerKey
+	gatewayOrderID := notif.OrderID
 	parts := strings.Split(notif.OrderID, "-")
 	if len(parts) >= 2 {
 		notif.OrderID = parts[1]
@@ -90,33 +164,56 @@ func (c *PaymentControllerImpl) GetTransactionStatus(ctx *fiber.Ctx) error {
 	}
 	c.logger.Info("Webhook received")
 
+	// Reservation deposits are paid through the same notification flow as orders
+	if status, ok := gatewayPaymentStatus(notif.TransactionStatus); ok {
+		handled, err := c.depositUseCase.HandleGatewayNotification(ctx.UserContext(), gatewayOrderID, status)
+		if err != nil {
+			c.logger.Errorf("Failed to handle deposit notification: %v", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update deposit status")
+		}
+		if handled {
+			return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Notification processed", nil)
+		}
+	}
+
+	// Payments created since split bills carry their own gateway order ID;
+	// older ones fall through to the one-payment-per-order handling below.
+	handled, err := c.handleSplitNotification(ctx.UserContext(), gatewayOrderID, notif.TransactionStatus)
+	if err != nil {
+		c.logger.Errorf("Failed to handle payment notification: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
+	}
+	if handled {
+		return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Notification processed", nil)
+	}
+
 	switch notif.TransactionStatus {
 	case "capture", "settlement":
-		if err := c.orderUseCase.UpdateOrderStatus(notif.OrderID, string(entity.OrderStatusPaid)); err != nil {
+		if err := c.orderUseCase.UpdateOrderStatus(ctx.UserContext(), notif.OrderID, string(entity.OrderStatusPaid)); err != nil {
 			c.logger.Errorf("Failed to update order status for settlement: %v", err)
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
 		}
-		if err := c.paymentUseCase.UpdateOrderStatus(notif.OrderID, constants.PaymentStatusSuccess); err != nil {
+		if err := c.paymentUseCase.UpdateOrderStatus(ctx.UserContext(), notif.OrderID, constants.PaymentStatusSuccess); err != nil {
 			c.logger.Info("Failed to update payment status for settelement")
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
 		}
 		return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Transaction successful", nil)
 	case "pending":
-		if err := c.orderUseCase.UpdateOrderStatus(notif.OrderID, string(entity.OrderStatusPending)); err != nil {
+		if err := c.orderUseCase.UpdateOrderStatus(ctx.UserContext(), notif.OrderID, string(entity.OrderStatusPending)); err != nil {
 			c.logger.Errorf("Failed to update order status for pending: %v", err)
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
 		}
-		if err := c.paymentUseCase.UpdateOrderStatus(notif.OrderID, constants.PaymentStatusPending); err != nil {
+		if err := c.paymentUseCase.UpdateOrderStatus(ctx.UserContext(), notif.OrderID, constants.PaymentStatusPending); err != nil {
 			c.logger.Errorf("Failed to update payment status for pending: %v", err)
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
 		}
 		return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Transaction pending", nil)
 	case "expire", "cancel":
-		if err := c.orderUseCase.UpdateOrderStatus(notif.OrderID, string(entity.OrderStatusCancelled)); err != nil {
+		if err := c.orderUseCase.UpdateOrderStatus(ctx.UserContext(), notif.OrderID, string(entity.OrderStatusCancelled)); err != nil {
 			c.logger.Errorf("Failed to update order status for expire: %v", err)
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
 		}
-		if err := c.paymentUseCase.UpdateOrderStatus(notif.OrderID, constants.PaymentStatusCancelled); err != nil {
+		if err := c.paymentUseCase.UpdateOrderStatus(ctx.UserContext(), notif.OrderID, constants.PaymentStatusCancelled); err != nil {
 			c.logger.Errorf("Failed to update payment status for expire: %v", err)
 			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update order status")
 		}
@@ -124,3 +221,142 @@ func (c *PaymentControllerImpl) GetTransactionStatus(ctx *fiber.Ctx) error {
 	}
 	return ctx.SendStatus(fiber.StatusOK)
 }
+
+// handleSplitNotification updates the single payment a notification is about and
+// only moves the order to paid (or cancelled) once the payments as a whole allow it.
+func (c *PaymentControllerImpl) handleSplitNotification(ctx context.Context, gatewayOrderID string, transactionStatus string) (bool, error) {
+	status, ok := gatewayPaymentStatus(transactionStatus)
+	if !ok {
+		return false, nil
+	}
+
+	payment, err := c.paymentUseCase.UpdateGatewayPaymentStatus(ctx, gatewayOrderID, status)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	// A replayed or out-of-order notification changed nothing
+	if payment.Status != status {
+		return true, nil
+	}
+
+	switch status {
+	case constants.PaymentStatusSuccess:
+		return true, c.markOrderPaidIfCovered(ctx, payment.OrderID)
+	case constants.PaymentStatusExpired, constants.PaymentStatusCancelled:
+		order, err := c.orderUseCase.GetOrderByID(ctx, payment.OrderID)
+		if err != nil {
+			return false, err
+		}
+		summary, err := c.paymentUseCase.GetPaymentSummary(ctx, model.ToOrderEntity(order))
+		if err != nil {
+			return false, err
+		}
+		// Another split may still be paid, so the order is only cancelled when nothing is left in flight
+		if summary.PaidAmount == 0 && summary.PendingAmount == 0 {
+			orderID := strconv.FormatInt(payment.OrderID, 10)
+			return true, c.orderUseCase.UpdateOrderStatus(ctx, orderID, string(entity.OrderStatusCancelled))
+		}
+	}
+	return true, nil
+}
+
+func (c *PaymentControllerImpl) markOrderPaidIfCovered(ctx context.Context, orderID int64) error {
+	order, err := c.orderUseCase.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	summary, err := c.paymentUseCase.GetPaymentSummary(ctx, model.ToOrderEntity(order))
+	if err != nil {
+		return err
+	}
+	if !summary.IsFullyPaid || order.Status == string(entity.OrderStatusPaid) {
+		return nil
+	}
+
+	return c.orderUseCase.UpdateOrderStatus(ctx, strconv.FormatInt(orderID, 10), string(entity.OrderStatusPaid))
+}
+
+// getAccessibleOrder loads the order in the :id param and checks the caller may see its payments.
+// Guests are limited to their table session. Everyone else sees their own orders, and any
+// order only with the order:read_all permission.
+func (c *PaymentControllerImpl) getAccessibleOrder(ctx *fiber.Ctx) (*model.OrderResponse, error) {
+	orderID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Errorf("Invalid orderID: %v", err)
+		return nil, constants.ErrInvalidOrderID
+	}
+
+	order, err := c.orderUseCase.GetOrderByID(ctx.UserContext(), orderID)
+	if err != nil {
+		c.logger.Errorf("Failed to get order: %v", err)
+		return nil, constants.ErrNotFound
+	}
+
+	var allowed bool
+	if session, ok := ctx.Locals(constants.LocalsKeyTableSession).(*entity.TableSession); ok {
+		allowed = order.SessionID != nil && *order.SessionID == session.ID
+	} else if key, ok := ctx.Locals(constants.LocalsKeyAPIKey).(*model.APIKeyPrincipal); ok {
+		// API keys own no orders
+		allowed = key.HasPermissions(constants.PermissionOrderReadAll)
+	} else {
+		customerID, _ := ctx.Locals(constants.ClaimsKeyID).(int64)
+		allowed = order.Customer.ID == customerID
+		if !allowed {
+			role, _ := ctx.Locals(constants.ClaimsKeyRole).(string)
+			allowed, err = c.roleUseCase.HasPermissions(ctx.UserContext(), role, constants.PermissionOrderReadAll)
+			if err != nil {
+				c.logger.Errorf("Failed to check permissions: %v", err)
+				return nil, err
+			}
+		}
+	}
+	if !allowed {
+		return nil, constants.ErrNotFound
+	}
+
+	return order, nil
+}
+
+// writeOrderAccessError answers a failed getAccessibleOrder
+func (c *PaymentControllerImpl) writeOrderAccessError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, constants.ErrInvalidOrderID):
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid orderID")
+	case errors.Is(err, constants.ErrNotFound):
+		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Order not found")
+	}
+	return utils.WriteErrorResponse(ctx, fiber.StatusServiceUnavailable, "Unable to check permissions, try again later")
+}
+
+// webhookOutcome names the outcome of a notification after the status it was answered with
+func webhookOutcome(status int) string {
+	switch {
+	case status == fiber.StatusUnauthorized:
+		return metrics.WebhookUnauthorized
+	case status >= fiber.StatusInternalServerError:
+		return metrics.WebhookFailed
+	case status >= fiber.StatusBadRequest:
+		return metrics.WebhookInvalid
+	default:
+		return metrics.WebhookProcessed
+	}
+}
+
+// gatewayPaymentStatus maps a Midtrans transaction_status to our payment status
+func gatewayPaymentStatus(transactionStatus string) (constants.PaymentStatus, bool) {
+	switch transactionStatus {
+	case "capture", "settlement":
+		return constants.PaymentStatusSuccess, true
+	case "pending":
+		return constants.PaymentStatusPending, true
+	case "expire":
+		return constants.PaymentStatusExpired, true
+	case "cancel":
+		return constants.PaymentStatusCancelled, true
+	}
+	return "", false
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type POSController struct {
+	posUseCase usecase.POSUseCase
+	logger     *logrus.Logger
+	validator  *validator.Validate
+}
+
+func NewPOSController(posUseCase usecase.POSUseCase, logger *logrus.Logger) *POSController {
+	return &POSController{
+		posUseCase: posUseCase,
+		logger:     logger,
+		validator:  validator.New(),
+	}
+}
+
+func (c *POSController) PayOrder(ctx *fiber.Ctx) error {
+	orderID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Errorf("Invalid orderID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid orderID")
+	}
+
+	request, err := c.parseRequest(ctx)
+	if err != nil {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	receipt, err := c.posUseCase.PayOrder(ctx.UserContext(), cashierID, orderID, request)
+	if err != nil {
+		return c.writePaymentError(ctx, err)
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, receipt, "Payment recorded successfully", nil)
+}
+
+func (c *POSController) PayTableSession(ctx *fiber.Ctx) error {
+	sessionID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Errorf("Invalid table session ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid table session ID")
+	}
+
+	request, err := c.parseRequest(ctx)
+	if err != nil {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	receipt, err := c.posUseCase.PayTableSession(ctx.UserContext(), cashierID, sessionID, request)
+	if err != nil {
+		return c.writePaymentError(ctx, err)
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, receipt, "Payment recorded successfully", nil)
+}
+
+// SettlePayment records a cash split as collected on the cashier's open shift
+// and marks the order paid once it is fully covered
+func (c *POSController) SettlePayment(ctx *fiber.Ctx) error {
+	paymentID, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		c.logger.Errorf("Invalid paymentID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid paymentID")
+	}
+
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	receipt, err := c.posUseCase.SettlePayment(ctx.UserContext(), cashierID, paymentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, constants.ErrNotFound):
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Payment not found")
+		case errors.Is(err, constants.ErrInvalidPaymentStatus):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Only pending cash payments can be settled")
+		case errors.Is(err, constants.ErrNoOpenShift):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		c.logger.Errorf("Failed to settle payment: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to settle payment")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, receipt, "Payment settled successfully", nil)
+}
+
+func (c *POSController) GetReceipt(ctx *fiber.Ctx) error {
+	receipt, err := c.posUseCase.GetReceipt(ctx.UserContext(), ctx.Params("number"))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Receipt not found")
+		}
+		c.logger.Errorf("Failed to get receipt: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get receipt")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, receipt, "Receipt fetched successfully", nil)
+}
+
+func (c *POSController) parseRequest(ctx *fiber.Ctx) (*model.POSPaymentRequest, error) {
+	var request model.POSPaymentRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Errorf("Failed to parse body: %v", err)
+		return nil, constants.ErrInvalidRequestBody
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Errorf("Validation failed: %v", err)
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+func (c *POSController) writePaymentError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Nothing to pay")
+	case errors.Is(err, constants.ErrOrderAlreadyPaid),
+		errors.Is(err, constants.ErrOrderNotPayable),
+		errors.Is(err, constants.ErrAmountExceedsOutstanding),
+		errors.Is(err, constants.ErrBalanceAwaitingSplits),
+		errors.Is(err, constants.ErrInsufficientTender),
+		errors.Is(err, constants.ErrNoOpenShift):
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.Errorf("Failed to record payment: %v", err)
+	return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to record payment")
+}
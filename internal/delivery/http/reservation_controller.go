@@ -5,9 +5,13 @@ import (
 	"cakestore/internal/domain/model"
 	"cakestore/internal/usecase"
 	"cakestore/utils"
+	"errors"
+	"html/template"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
@@ -33,7 +37,7 @@ func (c *ReservationController) AdminGetAllCustomerReservations(ctx *fiber.Ctx)
 	params.Page = int64(page)
 	params.Limit = int64(perPage)
 
-	reservations, err := c.useCase.AdminGetAllCustomerReservations(params)
+	reservations, err := c.useCase.AdminGetAllCustomerReservations(ctx.UserContext(), params)
 	if err!= nil {
 		c.logger.Errorf("Error getting reservations: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get reservations")
@@ -42,7 +46,10 @@ func (c *ReservationController) AdminGetAllCustomerReservations(ctx *fiber.Ctx)
 }
 
 func (c *ReservationController) CreateReservation(ctx *fiber.Ctx) error {
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
 	var request model.CreateReservationRequest
 	if err := ctx.BodyParser(&request); err != nil {
@@ -50,8 +57,11 @@ func (c *ReservationController) CreateReservation(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	reservation, err := c.useCase.Create(uint(customerID), &request)
+	reservation, err := c.useCase.Create(ctx.UserContext(), uint(customerID), &request)
 	if err != nil {
+		if errors.Is(err, constants.ErrReservationBlocked) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusForbidden, err.Error())
+		}
 		c.logger.Errorf("Error creating reservation: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create reservation")
 	}
@@ -69,7 +79,7 @@ func (c *ReservationController) GetReservationByID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid reservation ID")
 	}
 
-	reservation, err := c.useCase.GetByID(uint(id))
+	reservation, err := c.useCase.GetByID(ctx.UserContext(), uint(id))
 	if err != nil {
 		c.logger.Errorf("Error getting reservation: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Reservation not found")
@@ -90,7 +100,10 @@ func (c *ReservationController) GetAllReservations(ctx *fiber.Ctx) error {
 	params.Page = int64(page)
 	params.Limit = int64(perPage)
 
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 	if customerID != 0 {
 		params.CustomerID = uint(customerID)
 	}
@@ -113,7 +126,7 @@ func (c *ReservationController) GetAllReservations(ctx *fiber.Ctx) error {
 		}
 	}
 
-	reservations, err := c.useCase.GetAll(params)
+	reservations, err := c.useCase.GetAll(ctx.UserContext(), params)
 	if err != nil {
 		c.logger.Errorf("Error getting reservations: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get reservations")
@@ -135,7 +148,7 @@ func (c *ReservationController) UpdateReservation(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	reservation, err := c.useCase.Update(uint(id), &request)
+	reservation, err := c.useCase.Update(ctx.UserContext(), uint(id), &request)
 	if err != nil {
 		c.logger.Errorf("Error updating reservation: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update reservation")
@@ -154,7 +167,7 @@ func (c *ReservationController) DeleteReservation(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid reservation ID")
 	}
 
-	if err := c.useCase.Delete(uint(id)); err != nil {
+	if err := c.useCase.Delete(ctx.UserContext(), uint(id)); err != nil {
 		c.logger.Errorf("Error deleting reservation: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete reservation")
 	}
@@ -163,3 +176,223 @@ func (c *ReservationController) DeleteReservation(ctx *fiber.Ctx) error {
 		Message: "Reservation deleted successfully",
 	})
 }
+
+// reservationLinkPage is what a confirm or cancel link opens. Mail scanners and
+// link previews follow links with GET, so the page only describes the change
+// and the button posts it back.
+var reservationLinkPage = template.Must(template.New("reservation-link").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Button}}<form method="post">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{.Button}}</button>
+</form>{{end}}
+</body>
+</html>`))
+
+type reservationLinkView struct {
+	Title   string
+	Message string
+	Button  string
+	Token   string
+}
+
+// ShowConfirmReservation renders the page behind the confirm link; it changes nothing
+func (c *ReservationController) ShowConfirmReservation(ctx *fiber.Ctx) error {
+	return c.showLinkPage(ctx, usecase.ReservationLinkConfirm)
+}
+
+// ShowCancelReservation renders the page behind the cancel link; it changes nothing
+func (c *ReservationController) ShowCancelReservation(ctx *fiber.Ctx) error {
+	return c.showLinkPage(ctx, usecase.ReservationLinkCancel)
+}
+
+// ConfirmReservation confirms the reservation; it is public and authenticated by the token of the confirm link
+func (c *ReservationController) ConfirmReservation(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing reservation ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	reservation, err := c.useCase.ConfirmByToken(ctx.UserContext(), uint(id), linkToken(ctx))
+	if err != nil {
+		return c.writeLinkError(ctx, err, "Failed to confirm reservation")
+	}
+
+	if isFormPost(ctx) {
+		return c.renderLinkPage(ctx, fiber.StatusOK, reservationLinkView{
+			Title:   "Reservation confirmed",
+			Message: "See you on " + formatLinkDate(reservation) + ".",
+		})
+	}
+	return utils.WriteResponse(ctx, fiber.StatusOK, reservation, "Reservation confirmed successfully", nil)
+}
+
+// CancelReservation cancels the reservation; it is public and authenticated by the token of the cancel link
+func (c *ReservationController) CancelReservation(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing reservation ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	reservation, err := c.useCase.CancelByToken(ctx.UserContext(), uint(id), linkToken(ctx))
+	if err != nil {
+		return c.writeLinkError(ctx, err, "Failed to cancel reservation")
+	}
+
+	if isFormPost(ctx) {
+		return c.renderLinkPage(ctx, fiber.StatusOK, reservationLinkView{
+			Title:   "Reservation cancelled",
+			Message: "Your reservation on " + formatLinkDate(reservation) + " has been cancelled.",
+		})
+	}
+	return utils.WriteResponse(ctx, fiber.StatusOK, reservation, "Reservation cancelled successfully", nil)
+}
+
+func (c *ReservationController) showLinkPage(ctx *fiber.Ctx, action usecase.ReservationLinkAction) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return c.renderLinkPage(ctx, fiber.StatusBadRequest, reservationLinkView{Title: "Invalid link", Message: "This link is not valid."})
+	}
+
+	token := ctx.Query("token")
+	reservation, err := c.useCase.GetByToken(ctx.UserContext(), uint(id), action, token)
+	if err != nil {
+		return c.writeLinkError(ctx, err, "Failed to load reservation")
+	}
+
+	view := reservationLinkView{
+		Title:   "Confirm your reservation",
+		Message: "Reservation for " + strconv.Itoa(reservation.GuestCount) + " guests on " + formatLinkDate(reservation) + ".",
+		Button:  "Confirm reservation",
+		Token:   token,
+	}
+	if action == usecase.ReservationLinkCancel {
+		view.Title = "Cancel your reservation"
+		view.Button = "Cancel reservation"
+	}
+	return c.renderLinkPage(ctx, fiber.StatusOK, view)
+}
+
+func (c *ReservationController) renderLinkPage(ctx *fiber.Ctx, status int, view reservationLinkView) error {
+	ctx.Status(status)
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return reservationLinkPage.Execute(ctx, view)
+}
+
+// linkToken reads the token posted by the link page, or by an API client in the query
+func linkToken(ctx *fiber.Ctx) string {
+	if token := ctx.FormValue("token"); token != "" {
+		return token
+	}
+	return ctx.Query("token")
+}
+
+func isFormPost(ctx *fiber.Ctx) bool {
+	return strings.HasPrefix(string(ctx.Request().Header.ContentType()), fiber.MIMEApplicationForm)
+}
+
+func formatLinkDate(reservation *model.ReservationResponse) string {
+	return reservation.ReserveDate.Format("Monday, 02 Jan 2006 15:04")
+}
+
+// CheckInReservation records that the party has arrived
+func (c *ReservationController) CheckInReservation(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing reservation ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	reservation, err := c.useCase.CheckIn(ctx.UserContext(), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, constants.ErrNotFound):
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Reservation not found")
+		case errors.Is(err, constants.ErrReservationNotChangeable):
+			return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+		}
+		c.logger.Errorf("Error checking in reservation: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to check in reservation")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, reservation, "Reservation checked in successfully", nil)
+}
+
+func (c *ReservationController) GetCustomerNoShows(ctx *fiber.Ctx) error {
+	customerID, err := strconv.ParseUint(ctx.Params("customerId"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing customer ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid customer ID")
+	}
+
+	summary, err := c.useCase.GetNoShowSummary(ctx.UserContext(), uint(customerID))
+	if err != nil {
+		c.logger.Errorf("Error getting no-shows: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get no-shows")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, summary, "No-shows retrieved successfully", nil)
+}
+
+// ApplyDeposit credits the reservation's paid deposit to the party's bill
+func (c *ReservationController) ApplyDeposit(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing reservation ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid reservation ID")
+	}
+
+	var request model.ApplyDepositRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Errorf("Error parsing request body: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := validator.New().Struct(request); err != nil {
+		c.logger.Errorf("Validation failed: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	deposit, err := c.useCase.ApplyDeposit(ctx.UserContext(), uint(id), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, constants.ErrNotFound):
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Deposit or order not found")
+		case errors.Is(err, constants.ErrDepositUnpaid),
+			errors.Is(err, constants.ErrDepositNotApplicable),
+			errors.Is(err, constants.ErrOrderAlreadyPaid),
+			errors.Is(err, constants.ErrOrderNotPayable):
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+		c.logger.Errorf("Error applying deposit: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to apply deposit")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, deposit, "Deposit applied successfully", nil)
+}
+
+// writeLinkError answers the browser with the link page and API clients with JSON
+func (c *ReservationController) writeLinkError(ctx *fiber.Ctx, err error, message string) error {
+	status := fiber.StatusInternalServerError
+	switch {
+	case errors.Is(err, constants.ErrInvalidReservationToken):
+		status, message = fiber.StatusForbidden, err.Error()
+	case errors.Is(err, constants.ErrNotFound):
+		status, message = fiber.StatusNotFound, "Reservation not found"
+	case errors.Is(err, constants.ErrReservationNotChangeable),
+		errors.Is(err, constants.ErrDepositUnpaid):
+		status, message = fiber.StatusConflict, err.Error()
+	default:
+		c.logger.Errorf("%s: %v", message, err)
+	}
+
+	if ctx.Method() == fiber.MethodGet || isFormPost(ctx) {
+		return c.renderLinkPage(ctx, status, reservationLinkView{Title: "This link cannot be used", Message: message})
+	}
+	return utils.WriteErrorResponse(ctx, int64(status), message)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type RoleController struct {
+	roleUseCase usecase.RoleUseCase
+	logger      *logrus.Logger
+	validator   *validator.Validate
+}
+
+func NewRoleController(roleUseCase usecase.RoleUseCase, logger *logrus.Logger) *RoleController {
+	return &RoleController{
+		roleUseCase: roleUseCase,
+		logger:      logger,
+		validator:   validator.New(),
+	}
+}
+
+func (c *RoleController) GetAllRoles(ctx *fiber.Ctx) error {
+	roles, err := c.roleUseCase.GetAll(ctx.UserContext())
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to get roles")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, roles, "Roles fetched successfully", nil)
+}
+
+func (c *RoleController) GetRole(ctx *fiber.Ctx) error {
+	role, err := c.roleUseCase.GetByName(ctx.UserContext(), ctx.Params("name"))
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to get role")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, role, "Role fetched successfully", nil)
+}
+
+// GetPermissions lists the permissions that can be granted to a role
+func (c *RoleController) GetPermissions(ctx *fiber.Ctx) error {
+	return utils.WriteResponse(ctx, fiber.StatusOK, constants.Permissions, "Permissions fetched successfully", nil)
+}
+
+func (c *RoleController) CreateRole(ctx *fiber.Ctx) error {
+	var request model.CreateRoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	role, err := c.roleUseCase.Create(ctx.UserContext(), &request)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to create role")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, role, "Role created successfully", nil)
+}
+
+func (c *RoleController) UpdateRole(ctx *fiber.Ctx) error {
+	var request model.UpdateRoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	role, err := c.roleUseCase.Update(ctx.UserContext(), ctx.Params("name"), &request)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to update role")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, role, "Role updated successfully", nil)
+}
+
+func (c *RoleController) DeleteRole(ctx *fiber.Ctx) error {
+	if err := c.roleUseCase.Delete(ctx.UserContext(), ctx.Params("name")); err != nil {
+		return c.writeError(ctx, err, "Failed to delete role")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Role deleted successfully", nil)
+}
+
+func (c *RoleController) writeError(ctx *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, constants.ErrNotFound):
+		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Role not found")
+	case errors.Is(err, constants.ErrUnknownPermission),
+		errors.Is(err, constants.ErrAdminRoleFixed):
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, constants.ErrRoleAlreadyExists),
+		errors.Is(err, constants.ErrRoleInUse),
+		errors.Is(err, constants.ErrBuiltInRole):
+		return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+	}
+
+	c.logger.Error(message+": ", err)
+	return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, message)
+}
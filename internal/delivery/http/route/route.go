@@ -1,9 +1,12 @@
 package route
 
 import (
+	"cakestore/internal/auth"
 	"cakestore/internal/constants"
 	http "cakestore/internal/delivery/http"
 	"cakestore/internal/middleware"
+	"cakestore/internal/usecase"
+	"strings"
 
 	"github.com/gofiber/contrib/swagger"
 	"github.com/gofiber/fiber/v2"
@@ -13,18 +16,33 @@ import (
 )
 
 type RouteConfig struct {
-	App                   *fiber.App
-	MenuController        *http.MenuController
-	CustomerController    *http.CustomerController
-	CartController        *http.CartController
-	OrderController       *http.OrderController
-	WishlistController    *http.WishListController
-	PaymentController     http.PaymentController
-	ReservationController *http.ReservationController
-	InventoryController   *http.InventoryController
-	TableController       *http.TableController
-	JWTSecret             string
-	Log                   *logrus.Logger
+	App                    *fiber.App
+	MenuController         *http.MenuController
+	CustomerController     *http.CustomerController
+	AuthController         *http.AuthController
+	TwoFactorController    *http.TwoFactorController
+	CartController         *http.CartController
+	OrderController        *http.OrderController
+	WishlistController     *http.WishListController
+	PaymentController      http.PaymentController
+	ReservationController  *http.ReservationController
+	InventoryController    *http.InventoryController
+	TableController        *http.TableController
+	TableSessionController *http.TableSessionController
+	POSController          *http.POSController
+	ShiftController        *http.ShiftController
+	RoleController         *http.RoleController
+	APIKeyController       *http.APIKeyController
+	TableSessionUseCase    usecase.TableSessionUseCase
+	SessionUseCase         usecase.SessionUseCase
+	RoleUseCase            usecase.RoleUseCase
+	APIKeyUseCase          usecase.APIKeyUseCase
+	TokenVerifier          auth.TokenVerifier
+	Log                    *logrus.Logger
+	// AllowOrigins lists the browser origins allowed by CORS, * for any
+	AllowOrigins  []string
+	EnableSwagger bool
+	EnablePprof   bool
 }
 
 func (c *RouteConfig) Setup() {
@@ -33,50 +51,111 @@ func (c *RouteConfig) Setup() {
 
 func (c *RouteConfig) SetupRoute() {
 	c.App.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
+		AllowOrigins: strings.Join(c.AllowOrigins, ","),
 		AllowMethods: "GET,POST,PATCH,PUT,DELETE",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-App-Role, User-Agent",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-App-Role, User-Agent, X-Table-Token, X-API-Key",
 	}))
-	c.App.Use(pprof.New())
+	if c.EnablePprof {
+		c.App.Use(pprof.New())
+	}
 	c.App.Use(middleware.LogMiddleware(c.Log))
-	c.App.Static("/docs", "./docs")
-	cfg := swagger.Config{
-		FilePath: "./docs/swagger.json",
-		Path:     "docs",
-		Title:    "Swagger API Docs",
-		BasePath: "/api/v1/",
+	if c.EnableSwagger {
+		c.App.Static("/docs", "./docs")
+		cfg := swagger.Config{
+			FilePath: "./docs/swagger.json",
+			Path:     "docs",
+			Title:    "Swagger API Docs",
+			BasePath: "/api/v1/",
+		}
+		c.App.Use(swagger.New(cfg))
 	}
-	c.App.Use(swagger.New(cfg))
 
 	// Public routes
 	c.App.Post("/register", c.CustomerController.Register)
 	c.App.Post("/login", c.CustomerController.Login)
+	c.App.Post("/auth/refresh", c.AuthController.Refresh)
+	c.App.Get("/.well-known/jwks.json", c.AuthController.JWKS)
+	c.App.Post("/auth/forgot-password", c.AuthController.ForgotPassword)
+	c.App.Post("/auth/reset-password", c.AuthController.ResetPassword)
+	// new employees choose their password through the emailed invite
+	c.App.Post("/auth/accept-invite", c.AuthController.AcceptInvite)
+	// opened from the verification email
+	c.App.Get("/auth/verify-email", c.AuthController.VerifyEmail)
+	// second login step, authorised by the two_factor_token from POST /login
+	c.App.Post("/auth/2fa/verify", c.TwoFactorController.VerifyLogin)
+	c.App.Post("/auth/2fa/enroll", c.TwoFactorController.EnrollAtLogin)
+	c.App.Post("/auth/2fa/activate", c.TwoFactorController.ActivateAtLogin)
+	c.App.Post("/auth/logout", middleware.AuthMiddleware(c.TokenVerifier, c.SessionUseCase, nil), c.AuthController.Logout)
 	// Midtrans notification webhook
 	c.App.Post("/payment/notification/", c.PaymentController.GetTransactionStatus)
 	// menus
 	c.App.Get("/menus", c.MenuController.GetAllMenus)
 	c.App.Get("/menus/:id", c.MenuController.GetMenuByID)
+	// reservation links sent to customers, authenticated by a signed token
+	c.App.Get("/reservations/:id/confirm", c.ReservationController.ShowConfirmReservation)
+	c.App.Post("/reservations/:id/confirm", c.ReservationController.ConfirmReservation)
+	c.App.Get("/reservations/:id/cancel", c.ReservationController.ShowCancelReservation)
+	c.App.Post("/reservations/:id/cancel", c.ReservationController.CancelReservation)
+
+	// Guest (dine-in) routes, authenticated by the table QR token instead of a JWT
+	guest := c.App.Group("/guest", middleware.TableTokenMiddleware(c.TableSessionUseCase))
+	guest.Get("/session", c.TableSessionController.GuestGetSession)
+	guest.Get("/menus", c.MenuController.GetAllMenus)
+	guest.Get("/menus/:id", c.MenuController.GetMenuByID)
+	guest.Post("/orders", c.TableSessionController.GuestCreateOrder)
+	guest.Get("/orders", c.TableSessionController.GuestGetOrders)
+	guest.Get("/orders/:id/payments", c.PaymentController.GetOrderPayments)
+	guest.Post("/orders/:id/payments/split", c.PaymentController.SplitPayment)
 
-	// Protected routes
-	protectedRoutes := c.App.Group("/api/v1", middleware.AuthMiddleware(c.JWTSecret))
+	// Protected routes. API keys only reach routes that declare a permission the key holds.
+	protectedRoutes := apiKeyGuard{c.App.Group("/api/v1", middleware.AuthMiddleware(c.TokenVerifier, c.SessionUseCase, c.APIKeyUseCase))}
 
 	// Customer routes
 	protectedRoutes.Get("/authorize", c.CustomerController.Authorize)
 	protectedRoutes.Get("/customers/me", c.CustomerController.GetCustomerByID)
+	protectedRoutes.Put("/customers/me", c.CustomerController.UpdateProfile)
+	protectedRoutes.Delete("/customers/me", c.CustomerController.DeleteAccount)
+	protectedRoutes.Get("/customers/me/export", c.CustomerController.ExportData)
+	protectedRoutes.Put("/customers/me/password", c.CustomerController.ChangePassword)
+	protectedRoutes.Post("/customers/me/verify-email", c.AuthController.ResendVerification)
+	protectedRoutes.Get("/customers/me/2fa", c.TwoFactorController.Status)
+	protectedRoutes.Post("/customers/me/2fa", c.TwoFactorController.Enroll)
+	protectedRoutes.Post("/customers/me/2fa/activate", c.TwoFactorController.Activate)
+	protectedRoutes.Delete("/customers/me/2fa", c.TwoFactorController.Disable)
+	protectedRoutes.Post("/customers/me/2fa/recovery-codes", c.TwoFactorController.RegenerateRecoveryCodes)
 	protectedRoutes.Put("/customers/:id", c.CustomerController.UpdateProfile)
 
+	protectedRoutes.Post("/auth/unlock", c.requirePermission(constants.PermissionLoginUnlock), c.AuthController.UnlockLogin)
+
 	// employee routes
 	employeeRoutes := protectedRoutes.Group("/employees")
-	employeeRoutes.Get("/", c.CustomerController.GetEmployees)
-	employeeRoutes.Get("/:id", c.CustomerController.GetEmployeeByID)
-	employeeRoutes.Put("/:id", middleware.RoleMiddleware(constants.RoleAdmin), c.CustomerController.UpdateEmployee)
-	employeeRoutes.Delete("/:id", middleware.RoleMiddleware(constants.RoleAdmin), c.CustomerController.DeleteEmployee)
+	employeeRoutes.Get("/", c.requirePermission(constants.PermissionEmployeeRead), c.CustomerController.GetEmployees)
+	employeeRoutes.Get("/:id", c.requirePermission(constants.PermissionEmployeeRead), c.CustomerController.GetEmployeeByID)
+	employeeRoutes.Post("/", c.requirePermission(constants.PermissionEmployeeManage), c.CustomerController.CreateEmployee)
+	employeeRoutes.Post("/:id/invite", c.requirePermission(constants.PermissionEmployeeManage), c.CustomerController.InviteEmployee)
+	employeeRoutes.Put("/:id", c.requirePermission(constants.PermissionEmployeeManage), c.CustomerController.UpdateEmployee)
+	employeeRoutes.Delete("/:id", c.requirePermission(constants.PermissionEmployeeManage), c.CustomerController.DeleteEmployee)
+
+	// Role administration
+	roles := protectedRoutes.Group("/roles", c.requirePermission(constants.PermissionRoleManage))
+	roles.Get("/", c.RoleController.GetAllRoles)
+	roles.Post("/", c.RoleController.CreateRole)
+	roles.Get("/:name", c.RoleController.GetRole)
+	roles.Put("/:name", c.RoleController.UpdateRole)
+	roles.Delete("/:name", c.RoleController.DeleteRole)
+	protectedRoutes.Get("/permissions", c.requirePermission(constants.PermissionRoleManage), c.RoleController.GetPermissions)
+
+	// API keys for partner integrations
+	apiKeys := protectedRoutes.Group("/api-keys", c.requirePermission(constants.PermissionAPIKeyManage))
+	apiKeys.Get("/", c.APIKeyController.GetAllAPIKeys)
+	apiKeys.Post("/", c.APIKeyController.CreateAPIKey)
+	apiKeys.Delete("/:id", c.APIKeyController.RevokeAPIKey)
 
 	// Menu routes
 	menus := protectedRoutes.Group("/menus")
-	menus.Post("/", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier, constants.RoleKitchen, constants.RoleWaitress), c.MenuController.CreateMenu)
-	menus.Put("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier, constants.RoleKitchen, constants.RoleWaitress), c.MenuController.UpdateMenu)
-	menus.Delete("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier, constants.RoleKitchen, constants.RoleWaitress), c.MenuController.DeleteMenu)
+	menus.Post("/", c.requirePermission(constants.PermissionMenuWrite), c.MenuController.CreateMenu)
+	menus.Put("/:id", c.requirePermission(constants.PermissionMenuWrite), c.MenuController.UpdateMenu)
+	menus.Delete("/:id", c.requirePermission(constants.PermissionMenuWrite), c.MenuController.DeleteMenu)
 
 	// Cart routes
 	carts := protectedRoutes.Group("/carts")
@@ -93,11 +172,25 @@ func (c *RouteConfig) SetupRoute() {
 	orders.Post("/", c.OrderController.CreateOrder)
 	orders.Get("/", c.OrderController.GetCustomerOrders)
 	orders.Get("/:id", c.OrderController.GetOrderByID)
-	orders.Patch("/:id/food-status", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.OrderController.UpdateFoodStatus)
+	orders.Get("/:id/payments", middleware.AllowAPIKey(constants.PermissionOrderReadAll), c.PaymentController.GetOrderPayments)
+	orders.Post("/:id/payments/split", c.PaymentController.SplitPayment)
+	orders.Patch("/:id/food-status", c.requirePermission(constants.PermissionOrderUpdateStatus), c.OrderController.UpdateFoodStatus)
 
 	// payment routes
 	payment := protectedRoutes.Group("/payments")
 	payment.Get("/:id", c.PaymentController.GetPaymentURL)
+	payment.Post("/:id/settle", c.requirePermission(constants.PermissionPaymentSettle), c.POSController.SettlePayment)
+
+	// Point-of-sale routes for payments taken at the till
+	pos := protectedRoutes.Group("/pos", c.requirePermission(constants.PermissionPOSOperate))
+	pos.Post("/orders/:id/payments", c.POSController.PayOrder)
+	pos.Post("/table-sessions/:id/payments", c.POSController.PayTableSession)
+	pos.Get("/receipts/:number", c.POSController.GetReceipt)
+	pos.Post("/shifts", c.ShiftController.OpenShift)
+	pos.Get("/shifts/current", c.ShiftController.GetCurrentShift)
+	pos.Post("/shifts/current/transactions", c.ShiftController.RecordTransaction)
+	pos.Post("/shifts/current/close", c.ShiftController.CloseShift)
+	pos.Get("/reports/z", c.requirePermission(constants.PermissionReportRead), c.ShiftController.GetZReport)
 
 	// Wishlist routes
 	wishlist := protectedRoutes.Group("/wishlists")
@@ -109,28 +202,82 @@ func (c *RouteConfig) SetupRoute() {
 	reservation := protectedRoutes.Group("/reservations")
 	reservation.Post("/", c.ReservationController.CreateReservation)
 	reservation.Get("/", c.ReservationController.GetAllReservations)
-	reservation.Get("/admin", middleware.RoleMiddleware(constants.RoleAdmin), c.ReservationController.AdminGetAllCustomerReservations)
+	reservation.Get("/admin", c.requirePermission(constants.PermissionReservationReadAll), c.ReservationController.AdminGetAllCustomerReservations)
+	reservation.Get("/customers/:customerId/no-shows", c.requirePermission(constants.PermissionReservationManage), c.ReservationController.GetCustomerNoShows)
 	reservation.Get("/:id", c.ReservationController.GetReservationByID)
-	reservation.Put("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleWaitress), c.ReservationController.UpdateReservation)
-	reservation.Delete("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleWaitress), c.ReservationController.DeleteReservation)
+	reservation.Put("/:id", c.requirePermission(constants.PermissionReservationManage), c.ReservationController.UpdateReservation)
+	reservation.Delete("/:id", c.requirePermission(constants.PermissionReservationManage), c.ReservationController.DeleteReservation)
+	reservation.Post("/:id/check-in", c.requirePermission(constants.PermissionReservationManage), c.ReservationController.CheckInReservation)
+	reservation.Post("/:id/deposit/apply", c.requirePermission(constants.PermissionDepositApply), c.ReservationController.ApplyDeposit)
 
 	// Ingredient routes
 	inventory := protectedRoutes.Group("/inventories")
 	inventory.Get("/", c.InventoryController.GetAllInventories)
-	inventory.Get("/low-stock", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.InventoryController.GetLowStockInventories)
+	inventory.Get("/low-stock", c.requirePermission(constants.PermissionInventoryRead), c.InventoryController.GetLowStockInventories)
 	// temporary fix for conflicting route (/low-stock)
-	inventory.Get("/by-id/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.InventoryController.GetInventoryByID)
-	inventory.Post("/", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.InventoryController.CreateInventory)
-	inventory.Put("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.InventoryController.UpdateInventory)
-	inventory.Delete("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.InventoryController.DeleteInventory)
-	inventory.Put("/:id/stock", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleKitchen), c.InventoryController.UpdateInventoryStock)
+	inventory.Get("/by-id/:id", c.requirePermission(constants.PermissionInventoryRead), c.InventoryController.GetInventoryByID)
+	inventory.Post("/", c.requirePermission(constants.PermissionInventoryWrite), c.InventoryController.CreateInventory)
+	inventory.Put("/:id", c.requirePermission(constants.PermissionInventoryWrite), c.InventoryController.UpdateInventory)
+	inventory.Delete("/:id", c.requirePermission(constants.PermissionInventoryWrite), c.InventoryController.DeleteInventory)
+	inventory.Put("/:id/stock", c.requirePermission(constants.PermissionInventoryAdjust), c.InventoryController.UpdateInventoryStock)
 
 	// Table routes
 	tables := protectedRoutes.Group("/tables")
 	tables.Get("/", c.TableController.GetAllTables)
 	tables.Get("/:id", c.TableController.GetTableByID)
-	tables.Post("/", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier), c.TableController.CreateTable)
-	tables.Put("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier), c.TableController.UpdateTable)
-	tables.Patch("/:id/availability", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier), c.TableController.UpdateTableAvailability)
-	tables.Delete("/:id", middleware.RoleMiddleware(constants.RoleAdmin, constants.RoleCashier), c.TableController.DeleteTable)
+	tables.Post("/", c.requirePermission(constants.PermissionTableManage), c.TableController.CreateTable)
+	tables.Put("/:id", c.requirePermission(constants.PermissionTableManage), c.TableController.UpdateTable)
+	tables.Patch("/:id/availability", c.requirePermission(constants.PermissionTableManage), c.TableController.UpdateTableAvailability)
+	tables.Delete("/:id", c.requirePermission(constants.PermissionTableManage), c.TableController.DeleteTable)
+	tables.Get("/:id/qr", c.requirePermission(constants.PermissionTableManage), c.TableSessionController.GetQRCode)
+	tables.Post("/:id/qr/rotate", c.requirePermission(constants.PermissionTableManage), c.TableSessionController.RotateQRCode)
+	tables.Post("/:id/session/close", c.requirePermission(constants.PermissionTableSessionClose), c.TableSessionController.CloseSession)
+}
+
+func (c *RouteConfig) requirePermission(permissions ...string) fiber.Handler {
+	return middleware.RequirePermission(c.RoleUseCase, permissions...)
+}
+
+// apiKeyGuard registers routes so that their handler refuses API keys no
+// permission check has admitted. Groups made from it are guarded too.
+type apiKeyGuard struct {
+	fiber.Router
+}
+
+func (r apiKeyGuard) Group(prefix string, handlers ...fiber.Handler) fiber.Router {
+	return apiKeyGuard{r.Router.Group(prefix, handlers...)}
+}
+
+func (r apiKeyGuard) Get(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Get(path, guardAPIKey(handlers)...)
+	return r
+}
+
+func (r apiKeyGuard) Post(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Post(path, guardAPIKey(handlers)...)
+	return r
+}
+
+func (r apiKeyGuard) Put(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Put(path, guardAPIKey(handlers)...)
+	return r
+}
+
+func (r apiKeyGuard) Patch(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Patch(path, guardAPIKey(handlers)...)
+	return r
+}
+
+func (r apiKeyGuard) Delete(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Delete(path, guardAPIKey(handlers)...)
+	return r
+}
+
+// guardAPIKey runs the API key check right before the final handler, after any
+// permission middleware of the route
+func guardAPIKey(handlers []fiber.Handler) []fiber.Handler {
+	last := len(handlers) - 1
+	guarded := make([]fiber.Handler, 0, len(handlers)+1)
+	guarded = append(guarded, handlers[:last]...)
+	return append(guarded, middleware.RejectUnadmittedAPIKey, handlers[last])
 }
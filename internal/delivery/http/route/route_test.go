@@ -0,0 +1,65 @@
+package route
+
+import (
+	"cakestore/internal/constants"
+	http "cakestore/internal/delivery/http"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAPIKeys accepts any key with the given permissions
+type stubAPIKeys struct {
+	usecase.APIKeyUseCase
+	permissions []string
+}
+
+func (s *stubAPIKeys) Authenticate(ctx context.Context, rawKey string) (*model.APIKeyPrincipal, error) {
+	return &model.APIKeyPrincipal{ID: 1, Name: "partner", Permissions: s.permissions}, nil
+}
+
+func newTestApp(permissions ...string) *fiber.App {
+	app := fiber.New()
+	config := RouteConfig{
+		App:               app,
+		PaymentController: http.NewPaymentController(logrus.New(), "", nil, nil, nil, nil, nil),
+		APIKeyUseCase:     &stubAPIKeys{permissions: permissions},
+		Log:               logrus.New(),
+	}
+	config.Setup()
+	return app
+}
+
+func TestRoutes_APIKeyNeedsDeclaredPermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		permissions []string
+	}{
+		{"route without permission", fiber.MethodGet, "/api/v1/orders/customers", []string{constants.PermissionMenuWrite}},
+		{"route without permission in guarded group", fiber.MethodGet, "/api/v1/carts/customer", []string{constants.PermissionMenuWrite}},
+		{"route permission not held", fiber.MethodGet, "/api/v1/inventories/low-stock", []string{constants.PermissionMenuWrite}},
+		{"route open to keys with another permission", fiber.MethodGet, "/api/v1/orders/1/payments", []string{constants.PermissionMenuWrite}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(tt.permissions...)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set(constants.APIKeyHeader, "ck_test")
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, nethttp.StatusForbidden, resp.StatusCode)
+		})
+	}
+}
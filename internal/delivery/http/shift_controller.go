@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type ShiftController struct {
+	shiftUseCase usecase.ShiftUseCase
+	logger       *logrus.Logger
+	validator    *validator.Validate
+}
+
+func NewShiftController(shiftUseCase usecase.ShiftUseCase, logger *logrus.Logger) *ShiftController {
+	return &ShiftController{
+		shiftUseCase: shiftUseCase,
+		logger:       logger,
+		validator:    validator.New(),
+	}
+}
+
+func (c *ShiftController) OpenShift(ctx *fiber.Ctx) error {
+	var request model.OpenShiftRequest
+	if err := c.parseBody(ctx, &request); err != nil {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	shift, err := c.shiftUseCase.OpenShift(ctx.UserContext(), cashierID, &request)
+	if err != nil {
+		if errors.Is(err, constants.ErrShiftAlreadyOpen) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+		}
+		c.logger.Errorf("Failed to open shift: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to open shift")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, shift, "Shift opened successfully", nil)
+}
+
+func (c *ShiftController) GetCurrentShift(ctx *fiber.Ctx) error {
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	shift, err := c.shiftUseCase.GetCurrentShift(ctx.UserContext(), cashierID)
+	if err != nil {
+		return c.writeShiftError(ctx, err, "Failed to get shift")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, shift, "Shift fetched successfully", nil)
+}
+
+func (c *ShiftController) RecordTransaction(ctx *fiber.Ctx) error {
+	var request model.ShiftTransactionRequest
+	if err := c.parseBody(ctx, &request); err != nil {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	shift, err := c.shiftUseCase.RecordTransaction(ctx.UserContext(), cashierID, &request)
+	if err != nil {
+		return c.writeShiftError(ctx, err, "Failed to record transaction")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, shift, "Transaction recorded successfully", nil)
+}
+
+func (c *ShiftController) CloseShift(ctx *fiber.Ctx) error {
+	var request model.CloseShiftRequest
+	if err := c.parseBody(ctx, &request); err != nil {
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	cashierID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+	shift, err := c.shiftUseCase.CloseShift(ctx.UserContext(), cashierID, &request)
+	if err != nil {
+		return c.writeShiftError(ctx, err, "Failed to close shift")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, shift, "Shift closed successfully", nil)
+}
+
+// GetZReport returns the end-of-day report for ?date=YYYY-MM-DD, defaulting to today
+func (c *ShiftController) GetZReport(ctx *fiber.Ctx) error {
+	date := time.Now()
+	if dateStr := ctx.Query("date"); dateStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		if err != nil {
+			c.logger.Errorf("Invalid date format: %v", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid date format, expected YYYY-MM-DD")
+		}
+		date = parsed
+	}
+
+	report, err := c.shiftUseCase.GetZReport(ctx.UserContext(), date)
+	if err != nil {
+		c.logger.Errorf("Failed to build Z-report: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to build Z-report")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, report, "Z-report fetched successfully", nil)
+}
+
+func (c *ShiftController) parseBody(ctx *fiber.Ctx, request interface{}) error {
+	if err := ctx.BodyParser(request); err != nil {
+		c.logger.Errorf("Failed to parse body: %v", err)
+		return constants.ErrInvalidRequestBody
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Errorf("Validation failed: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *ShiftController) writeShiftError(ctx *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, constants.ErrNoOpenShift) {
+		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, err.Error())
+	}
+	c.logger.Errorf("%s: %v", message, err)
+	return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, message)
+}
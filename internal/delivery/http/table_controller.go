@@ -30,7 +30,7 @@ func (c *TableController) CreateTable(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	table, err := c.useCase.Create(&request)
+	table, err := c.useCase.Create(ctx.UserContext(), &request)
 	if err != nil {
 		c.logger.Errorf("Error creating table: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create table")
@@ -49,7 +49,7 @@ func (c *TableController) GetTableByID(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid table ID")
 	}
 
-	table, err := c.useCase.GetByID(uint(id))
+	table, err := c.useCase.GetByID(ctx.UserContext(), uint(id))
 	if err != nil {
 		c.logger.Errorf("Error getting table: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Table not found")
@@ -85,7 +85,7 @@ func (c *TableController) GetAllTables(ctx *fiber.Ctx) error {
 		}
 	}
 
-	tables, err := c.useCase.GetAll(params)
+	tables, err := c.useCase.GetAll(ctx.UserContext(), params)
 	if err != nil {
 		c.logger.Errorf("Error getting tables: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get tables")
@@ -107,7 +107,7 @@ func (c *TableController) UpdateTable(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	table, err := c.useCase.Update(uint(id), &request)
+	table, err := c.useCase.Update(ctx.UserContext(), uint(id), &request)
 	if err != nil {
 		c.logger.Errorf("Error updating table: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update table")
@@ -126,7 +126,7 @@ func (c *TableController) DeleteTable(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid table ID")
 	}
 
-	if err := c.useCase.Delete(uint(id)); err != nil {
+	if err := c.useCase.Delete(ctx.UserContext(), uint(id)); err != nil {
 		c.logger.Errorf("Error deleting table: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete table")
 	}
@@ -157,7 +157,7 @@ func (c *TableController) GetAvailableTables(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid duration format")
 	}
 
-	tables, err := c.useCase.GetAvailableTables(reserveTime, duration)
+	tables, err := c.useCase.GetAvailableTables(ctx.UserContext(), reserveTime, duration)
 	if err != nil {
 		c.logger.Errorf("Error getting available tables: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get available tables")
@@ -184,7 +184,7 @@ func (c *TableController) UpdateTableAvailability(ctx *fiber.Ctx) error {
 		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	if err := c.useCase.UpdateAvailability(uint(id), request.IsAvailable); err != nil {
+	if err := c.useCase.UpdateAvailability(ctx.UserContext(), uint(id), request.IsAvailable); err != nil {
 		c.logger.Errorf("Error updating table availability: %v", err)
 		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to update table availability")
 	}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type TableSessionController struct {
+	tableSessionUseCase usecase.TableSessionUseCase
+	orderUseCase        usecase.OrderUseCase
+	paymentUseCase      usecase.PaymentUseCase
+	logger              *logrus.Logger
+	validator           *validator.Validate
+}
+
+func NewTableSessionController(
+	tableSessionUseCase usecase.TableSessionUseCase,
+	orderUseCase usecase.OrderUseCase,
+	paymentUseCase usecase.PaymentUseCase,
+	logger *logrus.Logger,
+) *TableSessionController {
+	return &TableSessionController{
+		tableSessionUseCase: tableSessionUseCase,
+		orderUseCase:        orderUseCase,
+		paymentUseCase:      paymentUseCase,
+		logger:              logger,
+		validator:           validator.New(),
+	}
+}
+
+// GetQRCode renders the table's current QR code as PNG, SVG or JSON (?format=png|svg|json)
+func (c *TableSessionController) GetQRCode(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing table ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid table ID")
+	}
+
+	qr, err := c.tableSessionUseCase.GetQRCode(ctx.UserContext(), uint(id))
+	if err != nil {
+		c.logger.Errorf("Error getting table QR code: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "Table not found")
+	}
+
+	return c.writeQRCode(ctx, qr, "Table QR code retrieved successfully")
+}
+
+func (c *TableSessionController) RotateQRCode(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing table ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid table ID")
+	}
+
+	qr, err := c.tableSessionUseCase.RotateQRCode(ctx.UserContext(), uint(id))
+	if err != nil {
+		c.logger.Errorf("Error rotating table QR code: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to rotate table QR code")
+	}
+
+	return c.writeQRCode(ctx, qr, "Table QR code rotated successfully")
+}
+
+func (c *TableSessionController) CloseSession(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		c.logger.Errorf("Error parsing table ID: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid table ID")
+	}
+
+	if err := c.tableSessionUseCase.CloseSession(ctx.UserContext(), uint(id)); err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return utils.WriteErrorResponse(ctx, fiber.StatusNotFound, "No open session for this table")
+		}
+		c.logger.Errorf("Error closing table session: %v", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to close table session")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Table session closed successfully", nil)
+}
+
+func (c *TableSessionController) GuestGetSession(ctx *fiber.Ctx) error {
+	session := ctx.Locals(constants.LocalsKeyTableSession).(*entity.TableSession)
+	return utils.WriteResponse(ctx, fiber.StatusOK, model.ToTableSessionResponse(session), "Table session retrieved successfully", nil)
+}
+
+func (c *TableSessionController) GuestCreateOrder(ctx *fiber.Ctx) error {
+	session := ctx.Locals(constants.LocalsKeyTableSession).(*entity.TableSession)
+
+	var request model.CreateOrderRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	order, err := c.orderUseCase.CreateTableOrder(ctx.UserContext(), session, &request)
+	if err != nil {
+		c.logger.Error("Failed to create table order: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create order")
+	}
+
+	// make payment link from midtrans
+	paymentURL, err := c.paymentUseCase.CreatePaymentURL(ctx.UserContext(), order)
+	if err != nil {
+		c.logger.Error("Failed to create payment URL: ", err.Error())
+		// if error delete previous order
+		if err := c.orderUseCase.DeleteOrder(ctx.UserContext(), order.ID); err != nil {
+			c.logger.Error("Failed to delete order: ", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to delete order")
+		}
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to create payment URL")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusCreated, paymentURL, "Order created successfully", nil)
+}
+
+func (c *TableSessionController) GuestGetOrders(ctx *fiber.Ctx) error {
+	session := ctx.Locals(constants.LocalsKeyTableSession).(*entity.TableSession)
+
+	orders, err := c.orderUseCase.GetTableSessionOrders(ctx.UserContext(), session.ID)
+	if err != nil {
+		c.logger.Error("Failed to get table session orders: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to get orders")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, orders, "Table orders fetched successfully", nil)
+}
+
+func (c *TableSessionController) writeQRCode(ctx *fiber.Ctx, qr *model.TableQRCodeResponse, message string) error {
+	// Fall back to the guest session endpoint when no frontend URL is configured
+	if qr.URL == "" {
+		qr.URL = ctx.BaseURL() + "/guest/session?" + constants.TableTokenQuery + "=" + url.QueryEscape(qr.Token)
+	}
+
+	switch ctx.Query("format", "json") {
+	case "png":
+		size, _ := strconv.Atoi(ctx.Query("size", "512"))
+		png, err := utils.GenerateQRCodePNG(qr.URL, size)
+		if err != nil {
+			c.logger.Errorf("Error generating QR code PNG: %v", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to generate QR code")
+		}
+		ctx.Set(fiber.HeaderContentType, "image/png")
+		return ctx.Send(png)
+	case "svg":
+		svg, err := utils.GenerateQRCodeSVG(qr.URL, 8)
+		if err != nil {
+			c.logger.Errorf("Error generating QR code SVG: %v", err)
+			return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, "Failed to generate QR code")
+		}
+		ctx.Set(fiber.HeaderContentType, "image/svg+xml")
+		return ctx.SendString(svg)
+	default:
+		return utils.WriteResponse(ctx, fiber.StatusOK, qr, message, nil)
+	}
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"cakestore/utils"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+type TwoFactorController struct {
+	twoFactorUseCase usecase.TwoFactorUseCase
+	logger           *logrus.Logger
+	validator        *validator.Validate
+}
+
+func NewTwoFactorController(twoFactorUseCase usecase.TwoFactorUseCase, logger *logrus.Logger) *TwoFactorController {
+	return &TwoFactorController{
+		twoFactorUseCase: twoFactorUseCase,
+		logger:           logger,
+		validator:        validator.New(),
+	}
+}
+
+// VerifyLogin completes a login with the two-factor token from POST /login
+func (c *TwoFactorController) VerifyLogin(ctx *fiber.Ctx) error {
+	var request model.TwoFactorLoginRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := c.twoFactorUseCase.VerifyLogin(ctx.UserContext(), &request, ctx.IP())
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to verify two-factor code")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, tokens, "Login successful", nil)
+}
+
+// EnrollAtLogin starts setting up an authenticator for an account that must
+// have one before it can log in
+func (c *TwoFactorController) EnrollAtLogin(ctx *fiber.Ctx) error {
+	var request model.TwoFactorTokenRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	enrollment, err := c.twoFactorUseCase.EnrollWithChallenge(ctx.UserContext(), &request)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to set up two-factor authentication")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, enrollment, "Scan the QR code with an authenticator app", nil)
+}
+
+func (c *TwoFactorController) ActivateAtLogin(ctx *fiber.Ctx) error {
+	var request model.TwoFactorLoginRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	activation, err := c.twoFactorUseCase.ActivateWithChallenge(ctx.UserContext(), &request, ctx.IP())
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to enable two-factor authentication")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, activation, "Two-factor authentication enabled, store the recovery codes safely", nil)
+}
+
+func (c *TwoFactorController) Status(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	status, err := c.twoFactorUseCase.Status(ctx.UserContext(), customerID)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to get two-factor status")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, status, "Two-factor status fetched successfully", nil)
+}
+
+func (c *TwoFactorController) Enroll(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	enrollment, err := c.twoFactorUseCase.Enroll(ctx.UserContext(), customerID)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to set up two-factor authentication")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, enrollment, "Scan the QR code with an authenticator app", nil)
+}
+
+func (c *TwoFactorController) Activate(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var request model.TwoFactorCodeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	activation, err := c.twoFactorUseCase.Activate(ctx.UserContext(), customerID, &request)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to enable two-factor authentication")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, activation, "Two-factor authentication enabled, store the recovery codes safely", nil)
+}
+
+func (c *TwoFactorController) Disable(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var request model.DisableTwoFactorRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.twoFactorUseCase.Disable(ctx.UserContext(), customerID, &request); err != nil {
+		return c.writeError(ctx, err, "Failed to disable two-factor authentication")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, nil, "Two-factor authentication disabled", nil)
+}
+
+func (c *TwoFactorController) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		c.logger.Error("Failed to get customer ID from token")
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
+
+	var request model.TwoFactorCodeRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.Error("Failed to parse body: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := c.validator.Struct(request); err != nil {
+		c.logger.Error("Validation failed: ", err)
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	codes, err := c.twoFactorUseCase.RegenerateRecoveryCodes(ctx.UserContext(), customerID, &request)
+	if err != nil {
+		return c.writeError(ctx, err, "Failed to generate recovery codes")
+	}
+
+	return utils.WriteResponse(ctx, fiber.StatusOK, codes, "New recovery codes generated, the old ones no longer work", nil)
+}
+
+func (c *TwoFactorController) writeError(ctx *fiber.Ctx, err error, message string) error {
+	var blocked *usecase.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		return utils.WriteErrorResponse(ctx, fiber.StatusTooManyRequests, blocked.Error())
+	case errors.Is(err, constants.ErrInvalidTwoFactorToken),
+		errors.Is(err, constants.ErrInvalidTwoFactorCode):
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	case errors.Is(err, constants.ErrInvalidPassword):
+		return utils.WriteErrorResponse(ctx, fiber.StatusBadRequest, "Password is incorrect")
+	case errors.Is(err, constants.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, constants.ErrTwoFactorNotSetUp):
+		return utils.WriteErrorResponse(ctx, fiber.StatusConflict, err.Error())
+	case errors.Is(err, constants.ErrTwoFactorRequired):
+		return utils.WriteErrorResponse(ctx, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, constants.ErrTwoFactorNotConfigured):
+		return utils.WriteErrorResponse(ctx, fiber.StatusServiceUnavailable, err.Error())
+	}
+
+	c.logger.Error(message+": ", err)
+	return utils.WriteErrorResponse(ctx, fiber.StatusInternalServerError, message)
+}
@@ -28,14 +28,17 @@ func NewWishListController(
 
 func (h *WishListController) CreateWishList(ctx *fiber.Ctx) error {
 	h.logger.Trace("Creating wishlist")
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 	menuID, err := strconv.ParseInt(ctx.Params("menuId"), 10, 64)
 	if err != nil {
 		h.logger.Errorf("Error converting menu ID: %v", err)
 		return utils.WriteErrorResponse(ctx, http.StatusBadRequest, "Invalid menu ID")
 	}
 
-	err = h.wishListUseCase.CreateWishList(customerID, menuID)
+	err = h.wishListUseCase.CreateWishList(ctx.UserContext(), customerID, menuID)
 	if err != nil {
 		if err == constants.ErrMenuAlreadyInWishlist {
 			h.logger.Warnf("Wishlist already exists: %v", err)
@@ -50,11 +53,14 @@ func (h *WishListController) CreateWishList(ctx *fiber.Ctx) error {
 
 func (h *WishListController) GetWishListByCustomerID(ctx *fiber.Ctx) error {
 	h.logger.Trace("Getting wishlist by customer ID")
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 
 	paginationQuery := utils.GetPaginationFromRequest(ctx)
 
-	wishlists, meta, err := h.wishListUseCase.GetWishList(customerID, paginationQuery)
+	wishlists, meta, err := h.wishListUseCase.GetWishList(ctx.UserContext(), customerID, paginationQuery)
 	if err != nil {
 		h.logger.Errorf("Error getting wishlists: %v", err)
 		return utils.WriteErrorResponse(ctx, http.StatusInternalServerError, "Failed to get wishlists")
@@ -65,14 +71,17 @@ func (h *WishListController) GetWishListByCustomerID(ctx *fiber.Ctx) error {
 
 func (c *WishListController) DeleteWishList(ctx *fiber.Ctx) error {
 	c.logger.Trace("Deleting wishlist")
-	customerID := ctx.Locals(constants.ClaimsKeyID).(int64)
+	customerID, ok := ctx.Locals(constants.ClaimsKeyID).(int64)
+	if !ok {
+		return utils.WriteErrorResponse(ctx, fiber.StatusUnauthorized, "Unauthorized")
+	}
 	menuID, err := strconv.ParseInt(ctx.Params("menuId"), 10, 64)
 	if err != nil {
 		c.logger.Errorf("Error converting menu ID: %v", err)
 		return utils.WriteErrorResponse(ctx, http.StatusBadRequest, "Invalid menu ID")
 	}
 
-	err = c.wishListUseCase.DeleteWishList(customerID, menuID)
+	err = c.wishListUseCase.DeleteWishList(ctx.UserContext(), customerID, menuID)
 	if err != nil {
 		c.logger.Errorf("Error deleting wishlist: %v", err)
 		return utils.WriteErrorResponse(ctx, http.StatusInternalServerError, "Failed to delete wishlist")
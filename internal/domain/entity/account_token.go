@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type AccountTokenPurpose string
+
+const (
+	AccountTokenPasswordReset     AccountTokenPurpose = "password_reset"
+	AccountTokenEmailVerification AccountTokenPurpose = "email_verification"
+	AccountTokenInvite            AccountTokenPurpose = "invite"
+)
+
+// AccountToken is a single-use link token mailed to a customer. Only the
+// SHA-256 hash is stored; UsedAt is set when the link is redeemed or when a
+// newer link of the same purpose replaces it.
+type AccountToken struct {
+	ID         int64               `gorm:"column:id;primaryKey"`
+	CustomerID int64               `gorm:"column:customer_id;index"`
+	Purpose    AccountTokenPurpose `gorm:"column:purpose"`
+	TokenHash  string              `gorm:"column:token_hash;uniqueIndex"`
+	ExpiresAt  time.Time           `gorm:"column:expires_at"`
+	UsedAt     sql.NullTime        `gorm:"column:used_at"`
+	CreatedAt  time.Time           `gorm:"column:created_at"`
+}
+
+func (t *AccountToken) TableName() string {
+	return "account_tokens"
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// APIKey gives a partner system machine access without a login. Only the
+// SHA-256 hash of the key is stored; Prefix is kept in clear so admins can
+// tell keys apart. RateLimit is in requests per minute, 0 uses the default.
+type APIKey struct {
+	ID          int64              `gorm:"column:id;primaryKey"`
+	Name        string             `gorm:"column:name;not null"`
+	Prefix      string             `gorm:"column:prefix"`
+	KeyHash     string             `gorm:"column:key_hash;uniqueIndex"`
+	Permissions []APIKeyPermission `gorm:"foreignKey:APIKeyID"`
+	RateLimit   int                `gorm:"column:rate_limit"`
+	ExpiresAt   sql.NullTime       `gorm:"column:expires_at"`
+	LastUsedAt  sql.NullTime       `gorm:"column:last_used_at"`
+	RevokedAt   sql.NullTime       `gorm:"column:revoked_at"`
+	CreatedBy   int64              `gorm:"column:created_by"`
+	CreatedAt   time.Time          `gorm:"column:created_at"`
+}
+
+func (k *APIKey) TableName() string {
+	return "api_keys"
+}
+
+// PermissionNames returns the names of the permissions granted to the key
+func (k *APIKey) PermissionNames() []string {
+	names := make([]string, 0, len(k.Permissions))
+	for _, permission := range k.Permissions {
+		names = append(names, permission.Permission)
+	}
+	return names
+}
+
+type APIKeyPermission struct {
+	APIKeyID   int64  `gorm:"column:api_key_id;primaryKey;autoIncrement:false"`
+	Permission string `gorm:"column:permission;primaryKey"`
+}
+
+func (p *APIKeyPermission) TableName() string {
+	return "api_key_permissions"
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"cakestore/internal/constants"
+	"database/sql"
+	"time"
+)
+
+type ShiftStatus string
+
+const (
+	ShiftStatusOpen   ShiftStatus = "open"
+	ShiftStatusClosed ShiftStatus = "closed"
+)
+
+type ShiftTransactionType string
+
+const (
+	ShiftTransactionRefund ShiftTransactionType = "refund"
+	ShiftTransactionPayout ShiftTransactionType = "payout"
+)
+
+// CashierShift is one cashier's session at the cash drawer. A cashier can only
+// have one open shift; ExpectedAmount and Variance are filled in when it closes.
+type CashierShift struct {
+	ID             int64              `gorm:"column:id;primaryKey"`
+	CashierID      int64              `gorm:"column:cashier_id;uniqueIndex:idx_cashier_shifts_open,where:status = 'open'"`
+	Cashier        Customer           `gorm:"foreignKey:CashierID"`
+	Status         ShiftStatus        `gorm:"column:status"`
+	OpeningFloat   float64            `gorm:"column:opening_float"`
+	ExpectedAmount float64            `gorm:"column:expected_amount"`
+	CountedAmount  float64            `gorm:"column:counted_amount"`
+	Variance       float64            `gorm:"column:variance"`
+	Notes          string             `gorm:"column:notes"`
+	Transactions   []ShiftTransaction `gorm:"foreignKey:ShiftID"`
+	OpenedAt       time.Time          `gorm:"column:opened_at"`
+	ClosedAt       sql.NullTime       `gorm:"column:closed_at"`
+	CreatedAt      time.Time          `gorm:"column:created_at"`
+	UpdatedAt      time.Time          `gorm:"column:updated_at"`
+}
+
+// ShiftTransaction is money leaving the till outside of a sale: a refund to a
+// customer or a payout (e.g. buying supplies). Card refunds are recorded for
+// reporting but do not affect the cash expected in the drawer.
+type ShiftTransaction struct {
+	ID        int64                   `gorm:"column:id;primaryKey"`
+	ShiftID   int64                   `gorm:"column:shift_id;index"`
+	Type      ShiftTransactionType    `gorm:"column:type"`
+	Method    constants.PaymentMethod `gorm:"column:method"`
+	Amount    float64                 `gorm:"column:amount"`
+	OrderID   *int64                  `gorm:"column:order_id"`
+	Reason    string                  `gorm:"column:reason"`
+	CreatedAt time.Time               `gorm:"column:created_at;index"`
+}
+
+func (s *CashierShift) TableName() string {
+	return "cashier_shifts"
+}
+
+func (t *ShiftTransaction) TableName() string {
+	return "shift_transactions"
+}
@@ -25,18 +25,22 @@ const (
 	FoodStatusCancelled FoodStatus = "cancelled"
 )
 
+// Order.TableID and Order.TableSessionID are only set for dine-in orders
+// placed by guests through a table QR code.
 type Order struct {
-	ID         int64        `gorm:"column:id;primaryKey;autoIncrement"`
-	CustomerID int64        `gorm:"column:customer_id"`
-	Customer   Customer     `gorm:"foreignKey:CustomerID"`
-	Status     OrderStatus  `gorm:"column:status"`
-	FoodStatus FoodStatus   `gorm:"column:food_status"`
-	TotalPrice float64      `gorm:"column:total_price"`
-	Address    string       `gorm:"column:delivery_address"`
-	Items      []OrderItem  `gorm:"foreignKey:OrderID"`
-	CreatedAt  time.Time    `gorm:"column:created_at"`
-	UpdatedAt  time.Time    `gorm:"column:updated_at"`
-	DeletedAt  sql.NullTime `gorm:"column:deleted_at"`
+	ID             int64        `gorm:"column:id;primaryKey;autoIncrement"`
+	CustomerID     int64        `gorm:"column:customer_id"`
+	Customer       Customer     `gorm:"foreignKey:CustomerID"`
+	Status         OrderStatus  `gorm:"column:status"`
+	FoodStatus     FoodStatus   `gorm:"column:food_status"`
+	TotalPrice     float64      `gorm:"column:total_price"`
+	Address        string       `gorm:"column:delivery_address"`
+	TableID        *int64       `gorm:"column:table_id"`
+	TableSessionID *int64       `gorm:"column:table_session_id;index"`
+	Items          []OrderItem  `gorm:"foreignKey:OrderID"`
+	CreatedAt      time.Time    `gorm:"column:created_at"`
+	UpdatedAt      time.Time    `gorm:"column:updated_at"`
+	DeletedAt      sql.NullTime `gorm:"column:deleted_at"`
 }
 
 type OrderItem struct {
@@ -8,17 +8,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// Payment is one settlement towards an order. An order may have several
+// payments (a split bill); it is paid once the successful ones cover its total.
+// GatewayOrderID is the unique order_id sent to Midtrans for this payment.
+// ReceiptID links payments taken at the till to the receipt that was printed.
+// PaidAt is when the money was received and is only set on successful payments.
 type Payment struct {
-	ID           int64                   `gorm:"column:id;primaryKey"`
-	OrderID      int64                   `gorm:"column:order_id"`
-	Order        Order                   `gorm:"foreignKey:OrderID"`
-	Amount       float64                 `gorm:"column:amount"`
-	Status       constants.PaymentStatus `gorm:"column:status"`
-	PaymentToken string                  `gorm:"column:payment_token"`
-	PaymentURL   string                  `gorm:"column:payment_url"`
-	CreatedAt    time.Time               `gorm:"column:created_at"`
-	UpdatedAt    time.Time               `gorm:"column:updated_at"`
-	DeletedAt    sql.NullTime            `gorm:"column:deleted_at"`
+	ID             int64                   `gorm:"column:id;primaryKey"`
+	OrderID        int64                   `gorm:"column:order_id;index"`
+	Order          Order                   `gorm:"foreignKey:OrderID"`
+	Amount         float64                 `gorm:"column:amount"`
+	Method         constants.PaymentMethod `gorm:"column:method;default:midtrans"`
+	Status         constants.PaymentStatus `gorm:"column:status"`
+	GatewayOrderID string                  `gorm:"column:gateway_order_id;index"`
+	Description    string                  `gorm:"column:description"`
+	ReceiptID      *int64                  `gorm:"column:receipt_id;index"`
+	Reference      string                  `gorm:"column:reference"`
+	PaymentToken   string                  `gorm:"column:payment_token"`
+	PaymentURL     string                  `gorm:"column:payment_url"`
+	PaidAt         sql.NullTime            `gorm:"column:paid_at;index"`
+	CreatedAt      time.Time               `gorm:"column:created_at"`
+	UpdatedAt      time.Time               `gorm:"column:updated_at"`
+	DeletedAt      sql.NullTime            `gorm:"column:deleted_at"`
 }
 
 func (p *Payment) TableName() string {
@@ -26,6 +37,14 @@ func (p *Payment) TableName() string {
 }
 
 func (p *Payment) BeforeCreate(tx *gorm.DB) error {
+	// In-person payments are only recorded once the money has been taken;
+	// everything else starts pending until it is confirmed.
+	if p.Method != constants.PaymentMethodMidtrans && p.Status == constants.PaymentStatusSuccess {
+		if !p.PaidAt.Valid {
+			p.PaidAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+		return nil
+	}
 	p.Status = constants.PaymentStatusPending
 	return nil
 }
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"cakestore/internal/constants"
+	"time"
+)
+
+// Receipt is a point-of-sale transaction taken by a cashier. One receipt may
+// settle several orders (a whole table session), with one Payment per order.
+// ShiftID is the cashier's open drawer shift when the receipt was issued.
+type Receipt struct {
+	ID             int64                   `gorm:"column:id;primaryKey"`
+	Number         string                  `gorm:"column:number;uniqueIndex"`
+	CashierID      int64                   `gorm:"column:cashier_id;index"`
+	Cashier        Customer                `gorm:"foreignKey:CashierID"`
+	ShiftID        *int64                  `gorm:"column:shift_id;index"`
+	TableSessionID *int64                  `gorm:"column:table_session_id"`
+	Method         constants.PaymentMethod `gorm:"column:method"`
+	Amount         float64                 `gorm:"column:amount"`
+	AmountTendered float64                 `gorm:"column:amount_tendered"`
+	ChangeGiven    float64                 `gorm:"column:change_given"`
+	Reference      string                  `gorm:"column:reference"`
+	Payments       []Payment               `gorm:"foreignKey:ReceiptID"`
+	CreatedAt      time.Time               `gorm:"column:created_at"`
+	UpdatedAt      time.Time               `gorm:"column:updated_at"`
+}
+
+func (r *Receipt) TableName() string {
+	return "receipts"
+}
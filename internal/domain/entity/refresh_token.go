@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// RefreshToken is one link of a login session. Only the SHA-256 hash of the
+// token is stored. Every refresh revokes the presented token and issues a new
+// one in the same FamilyID, so presenting a revoked token again reveals a
+// stolen token and revokes the whole family.
+type RefreshToken struct {
+	ID           int64        `gorm:"column:id;primaryKey"`
+	CustomerID   int64        `gorm:"column:customer_id;index"`
+	FamilyID     string       `gorm:"column:family_id;index"`
+	TokenHash    string       `gorm:"column:token_hash;uniqueIndex"`
+	ExpiresAt    time.Time    `gorm:"column:expires_at"`
+	RevokedAt    sql.NullTime `gorm:"column:revoked_at"`
+	ReplacedByID *int64       `gorm:"column:replaced_by_id"`
+	CreatedAt    time.Time    `gorm:"column:created_at"`
+}
+
+func (t *RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
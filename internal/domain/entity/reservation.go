@@ -13,20 +13,27 @@ const (
 	ReservationStatusConfirmed ReservationStatus = "confirmed"
 	ReservationStatusCancelled ReservationStatus = "cancelled"
 	ReservationStatusCompleted ReservationStatus = "completed"
+	ReservationStatusNoShow    ReservationStatus = "no_show"
 )
 
+// Reservation is a booked table. CheckedInAt is set when staff seat the party,
+// and a checked-in reservation is never marked as a no-show.
 type Reservation struct {
-	ID           uint              `json:"id" gorm:"primaryKey"`
-	CustomerID   uint              `json:"customer_id"`
-	Customer     Customer          `json:"customer" gorm:"foreignKey:CustomerID"`
-	TableID      *uint             `json:"table_id" gorm:"foreignKey:TableID"`
-	Table        *Table            `json:"table" gorm:"foreignKey:TableID"`
-	TableNumber  int               `json:"table_number"`
-	GuestCount   int               `json:"guest_count"`
-	ReserveDate  time.Time         `json:"reserve_date"`
-	Status       ReservationStatus `json:"status"`
-	SpecialNotes string            `json:"special_notes"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt    `json:"deleted_at" gorm:"index"`
+	ID             uint                `json:"id" gorm:"primaryKey"`
+	CustomerID     uint                `json:"customer_id"`
+	Customer       Customer            `json:"customer" gorm:"foreignKey:CustomerID"`
+	TableID        *uint               `json:"table_id" gorm:"foreignKey:TableID"`
+	Table          *Table              `json:"table" gorm:"foreignKey:TableID"`
+	TableNumber    int                 `json:"table_number"`
+	GuestCount     int                 `json:"guest_count"`
+	ReserveDate    time.Time           `json:"reserve_date"`
+	Status         ReservationStatus   `json:"status"`
+	SpecialNotes   string              `json:"special_notes"`
+	ConfirmedAt    *time.Time          `json:"confirmed_at"`
+	CheckedInAt    *time.Time          `json:"checked_in_at"`
+	ReminderSentAt *time.Time          `json:"reminder_sent_at"`
+	Deposit        *ReservationDeposit `json:"deposit" gorm:"foreignKey:ReservationID"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt      `json:"deleted_at" gorm:"index"`
 }
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type DepositStatus string
+
+const (
+	// DepositStatusPending waits for the customer to pay the link before DueAt
+	DepositStatusPending DepositStatus = "pending"
+	DepositStatusPaid    DepositStatus = "paid"
+	// DepositStatusExpired was not paid in time; the reservation was cancelled
+	DepositStatusExpired DepositStatus = "expired"
+	// DepositStatusCancelled was never paid because the reservation was cancelled first
+	DepositStatusCancelled DepositStatus = "cancelled"
+	// DepositStatusApplied was credited to the final bill
+	DepositStatusApplied DepositStatus = "applied"
+	// DepositStatusRefunded is owed back in full. The refund is made by staff
+	// from the Midtrans dashboard; the API does not call the gateway.
+	DepositStatusRefunded DepositStatus = "refunded"
+	// DepositStatusForfeited is kept after a late cancellation or a no-show
+	DepositStatusForfeited DepositStatus = "forfeited"
+)
+
+// ReservationDeposit is the up-front payment required for large parties and
+// flagged dates. It is paid through Midtrans under its own GatewayOrderID and
+// later either credited to the bill (AppliedOrderID) or refunded. RefundAmount
+// is what staff owe the customer back by hand.
+type ReservationDeposit struct {
+	ID             int64         `gorm:"column:id;primaryKey"`
+	ReservationID  uint          `gorm:"column:reservation_id;uniqueIndex"`
+	Amount         float64       `gorm:"column:amount"`
+	Status         DepositStatus `gorm:"column:status;index"`
+	GatewayOrderID string        `gorm:"column:gateway_order_id;uniqueIndex"`
+	PaymentToken   string        `gorm:"column:payment_token"`
+	PaymentURL     string        `gorm:"column:payment_url"`
+	DueAt          time.Time     `gorm:"column:due_at;index"`
+	PaidAt         sql.NullTime  `gorm:"column:paid_at"`
+	AppliedOrderID *int64        `gorm:"column:applied_order_id"`
+	AppliedAmount  float64       `gorm:"column:applied_amount"`
+	RefundAmount   float64       `gorm:"column:refund_amount"`
+	CreatedAt      time.Time     `gorm:"column:created_at"`
+	UpdatedAt      time.Time     `gorm:"column:updated_at"`
+}
+
+func (d *ReservationDeposit) TableName() string {
+	return "reservation_deposits"
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// Role is a named set of permissions. Customer.Role holds the role name.
+type Role struct {
+	Name        string           `gorm:"column:name;primaryKey"`
+	Description string           `gorm:"column:description"`
+	Permissions []RolePermission `gorm:"foreignKey:RoleName;references:Name"`
+	CreatedAt   time.Time        `gorm:"column:created_at"`
+	UpdatedAt   time.Time        `gorm:"column:updated_at"`
+}
+
+func (r *Role) TableName() string {
+	return "roles"
+}
+
+// PermissionNames returns the names of the permissions granted to the role
+func (r *Role) PermissionNames() []string {
+	names := make([]string, 0, len(r.Permissions))
+	for _, permission := range r.Permissions {
+		names = append(names, permission.Permission)
+	}
+	return names
+}
+
+type RolePermission struct {
+	RoleName   string `gorm:"column:role_name;primaryKey"`
+	Permission string `gorm:"column:permission;primaryKey"`
+}
+
+func (p *RolePermission) TableName() string {
+	return "role_permissions"
+}
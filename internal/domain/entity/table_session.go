@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+type TableSessionStatus string
+
+const (
+	TableSessionStatusOpen   TableSessionStatus = "open"
+	TableSessionStatusClosed TableSessionStatus = "closed"
+)
+
+// TableSession groups the guest orders placed at a table between the first
+// QR scan and the moment staff close the table.
+type TableSession struct {
+	ID              int64              `gorm:"column:id;primaryKey"`
+	TableID         int64              `gorm:"column:table_id;uniqueIndex:idx_table_sessions_open,where:status = 'open'"`
+	Table           Table              `gorm:"foreignKey:TableID"`
+	GuestCustomerID int64              `gorm:"column:guest_customer_id"`
+	GuestCustomer   Customer           `gorm:"foreignKey:GuestCustomerID"`
+	TokenVersion    int                `gorm:"column:token_version"`
+	Status          TableSessionStatus `gorm:"column:status"`
+	OpenedAt        time.Time          `gorm:"column:opened_at"`
+	ClosedAt        sql.NullTime       `gorm:"column:closed_at"`
+	CreatedAt       time.Time          `gorm:"column:created_at"`
+	UpdatedAt       time.Time          `gorm:"column:updated_at"`
+}
+
+func (s *TableSession) TableName() string {
+	return "table_sessions"
+}
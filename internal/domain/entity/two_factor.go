@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TwoFactor is an account's TOTP enrolment. Secret is encrypted with
+// utils.EncryptAES. The enrolment only counts once EnabledAt is set, after the
+// first code has been confirmed. LastUsedStep is the TOTP time step of the
+// last accepted code, so a code cannot be replayed.
+type TwoFactor struct {
+	CustomerID   int64        `gorm:"column:customer_id;primaryKey;autoIncrement:false"`
+	Secret       string       `gorm:"column:secret;not null"`
+	EnabledAt    sql.NullTime `gorm:"column:enabled_at"`
+	LastUsedStep int64        `gorm:"column:last_used_step"`
+	CreatedAt    time.Time    `gorm:"column:created_at"`
+	UpdatedAt    time.Time    `gorm:"column:updated_at"`
+}
+
+func (t *TwoFactor) TableName() string {
+	return "two_factors"
+}
+
+// RecoveryCode is a single-use fallback for a lost authenticator. Only the
+// SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID         int64        `gorm:"column:id;primaryKey"`
+	CustomerID int64        `gorm:"column:customer_id;index"`
+	CodeHash   string       `gorm:"column:code_hash"`
+	UsedAt     sql.NullTime `gorm:"column:used_at"`
+	CreatedAt  time.Time    `gorm:"column:created_at"`
+}
+
+func (c *RecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
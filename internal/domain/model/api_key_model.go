@@ -0,0 +1,76 @@
+package model
+
+import (
+	"cakestore/internal/domain/entity"
+	"slices"
+	"time"
+)
+
+type CreateAPIKeyRequest struct {
+	Name        string   `json:"name" validate:"required,max=100"`
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+	// ExpiresInDays leaves the key valid indefinitely when zero
+	ExpiresInDays int `json:"expires_in_days" validate:"omitempty,min=1"`
+	// RateLimit is in requests per minute, zero uses the server default
+	RateLimit int `json:"rate_limit" validate:"omitempty,min=1"`
+}
+
+type APIKeyResponse struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Permissions []string   `json:"permissions"`
+	RateLimit   int        `json:"rate_limit"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   int64      `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse carries the key itself, which is only shown once
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// APIKeyPrincipal is the caller of a request authenticated with an API key
+type APIKeyPrincipal struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions"`
+	RateLimit   int        `json:"rate_limit"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// HasPermissions reports whether the key was granted every one of the permissions
+func (p *APIKeyPrincipal) HasPermissions(permissions ...string) bool {
+	for _, permission := range permissions {
+		if !slices.Contains(p.Permissions, permission) {
+			return false
+		}
+	}
+	return true
+}
+
+func ToAPIKeyResponse(key *entity.APIKey) *APIKeyResponse {
+	response := &APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Permissions: key.PermissionNames(),
+		RateLimit:   key.RateLimit,
+		CreatedBy:   key.CreatedBy,
+		CreatedAt:   key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		response.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		response.LastUsedAt = &key.LastUsedAt.Time
+	}
+	if key.RevokedAt.Valid {
+		response.RevokedAt = &key.RevokedAt.Time
+	}
+	return response
+}
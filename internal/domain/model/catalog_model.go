@@ -0,0 +1,79 @@
+package model
+
+import (
+	"cakestore/internal/domain/entity"
+	"time"
+)
+
+// CatalogExport is the menu, inventory and table setup of a store. It is
+// written by the export command and read back by import, for example to copy
+// a staging catalog into production. Records carry no IDs: import matches
+// menus by title, inventory by name and tables by number.
+type CatalogExport struct {
+	ExportedAt time.Time              `json:"exported_at"`
+	Menus      []CatalogMenu          `json:"menus"`
+	Inventory  []CatalogInventoryItem `json:"inventory"`
+	Tables     []CatalogTable         `json:"tables"`
+}
+
+type CatalogMenu struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Quantity    int64   `json:"quantity"`
+	Category    string  `json:"category"`
+	Image       string  `json:"image"`
+}
+
+type CatalogInventoryItem struct {
+	Name         string  `json:"name"`
+	Quantity     float64 `json:"quantity"`
+	Unit         string  `json:"unit"`
+	MinimumStock float64 `json:"minimum_stock"`
+	ReorderPoint float64 `json:"reorder_point"`
+	UnitPrice    float64 `json:"unit_price"`
+}
+
+type CatalogTable struct {
+	TableNumber int `json:"table_number"`
+	Capacity    int `json:"capacity"`
+}
+
+// CatalogImportResult counts what an import created and updated
+type CatalogImportResult struct {
+	MenusCreated     int `json:"menus_created"`
+	MenusUpdated     int `json:"menus_updated"`
+	InventoryCreated int `json:"inventory_created"`
+	InventoryUpdated int `json:"inventory_updated"`
+	TablesCreated    int `json:"tables_created"`
+	TablesUpdated    int `json:"tables_updated"`
+}
+
+func ToCatalogMenu(menu *entity.Menu) CatalogMenu {
+	return CatalogMenu{
+		Title:       menu.Title,
+		Description: menu.Description,
+		Price:       menu.Price,
+		Quantity:    menu.Quantity,
+		Category:    menu.Category,
+		Image:       menu.Image,
+	}
+}
+
+func ToCatalogInventoryItem(item *entity.Inventory) CatalogInventoryItem {
+	return CatalogInventoryItem{
+		Name:         item.Name,
+		Quantity:     item.Quantity,
+		Unit:         item.Unit,
+		MinimumStock: item.MinimumStock,
+		ReorderPoint: item.ReorderPoint,
+		UnitPrice:    item.UnitPrice,
+	}
+}
+
+func ToCatalogTable(table *entity.Table) CatalogTable {
+	return CatalogTable{
+		TableNumber: table.TableNumber,
+		Capacity:    table.Capacity,
+	}
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"cakestore/internal/domain/entity"
+	"time"
+)
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// CustomerDataExport holds the personal data kept about a customer
+type CustomerDataExport struct {
+	ExportedAt   time.Time              `json:"exported_at"`
+	Profile      ExportedProfile        `json:"profile"`
+	Orders       []ExportedOrder        `json:"orders"`
+	Reservations []ExportedReservation  `json:"reservations"`
+	Wishlist     []ExportedWishlistItem `json:"wishlist"`
+	Carts        []ExportedCartItem     `json:"carts"`
+}
+
+type ExportedProfile struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Address         string     `json:"address"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type ExportedOrder struct {
+	ID         int64               `json:"id"`
+	Status     string              `json:"status"`
+	TotalPrice float64             `json:"total_price"`
+	Address    string              `json:"delivery_address"`
+	Items      []ExportedOrderItem `json:"items"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+type ExportedOrderItem struct {
+	MenuID    int64   `json:"menu_id"`
+	MenuTitle string  `json:"menu_title"`
+	Quantity  int64   `json:"quantity"`
+	Price     float64 `json:"price"`
+}
+
+type ExportedReservation struct {
+	ID           uint      `json:"id"`
+	TableNumber  int       `json:"table_number"`
+	GuestCount   int       `json:"guest_count"`
+	ReserveDate  time.Time `json:"reserve_date"`
+	Status       string    `json:"status"`
+	SpecialNotes string    `json:"special_notes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ExportedWishlistItem struct {
+	MenuID    int64     `json:"menu_id"`
+	MenuTitle string    `json:"menu_title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedCartItem struct {
+	MenuID    int64     `json:"menu_id"`
+	Quantity  int64     `json:"quantity"`
+	Price     float64   `json:"price"`
+	Subtotal  float64   `json:"subtotal"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ToExportedProfile(customer *entity.Customer) ExportedProfile {
+	profile := ExportedProfile{
+		ID:        customer.ID,
+		Name:      customer.Name,
+		Email:     customer.Email,
+		Address:   customer.Address,
+		Role:      customer.Role,
+		CreatedAt: customer.CreatedAt,
+		UpdatedAt: customer.UpdatedAt,
+	}
+	if customer.EmailVerifiedAt.Valid {
+		profile.EmailVerifiedAt = &customer.EmailVerifiedAt.Time
+	}
+	return profile
+}
+
+func ToExportedOrder(order *entity.Order) ExportedOrder {
+	exported := ExportedOrder{
+		ID:         order.ID,
+		Status:     string(order.Status),
+		TotalPrice: order.TotalPrice,
+		Address:    order.Address,
+		Items:      make([]ExportedOrderItem, 0, len(order.Items)),
+		CreatedAt:  order.CreatedAt,
+	}
+	for _, item := range order.Items {
+		exported.Items = append(exported.Items, ExportedOrderItem{
+			MenuID:    item.MenuID,
+			MenuTitle: item.Menu.Title,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+		})
+	}
+	return exported
+}
+
+func ToExportedReservation(reservation *entity.Reservation) ExportedReservation {
+	return ExportedReservation{
+		ID:           reservation.ID,
+		TableNumber:  reservation.TableNumber,
+		GuestCount:   reservation.GuestCount,
+		ReserveDate:  reservation.ReserveDate,
+		Status:       string(reservation.Status),
+		SpecialNotes: reservation.SpecialNotes,
+		CreatedAt:    reservation.CreatedAt,
+	}
+}
+
+func ToExportedWishlistItem(item *entity.WishList) ExportedWishlistItem {
+	return ExportedWishlistItem{
+		MenuID:    item.MenuID,
+		MenuTitle: item.Menu.Title,
+		CreatedAt: item.CreatedAt,
+	}
+}
+
+func ToExportedCartItem(cart *entity.Cart) ExportedCartItem {
+	return ExportedCartItem{
+		MenuID:    cart.MenuID,
+		Quantity:  cart.Quantity,
+		Price:     cart.Price,
+		Subtotal:  cart.Subtotal,
+		CreatedAt: cart.CreatedAt,
+	}
+}
@@ -1,12 +1,16 @@
 package model
 
-import "cakestore/internal/domain/entity"
+import (
+	"cakestore/internal/domain/entity"
+	"time"
+)
 
 type CustomerResponse struct {
-	ID      int64  `json:"id"`
-	Name    string `json:"name"`
-	Email   string `json:"email"`
-	Address string `json:"address"`
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Address       string `json:"address"`
+	EmailVerified bool   `json:"email_verified"`
 }
 
 type EmployeeResponse struct {
@@ -39,12 +43,39 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	// RefreshToken ends the session it belongs to; it may be omitted when AllSessions is set
+	RefreshToken string `json:"refresh_token"`
+	AllSessions  bool   `json:"all_sessions"`
+}
+
+// TokenResponse is returned by login, registration and refresh. The access
+// token is short-lived; the refresh token is single use and must be swapped
+// through /auth/refresh before it expires.
+type TokenResponse struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	ExpiresIn             int64     `json:"expires_in"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
 func ToCustomerResponse(customer *entity.Customer) *CustomerResponse {
 	return &CustomerResponse{
-		ID:      customer.ID,
-		Name:    customer.Name,
-		Email:   customer.Email,
-		Address: customer.Address,
+		ID:            customer.ID,
+		Name:          customer.Name,
+		Email:         customer.Email,
+		Address:       customer.Address,
+		EmailVerified: customer.EmailVerifiedAt.Valid,
 	}
 }
 
@@ -63,3 +94,32 @@ func ToLoginResponse(token string) *LoginResponse {
 		Token: token,
 	}
 }
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// CreateEmployeeRequest creates a staff account without a password. The
+// employee chooses one through the emailed invite link.
+type CreateEmployeeRequest struct {
+	Name    string `json:"name" validate:"required"`
+	Email   string `json:"email" validate:"required,email"`
+	Address string `json:"address"`
+	Role    string `json:"role" validate:"required"`
+}
+
+type AcceptInviteRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// UnlockLoginRequest lifts a login lockout for an email, an IP address or both
+type UnlockLoginRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+	IP    string `json:"ip" validate:"omitempty,ip"`
+}
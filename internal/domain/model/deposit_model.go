@@ -0,0 +1,47 @@
+package model
+
+import (
+	"cakestore/internal/domain/entity"
+	"time"
+)
+
+type ApplyDepositRequest struct {
+	OrderID int64 `json:"order_id" validate:"required,min=1"`
+}
+
+type DepositResponse struct {
+	ID             int64      `json:"id"`
+	ReservationID  uint       `json:"reservation_id"`
+	Amount         float64    `json:"amount"`
+	Status         string     `json:"status"`
+	PaymentToken   string     `json:"payment_token,omitempty"`
+	PaymentURL     string     `json:"payment_url,omitempty"`
+	DueAt          time.Time  `json:"due_at"`
+	PaidAt         *time.Time `json:"paid_at,omitempty"`
+	AppliedOrderID *int64     `json:"applied_order_id,omitempty"`
+	AppliedAmount  float64    `json:"applied_amount"`
+	RefundAmount   float64    `json:"refund_amount"`
+}
+
+func ToDepositResponse(deposit *entity.ReservationDeposit) *DepositResponse {
+	response := &DepositResponse{
+		ID:             deposit.ID,
+		ReservationID:  deposit.ReservationID,
+		Amount:         deposit.Amount,
+		Status:         string(deposit.Status),
+		DueAt:          deposit.DueAt,
+		AppliedOrderID: deposit.AppliedOrderID,
+		AppliedAmount:  deposit.AppliedAmount,
+		RefundAmount:   deposit.RefundAmount,
+	}
+	// The payment link is only useful while the deposit is still owed
+	if deposit.Status == entity.DepositStatusPending {
+		response.PaymentToken = deposit.PaymentToken
+		response.PaymentURL = deposit.PaymentURL
+	}
+	if deposit.PaidAt.Valid {
+		paidAt := deposit.PaidAt.Time
+		response.PaidAt = &paidAt
+	}
+	return response
+}
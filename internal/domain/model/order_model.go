@@ -5,11 +5,12 @@ import (
 	"time"
 )
 
+// OrderItemRequest names a menu and a quantity; the price always comes from
+// the menu, never from the client.
 type OrderItemRequest struct {
-	MenuID   int64   `json:"menu_id" validate:"required"`
-	Title    string  `json:"title" validate:"required"`
-	Quantity int64   `json:"quantity" validate:"required,min=1"`
-	Price    float64 `json:"price" validate:"required,min=0"`
+	MenuID   int64  `json:"menu_id" validate:"required"`
+	Title    string `json:"title" validate:"required"`
+	Quantity int64  `json:"quantity" validate:"required,min=1"`
 }
 
 type UpdateFoodStatusRequest struct {
@@ -34,6 +35,8 @@ type OrderResponse struct {
 	TotalPrice float64             `json:"total_price"`
 	Address    string              `json:"delivery_address"`
 	FoodStatus string              `json:"food_status"`
+	TableID    *int64              `json:"table_id,omitempty"`
+	SessionID  *int64              `json:"table_session_id,omitempty"`
 	Items      []OrderItemResponse `json:"items"`
 	CreatedAt  string              `json:"created_at"`
 	UpdatedAt  string              `json:"updated_at"`
@@ -66,6 +69,8 @@ func ToOrderResponse(order *entity.Order) *OrderResponse {
 		TotalPrice: order.TotalPrice,
 		FoodStatus: string(order.FoodStatus),
 		Address:    order.Address,
+		TableID:    order.TableID,
+		SessionID:  order.TableSessionID,
 		Items:      itemResponses,
 		CreatedAt:  order.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:  order.UpdatedAt.Format(time.RFC3339),
@@ -73,24 +78,28 @@ func ToOrderResponse(order *entity.Order) *OrderResponse {
 }
 
 func ToOrderEntity(order *OrderResponse) *entity.Order {
-	var item entity.OrderItem
-	for _, itemResponse := range order.Items {
-		item = entity.OrderItem{
+	items := make([]entity.OrderItem, len(order.Items))
+	for i, itemResponse := range order.Items {
+		items[i] = entity.OrderItem{
+			ID:       itemResponse.ID,
+			OrderID:  order.ID,
 			MenuID:   itemResponse.Menu.ID,
 			Quantity: itemResponse.Quantity,
 			Price:    itemResponse.Price,
 		}
 	}
 	return &entity.Order{
-		ID:         order.ID,
-		CustomerID: order.Customer.ID,
-		Customer:   entity.Customer{},
-		Status:     entity.OrderStatus(order.Status),
-		TotalPrice: order.TotalPrice,
-		FoodStatus: entity.FoodStatus(order.FoodStatus),
-		Address:    order.Address,
-		Items:      []entity.OrderItem{item},
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		ID:             order.ID,
+		CustomerID:     order.Customer.ID,
+		Customer:       entity.Customer{},
+		Status:         entity.OrderStatus(order.Status),
+		TotalPrice:     order.TotalPrice,
+		FoodStatus:     entity.FoodStatus(order.FoodStatus),
+		Address:        order.Address,
+		TableID:        order.TableID,
+		TableSessionID: order.SessionID,
+		Items:          items,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 }
@@ -12,9 +12,37 @@ type PaymentModel struct {
 	ID           int64                   `json:"id"`
 	OrderID      int64                   `json:"order_id"`
 	Amount       float64                 `json:"amount"`
+	Method       constants.PaymentMethod `json:"method"`
 	Status       constants.PaymentStatus `json:"status"`
+	Description  string                  `json:"description,omitempty"`
 	PaymentToken string                  `json:"payment_token"`
 	PaymentURL   string                  `json:"payment_url"`
+	CreatedAt    string                  `json:"created_at,omitempty"`
+}
+
+type PaymentSplitRequest struct {
+	Method  constants.PaymentMethod `json:"method" validate:"required,oneof=midtrans cash"`
+	Amount  float64                 `json:"amount" validate:"omitempty,gt=0"`
+	ItemIDs []int64                 `json:"item_ids"`
+	Label   string                  `json:"label"`
+}
+
+// SplitPaymentRequest splits the outstanding balance of an order.
+// Mode "even" divides it into len(Splits) equal parts, "item" charges each split
+// for the order items listed in ItemIDs and "custom" uses each split's Amount.
+type SplitPaymentRequest struct {
+	Mode   constants.SplitMode   `json:"mode" validate:"required,oneof=even item custom"`
+	Splits []PaymentSplitRequest `json:"splits" validate:"required,min=1,dive"`
+}
+
+type PaymentSummaryResponse struct {
+	OrderID       int64          `json:"order_id"`
+	TotalAmount   float64        `json:"total_amount"`
+	PaidAmount    float64        `json:"paid_amount"`
+	PendingAmount float64        `json:"pending_amount"`
+	Outstanding   float64        `json:"outstanding"`
+	IsFullyPaid   bool           `json:"is_fully_paid"`
+	Payments      []PaymentModel `json:"payments"`
 }
 
 type CreatePaymentRequest struct {
@@ -86,3 +114,17 @@ func ToPaymentEntity(paymentModel *PaymentModel) *entity.Payment {
 		UpdatedAt:    time.Now(),
 	}
 }
+
+func ToPaymentModel(payment *entity.Payment) *PaymentModel {
+	return &PaymentModel{
+		ID:           payment.ID,
+		OrderID:      payment.OrderID,
+		Amount:       payment.Amount,
+		Method:       payment.Method,
+		Status:       payment.Status,
+		Description:  payment.Description,
+		PaymentToken: payment.PaymentToken,
+		PaymentURL:   payment.PaymentURL,
+		CreatedAt:    payment.CreatedAt.Format(time.RFC3339),
+	}
+}
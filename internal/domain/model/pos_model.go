@@ -0,0 +1,83 @@
+package model
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"time"
+)
+
+// POSPaymentRequest records money taken at the till. Amount defaults to the
+// whole outstanding balance; cash needs the amount tendered, card the terminal reference.
+type POSPaymentRequest struct {
+	Method         constants.PaymentMethod `json:"method" validate:"required,oneof=cash card"`
+	Amount         float64                 `json:"amount" validate:"omitempty,gt=0"`
+	AmountTendered float64                 `json:"amount_tendered" validate:"required_if=Method cash,omitempty,gt=0"`
+	Reference      string                  `json:"reference" validate:"required_if=Method card,max=64"`
+}
+
+type ReceiptLine struct {
+	Title     string  `json:"title"`
+	Quantity  int64   `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Subtotal  float64 `json:"subtotal"`
+}
+
+type ReceiptOrder struct {
+	OrderID    int64         `json:"order_id"`
+	Address    string        `json:"delivery_address"`
+	Items      []ReceiptLine `json:"items"`
+	TotalPrice float64       `json:"total_price"`
+	AmountPaid float64       `json:"amount_paid"`
+}
+
+type ReceiptResponse struct {
+	Number         string                  `json:"receipt_number"`
+	IssuedAt       string                  `json:"issued_at"`
+	Cashier        string                  `json:"cashier"`
+	Method         constants.PaymentMethod `json:"method"`
+	Orders         []ReceiptOrder          `json:"orders"`
+	Amount         float64                 `json:"amount"`
+	AmountTendered float64                 `json:"amount_tendered"`
+	ChangeGiven    float64                 `json:"change_given"`
+	Reference      string                  `json:"reference,omitempty"`
+}
+
+// ToReceiptResponse renders a receipt; orders must hold every order the receipt paid for.
+func ToReceiptResponse(receipt *entity.Receipt, orders []entity.Order) *ReceiptResponse {
+	paid := make(map[int64]float64, len(receipt.Payments))
+	for _, payment := range receipt.Payments {
+		paid[payment.OrderID] += payment.Amount
+	}
+
+	receiptOrders := make([]ReceiptOrder, len(orders))
+	for i, order := range orders {
+		lines := make([]ReceiptLine, len(order.Items))
+		for j, item := range order.Items {
+			lines[j] = ReceiptLine{
+				Title:     item.Menu.Title,
+				Quantity:  item.Quantity,
+				UnitPrice: item.Price,
+				Subtotal:  item.Price * float64(item.Quantity),
+			}
+		}
+		receiptOrders[i] = ReceiptOrder{
+			OrderID:    order.ID,
+			Address:    order.Address,
+			Items:      lines,
+			TotalPrice: order.TotalPrice,
+			AmountPaid: paid[order.ID],
+		}
+	}
+
+	return &ReceiptResponse{
+		Number:         receipt.Number,
+		IssuedAt:       receipt.CreatedAt.Format(time.RFC3339),
+		Cashier:        receipt.Cashier.Name,
+		Method:         receipt.Method,
+		Orders:         receiptOrders,
+		Amount:         receipt.Amount,
+		AmountTendered: receipt.AmountTendered,
+		ChangeGiven:    receipt.ChangeGiven,
+		Reference:      receipt.Reference,
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"cakestore/internal/domain/entity"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -22,7 +23,7 @@ type CreateReservationRequest struct {
 }
 
 type UpdateReservationRequest struct {
-	Status       string    `json:"status" validate:"omitempty,oneof=pending confirmed cancelled completed"`
+	Status       string    `json:"status" validate:"omitempty,oneof=pending confirmed cancelled completed no_show"`
 	TableNumber  int       `json:"table_number"`
 	GuestCount   int       `json:"guest_count" validate:"omitempty,min=1"`
 	ReserveDate  time.Time `json:"reserve_date" validate:"omitempty,future"`
@@ -38,10 +39,41 @@ type ReservationResponse struct {
 	ReserveDate  time.Time        `json:"reserve_date"`
 	Status       string           `json:"status"`
 	SpecialNotes string           `json:"special_notes"`
+	ConfirmedAt  *time.Time       `json:"confirmed_at,omitempty"`
+	CheckedInAt  *time.Time       `json:"checked_in_at,omitempty"`
+	Deposit      *DepositResponse `json:"deposit,omitempty"`
 	CreatedAt    time.Time        `json:"created_at"`
 	UpdatedAt    time.Time        `json:"updated_at"`
 }
 
+type NoShowSummaryResponse struct {
+	CustomerID  uint  `json:"customer_id"`
+	NoShowCount int64 `json:"no_show_count"`
+	// Blocked is true once the count reaches the configured no-show limit
+	Blocked bool `json:"blocked"`
+}
+
+func ToReservationResponse(reservation *entity.Reservation) *ReservationResponse {
+	response := &ReservationResponse{
+		ID:           reservation.ID,
+		CustomerID:   reservation.CustomerID,
+		Customer:     *ToCustomerResponse(&reservation.Customer),
+		TableNumber:  reservation.TableNumber,
+		GuestCount:   reservation.GuestCount,
+		ReserveDate:  reservation.ReserveDate,
+		Status:       string(reservation.Status),
+		SpecialNotes: reservation.SpecialNotes,
+		ConfirmedAt:  reservation.ConfirmedAt,
+		CheckedInAt:  reservation.CheckedInAt,
+		CreatedAt:    reservation.CreatedAt,
+		UpdatedAt:    reservation.UpdatedAt,
+	}
+	if reservation.Deposit != nil {
+		response.Deposit = ToDepositResponse(reservation.Deposit)
+	}
+	return response
+}
+
 func (r *CreateReservationRequest) Validate() error {
 	validate := validator.New()
 
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"time"
+)
+
+type OpenShiftRequest struct {
+	OpeningFloat float64 `json:"opening_float" validate:"min=0"`
+}
+
+// ShiftTransactionRequest records money leaving the till. Payouts are always cash;
+// refunds default to cash unless the card terminal was used.
+type ShiftTransactionRequest struct {
+	Type    entity.ShiftTransactionType `json:"type" validate:"required,oneof=refund payout"`
+	Method  constants.PaymentMethod     `json:"method" validate:"omitempty,oneof=cash card"`
+	Amount  float64                     `json:"amount" validate:"required,gt=0"`
+	OrderID *int64                      `json:"order_id"`
+	Reason  string                      `json:"reason" validate:"required,max=255"`
+}
+
+type CloseShiftRequest struct {
+	CountedAmount float64 `json:"counted_amount" validate:"min=0"`
+	Notes         string  `json:"notes" validate:"max=255"`
+}
+
+type ShiftTransactionResponse struct {
+	ID        int64                       `json:"id"`
+	Type      entity.ShiftTransactionType `json:"type"`
+	Method    constants.PaymentMethod     `json:"method"`
+	Amount    float64                     `json:"amount"`
+	OrderID   *int64                      `json:"order_id,omitempty"`
+	Reason    string                      `json:"reason"`
+	CreatedAt string                      `json:"created_at"`
+}
+
+// ShiftResponse reports the drawer of a shift. ExpectedAmount is the opening
+// float plus cash sales minus cash refunds and payouts; for an open shift it is
+// the running figure and CountedAmount/Variance are not set yet.
+type ShiftResponse struct {
+	ID             int64                      `json:"id"`
+	CashierID      int64                      `json:"cashier_id"`
+	Cashier        string                     `json:"cashier"`
+	Status         entity.ShiftStatus         `json:"status"`
+	OpeningFloat   float64                    `json:"opening_float"`
+	CashSales      float64                    `json:"cash_sales"`
+	CardSales      float64                    `json:"card_sales"`
+	CashRefunds    float64                    `json:"cash_refunds"`
+	CardRefunds    float64                    `json:"card_refunds"`
+	Payouts        float64                    `json:"payouts"`
+	ExpectedAmount float64                    `json:"expected_amount"`
+	CountedAmount  *float64                   `json:"counted_amount,omitempty"`
+	Variance       *float64                   `json:"variance,omitempty"`
+	Notes          string                     `json:"notes,omitempty"`
+	OpenedAt       string                     `json:"opened_at"`
+	ClosedAt       string                     `json:"closed_at,omitempty"`
+	Transactions   []ShiftTransactionResponse `json:"transactions,omitempty"`
+}
+
+type MethodTotal struct {
+	Method constants.PaymentMethod `json:"method"`
+	Count  int                     `json:"count"`
+	Amount float64                 `json:"amount"`
+}
+
+// ZReportResponse is the end-of-day summary. Prices include tax, so Tax is the
+// share of NetSales collected as tax at TaxRate percent. Sales are counted on
+// the day the money was received. Orders are charged at menu prices, so there
+// are no discounts to report and NetSales is GrossSales less Refunds.
+type ZReportResponse struct {
+	Date            string          `json:"date"`
+	GrossSales      float64         `json:"gross_sales"`
+	SalesByMethod   []MethodTotal   `json:"sales_by_method"`
+	Refunds         float64         `json:"refunds"`
+	RefundsByMethod []MethodTotal   `json:"refunds_by_method"`
+	Payouts         float64         `json:"payouts"`
+	NetSales        float64         `json:"net_sales"`
+	TaxRate         float64         `json:"tax_rate"`
+	Tax             float64         `json:"tax"`
+	Shifts          []ShiftResponse `json:"shifts"`
+	CashVariance    float64         `json:"cash_variance"`
+}
+
+func ToShiftTransactionResponse(transaction *entity.ShiftTransaction) ShiftTransactionResponse {
+	return ShiftTransactionResponse{
+		ID:        transaction.ID,
+		Type:      transaction.Type,
+		Method:    transaction.Method,
+		Amount:    transaction.Amount,
+		OrderID:   transaction.OrderID,
+		Reason:    transaction.Reason,
+		CreatedAt: transaction.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -42,3 +42,29 @@ func ToTableResponse(table *entity.Table) *TableResponse {
 		UpdatedAt:   table.UpdatedAt,
 	}
 }
+
+type TableQRCodeResponse struct {
+	TableID     int64  `json:"table_id"`
+	TableNumber int    `json:"table_number"`
+	Version     int    `json:"version"`
+	Token       string `json:"token"`
+	URL         string `json:"url"`
+}
+
+type TableSessionResponse struct {
+	ID          int64     `json:"id"`
+	TableID     int64     `json:"table_id"`
+	TableNumber int       `json:"table_number"`
+	Status      string    `json:"status"`
+	OpenedAt    time.Time `json:"opened_at"`
+}
+
+func ToTableSessionResponse(session *entity.TableSession) *TableSessionResponse {
+	return &TableSessionResponse{
+		ID:          session.ID,
+		TableID:     session.TableID,
+		TableNumber: session.Table.TableNumber,
+		Status:      string(session.Status),
+		OpenedAt:    session.OpenedAt,
+	}
+}
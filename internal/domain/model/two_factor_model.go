@@ -0,0 +1,53 @@
+package model
+
+// LoginResult is either a session or, for accounts with two-factor
+// authentication, a challenge to complete before the session is issued.
+type LoginResult struct {
+	Tokens    *TokenResponse
+	Challenge *TwoFactorChallenge
+}
+
+type TwoFactorChallenge struct {
+	TwoFactorToken string `json:"two_factor_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+	// EnrollmentRequired is set when the account's role requires two-factor
+	// authentication but it has not been set up yet
+	EnrollmentRequired bool `json:"enrollment_required"`
+}
+
+type TwoFactorTokenRequest struct {
+	TwoFactorToken string `json:"two_factor_token" validate:"required"`
+}
+
+// TwoFactorLoginRequest completes a login challenge with an authenticator
+// code or a recovery code
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+	// QRCode is a PNG data URI of the otpauth URI
+	QRCode string `json:"qr_code"`
+}
+
+type TwoFactorActivation struct {
+	RecoveryCodes []string       `json:"recovery_codes"`
+	Session       *TokenResponse `json:"session,omitempty"`
+}
+
+type TwoFactorStatus struct {
+	Enabled  bool `json:"enabled"`
+	Required bool `json:"required"`
+}
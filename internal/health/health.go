@@ -1,11 +1,46 @@
+// Package health runs the dependency checks behind /readyz. Each subsystem
+// provides its own Checker and bootstrap registers it.
 package health
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"time"
+)
+
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
 
-	"gorm.io/gorm"
+	defaultTimeout  = 2 * time.Second
+	defaultCacheTTL = 5 * time.Second
 )
 
+// Checker checks one dependency. Details, when not nil, are reported next to
+// the result, for example connection pool figures.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) (details map[string]interface{}, err error)
+}
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c checkerFunc) Name() string { return c.name }
+
+func (c checkerFunc) Check(ctx context.Context) (map[string]interface{}, error) {
+	return nil, c.check(ctx)
+}
+
+// CheckerFunc turns a function into a Checker without details.
+func CheckerFunc(name string, check func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, check: check}
+}
+
 type HealthStatus struct {
 	Status    string            `json:"status"`
 	Timestamp time.Time         `json:"timestamp"`
@@ -13,58 +48,133 @@ type HealthStatus struct {
 }
 
 type Status struct {
-	Status    string        `json:"status"`
-	Latency   time.Duration `json:"latency"`
-	Error     string        `json:"error,omitempty"`
-	Timestamp time.Time     `json:"timestamp"`
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	Latency   time.Duration          `json:"latency"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Options tunes a Registry. Zero values fall back to a 2 second timeout per
+// check and results cached for 5 seconds.
+type Options struct {
+	Timeout  time.Duration
+	CacheTTL time.Duration
 }
 
-type HealthChecker struct {
-	db *gorm.DB
+type registration struct {
+	checker  Checker
+	critical bool
 }
 
-func NewHealthChecker(db *gorm.DB) *HealthChecker {
-	return &HealthChecker{
-		db: db,
+// Registry runs the registered checks in parallel, each bounded by the
+// timeout. The report is cached for CacheTTL, so probes from several load
+// balancers or a tight probe interval do not turn into a stream of queries.
+type Registry struct {
+	options Options
+	checks  []registration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	cached   HealthStatus
+	cachedAt time.Time
+}
+
+func NewRegistry(options Options) *Registry {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = defaultCacheTTL
 	}
+	return &Registry{options: options, now: time.Now}
 }
 
-func (h *HealthChecker) Check() HealthStatus {
-	health := HealthStatus{
-		Status:    "healthy",
-		Timestamp: time.Now(),
-		Services:  make(map[string]Status),
+// Register adds a check. A failing critical check makes the service
+// unhealthy and not ready; any other failing check only degrades it, for
+// dependencies the API can run without.
+func (r *Registry) Register(checker Checker, critical bool) {
+	r.checks = append(r.checks, registration{checker: checker, critical: critical})
+}
+
+// Check returns the cached report while it is fresh and runs every check
+// otherwise. Concurrent callers share one run.
+func (r *Registry) Check(ctx context.Context) HealthStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.cachedAt.IsZero() && r.now().Sub(r.cachedAt) < r.options.CacheTTL {
+		return r.cached
 	}
 
-	// Check database
-	dbStart := time.Now()
-	sqlDB, err := h.db.DB()
-	if err != nil {
-		health.Services["database"] = Status{
-			Status:    "unhealthy",
-			Latency:   time.Since(dbStart),
-			Error:     err.Error(),
-			Timestamp: time.Now(),
-		}
-		health.Status = "unhealthy"
-	} else {
-		err = sqlDB.Ping()
-		if err != nil {
-			health.Services["database"] = Status{
-				Status:    "unhealthy",
-				Latency:   time.Since(dbStart),
-				Error:     err.Error(),
-				Timestamp: time.Now(),
-			}
-			health.Status = "unhealthy"
-		} else {
-			health.Services["database"] = Status{
-				Status:    "healthy",
-				Latency:   time.Since(dbStart),
-				Timestamp: time.Now(),
-			}
+	report := HealthStatus{
+		Status:    StatusHealthy,
+		Timestamp: r.now(),
+		Services:  make(map[string]Status, len(r.checks)),
+	}
+	results := make([]Status, len(r.checks))
+	var wg sync.WaitGroup
+	for i, check := range r.checks {
+		wg.Add(1)
+		go func(i int, check registration) {
+			defer wg.Done()
+			results[i] = r.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for i, check := range r.checks {
+		result := results[i]
+		report.Services[check.checker.Name()] = result
+		switch {
+		case result.Status == StatusHealthy:
+		case check.critical:
+			report.Status = StatusUnhealthy
+		case report.Status == StatusHealthy:
+			report.Status = StatusDegraded
 		}
 	}
 
-	return health
+	r.cached, r.cachedAt = report, r.now()
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, check registration) Status {
+	ctx, cancel := context.WithTimeout(ctx, r.options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	type outcome struct {
+		details map[string]interface{}
+		err     error
+	}
+	// A check that ignores ctx still cannot hold up the report
+	done := make(chan outcome, 1)
+	go func() {
+		details, err := check.checker.Check(ctx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	var result outcome
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result.err = ctx.Err()
+	}
+
+	status := Status{
+		Status:    StatusHealthy,
+		Critical:  check.critical,
+		Latency:   time.Since(start),
+		Details:   result.details,
+		Timestamp: r.now(),
+	}
+	if result.err != nil {
+		status.Status = StatusUnhealthy
+		status.Error = result.err.Error()
+		if errors.Is(result.err, context.DeadlineExceeded) {
+			status.Error = "timed out after " + r.options.Timeout.String()
+		}
+	}
+	return status
 }
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_CriticalFailureMakesUnhealthy(t *testing.T) {
+	registry := NewRegistry(Options{})
+	registry.Register(CheckerFunc("postgres", func(context.Context) error { return errors.New("connection refused") }), true)
+	registry.Register(CheckerFunc("cache", func(context.Context) error { return nil }), false)
+
+	report := registry.Check(context.Background())
+
+	assert.Equal(t, StatusUnhealthy, report.Status)
+	assert.Equal(t, "connection refused", report.Services["postgres"].Error)
+	assert.True(t, report.Services["postgres"].Critical)
+	assert.Equal(t, StatusHealthy, report.Services["cache"].Status)
+}
+
+func TestRegistry_OptionalFailureOnlyDegrades(t *testing.T) {
+	registry := NewRegistry(Options{})
+	registry.Register(CheckerFunc("postgres", func(context.Context) error { return nil }), true)
+	registry.Register(CheckerFunc("cache", func(context.Context) error { return errors.New("cache unavailable") }), false)
+
+	assert.Equal(t, StatusDegraded, registry.Check(context.Background()).Status)
+}
+
+func TestRegistry_TimesOutSlowChecks(t *testing.T) {
+	registry := NewRegistry(Options{Timeout: 10 * time.Millisecond})
+	block := make(chan struct{})
+	defer close(block)
+	// Ignores its context, so only the registry's own timeout can end it
+	registry.Register(CheckerFunc("payment_gateway", func(context.Context) error {
+		<-block
+		return nil
+	}), true)
+
+	report := registry.Check(context.Background())
+
+	assert.Equal(t, StatusUnhealthy, report.Status)
+	assert.Equal(t, "timed out after 10ms", report.Services["payment_gateway"].Error)
+}
+
+func TestRegistry_CachesReport(t *testing.T) {
+	registry := NewRegistry(Options{CacheTTL: time.Minute})
+	now := time.Now()
+	registry.now = func() time.Time { return now }
+	var calls atomic.Int32
+	registry.Register(CheckerFunc("postgres", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}), true)
+
+	registry.Check(context.Background())
+	registry.Check(context.Background())
+	require.Equal(t, int32(1), calls.Load())
+
+	now = now.Add(time.Minute)
+	registry.Check(context.Background())
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,112 @@
+// Package lifecycle runs the long-lived parts of the API process and shuts
+// them down in order when the process is asked to stop.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultShutdownTimeout = 20 * time.Second
+
+// Service is a long-lived part of the process, such as the HTTP server or
+// the job scheduler.
+type Service struct {
+	Name string
+	// Run blocks while the service is up. It returns nil once Stop has been
+	// called, and an error if the service fails on its own.
+	Run func() error
+	// Stop makes Run return, finishing in-flight work until ctx expires.
+	Stop func(ctx context.Context) error
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// Manager starts services and stops them again, followed by the resources
+// they share.
+type Manager struct {
+	log      *logrus.Logger
+	timeout  time.Duration
+	services []Service
+	closers  []closer
+}
+
+// NewManager returns a manager that gives services timeout to stop, 20
+// seconds when timeout is not positive.
+func NewManager(log *logrus.Logger, timeout time.Duration) *Manager {
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	return &Manager{log: log, timeout: timeout}
+}
+
+// Add registers a service. Services stop in the order they were added, so
+// add the ones that take in work, like the HTTP server, first.
+func (m *Manager) Add(service Service) {
+	m.services = append(m.services, service)
+}
+
+// OnClose registers a resource to close once every service has stopped, in
+// the order registered.
+func (m *Manager) OnClose(name string, close func() error) {
+	m.closers = append(m.closers, closer{name: name, close: close})
+}
+
+type exit struct {
+	name string
+	err  error
+}
+
+// Run starts every service and blocks until ctx is cancelled, usually by a
+// signal, or until a service exits on its own. It then stops the services
+// one by one, all within the shutdown timeout, and closes the resources. A
+// resource is closed even when a service failed to stop in time.
+func (m *Manager) Run(ctx context.Context) error {
+	exits := make(chan exit, len(m.services))
+	for _, service := range m.services {
+		go func(service Service) {
+			exits <- exit{name: service.Name, err: service.Run()}
+		}(service)
+	}
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+		m.log.Info("Shutting down")
+	case exited := <-exits:
+		if exited.err == nil {
+			exited.err = errors.New("stopped unexpectedly")
+		}
+		m.log.Errorf("%s exited, shutting down: %v", exited.name, exited.err)
+		errs = append(errs, fmt.Errorf("%s: %w", exited.name, exited.err))
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	for _, service := range m.services {
+		start := time.Now()
+		if err := service.Stop(stopCtx); err != nil {
+			m.log.Errorf("Failed to stop %s: %v", service.Name, err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", service.Name, err))
+			continue
+		}
+		m.log.Infof("Stopped %s in %v", service.Name, time.Since(start).Round(time.Millisecond))
+	}
+
+	for _, c := range m.closers {
+		if err := c.close(); err != nil {
+			m.log.Errorf("Failed to close %s: %v", c.name, err)
+			errs = append(errs, fmt.Errorf("closing %s: %w", c.name, err))
+			continue
+		}
+		m.log.Infof("Closed %s", c.name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder keeps the order in which services stop and resources close
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// blockingService runs until it is stopped
+func blockingService(name string, events *recorder) Service {
+	stop := make(chan struct{})
+	return Service{
+		Name: name,
+		Run: func() error {
+			<-stop
+			return nil
+		},
+		Stop: func(context.Context) error {
+			events.add("stop " + name)
+			close(stop)
+			return nil
+		},
+	}
+}
+
+func TestManager_StopsServicesThenClosesInOrder(t *testing.T) {
+	events := &recorder{}
+	manager := NewManager(logrus.New(), time.Second)
+	manager.Add(blockingService("http", events))
+	manager.Add(blockingService("jobs", events))
+	manager.OnClose("postgres", func() error { events.add("close postgres"); return nil })
+	manager.OnClose("redis", func() error { events.add("close redis"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, manager.Run(ctx))
+	assert.Equal(t, []string{"stop http", "stop jobs", "close postgres", "close redis"}, events.events)
+}
+
+func TestManager_ServiceFailureShutsDownTheRest(t *testing.T) {
+	events := &recorder{}
+	manager := NewManager(logrus.New(), time.Second)
+	manager.Add(Service{
+		Name: "http",
+		Run:  func() error { return errors.New("address already in use") },
+		Stop: func(context.Context) error { return nil },
+	})
+	manager.Add(blockingService("jobs", events))
+	manager.OnClose("postgres", func() error { events.add("close postgres"); return nil })
+
+	err := manager.Run(context.Background())
+
+	assert.ErrorContains(t, err, "http: address already in use")
+	assert.Equal(t, []string{"stop jobs", "close postgres"}, events.events)
+}
+
+func TestManager_ClosesResourcesWhenStopTimesOut(t *testing.T) {
+	events := &recorder{}
+	manager := NewManager(logrus.New(), 10*time.Millisecond)
+	manager.Add(Service{
+		Name: "http",
+		Run:  func() error { select {} },
+		Stop: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	manager.OnClose("postgres", func() error { events.add("close postgres"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, manager.Run(ctx), context.DeadlineExceeded)
+	assert.Equal(t, []string{"close postgres"}, events.events)
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Order channels
+const (
+	ChannelOnline = "online"
+	ChannelDineIn = "dine_in"
+)
+
+// Webhook outcomes, from the status code the notification was answered with
+const (
+	WebhookProcessed    = "processed"
+	WebhookInvalid      = "invalid"
+	WebhookUnauthorized = "unauthorized"
+	WebhookFailed       = "failed"
+)
+
+// ReservationBooked is recorded for new reservations; later changes are
+// recorded under the status they moved to, such as cancelled or no_show.
+const ReservationBooked = "booked"
+
+// Order is what the business metrics need to know about an order.
+type Order struct {
+	Channel string
+	Total   float64
+	// Items holds the ordered quantities by menu category
+	Items map[string]int64
+}
+
+// Business records what happens in the shop, as opposed to the HTTP and
+// cache metrics. Use cases take it as a dependency; Prometheus exports it
+// and Noop discards it in tests and commands.
+type Business interface {
+	OrderCreated(order Order)
+	// OrderPaid also adds the order total to the revenue
+	OrderPaid(order Order)
+	OrderCancelled(order Order)
+	// GatewayRequest records one call to the payment gateway and whether it failed
+	GatewayRequest(operation string, took time.Duration, err error)
+	Webhook(outcome string)
+	CartItemAdded()
+	Reservation(event string)
+	LowStockIngredients(count int)
+	// KitchenQueue sets the number of orders waiting on the kitchen, by food status
+	KitchenQueue(depth map[string]int64)
+}
+
+var (
+	orders = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_orders_total",
+		Help: "Orders by event (created, paid or cancelled) and channel (online or dine_in).",
+	}, []string{"event", "channel"})
+
+	orderItems = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_order_items_total",
+		Help: "Ordered item quantities by order event and menu category.",
+	}, []string{"event", "category"})
+
+	revenue = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_revenue_total",
+		Help: "Total price of paid orders, by channel.",
+	}, []string{"channel"})
+
+	gatewayDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cakestore_payment_gateway_request_duration_seconds",
+		Help:    "Payment gateway request latency, by operation.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation"})
+
+	gatewayErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_payment_gateway_errors_total",
+		Help: "Failed payment gateway requests, by operation.",
+	}, []string{"operation"})
+
+	webhooks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_payment_webhooks_total",
+		Help: "Payment notifications by outcome (processed, invalid, unauthorized or failed).",
+	}, []string{"outcome"})
+
+	cartAdditions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cakestore_cart_additions_total",
+		Help: "Menus added to a cart.",
+	})
+
+	reservations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_reservations_total",
+		Help: "Reservations by event (booked, confirmed, cancelled, no_show or completed).",
+	}, []string{"event"})
+
+	lowStock = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cakestore_low_stock_ingredients",
+		Help: "Ingredients at or below their reorder point.",
+	})
+
+	kitchenQueue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cakestore_kitchen_queue",
+		Help: "Orders waiting on the kitchen, by food status (pending or cooking).",
+	}, []string{"food_status"})
+)
+
+// Prometheus records business metrics in the default registry, next to the
+// other metrics in this package.
+type Prometheus struct{}
+
+func (Prometheus) OrderCreated(order Order) {
+	recordOrder("created", order)
+}
+
+func (Prometheus) OrderPaid(order Order) {
+	recordOrder("paid", order)
+	revenue.WithLabelValues(order.Channel).Add(order.Total)
+}
+
+func (Prometheus) OrderCancelled(order Order) {
+	recordOrder("cancelled", order)
+}
+
+func recordOrder(event string, order Order) {
+	orders.WithLabelValues(event, order.Channel).Inc()
+	for category, quantity := range order.Items {
+		orderItems.WithLabelValues(event, category).Add(float64(quantity))
+	}
+}
+
+func (Prometheus) GatewayRequest(operation string, took time.Duration, err error) {
+	gatewayDuration.WithLabelValues(operation).Observe(took.Seconds())
+	if err != nil {
+		gatewayErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (Prometheus) Webhook(outcome string) {
+	webhooks.WithLabelValues(outcome).Inc()
+}
+
+func (Prometheus) CartItemAdded() {
+	cartAdditions.Inc()
+}
+
+func (Prometheus) Reservation(event string) {
+	reservations.WithLabelValues(event).Inc()
+}
+
+func (Prometheus) LowStockIngredients(count int) {
+	lowStock.Set(float64(count))
+}
+
+func (Prometheus) KitchenQueue(depth map[string]int64) {
+	for status, count := range depth {
+		kitchenQueue.WithLabelValues(status).Set(float64(count))
+	}
+}
+
+// Noop discards every business metric.
+type Noop struct{}
+
+func (Noop) OrderCreated(Order)                          {}
+func (Noop) OrderPaid(Order)                             {}
+func (Noop) OrderCancelled(Order)                        {}
+func (Noop) GatewayRequest(string, time.Duration, error) {}
+func (Noop) Webhook(string)                              {}
+func (Noop) CartItemAdded()                              {}
+func (Noop) Reservation(string)                          {}
+func (Noop) LowStockIngredients(int)                     {}
+func (Noop) KitchenQueue(map[string]int64)               {}
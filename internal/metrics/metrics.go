@@ -0,0 +1,57 @@
+// Package metrics holds the application's own Prometheus metrics. They are
+// registered with the default registry, which fiberprometheus serves at
+// /metrics next to the HTTP request metrics.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	LoginFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cakestore_login_failures_total",
+		Help: "Failed login attempts.",
+	})
+
+	LoginLockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_login_lockouts_total",
+		Help: "Temporary login lockouts, by scope (email or ip).",
+	}, []string{"scope"})
+
+	LoginBlocked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_login_blocked_total",
+		Help: "Login attempts rejected before checking the password, by reason (locked or throttled).",
+	}, []string{"reason"})
+
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_cache_lookups_total",
+		Help: "Cache reads, by backend (redis, memory or none) and result (hit, miss or error).",
+	}, []string{"backend", "result"})
+
+	CacheErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cakestore_cache_errors_total",
+		Help: "Failed cache operations, by backend and operation.",
+	}, []string{"backend", "operation"})
+
+	CacheCircuitOpen = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cakestore_cache_circuit_open",
+		Help: "1 while Redis is bypassed after repeated errors, 0 otherwise.",
+	})
+)
+
+// RegisterDBStats exports the sql.DBStats of db as the go_sql_* metrics,
+// such as go_sql_in_use_connections and go_sql_wait_count_total, labelled
+// with db_name. Registering the same name twice is a no-op.
+func RegisterDBStats(db *sql.DB, name string) error {
+	err := prometheus.Register(collectors.NewDBStatsCollector(db, name))
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+	return err
+}
@@ -1,18 +1,29 @@
 package middleware
 
 import (
-	"cakestore/utils"
-	"fmt"
+	"cakestore/internal/auth"
+	"cakestore/internal/constants"
+	"cakestore/internal/usecase"
+	"errors"
 	"log"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(jwtSecret string) fiber.Handler {
+// AuthMiddleware validates the bearer access token. When sessions is set, tokens
+// revoked by logout, a password change or an employee deletion are rejected,
+// and every token is refused with 503 while revocations cannot be read.
+// When apiKeys is set, partner systems may send an X-API-Key header instead;
+// such requests carry only the key's permissions and no customer.
+func AuthMiddleware(verifier auth.TokenVerifier, sessions usecase.SessionUseCase, apiKeys usecase.APIKeyUseCase) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
+		if apiKey := c.Get(constants.APIKeyHeader); apiKey != "" && authHeader == "" && apiKeys != nil {
+			return authenticateAPIKey(c, apiKeys, apiKey)
+		}
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"message": "Missing authorization header",
@@ -21,27 +32,64 @@ func AuthMiddleware(jwtSecret string) fiber.Handler {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		claims := &utils.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := verifier.Verify(tokenString)
+		if err != nil {
 			log.Println(err.Error())
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"message": "Invalid or expired token",
 			})
 		}
 
+		if sessions != nil {
+			revoked, err := sessions.IsRevoked(c.UserContext(), claims.CustomerID, claims.ID, claims.IssuedAtTime())
+			if err != nil {
+				log.Println(err.Error())
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+					"message": "Unable to check session, try again later",
+				})
+			}
+			if revoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"message": "Token has been revoked",
+				})
+			}
+		}
+
 		// Use data from custom claims
-		c.Locals("email", claims.Email)
-		c.Locals("name", claims.Name)
-		c.Locals("customer_id", claims.CustomerID)
-		c.Locals("role", claims.Role)
+		c.Locals(constants.ClaimsKeyEmail, claims.Email)
+		c.Locals(constants.ClaimsKeyName, claims.Name)
+		c.Locals(constants.ClaimsKeyID, claims.CustomerID)
+		c.Locals(constants.ClaimsKeyRole, claims.Role)
+		c.Locals(constants.ClaimsKeyTokenID, claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Locals(constants.ClaimsKeyTokenExpiry, claims.ExpiresAt.Time)
+		}
 
 		return c.Next()
 	}
 }
+
+func authenticateAPIKey(c *fiber.Ctx, apiKeys usecase.APIKeyUseCase, rawKey string) error {
+	principal, err := apiKeys.Authenticate(c.UserContext(), rawKey)
+	if err != nil {
+		var limited *usecase.RateLimitedError
+		switch {
+		case errors.As(err, &limited):
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"message": limited.Error(),
+			})
+		case errors.Is(err, constants.ErrInvalidAPIKey):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid or expired API key",
+			})
+		}
+		log.Println(err.Error())
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"message": "Unable to check API key, try again later",
+		})
+	}
+
+	c.Locals(constants.LocalsKeyAPIKey, principal)
+	return c.Next()
+}
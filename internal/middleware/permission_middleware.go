@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/usecase"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequirePermission lets the request through when the role from the access
+// token, or the API key, grants every one of the permissions. It must run
+// after AuthMiddleware. Role permissions are looked up per request, so role
+// edits apply to existing tokens.
+func RequirePermission(roles usecase.RoleUseCase, permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key, ok := c.Locals(constants.LocalsKeyAPIKey).(*model.APIKeyPrincipal); ok {
+			return admitAPIKey(c, key, permissions)
+		}
+
+		role, _ := c.Locals(constants.ClaimsKeyRole).(string)
+
+		allowed, err := roles.HasPermissions(c.UserContext(), role, permissions...)
+		if err != nil {
+			log.Println(err.Error())
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "Unable to check permissions, try again later",
+			})
+		}
+		if !allowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You don't have permission to access this resource",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// AllowAPIKey admits API keys holding every one of the permissions to a route
+// that checks access for customers itself. Other requests pass unchanged.
+func AllowAPIKey(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key, ok := c.Locals(constants.LocalsKeyAPIKey).(*model.APIKeyPrincipal); ok {
+			return admitAPIKey(c, key, permissions)
+		}
+		return c.Next()
+	}
+}
+
+// RejectUnadmittedAPIKey refuses API-key requests that no RequirePermission or
+// AllowAPIKey admitted, so a key only reaches routes that declare a permission.
+func RejectUnadmittedAPIKey(c *fiber.Ctx) error {
+	if _, ok := c.Locals(constants.LocalsKeyAPIKey).(*model.APIKeyPrincipal); ok {
+		if admitted, _ := c.Locals(constants.LocalsKeyAPIKeyAdmitted).(bool); !admitted {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You don't have permission to access this resource",
+			})
+		}
+	}
+	return c.Next()
+}
+
+func admitAPIKey(c *fiber.Ctx, key *model.APIKeyPrincipal, permissions []string) error {
+	if len(permissions) == 0 || !key.HasPermissions(permissions...) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You don't have permission to access this resource",
+		})
+	}
+	c.Locals(constants.LocalsKeyAPIKeyAdmitted, true)
+	return c.Next()
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/usecase"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TableTokenMiddleware authenticates dine-in guests by the signed QR token printed on their table
+// instead of a JWT, and exposes the guest account the same way AuthMiddleware exposes a customer.
+func TableTokenMiddleware(tableSessionUseCase usecase.TableSessionUseCase) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Get(constants.TableTokenHeader)
+		if token == "" {
+			token = c.Query(constants.TableTokenQuery)
+		}
+		if token == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Missing table token",
+			})
+		}
+
+		session, err := tableSessionUseCase.ResolveToken(c.UserContext(), token)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid or expired table token",
+			})
+		}
+
+		c.Locals(constants.LocalsKeyTableSession, session)
+		c.Locals(constants.ClaimsKeyID, session.GuestCustomerID)
+		c.Locals(constants.ClaimsKeyRole, constants.RoleGuest)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type logSender struct {
+	log *logrus.Logger
+}
+
+func NewLogSender(log *logrus.Logger) Sender {
+	return &logSender{log: log}
+}
+
+func (s *logSender) Send(ctx context.Context, message Message) error {
+	s.log.WithFields(logrus.Fields{
+		"to":      message.To,
+		"subject": message.Subject,
+	}).Info(message.Body)
+	return nil
+}
@@ -0,0 +1,50 @@
+// Package notification delivers customer-facing messages such as reservation
+// confirmations, reminders and account emails. Senders are pluggable so
+// production can send real email over SMTP while local development logs or
+// writes messages to a file.
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DriverLog  = "log"
+	DriverFile = "file"
+	DriverSMTP = "smtp"
+)
+
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type Config struct {
+	Driver   string
+	FilePath string
+	SMTP     SMTPConfig
+}
+
+// NewSender builds the sender selected by NOTIFICATION_DRIVER, defaulting to the log sender
+func NewSender(cfg Config, log *logrus.Logger) (Sender, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return NewLogSender(log), nil
+	case DriverFile:
+		return NewFileSender(cfg.FilePath)
+	case DriverSMTP:
+		return NewSMTPSender(cfg.SMTP)
+	default:
+		return nil, fmt.Errorf("unknown notification driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,75 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig points the SMTP sender at a mail server. Username and Password
+// are optional; when set, PLAIN auth is used, which net/smtp only allows over
+// TLS or to localhost.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type smtpSender struct {
+	cfg      SMTPConfig
+	addr     string
+	envelope string
+	auth     smtp.Auth
+}
+
+func NewSMTPSender(cfg SMTPConfig) (Sender, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, fmt.Errorf("smtp sender needs a host and a from address")
+	}
+	// From may carry a display name, e.g. "CakeStore <no-reply@example.com>"
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP from address %q: %w", cfg.From, err)
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &smtpSender{
+		cfg:      cfg,
+		addr:     net.JoinHostPort(cfg.Host, fmt.Sprintf("%d", cfg.Port)),
+		envelope: from.Address,
+		auth:     auth,
+	}, nil
+}
+
+func (s *smtpSender) Send(ctx context.Context, message Message) error {
+	if message.SentAt.IsZero() {
+		message.SentAt = time.Now()
+	}
+	if strings.ContainsAny(message.To+message.Subject, "\r\n") {
+		return fmt.Errorf("invalid header value in message to %q", message.To)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", message.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", message.SentAt.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(s.addr, s.auth, s.envelope, []string{message.To}, []byte(msg.String()))
+}
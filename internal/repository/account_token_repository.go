@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type AccountTokenRepository interface {
+	Create(ctx context.Context, token *entity.AccountToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.AccountToken, error)
+	// MarkUsed reports false when the token had already been used
+	MarkUsed(ctx context.Context, id int64) (bool, error)
+	// InvalidateUnused retires every outstanding token of the purpose, so only the newest link works
+	InvalidateUnused(ctx context.Context, customerID int64, purpose entity.AccountTokenPurpose) error
+}
+
+type accountTokenRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewAccountTokenRepository(db *gorm.DB, log *logrus.Logger) AccountTokenRepository {
+	return &accountTokenRepository{db: db, log: log}
+}
+
+func (r *accountTokenRepository) Create(ctx context.Context, token *entity.AccountToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		r.log.WithError(err).Error("Failed to create account token")
+		return err
+	}
+	return nil
+}
+
+func (r *accountTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.AccountToken, error) {
+	var token entity.AccountToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get account token")
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *accountTokenRepository) MarkUsed(ctx context.Context, id int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.AccountToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to mark account token used")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *accountTokenRepository) InvalidateUnused(ctx context.Context, customerID int64, purpose entity.AccountTokenPurpose) error {
+	if err := r.db.WithContext(ctx).Model(&entity.AccountToken{}).
+		Where("customer_id = ? AND purpose = ? AND used_at IS NULL", customerID, purpose).
+		Update("used_at", time.Now()).Error; err != nil {
+		r.log.WithError(err).Error("Failed to invalidate account tokens")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *entity.APIKey) error
+	GetAll(ctx context.Context) ([]entity.APIKey, error)
+	GetByID(ctx context.Context, id int64) (*entity.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+	// Revoke reports false when the key was already revoked
+	Revoke(ctx context.Context, id int64, at time.Time) (bool, error)
+	// TouchLastUsed records a use unless one was already recorded since notBefore
+	TouchLastUsed(ctx context.Context, id int64, at time.Time, notBefore time.Time) error
+}
+
+type apiKeyRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewAPIKeyRepository(db *gorm.DB, log *logrus.Logger) APIKeyRepository {
+	return &apiKeyRepository{db: db, log: log}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		r.log.WithError(err).Error("Failed to create API key")
+		return err
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) GetAll(ctx context.Context) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	if err := r.db.WithContext(ctx).Preload("Permissions").Order("created_at DESC").Find(&keys).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get API keys")
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) GetByID(ctx context.Context, id int64) (*entity.APIKey, error) {
+	var key entity.APIKey
+	if err := r.db.WithContext(ctx).Preload("Permissions").First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get API key")
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get API key")
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to revoke API key")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time, notBefore time.Time) error {
+	err := r.db.WithContext(ctx).Model(&entity.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, notBefore).
+		Update("last_used_at", at).Error
+	if err != nil {
+		r.log.WithError(err).Error("Failed to record API key use")
+		return err
+	}
+	return nil
+}
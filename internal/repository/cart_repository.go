@@ -3,21 +3,22 @@ package repository
 import (
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type CartRepository interface {
-	Create(cart *entity.Cart) error
-	GetByID(id int64) (*entity.Cart, error)
-	GetByCustomerID(customerID int64, params *model.PaginationQuery) (*model.PaginationResponse[[]model.UserCartResponse], error)
-	GetByCustomerIDAndMenuID(customerID int64, menuID int64) (*entity.Cart, error)
-	Update(cart *entity.Cart) error
-	Delete(cartID int64) error
-	RemoveItem(customerID int64, cartID int64) error
-	ClearCustomerCart(customerID int64) error
-	BulkDelete(customerID int64, cartIDs []int64) error
+	Create(ctx context.Context, cart *entity.Cart) error
+	GetByID(ctx context.Context, id int64) (*entity.Cart, error)
+	GetByCustomerID(ctx context.Context, customerID int64, params *model.PaginationQuery) (*model.PaginationResponse[[]model.UserCartResponse], error)
+	GetByCustomerIDAndMenuID(ctx context.Context, customerID int64, menuID int64) (*entity.Cart, error)
+	Update(ctx context.Context, cart *entity.Cart) error
+	Delete(ctx context.Context, cartID int64) error
+	RemoveItem(ctx context.Context, customerID int64, cartID int64) error
+	ClearCustomerCart(ctx context.Context, customerID int64) error
+	BulkDelete(ctx context.Context, customerID int64, cartIDs []int64) error
 }
 
 type cartRepository struct {
@@ -32,24 +33,24 @@ func NewCartRepository(db *gorm.DB, logger *logrus.Logger) CartRepository {
 	}
 }
 
-func (r *cartRepository) Create(cart *entity.Cart) error {
-	if err := r.db.Create(cart).Error; err != nil {
+func (r *cartRepository) Create(ctx context.Context, cart *entity.Cart) error {
+	if err := r.db.WithContext(ctx).Create(cart).Error; err != nil {
 		r.logger.Errorf("cartRepository.Create - failed to create cart: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *cartRepository) GetByID(id int64) (*entity.Cart, error) {
+func (r *cartRepository) GetByID(ctx context.Context, id int64) (*entity.Cart, error) {
 	var cart entity.Cart
-	if err := r.db.First(&cart, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&cart, id).Error; err != nil {
 		r.logger.Errorf("cartRepository.GetByID - failed to get cart with ID %d: %v", id, err)
 		return nil, err
 	}
 	return &cart, nil
 }
 
-func (r *cartRepository) GetByCustomerID(customerID int64, params *model.PaginationQuery) (*model.PaginationResponse[[]model.UserCartResponse], error) {
+func (r *cartRepository) GetByCustomerID(ctx context.Context, customerID int64, params *model.PaginationQuery) (*model.PaginationResponse[[]model.UserCartResponse], error) {
 	var carts []model.UserCartResponse
 	var total int64
 	var perPage int64
@@ -59,7 +60,7 @@ func (r *cartRepository) GetByCustomerID(customerID int64, params *model.Paginat
 		page = int64(params.Page)
 	}
 
-	query := r.db.Model(&entity.Cart{}).
+	query := r.db.WithContext(ctx).Model(&entity.Cart{}).
 		Select("carts.id, menus.title as menu_name, menus.image as menu_image, carts.customer_id, carts.menu_id, carts.quantity, carts.price, carts.subtotal, carts.created_at, carts.updated_at").
 		Joins("JOIN menus ON menus.id = carts.menu_id").
 		Where("carts.customer_id = ?", customerID)
@@ -88,32 +89,32 @@ func (r *cartRepository) GetByCustomerID(customerID int64, params *model.Paginat
 	}, nil
 }
 
-func (r *cartRepository) GetByCustomerIDAndMenuID(customerID int64, menuID int64) (*entity.Cart, error) {
+func (r *cartRepository) GetByCustomerIDAndMenuID(ctx context.Context, customerID int64, menuID int64) (*entity.Cart, error) {
 	var cart entity.Cart
-	if err := r.db.Where("customer_id = ? AND menu_id = ?", customerID, menuID).First(&cart).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("customer_id = ? AND menu_id = ?", customerID, menuID).First(&cart).Error; err != nil {
 		r.logger.Errorf("cartRepository.GetByCustomerIDAndMenuID - failed to get cart for customer ID %d and menu ID %d: %v", customerID, menuID, err)
 		return nil, err
 	}
 	return &cart, nil
 }
 
-func (r *cartRepository) Update(cart *entity.Cart) error {
-	if err := r.db.Save(cart).Error; err != nil {
+func (r *cartRepository) Update(ctx context.Context, cart *entity.Cart) error {
+	if err := r.db.WithContext(ctx).Save(cart).Error; err != nil {
 		r.logger.Errorf("cartRepository.Update - failed to update cart with ID %d: %v", cart.ID, err)
 		return err
 	}
 	return nil
 }
 
-func (r *cartRepository) Delete(cartID int64) error {
-	if err := r.db.Delete(&entity.Cart{}, cartID).Error; err != nil {
+func (r *cartRepository) Delete(ctx context.Context, cartID int64) error {
+	if err := r.db.WithContext(ctx).Delete(&entity.Cart{}, cartID).Error; err != nil {
 		r.logger.Errorf("cartRepository.Delete - failed to delete cart with ID %d: %v", cartID, err)
 		return err
 	}
 	return nil
 }
 
-func (r *cartRepository) RemoveItem(customerID int64, cartID int64) error {
+func (r *cartRepository) RemoveItem(ctx context.Context, customerID int64, cartID int64) error {
 	type result struct {
 		Quantity int64
 		Subtotal float64
@@ -122,7 +123,7 @@ func (r *cartRepository) RemoveItem(customerID int64, cartID int64) error {
 	var res result
 
 	// Retrieve quantity and subtotal
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Model(&entity.Cart{}).
 		Where("id = ? AND customer_id = ?", cartID, customerID).
 		Select("quantity", "subtotal").
@@ -133,7 +134,7 @@ func (r *cartRepository) RemoveItem(customerID int64, cartID int64) error {
 
 	// If only 1 item, delete the cart item
 	if res.Quantity <= 1 {
-		if err := r.db.
+		if err := r.db.WithContext(ctx).
 			Where("id = ? AND customer_id = ?", cartID, customerID).
 			Delete(&entity.Cart{}).Error; err != nil {
 			r.logger.Errorf("cartRepository.RemoveItem - failed to delete cart with ID %d: %v", cartID, err)
@@ -142,7 +143,7 @@ func (r *cartRepository) RemoveItem(customerID int64, cartID int64) error {
 	} else {
 		// Update: decrement quantity and subtotal
 		newSubtotal := res.Subtotal / float64(res.Quantity)
-		if err := r.db.
+		if err := r.db.WithContext(ctx).
 			Model(&entity.Cart{}).
 			Where("id = ? AND customer_id = ?", cartID, customerID).
 			Updates(map[string]interface{}{
@@ -157,8 +158,8 @@ func (r *cartRepository) RemoveItem(customerID int64, cartID int64) error {
 	return nil
 }
 
-func (r *cartRepository) ClearCustomerCart(customerID int64) error {
-	result := r.db.Where("customer_id = ?", customerID).Delete(&entity.Cart{})
+func (r *cartRepository) ClearCustomerCart(ctx context.Context, customerID int64) error {
+	result := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Delete(&entity.Cart{})
 	if result.Error != nil {
 		r.logger.Errorf("cartRepository.ClearCustomerCart - failed to clear carts for customer ID %d: %v", customerID, result.Error)
 		return result.Error
@@ -170,8 +171,8 @@ func (r *cartRepository) ClearCustomerCart(customerID int64) error {
 	return nil
 }
 
-func (r *cartRepository) BulkDelete(customerID int64, cartIDs []int64) error {
-	result := r.db.Where("customer_id = ? AND id IN (?)", customerID, cartIDs).Delete(&entity.Cart{})
+func (r *cartRepository) BulkDelete(ctx context.Context, customerID int64, cartIDs []int64) error {
+	result := r.db.WithContext(ctx).Where("customer_id = ? AND id IN (?)", customerID, cartIDs).Delete(&entity.Cart{})
 	if result.Error != nil {
 		r.logger.Errorf("cartRepository.BulkDelete - failed to delete carts for customer ID %d and cart IDs %v: %v", customerID, cartIDs, result.Error)
 		return result.Error
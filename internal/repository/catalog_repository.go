@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// CatalogRepository reads and writes the menus, inventory and tables as a
+// whole, for the export and import commands
+type CatalogRepository interface {
+	GetMenus(ctx context.Context) ([]entity.Menu, error)
+	GetInventory(ctx context.Context) ([]entity.Inventory, error)
+	GetTables(ctx context.Context) ([]entity.Table, error)
+	// Import creates or updates every record in one transaction, matching
+	// menus by title, inventory by name and tables by number. The IDs of the
+	// saved records are written back into the slices.
+	Import(ctx context.Context, menus []entity.Menu, inventory []entity.Inventory, tables []entity.Table) (*model.CatalogImportResult, error)
+}
+
+type catalogRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewCatalogRepository(db *gorm.DB, log *logrus.Logger) CatalogRepository {
+	return &catalogRepository{db: db, log: log}
+}
+
+func (r *catalogRepository) GetMenus(ctx context.Context) ([]entity.Menu, error) {
+	var menus []entity.Menu
+	if err := r.db.WithContext(ctx).Where("deleted_at IS NULL").Order("id").Find(&menus).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get menus")
+		return nil, err
+	}
+	return menus, nil
+}
+
+func (r *catalogRepository) GetInventory(ctx context.Context) ([]entity.Inventory, error) {
+	var inventory []entity.Inventory
+	if err := r.db.WithContext(ctx).Order("id").Find(&inventory).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get inventory")
+		return nil, err
+	}
+	return inventory, nil
+}
+
+func (r *catalogRepository) GetTables(ctx context.Context) ([]entity.Table, error) {
+	var tables []entity.Table
+	if err := r.db.WithContext(ctx).Order("table_number").Find(&tables).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get tables")
+		return nil, err
+	}
+	return tables, nil
+}
+
+func (r *catalogRepository) Import(ctx context.Context, menus []entity.Menu, inventory []entity.Inventory, tables []entity.Table) (*model.CatalogImportResult, error) {
+	result := &model.CatalogImportResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		for i := range menus {
+			menu := &menus[i]
+			var existing entity.Menu
+			err := tx.Where("title = ? AND deleted_at IS NULL", menu.Title).First(&existing).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				menu.CreatedAt, menu.UpdatedAt = now, now
+				if err := tx.Create(menu).Error; err != nil {
+					r.log.WithError(err).Errorf("Failed to create menu %q", menu.Title)
+					return err
+				}
+				result.MenusCreated++
+			case err != nil:
+				r.log.WithError(err).Errorf("Failed to look up menu %q", menu.Title)
+				return err
+			default:
+				menu.ID, menu.Rating, menu.CreatedAt, menu.UpdatedAt = existing.ID, existing.Rating, existing.CreatedAt, now
+				if err := tx.Save(menu).Error; err != nil {
+					r.log.WithError(err).Errorf("Failed to update menu %q", menu.Title)
+					return err
+				}
+				result.MenusUpdated++
+			}
+		}
+
+		for i := range inventory {
+			item := &inventory[i]
+			var existing entity.Inventory
+			err := tx.Where("name = ?", item.Name).First(&existing).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				item.LastRestockDate, item.CreatedAt, item.UpdatedAt = now, now, now
+				if err := tx.Create(item).Error; err != nil {
+					r.log.WithError(err).Errorf("Failed to create inventory item %q", item.Name)
+					return err
+				}
+				result.InventoryCreated++
+			case err != nil:
+				r.log.WithError(err).Errorf("Failed to look up inventory item %q", item.Name)
+				return err
+			default:
+				item.ID, item.LastRestockDate, item.CreatedAt, item.UpdatedAt = existing.ID, existing.LastRestockDate, existing.CreatedAt, now
+				if err := tx.Save(item).Error; err != nil {
+					r.log.WithError(err).Errorf("Failed to update inventory item %q", item.Name)
+					return err
+				}
+				result.InventoryUpdated++
+			}
+		}
+
+		// Table numbers stay unique across deleted tables, so a deleted
+		// table with the same number is restored rather than duplicated
+		for i := range tables {
+			table := &tables[i]
+			var existing entity.Table
+			err := tx.Unscoped().Where("table_number = ?", table.TableNumber).First(&existing).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				table.IsAvailable, table.QRTokenVersion = true, 1
+				if err := tx.Create(table).Error; err != nil {
+					r.log.WithError(err).Errorf("Failed to create table %d", table.TableNumber)
+					return err
+				}
+				result.TablesCreated++
+			case err != nil:
+				r.log.WithError(err).Errorf("Failed to look up table %d", table.TableNumber)
+				return err
+			default:
+				table.ID = existing.ID
+				if err := tx.Unscoped().Model(&existing).Updates(map[string]interface{}{
+					"capacity":   table.Capacity,
+					"deleted_at": nil,
+				}).Error; err != nil {
+					r.log.WithError(err).Errorf("Failed to update table %d", table.TableNumber)
+					return err
+				}
+				result.TablesUpdated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"cakestore/internal/domain/entity"
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// CustomerDataRepository reads and erases the personal data held about a
+// customer across tables, for data exports and account deletion
+type CustomerDataRepository interface {
+	GetOrders(ctx context.Context, customerID int64) ([]entity.Order, error)
+	GetReservations(ctx context.Context, customerID int64) ([]entity.Reservation, error)
+	GetWishlist(ctx context.Context, customerID int64) ([]entity.WishList, error)
+	GetCarts(ctx context.Context, customerID int64) ([]entity.Cart, error)
+	// Anonymize saves the scrubbed customer and, in the same transaction,
+	// clears delivery addresses and reservation notes, deletes the wishlist,
+	// carts and two-factor enrolment, and retires unused emailed links.
+	// Orders and reservations are kept so sales figures stay correct.
+	Anonymize(ctx context.Context, customer *entity.Customer) error
+}
+
+type customerDataRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewCustomerDataRepository(db *gorm.DB, log *logrus.Logger) CustomerDataRepository {
+	return &customerDataRepository{db: db, log: log}
+}
+
+func (r *customerDataRepository) GetOrders(ctx context.Context, customerID int64) ([]entity.Order, error) {
+	var orders []entity.Order
+	if err := r.db.WithContext(ctx).Preload("Items.Menu").Where("customer_id = ?", customerID).Order("created_at").Find(&orders).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get customer orders")
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *customerDataRepository) GetReservations(ctx context.Context, customerID int64) ([]entity.Reservation, error) {
+	var reservations []entity.Reservation
+	if err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Order("reserve_date").Find(&reservations).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get customer reservations")
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *customerDataRepository) GetWishlist(ctx context.Context, customerID int64) ([]entity.WishList, error) {
+	var wishlist []entity.WishList
+	if err := r.db.WithContext(ctx).Preload("Menu").Where("customer_id = ? AND deleted_at IS NULL", customerID).Order("created_at").Find(&wishlist).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get customer wishlist")
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (r *customerDataRepository) GetCarts(ctx context.Context, customerID int64) ([]entity.Cart, error) {
+	var carts []entity.Cart
+	if err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Order("created_at").Find(&carts).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get customer carts")
+		return nil, err
+	}
+	return carts, nil
+}
+
+func (r *customerDataRepository) Anonymize(ctx context.Context, customer *entity.Customer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(customer).Error; err != nil {
+			r.log.WithError(err).Error("Failed to anonymize customer")
+			return err
+		}
+		if err := tx.Model(&entity.Order{}).Where("customer_id = ?", customer.ID).
+			Update("delivery_address", "").Error; err != nil {
+			r.log.WithError(err).Error("Failed to clear order addresses")
+			return err
+		}
+		if err := tx.Unscoped().Model(&entity.Reservation{}).Where("customer_id = ?", customer.ID).
+			Update("special_notes", "").Error; err != nil {
+			r.log.WithError(err).Error("Failed to clear reservation notes")
+			return err
+		}
+		if err := tx.Where("customer_id = ?", customer.ID).Delete(&entity.WishList{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete wishlist")
+			return err
+		}
+		if err := tx.Where("customer_id = ?", customer.ID).Delete(&entity.Cart{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete carts")
+			return err
+		}
+		if err := tx.Where("customer_id = ?", customer.ID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete recovery codes")
+			return err
+		}
+		if err := tx.Where("customer_id = ?", customer.ID).Delete(&entity.TwoFactor{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete two-factor enrolment")
+			return err
+		}
+		if err := tx.Model(&entity.AccountToken{}).Where("customer_id = ? AND used_at IS NULL", customer.ID).
+			Update("used_at", customer.UpdatedAt).Error; err != nil {
+			r.log.WithError(err).Error("Failed to retire account tokens")
+			return err
+		}
+		return nil
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
 	"errors"
 	"time"
 
@@ -12,15 +13,15 @@ import (
 )
 
 type CustomerRepository interface {
-	Create(customer *entity.Customer) error
-	GetByID(id int64) (*entity.Customer, error)
-	GetByEmail(email string) (*entity.Customer, error)
-	Update(customer *entity.Customer) error
-	Delete(id int64) error
-	GetEmployees() ([]entity.Customer, error)
-	GetEmployeeByID(id int64) (*entity.Customer, error)
-	UpdateEmployee(id int64, request *model.UpdateUserRequest, role string) error
-	DeleteEmployee(id int64) error
+	Create(ctx context.Context, customer *entity.Customer) error
+	GetByID(ctx context.Context, id int64) (*entity.Customer, error)
+	GetByEmail(ctx context.Context, email string) (*entity.Customer, error)
+	Update(ctx context.Context, customer *entity.Customer) error
+	Delete(ctx context.Context, id int64) error
+	GetEmployees(ctx context.Context) ([]entity.Customer, error)
+	GetEmployeeByID(ctx context.Context, id int64) (*entity.Customer, error)
+	UpdateEmployee(ctx context.Context, id int64, request *model.UpdateUserRequest, role string) error
+	DeleteEmployee(ctx context.Context, id int64) error
 }
 
 type customerRepository struct {
@@ -35,9 +36,9 @@ func NewCustomerRepository(db *gorm.DB, logger *logrus.Logger) CustomerRepositor
 	}
 }
 
-func (r *customerRepository) GetEmployees() ([]entity.Customer, error) {
+func (r *customerRepository) GetEmployees(ctx context.Context) ([]entity.Customer, error) {
 	var customer []entity.Customer
-	if err := r.db.Where("role != ?", "customer").Find(&customer).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("role NOT IN ?", []string{constants.RoleCustomer, constants.RoleGuest}).Find(&customer).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("employee not found")
 		}
@@ -47,10 +48,10 @@ func (r *customerRepository) GetEmployees() ([]entity.Customer, error) {
 	return customer, nil
 }
 
-func (r *customerRepository) GetEmployeeByID(id int64) (*entity.Customer, error) {
+func (r *customerRepository) GetEmployeeByID(ctx context.Context, id int64) (*entity.Customer, error) {
 	var customer entity.Customer
-	if err := r.db.
-		Where("id = ? AND role != ?", id, "customer").
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND role NOT IN ?", id, []string{constants.RoleCustomer, constants.RoleGuest}).
 		First(&customer).Error; err != nil {
 
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -62,8 +63,8 @@ func (r *customerRepository) GetEmployeeByID(id int64) (*entity.Customer, error)
 	return &customer, nil
 }
 
-func (r *customerRepository) UpdateEmployee(id int64, request *model.UpdateUserRequest, role string) error {
-	customer, err := r.GetEmployeeByID(id)
+func (r *customerRepository) UpdateEmployee(ctx context.Context, id int64, request *model.UpdateUserRequest, role string) error {
+	customer, err := r.GetEmployeeByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -77,15 +78,15 @@ func (r *customerRepository) UpdateEmployee(id int64, request *model.UpdateUserR
 		customer.Role = role
 
 	}
-	if err := r.db.Save(customer).Error; err != nil {
+	if err := r.db.WithContext(ctx).Save(customer).Error; err != nil {
 		r.logger.Errorf("Error updating employee: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *customerRepository) DeleteEmployee(id int64) error {
-	result := r.db.Delete(&entity.Customer{}, id)
+func (r *customerRepository) DeleteEmployee(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Delete(&entity.Customer{}, id)
 	if result.Error != nil {
 		r.logger.Errorf("Error deleting employee: %v", result.Error)
 		return result.Error
@@ -96,17 +97,17 @@ func (r *customerRepository) DeleteEmployee(id int64) error {
 	return nil
 }
 
-func (r *customerRepository) Create(customer *entity.Customer) error {
-	if err := r.db.Create(customer).Error; err != nil {
+func (r *customerRepository) Create(ctx context.Context, customer *entity.Customer) error {
+	if err := r.db.WithContext(ctx).Create(customer).Error; err != nil {
 		r.logger.Errorf("Error creating customer: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *customerRepository) GetByID(id int64) (*entity.Customer, error) {
+func (r *customerRepository) GetByID(ctx context.Context, id int64) (*entity.Customer, error) {
 	var customer entity.Customer
-	if err := r.db.First(&customer, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&customer, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("customer not found")
 		}
@@ -116,9 +117,9 @@ func (r *customerRepository) GetByID(id int64) (*entity.Customer, error) {
 	return &customer, nil
 }
 
-func (r *customerRepository) GetByEmail(email string) (*entity.Customer, error) {
+func (r *customerRepository) GetByEmail(ctx context.Context, email string) (*entity.Customer, error) {
 	var customer entity.Customer
-	if err := r.db.Where("email = ?", email).First(&customer).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&customer).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("customer not found")
 		}
@@ -128,16 +129,16 @@ func (r *customerRepository) GetByEmail(email string) (*entity.Customer, error)
 	return &customer, nil
 }
 
-func (r *customerRepository) Update(customer *entity.Customer) error {
-	if err := r.db.Save(customer).Error; err != nil {
+func (r *customerRepository) Update(ctx context.Context, customer *entity.Customer) error {
+	if err := r.db.WithContext(ctx).Save(customer).Error; err != nil {
 		r.logger.Errorf("Error updating customer: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *customerRepository) Delete(id int64) error {
-	result := r.db.Delete(&entity.Customer{}, id)
+func (r *customerRepository) Delete(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Delete(&entity.Customer{}, id)
 	if result.Error != nil {
 		r.logger.Errorf("Error deleting customer: %v", result.Error)
 		return result.Error
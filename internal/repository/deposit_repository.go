@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type DepositRepository interface {
+	Create(ctx context.Context, deposit *entity.ReservationDeposit) error
+	GetByReservationID(ctx context.Context, reservationID uint) (*entity.ReservationDeposit, error)
+	GetByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.ReservationDeposit, error)
+	GetOverdue(ctx context.Context, now time.Time) ([]entity.ReservationDeposit, error)
+	Update(ctx context.Context, deposit *entity.ReservationDeposit) error
+	// Apply claims a paid deposit for an order and records its credit, settling
+	// the order when settlesOrder is set, all or nothing. Returns
+	// constants.ErrDepositNotApplicable when the deposit is no longer paid.
+	Apply(ctx context.Context, deposit *entity.ReservationDeposit, credit *entity.Payment, settlesOrder bool) error
+}
+
+type depositRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewDepositRepository(db *gorm.DB, log *logrus.Logger) DepositRepository {
+	return &depositRepository{db: db, log: log}
+}
+
+func (r *depositRepository) Create(ctx context.Context, deposit *entity.ReservationDeposit) error {
+	if err := r.db.WithContext(ctx).Create(deposit).Error; err != nil {
+		r.log.WithError(err).Error("Failed to create reservation deposit")
+		return err
+	}
+	return nil
+}
+
+func (r *depositRepository) GetByReservationID(ctx context.Context, reservationID uint) (*entity.ReservationDeposit, error) {
+	var deposit entity.ReservationDeposit
+	if err := r.db.WithContext(ctx).Where("reservation_id = ?", reservationID).First(&deposit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get reservation deposit")
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+func (r *depositRepository) GetByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.ReservationDeposit, error) {
+	var deposit entity.ReservationDeposit
+	if err := r.db.WithContext(ctx).Where("gateway_order_id = ?", gatewayOrderID).First(&deposit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get reservation deposit by gateway order ID")
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// GetOverdue returns deposits still waiting for payment after their deadline
+func (r *depositRepository) GetOverdue(ctx context.Context, now time.Time) ([]entity.ReservationDeposit, error) {
+	var deposits []entity.ReservationDeposit
+	if err := r.db.WithContext(ctx).Where("status = ? AND due_at < ?", entity.DepositStatusPending, now).Find(&deposits).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get overdue reservation deposits")
+		return nil, err
+	}
+	return deposits, nil
+}
+
+func (r *depositRepository) Update(ctx context.Context, deposit *entity.ReservationDeposit) error {
+	if err := r.db.WithContext(ctx).Save(deposit).Error; err != nil {
+		r.log.WithError(err).Error("Failed to update reservation deposit")
+		return err
+	}
+	return nil
+}
+
+func (r *depositRepository) Apply(ctx context.Context, deposit *entity.ReservationDeposit, credit *entity.Payment, settlesOrder bool) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Claimed only while still paid, so two tills cannot apply the same deposit
+		result := tx.Model(&entity.ReservationDeposit{}).
+			Where("id = ? AND status = ?", deposit.ID, entity.DepositStatusPaid).
+			Updates(map[string]interface{}{
+				"status":           entity.DepositStatusApplied,
+				"applied_order_id": deposit.AppliedOrderID,
+				"applied_amount":   deposit.AppliedAmount,
+				"refund_amount":    deposit.RefundAmount,
+			})
+		if result.Error != nil {
+			r.log.WithError(result.Error).Error("Failed to apply reservation deposit")
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrDepositNotApplicable
+		}
+
+		if err := tx.Create(credit).Error; err != nil {
+			r.log.WithError(err).Error("Failed to create deposit payment")
+			return err
+		}
+		if !settlesOrder {
+			return nil
+		}
+
+		if err := tx.Model(&entity.Payment{}).
+			Where("order_id = ? AND status = ?", credit.OrderID, constants.PaymentStatusPending).
+			Update("status", constants.PaymentStatusCancelled).Error; err != nil {
+			r.log.WithError(err).Error("Failed to cancel pending payments")
+			return err
+		}
+		if err := tx.Model(&entity.Order{}).Where("id = ?", credit.OrderID).Update("status", entity.OrderStatusPaid).Error; err != nil {
+			r.log.WithError(err).Error("Failed to mark order as paid")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -1,28 +1,40 @@
 package repository
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
 	"cakestore/utils"
+	"context"
 	"errors"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository interface {
-	Create(order *entity.Order) error
-	GetByID(id int64) (*entity.Order, error)
-	GetAll(params *model.PaginationQuery) ([]entity.Order, *model.PaginatedMeta, error)
-	GetByCustomerID(customerID int64) ([]entity.Order, error)
-	Update(order *entity.Order) error
-	Delete(id int64) error
-	UpdateStatus(id int64, status entity.OrderStatus) error
+	Create(ctx context.Context, order *entity.Order) error
+	GetByID(ctx context.Context, id int64) (*entity.Order, error)
+	GetAll(ctx context.Context, params *model.PaginationQuery) ([]entity.Order, *model.PaginatedMeta, error)
+	GetByCustomerID(ctx context.Context, customerID int64) ([]entity.Order, error)
+	Update(ctx context.Context, order *entity.Order) error
+	Delete(ctx context.Context, id int64) error
+	UpdateStatus(ctx context.Context, id int64, status entity.OrderStatus) error
 	// GetPendingOrder retrieves the first pending order from the database for testing purposes
-	GetPendingOrder() (int64, error)
-	FindByDateRange(startDate, endDate string) ([]entity.Order, error)
-	GetPendingPaymentByOrderID(customerID, orderID int64) (entity.Order, error)
-	UpdateFoodStatus(orderID int64, foodStatus entity.FoodStatus) error
+	GetPendingOrder(ctx context.Context) (int64, error)
+	FindByDateRange(ctx context.Context, startDate, endDate string) ([]entity.Order, error)
+	GetPendingPaymentByOrderID(ctx context.Context, customerID, orderID int64) (entity.Order, error)
+	UpdateFoodStatus(ctx context.Context, orderID int64, foodStatus entity.FoodStatus) error
+	GetByTableSessionID(ctx context.Context, sessionID int64) ([]entity.Order, error)
+	// ExpirePending cancels pending orders created before the cutoff, other
+	// than table session orders, and expires their pending payments. It
+	// returns the IDs of the cancelled orders.
+	ExpirePending(ctx context.Context, before time.Time) ([]int64, error)
+	// CountKitchenQueue counts the orders the kitchen still has to prepare,
+	// by food status.
+	CountKitchenQueue(ctx context.Context) (map[entity.FoodStatus]int64, error)
 }
 
 type orderRepository struct {
@@ -37,8 +49,8 @@ func NewOrderRepository(db *gorm.DB, logger *logrus.Logger) OrderRepository {
 	}
 }
 
-func (r *orderRepository) UpdateFoodStatus(orderID int64, foodStatus entity.FoodStatus) error {
-	result := r.db.Model(&entity.Order{}).Where("id =?", orderID).Update("food_status", foodStatus)
+func (r *orderRepository) UpdateFoodStatus(ctx context.Context, orderID int64, foodStatus entity.FoodStatus) error {
+	result := r.db.WithContext(ctx).Model(&entity.Order{}).Where("id =?", orderID).Update("food_status", foodStatus)
 	if result.Error != nil {
 		r.logger.Errorf("UpdateFoodStatus repository ~ Error updating order status: %v", result.Error)
 		return result.Error
@@ -49,9 +61,9 @@ func (r *orderRepository) UpdateFoodStatus(orderID int64, foodStatus entity.Food
 	return nil
 }
 
-func (r *orderRepository) GetPendingPaymentByOrderID(customerID, orderID int64) (entity.Order, error) {
+func (r *orderRepository) GetPendingPaymentByOrderID(ctx context.Context, customerID, orderID int64) (entity.Order, error) {
 	var order entity.Order
-	if err := r.db.Preload("Items.Menu").Preload("Customer").Where("customer_id = ? AND status = ? AND id = ?", customerID, entity.OrderStatusPending, orderID).First(&order).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Items.Menu").Preload("Customer").Where("customer_id = ? AND status = ? AND id = ?", customerID, entity.OrderStatusPending, orderID).First(&order).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return entity.Order{}, errors.New("order not found")
 		}
@@ -61,16 +73,16 @@ func (r *orderRepository) GetPendingPaymentByOrderID(customerID, orderID int64)
 	return order, nil
 }
 
-func (r *orderRepository) FindByDateRange(startDate, endDate string) ([]entity.Order, error) {
+func (r *orderRepository) FindByDateRange(ctx context.Context, startDate, endDate string) ([]entity.Order, error) {
 	var orders []entity.Order
-	if err := r.db.Preload("Items.Menu").Preload("Customer").Where("created_at BETWEEN ? AND ?", startDate, endDate).Find(&orders).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Items.Menu").Preload("Customer").Where("created_at BETWEEN ? AND ?", startDate, endDate).Find(&orders).Error; err != nil {
 		r.logger.Errorf("Error getting orders by date range: %v", err)
 		return nil, err
 	}
 	return orders, nil
 }
 
-func (r *orderRepository) GetAll(params *model.PaginationQuery) ([]entity.Order, *model.PaginatedMeta, error) {
+func (r *orderRepository) GetAll(ctx context.Context, params *model.PaginationQuery) ([]entity.Order, *model.PaginatedMeta, error) {
 	var orders []entity.Order
 	var total int64
 	var meta *model.PaginatedMeta
@@ -88,14 +100,14 @@ func (r *orderRepository) GetAll(params *model.PaginationQuery) ([]entity.Order,
 		params.Offset = params.Page - 1*params.Limit
 	}
 
-	if err := r.db.Model(&entity.Order{}).Count(&total).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&entity.Order{}).Count(&total).Error; err != nil {
 		r.logger.Errorf("Error getting total orders: %v", err)
 		return nil, nil, err
 	}
 
 	meta = utils.CreatePaginationMeta(params.Page, params.Limit, total)
 
-	if err := r.db.Preload("Items.Menu").
+	if err := r.db.WithContext(ctx).Preload("Items.Menu").
 		Preload("Customer").
 		Limit(int(params.Limit)).
 		Offset(int((params.Page - 1) * params.Limit)).
@@ -106,8 +118,8 @@ func (r *orderRepository) GetAll(params *model.PaginationQuery) ([]entity.Order,
 	return orders, meta, nil
 }
 
-func (r *orderRepository) Create(order *entity.Order) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *orderRepository) Create(ctx context.Context, order *entity.Order) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(order).Error; err != nil {
 			r.logger.Errorf("Error creating order: %v", err)
 			return err
@@ -116,9 +128,9 @@ func (r *orderRepository) Create(order *entity.Order) error {
 	})
 }
 
-func (r *orderRepository) GetByID(id int64) (*entity.Order, error) {
+func (r *orderRepository) GetByID(ctx context.Context, id int64) (*entity.Order, error) {
 	var order entity.Order
-	if err := r.db.Preload("Items.Menu").Preload("Customer").First(&order, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Items.Menu").Preload("Customer").First(&order, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("order not found")
 		}
@@ -128,17 +140,26 @@ func (r *orderRepository) GetByID(id int64) (*entity.Order, error) {
 	return &order, nil
 }
 
-func (r *orderRepository) GetByCustomerID(customerID int64) ([]entity.Order, error) {
+func (r *orderRepository) GetByCustomerID(ctx context.Context, customerID int64) ([]entity.Order, error) {
 	var orders []entity.Order
-	if err := r.db.Preload("Customer").Preload("Items.Menu").Where("customer_id = ?", customerID).Find(&orders).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Customer").Preload("Items.Menu").Where("customer_id = ?", customerID).Find(&orders).Error; err != nil {
 		r.logger.Errorf("Error getting orders by customer ID: %v", err)
 		return nil, err
 	}
 	return orders, nil
 }
 
-func (r *orderRepository) Update(order *entity.Order) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *orderRepository) GetByTableSessionID(ctx context.Context, sessionID int64) ([]entity.Order, error) {
+	var orders []entity.Order
+	if err := r.db.WithContext(ctx).Preload("Customer").Preload("Items.Menu").Where("table_session_id = ?", sessionID).Order("created_at ASC").Find(&orders).Error; err != nil {
+		r.logger.Errorf("Error getting orders by table session ID: %v", err)
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *orderRepository) Update(ctx context.Context, order *entity.Order) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Save(order).Error; err != nil {
 			r.logger.Errorf("Error updating order: %v", err)
 			return err
@@ -154,8 +175,8 @@ func (r *orderRepository) Update(order *entity.Order) error {
 	})
 }
 
-func (r *orderRepository) Delete(id int64) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *orderRepository) Delete(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("order_id = ?", id).Delete(&entity.OrderItem{}).Error; err != nil {
 			r.logger.Errorf("Error deleting order items: %v", err)
 			return err
@@ -173,8 +194,8 @@ func (r *orderRepository) Delete(id int64) error {
 	})
 }
 
-func (r *orderRepository) UpdateStatus(id int64, status entity.OrderStatus) error {
-	result := r.db.Model(&entity.Order{}).Where("id = ?", id).Update("status", status)
+func (r *orderRepository) UpdateStatus(ctx context.Context, id int64, status entity.OrderStatus) error {
+	result := r.db.WithContext(ctx).Model(&entity.Order{}).Where("id = ?", id).Update("status", status)
 	if result.Error != nil {
 		r.logger.Errorf("UpdateStatus repository ~ Error updating order status: %v", result.Error)
 		return result.Error
@@ -186,9 +207,9 @@ func (r *orderRepository) UpdateStatus(id int64, status entity.OrderStatus) erro
 }
 
 // GetPendingOrder retrieves the first pending order from the database for testing purposes
-func (r *orderRepository) GetPendingOrder() (int64, error) {
+func (r *orderRepository) GetPendingOrder(ctx context.Context) (int64, error) {
 	var order entity.Order
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Preload("Items.Menu").
 		Preload("Customer").
 		Where("status = ?", entity.OrderStatusPending).
@@ -202,3 +223,64 @@ func (r *orderRepository) GetPendingOrder() (int64, error) {
 	}
 	return order.ID, nil
 }
+
+// ExpirePending locks the orders it cancels for the rest of the transaction.
+// Orders another transaction is updating are skipped and left for the next
+// run. Table session orders are left alone: they stay pending until the bill
+// is settled.
+func (r *orderRepository) ExpirePending(ctx context.Context, before time.Time) ([]int64, error) {
+	var ids []int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Order{}).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND table_session_id IS NULL AND created_at < ? AND deleted_at IS NULL", entity.OrderStatusPending, before).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&entity.Order{}).Where("id IN ?", ids).
+			Update("status", entity.OrderStatusCancelled).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entity.Payment{}).
+			Where("order_id IN ? AND status = ?", ids, constants.PaymentStatusPending).
+			Update("status", constants.PaymentStatusExpired).Error
+	})
+	if err != nil {
+		r.logger.Errorf("ExpirePending repository ~ Error expiring orders: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CountKitchenQueue counts orders whose food is pending or cooking. Online
+// orders only reach the kitchen once paid, while dine-in orders are cooked
+// before the table settles the bill.
+func (r *orderRepository) CountKitchenQueue(ctx context.Context) (map[entity.FoodStatus]int64, error) {
+	var rows []struct {
+		FoodStatus entity.FoodStatus
+		Count      int64
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.Order{}).
+		Select("food_status, COUNT(*) AS count").
+		Where("food_status IN ? AND deleted_at IS NULL", []entity.FoodStatus{entity.FoodStatusPending, entity.FoodStatusCooking}).
+		Where("status IN ? OR (table_session_id IS NOT NULL AND status <> ?)",
+			[]entity.OrderStatus{entity.OrderStatusPaid, entity.OrderStatusPreparing}, entity.OrderStatusCancelled).
+		Group("food_status").
+		Scan(&rows).Error; err != nil {
+		r.logger.Errorf("CountKitchenQueue repository ~ Error counting orders: %v", err)
+		return nil, err
+	}
+
+	counts := map[entity.FoodStatus]int64{
+		entity.FoodStatusPending: 0,
+		entity.FoodStatusCooking: 0,
+	}
+	for _, row := range rows {
+		counts[row.FoodStatus] = row.Count
+	}
+	return counts, nil
+}
@@ -3,18 +3,32 @@ package repository
 import (
 	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
+	"context"
 	"errors"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type PaymentRepository interface {
-	CreatePayment(payment *entity.Payment) error
-	GetPaymentByOrderID(orderID int64) (*entity.Payment, error)
-	UpdatePayment(payment *entity.Payment) error
+	CreatePayment(ctx context.Context, payment *entity.Payment) error
+	GetPaymentByOrderID(ctx context.Context, orderID int64) (*entity.Payment, error)
+	GetPaymentsByOrderID(ctx context.Context, orderID int64) ([]entity.Payment, error)
+	GetPaymentByID(ctx context.Context, id int64) (*entity.Payment, error)
+	GetPaymentByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.Payment, error)
+	UpdatePayment(ctx context.Context, payment *entity.Payment) error
+	UpdatePaymentStatus(ctx context.Context, id int64, status constants.PaymentStatus) error
+	// TransitionPaymentStatus moves a payment to status only while it is in one
+	// of from, and reports whether it did.
+	TransitionPaymentStatus(ctx context.Context, id int64, status constants.PaymentStatus, from []constants.PaymentStatus) (bool, error)
+	CancelPendingPayments(ctx context.Context, orderID int64) error
+	// ReplacePendingPayments cancels the pending payments of an order and
+	// creates the given ones in their place, all or nothing.
+	ReplacePendingPayments(ctx context.Context, orderID int64, payments []*entity.Payment) error
+	GetSuccessfulPaymentsByDateRange(ctx context.Context, start, end time.Time) ([]entity.Payment, error)
 	// function to retrieve the first pending payment for testing purposes in development mode
-	GetPendingPayment() (int64, error)
+	GetPendingPayment(ctx context.Context) (int64, error)
 }
 
 type paymentRespositoryImpl struct {
@@ -29,8 +43,8 @@ func NewPaymentRepository(db *gorm.DB, log *logrus.Logger) PaymentRepository {
 	}
 }
 
-func (r *paymentRespositoryImpl) CreatePayment(payment *entity.Payment) error {
-	err := r.db.Transaction(func(tx *gorm.DB) error {
+func (r *paymentRespositoryImpl) CreatePayment(ctx context.Context, payment *entity.Payment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(payment).Error; err != nil {
 			r.log.WithError(err).Error("Failed to create payment")
 			return err
@@ -43,22 +57,53 @@ func (r *paymentRespositoryImpl) CreatePayment(payment *entity.Payment) error {
 	return nil
 }
 
-func (r *paymentRespositoryImpl) GetPaymentByOrderID(orderID int64) (*entity.Payment, error) {
+func (r *paymentRespositoryImpl) GetPaymentByOrderID(ctx context.Context, orderID int64) (*entity.Payment, error) {
 	var payment entity.Payment
-	if err := r.db.Where("order_id = ?", orderID).First(&payment).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at DESC").First(&payment).Error; err != nil {
 		r.log.WithError(err).Error("Failed to get payment")
 		return nil, err
 	}
 	return &payment, nil
 }
 
-func (r *paymentRespositoryImpl) UpdatePayment(payment *entity.Payment) error {
-	err := r.db.Transaction(func(tx *gorm.DB) error {
+func (r *paymentRespositoryImpl) GetPaymentsByOrderID(ctx context.Context, orderID int64) ([]entity.Payment, error) {
+	var payments []entity.Payment
+	if err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at ASC").Find(&payments).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get payments")
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *paymentRespositoryImpl) GetPaymentByID(ctx context.Context, id int64) (*entity.Payment, error) {
+	var payment entity.Payment
+	if err := r.db.WithContext(ctx).First(&payment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get payment")
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRespositoryImpl) GetPaymentByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.Payment, error) {
+	var payment entity.Payment
+	if err := r.db.WithContext(ctx).Where("gateway_order_id = ?", gatewayOrderID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get payment")
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRespositoryImpl) UpdatePayment(ctx context.Context, payment *entity.Payment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Model(&entity.Payment{}).
 			Where("order_id = ?", payment.OrderID).
-			Updates(map[string]interface{}{
-				"status": payment.Status,
-			}).Error; err != nil {
+			Updates(statusUpdates(payment.Status)).Error; err != nil {
 			r.log.WithError(err).Error("Failed to update payment")
 			return err
 		}
@@ -70,9 +115,75 @@ func (r *paymentRespositoryImpl) UpdatePayment(payment *entity.Payment) error {
 	return nil
 }
 
-func (r *paymentRespositoryImpl) GetPendingPayment() (int64, error) {
+func (r *paymentRespositoryImpl) UpdatePaymentStatus(ctx context.Context, id int64, status constants.PaymentStatus) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Payment{}).
+		Where("id = ?", id).
+		Updates(statusUpdates(status)).Error; err != nil {
+		r.log.WithError(err).Error("Failed to update payment status")
+		return err
+	}
+	return nil
+}
+
+func (r *paymentRespositoryImpl) TransitionPaymentStatus(ctx context.Context, id int64, status constants.PaymentStatus, from []constants.PaymentStatus) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.Payment{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(statusUpdates(status))
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to update payment status")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CancelPendingPayments cancels every unpaid split of an order so the bill can be split again
+func (r *paymentRespositoryImpl) CancelPendingPayments(ctx context.Context, orderID int64) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Payment{}).
+		Where("order_id = ? AND status = ?", orderID, constants.PaymentStatusPending).
+		Update("status", constants.PaymentStatusCancelled).Error; err != nil {
+		r.log.WithError(err).Error("Failed to cancel pending payments")
+		return err
+	}
+	return nil
+}
+
+func (r *paymentRespositoryImpl) ReplacePendingPayments(ctx context.Context, orderID int64, payments []*entity.Payment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.Payment{}).
+			Where("order_id = ? AND status = ?", orderID, constants.PaymentStatusPending).
+			Update("status", constants.PaymentStatusCancelled).Error; err != nil {
+			r.log.WithError(err).Error("Failed to cancel pending payments")
+			return err
+		}
+		for _, payment := range payments {
+			if err := tx.Create(payment).Error; err != nil {
+				r.log.WithError(err).Error("Failed to create payment")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetSuccessfulPaymentsByDateRange returns payments whose money was received in [start, end)
+func (r *paymentRespositoryImpl) GetSuccessfulPaymentsByDateRange(ctx context.Context, start, end time.Time) ([]entity.Payment, error) {
+	var payments []entity.Payment
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND paid_at >= ? AND paid_at < ?", constants.PaymentStatusSuccess, start, end).
+		Find(&payments).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get payments by date range")
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (r *paymentRespositoryImpl) GetPendingPayment(ctx context.Context) (int64, error) {
 	var payment entity.Payment
-	if err := r.db.
+	if err := r.db.WithContext(ctx).
 		Preload("Order").
 		Where("status = ?", constants.PaymentStatusPending).
 		Order("created_at DESC").
@@ -85,3 +196,12 @@ func (r *paymentRespositoryImpl) GetPendingPayment() (int64, error) {
 	}
 	return payment.ID, nil
 }
+
+// statusUpdates stamps paid_at when a payment succeeds
+func statusUpdates(status constants.PaymentStatus) map[string]interface{} {
+	updates := map[string]interface{}{"status": status}
+	if status == constants.PaymentStatusSuccess {
+		updates["paid_at"] = time.Now()
+	}
+	return updates
+}
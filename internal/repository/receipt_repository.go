@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReceiptRepository interface {
+	Create(ctx context.Context, receipt *entity.Receipt, settledOrderIDs []int64) error
+	SettlePayment(ctx context.Context, receipt *entity.Receipt, paymentID int64, settledOrderIDs []int64) error
+	GetByNumber(ctx context.Context, number string) (*entity.Receipt, error)
+	GetByShiftID(ctx context.Context, shiftID int64) ([]entity.Receipt, error)
+}
+
+type receiptRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewReceiptRepository(db *gorm.DB, log *logrus.Logger) ReceiptRepository {
+	return &receiptRepository{db: db, log: log}
+}
+
+// Create stores the receipt together with its payments and marks the orders
+// it settles as paid, cancelling their unpaid gateway links, in one transaction.
+// Returns constants.ErrAmountExceedsOutstanding when another payment got in first.
+func (r *receiptRepository) Create(ctx context.Context, receipt *entity.Receipt, settledOrderIDs []int64) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockOutstanding(tx, receipt.Payments); err != nil {
+			return err
+		}
+		if err := r.cancelPendingPayments(tx, settledOrderIDs); err != nil {
+			return err
+		}
+		if err := tx.Omit("Cashier").Create(receipt).Error; err != nil {
+			r.log.WithError(err).Error("Failed to create receipt")
+			return err
+		}
+		return r.markOrdersPaid(tx, settledOrderIDs)
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SettlePayment issues the receipt for a cash split collected at the till and
+// links the split to it, so the cash counts towards the cashier's shift.
+// Returns constants.ErrInvalidPaymentStatus when the split is no longer pending.
+func (r *receiptRepository) SettlePayment(ctx context.Context, receipt *entity.Receipt, paymentID int64, settledOrderIDs []int64) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := r.lockOutstanding(tx, receipt.Payments); err != nil {
+			return err
+		}
+		if err := tx.Omit("Cashier", "Payments").Create(receipt).Error; err != nil {
+			r.log.WithError(err).Error("Failed to create receipt")
+			return err
+		}
+		result := tx.Model(&entity.Payment{}).
+			Where("id = ? AND status = ?", paymentID, constants.PaymentStatusPending).
+			Updates(map[string]interface{}{
+				"status":     constants.PaymentStatusSuccess,
+				"receipt_id": receipt.ID,
+				"paid_at":    receipt.CreatedAt,
+			})
+		if result.Error != nil {
+			r.log.WithError(result.Error).Error("Failed to settle payment")
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrInvalidPaymentStatus
+		}
+		if err := r.cancelPendingPayments(tx, settledOrderIDs); err != nil {
+			return err
+		}
+		return r.markOrdersPaid(tx, settledOrderIDs)
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// lockOutstanding locks the orders the payments are for until the transaction
+// ends, so two tills cannot both collect the same balance, and checks that the
+// payments still fit in what is left to pay
+func (r *receiptRepository) lockOutstanding(tx *gorm.DB, payments []entity.Payment) error {
+	amounts := make(map[int64]float64, len(payments))
+	orderIDs := make([]int64, 0, len(payments))
+	for _, payment := range payments {
+		if _, ok := amounts[payment.OrderID]; !ok {
+			orderIDs = append(orderIDs, payment.OrderID)
+		}
+		amounts[payment.OrderID] += payment.Amount
+	}
+	// Always lock in the same order so concurrent receipts cannot deadlock
+	sort.Slice(orderIDs, func(i, j int) bool { return orderIDs[i] < orderIDs[j] })
+
+	for _, orderID := range orderIDs {
+		var order entity.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "total_price").First(&order, orderID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return constants.ErrNotFound
+			}
+			r.log.WithError(err).Error("Failed to lock order")
+			return err
+		}
+		var paid float64
+		if err := tx.Model(&entity.Payment{}).
+			Where("order_id = ? AND status = ?", orderID, constants.PaymentStatusSuccess).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&paid).Error; err != nil {
+			r.log.WithError(err).Error("Failed to sum order payments")
+			return err
+		}
+		if amounts[orderID] > math.Round(order.TotalPrice-paid) {
+			return constants.ErrAmountExceedsOutstanding
+		}
+	}
+	return nil
+}
+
+func (r *receiptRepository) cancelPendingPayments(tx *gorm.DB, orderIDs []int64) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+	if err := tx.Model(&entity.Payment{}).
+		Where("order_id IN ? AND status = ?", orderIDs, constants.PaymentStatusPending).
+		Update("status", constants.PaymentStatusCancelled).Error; err != nil {
+		r.log.WithError(err).Error("Failed to cancel pending payments of settled orders")
+		return err
+	}
+	return nil
+}
+
+func (r *receiptRepository) markOrdersPaid(tx *gorm.DB, orderIDs []int64) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+	result := tx.Model(&entity.Order{}).Where("id IN ?", orderIDs).Update("status", entity.OrderStatusPaid)
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to mark settled orders as paid")
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(orderIDs)) {
+		return constants.ErrNotFound
+	}
+	return nil
+}
+
+func (r *receiptRepository) GetByNumber(ctx context.Context, number string) (*entity.Receipt, error) {
+	var receipt entity.Receipt
+	if err := r.db.WithContext(ctx).Preload("Cashier").Preload("Payments").Where("number = ?", number).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get receipt")
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+func (r *receiptRepository) GetByShiftID(ctx context.Context, shiftID int64) ([]entity.Receipt, error) {
+	var receipts []entity.Receipt
+	if err := r.db.WithContext(ctx).Where("shift_id = ?", shiftID).Order("created_at ASC").Find(&receipts).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get receipts by shift")
+		return nil, err
+	}
+	return receipts, nil
+}
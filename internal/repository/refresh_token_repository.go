@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *entity.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error)
+	// Revoke reports false when the token had already been revoked, e.g. by a concurrent refresh
+	Revoke(ctx context.Context, id int64, replacedByID *int64) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllByCustomer(ctx context.Context, customerID int64) error
+}
+
+type refreshTokenRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewRefreshTokenRepository(db *gorm.DB, log *logrus.Logger) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db, log: log}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		r.log.WithError(err).Error("Failed to create refresh token")
+		return err
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get refresh token")
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) Revoke(ctx context.Context, id int64, replacedByID *int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by_id": replacedByID})
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to revoke refresh token")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	if err := r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		r.log.WithError(err).Error("Failed to revoke refresh token family")
+		return err
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllByCustomer(ctx context.Context, customerID int64) error {
+	if err := r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where("customer_id = ? AND revoked_at IS NULL", customerID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		r.log.WithError(err).Error("Failed to revoke refresh tokens")
+		return err
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -10,13 +13,17 @@ import (
 )
 
 type ReservationRepository interface {
-	Create(reservation *entity.Reservation) error
-	GetByID(id uint) (*entity.Reservation, error)
-	GetAll(params *model.ReservationQueryParams) (*model.PaginationResponse[[]entity.Reservation], error)
-	AdminGetAllCustomerReservations(params *model.PaginationQuery) (*model.PaginationResponse[[]entity.Reservation], error)
-	Update(reservation *entity.Reservation) error
-	Delete(id uint) error
-	CheckTableAvailability(tableID uint, reserveDate time.Time) (bool, error)
+	Create(ctx context.Context, reservation *entity.Reservation) error
+	GetByID(ctx context.Context, id uint) (*entity.Reservation, error)
+	GetAll(ctx context.Context, params *model.ReservationQueryParams) (*model.PaginationResponse[[]entity.Reservation], error)
+	AdminGetAllCustomerReservations(ctx context.Context, params *model.PaginationQuery) (*model.PaginationResponse[[]entity.Reservation], error)
+	Update(ctx context.Context, reservation *entity.Reservation) error
+	Delete(ctx context.Context, id uint) error
+	CheckTableAvailability(ctx context.Context, tableID uint, reserveDate time.Time) (bool, error)
+	GetDueForReminder(ctx context.Context, from, to time.Time) ([]entity.Reservation, error)
+	GetOverdue(ctx context.Context, before time.Time) ([]entity.Reservation, error)
+	MarkReminderSent(ctx context.Context, id uint, sentAt time.Time) error
+	CountNoShowsByCustomer(ctx context.Context, customerID uint) (int64, error)
 }
 
 type reservationRepository struct {
@@ -31,11 +38,11 @@ func NewReservationRepository(db *gorm.DB, logger *logrus.Logger) ReservationRep
 	}
 }
 
-func (r *reservationRepository) AdminGetAllCustomerReservations(params *model.PaginationQuery) (*model.PaginationResponse[[]entity.Reservation], error) {
+func (r *reservationRepository) AdminGetAllCustomerReservations(ctx context.Context, params *model.PaginationQuery) (*model.PaginationResponse[[]entity.Reservation], error) {
 	var reservations []entity.Reservation
 	var total int64
 
-	query := r.db.Model(&entity.Reservation{})
+	query := r.db.WithContext(ctx).Model(&entity.Reservation{})
 
 	if err := query.Count(&total).Error; err != nil {
 		r.logger.Errorf("Error counting reservations: %v", err)
@@ -44,7 +51,7 @@ func (r *reservationRepository) AdminGetAllCustomerReservations(params *model.Pa
 
 	offset := (params.Page - 1) * params.Limit
 	query = query.Offset(int(offset)).Limit(int(params.Limit))
-	query = query.Preload("Customer")
+	query = query.Preload("Customer").Preload("Deposit")
 	if err := query.Find(&reservations).Error; err != nil {
 		r.logger.Errorf("Error getting reservations: %v", err)
 		return nil, err
@@ -58,28 +65,31 @@ func (r *reservationRepository) AdminGetAllCustomerReservations(params *model.Pa
 	}, nil
 }
 
-func (r *reservationRepository) Create(reservation *entity.Reservation) error {
-	if err := r.db.Create(reservation).Error; err != nil {
+func (r *reservationRepository) Create(ctx context.Context, reservation *entity.Reservation) error {
+	if err := r.db.WithContext(ctx).Create(reservation).Error; err != nil {
 		r.logger.Errorf("Error creating reservation: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *reservationRepository) GetByID(id uint) (*entity.Reservation, error) {
+func (r *reservationRepository) GetByID(ctx context.Context, id uint) (*entity.Reservation, error) {
 	var reservation entity.Reservation
-	if err := r.db.Preload("Customer").First(&reservation, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Customer").Preload("Deposit").First(&reservation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
 		r.logger.Errorf("Error getting reservation by ID: %v", err)
 		return nil, err
 	}
 	return &reservation, nil
 }
 
-func (r *reservationRepository) GetAll(params *model.ReservationQueryParams) (*model.PaginationResponse[[]entity.Reservation], error) {
+func (r *reservationRepository) GetAll(ctx context.Context, params *model.ReservationQueryParams) (*model.PaginationResponse[[]entity.Reservation], error) {
 	var reservations []entity.Reservation
 	var total int64
 
-	query := r.db.Model(&entity.Reservation{})
+	query := r.db.WithContext(ctx).Model(&entity.Reservation{})
 	if params.CustomerID != 0 {
 		query = query.Where("customer_id = ?", params.CustomerID)
 	}
@@ -105,7 +115,7 @@ func (r *reservationRepository) GetAll(params *model.ReservationQueryParams) (*m
 
 	offset := (params.Page - 1) * params.Limit
 	query = query.Offset(int(offset)).Limit(int(params.Limit))
-	query = query.Preload("Customer")
+	query = query.Preload("Customer").Preload("Deposit")
 
 	if err := query.Find(&reservations).Error; err != nil {
 		r.logger.Errorf("Error getting reservations: %v", err)
@@ -121,28 +131,29 @@ func (r *reservationRepository) GetAll(params *model.ReservationQueryParams) (*m
 	}, nil
 }
 
-func (r *reservationRepository) Update(reservation *entity.Reservation) error {
-	if err := r.db.Save(reservation).Error; err != nil {
+func (r *reservationRepository) Update(ctx context.Context, reservation *entity.Reservation) error {
+	// Deposits are owned by the deposit repository and must not be overwritten with a stale copy
+	if err := r.db.WithContext(ctx).Omit("Deposit").Save(reservation).Error; err != nil {
 		r.logger.Errorf("Error updating reservation: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *reservationRepository) Delete(id uint) error {
-	if err := r.db.Delete(&entity.Reservation{}, id).Error; err != nil {
+func (r *reservationRepository) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Delete(&entity.Reservation{}, id).Error; err != nil {
 		r.logger.Errorf("Error deleting reservation: %v", err)
 		return err
 	}
 	return nil
 }
 
-func (r *reservationRepository) CheckTableAvailability(tableID uint, reserveDate time.Time) (bool, error) {
+func (r *reservationRepository) CheckTableAvailability(ctx context.Context, tableID uint, reserveDate time.Time) (bool, error) {
 	var count int64
 	start := reserveDate.Truncate(24 * time.Hour)
 	end := start.Add(24 * time.Hour)
 
-	if err := r.db.Model(&entity.Reservation{}).Where(
+	if err := r.db.WithContext(ctx).Model(&entity.Reservation{}).Where(
 		"table_id = ? AND reserve_date BETWEEN ? AND ? AND status NOT IN ?",
 		tableID,
 		start,
@@ -155,3 +166,54 @@ func (r *reservationRepository) CheckTableAvailability(tableID uint, reserveDate
 
 	return count == 0, nil
 }
+
+// GetDueForReminder returns open reservations starting between from and to that have not been reminded yet
+func (r *reservationRepository) GetDueForReminder(ctx context.Context, from, to time.Time) ([]entity.Reservation, error) {
+	var reservations []entity.Reservation
+	if err := r.db.WithContext(ctx).Preload("Customer").Where(
+		"reserve_date BETWEEN ? AND ? AND reminder_sent_at IS NULL AND status IN ?",
+		from,
+		to,
+		[]string{string(entity.ReservationStatusPending), string(entity.ReservationStatusConfirmed)},
+	).Find(&reservations).Error; err != nil {
+		r.logger.Errorf("Error getting reservations due for reminder: %v", err)
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// GetOverdue returns reservations that started before the given time but were
+// never checked in, completed or cancelled
+func (r *reservationRepository) GetOverdue(ctx context.Context, before time.Time) ([]entity.Reservation, error) {
+	var reservations []entity.Reservation
+	if err := r.db.WithContext(ctx).Preload("Customer").Where(
+		"reserve_date < ? AND status IN ? AND checked_in_at IS NULL",
+		before,
+		[]string{string(entity.ReservationStatusPending), string(entity.ReservationStatusConfirmed)},
+	).Find(&reservations).Error; err != nil {
+		r.logger.Errorf("Error getting overdue reservations: %v", err)
+		return nil, err
+	}
+	return reservations, nil
+}
+
+func (r *reservationRepository) MarkReminderSent(ctx context.Context, id uint, sentAt time.Time) error {
+	if err := r.db.WithContext(ctx).Model(&entity.Reservation{}).Where("id = ?", id).Update("reminder_sent_at", sentAt).Error; err != nil {
+		r.logger.Errorf("Error marking reminder sent for reservation ID %d: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+func (r *reservationRepository) CountNoShowsByCustomer(ctx context.Context, customerID uint) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.Reservation{}).Where(
+		"customer_id = ? AND status = ?",
+		customerID,
+		entity.ReservationStatusNoShow,
+	).Count(&count).Error; err != nil {
+		r.logger.Errorf("Error counting no-shows for customer ID %d: %v", customerID, err)
+		return 0, err
+	}
+	return count, nil
+}
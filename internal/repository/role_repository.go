@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	GetAll(ctx context.Context) ([]entity.Role, error)
+	GetByName(ctx context.Context, name string) (*entity.Role, error)
+	Create(ctx context.Context, role *entity.Role) error
+	// Update saves the description and replaces the permission set
+	Update(ctx context.Context, role *entity.Role) error
+	Delete(ctx context.Context, name string) error
+	// CountMembers counts the accounts that hold the role
+	CountMembers(ctx context.Context, name string) (int64, error)
+}
+
+type roleRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewRoleRepository(db *gorm.DB, log *logrus.Logger) RoleRepository {
+	return &roleRepository{db: db, log: log}
+}
+
+func (r *roleRepository) GetAll(ctx context.Context) ([]entity.Role, error) {
+	var roles []entity.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		r.log.WithError(err).Error("Failed to get roles")
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*entity.Role, error) {
+	var role entity.Role
+	if err := r.db.WithContext(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get role")
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *entity.Role) error {
+	if err := r.db.WithContext(ctx).Create(role).Error; err != nil {
+		r.log.WithError(err).Error("Failed to create role")
+		return err
+	}
+	return nil
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *entity.Role) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Select("description", "updated_at").Updates(role).Error; err != nil {
+			r.log.WithError(err).Error("Failed to update role")
+			return err
+		}
+		if err := tx.Where("role_name = ?", role.Name).Delete(&entity.RolePermission{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to clear role permissions")
+			return err
+		}
+		if len(role.Permissions) == 0 {
+			return nil
+		}
+		if err := tx.Create(&role.Permissions).Error; err != nil {
+			r.log.WithError(err).Error("Failed to save role permissions")
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) Delete(ctx context.Context, name string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_name = ?", name).Delete(&entity.RolePermission{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete role permissions")
+			return err
+		}
+		result := tx.Where("name = ?", name).Delete(&entity.Role{})
+		if result.Error != nil {
+			r.log.WithError(result.Error).Error("Failed to delete role")
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return constants.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) CountMembers(ctx context.Context, name string) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&entity.Customer{}).Where("role = ?", name).Count(&count).Error; err != nil {
+		r.log.WithError(err).Error("Failed to count role members")
+		return 0, err
+	}
+	return count, nil
+}
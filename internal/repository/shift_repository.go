@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShiftRepository interface {
+	Create(ctx context.Context, shift *entity.CashierShift) error
+	GetOpenByCashierID(ctx context.Context, cashierID int64) (*entity.CashierShift, error)
+	GetByDateRange(ctx context.Context, start, end time.Time) ([]entity.CashierShift, error)
+	Close(ctx context.Context, shift *entity.CashierShift) error
+	CreateTransaction(ctx context.Context, transaction *entity.ShiftTransaction) error
+	GetTransactions(ctx context.Context, shiftID int64) ([]entity.ShiftTransaction, error)
+	GetTransactionsByDateRange(ctx context.Context, start, end time.Time) ([]entity.ShiftTransaction, error)
+}
+
+type shiftRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewShiftRepository(db *gorm.DB, log *logrus.Logger) ShiftRepository {
+	return &shiftRepository{db: db, log: log}
+}
+
+func (r *shiftRepository) Create(ctx context.Context, shift *entity.CashierShift) error {
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Create(shift).Error; err != nil {
+		r.log.Errorf("Error creating cashier shift: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *shiftRepository) GetOpenByCashierID(ctx context.Context, cashierID int64) (*entity.CashierShift, error) {
+	var shift entity.CashierShift
+	if err := r.db.WithContext(ctx).Preload("Cashier").
+		Where("cashier_id = ? AND status = ?", cashierID, entity.ShiftStatusOpen).
+		First(&shift).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNoOpenShift
+		}
+		r.log.Errorf("Error getting open cashier shift: %v", err)
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// GetByDateRange returns the shifts opened in [start, end)
+func (r *shiftRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]entity.CashierShift, error) {
+	var shifts []entity.CashierShift
+	if err := r.db.WithContext(ctx).Preload("Cashier").
+		Where("opened_at >= ? AND opened_at < ?", start, end).
+		Order("opened_at ASC").
+		Find(&shifts).Error; err != nil {
+		r.log.Errorf("Error getting cashier shifts by date range: %v", err)
+		return nil, err
+	}
+	return shifts, nil
+}
+
+func (r *shiftRepository) Close(ctx context.Context, shift *entity.CashierShift) error {
+	result := r.db.WithContext(ctx).Model(&entity.CashierShift{}).
+		Where("id = ? AND status = ?", shift.ID, entity.ShiftStatusOpen).
+		Updates(map[string]interface{}{
+			"status":          entity.ShiftStatusClosed,
+			"expected_amount": shift.ExpectedAmount,
+			"counted_amount":  shift.CountedAmount,
+			"variance":        shift.Variance,
+			"notes":           shift.Notes,
+			"closed_at":       shift.ClosedAt,
+		})
+	if result.Error != nil {
+		r.log.Errorf("Error closing cashier shift: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return constants.ErrNoOpenShift
+	}
+	return nil
+}
+
+func (r *shiftRepository) CreateTransaction(ctx context.Context, transaction *entity.ShiftTransaction) error {
+	if err := r.db.WithContext(ctx).Create(transaction).Error; err != nil {
+		r.log.Errorf("Error creating shift transaction: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *shiftRepository) GetTransactions(ctx context.Context, shiftID int64) ([]entity.ShiftTransaction, error) {
+	var transactions []entity.ShiftTransaction
+	if err := r.db.WithContext(ctx).Where("shift_id = ?", shiftID).Order("created_at ASC").Find(&transactions).Error; err != nil {
+		r.log.Errorf("Error getting shift transactions: %v", err)
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (r *shiftRepository) GetTransactionsByDateRange(ctx context.Context, start, end time.Time) ([]entity.ShiftTransaction, error) {
+	var transactions []entity.ShiftTransaction
+	if err := r.db.WithContext(ctx).Where("created_at >= ? AND created_at < ?", start, end).Find(&transactions).Error; err != nil {
+		r.log.Errorf("Error getting shift transactions by date range: %v", err)
+		return nil, err
+	}
+	return transactions, nil
+}
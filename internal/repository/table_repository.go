@@ -2,21 +2,24 @@ package repository
 
 import (
 	"cakestore/internal/domain/entity"
+	"context"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TableRepository interface {
-	Count() (int64, error)
-	Create(table *entity.Table) error
-	GetByID(id uint) (*entity.Table, error)
-	GetAll() ([]entity.Table, error)
-	Update(table *entity.Table) error
-	Delete(id uint) error
-	GetAvailableTables(reserveTime time.Time, duration time.Duration) ([]entity.Table, error)
-	UpdateAvailability(id uint, isAvailable bool) error
+	Count(ctx context.Context) (int64, error)
+	Create(ctx context.Context, table *entity.Table) error
+	GetByID(ctx context.Context, id uint) (*entity.Table, error)
+	GetAll(ctx context.Context) ([]entity.Table, error)
+	Update(ctx context.Context, table *entity.Table) error
+	Delete(ctx context.Context, id uint) error
+	GetAvailableTables(ctx context.Context, reserveTime time.Time, duration time.Duration) ([]entity.Table, error)
+	UpdateAvailability(ctx context.Context, id uint, isAvailable bool) error
+	IncrementQRTokenVersion(ctx context.Context, id uint) (int, error)
 }
 
 type tableRepository struct {
@@ -28,58 +31,73 @@ func NewTableRepository(db *gorm.DB, log *logrus.Logger) TableRepository {
 	return &tableRepository{db: db, log: log}
 }
 
-func (r *tableRepository) Count() (int64, error) {
+func (r *tableRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.Model(&entity.Table{}).Count(&count).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&entity.Table{}).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-func (r *tableRepository) Create(table *entity.Table) error {
-	return r.db.Create(table).Error
+func (r *tableRepository) Create(ctx context.Context, table *entity.Table) error {
+	return r.db.WithContext(ctx).Create(table).Error
 }
 
-func (r *tableRepository) GetByID(id uint) (*entity.Table, error) {
+func (r *tableRepository) GetByID(ctx context.Context, id uint) (*entity.Table, error) {
 	var table entity.Table
-	if err := r.db.First(&table, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&table, id).Error; err != nil {
 		return nil, err
 	}
 	return &table, nil
 }
 
-func (r *tableRepository) GetAll() ([]entity.Table, error) {
+func (r *tableRepository) GetAll(ctx context.Context) ([]entity.Table, error) {
 	var tables []entity.Table
-	if err := r.db.Find(&tables).Error; err != nil {
+	if err := r.db.WithContext(ctx).Find(&tables).Error; err != nil {
 		return nil, err
 	}
 	return tables, nil
 }
 
-func (r *tableRepository) Update(table *entity.Table) error {
-	return r.db.Save(table).Error
+func (r *tableRepository) Update(ctx context.Context, table *entity.Table) error {
+	return r.db.WithContext(ctx).Save(table).Error
 }
 
-func (r *tableRepository) Delete(id uint) error {
-	return r.db.Delete(&entity.Table{}, id).Error
+func (r *tableRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&entity.Table{}, id).Error
 }
 
-func (r *tableRepository) GetAvailableTables(reserveTime time.Time, duration time.Duration) ([]entity.Table, error) {
+func (r *tableRepository) GetAvailableTables(ctx context.Context, reserveTime time.Time, duration time.Duration) ([]entity.Table, error) {
 	var tables []entity.Table
 	endTime := reserveTime.Add(duration)
 
-	subQuery := r.db.Model(&entity.Reservation{}).Select("table_id").Where(
+	subQuery := r.db.WithContext(ctx).Model(&entity.Reservation{}).Select("table_id").Where(
 		"(reserved_at BETWEEN ? AND ?) OR (reserved_at + duration * interval '1 minute' BETWEEN ? AND ?)",
 		reserveTime, endTime, reserveTime, endTime,
 	)
 
-	if err := r.db.Where("id NOT IN (?) AND is_available = ?", subQuery, true).Find(&tables).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("id NOT IN (?) AND is_available = ?", subQuery, true).Find(&tables).Error; err != nil {
 		return nil, err
 	}
 
 	return tables, nil
 }
 
-func (r *tableRepository) UpdateAvailability(id uint, isAvailable bool) error {
-	return r.db.Model(&entity.Table{}).Where("id = ?", id).Update("is_available", isAvailable).Error
+func (r *tableRepository) UpdateAvailability(ctx context.Context, id uint, isAvailable bool) error {
+	return r.db.WithContext(ctx).Model(&entity.Table{}).Where("id = ?", id).Update("is_available", isAvailable).Error
+}
+
+func (r *tableRepository) IncrementQRTokenVersion(ctx context.Context, id uint) (int, error) {
+	var table entity.Table
+	result := r.db.WithContext(ctx).Model(&table).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "qr_token_version"}}}).
+		Where("id = ?", id).
+		UpdateColumn("qr_token_version", gorm.Expr("qr_token_version + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return table.QRTokenVersion, nil
 }
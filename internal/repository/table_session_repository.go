@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TableSessionRepository interface {
+	Create(ctx context.Context, session *entity.TableSession) error
+	GetByID(ctx context.Context, id int64) (*entity.TableSession, error)
+	GetOpenByTableID(ctx context.Context, tableID int64) (*entity.TableSession, error)
+	Close(ctx context.Context, id int64) error
+}
+
+type tableSessionRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewTableSessionRepository(db *gorm.DB, log *logrus.Logger) TableSessionRepository {
+	return &tableSessionRepository{db: db, log: log}
+}
+
+func (r *tableSessionRepository) Create(ctx context.Context, session *entity.TableSession) error {
+	if err := r.db.WithContext(ctx).Omit(clause.Associations).Create(session).Error; err != nil {
+		r.log.Errorf("Error creating table session: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *tableSessionRepository) GetByID(ctx context.Context, id int64) (*entity.TableSession, error) {
+	var session entity.TableSession
+	if err := r.db.WithContext(ctx).Preload("Table").First(&session, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.Errorf("Error getting table session by ID: %v", err)
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *tableSessionRepository) GetOpenByTableID(ctx context.Context, tableID int64) (*entity.TableSession, error) {
+	var session entity.TableSession
+	if err := r.db.WithContext(ctx).Preload("Table").
+		Where("table_id = ? AND status = ?", tableID, entity.TableSessionStatusOpen).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.Errorf("Error getting open table session: %v", err)
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *tableSessionRepository) Close(ctx context.Context, id int64) error {
+	result := r.db.WithContext(ctx).Model(&entity.TableSession{}).
+		Where("id = ? AND status = ?", id, entity.TableSessionStatusOpen).
+		Updates(map[string]interface{}{
+			"status":    entity.TableSessionStatusClosed,
+			"closed_at": sql.NullTime{Time: time.Now(), Valid: true},
+		})
+	if result.Error != nil {
+		r.log.Errorf("Error closing table session: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return constants.ErrTableSessionClosed
+	}
+	return nil
+}
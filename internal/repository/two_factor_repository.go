@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	GetByCustomer(ctx context.Context, customerID int64) (*entity.TwoFactor, error)
+	Save(ctx context.Context, twoFactor *entity.TwoFactor) error
+	// Delete removes the enrolment together with its recovery codes
+	Delete(ctx context.Context, customerID int64) error
+	// MarkStepUsed reports false when a code of this or a later step was already accepted
+	MarkStepUsed(ctx context.Context, customerID int64, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, customerID int64, codeHashes []string) error
+	// UseRecoveryCode reports false when the code does not exist or was already used
+	UseRecoveryCode(ctx context.Context, customerID int64, codeHash string) (bool, error)
+}
+
+type twoFactorRepository struct {
+	db  *gorm.DB
+	log *logrus.Logger
+}
+
+func NewTwoFactorRepository(db *gorm.DB, log *logrus.Logger) TwoFactorRepository {
+	return &twoFactorRepository{db: db, log: log}
+}
+
+func (r *twoFactorRepository) GetByCustomer(ctx context.Context, customerID int64) (*entity.TwoFactor, error) {
+	var twoFactor entity.TwoFactor
+	if err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, constants.ErrNotFound
+		}
+		r.log.WithError(err).Error("Failed to get two-factor enrolment")
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepository) Save(ctx context.Context, twoFactor *entity.TwoFactor) error {
+	if err := r.db.WithContext(ctx).Save(twoFactor).Error; err != nil {
+		r.log.WithError(err).Error("Failed to save two-factor enrolment")
+		return err
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, customerID int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("customer_id = ?", customerID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete recovery codes")
+			return err
+		}
+		if err := tx.Where("customer_id = ?", customerID).Delete(&entity.TwoFactor{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete two-factor enrolment")
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *twoFactorRepository) MarkStepUsed(ctx context.Context, customerID int64, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.TwoFactor{}).
+		Where("customer_id = ? AND last_used_step < ?", customerID, step).
+		Updates(map[string]interface{}{"last_used_step": step, "updated_at": time.Now()})
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to record two-factor code use")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, customerID int64, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("customer_id = ?", customerID).Delete(&entity.RecoveryCode{}).Error; err != nil {
+			r.log.WithError(err).Error("Failed to delete recovery codes")
+			return err
+		}
+
+		now := time.Now()
+		codes := make([]entity.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, entity.RecoveryCode{CustomerID: customerID, CodeHash: hash, CreatedAt: now})
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			r.log.WithError(err).Error("Failed to create recovery codes")
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, customerID int64, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.RecoveryCode{}).
+		Where("customer_id = ? AND code_hash = ? AND used_at IS NULL", customerID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		r.log.WithError(result.Error).Error("Failed to use recovery code")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_StopLetsRunningJobFinish(t *testing.T) {
+	s := NewScheduler(logrus.New())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var jobErr error
+	s.Add(Job{Name: "slow", Interval: time.Hour, Run: func(ctx context.Context) error {
+		close(started)
+		<-release
+		jobErr = ctx.Err()
+		return nil
+	}})
+	s.Start(context.Background())
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while the job was still running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-stopped)
+	assert.NoError(t, jobErr)
+}
+
+func TestScheduler_StopCancelsJobsAfterDeadline(t *testing.T) {
+	s := NewScheduler(logrus.New())
+	started := make(chan struct{})
+	s.Add(Job{Name: "stuck", Interval: time.Hour, Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	s.Start(context.Background())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Stop(ctx), context.DeadlineExceeded)
+	s.Wait()
+}
@@ -4,6 +4,9 @@ import (
 	"cakestore/internal/constants"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,9 +25,46 @@ func NewCustomerSeeder(repo repository.CustomerRepository, logger *logrus.Logger
 	}
 }
 
+// minAdminPasswordLength keeps create-admin from setting a trivial password
+const minAdminPasswordLength = 12
+
+// CreateAdmin adds another admin account. Unlike SeedAdmin it fails when the
+// email is taken, so an existing account is never mistaken for the new one.
+func (s *CustomerSeeder) CreateAdmin(name, email, password string) (*entity.Customer, error) {
+	if len(password) < minAdminPasswordLength {
+		return nil, fmt.Errorf("admin passwords need at least %d characters", minAdminPasswordLength)
+	}
+	if existing, err := s.repo.GetByEmail(context.Background(), email); err == nil && existing != nil {
+		return nil, constants.ErrEmailAlreadyRegistered
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	admin := &entity.Customer{
+		Name:            name,
+		Email:           email,
+		Password:        string(hashedPassword),
+		Role:            constants.RoleAdmin,
+		EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := s.repo.Create(context.Background(), admin); err != nil {
+		s.logger.Errorf("Error creating admin user: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Admin %s created", email)
+	return admin, nil
+}
+
 func (s *CustomerSeeder) SeedAdmin(email, password string) error {
 	// Check if admin already exists
-	existingAdmin, err := s.repo.GetByEmail(email)
+	existingAdmin, err := s.repo.GetByEmail(context.Background(), email)
 	if err == nil && existingAdmin != nil {
 		s.logger.Info("Admin user already exists")
 		return nil
@@ -48,7 +88,7 @@ func (s *CustomerSeeder) SeedAdmin(email, password string) error {
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.repo.Create(admin); err != nil {
+	if err := s.repo.Create(context.Background(), admin); err != nil {
 		s.logger.Errorf("Error creating admin user: %v", err)
 		return err
 	}
@@ -59,7 +99,7 @@ func (s *CustomerSeeder) SeedAdmin(email, password string) error {
 
 func (s *CustomerSeeder) SeedKitchenStaff(email, password string) error {
 	// Check if admin already exists
-	existingKitchenStaff, err := s.repo.GetByEmail(email)
+	existingKitchenStaff, err := s.repo.GetByEmail(context.Background(), email)
 	if err == nil && existingKitchenStaff != nil {
 		s.logger.Info("Kitchen staff user already exists")
 		return nil
@@ -83,7 +123,7 @@ func (s *CustomerSeeder) SeedKitchenStaff(email, password string) error {
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.repo.Create(kitchenStaff); err != nil {
+	if err := s.repo.Create(context.Background(), kitchenStaff); err != nil {
 		s.logger.Errorf("Error creating kitchen staff user: %v", err)
 		return err
 	}
@@ -94,7 +134,7 @@ func (s *CustomerSeeder) SeedKitchenStaff(email, password string) error {
 
 func (s *CustomerSeeder) SeedWaiter(email, password string) error {
 	// Check if admin already exists
-	existingWaiter, err := s.repo.GetByEmail(email)
+	existingWaiter, err := s.repo.GetByEmail(context.Background(), email)
 	if err == nil && existingWaiter != nil {
 		s.logger.Info("Waiter user already exists")
 		return nil
@@ -118,7 +158,7 @@ func (s *CustomerSeeder) SeedWaiter(email, password string) error {
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.repo.Create(waiter); err != nil {
+	if err := s.repo.Create(context.Background(), waiter); err != nil {
 		s.logger.Errorf("Error creating waiter user: %v", err)
 		return err
 	}
@@ -129,7 +169,7 @@ func (s *CustomerSeeder) SeedWaiter(email, password string) error {
 
 func (s *CustomerSeeder) SeedCashier(email, password string) error {
 	// Check if admin already exists
-	existingCashier, err := s.repo.GetByEmail(email)
+	existingCashier, err := s.repo.GetByEmail(context.Background(), email)
 	if err == nil && existingCashier != nil {
 		s.logger.Info("Cashier user already exists")
 		return nil
@@ -152,7 +192,7 @@ func (s *CustomerSeeder) SeedCashier(email, password string) error {
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.repo.Create(cashier); err != nil {
+	if err := s.repo.Create(context.Background(), cashier); err != nil {
 		s.logger.Errorf("Error creating cashier user: %v", err)
 		return err
 	}
@@ -163,7 +203,7 @@ func (s *CustomerSeeder) SeedCashier(email, password string) error {
 
 func (s *CustomerSeeder) SeedBasic(email, password string) error {
 	// Check if admin already exists
-	existingCust, err := s.repo.GetByEmail(email)
+	existingCust, err := s.repo.GetByEmail(context.Background(), email)
 	if err == nil && existingCust != nil {
 		s.logger.Info("user already exists")
 		return nil
@@ -176,18 +216,19 @@ func (s *CustomerSeeder) SeedBasic(email, password string) error {
 		return err
 	}
 
-	// Create cust user
+	// Create cust user, already verified so it can place orders
 	cust := &entity.Customer{
-		Name:      "Rafli Dewanto",
-		Email:     email,
-		Password:  string(hashedPassword),
-		Address:   "Bekasi",
-		Role:      constants.RoleCustomer,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Name:            "Rafli Dewanto",
+		Email:           email,
+		Password:        string(hashedPassword),
+		Address:         "Bekasi",
+		Role:            constants.RoleCustomer,
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
-	if err := s.repo.Create(cust); err != nil {
+	if err := s.repo.Create(context.Background(), cust); err != nil {
 		s.logger.Errorf("Error creating customer user: %v", err)
 		return err
 	}
@@ -5,6 +5,7 @@ import (
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
 	"cakestore/internal/repository"
+	"context"
 	"fmt"
 	"math/rand"
 	"time"
@@ -26,7 +27,7 @@ func NewMenuSeeder(repo repository.MenuRepository, logger *logrus.Logger) *MenuS
 
 func (s *MenuSeeder) SeedMenus() error {
 	// Seed only if no data exists to avoid re-seeding 100,000 records every time
-	menuData, err := s.repo.GetAll(&model.MenuQueryParams{Limit: 1}) // Just check if any record exists
+	menuData, err := s.repo.GetAll(context.Background(), &model.MenuQueryParams{Limit: 1}) // Just check if any record exists
 	if err != nil {
 		s.logger.Errorf("Error checking for existing menus: %v", err)
 		return err
@@ -87,7 +88,7 @@ func (s *MenuSeeder) SeedMenus() error {
 	// If you don't have a batch insert, the loop below will work but will be slow.
 
 	for i, m := range menus {
-		if err := s.repo.Create(&m); err != nil {
+		if err := s.repo.Create(context.Background(), &m); err != nil {
 			s.logger.Errorf("Error seeding menu %s (item %d/%d): %v", m.Title, i+1, numberOfMenusToSeed, err)
 			return err
 		}
@@ -2,11 +2,44 @@ package seeder
 
 import (
 	"cakestore/internal/repository"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 )
 
+// devPassword is shared by every development account
+const devPassword = "master123"
+
+// Profile picks what Seed creates
+type Profile string
+
+const (
+	// ProfileMinimal creates only the admin account, and only when an admin
+	// password is configured
+	ProfileMinimal Profile = "minimal"
+	// ProfileDemo adds demo staff and customer accounts sharing the password
+	// master123, plus sample inventory, menus and tables. Development only.
+	ProfileDemo Profile = "demo"
+)
+
+// ParseProfile checks a profile name from the command line or config
+func ParseProfile(name string) (Profile, error) {
+	switch profile := Profile(name); profile {
+	case ProfileMinimal, ProfileDemo:
+		return profile, nil
+	default:
+		return "", fmt.Errorf("unknown seed profile %q, expected %s or %s", name, ProfileMinimal, ProfileDemo)
+	}
+}
+
+// Options holds the admin account created by every profile
+type Options struct {
+	AdminEmail    string
+	AdminPassword string
+}
+
 type Seeder struct {
+	options         Options
 	customerSeeder  *CustomerSeeder
 	menuSeeder      *MenuSeeder
 	inventorySeeder *InventorySeeder
@@ -20,8 +53,13 @@ func NewSeeder(
 	logger *logrus.Logger,
 	inventorySeeder repository.InventoryRepository,
 	tableSeeder repository.TableRepository,
+	options Options,
 ) *Seeder {
+	if options.AdminEmail == "" {
+		options.AdminEmail = "admin@email.com"
+	}
 	return &Seeder{
+		options:         options,
 		customerSeeder:  NewCustomerSeeder(customerRepo, logger),
 		menuSeeder:      NewMenuSeeder(menuRepo, logger),
 		logger:          logger,
@@ -30,33 +68,32 @@ func NewSeeder(
 	}
 }
 
-func (s *Seeder) SeedAll() error {
-	s.logger.Info("Starting database seeding...")
+// Seed creates what the profile describes. Records that already exist are
+// left alone, so seeding twice is harmless.
+func (s *Seeder) Seed(profile Profile) error {
+	s.logger.Infof("Starting database seeding with the %s profile...", profile)
+	demo := profile == ProfileDemo
 
-	// Seed admin user
-	if err := s.customerSeeder.SeedAdmin("admin@email.com", "master123"); err != nil {
-		s.logger.Errorf("Error seeding admin user: %v", err)
-		return err
-	}
-
-	// seed staffs
-	if err := s.customerSeeder.SeedKitchenStaff("andy@email.com", "master123"); err != nil {
-		s.logger.Errorf("Error seeding kitchen staff user: %v", err)
-		return err
+	// Seed admin user, who creates the other staff accounts
+	adminPassword := s.options.AdminPassword
+	if adminPassword == "" && demo {
+		adminPassword = devPassword
 	}
-
-	if err := s.customerSeeder.SeedWaiter("jack@email.com", "master123"); err != nil {
-		s.logger.Errorf("Error seeding waiter user: %v", err)
-		return err
+	if adminPassword != "" {
+		if err := s.customerSeeder.SeedAdmin(s.options.AdminEmail, adminPassword); err != nil {
+			s.logger.Errorf("Error seeding admin user: %v", err)
+			return err
+		}
+	} else {
+		s.logger.Info("Skipping admin user, no admin password configured")
 	}
 
-	if err := s.customerSeeder.SeedCashier("amanda@email.com", "master123"); err != nil {
-		s.logger.Errorf("Error seeding cashier user: %v", err)
-		return err
+	if !demo {
+		s.logger.Info("Database seeding completed successfully")
+		return nil
 	}
 
-	if err := s.customerSeeder.SeedBasic("rafli@email.com", "master123"); err != nil {
-		s.logger.Errorf("Error seeding customer user: %v", err)
+	if err := s.seedDevAccounts(); err != nil {
 		return err
 	}
 
@@ -81,3 +118,29 @@ func (s *Seeder) SeedAll() error {
 	s.logger.Info("Database seeding completed successfully")
 	return nil
 }
+
+// seedDevAccounts creates one account per staff role and a verified customer
+func (s *Seeder) seedDevAccounts() error {
+	s.logger.Warn("Seeding development accounts with a shared password, do not enable this in production")
+
+	if err := s.customerSeeder.SeedKitchenStaff("andy@email.com", devPassword); err != nil {
+		s.logger.Errorf("Error seeding kitchen staff user: %v", err)
+		return err
+	}
+
+	if err := s.customerSeeder.SeedWaiter("jack@email.com", devPassword); err != nil {
+		s.logger.Errorf("Error seeding waiter user: %v", err)
+		return err
+	}
+
+	if err := s.customerSeeder.SeedCashier("amanda@email.com", devPassword); err != nil {
+		s.logger.Errorf("Error seeding cashier user: %v", err)
+		return err
+	}
+
+	if err := s.customerSeeder.SeedBasic("rafli@email.com", devPassword); err != nil {
+		s.logger.Errorf("Error seeding customer user: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package tracing sets up OpenTelemetry for the API process. Spans start in
+// the HTTP middleware and follow the request context through the use cases
+// into GORM, Redis and the calls to Midtrans.
+package tracing
+
+import (
+	configs "cakestore/internal/config"
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Setup installs the global tracer provider for TRACING_EXPORTER and the W3C
+// trace context propagator, so a traceparent header sent by a client or a
+// proxy continues its trace here. The returned func flushes the spans still
+// buffered; with the none exporter there is nothing to flush and spans are
+// not recorded at all.
+func Setup(ctx context.Context, cfg *configs.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(cfg.TRACING_SERVICE_NAME),
+			semconv.DeploymentEnvironment(cfg.SERVER_ENV),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe the tracing resource: %w", err)
+	}
+
+	// Requests that arrive with a sampled parent stay sampled, so a trace
+	// started upstream is not cut short by the local ratio
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TRACING_SAMPLE_RATIO))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg *configs.Config) (sdktrace.SpanExporter, error) {
+	switch cfg.TRACING_EXPORTER {
+	case configs.TracingStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case configs.TracingOTLP:
+		// Without an endpoint the exporter reads the standard
+		// OTEL_EXPORTER_OTLP_* variables
+		var opts []otlptracehttp.Option
+		if cfg.TRACING_OTLP_ENDPOINT != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.TRACING_OTLP_ENDPOINT))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	configs "cakestore/internal/config"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetup_ContinuesIncomingTraceContext(t *testing.T) {
+	shutdown, err := Setup(context.Background(), &configs.Config{
+		TRACING_EXPORTER:     configs.TracingNone,
+		TRACING_SERVICE_NAME: "cakestore",
+	})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+
+	span := trace.SpanContextFromContext(ctx)
+	assert.True(t, span.IsRemote())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID().String())
+}
+
+func TestSetup_StdoutRecordsSpans(t *testing.T) {
+	shutdown, err := Setup(context.Background(), &configs.Config{
+		TRACING_EXPORTER:     configs.TracingStdout,
+		TRACING_SERVICE_NAME: "cakestore",
+		TRACING_SAMPLE_RATIO: 1,
+	})
+	require.NoError(t, err)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test")
+	assert.True(t, span.IsRecording())
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+}
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/notification"
+	"cakestore/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AccountPolicy configures the emailed account links. The URLs are the pages
+// the links open; the token is appended as ?token=. Without a URL the email
+// carries the bare token.
+type AccountPolicy struct {
+	PasswordResetURL string
+	VerificationURL  string
+	InviteURL        string
+	PasswordResetTTL time.Duration
+	VerificationTTL  time.Duration
+	InviteTTL        time.Duration
+}
+
+// AccountUseCase owns the flows that prove control of an email address:
+// password reset, email verification and staff invites.
+type AccountUseCase interface {
+	ForgotPassword(ctx context.Context, request *model.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, request *model.ResetPasswordRequest) error
+	SendVerification(ctx context.Context, customer *entity.Customer) error
+	ResendVerification(ctx context.Context, customerID int64) error
+	VerifyEmail(ctx context.Context, token string) error
+	// SendInvite mails a new employee the link to choose their password
+	SendInvite(ctx context.Context, customer *entity.Customer) error
+	AcceptInvite(ctx context.Context, request *model.AcceptInviteRequest) error
+}
+
+type accountUseCase struct {
+	tokenRepo    repository.AccountTokenRepository
+	customerRepo repository.CustomerRepository
+	sessions     SessionUseCase
+	sender       notification.Sender
+	log          *logrus.Logger
+	cache        database.RedisCache
+	policy       AccountPolicy
+}
+
+func NewAccountUseCase(
+	tokenRepo repository.AccountTokenRepository,
+	customerRepo repository.CustomerRepository,
+	sessions SessionUseCase,
+	sender notification.Sender,
+	log *logrus.Logger,
+	cache database.RedisCache,
+	policy AccountPolicy,
+) AccountUseCase {
+	if policy.PasswordResetTTL <= 0 {
+		policy.PasswordResetTTL = time.Hour
+	}
+	if policy.VerificationTTL <= 0 {
+		policy.VerificationTTL = 48 * time.Hour
+	}
+	if policy.InviteTTL <= 0 {
+		policy.InviteTTL = 72 * time.Hour
+	}
+	return &accountUseCase{
+		tokenRepo:    tokenRepo,
+		customerRepo: customerRepo,
+		sessions:     sessions,
+		sender:       sender,
+		log:          log,
+		cache:        cache,
+		policy:       policy,
+	}
+}
+
+// ForgotPassword mails a reset link. It succeeds for unknown addresses too, so
+// the endpoint cannot be used to find out who has an account.
+func (uc *accountUseCase) ForgotPassword(ctx context.Context, request *model.ForgotPasswordRequest) error {
+	customer, err := uc.customerRepo.GetByEmail(ctx, request.Email)
+	if err != nil {
+		uc.log.Infof("Password reset requested for unknown email")
+		return nil
+	}
+
+	link, err := uc.newLink(ctx, customer.ID, entity.AccountTokenPasswordReset, uc.policy.PasswordResetTTL, uc.policy.PasswordResetURL)
+	if err != nil {
+		return err
+	}
+
+	return uc.sender.Send(ctx, notification.Message{
+		To:      customer.Email,
+		Subject: "Reset your CakeStore password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your CakeStore account. "+
+			"If it was you, use this link within %s:\n%s\n\nIf it wasn't, you can ignore this email.",
+			customer.Name, formatValidity(uc.policy.PasswordResetTTL), link),
+	})
+}
+
+func (uc *accountUseCase) ResetPassword(ctx context.Context, request *model.ResetPasswordRequest) error {
+	token, err := uc.redeem(ctx, request.Token, entity.AccountTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+
+	customer, err := uc.setPassword(ctx, token.CustomerID, request.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.tokenRepo.InvalidateUnused(ctx, customer.ID, entity.AccountTokenPasswordReset); err != nil {
+		uc.log.Errorf("Error invalidating reset links for customer %d: %v", customer.ID, err)
+	}
+
+	return uc.sessions.RevokeAll(ctx, customer.ID)
+}
+
+func (uc *accountUseCase) SendVerification(ctx context.Context, customer *entity.Customer) error {
+	link, err := uc.newLink(ctx, customer.ID, entity.AccountTokenEmailVerification, uc.policy.VerificationTTL, uc.policy.VerificationURL)
+	if err != nil {
+		return err
+	}
+
+	return uc.sender.Send(ctx, notification.Message{
+		To:      customer.Email,
+		Subject: "Verify your CakeStore email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm this is your email address so you can start ordering:\n%s\n\nThe link is valid for %s.",
+			customer.Name, link, formatValidity(uc.policy.VerificationTTL)),
+	})
+}
+
+func (uc *accountUseCase) ResendVerification(ctx context.Context, customerID int64) error {
+	customer, err := uc.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if customer.EmailVerifiedAt.Valid {
+		return constants.ErrEmailAlreadyVerified
+	}
+	return uc.SendVerification(ctx, customer)
+}
+
+func (uc *accountUseCase) VerifyEmail(ctx context.Context, rawToken string) error {
+	token, err := uc.redeem(ctx, rawToken, entity.AccountTokenEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	customer, err := uc.customerRepo.GetByID(ctx, token.CustomerID)
+	if err != nil {
+		return constants.ErrInvalidAccountToken
+	}
+	if customer.EmailVerifiedAt.Valid {
+		return nil
+	}
+
+	customer.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	customer.UpdatedAt = time.Now()
+	if err := uc.customerRepo.Update(ctx, customer); err != nil {
+		uc.log.Errorf("Error verifying email: %v", err)
+		return err
+	}
+	uc.invalidateCustomerCache(ctx, customer.ID)
+
+	uc.log.Infof("Customer %d verified their email address", customer.ID)
+	return nil
+}
+
+func (uc *accountUseCase) SendInvite(ctx context.Context, customer *entity.Customer) error {
+	link, err := uc.newLink(ctx, customer.ID, entity.AccountTokenInvite, uc.policy.InviteTTL, uc.policy.InviteURL)
+	if err != nil {
+		return err
+	}
+
+	return uc.sender.Send(ctx, notification.Message{
+		To:      customer.Email,
+		Subject: "You have been invited to CakeStore",
+		Body: fmt.Sprintf("Hi %s,\n\nA CakeStore staff account has been created for you. "+
+			"Use this link within %s to choose your password:\n%s",
+			customer.Name, formatValidity(uc.policy.InviteTTL), link),
+	})
+}
+
+func (uc *accountUseCase) AcceptInvite(ctx context.Context, request *model.AcceptInviteRequest) error {
+	token, err := uc.redeem(ctx, request.Token, entity.AccountTokenInvite)
+	if err != nil {
+		return err
+	}
+
+	customer, err := uc.setPassword(ctx, token.CustomerID, request.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.tokenRepo.InvalidateUnused(ctx, customer.ID, entity.AccountTokenInvite); err != nil {
+		uc.log.Errorf("Error invalidating invites for customer %d: %v", customer.ID, err)
+	}
+
+	uc.log.Infof("Employee %d accepted their invite", customer.ID)
+	return nil
+}
+
+// setPassword stores the password chosen through an emailed link. Opening the
+// link proves the account holder controls the address, so it is verified too.
+func (uc *accountUseCase) setPassword(ctx context.Context, customerID int64, password string) (*entity.Customer, error) {
+	customer, err := uc.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, constants.ErrInvalidAccountToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		uc.log.Errorf("Error hashing password: %v", err)
+		return nil, err
+	}
+
+	customer.Password = string(hashedPassword)
+	if !customer.EmailVerifiedAt.Valid {
+		customer.EmailVerifiedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	customer.UpdatedAt = time.Now()
+	if err := uc.customerRepo.Update(ctx, customer); err != nil {
+		uc.log.Errorf("Error setting password: %v", err)
+		return nil, err
+	}
+	uc.invalidateCustomerCache(ctx, customer.ID)
+	return customer, nil
+}
+
+// newLink stores a fresh token, retiring older unused ones of the same
+// purpose, and returns the link to mail.
+func (uc *accountUseCase) newLink(ctx context.Context, customerID int64, purpose entity.AccountTokenPurpose, ttl time.Duration, baseURL string) (string, error) {
+	if err := uc.tokenRepo.InvalidateUnused(ctx, customerID, purpose); err != nil {
+		return "", err
+	}
+
+	raw, err := newOpaqueToken()
+	if err != nil {
+		uc.log.Errorf("Error generating %s token: %v", purpose, err)
+		return "", err
+	}
+
+	now := time.Now()
+	if err := uc.tokenRepo.Create(ctx, &entity.AccountToken{
+		CustomerID: customerID,
+		Purpose:    purpose,
+		TokenHash:  hashOpaqueToken(raw),
+		ExpiresAt:  now.Add(ttl),
+		CreatedAt:  now,
+	}); err != nil {
+		return "", err
+	}
+
+	if baseURL == "" {
+		return raw, nil
+	}
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s URL: %w", purpose, err)
+	}
+	query := link.Query()
+	query.Set("token", raw)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// redeem checks a token and marks it used so it only ever works once.
+func (uc *accountUseCase) redeem(ctx context.Context, raw string, purpose entity.AccountTokenPurpose) (*entity.AccountToken, error) {
+	token, err := uc.tokenRepo.GetByHash(ctx, hashOpaqueToken(raw))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrInvalidAccountToken
+		}
+		return nil, err
+	}
+	if token.Purpose != purpose || token.UsedAt.Valid || time.Now().After(token.ExpiresAt) {
+		return nil, constants.ErrInvalidAccountToken
+	}
+
+	used, err := uc.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, constants.ErrInvalidAccountToken
+	}
+	return token, nil
+}
+
+func (uc *accountUseCase) invalidateCustomerCache(ctx context.Context, customerID int64) {
+	cacheKey := fmt.Sprintf("customer:%d", customerID)
+	if err := uc.cache.Delete(ctx, cacheKey); err != nil {
+		uc.log.Errorf("Error deleting cache for customer ID %d: %v", customerID, err)
+	}
+}
+
+// formatValidity renders a link lifetime for an email, e.g. "1 hour" or "30 minutes"
+func formatValidity(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		if hours := int(d / time.Hour); hours != 1 {
+			return fmt.Sprintf("%d hours", hours)
+		}
+		return "1 hour"
+	}
+	return fmt.Sprintf("%d minutes", int(d/time.Minute))
+}
@@ -0,0 +1,373 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/notification"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockAccountTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountTokenRepository) Create(ctx context.Context, token *entity.AccountToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAccountTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.AccountToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.AccountToken), args.Error(1)
+}
+
+func (m *MockAccountTokenRepository) MarkUsed(ctx context.Context, id int64) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccountTokenRepository) InvalidateUnused(ctx context.Context, customerID int64, purpose entity.AccountTokenPurpose) error {
+	args := m.Called(customerID, purpose)
+	return args.Error(0)
+}
+
+type MockAccountUseCase struct {
+	mock.Mock
+}
+
+func (m *MockAccountUseCase) ForgotPassword(ctx context.Context, request *model.ForgotPasswordRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockAccountUseCase) ResetPassword(ctx context.Context, request *model.ResetPasswordRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func (m *MockAccountUseCase) SendVerification(ctx context.Context, customer *entity.Customer) error {
+	args := m.Called(customer)
+	return args.Error(0)
+}
+
+func (m *MockAccountUseCase) ResendVerification(ctx context.Context, customerID int64) error {
+	args := m.Called(customerID)
+	return args.Error(0)
+}
+
+func (m *MockAccountUseCase) VerifyEmail(ctx context.Context, token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAccountUseCase) SendInvite(ctx context.Context, customer *entity.Customer) error {
+	args := m.Called(customer)
+	return args.Error(0)
+}
+
+func (m *MockAccountUseCase) AcceptInvite(ctx context.Context, request *model.AcceptInviteRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func TestAccountUseCase_ForgotPassword(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("unknown email", func(t *testing.T) {
+		mockTokenRepo := new(MockAccountTokenRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockSender := new(MockNotificationSender)
+		useCase := NewAccountUseCase(mockTokenRepo, mockCustomerRepo, nil, mockSender, logger, nil, AccountPolicy{})
+
+		mockCustomerRepo.On("GetByEmail", "nobody@example.com").Return(nil, constants.ErrNotFound).Once()
+
+		err := useCase.ForgotPassword(context.Background(), &model.ForgotPasswordRequest{Email: "nobody@example.com"})
+
+		assert.NoError(t, err)
+		mockTokenRepo.AssertNotCalled(t, "Create", mock.Anything)
+		mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("mails a reset link", func(t *testing.T) {
+		mockTokenRepo := new(MockAccountTokenRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockSender := new(MockNotificationSender)
+		useCase := NewAccountUseCase(mockTokenRepo, mockCustomerRepo, nil, mockSender, logger, nil, AccountPolicy{
+			PasswordResetURL: "https://cakestore.example/reset",
+		})
+
+		mockCustomerRepo.On("GetByEmail", "a@example.com").Return(&entity.Customer{ID: 7, Name: "Alice", Email: "a@example.com"}, nil).Once()
+		mockTokenRepo.On("InvalidateUnused", int64(7), entity.AccountTokenPasswordReset).Return(nil).Once()
+		mockTokenRepo.On("Create", mock.MatchedBy(func(token *entity.AccountToken) bool {
+			return token.CustomerID == 7 && token.Purpose == entity.AccountTokenPasswordReset &&
+				token.ExpiresAt.Sub(token.CreatedAt) == time.Hour
+		})).Return(nil).Once()
+		mockSender.On("Send", mock.Anything, mock.MatchedBy(func(message notification.Message) bool {
+			return message.To == "a@example.com" &&
+				strings.Contains(message.Body, "https://cakestore.example/reset?token=") &&
+				strings.Contains(message.Body, "1 hour")
+		})).Return(nil).Once()
+
+		err := useCase.ForgotPassword(context.Background(), &model.ForgotPasswordRequest{Email: "a@example.com"})
+
+		assert.NoError(t, err)
+		mockTokenRepo.AssertExpectations(t)
+		mockSender.AssertExpectations(t)
+	})
+}
+
+func TestAccountUseCase_ResetPassword(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("sets the password and revokes sessions", func(t *testing.T) {
+		mockTokenRepo := new(MockAccountTokenRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockSessions := new(MockSessionUseCase)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewAccountUseCase(mockTokenRepo, mockCustomerRepo, mockSessions, nil, logger, mockCache, AccountPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("reset")).Return(&entity.AccountToken{
+			ID:         1,
+			CustomerID: 7,
+			Purpose:    entity.AccountTokenPasswordReset,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockTokenRepo.On("MarkUsed", int64(1)).Return(true, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7}, nil).Once()
+		mockCustomerRepo.On("Update", mock.MatchedBy(func(c *entity.Customer) bool {
+			return c.EmailVerifiedAt.Valid &&
+				bcrypt.CompareHashAndPassword([]byte(c.Password), []byte("new-password")) == nil
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "customer:7").Return(nil).Once()
+		mockTokenRepo.On("InvalidateUnused", int64(7), entity.AccountTokenPasswordReset).Return(nil).Once()
+		mockSessions.On("RevokeAll", int64(7)).Return(nil).Once()
+
+		err := useCase.ResetPassword(context.Background(), &model.ResetPasswordRequest{Token: "reset", NewPassword: "new-password"})
+
+		assert.NoError(t, err)
+		mockCustomerRepo.AssertExpectations(t)
+		mockSessions.AssertExpectations(t)
+	})
+
+	t.Run("rejects expired, used and foreign tokens", func(t *testing.T) {
+		tokens := map[string]*entity.AccountToken{
+			"expired": {ID: 1, CustomerID: 7, Purpose: entity.AccountTokenPasswordReset, ExpiresAt: time.Now().Add(-time.Minute)},
+			"used": {ID: 2, CustomerID: 7, Purpose: entity.AccountTokenPasswordReset, ExpiresAt: time.Now().Add(time.Hour),
+				UsedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			"verification": {ID: 3, CustomerID: 7, Purpose: entity.AccountTokenEmailVerification, ExpiresAt: time.Now().Add(time.Hour)},
+		}
+
+		for raw, token := range tokens {
+			mockTokenRepo := new(MockAccountTokenRepository)
+			useCase := NewAccountUseCase(mockTokenRepo, nil, nil, nil, logger, nil, AccountPolicy{})
+			mockTokenRepo.On("GetByHash", hashOpaqueToken(raw)).Return(token, nil).Once()
+
+			err := useCase.ResetPassword(context.Background(), &model.ResetPasswordRequest{Token: raw, NewPassword: "new-password"})
+
+			assert.ErrorIs(t, err, constants.ErrInvalidAccountToken, raw)
+			mockTokenRepo.AssertNotCalled(t, "MarkUsed", mock.Anything)
+		}
+	})
+
+	t.Run("token redeemed concurrently", func(t *testing.T) {
+		mockTokenRepo := new(MockAccountTokenRepository)
+		useCase := NewAccountUseCase(mockTokenRepo, nil, nil, nil, logger, nil, AccountPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("reset")).Return(&entity.AccountToken{
+			ID:         1,
+			CustomerID: 7,
+			Purpose:    entity.AccountTokenPasswordReset,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockTokenRepo.On("MarkUsed", int64(1)).Return(false, nil).Once()
+
+		err := useCase.ResetPassword(context.Background(), &model.ResetPasswordRequest{Token: "reset", NewPassword: "new-password"})
+
+		assert.ErrorIs(t, err, constants.ErrInvalidAccountToken)
+	})
+}
+
+func TestAccountUseCase_VerifyEmail(t *testing.T) {
+	logger := logrus.New()
+	mockTokenRepo := new(MockAccountTokenRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewAccountUseCase(mockTokenRepo, mockCustomerRepo, nil, nil, logger, mockCache, AccountPolicy{})
+
+	mockTokenRepo.On("GetByHash", hashOpaqueToken("verify")).Return(&entity.AccountToken{
+		ID:         1,
+		CustomerID: 7,
+		Purpose:    entity.AccountTokenEmailVerification,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}, nil).Once()
+	mockTokenRepo.On("MarkUsed", int64(1)).Return(true, nil).Once()
+	mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7}, nil).Once()
+	mockCustomerRepo.On("Update", mock.MatchedBy(func(c *entity.Customer) bool {
+		return c.EmailVerifiedAt.Valid
+	})).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, "customer:7").Return(nil).Once()
+
+	err := useCase.VerifyEmail(context.Background(), "verify")
+
+	assert.NoError(t, err)
+	mockCustomerRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestAccountUseCase_ResendVerification(t *testing.T) {
+	logger := logrus.New()
+	mockCustomerRepo := new(MockCustomerRepository)
+	useCase := NewAccountUseCase(nil, mockCustomerRepo, nil, nil, logger, nil, AccountPolicy{})
+
+	mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{
+		ID:              7,
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}, nil).Once()
+
+	err := useCase.ResendVerification(context.Background(), 7)
+
+	assert.ErrorIs(t, err, constants.ErrEmailAlreadyVerified)
+}
+
+func TestAccountUseCase_SendInvite(t *testing.T) {
+	logger := logrus.New()
+	mockTokenRepo := new(MockAccountTokenRepository)
+	mockSender := new(MockNotificationSender)
+	useCase := NewAccountUseCase(mockTokenRepo, nil, nil, mockSender, logger, nil, AccountPolicy{
+		InviteURL: "https://cakestore.example/invite",
+	})
+
+	mockTokenRepo.On("InvalidateUnused", int64(9), entity.AccountTokenInvite).Return(nil).Once()
+	mockTokenRepo.On("Create", mock.MatchedBy(func(token *entity.AccountToken) bool {
+		return token.Purpose == entity.AccountTokenInvite && token.ExpiresAt.Sub(token.CreatedAt) == 72*time.Hour
+	})).Return(nil).Once()
+	mockSender.On("Send", mock.Anything, mock.MatchedBy(func(message notification.Message) bool {
+		return message.To == "bob@example.com" &&
+			strings.Contains(message.Body, "https://cakestore.example/invite?token=") &&
+			strings.Contains(message.Body, "72 hours")
+	})).Return(nil).Once()
+
+	err := useCase.SendInvite(context.Background(), &entity.Customer{ID: 9, Name: "Bob", Email: "bob@example.com"})
+
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+	mockSender.AssertExpectations(t)
+}
+
+func TestAccountUseCase_AcceptInvite(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("sets the password and verifies the address", func(t *testing.T) {
+		mockTokenRepo := new(MockAccountTokenRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewAccountUseCase(mockTokenRepo, mockCustomerRepo, nil, nil, logger, mockCache, AccountPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("invite")).Return(&entity.AccountToken{
+			ID:         4,
+			CustomerID: 9,
+			Purpose:    entity.AccountTokenInvite,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockTokenRepo.On("MarkUsed", int64(4)).Return(true, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(&entity.Customer{ID: 9}, nil).Once()
+		mockCustomerRepo.On("Update", mock.MatchedBy(func(c *entity.Customer) bool {
+			return c.EmailVerifiedAt.Valid &&
+				bcrypt.CompareHashAndPassword([]byte(c.Password), []byte("chosen-password")) == nil
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "customer:9").Return(nil).Once()
+		mockTokenRepo.On("InvalidateUnused", int64(9), entity.AccountTokenInvite).Return(nil).Once()
+
+		err := useCase.AcceptInvite(context.Background(), &model.AcceptInviteRequest{Token: "invite", Password: "chosen-password"})
+
+		assert.NoError(t, err)
+		mockCustomerRepo.AssertExpectations(t)
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("reset links are not invites", func(t *testing.T) {
+		mockTokenRepo := new(MockAccountTokenRepository)
+		useCase := NewAccountUseCase(mockTokenRepo, nil, nil, nil, logger, nil, AccountPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("reset")).Return(&entity.AccountToken{
+			ID:         5,
+			CustomerID: 9,
+			Purpose:    entity.AccountTokenPasswordReset,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}, nil).Once()
+
+		err := useCase.AcceptInvite(context.Background(), &model.AcceptInviteRequest{Token: "reset", Password: "chosen-password"})
+
+		assert.ErrorIs(t, err, constants.ErrInvalidAccountToken)
+		mockTokenRepo.AssertNotCalled(t, "MarkUsed", mock.Anything)
+	})
+}
+
+func TestCustomerUseCase_CreateEmployee(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("creates the account and sends an invite", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockRoles := new(MockRoleUseCase)
+		mockAccounts := new(MockAccountUseCase)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, mockAccounts, nil, nil, mockRoles, mockCache)
+
+		mockRoles.On("Exists", constants.RoleCashier).Return(nil).Once()
+		mockCustomerRepo.On("GetByEmail", "bob@example.com").Return(nil, constants.ErrNotFound).Once()
+		mockCustomerRepo.On("Create", mock.MatchedBy(func(c *entity.Customer) bool {
+			return c.Role == constants.RoleCashier && c.Password != "" && !c.EmailVerifiedAt.Valid
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "employee:0").Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "employees").Return(nil).Once()
+		mockAccounts.On("SendInvite", mock.AnythingOfType("*entity.Customer")).Return(nil).Once()
+
+		employee, err := useCase.CreateEmployee(context.Background(), &model.CreateEmployeeRequest{Name: "Bob", Email: "bob@example.com", Role: constants.RoleCashier})
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.RoleCashier, employee.Role)
+		mockCustomerRepo.AssertExpectations(t)
+		mockAccounts.AssertExpectations(t)
+	})
+
+	t.Run("customer role is not a staff role", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, nil, nil, nil, nil)
+
+		_, err := useCase.CreateEmployee(context.Background(), &model.CreateEmployeeRequest{Name: "Bob", Email: "bob@example.com", Role: constants.RoleCustomer})
+
+		assert.ErrorIs(t, err, constants.ErrInvalidEmployeeRole)
+		mockCustomerRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("invite already accepted", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockAccounts := new(MockAccountUseCase)
+		useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, mockAccounts, nil, nil, nil, nil)
+
+		mockCustomerRepo.On("GetEmployeeByID", int64(9)).Return(&entity.Customer{
+			ID:              9,
+			Role:            constants.RoleCashier,
+			EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		}, nil).Once()
+
+		err := useCase.InviteEmployee(context.Background(), 9)
+
+		assert.ErrorIs(t, err, constants.ErrInviteAlreadyAccepted)
+		mockAccounts.AssertNotCalled(t, "SendInvite", mock.Anything)
+	})
+}
@@ -0,0 +1,250 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// apiKeyPrefix marks our keys so they are easy to spot in logs and secret scanners
+	apiKeyPrefix = "ck_"
+	// apiKeyCacheTTL bounds how long a revoked key keeps working on other
+	// instances if the cache delete is lost
+	apiKeyCacheTTL = time.Minute
+	// apiKeyTouchInterval limits last-used tracking to one write per key per minute
+	apiKeyTouchInterval = time.Minute
+)
+
+// apiKeyExcludedPermissions cannot be given to a key, so a leaked key can
+// never grant itself or anyone else more access
+var apiKeyExcludedPermissions = []string{
+	constants.PermissionRoleManage,
+	constants.PermissionAPIKeyManage,
+	constants.PermissionEmployeeManage,
+}
+
+// APIKeyPolicy configures API keys. RateLimit is the default number of
+// requests per key per minute.
+type APIKeyPolicy struct {
+	RateLimit int
+}
+
+// RateLimitedError is returned when an API key has used up its requests for
+// the current minute. It wraps constants.ErrAPIKeyRateLimited.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return constants.ErrAPIKeyRateLimited.Error()
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return constants.ErrAPIKeyRateLimited
+}
+
+type APIKeyUseCase interface {
+	// Create returns the key itself, which cannot be recovered later
+	Create(ctx context.Context, request *model.CreateAPIKeyRequest, createdBy int64) (*model.CreatedAPIKeyResponse, error)
+	GetAll(ctx context.Context) ([]model.APIKeyResponse, error)
+	Revoke(ctx context.Context, id int64) error
+	// Authenticate resolves the key sent with a request and counts the request
+	// against the key's rate limit
+	Authenticate(ctx context.Context, rawKey string) (*model.APIKeyPrincipal, error)
+}
+
+type apiKeyUseCase struct {
+	repo   repository.APIKeyRepository
+	logger *logrus.Logger
+	cache  database.RedisCache
+	policy APIKeyPolicy
+	now    func() time.Time
+}
+
+func NewAPIKeyUseCase(repo repository.APIKeyRepository, logger *logrus.Logger, cache database.RedisCache, policy APIKeyPolicy) APIKeyUseCase {
+	if policy.RateLimit <= 0 {
+		policy.RateLimit = 60
+	}
+	return &apiKeyUseCase{
+		repo:   repo,
+		logger: logger,
+		cache:  cache,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+func apiKeyCacheKey(keyHash string) string {
+	return fmt.Sprintf("apikey:%s", keyHash)
+}
+
+func apiKeyRateKey(id int64, window int64) string {
+	return fmt.Sprintf("apikey:rate:%d:%d", id, window)
+}
+
+func (u *apiKeyUseCase) Create(ctx context.Context, request *model.CreateAPIKeyRequest, createdBy int64) (*model.CreatedAPIKeyResponse, error) {
+	var permissions []entity.APIKeyPermission
+	for _, permission := range request.Permissions {
+		if !slices.Contains(constants.Permissions, permission) {
+			return nil, fmt.Errorf("%w: %s", constants.ErrUnknownPermission, permission)
+		}
+		if slices.Contains(apiKeyExcludedPermissions, permission) {
+			return nil, fmt.Errorf("%w: %s", constants.ErrPermissionNotForAPIKeys, permission)
+		}
+		if !slices.ContainsFunc(permissions, func(p entity.APIKeyPermission) bool { return p.Permission == permission }) {
+			permissions = append(permissions, entity.APIKeyPermission{Permission: permission})
+		}
+	}
+
+	secret, err := newOpaqueToken()
+	if err != nil {
+		u.logger.Errorf("Error generating API key: %v", err)
+		return nil, err
+	}
+	raw := apiKeyPrefix + secret
+
+	now := u.now()
+	key := &entity.APIKey{
+		Name:        request.Name,
+		Prefix:      raw[:len(apiKeyPrefix)+8],
+		KeyHash:     hashOpaqueToken(raw),
+		Permissions: permissions,
+		RateLimit:   request.RateLimit,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+	}
+	if request.ExpiresInDays > 0 {
+		key.ExpiresAt = sql.NullTime{Time: now.AddDate(0, 0, request.ExpiresInDays), Valid: true}
+	}
+	if err := u.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	u.logger.Infof("API key %d (%s) created by %d", key.ID, key.Name, createdBy)
+	return &model.CreatedAPIKeyResponse{APIKeyResponse: *model.ToAPIKeyResponse(key), Key: raw}, nil
+}
+
+func (u *apiKeyUseCase) GetAll(ctx context.Context) ([]model.APIKeyResponse, error) {
+	keys, err := u.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, *model.ToAPIKeyResponse(&keys[i]))
+	}
+	return responses, nil
+}
+
+func (u *apiKeyUseCase) Revoke(ctx context.Context, id int64) error {
+	key, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.repo.Revoke(ctx, id, u.now()); err != nil {
+		return err
+	}
+
+	if err := u.cache.Delete(ctx, apiKeyCacheKey(key.KeyHash)); err != nil {
+		u.logger.Errorf("Error deleting cached API key %d: %v", id, err)
+	}
+
+	u.logger.Infof("API key %d (%s) revoked", key.ID, key.Name)
+	return nil
+}
+
+func (u *apiKeyUseCase) Authenticate(ctx context.Context, rawKey string) (*model.APIKeyPrincipal, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, constants.ErrInvalidAPIKey
+	}
+
+	principal, err := u.resolve(ctx, hashOpaqueToken(rawKey))
+	if err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	if principal.ExpiresAt != nil && now.After(*principal.ExpiresAt) {
+		return nil, constants.ErrInvalidAPIKey
+	}
+
+	if err := u.checkRateLimit(ctx, principal, now); err != nil {
+		return nil, err
+	}
+
+	// Tracking is best effort and must not fail the request
+	_ = u.repo.TouchLastUsed(ctx, principal.ID, now, now.Add(-apiKeyTouchInterval))
+
+	return principal, nil
+}
+
+// resolve looks the key up by its hash, through a short-lived cache so the
+// database is not hit on every partner request
+func (u *apiKeyUseCase) resolve(ctx context.Context, keyHash string) (*model.APIKeyPrincipal, error) {
+	cacheKey := apiKeyCacheKey(keyHash)
+	var principal model.APIKeyPrincipal
+	if err := u.cache.Get(ctx, cacheKey, &principal); err == nil {
+		return &principal, nil
+	}
+
+	key, err := u.repo.GetByHash(ctx, keyHash)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if key.RevokedAt.Valid {
+		return nil, constants.ErrInvalidAPIKey
+	}
+
+	principal = model.APIKeyPrincipal{
+		ID:          key.ID,
+		Name:        key.Name,
+		Permissions: key.PermissionNames(),
+		RateLimit:   key.RateLimit,
+	}
+	if key.ExpiresAt.Valid {
+		principal.ExpiresAt = &key.ExpiresAt.Time
+	}
+
+	if err := u.cache.Set(ctx, cacheKey, principal, apiKeyCacheTTL); err != nil {
+		u.logger.Errorf("Error caching API key %d: %v", key.ID, err)
+	}
+	return &principal, nil
+}
+
+// checkRateLimit counts requests per key in fixed one-minute windows. When
+// Redis is unreachable requests are let through, like login throttling.
+func (u *apiKeyUseCase) checkRateLimit(ctx context.Context, principal *model.APIKeyPrincipal, now time.Time) error {
+	limit := principal.RateLimit
+	if limit <= 0 {
+		limit = u.policy.RateLimit
+	}
+
+	window := now.Truncate(time.Minute)
+	count, err := u.cache.Increment(ctx, apiKeyRateKey(principal.ID, window.Unix()), time.Minute)
+	if err != nil {
+		u.logger.Errorf("Error counting requests for API key %d: %v", principal.ID, err)
+		return nil
+	}
+
+	if count > int64(limit) {
+		return &RateLimitedError{RetryAfter: window.Add(time.Minute).Sub(now)}
+	}
+	return nil
+}
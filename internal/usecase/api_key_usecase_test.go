@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *entity.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetAll(ctx context.Context) ([]entity.APIKey, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByID(ctx context.Context, id int64) (*entity.APIKey, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	args := m.Called(keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) (bool, error) {
+	args := m.Called(id, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time, notBefore time.Time) error {
+	args := m.Called(id, at, notBefore)
+	return args.Error(0)
+}
+
+func newTestAPIKeyUseCase(repo *MockAPIKeyRepository, cache *database.MockRedisCacheService, now time.Time) *apiKeyUseCase {
+	useCase := NewAPIKeyUseCase(repo, logrus.New(), cache, APIKeyPolicy{RateLimit: 2}).(*apiKeyUseCase)
+	useCase.now = func() time.Time { return now }
+	return useCase
+}
+
+func TestAPIKeyUseCase_Create(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+
+	t.Run("stores only the hash", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		useCase := newTestAPIKeyUseCase(mockRepo, nil, now)
+
+		var stored *entity.APIKey
+		mockRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(0).(*entity.APIKey)
+			stored.ID = 4
+		}).Return(nil).Once()
+
+		created, err := useCase.Create(context.Background(), &model.CreateAPIKeyRequest{
+			Name:          "Delivery partner",
+			Permissions:   []string{constants.PermissionInventoryRead, constants.PermissionInventoryRead},
+			ExpiresInDays: 30,
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Key, apiKeyPrefix))
+		assert.Equal(t, hashOpaqueToken(created.Key), stored.KeyHash)
+		assert.Equal(t, created.Key[:len(apiKeyPrefix)+8], stored.Prefix)
+		assert.Equal(t, []string{constants.PermissionInventoryRead}, created.Permissions)
+		assert.Equal(t, now.AddDate(0, 0, 30), *created.ExpiresAt)
+	})
+
+	t.Run("administrative permission", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		useCase := newTestAPIKeyUseCase(mockRepo, nil, now)
+
+		_, err := useCase.Create(context.Background(), &model.CreateAPIKeyRequest{
+			Name:        "Too powerful",
+			Permissions: []string{constants.PermissionRoleManage},
+		}, 1)
+
+		assert.ErrorIs(t, err, constants.ErrPermissionNotForAPIKeys)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("unknown permission", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		useCase := newTestAPIKeyUseCase(mockRepo, nil, now)
+
+		_, err := useCase.Create(context.Background(), &model.CreateAPIKeyRequest{
+			Name:        "Typo",
+			Permissions: []string{"inventory:everything"},
+		}, 1)
+
+		assert.ErrorIs(t, err, constants.ErrUnknownPermission)
+	})
+}
+
+func TestAPIKeyUseCase_Authenticate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+	rawKey := apiKeyPrefix + "secret"
+	keyHash := hashOpaqueToken(rawKey)
+	rateKey := apiKeyRateKey(4, now.Truncate(time.Minute).Unix())
+
+	t.Run("loads the key and records its use", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := newTestAPIKeyUseCase(mockRepo, mockCache, now)
+
+		mockCache.On("Get", mock.Anything, apiKeyCacheKey(keyHash), mock.Anything).Return(errors.New("key not found")).Once()
+		mockRepo.On("GetByHash", keyHash).Return(&entity.APIKey{
+			ID:          4,
+			Name:        "Delivery partner",
+			Permissions: []entity.APIKeyPermission{{APIKeyID: 4, Permission: constants.PermissionInventoryRead}},
+		}, nil).Once()
+		mockCache.On("Set", mock.Anything, apiKeyCacheKey(keyHash), mock.Anything, apiKeyCacheTTL).Return(nil).Once()
+		mockCache.On("Increment", mock.Anything, rateKey, time.Minute).Return(int64(1), nil).Once()
+		mockRepo.On("TouchLastUsed", int64(4), now, now.Add(-apiKeyTouchInterval)).Return(nil).Once()
+
+		principal, err := useCase.Authenticate(context.Background(), rawKey)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), principal.ID)
+		assert.True(t, principal.HasPermissions(constants.PermissionInventoryRead))
+		assert.False(t, principal.HasPermissions(constants.PermissionInventoryWrite))
+		mockRepo.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("revoked key", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := newTestAPIKeyUseCase(mockRepo, mockCache, now)
+
+		mockCache.On("Get", mock.Anything, apiKeyCacheKey(keyHash), mock.Anything).Return(errors.New("key not found")).Once()
+		mockRepo.On("GetByHash", keyHash).Return(&entity.APIKey{
+			ID:        4,
+			RevokedAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+		}, nil).Once()
+
+		_, err := useCase.Authenticate(context.Background(), rawKey)
+
+		assert.ErrorIs(t, err, constants.ErrInvalidAPIKey)
+		mockCache.AssertNotCalled(t, "Increment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := newTestAPIKeyUseCase(new(MockAPIKeyRepository), mockCache, now)
+
+		expiredAt := now.Add(-time.Second)
+		mockCache.On("Get", mock.Anything, apiKeyCacheKey(keyHash), mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*model.APIKeyPrincipal) = model.APIKeyPrincipal{ID: 4, ExpiresAt: &expiredAt}
+		}).Return(nil).Once()
+
+		_, err := useCase.Authenticate(context.Background(), rawKey)
+
+		assert.ErrorIs(t, err, constants.ErrInvalidAPIKey)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := newTestAPIKeyUseCase(mockRepo, mockCache, now)
+
+		mockCache.On("Get", mock.Anything, apiKeyCacheKey(keyHash), mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*model.APIKeyPrincipal) = model.APIKeyPrincipal{ID: 4}
+		}).Return(nil).Once()
+		mockCache.On("Increment", mock.Anything, rateKey, time.Minute).Return(int64(3), nil).Once()
+
+		_, err := useCase.Authenticate(context.Background(), rawKey)
+
+		var limited *RateLimitedError
+		assert.ErrorAs(t, err, &limited)
+		assert.ErrorIs(t, err, constants.ErrAPIKeyRateLimited)
+		assert.Equal(t, 30*time.Second, limited.RetryAfter)
+		mockRepo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not one of our keys", func(t *testing.T) {
+		useCase := newTestAPIKeyUseCase(new(MockAPIKeyRepository), nil, now)
+
+		_, err := useCase.Authenticate(context.Background(), "Bearer something")
+
+		assert.ErrorIs(t, err, constants.ErrInvalidAPIKey)
+	})
+}
+
+func TestAPIKeyUseCase_Revoke(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 30, 0, time.UTC)
+	mockRepo := new(MockAPIKeyRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := newTestAPIKeyUseCase(mockRepo, mockCache, now)
+
+	mockRepo.On("GetByID", int64(4)).Return(&entity.APIKey{ID: 4, KeyHash: "hash"}, nil).Once()
+	mockRepo.On("Revoke", int64(4), now).Return(true, nil).Once()
+	mockCache.On("Delete", mock.Anything, apiKeyCacheKey("hash")).Return(nil).Once()
+
+	assert.NoError(t, useCase.Revoke(context.Background(), 4))
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"cakestore/internal/database"
+	"cakestore/internal/domain/model"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Cache tags group keys that must be dropped together, usually every page and
+// filter of a list. Reads go through database.GetOrLoad with the tags below
+// and writes call RedisCache.InvalidateTags.
+const (
+	menuListTag      = "menus:list"
+	inventoryListTag = "inventory:list"
+	tableListTag     = "tables:list"
+	// availableTablesTag depends on reservations as well as on the tables
+	availableTablesTag      = "tables:available"
+	reservationListTag      = "reservations:list"
+	adminReservationListTag = "reservations:admin:list"
+	// orderListTag covers every list of orders: all orders, a customer's
+	// orders and a table session's orders
+	orderListTag = "orders:list"
+)
+
+const (
+	// listCacheTTL is how long list pages stay cached when nothing invalidates them
+	listCacheTTL = 5 * time.Minute
+	// itemCacheTTL is how long a single record stays cached
+	itemCacheTTL = 5 * time.Minute
+	// cacheJitter spreads expirations by up to 10%, so keys filled together
+	// are not reloaded together
+	cacheJitter = 0.1
+	// negativeCacheTTL is how long a lookup of a missing record is remembered
+	negativeCacheTTL = 30 * time.Second
+)
+
+// cachedPage is how lists that return their pagination meta separately are cached
+type cachedPage[T any] struct {
+	Data T
+	Meta *model.PaginatedMeta
+}
+
+// listLoad returns the read-through options for a cached list
+func listLoad(tags ...string) database.LoadOptions {
+	return database.LoadOptions{TTL: listCacheTTL, Jitter: cacheJitter, Tags: tags}
+}
+
+// itemLoad returns the read-through options for a single record. A notFound
+// error from the repository is cached briefly so repeated lookups of a
+// missing ID do not all reach the database.
+func itemLoad(notFound error, tags ...string) database.LoadOptions {
+	return database.LoadOptions{
+		TTL:         itemCacheTTL,
+		Jitter:      cacheJitter,
+		NotFound:    notFound,
+		NegativeTTL: negativeCacheTTL,
+		Tags:        tags,
+	}
+}
+
+// orderTag groups the cached views of a single order
+func orderTag(orderID int64) string {
+	return fmt.Sprintf("order:%d:views", orderID)
+}
+
+// invalidateOrderViews drops the cached views of one order together with every
+// order list, since any change to an order can move it in or out of a list
+func invalidateOrderViews(ctx context.Context, cache database.RedisCache, log *logrus.Logger, orderID int64) {
+	if err := cache.InvalidateTags(ctx, orderTag(orderID), orderListTag); err != nil {
+		log.Errorf("Error deleting cache for order ID %d: %v", orderID, err)
+	}
+}
+
+func cartListTag(customerID int64) string {
+	return fmt.Sprintf("cart:customer:%d:list", customerID)
+}
+
+func wishlistTag(customerID int64) string {
+	return fmt.Sprintf("wishlist:%d:list", customerID)
+}
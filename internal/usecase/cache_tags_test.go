@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTagCache is a RedisCache that keeps values and tag sets in memory, so
+// tests can exercise real hits, misses and invalidations
+type memoryTagCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	tags   map[string]map[string]struct{}
+}
+
+func newMemoryTagCache() *memoryTagCache {
+	return &memoryTagCache{
+		values: make(map[string][]byte),
+		tags:   make(map[string]map[string]struct{}),
+	}
+}
+
+func (c *memoryTagCache) Get(_ context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.values[key]
+	if !ok {
+		return errors.New("cache miss")
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (c *memoryTagCache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = data
+	return nil
+}
+
+func (c *memoryTagCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+func (c *memoryTagCache) Increment(context.Context, string, time.Duration) (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (c *memoryTagCache) SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	if err := c.Set(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+	return nil
+}
+
+func (c *memoryTagCache) InvalidateTags(_ context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			delete(c.values, key)
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+// The repository mocks below only allow each list query once, so a second
+// read that is not served from the cache fails the test.
+
+func TestCacheTags_MenuCreateRefreshesEveryPage(t *testing.T) {
+	mockMenuRepo := new(MockMenuRepository)
+	useCase := NewMenuUseCase(mockMenuRepo, logrus.New(), newMemoryTagCache())
+
+	first := &model.PaginationQuery{Page: 1, Limit: 10}
+	second := &model.PaginationQuery{Page: 2, Limit: 10}
+	for _, page := range []*model.PaginationQuery{first, second} {
+		params := &model.MenuQueryParams{Page: page.Page, Limit: page.Limit}
+		mockMenuRepo.On("GetAll", params).Return(&model.PaginationResponse[[]entity.Menu]{
+			Data: []entity.Menu{{ID: 1, Title: "Black forest"}}, Total: 1,
+		}, nil).Once()
+		_, err := useCase.GetAllMenus(context.Background(), params)
+		require.NoError(t, err)
+		_, err = useCase.GetAllMenus(context.Background(), params)
+		require.NoError(t, err)
+	}
+
+	created := &entity.Menu{Title: "Cheesecake"}
+	mockMenuRepo.On("Create", created).Return(nil).Once()
+	require.NoError(t, useCase.CreateMenu(context.Background(), created))
+
+	for _, page := range []*model.PaginationQuery{first, second} {
+		params := &model.MenuQueryParams{Page: page.Page, Limit: page.Limit}
+		mockMenuRepo.On("GetAll", params).Return(&model.PaginationResponse[[]entity.Menu]{
+			Data: []entity.Menu{{ID: 1, Title: "Black forest"}, {ID: 2, Title: "Cheesecake"}}, Total: 2,
+		}, nil).Once()
+		menus, err := useCase.GetAllMenus(context.Background(), params)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), menus.Total)
+	}
+	mockMenuRepo.AssertExpectations(t)
+}
+
+func TestCacheTags_InventoryUpdateRefreshesLowStock(t *testing.T) {
+	mockInventoryRepo := new(MockInventoryRepository)
+	useCase := NewInventoryUseCase(mockInventoryRepo, logrus.New(), newMemoryTagCache(), metrics.Noop{})
+
+	mockInventoryRepo.On("GetLowStockIngredients").Return([]entity.Inventory{
+		{ID: 1, Name: "Sugar", Quantity: 2, MinimumStock: 5},
+	}, nil).Once()
+	low, err := useCase.GetLowStockIngredients(context.Background())
+	require.NoError(t, err)
+	require.Len(t, low, 1)
+	_, err = useCase.GetLowStockIngredients(context.Background())
+	require.NoError(t, err)
+
+	mockInventoryRepo.On("GetByID", uint(1)).Return(&entity.Inventory{ID: 1, Name: "Sugar", Quantity: 2, MinimumStock: 5}, nil).Once()
+	mockInventoryRepo.On("Update", mock.AnythingOfType("*entity.Inventory")).Return(nil).Once()
+	_, err = useCase.Update(context.Background(), 1, &model.UpdateInventoryRequest{Quantity: 20})
+	require.NoError(t, err)
+
+	mockInventoryRepo.On("GetLowStockIngredients").Return([]entity.Inventory{}, nil).Once()
+	low, err = useCase.GetLowStockIngredients(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, low)
+	mockInventoryRepo.AssertExpectations(t)
+}
+
+func TestCacheTags_CartWriteOnlyRefreshesThatCustomer(t *testing.T) {
+	mockCartRepo := new(MockCartRepository)
+	mockMenuRepo := new(MockMenuRepository)
+	useCase := NewCartUseCase(mockCartRepo, mockMenuRepo, logrus.New(), newMemoryTagCache(), metrics.Noop{})
+
+	params := &model.PaginationQuery{Page: 1, Limit: 10}
+	for _, customerID := range []int64{1, 2} {
+		mockCartRepo.On("GetByCustomerID", customerID, params).Return(&model.PaginationResponse[[]model.UserCartResponse]{
+			Data: []model.UserCartResponse{{ID: customerID, MenuID: 1, Quantity: 1}}, Total: 1, Page: 1,
+		}, nil).Once()
+		_, _, err := useCase.GetCartByCustomerID(context.Background(), customerID, params)
+		require.NoError(t, err)
+	}
+
+	existing := &entity.Cart{ID: 1, CustomerID: 1, MenuID: 1, Quantity: 1}
+	mockMenuRepo.On("GetByID", int64(1)).Return(&entity.Menu{ID: 1, Price: 10000}, nil).Once()
+	mockCartRepo.On("GetByCustomerIDAndMenuID", int64(1), int64(1)).Return(existing, nil).Once()
+	mockCartRepo.On("Update", existing).Return(nil).Once()
+	require.NoError(t, useCase.CreateCart(context.Background(), 1, &model.AddCart{MenuID: 1, Quantity: 2}))
+
+	mockCartRepo.On("GetByCustomerID", int64(1), params).Return(&model.PaginationResponse[[]model.UserCartResponse]{
+		Data: []model.UserCartResponse{{ID: 1, MenuID: 1, Quantity: 3}}, Total: 1, Page: 1,
+	}, nil).Once()
+	carts, _, err := useCase.GetCartByCustomerID(context.Background(), 1, params)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), carts[0].Quantity)
+
+	// Customer 2 was untouched and is still served from the cache
+	carts, _, err = useCase.GetCartByCustomerID(context.Background(), 2, params)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), carts[0].Quantity)
+	mockCartRepo.AssertExpectations(t)
+}
+
+func TestCacheTags_ReservationWriteRefreshesAvailableTables(t *testing.T) {
+	cache := newMemoryTagCache()
+	mockTableRepo := new(MockTableRepository)
+	tables := NewTableUseCase(mockTableRepo, logrus.New(), cache)
+
+	reserveTime := time.Date(2030, 1, 1, 19, 0, 0, 0, time.UTC)
+	mockTableRepo.On("GetAvailableTables", reserveTime, time.Hour).Return([]entity.Table{{ID: 1}, {ID: 2}}, nil).Once()
+	available, err := tables.GetAvailableTables(context.Background(), reserveTime, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, available, 2)
+	_, err = tables.GetAvailableTables(context.Background(), reserveTime, time.Hour)
+	require.NoError(t, err)
+
+	invalidateReservationCache(context.Background(), cache, logrus.New(), 1)
+
+	mockTableRepo.On("GetAvailableTables", reserveTime, time.Hour).Return([]entity.Table{{ID: 2}}, nil).Once()
+	available, err = tables.GetAvailableTables(context.Background(), reserveTime, time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, available, 1)
+	mockTableRepo.AssertExpectations(t)
+}
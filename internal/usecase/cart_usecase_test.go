@@ -4,6 +4,8 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -17,12 +19,12 @@ type MockCartRepository struct {
 	mock.Mock
 }
 
-func (m *MockCartRepository) Create(cart *entity.Cart) error {
+func (m *MockCartRepository) Create(ctx context.Context, cart *entity.Cart) error {
 	args := m.Called(cart)
 	return args.Error(0)
 }
 
-func (m *MockCartRepository) GetByID(id int64) (*entity.Cart, error) {
+func (m *MockCartRepository) GetByID(ctx context.Context, id int64) (*entity.Cart, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -30,7 +32,7 @@ func (m *MockCartRepository) GetByID(id int64) (*entity.Cart, error) {
 	return args.Get(0).(*entity.Cart), args.Error(1)
 }
 
-func (m *MockCartRepository) GetByCustomerID(customerID int64, params *model.PaginationQuery) (*model.PaginationResponse[[]model.UserCartResponse], error) {
+func (m *MockCartRepository) GetByCustomerID(ctx context.Context, customerID int64, params *model.PaginationQuery) (*model.PaginationResponse[[]model.UserCartResponse], error) {
 	args := m.Called(customerID, params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,7 +40,7 @@ func (m *MockCartRepository) GetByCustomerID(customerID int64, params *model.Pag
 	return args.Get(0).(*model.PaginationResponse[[]model.UserCartResponse]), args.Error(1)
 }
 
-func (m *MockCartRepository) GetByCustomerIDAndMenuID(customerID, menuID int64) (*entity.Cart, error) {
+func (m *MockCartRepository) GetByCustomerIDAndMenuID(ctx context.Context, customerID, menuID int64) (*entity.Cart, error) {
 	args := m.Called(customerID, menuID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -46,27 +48,27 @@ func (m *MockCartRepository) GetByCustomerIDAndMenuID(customerID, menuID int64)
 	return args.Get(0).(*entity.Cart), args.Error(1)
 }
 
-func (m *MockCartRepository) Update(cart *entity.Cart) error {
+func (m *MockCartRepository) Update(ctx context.Context, cart *entity.Cart) error {
 	args := m.Called(cart)
 	return args.Error(0)
 }
 
-func (m *MockCartRepository) RemoveItem(customerID, cartID int64) error {
+func (m *MockCartRepository) RemoveItem(ctx context.Context, customerID, cartID int64) error {
 	args := m.Called(customerID, cartID)
 	return args.Error(0)
 }
 
-func (m *MockCartRepository) ClearCustomerCart(customerID int64) error {
+func (m *MockCartRepository) ClearCustomerCart(ctx context.Context, customerID int64) error {
 	args := m.Called(customerID)
 	return args.Error(0)
 }
 
-func (m *MockCartRepository) BulkDelete(customerID int64, cartIDs []int64) error {
+func (m *MockCartRepository) BulkDelete(ctx context.Context, customerID int64, cartIDs []int64) error {
 	args := m.Called(customerID, cartIDs)
 	return args.Error(0)
 }
 
-func (m *MockCartRepository) Delete(id int64) error {
+func (m *MockCartRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -75,7 +77,7 @@ func TestCartUseCase_GetCartByID(t *testing.T) {
 	logger := logrus.New()
 	mockCartRepo := new(MockCartRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewCartUseCase(mockCartRepo, nil, logger, mockCache)
+	useCase := NewCartUseCase(mockCartRepo, nil, logger, mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedCart := &entity.Cart{
@@ -92,7 +94,7 @@ func TestCartUseCase_GetCartByID(t *testing.T) {
 		mockCartRepo.On("GetByID", int64(1)).Return(expectedCart, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		cart, err := useCase.GetCartByID(1)
+		cart, err := useCase.GetCartByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, cart)
@@ -104,7 +106,7 @@ func TestCartUseCase_GetCartByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockCartRepo.On("GetByID", int64(1)).Return(nil, errors.New("not found")).Once()
 
-		cart, err := useCase.GetCartByID(1)
+		cart, err := useCase.GetCartByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, cart)
@@ -116,7 +118,7 @@ func TestCartUseCase_GetCartByCustomerID(t *testing.T) {
 	logger := logrus.New()
 	mockCartRepo := new(MockCartRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewCartUseCase(mockCartRepo, nil, logger, mockCache)
+	useCase := NewCartUseCase(mockCartRepo, nil, logger, mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedResponse := &model.PaginationResponse[[]model.UserCartResponse]{
@@ -135,9 +137,9 @@ func TestCartUseCase_GetCartByCustomerID(t *testing.T) {
 		params := &model.PaginationQuery{Page: 1, Limit: 10}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockCartRepo.On("GetByCustomerID", int64(1), params).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "cart:customer:1:page:1:limit:10", mock.Anything, mock.Anything, []string{cartListTag(1)}).Return(nil)
 
-		carts, meta, err := useCase.GetCartByCustomerID(1, params)
+		carts, meta, err := useCase.GetCartByCustomerID(context.Background(), 1, params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, carts)
@@ -151,7 +153,7 @@ func TestCartUseCase_GetCartByCustomerID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockCartRepo.On("GetByCustomerID", int64(1), params).Return(nil, errors.New("error")).Once()
 
-		carts, meta, err := useCase.GetCartByCustomerID(1, params)
+		carts, meta, err := useCase.GetCartByCustomerID(context.Background(), 1, params)
 
 		assert.Error(t, err)
 		assert.Nil(t, carts)
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CatalogUseCase copies the menus, inventory and tables between databases
+type CatalogUseCase interface {
+	Export(ctx context.Context) (*model.CatalogExport, error)
+	// Import checks the whole catalog before writing any of it, then creates
+	// or updates every record. Nothing is deleted.
+	Import(ctx context.Context, catalog *model.CatalogExport) (*model.CatalogImportResult, error)
+}
+
+type catalogUseCase struct {
+	repo   repository.CatalogRepository
+	logger *logrus.Logger
+	cache  database.RedisCache
+	now    func() time.Time
+}
+
+func NewCatalogUseCase(repo repository.CatalogRepository, logger *logrus.Logger, cache database.RedisCache) CatalogUseCase {
+	return &catalogUseCase{
+		repo:   repo,
+		logger: logger,
+		cache:  cache,
+		now:    time.Now,
+	}
+}
+
+func (u *catalogUseCase) Export(ctx context.Context) (*model.CatalogExport, error) {
+	menus, err := u.repo.GetMenus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inventory, err := u.repo.GetInventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := u.repo.GetTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.CatalogExport{
+		ExportedAt: u.now(),
+		Menus:      make([]model.CatalogMenu, 0, len(menus)),
+		Inventory:  make([]model.CatalogInventoryItem, 0, len(inventory)),
+		Tables:     make([]model.CatalogTable, 0, len(tables)),
+	}
+	for i := range menus {
+		export.Menus = append(export.Menus, model.ToCatalogMenu(&menus[i]))
+	}
+	for i := range inventory {
+		export.Inventory = append(export.Inventory, model.ToCatalogInventoryItem(&inventory[i]))
+	}
+	for i := range tables {
+		export.Tables = append(export.Tables, model.ToCatalogTable(&tables[i]))
+	}
+	return export, nil
+}
+
+func (u *catalogUseCase) Import(ctx context.Context, catalog *model.CatalogExport) (*model.CatalogImportResult, error) {
+	if err := validateCatalog(catalog); err != nil {
+		return nil, err
+	}
+
+	menus := make([]entity.Menu, 0, len(catalog.Menus))
+	for _, menu := range catalog.Menus {
+		menus = append(menus, entity.Menu{
+			Title:       menu.Title,
+			Description: menu.Description,
+			Price:       menu.Price,
+			Quantity:    menu.Quantity,
+			Category:    menu.Category,
+			Image:       menu.Image,
+		})
+	}
+	inventory := make([]entity.Inventory, 0, len(catalog.Inventory))
+	for _, item := range catalog.Inventory {
+		inventory = append(inventory, entity.Inventory{
+			Name:         item.Name,
+			Quantity:     item.Quantity,
+			Unit:         item.Unit,
+			MinimumStock: item.MinimumStock,
+			ReorderPoint: item.ReorderPoint,
+			UnitPrice:    item.UnitPrice,
+		})
+	}
+	tables := make([]entity.Table, 0, len(catalog.Tables))
+	for _, table := range catalog.Tables {
+		tables = append(tables, entity.Table{TableNumber: table.TableNumber, Capacity: table.Capacity})
+	}
+
+	result, err := u.repo.Import(ctx, menus, inventory, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, menu := range menus {
+		keys = append(keys, fmt.Sprintf("menu:%d", menu.ID))
+	}
+	for _, item := range inventory {
+		keys = append(keys, fmt.Sprintf("inventory:%d", item.ID))
+	}
+	for _, table := range tables {
+		keys = append(keys, fmt.Sprintf("table:%d", table.ID))
+	}
+	for _, key := range keys {
+		if err := u.cache.Delete(ctx, key); err != nil {
+			u.logger.Errorf("Error deleting cache %s: %v", key, err)
+		}
+	}
+	if err := u.cache.InvalidateTags(ctx, menuListTag, inventoryListTag, tableListTag, availableTablesTag); err != nil {
+		u.logger.Errorf("Error invalidating catalog cache: %v", err)
+	}
+
+	u.logger.Infof("Imported catalog: %+v", *result)
+	return result, nil
+}
+
+// validateCatalog reports every problem in the catalog at once
+func validateCatalog(catalog *model.CatalogExport) error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	titles := make(map[string]bool)
+	for i, menu := range catalog.Menus {
+		switch {
+		case strings.TrimSpace(menu.Title) == "":
+			fail("menu %d has no title", i+1)
+		case titles[menu.Title]:
+			fail("menu %q appears more than once", menu.Title)
+		}
+		titles[menu.Title] = true
+		if menu.Price < 0 || menu.Quantity < 0 {
+			fail("menu %q has a negative price or quantity", menu.Title)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, item := range catalog.Inventory {
+		switch {
+		case strings.TrimSpace(item.Name) == "" || item.Unit == "":
+			fail("inventory item %d needs a name and a unit", i+1)
+		case names[item.Name]:
+			fail("inventory item %q appears more than once", item.Name)
+		}
+		names[item.Name] = true
+		if item.Quantity < 0 || item.MinimumStock < 0 || item.ReorderPoint < 0 || item.UnitPrice < 0 {
+			fail("inventory item %q has a negative amount", item.Name)
+		}
+	}
+
+	numbers := make(map[int]bool)
+	for _, table := range catalog.Tables {
+		switch {
+		case table.TableNumber < 1:
+			fail("table number %d must be positive", table.TableNumber)
+		case numbers[table.TableNumber]:
+			fail("table %d appears more than once", table.TableNumber)
+		}
+		numbers[table.TableNumber] = true
+		if table.Capacity < 1 {
+			fail("table %d must seat at least one guest", table.TableNumber)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", constants.ErrInvalidCatalog, errors.Join(errs...))
+	}
+	return nil
+}
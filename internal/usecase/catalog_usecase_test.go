@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCatalogRepository struct {
+	mock.Mock
+}
+
+func (m *MockCatalogRepository) GetMenus(ctx context.Context) ([]entity.Menu, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.Menu), args.Error(1)
+}
+
+func (m *MockCatalogRepository) GetInventory(ctx context.Context) ([]entity.Inventory, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.Inventory), args.Error(1)
+}
+
+func (m *MockCatalogRepository) GetTables(ctx context.Context) ([]entity.Table, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.Table), args.Error(1)
+}
+
+func (m *MockCatalogRepository) Import(ctx context.Context, menus []entity.Menu, inventory []entity.Inventory, tables []entity.Table) (*model.CatalogImportResult, error) {
+	args := m.Called(menus, inventory, tables)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.CatalogImportResult), args.Error(1)
+}
+
+func TestCatalogUseCase_Export(t *testing.T) {
+	mockRepo := new(MockCatalogRepository)
+	useCase := NewCatalogUseCase(mockRepo, logrus.New(), nil)
+
+	mockRepo.On("GetMenus").Return([]entity.Menu{{ID: 2, Title: "Black Forest", Price: 150000, Rating: 4.5}}, nil).Once()
+	mockRepo.On("GetInventory").Return([]entity.Inventory{{ID: 1, Name: "Flour", Unit: "kg", Quantity: 20}}, nil).Once()
+	mockRepo.On("GetTables").Return([]entity.Table{}, nil).Once()
+
+	export, err := useCase.Export(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []model.CatalogMenu{{Title: "Black Forest", Price: 150000}}, export.Menus)
+	assert.Equal(t, "kg", export.Inventory[0].Unit)
+	assert.NotNil(t, export.Tables)
+}
+
+func TestCatalogUseCase_Import(t *testing.T) {
+	catalog := &model.CatalogExport{
+		Menus:     []model.CatalogMenu{{Title: "Black Forest", Price: 150000, Quantity: 10}},
+		Inventory: []model.CatalogInventoryItem{{Name: "Flour", Unit: "kg", Quantity: 20}},
+		Tables:    []model.CatalogTable{{TableNumber: 1, Capacity: 4}},
+	}
+
+	t.Run("saves and invalidates", func(t *testing.T) {
+		mockRepo := new(MockCatalogRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewCatalogUseCase(mockRepo, logrus.New(), mockCache)
+
+		result := &model.CatalogImportResult{MenusUpdated: 1, InventoryCreated: 1, TablesCreated: 1}
+		mockRepo.On("Import",
+			[]entity.Menu{{Title: "Black Forest", Price: 150000, Quantity: 10}},
+			[]entity.Inventory{{Name: "Flour", Unit: "kg", Quantity: 20}},
+			[]entity.Table{{TableNumber: 1, Capacity: 4}},
+		).Run(func(args mock.Arguments) {
+			args.Get(0).([]entity.Menu)[0].ID = 2
+			args.Get(1).([]entity.Inventory)[0].ID = 3
+			args.Get(2).([]entity.Table)[0].ID = 4
+		}).Return(result, nil).Once()
+		for _, key := range []string{"menu:2", "inventory:3", "table:4"} {
+			mockCache.On("Delete", mock.Anything, key).Return(nil).Once()
+		}
+		mockCache.On("InvalidateTags", mock.Anything, []string{menuListTag, inventoryListTag, tableListTag, availableTablesTag}).Return(nil).Once()
+
+		imported, err := useCase.Import(context.Background(), catalog)
+
+		require.NoError(t, err)
+		assert.Equal(t, result, imported)
+		mockRepo.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("rejects an invalid catalog before writing", func(t *testing.T) {
+		mockRepo := new(MockCatalogRepository)
+		useCase := NewCatalogUseCase(mockRepo, logrus.New(), nil)
+
+		_, err := useCase.Import(context.Background(), &model.CatalogExport{
+			Menus:  []model.CatalogMenu{{Title: "Black Forest"}, {Title: "Black Forest"}, {Title: " ", Price: -1}},
+			Tables: []model.CatalogTable{{TableNumber: 1, Capacity: 0}},
+		})
+
+		assert.ErrorIs(t, err, constants.ErrInvalidCatalog)
+		assert.ErrorContains(t, err, `menu "Black Forest" appears more than once`)
+		assert.ErrorContains(t, err, "menu 3 has no title")
+		assert.ErrorContains(t, err, "table 1 must seat at least one guest")
+		mockRepo.AssertNotCalled(t, "Import", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,140 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// deletedCustomerName replaces the name of a deleted account, which stays
+// behind its orders
+const deletedCustomerName = "Deleted customer"
+
+// CustomerDataUseCase covers a customer's rights over their personal data
+type CustomerDataUseCase interface {
+	// Export gathers everything stored about the customer
+	Export(ctx context.Context, customerID int64) (*model.CustomerDataExport, error)
+	// DeleteAccount anonymises the account once the password is confirmed.
+	// Orders are kept without personal details so sales figures still add up.
+	DeleteAccount(ctx context.Context, customerID int64, request *model.DeleteAccountRequest) error
+}
+
+type customerDataUseCase struct {
+	customers repository.CustomerRepository
+	repo      repository.CustomerDataRepository
+	logger    *logrus.Logger
+	sessions  SessionUseCase
+	cache     database.RedisCache
+	now       func() time.Time
+}
+
+func NewCustomerDataUseCase(customers repository.CustomerRepository, repo repository.CustomerDataRepository, logger *logrus.Logger, sessions SessionUseCase, cache database.RedisCache) CustomerDataUseCase {
+	return &customerDataUseCase{
+		customers: customers,
+		repo:      repo,
+		logger:    logger,
+		sessions:  sessions,
+		cache:     cache,
+		now:       time.Now,
+	}
+}
+
+func (u *customerDataUseCase) Export(ctx context.Context, customerID int64) (*model.CustomerDataExport, error) {
+	customer, err := u.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := u.repo.GetOrders(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	reservations, err := u.repo.GetReservations(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	wishlist, err := u.repo.GetWishlist(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	carts, err := u.repo.GetCarts(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.CustomerDataExport{
+		ExportedAt:   u.now(),
+		Profile:      model.ToExportedProfile(customer),
+		Orders:       make([]model.ExportedOrder, 0, len(orders)),
+		Reservations: make([]model.ExportedReservation, 0, len(reservations)),
+		Wishlist:     make([]model.ExportedWishlistItem, 0, len(wishlist)),
+		Carts:        make([]model.ExportedCartItem, 0, len(carts)),
+	}
+	for i := range orders {
+		export.Orders = append(export.Orders, model.ToExportedOrder(&orders[i]))
+	}
+	for i := range reservations {
+		export.Reservations = append(export.Reservations, model.ToExportedReservation(&reservations[i]))
+	}
+	for i := range wishlist {
+		export.Wishlist = append(export.Wishlist, model.ToExportedWishlistItem(&wishlist[i]))
+	}
+	for i := range carts {
+		export.Carts = append(export.Carts, model.ToExportedCartItem(&carts[i]))
+	}
+
+	u.logger.Infof("Customer %d exported their data", customerID)
+	return export, nil
+}
+
+func (u *customerDataUseCase) DeleteAccount(ctx context.Context, customerID int64, request *model.DeleteAccountRequest) error {
+	customer, err := u.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+
+	// Staff accounts are referenced by shifts and receipts and are removed by an admin
+	if customer.Role != constants.RoleCustomer {
+		return constants.ErrStaffAccountDeletion
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(request.Password)); err != nil {
+		return constants.ErrInvalidPassword
+	}
+
+	now := u.now()
+	// The placeholder address keeps the unique email column satisfied and
+	// frees the real address for a new registration
+	customer.Name = deletedCustomerName
+	customer.Email = fmt.Sprintf("deleted-%d@deleted.invalid", customer.ID)
+	customer.Address = ""
+	customer.Password = ""
+	customer.EmailVerifiedAt = sql.NullTime{}
+	customer.UpdatedAt = now
+	customer.DeletedAt = sql.NullTime{Time: now, Valid: true}
+	if err := u.repo.Anonymize(ctx, customer); err != nil {
+		return err
+	}
+
+	if err := u.cache.Delete(ctx, fmt.Sprintf("customer:%d", customerID)); err != nil {
+		u.logger.Errorf("Error deleting cache for customer ID %d: %v", customerID, err)
+	}
+	// Lists may still hold the scrubbed address, notes, cart and wishlist
+	if err := u.cache.InvalidateTags(ctx,
+		cartListTag(customerID), wishlistTag(customerID),
+		orderListTag, reservationListTag, adminReservationListTag,
+	); err != nil {
+		u.logger.Errorf("Error deleting cached lists for customer ID %d: %v", customerID, err)
+	}
+
+	u.logger.Infof("Customer %d deleted their account", customerID)
+	return u.sessions.RevokeAll(ctx, customerID)
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockCustomerDataRepository struct {
+	mock.Mock
+}
+
+func (m *MockCustomerDataRepository) GetOrders(ctx context.Context, customerID int64) ([]entity.Order, error) {
+	args := m.Called(customerID)
+	return args.Get(0).([]entity.Order), args.Error(1)
+}
+
+func (m *MockCustomerDataRepository) GetReservations(ctx context.Context, customerID int64) ([]entity.Reservation, error) {
+	args := m.Called(customerID)
+	return args.Get(0).([]entity.Reservation), args.Error(1)
+}
+
+func (m *MockCustomerDataRepository) GetWishlist(ctx context.Context, customerID int64) ([]entity.WishList, error) {
+	args := m.Called(customerID)
+	return args.Get(0).([]entity.WishList), args.Error(1)
+}
+
+func (m *MockCustomerDataRepository) GetCarts(ctx context.Context, customerID int64) ([]entity.Cart, error) {
+	args := m.Called(customerID)
+	return args.Get(0).([]entity.Cart), args.Error(1)
+}
+
+func (m *MockCustomerDataRepository) Anonymize(ctx context.Context, customer *entity.Customer) error {
+	args := m.Called(customer)
+	return args.Error(0)
+}
+
+func TestCustomerDataUseCase_Export(t *testing.T) {
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockRepo := new(MockCustomerDataRepository)
+	useCase := NewCustomerDataUseCase(mockCustomerRepo, mockRepo, logrus.New(), nil, nil)
+
+	mockCustomerRepo.On("GetByID", int64(5)).Return(&entity.Customer{ID: 5, Name: "Rafli", Email: "rafli@email.com", Password: "hash"}, nil).Once()
+	mockRepo.On("GetOrders", int64(5)).Return([]entity.Order{{
+		ID:      9,
+		Status:  entity.OrderStatusDelivered,
+		Address: "Jl. Merdeka 1",
+		Items:   []entity.OrderItem{{MenuID: 2, Menu: entity.Menu{Title: "Black Forest"}, Quantity: 1, Price: 150000}},
+	}}, nil).Once()
+	mockRepo.On("GetReservations", int64(5)).Return([]entity.Reservation{{ID: 3, GuestCount: 4, SpecialNotes: "Birthday"}}, nil).Once()
+	mockRepo.On("GetWishlist", int64(5)).Return([]entity.WishList{{MenuID: 2, Menu: entity.Menu{Title: "Black Forest"}}}, nil).Once()
+	mockRepo.On("GetCarts", int64(5)).Return([]entity.Cart{}, nil).Once()
+
+	export, err := useCase.Export(context.Background(), 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "rafli@email.com", export.Profile.Email)
+	assert.Equal(t, "Black Forest", export.Orders[0].Items[0].MenuTitle)
+	assert.Equal(t, "Birthday", export.Reservations[0].SpecialNotes)
+	assert.Len(t, export.Wishlist, 1)
+	assert.NotNil(t, export.Carts)
+}
+
+func TestCustomerDataUseCase_DeleteAccount(t *testing.T) {
+	logger := logrus.New()
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+
+	t.Run("anonymises the account", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockRepo := new(MockCustomerDataRepository)
+		mockSessions := new(MockSessionUseCase)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewCustomerDataUseCase(mockCustomerRepo, mockRepo, logger, mockSessions, mockCache).(*customerDataUseCase)
+		now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		useCase.now = func() time.Time { return now }
+
+		mockCustomerRepo.On("GetByID", int64(5)).Return(&entity.Customer{
+			ID: 5, Name: "Rafli", Email: "rafli@email.com", Address: "Jl. Merdeka 1", Password: string(hashed), Role: constants.RoleCustomer,
+		}, nil).Once()
+		mockRepo.On("Anonymize", mock.MatchedBy(func(customer *entity.Customer) bool {
+			return customer.Name == deletedCustomerName &&
+				customer.Email == "deleted-5@deleted.invalid" &&
+				customer.Address == "" &&
+				customer.Password == "" &&
+				customer.DeletedAt.Valid && customer.DeletedAt.Time.Equal(now)
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "customer:5").Return(nil).Once()
+		mockCache.On("InvalidateTags", mock.Anything, []string{cartListTag(5), wishlistTag(5), orderListTag, reservationListTag, adminReservationListTag}).Return(nil).Once()
+		mockSessions.On("RevokeAll", int64(5)).Return(nil).Once()
+
+		err := useCase.DeleteAccount(context.Background(), 5, &model.DeleteAccountRequest{Password: "secret123"})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockSessions.AssertExpectations(t)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockRepo := new(MockCustomerDataRepository)
+		useCase := NewCustomerDataUseCase(mockCustomerRepo, mockRepo, logger, nil, nil)
+
+		mockCustomerRepo.On("GetByID", int64(5)).Return(&entity.Customer{ID: 5, Password: string(hashed), Role: constants.RoleCustomer}, nil).Once()
+
+		err := useCase.DeleteAccount(context.Background(), 5, &model.DeleteAccountRequest{Password: "guess"})
+
+		assert.ErrorIs(t, err, constants.ErrInvalidPassword)
+		mockRepo.AssertNotCalled(t, "Anonymize", mock.Anything)
+	})
+
+	t.Run("staff account", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockRepo := new(MockCustomerDataRepository)
+		useCase := NewCustomerDataUseCase(mockCustomerRepo, mockRepo, logger, nil, nil)
+
+		mockCustomerRepo.On("GetByID", int64(2)).Return(&entity.Customer{ID: 2, Password: string(hashed), Role: constants.RoleCashier}, nil).Once()
+
+		err := useCase.DeleteAccount(context.Background(), 2, &model.DeleteAccountRequest{Password: "secret123"})
+
+		assert.ErrorIs(t, err, constants.ErrStaffAccountDeletion)
+		mockRepo.AssertNotCalled(t, "Anonymize", mock.Anything)
+	})
+}
@@ -7,47 +7,62 @@ import (
 	"cakestore/internal/domain/model"
 	"cakestore/internal/repository"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type CustomerUseCase interface {
-	Register(request *model.CreateCustomerRequest, role string) (*entity.Customer, error)
-	Login(request *model.LoginRequest) (*string, error)
-	GetCustomerByID(id int64) (*entity.Customer, error)
-	UpdateCustomer(id int64, request *model.UpdateUserRequest) error
-	GetEmployees() ([]entity.Customer, error)
-	GetEmployeeByID(id int64) (*entity.Customer, error)
-	UpdateEmployee(id int64, request *model.UpdateUserRequest, role string) error
-	DeleteEmployee(id int64) error
+	Register(ctx context.Context, request *model.CreateCustomerRequest) (*entity.Customer, error)
+	Login(ctx context.Context, request *model.LoginRequest, clientIP string) (*model.LoginResult, error)
+	GetCustomerByID(ctx context.Context, id int64) (*entity.Customer, error)
+	UpdateCustomer(ctx context.Context, id int64, request *model.UpdateUserRequest) error
+	ChangePassword(ctx context.Context, id int64, request *model.ChangePasswordRequest) error
+	// CreateEmployee creates a staff account and mails it an invite link
+	CreateEmployee(ctx context.Context, request *model.CreateEmployeeRequest) (*entity.Customer, error)
+	// InviteEmployee mails a new invite link to an employee who has not accepted one yet
+	InviteEmployee(ctx context.Context, id int64) error
+	GetEmployees(ctx context.Context) ([]entity.Customer, error)
+	GetEmployeeByID(ctx context.Context, id int64) (*entity.Customer, error)
+	UpdateEmployee(ctx context.Context, id int64, request *model.UpdateUserRequest, role string) error
+	DeleteEmployee(ctx context.Context, id int64) error
 }
 
 type customerUseCase struct {
 	repo      repository.CustomerRepository
 	logger    *logrus.Logger
-	jwtSecret string
+	sessions  SessionUseCase
+	accounts  AccountUseCase
+	attempts  LoginAttemptUseCase
+	twoFactor TwoFactorUseCase
+	roles     RoleUseCase
 	cache     database.RedisCache
 }
 
-func NewCustomerUseCase(repo repository.CustomerRepository, logger *logrus.Logger, jwtSecret string, cache database.RedisCache) CustomerUseCase {
+func NewCustomerUseCase(repo repository.CustomerRepository, logger *logrus.Logger, sessions SessionUseCase, accounts AccountUseCase, attempts LoginAttemptUseCase, twoFactor TwoFactorUseCase, roles RoleUseCase, cache database.RedisCache) CustomerUseCase {
 	return &customerUseCase{
 		repo:      repo,
 		logger:    logger,
-		jwtSecret: jwtSecret,
+		sessions:  sessions,
+		accounts:  accounts,
+		attempts:  attempts,
+		twoFactor: twoFactor,
+		roles:     roles,
 		cache:     cache,
 	}
 }
 
-func (uc *customerUseCase) Register(request *model.CreateCustomerRequest, role string) (*entity.Customer, error) {
+// Register signs up a customer. Staff accounts are created by an admin with
+// CreateEmployee.
+func (uc *customerUseCase) Register(ctx context.Context, request *model.CreateCustomerRequest) (*entity.Customer, error) {
 	// Check if email already exists
-	existingCustomer, err := uc.repo.GetByEmail(request.Email)
+	existingCustomer, err := uc.repo.GetByEmail(ctx, request.Email)
 	if err == nil && existingCustomer != nil {
-		return nil, errors.New("email already registered")
+		return nil, constants.ErrEmailAlreadyRegistered
 	}
 
 	// Hash password
@@ -57,177 +72,258 @@ func (uc *customerUseCase) Register(request *model.CreateCustomerRequest, role s
 		return nil, err
 	}
 
-	if role == "" {
-		role = constants.RoleCustomer
-	}
-
 	customer := &entity.Customer{
 		Name:      request.Name,
 		Email:     request.Email,
 		Password:  string(hashedPassword),
 		Address:   request.Address,
-		Role:      role,
+		Role:      constants.RoleCustomer,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := uc.repo.Create(customer); err != nil {
+	if err := uc.repo.Create(ctx, customer); err != nil {
 		uc.logger.Errorf("Error creating customer: %v", err)
 		return nil, err
 	}
 
+	// Customers must verify their address before ordering. A failed email is
+	// not fatal: the customer can ask for a new link.
+	if err := uc.accounts.SendVerification(ctx, customer); err != nil {
+		uc.logger.Errorf("Error sending verification email to customer %d: %v", customer.ID, err)
+	}
+
 	return customer, nil
 }
 
-func (uc *customerUseCase) Login(request *model.LoginRequest) (*string, error) {
-	customer, err := uc.repo.GetByEmail(request.Email)
+func (uc *customerUseCase) CreateEmployee(ctx context.Context, request *model.CreateEmployeeRequest) (*entity.Customer, error) {
+	if request.Role == constants.RoleCustomer || request.Role == constants.RoleGuest {
+		return nil, constants.ErrInvalidEmployeeRole
+	}
+	if err := uc.roles.Exists(ctx, request.Role); err != nil {
+		return nil, err
+	}
+
+	existing, err := uc.repo.GetByEmail(ctx, request.Email)
+	if err == nil && existing != nil {
+		return nil, constants.ErrEmailAlreadyRegistered
+	}
+
+	// Nobody knows this password; the employee sets their own through the invite
+	placeholder, err := newOpaqueToken()
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(placeholder), bcrypt.DefaultCost)
+	if err != nil {
+		uc.logger.Errorf("Error hashing password: %v", err)
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(request.Password)); err != nil {
-		return nil, errors.New("invalid email or password")
+	employee := &entity.Customer{
+		Name:      request.Name,
+		Email:     request.Email,
+		Password:  string(hashedPassword),
+		Address:   request.Address,
+		Role:      request.Role,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := uc.repo.Create(ctx, employee); err != nil {
+		uc.logger.Errorf("Error creating employee: %v", err)
+		return nil, err
 	}
 
-	// Generate JWT token
-	claims := jwt.MapClaims{
-		"customer_id": customer.ID,
-		"email":       customer.Email,
-		"role":        customer.Role,
-		"exp":         time.Now().Add(time.Hour * 24).Unix(),
+	// A lookup of the new ID may have been cached as not found
+	if err := uc.cache.Delete(ctx, fmt.Sprintf("employee:%d", employee.ID)); err != nil {
+		uc.logger.Errorf("Error deleting cache for employee ID %d: %v", employee.ID, err)
+	}
+	if err := uc.cache.Delete(ctx, "employees"); err != nil {
+		uc.logger.Errorf("Error deleting cache for employees: %v", err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(uc.jwtSecret))
+	// The account exists either way; a lost invite can be sent again
+	if err := uc.accounts.SendInvite(ctx, employee); err != nil {
+		uc.logger.Errorf("Error sending invite to employee %d: %v", employee.ID, err)
+	}
+
+	uc.logger.Infof("Employee %d created with role %s", employee.ID, employee.Role)
+	return employee, nil
+}
+
+func (uc *customerUseCase) InviteEmployee(ctx context.Context, id int64) error {
+	employee, err := uc.repo.GetEmployeeByID(ctx, id)
 	if err != nil {
-		uc.logger.Errorf("Error generating token: %v", err)
-		return nil, err
+		return err
 	}
 
-	return &tokenString, nil
+	// The address is verified when the invite is accepted
+	if employee.EmailVerifiedAt.Valid {
+		return constants.ErrInviteAlreadyAccepted
+	}
+
+	return uc.accounts.SendInvite(ctx, employee)
 }
 
-func (uc *customerUseCase) GetCustomerByID(id int64) (*entity.Customer, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetCustomerByID took %v", time.Since(start))
-	}()
+func (uc *customerUseCase) Login(ctx context.Context, request *model.LoginRequest, clientIP string) (*model.LoginResult, error) {
+	if err := uc.attempts.Check(ctx, request.Email, clientIP); err != nil {
+		return nil, err
+	}
 
-	// Try to get the customer from the cache first
-	cacheKey := fmt.Sprintf("customer:%d", id)
-	var customer entity.Customer
-	if err := uc.cache.Get(context.Background(), cacheKey, &customer); err == nil {
-		uc.logger.Info("Customer fetched from cache")
-		return &customer, nil
+	// Unknown emails count as failures too, so probing for accounts is throttled the same way
+	customer, err := uc.repo.GetByEmail(ctx, request.Email)
+	if err != nil {
+		uc.attempts.RecordFailure(ctx, request.Email, clientIP)
+		return nil, constants.ErrInvalidCredentials
 	}
 
-	// If not in cache, get from the database
-	customerEntity, err := uc.repo.GetByID(id)
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(request.Password)); err != nil {
+		uc.attempts.RecordFailure(ctx, request.Email, clientIP)
+		return nil, constants.ErrInvalidCredentials
+	}
+
+	// With two-factor authentication the failures are only cleared once the
+	// second step succeeds
+	challenge, err := uc.twoFactor.Challenge(ctx, customer)
 	if err != nil {
-		uc.logger.Errorf("Error getting customer by ID: %v", err)
 		return nil, err
 	}
+	if challenge != nil {
+		return &model.LoginResult{Challenge: challenge}, nil
+	}
 
-	// Store the customer in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, customerEntity, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for customer ID %d: %v", id, err)
+	uc.attempts.RecordSuccess(ctx, request.Email)
+	tokens, err := uc.sessions.Issue(ctx, customer)
+	if err != nil {
+		return nil, err
 	}
+	return &model.LoginResult{Tokens: tokens}, nil
+}
 
-	return customerEntity, nil
+func (uc *customerUseCase) GetCustomerByID(ctx context.Context, id int64) (*entity.Customer, error) {
+	ctx, span := tracer.Start(ctx, "CustomerUseCase.GetCustomerByID")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("customer:%d", id)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(nil), func() (*entity.Customer, error) {
+		customerEntity, err := uc.repo.GetByID(ctx, id)
+		if err != nil {
+			uc.logger.Errorf("Error getting customer by ID: %v", err)
+			return nil, err
+		}
+		return customerEntity, nil
+	})
 }
 
-func (uc *customerUseCase) UpdateCustomer(id int64, request *model.UpdateUserRequest) error {
-	customer, err := uc.repo.GetByID(id)
+func (uc *customerUseCase) UpdateCustomer(ctx context.Context, id int64, request *model.UpdateUserRequest) error {
+	customer, err := uc.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	emailChanged := customer.Email != request.Email
+
 	customer.Name = request.Name
 	customer.Address = request.Address
 	customer.Email = request.Email
 	customer.UpdatedAt = time.Now()
+	if emailChanged {
+		customer.EmailVerifiedAt = sql.NullTime{}
+	}
 
-	if err := uc.repo.Update(customer); err != nil {
+	if err := uc.repo.Update(ctx, customer); err != nil {
 		uc.logger.Errorf("Error updating customer: %v", err)
 		return err
 	}
 
+	if emailChanged {
+		if err := uc.accounts.SendVerification(ctx, customer); err != nil {
+			uc.logger.Errorf("Error sending verification email to customer %d: %v", customer.ID, err)
+		}
+	}
+
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("customer:%d", id)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := uc.cache.Delete(ctx, cacheKey); err != nil {
 		uc.logger.Errorf("Error deleting cache for customer ID %d: %v", id, err)
 	}
 
 	return nil
 }
 
-func (uc *customerUseCase) GetEmployees() ([]entity.Customer, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetEmployees took %v", time.Since(start))
-	}()
+func (uc *customerUseCase) ChangePassword(ctx context.Context, id int64, request *model.ChangePasswordRequest) error {
+	customer, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
 
-	// Try to get the employees from the cache first
-	cacheKey := "employees"
-	var employees []entity.Customer
-	if err := uc.cache.Get(context.Background(), cacheKey, &employees); err == nil {
-		uc.logger.Info("Employees fetched from cache")
-		return employees, nil
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(request.CurrentPassword)); err != nil {
+		return constants.ErrInvalidPassword
 	}
 
-	// If not in cache, get from the database
-	employees, err := uc.repo.GetEmployees()
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.NewPassword), bcrypt.DefaultCost)
 	if err != nil {
-		uc.logger.Errorf("Error getting employees: %v", err)
-		return nil, err
+		uc.logger.Errorf("Error hashing password: %v", err)
+		return err
 	}
 
-	// Store the employees in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, employees, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for employees: %v", err)
+	customer.Password = string(hashedPassword)
+	customer.UpdatedAt = time.Now()
+	if err := uc.repo.Update(ctx, customer); err != nil {
+		uc.logger.Errorf("Error updating password: %v", err)
+		return err
 	}
 
-	return employees, nil
+	// Whoever knew the old password may still hold a session
+	return uc.sessions.RevokeAll(ctx, id)
 }
 
-func (uc *customerUseCase) GetEmployeeByID(id int64) (*entity.Customer, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetEmployeeByID took %v", time.Since(start))
-	}()
-
-	// Try to get the employee from the cache first
-	cacheKey := fmt.Sprintf("employee:%d", id)
-	var employee entity.Customer
-	if err := uc.cache.Get(context.Background(), cacheKey, &employee); err == nil {
-		uc.logger.Info("Employee fetched from cache")
-		return &employee, nil
-	}
+func (uc *customerUseCase) GetEmployees(ctx context.Context) ([]entity.Customer, error) {
+	ctx, span := tracer.Start(ctx, "CustomerUseCase.GetEmployees")
+	defer span.End()
 
-	// If not in cache, get from the database
-	employeeEntity, err := uc.repo.GetEmployeeByID(id)
-	if err != nil {
-		if errors.Is(err, constants.ErrNotFound) {
+	cacheKey := "employees"
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, listLoad(), func() ([]entity.Customer, error) {
+		employees, err := uc.repo.GetEmployees(ctx)
+		if err != nil {
+			uc.logger.Errorf("Error getting employees: %v", err)
 			return nil, err
 		}
-		uc.logger.Errorf("Error getting employee by ID: %v", err)
-		return nil, err
-	}
+		return employees, nil
+	})
+}
 
-	// Store the employee in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, employeeEntity, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for employee ID %d: %v", id, err)
-	}
+func (uc *customerUseCase) GetEmployeeByID(ctx context.Context, id int64) (*entity.Customer, error) {
+	ctx, span := tracer.Start(ctx, "CustomerUseCase.GetEmployeeByID")
+	defer span.End()
 
-	return employeeEntity, nil
+	cacheKey := fmt.Sprintf("employee:%d", id)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(constants.ErrNotFound), func() (*entity.Customer, error) {
+		employeeEntity, err := uc.repo.GetEmployeeByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, constants.ErrNotFound) {
+				return nil, err
+			}
+			uc.logger.Errorf("Error getting employee by ID: %v", err)
+			return nil, err
+		}
+		return employeeEntity, nil
+	})
 }
 
-func (uc *customerUseCase) UpdateEmployee(id int64, request *model.UpdateUserRequest, role string) error {
-	employee, err := uc.repo.GetEmployeeByID(id)
+func (uc *customerUseCase) UpdateEmployee(ctx context.Context, id int64, request *model.UpdateUserRequest, role string) error {
+	employee, err := uc.repo.GetEmployeeByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	roleChanged := role != "" && role != employee.Role
+	if roleChanged {
+		if err := uc.roles.Exists(ctx, role); err != nil {
+			return err
+		}
+	}
+
 	// Update fields
 	employee.Name = request.Name
 	employee.Email = request.Email
@@ -237,37 +333,44 @@ func (uc *customerUseCase) UpdateEmployee(id int64, request *model.UpdateUserReq
 		employee.Role = role
 	}
 
-	if err := uc.repo.UpdateEmployee(id, request, role); err != nil {
+	if err := uc.repo.UpdateEmployee(ctx, id, request, role); err != nil {
 		uc.logger.Errorf("Error updating employee: %v", err)
 		return err
 	}
 
+	// The role is baked into issued tokens, so a role change needs a fresh login
+	if roleChanged {
+		if err := uc.sessions.RevokeAll(ctx, id); err != nil {
+			return err
+		}
+	}
+
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("employee:%d", id)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := uc.cache.Delete(ctx, cacheKey); err != nil {
 		uc.logger.Errorf("Error deleting cache for employee ID %d: %v", id, err)
 	}
-	if err := uc.cache.Delete(context.Background(), "employees"); err != nil {
+	if err := uc.cache.Delete(ctx, "employees"); err != nil {
 		uc.logger.Errorf("Error deleting cache for employees: %v", err)
 	}
 
 	return nil
 }
 
-func (uc *customerUseCase) DeleteEmployee(id int64) error {
-	if err := uc.repo.DeleteEmployee(id); err != nil {
+func (uc *customerUseCase) DeleteEmployee(ctx context.Context, id int64) error {
+	if err := uc.repo.DeleteEmployee(ctx, id); err != nil {
 		uc.logger.Errorf("Error deleting employee: %v", err)
 		return err
 	}
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("employee:%d", id)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := uc.cache.Delete(ctx, cacheKey); err != nil {
 		uc.logger.Errorf("Error deleting cache for employee ID %d: %v", id, err)
 	}
-	if err := uc.cache.Delete(context.Background(), "employees"); err != nil {
+	if err := uc.cache.Delete(ctx, "employees"); err != nil {
 		uc.logger.Errorf("Error deleting cache for employees: %v", err)
 	}
 
-	return nil
+	return uc.sessions.RevokeAll(ctx, id)
 }
@@ -4,6 +4,7 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -17,12 +18,12 @@ type MockCustomerRepository struct {
 	mock.Mock
 }
 
-func (m *MockCustomerRepository) Create(customer *entity.Customer) error {
+func (m *MockCustomerRepository) Create(ctx context.Context, customer *entity.Customer) error {
 	args := m.Called(customer)
 	return args.Error(0)
 }
 
-func (m *MockCustomerRepository) GetByEmail(email string) (*entity.Customer, error) {
+func (m *MockCustomerRepository) GetByEmail(ctx context.Context, email string) (*entity.Customer, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -30,7 +31,7 @@ func (m *MockCustomerRepository) GetByEmail(email string) (*entity.Customer, err
 	return args.Get(0).(*entity.Customer), args.Error(1)
 }
 
-func (m *MockCustomerRepository) GetByID(id int64) (*entity.Customer, error) {
+func (m *MockCustomerRepository) GetByID(ctx context.Context, id int64) (*entity.Customer, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,12 +39,12 @@ func (m *MockCustomerRepository) GetByID(id int64) (*entity.Customer, error) {
 	return args.Get(0).(*entity.Customer), args.Error(1)
 }
 
-func (m *MockCustomerRepository) Update(customer *entity.Customer) error {
+func (m *MockCustomerRepository) Update(ctx context.Context, customer *entity.Customer) error {
 	args := m.Called(customer)
 	return args.Error(0)
 }
 
-func (m *MockCustomerRepository) GetEmployees() ([]entity.Customer, error) {
+func (m *MockCustomerRepository) GetEmployees(ctx context.Context) ([]entity.Customer, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -51,7 +52,7 @@ func (m *MockCustomerRepository) GetEmployees() ([]entity.Customer, error) {
 	return args.Get(0).([]entity.Customer), args.Error(1)
 }
 
-func (m *MockCustomerRepository) GetEmployeeByID(id int64) (*entity.Customer, error) {
+func (m *MockCustomerRepository) GetEmployeeByID(ctx context.Context, id int64) (*entity.Customer, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -59,17 +60,17 @@ func (m *MockCustomerRepository) GetEmployeeByID(id int64) (*entity.Customer, er
 	return args.Get(0).(*entity.Customer), args.Error(1)
 }
 
-func (m *MockCustomerRepository) UpdateEmployee(id int64, request *model.UpdateUserRequest, role string) error {
+func (m *MockCustomerRepository) UpdateEmployee(ctx context.Context, id int64, request *model.UpdateUserRequest, role string) error {
 	args := m.Called(id, request, role)
 	return args.Error(0)
 }
 
-func (m *MockCustomerRepository) DeleteEmployee(id int64) error {
+func (m *MockCustomerRepository) DeleteEmployee(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockCustomerRepository) Delete(id int64) error {
+func (m *MockCustomerRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -78,7 +79,7 @@ func TestCustomerUseCase_GetCustomerByID(t *testing.T) {
 	logger := logrus.New()
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewCustomerUseCase(mockCustomerRepo, logger, "secret", mockCache)
+	useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, nil, nil, nil, mockCache)
 
 	t.Run("success", func(t *testing.T) {
 		expectedCustomer := &entity.Customer{
@@ -94,7 +95,7 @@ func TestCustomerUseCase_GetCustomerByID(t *testing.T) {
 		mockCustomerRepo.On("GetByID", int64(1)).Return(expectedCustomer, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		customer, err := useCase.GetCustomerByID(1)
+		customer, err := useCase.GetCustomerByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, customer)
@@ -106,7 +107,7 @@ func TestCustomerUseCase_GetCustomerByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockCustomerRepo.On("GetByID", int64(1)).Return(nil, errors.New("not found")).Once()
 
-		customer, err := useCase.GetCustomerByID(1)
+		customer, err := useCase.GetCustomerByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, customer)
@@ -118,7 +119,7 @@ func TestCustomerUseCase_GetEmployees(t *testing.T) {
 	logger := logrus.New()
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewCustomerUseCase(mockCustomerRepo, logger, "secret", mockCache)
+	useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, nil, nil, nil, mockCache)
 
 	t.Run("success", func(t *testing.T) {
 		expectedEmployees := []entity.Customer{
@@ -136,7 +137,7 @@ func TestCustomerUseCase_GetEmployees(t *testing.T) {
 		mockCustomerRepo.On("GetEmployees").Return(expectedEmployees, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		employees, err := useCase.GetEmployees()
+		employees, err := useCase.GetEmployees(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, employees)
@@ -148,7 +149,7 @@ func TestCustomerUseCase_GetEmployees(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockCustomerRepo.On("GetEmployees").Return(nil, errors.New("error")).Once()
 
-		employees, err := useCase.GetEmployees()
+		employees, err := useCase.GetEmployees(context.Background())
 
 		assert.Error(t, err)
 		assert.Nil(t, employees)
@@ -160,7 +161,7 @@ func TestCustomerUseCase_GetEmployeeByID(t *testing.T) {
 	logger := logrus.New()
 	mockCustomerRepo := new(MockCustomerRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewCustomerUseCase(mockCustomerRepo, logger, "secret", mockCache)
+	useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, nil, nil, nil, mockCache)
 
 	t.Run("success", func(t *testing.T) {
 		expectedEmployee := &entity.Customer{
@@ -176,7 +177,7 @@ func TestCustomerUseCase_GetEmployeeByID(t *testing.T) {
 		mockCustomerRepo.On("GetEmployeeByID", int64(1)).Return(expectedEmployee, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		employee, err := useCase.GetEmployeeByID(1)
+		employee, err := useCase.GetEmployeeByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, employee)
@@ -188,7 +189,7 @@ func TestCustomerUseCase_GetEmployeeByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockCustomerRepo.On("GetEmployeeByID", int64(1)).Return(nil, errors.New("not found")).Once()
 
-		employee, err := useCase.GetEmployeeByID(1)
+		employee, err := useCase.GetEmployeeByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, employee)
@@ -0,0 +1,392 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"cakestore/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DepositPolicy decides which reservations need a deposit and how it is
+// refunded when the booking is cancelled.
+type DepositPolicy struct {
+	// GuestThreshold requires a deposit for parties larger than it; 0 disables the rule
+	GuestThreshold int
+	// Dates are flagged days (YYYY-MM-DD) on which every reservation needs a deposit
+	Dates []string
+	// NoShowThreshold requires a deposit from customers with at least this many no-shows; 0 disables the rule
+	NoShowThreshold int
+	AmountPerGuest  float64
+	// PaymentWindow is how long the customer has to pay before the reservation is cancelled
+	PaymentWindow time.Duration
+	// RefundCutoff is how long before the reservation a cancellation still gets the deposit back
+	RefundCutoff time.Duration
+}
+
+// DepositUseCase handles reservation deposits: the payment link issued at
+// booking, the gateway notifications for it, and what happens to the money
+// when the party shows up, cancels or never arrives.
+type DepositUseCase interface {
+	IsRequired(ctx context.Context, reservation *entity.Reservation) (bool, error)
+	Create(ctx context.Context, reservation *entity.Reservation) (*entity.ReservationDeposit, error)
+	HandleGatewayNotification(ctx context.Context, gatewayOrderID string, status constants.PaymentStatus) (bool, error)
+	ExpireUnpaid(ctx context.Context, now time.Time) (int, error)
+	ApplyToOrder(ctx context.Context, reservationID uint, orderID int64) (*model.DepositResponse, error)
+	Release(ctx context.Context, reservation *entity.Reservation, now time.Time) error
+	Forfeit(ctx context.Context, reservationID uint) error
+}
+
+type depositUseCase struct {
+	depositRepo     repository.DepositRepository
+	reservationRepo repository.ReservationRepository
+	paymentRepo     repository.PaymentRepository
+	orderRepo       repository.OrderRepository
+	gateway         MidtransConfig
+	log             *logrus.Logger
+	cache           database.RedisCache
+	metrics         metrics.Business
+	policy          DepositPolicy
+}
+
+func NewDepositUseCase(
+	depositRepo repository.DepositRepository,
+	reservationRepo repository.ReservationRepository,
+	paymentRepo repository.PaymentRepository,
+	orderRepo repository.OrderRepository,
+	gateway MidtransConfig,
+	log *logrus.Logger,
+	cache database.RedisCache,
+	recorder metrics.Business,
+	policy DepositPolicy,
+) DepositUseCase {
+	if policy.PaymentWindow <= 0 {
+		policy.PaymentWindow = 24 * time.Hour
+	}
+	if policy.RefundCutoff <= 0 {
+		policy.RefundCutoff = 48 * time.Hour
+	}
+
+	return &depositUseCase{
+		depositRepo:     depositRepo,
+		reservationRepo: reservationRepo,
+		paymentRepo:     paymentRepo,
+		orderRepo:       orderRepo,
+		gateway:         gateway,
+		log:             log,
+		cache:           cache,
+		metrics:         recorder,
+		policy:          policy,
+	}
+}
+
+func (u *depositUseCase) IsRequired(ctx context.Context, reservation *entity.Reservation) (bool, error) {
+	if u.policy.AmountPerGuest <= 0 {
+		return false, nil
+	}
+	if u.policy.GuestThreshold > 0 && reservation.GuestCount > u.policy.GuestThreshold {
+		return true, nil
+	}
+
+	day := reservation.ReserveDate.Format("2006-01-02")
+	for _, date := range u.policy.Dates {
+		if date == day {
+			return true, nil
+		}
+	}
+
+	if u.policy.NoShowThreshold > 0 {
+		noShows, err := u.reservationRepo.CountNoShowsByCustomer(ctx, reservation.CustomerID)
+		if err != nil {
+			return false, err
+		}
+		if noShows >= int64(u.policy.NoShowThreshold) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Create issues the Midtrans link for the reservation's deposit. The customer
+// has until the payment window closes, or the reservation starts, to pay it.
+func (u *depositUseCase) Create(ctx context.Context, reservation *entity.Reservation) (*entity.ReservationDeposit, error) {
+	dueAt := time.Now().Add(u.policy.PaymentWindow)
+	if reservation.ReserveDate.Before(dueAt) {
+		dueAt = reservation.ReserveDate
+	}
+
+	deposit := &entity.ReservationDeposit{
+		ReservationID:  reservation.ID,
+		Amount:         math.Round(u.policy.AmountPerGuest * float64(reservation.GuestCount)),
+		Status:         entity.DepositStatusPending,
+		GatewayOrderID: newDepositGatewayOrderID(reservation.ID),
+		DueAt:          dueAt,
+	}
+
+	paymentResponse, err := createSnapTransaction(ctx, u.gateway, u.metrics, deposit.GatewayOrderID, int64(deposit.Amount))
+	if err != nil {
+		u.log.Errorf("Error creating deposit payment link for reservation ID %d: %v", reservation.ID, err)
+		return nil, err
+	}
+	deposit.PaymentToken = paymentResponse.Token
+	deposit.PaymentURL = paymentResponse.RedirectURL
+
+	if err := u.depositRepo.Create(ctx, deposit); err != nil {
+		return nil, err
+	}
+
+	u.log.Infof("Deposit of %.0f required for reservation %d by %s", deposit.Amount, reservation.ID, dueAt.Format(time.RFC3339))
+	return deposit, nil
+}
+
+// HandleGatewayNotification applies a Midtrans notification to the deposit it was issued for.
+// Returns false when the notification is not about a deposit.
+func (u *depositUseCase) HandleGatewayNotification(ctx context.Context, gatewayOrderID string, status constants.PaymentStatus) (bool, error) {
+	deposit, err := u.depositRepo.GetByGatewayOrderID(ctx, gatewayOrderID)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	switch status {
+	case constants.PaymentStatusSuccess:
+		return true, u.markPaid(ctx, deposit)
+	case constants.PaymentStatusExpired, constants.PaymentStatusCancelled:
+		if deposit.Status != entity.DepositStatusPending {
+			return true, nil
+		}
+		return true, u.expire(ctx, deposit)
+	}
+	return true, nil
+}
+
+// ExpireUnpaid cancels the reservations whose deposit was not paid by the
+// deadline and returns how many were cancelled.
+func (u *depositUseCase) ExpireUnpaid(ctx context.Context, now time.Time) (int, error) {
+	deposits, err := u.depositRepo.GetOverdue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deposits {
+		if err := u.expire(ctx, &deposits[i]); err != nil {
+			return i, err
+		}
+	}
+
+	if len(deposits) > 0 {
+		u.log.Infof("Cancelled %d reservations with unpaid deposits", len(deposits))
+	}
+	return len(deposits), nil
+}
+
+// ApplyToOrder credits a paid deposit to the party's bill as a payment. Any
+// part of the deposit larger than the outstanding balance is recorded as
+// RefundAmount for staff to return by hand; nothing is sent to the gateway.
+func (u *depositUseCase) ApplyToOrder(ctx context.Context, reservationID uint, orderID int64) (*model.DepositResponse, error) {
+	deposit, err := u.depositRepo.GetByReservationID(ctx, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	switch deposit.Status {
+	case entity.DepositStatusPaid:
+	case entity.DepositStatusPending:
+		return nil, constants.ErrDepositUnpaid
+	default:
+		return nil, constants.ErrDepositNotApplicable
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == entity.OrderStatusCancelled {
+		return nil, constants.ErrOrderNotPayable
+	}
+
+	payments, err := u.paymentRepo.GetPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := math.Max(math.Round(order.TotalPrice-sumPayments(payments, constants.PaymentStatusSuccess)), 0)
+	if outstanding == 0 {
+		return nil, constants.ErrOrderAlreadyPaid
+	}
+
+	credit := math.Min(deposit.Amount, outstanding)
+	deposit.Status = entity.DepositStatusApplied
+	deposit.AppliedOrderID = &order.ID
+	deposit.AppliedAmount = credit
+	deposit.RefundAmount = deposit.Amount - credit
+
+	// The credit, the claimed deposit and a fully covered order are written together
+	if err := u.depositRepo.Apply(ctx, deposit, &entity.Payment{
+		OrderID:     order.ID,
+		Amount:      credit,
+		Method:      constants.PaymentMethodDeposit,
+		Status:      constants.PaymentStatusSuccess,
+		Reference:   deposit.GatewayOrderID,
+		Description: fmt.Sprintf("Deposit for reservation %d", reservationID),
+	}, credit == outstanding); err != nil {
+		u.log.Errorf("Error applying deposit of reservation %d to order ID %d: %v", reservationID, order.ID, err)
+		return nil, err
+	}
+	invalidateOrderCache(ctx, u.cache, u.log, order)
+
+	// Applying the deposit means the party turned up
+	if err := u.setReservationStatus(ctx, reservationID, entity.ReservationStatusCompleted); err != nil {
+		return nil, err
+	}
+
+	u.log.Infof("Deposit of reservation %d applied to order %d: %.0f credited, %.0f to refund", reservationID, order.ID, credit, deposit.RefundAmount)
+	return model.ToDepositResponse(deposit), nil
+}
+
+// Release settles the deposit of a cancelled reservation: paid deposits are
+// refunded in full when the cancellation is at least RefundCutoff before the
+// reservation and forfeited otherwise.
+func (u *depositUseCase) Release(ctx context.Context, reservation *entity.Reservation, now time.Time) error {
+	deposit, err := u.depositRepo.GetByReservationID(ctx, reservation.ID)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	switch deposit.Status {
+	case entity.DepositStatusPending:
+		deposit.Status = entity.DepositStatusCancelled
+	case entity.DepositStatusPaid:
+		if reservation.ReserveDate.Sub(now) >= u.policy.RefundCutoff {
+			deposit.Status = entity.DepositStatusRefunded
+			deposit.RefundAmount = deposit.Amount
+			u.log.Infof("Deposit of %.0f for reservation %d to be refunded", deposit.Amount, reservation.ID)
+		} else {
+			deposit.Status = entity.DepositStatusForfeited
+		}
+	default:
+		return nil
+	}
+
+	if err := u.depositRepo.Update(ctx, deposit); err != nil {
+		return err
+	}
+	invalidateReservationCache(ctx, u.cache, u.log, reservation.ID)
+	return nil
+}
+
+// Forfeit keeps the deposit of a reservation the customer never turned up for
+func (u *depositUseCase) Forfeit(ctx context.Context, reservationID uint) error {
+	deposit, err := u.depositRepo.GetByReservationID(ctx, reservationID)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	switch deposit.Status {
+	case entity.DepositStatusPaid:
+		deposit.Status = entity.DepositStatusForfeited
+	case entity.DepositStatusPending:
+		deposit.Status = entity.DepositStatusCancelled
+	default:
+		return nil
+	}
+
+	if err := u.depositRepo.Update(ctx, deposit); err != nil {
+		return err
+	}
+	invalidateReservationCache(ctx, u.cache, u.log, reservationID)
+	return nil
+}
+
+func (u *depositUseCase) markPaid(ctx context.Context, deposit *entity.ReservationDeposit) error {
+	switch deposit.Status {
+	case entity.DepositStatusPending:
+		deposit.Status = entity.DepositStatusPaid
+	case entity.DepositStatusExpired, entity.DepositStatusCancelled:
+		// The money arrived after the reservation was already cancelled, so it is owed back
+		deposit.Status = entity.DepositStatusRefunded
+		deposit.RefundAmount = deposit.Amount
+		u.log.Warnf("Deposit for reservation %d paid after cancellation; %.0f to be refunded", deposit.ReservationID, deposit.Amount)
+	default:
+		return nil
+	}
+	deposit.PaidAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	if err := u.depositRepo.Update(ctx, deposit); err != nil {
+		return err
+	}
+	if deposit.Status != entity.DepositStatusPaid {
+		invalidateReservationCache(ctx, u.cache, u.log, deposit.ReservationID)
+		return nil
+	}
+
+	reservation, err := u.reservationRepo.GetByID(ctx, deposit.ReservationID)
+	if err != nil {
+		return err
+	}
+	if reservation.Status == entity.ReservationStatusPending {
+		now := time.Now()
+		reservation.Status = entity.ReservationStatusConfirmed
+		reservation.ConfirmedAt = &now
+		if err := u.reservationRepo.Update(ctx, reservation); err != nil {
+			return err
+		}
+	}
+	invalidateReservationCache(ctx, u.cache, u.log, deposit.ReservationID)
+
+	u.log.Infof("Deposit for reservation %d paid", deposit.ReservationID)
+	return nil
+}
+
+func (u *depositUseCase) expire(ctx context.Context, deposit *entity.ReservationDeposit) error {
+	deposit.Status = entity.DepositStatusExpired
+	if err := u.depositRepo.Update(ctx, deposit); err != nil {
+		return err
+	}
+
+	if err := u.setReservationStatus(ctx, deposit.ReservationID, entity.ReservationStatusCancelled); err != nil {
+		return err
+	}
+
+	u.log.Infof("Reservation %d cancelled: deposit not paid", deposit.ReservationID)
+	return nil
+}
+
+func (u *depositUseCase) setReservationStatus(ctx context.Context, reservationID uint, status entity.ReservationStatus) error {
+	reservation, err := u.reservationRepo.GetByID(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	if reservation.Status != status {
+		reservation.Status = status
+		if err := u.reservationRepo.Update(ctx, reservation); err != nil {
+			return err
+		}
+		u.metrics.Reservation(string(status))
+	}
+	invalidateReservationCache(ctx, u.cache, u.log, reservationID)
+	return nil
+}
+
+// newDepositGatewayOrderID follows the "<PREFIX>-<id>-<uuid>" shape the webhook expects
+func newDepositGatewayOrderID(reservationID uint) string {
+	return "DEPOSIT-" + strconv.FormatUint(uint64(reservationID), 10) + "-" + uuid.New().String()
+}
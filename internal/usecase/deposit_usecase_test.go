@@ -0,0 +1,339 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDepositRepository struct {
+	mock.Mock
+}
+
+func (m *MockDepositRepository) Create(ctx context.Context, deposit *entity.ReservationDeposit) error {
+	args := m.Called(deposit)
+	return args.Error(0)
+}
+
+func (m *MockDepositRepository) GetByReservationID(ctx context.Context, reservationID uint) (*entity.ReservationDeposit, error) {
+	args := m.Called(reservationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.ReservationDeposit), args.Error(1)
+}
+
+func (m *MockDepositRepository) GetByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.ReservationDeposit, error) {
+	args := m.Called(gatewayOrderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.ReservationDeposit), args.Error(1)
+}
+
+func (m *MockDepositRepository) GetOverdue(ctx context.Context, now time.Time) ([]entity.ReservationDeposit, error) {
+	args := m.Called(now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.ReservationDeposit), args.Error(1)
+}
+
+func (m *MockDepositRepository) Update(ctx context.Context, deposit *entity.ReservationDeposit) error {
+	args := m.Called(deposit)
+	return args.Error(0)
+}
+
+func (m *MockDepositRepository) Apply(ctx context.Context, deposit *entity.ReservationDeposit, credit *entity.Payment, settlesOrder bool) error {
+	args := m.Called(deposit, credit, settlesOrder)
+	return args.Error(0)
+}
+
+func TestDepositUseCase_IsRequired(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	useCase := NewDepositUseCase(nil, mockReservationRepo, nil, nil, MidtransConfig{}, logger, nil, metrics.Noop{}, DepositPolicy{
+		GuestThreshold:  8,
+		Dates:           []string{"2025-12-24"},
+		NoShowThreshold: 2,
+		AmountPerGuest:  50000,
+	})
+
+	tests := []struct {
+		name        string
+		reservation *entity.Reservation
+		noShows     int64
+		expected    bool
+	}{
+		{"large party", &entity.Reservation{CustomerID: 1, GuestCount: 9, ReserveDate: time.Date(2025, 6, 12, 19, 0, 0, 0, time.UTC)}, 0, true},
+		{"flagged date", &entity.Reservation{CustomerID: 1, GuestCount: 2, ReserveDate: time.Date(2025, 12, 24, 19, 0, 0, 0, time.UTC)}, 0, true},
+		{"repeat no-show", &entity.Reservation{CustomerID: 2, GuestCount: 2, ReserveDate: time.Date(2025, 6, 12, 19, 0, 0, 0, time.UTC)}, 2, true},
+		{"regular booking", &entity.Reservation{CustomerID: 3, GuestCount: 8, ReserveDate: time.Date(2025, 6, 12, 19, 0, 0, 0, time.UTC)}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockReservationRepo.On("CountNoShowsByCustomer", tt.reservation.CustomerID).Return(tt.noShows, nil).Maybe()
+
+			required, err := useCase.IsRequired(context.Background(), tt.reservation)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, required)
+		})
+	}
+}
+
+func TestDepositUseCase_HandleGatewayNotification(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("paid deposit confirms the reservation", func(t *testing.T) {
+		mockDepositRepo := new(MockDepositRepository)
+		mockReservationRepo := new(MockReservationRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewDepositUseCase(mockDepositRepo, mockReservationRepo, nil, nil, MidtransConfig{}, logger, mockCache, metrics.Noop{}, DepositPolicy{})
+
+		mockDepositRepo.On("GetByGatewayOrderID", "DEPOSIT-5-abc").Return(&entity.ReservationDeposit{
+			ID:            1,
+			ReservationID: 5,
+			Status:        entity.DepositStatusPending,
+		}, nil).Once()
+		mockDepositRepo.On("Update", mock.MatchedBy(func(d *entity.ReservationDeposit) bool {
+			return d.Status == entity.DepositStatusPaid && d.PaidAt.Valid
+		})).Return(nil).Once()
+		mockReservationRepo.On("GetByID", uint(5)).Return(&entity.Reservation{ID: 5, Status: entity.ReservationStatusPending}, nil).Once()
+		mockReservationRepo.On("Update", mock.MatchedBy(func(r *entity.Reservation) bool {
+			return r.Status == entity.ReservationStatusConfirmed && r.ConfirmedAt != nil
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+		handled, err := useCase.HandleGatewayNotification(context.Background(), "DEPOSIT-5-abc", constants.PaymentStatusSuccess)
+
+		assert.NoError(t, err)
+		assert.True(t, handled)
+		mockDepositRepo.AssertExpectations(t)
+		mockReservationRepo.AssertExpectations(t)
+	})
+
+	t.Run("not a deposit", func(t *testing.T) {
+		mockDepositRepo := new(MockDepositRepository)
+		useCase := NewDepositUseCase(mockDepositRepo, nil, nil, nil, MidtransConfig{}, logger, nil, metrics.Noop{}, DepositPolicy{})
+
+		mockDepositRepo.On("GetByGatewayOrderID", "ORDER-1-abc").Return(nil, constants.ErrNotFound).Once()
+
+		handled, err := useCase.HandleGatewayNotification(context.Background(), "ORDER-1-abc", constants.PaymentStatusSuccess)
+
+		assert.NoError(t, err)
+		assert.False(t, handled)
+	})
+}
+
+func TestDepositUseCase_ExpireUnpaid(t *testing.T) {
+	logger := logrus.New()
+	mockDepositRepo := new(MockDepositRepository)
+	mockReservationRepo := new(MockReservationRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewDepositUseCase(mockDepositRepo, mockReservationRepo, nil, nil, MidtransConfig{}, logger, mockCache, metrics.Noop{}, DepositPolicy{})
+
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+	mockDepositRepo.On("GetOverdue", now).Return([]entity.ReservationDeposit{
+		{ID: 1, ReservationID: 5, Status: entity.DepositStatusPending},
+	}, nil).Once()
+	mockDepositRepo.On("Update", mock.MatchedBy(func(d *entity.ReservationDeposit) bool {
+		return d.Status == entity.DepositStatusExpired
+	})).Return(nil).Once()
+	mockReservationRepo.On("GetByID", uint(5)).Return(&entity.Reservation{ID: 5, Status: entity.ReservationStatusPending}, nil).Once()
+	mockReservationRepo.On("Update", mock.MatchedBy(func(r *entity.Reservation) bool {
+		return r.Status == entity.ReservationStatusCancelled
+	})).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	expired, err := useCase.ExpireUnpaid(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	mockReservationRepo.AssertExpectations(t)
+}
+
+func TestDepositUseCase_ApplyToOrder(t *testing.T) {
+	logger := logrus.New()
+	mockDepositRepo := new(MockDepositRepository)
+	mockReservationRepo := new(MockReservationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockOrderRepo := new(MockOrderRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewDepositUseCase(mockDepositRepo, mockReservationRepo, mockPaymentRepo, mockOrderRepo, MidtransConfig{}, logger, mockCache, metrics.Noop{}, DepositPolicy{})
+
+	mockDepositRepo.On("GetByReservationID", uint(5)).Return(&entity.ReservationDeposit{
+		ID:             1,
+		ReservationID:  5,
+		Amount:         300000,
+		Status:         entity.DepositStatusPaid,
+		GatewayOrderID: "DEPOSIT-5-abc",
+	}, nil).Once()
+	mockOrderRepo.On("GetByID", int64(10)).Return(&entity.Order{ID: 10, Status: entity.OrderStatusPending, TotalPrice: 250000}, nil).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", int64(10)).Return([]entity.Payment{}, nil).Once()
+	mockDepositRepo.On("Apply", mock.MatchedBy(func(d *entity.ReservationDeposit) bool {
+		return d.Status == entity.DepositStatusApplied && *d.AppliedOrderID == 10
+	}), mock.MatchedBy(func(p *entity.Payment) bool {
+		return p.OrderID == 10 && p.Amount == 250000 && p.Method == constants.PaymentMethodDeposit && p.Status == constants.PaymentStatusSuccess
+	}), true).Return(nil).Once()
+	mockReservationRepo.On("GetByID", uint(5)).Return(&entity.Reservation{ID: 5, Status: entity.ReservationStatusConfirmed}, nil).Once()
+	mockReservationRepo.On("Update", mock.MatchedBy(func(r *entity.Reservation) bool {
+		return r.Status == entity.ReservationStatusCompleted
+	})).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	deposit, err := useCase.ApplyToOrder(context.Background(), 5, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, string(entity.DepositStatusApplied), deposit.Status)
+	assert.Equal(t, 250000.0, deposit.AppliedAmount)
+	// the deposit was larger than the bill, so the difference is owed back
+	assert.Equal(t, 50000.0, deposit.RefundAmount)
+	mockDepositRepo.AssertExpectations(t)
+	mockOrderRepo.AssertExpectations(t)
+}
+
+func TestDepositUseCase_ApplyToOrderAlreadyClaimed(t *testing.T) {
+	logger := logrus.New()
+	mockDepositRepo := new(MockDepositRepository)
+	mockReservationRepo := new(MockReservationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockOrderRepo := new(MockOrderRepository)
+	useCase := NewDepositUseCase(mockDepositRepo, mockReservationRepo, mockPaymentRepo, mockOrderRepo, MidtransConfig{}, logger, nil, metrics.Noop{}, DepositPolicy{})
+
+	// Another till applied the deposit between the read and the write
+	mockDepositRepo.On("GetByReservationID", uint(5)).Return(&entity.ReservationDeposit{
+		ID:            1,
+		ReservationID: 5,
+		Amount:        100000,
+		Status:        entity.DepositStatusPaid,
+	}, nil).Once()
+	mockOrderRepo.On("GetByID", int64(10)).Return(&entity.Order{ID: 10, Status: entity.OrderStatusPending, TotalPrice: 250000}, nil).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", int64(10)).Return([]entity.Payment{}, nil).Once()
+	mockDepositRepo.On("Apply", mock.Anything, mock.Anything, false).Return(constants.ErrDepositNotApplicable).Once()
+
+	deposit, err := useCase.ApplyToOrder(context.Background(), 5, 10)
+
+	assert.ErrorIs(t, err, constants.ErrDepositNotApplicable)
+	assert.Nil(t, deposit)
+	mockReservationRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestDepositUseCase_ApplyToOrderAfterCheckout(t *testing.T) {
+	logger := logrus.New()
+	snap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":"snap-token","redirect_url":"https://pay.example/snap"}`))
+	}))
+	defer snap.Close()
+
+	mockDepositRepo := new(MockDepositRepository)
+	mockReservationRepo := new(MockReservationRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockOrderRepo := new(MockOrderRepository)
+	mockMenuRepo := new(MockMenuRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockCache := new(database.MockRedisCacheService)
+	orderUseCase := NewOrderUseCase(mockOrderRepo, mockMenuRepo, mockCustomerRepo, logger, "test", mockCache, metrics.Noop{})
+	paymentUseCase := NewPaymentUseCase(MidtransConfig{Endpoint: snap.URL}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+	useCase := NewDepositUseCase(mockDepositRepo, mockReservationRepo, mockPaymentRepo, mockOrderRepo, MidtransConfig{}, logger, mockCache, metrics.Noop{}, DepositPolicy{})
+
+	mockCustomerRepo.On("GetByID", int64(2)).Return(&entity.Customer{ID: 2, Role: constants.RoleAdmin}, nil)
+	mockMenuRepo.On("GetByID", int64(5)).Return(&entity.Menu{ID: 5, Price: 45000}, nil)
+	mockOrderRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*entity.Order).ID = 11
+	}).Return(nil).Once()
+	var checkout *entity.Payment
+	mockPaymentRepo.On("CreatePayment", mock.MatchedBy(func(p *entity.Payment) bool {
+		return p.Method == constants.PaymentMethodMidtrans
+	})).Run(func(args mock.Arguments) {
+		checkout = args.Get(0).(*entity.Payment)
+	}).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	order, err := orderUseCase.CreateOrder(context.Background(), 2, &model.CreateOrderRequest{
+		Items: []model.OrderItemRequest{{MenuID: 5, Title: "Tiramisu", Quantity: 2}},
+	})
+	assert.NoError(t, err)
+	_, err = paymentUseCase.CreatePaymentURL(context.Background(), order)
+	assert.NoError(t, err)
+
+	// The unpaid checkout link does not count, so the whole bill is outstanding
+	mockDepositRepo.On("GetByReservationID", uint(5)).Return(&entity.ReservationDeposit{
+		ID:            1,
+		ReservationID: 5,
+		Amount:        50000,
+		Status:        entity.DepositStatusPaid,
+	}, nil).Once()
+	mockOrderRepo.On("GetByID", order.ID).Return(order, nil).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{*checkout}, nil).Once()
+	mockDepositRepo.On("Apply", mock.Anything, mock.MatchedBy(func(p *entity.Payment) bool {
+		return p.Method == constants.PaymentMethodDeposit && p.Amount == 50000
+	}), false).Return(nil).Once()
+	mockReservationRepo.On("GetByID", uint(5)).Return(&entity.Reservation{ID: 5, Status: entity.ReservationStatusConfirmed}, nil).Once()
+	mockReservationRepo.On("Update", mock.Anything).Return(nil).Once()
+
+	deposit, err := useCase.ApplyToOrder(context.Background(), 5, order.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 50000.0, deposit.AppliedAmount)
+	assert.Equal(t, 0.0, deposit.RefundAmount)
+	// 40000 is still owed, so the order stays open
+	mockOrderRepo.AssertNotCalled(t, "UpdateStatus", order.ID, entity.OrderStatusPaid)
+	mockPaymentRepo.AssertExpectations(t)
+}
+
+func TestDepositUseCase_Release(t *testing.T) {
+	logger := logrus.New()
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		reserveDate    time.Time
+		expectedStatus entity.DepositStatus
+		expectedRefund float64
+	}{
+		{"cancelled early is refunded", now.Add(72 * time.Hour), entity.DepositStatusRefunded, 200000},
+		{"cancelled late is forfeited", now.Add(6 * time.Hour), entity.DepositStatusForfeited, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDepositRepo := new(MockDepositRepository)
+			mockCache := new(database.MockRedisCacheService)
+			useCase := NewDepositUseCase(mockDepositRepo, nil, nil, nil, MidtransConfig{}, logger, mockCache, metrics.Noop{}, DepositPolicy{RefundCutoff: 48 * time.Hour})
+
+			mockDepositRepo.On("GetByReservationID", uint(5)).Return(&entity.ReservationDeposit{
+				ReservationID: 5,
+				Amount:        200000,
+				Status:        entity.DepositStatusPaid,
+			}, nil).Once()
+			mockDepositRepo.On("Update", mock.MatchedBy(func(d *entity.ReservationDeposit) bool {
+				return d.Status == tt.expectedStatus && d.RefundAmount == tt.expectedRefund
+			})).Return(nil).Once()
+			mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+			mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+			err := useCase.Release(context.Background(), &entity.Reservation{ID: 5, ReserveDate: tt.reserveDate}, now)
+
+			assert.NoError(t, err)
+			mockDepositRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
 	"cakestore/internal/repository"
 	"context"
 	"errors"
@@ -14,30 +15,34 @@ import (
 )
 
 type InventoryUseCase interface {
-	Create(request *model.CreateInventoryRequest) (*model.InventoryResponse, error)
-	GetByID(id uint) (*model.InventoryResponse, error)
-	GetAll(params *model.InventoryQueryParams) (*model.PaginationResponse[[]model.InventoryResponse], error)
-	Update(id uint, request *model.UpdateInventoryRequest) (*model.InventoryResponse, error)
-	Delete(id uint) error
-	UpdateStock(id uint, quantity float64) error
-	GetLowStockIngredients() ([]model.InventoryResponse, error)
+	Create(ctx context.Context, request *model.CreateInventoryRequest) (*model.InventoryResponse, error)
+	GetByID(ctx context.Context, id uint) (*model.InventoryResponse, error)
+	GetAll(ctx context.Context, params *model.InventoryQueryParams) (*model.PaginationResponse[[]model.InventoryResponse], error)
+	Update(ctx context.Context, id uint, request *model.UpdateInventoryRequest) (*model.InventoryResponse, error)
+	Delete(ctx context.Context, id uint) error
+	UpdateStock(ctx context.Context, id uint, quantity float64) error
+	GetLowStockIngredients(ctx context.Context) ([]model.InventoryResponse, error)
+	// RecordLowStock refreshes the low stock metric from the database.
+	RecordLowStock(ctx context.Context) error
 }
 
 type inventoryUseCase struct {
-	repo   repository.InventoryRepository
-	logger *logrus.Logger
-	cache  database.RedisCache
+	repo    repository.InventoryRepository
+	logger  *logrus.Logger
+	cache   database.RedisCache
+	metrics metrics.Business
 }
 
-func NewInventoryUseCase(repo repository.InventoryRepository, logger *logrus.Logger, cache database.RedisCache) InventoryUseCase {
+func NewInventoryUseCase(repo repository.InventoryRepository, logger *logrus.Logger, cache database.RedisCache, recorder metrics.Business) InventoryUseCase {
 	return &inventoryUseCase{
-		repo:   repo,
-		logger: logger,
-		cache:  cache,
+		repo:    repo,
+		logger:  logger,
+		cache:   cache,
+		metrics: recorder,
 	}
 }
 
-func (u *inventoryUseCase) Create(request *model.CreateInventoryRequest) (*model.InventoryResponse, error) {
+func (u *inventoryUseCase) Create(ctx context.Context, request *model.CreateInventoryRequest) (*model.InventoryResponse, error) {
 	ingredient := &entity.Inventory{
 		Name:            request.Name,
 		Quantity:        request.Quantity,
@@ -48,10 +53,14 @@ func (u *inventoryUseCase) Create(request *model.CreateInventoryRequest) (*model
 		LastRestockDate: time.Now(),
 	}
 
-	if err := u.repo.Create(ingredient); err != nil {
+	if err := u.repo.Create(ctx, ingredient); err != nil {
 		return nil, err
 	}
 
+	if err := u.cache.InvalidateTags(ctx, inventoryListTag); err != nil {
+		u.logger.Errorf("Error deleting cache for all ingredients: %v", err)
+	}
+
 	return &model.InventoryResponse{
 		ID:              ingredient.ID,
 		Name:            ingredient.Name,
@@ -66,99 +75,70 @@ func (u *inventoryUseCase) Create(request *model.CreateInventoryRequest) (*model
 	}, nil
 }
 
-func (u *inventoryUseCase) GetByID(id uint) (*model.InventoryResponse, error) {
-	start := time.Now()
-	defer func() {
-		u.logger.Infof("GetByID took %v", time.Since(start))
-	}()
+func (u *inventoryUseCase) GetByID(ctx context.Context, id uint) (*model.InventoryResponse, error) {
+	ctx, span := tracer.Start(ctx, "InventoryUseCase.GetByID")
+	defer span.End()
 
-	// Try to get the ingredient from the cache first
 	cacheKey := fmt.Sprintf("inventory:%d", id)
-	var ingredient model.InventoryResponse
-	if err := u.cache.Get(context.Background(), cacheKey, &ingredient); err == nil {
-		u.logger.Info("Ingredient fetched from cache")
-		return &ingredient, nil
-	}
-
-	// If not in cache, get from the database
-	ingredientEntity, err := u.repo.GetByID(id)
-	if err != nil {
-		return nil, err
-	}
-
-	// Store the ingredient in the cache for future requests
-	ingredientModel := &model.InventoryResponse{
-		ID:              ingredientEntity.ID,
-		Name:            ingredientEntity.Name,
-		Quantity:        ingredientEntity.Quantity,
-		Unit:            ingredientEntity.Unit,
-		MinimumStock:    ingredientEntity.MinimumStock,
-		ReorderPoint:    ingredientEntity.ReorderPoint,
-		UnitPrice:       ingredientEntity.UnitPrice,
-		LastRestockDate: ingredientEntity.LastRestockDate,
-		CreatedAt:       ingredientEntity.CreatedAt,
-		UpdatedAt:       ingredientEntity.UpdatedAt,
-	}
-	if err := u.cache.Set(context.Background(), cacheKey, ingredientModel, 5*time.Minute); err != nil {
-		u.logger.Errorf("Error setting cache for ingredient ID %d: %v", id, err)
-	}
-
-	return ingredientModel, nil
+	return database.GetOrLoad(ctx, u.cache, cacheKey, itemLoad(nil), func() (*model.InventoryResponse, error) {
+		ingredientEntity, err := u.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &model.InventoryResponse{
+			ID:              ingredientEntity.ID,
+			Name:            ingredientEntity.Name,
+			Quantity:        ingredientEntity.Quantity,
+			Unit:            ingredientEntity.Unit,
+			MinimumStock:    ingredientEntity.MinimumStock,
+			ReorderPoint:    ingredientEntity.ReorderPoint,
+			UnitPrice:       ingredientEntity.UnitPrice,
+			LastRestockDate: ingredientEntity.LastRestockDate,
+			CreatedAt:       ingredientEntity.CreatedAt,
+			UpdatedAt:       ingredientEntity.UpdatedAt,
+		}, nil
+	})
 }
 
-func (u *inventoryUseCase) GetAll(params *model.InventoryQueryParams) (*model.PaginationResponse[[]model.InventoryResponse], error) {
-	start := time.Now()
-	defer func() {
-		u.logger.Infof("GetAll took %v", time.Since(start))
-	}()
-
-	// Try to get the ingredients from the cache first
-	cacheKey := fmt.Sprintf("inventory:all:page:%d:limit:%d", params.Page, params.Limit)
-	var cachedData model.PaginationResponse[[]model.InventoryResponse]
-	if err := u.cache.Get(context.Background(), cacheKey, &cachedData); err == nil {
-		u.logger.Info("Ingredients fetched from cache")
-		return &cachedData, nil
-	}
-
-	// If not in cache, get from the database
-	result, err := u.repo.GetAll(params)
-	if err != nil {
-		return nil, err
-	}
+func (u *inventoryUseCase) GetAll(ctx context.Context, params *model.InventoryQueryParams) (*model.PaginationResponse[[]model.InventoryResponse], error) {
+	ctx, span := tracer.Start(ctx, "InventoryUseCase.GetAll")
+	defer span.End()
 
-	responses := make([]model.InventoryResponse, len(result.Data))
-	for i, ingredient := range result.Data {
-		responses[i] = model.InventoryResponse{
-			ID:              ingredient.ID,
-			Name:            ingredient.Name,
-			Quantity:        ingredient.Quantity,
-			Unit:            ingredient.Unit,
-			MinimumStock:    ingredient.MinimumStock,
-			ReorderPoint:    ingredient.ReorderPoint,
-			UnitPrice:       ingredient.UnitPrice,
-			LastRestockDate: ingredient.LastRestockDate,
-			CreatedAt:       ingredient.CreatedAt,
-			UpdatedAt:       ingredient.UpdatedAt,
+	cacheKey := fmt.Sprintf("inventory:all:page:%d:limit:%d:search:%s", params.Page, params.Limit, params.Search)
+	return database.GetOrLoad(ctx, u.cache, cacheKey, listLoad(inventoryListTag), func() (*model.PaginationResponse[[]model.InventoryResponse], error) {
+		result, err := u.repo.GetAll(ctx, params)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	paginatedResponse := &model.PaginationResponse[[]model.InventoryResponse]{
-		Data:       responses,
-		Total:      result.Total,
-		Page:       result.Page,
-		TotalPages: result.TotalPages,
-	}
-
-	// Store the ingredients in the cache for future requests
-	if err := u.cache.Set(context.Background(), cacheKey, paginatedResponse, 5*time.Minute); err != nil {
-		u.logger.Errorf("Error setting cache for all ingredients: %v", err)
-	}
+		responses := make([]model.InventoryResponse, len(result.Data))
+		for i, ingredient := range result.Data {
+			responses[i] = model.InventoryResponse{
+				ID:              ingredient.ID,
+				Name:            ingredient.Name,
+				Quantity:        ingredient.Quantity,
+				Unit:            ingredient.Unit,
+				MinimumStock:    ingredient.MinimumStock,
+				ReorderPoint:    ingredient.ReorderPoint,
+				UnitPrice:       ingredient.UnitPrice,
+				LastRestockDate: ingredient.LastRestockDate,
+				CreatedAt:       ingredient.CreatedAt,
+				UpdatedAt:       ingredient.UpdatedAt,
+			}
+		}
 
-	return paginatedResponse, nil
+		paginatedResponse := &model.PaginationResponse[[]model.InventoryResponse]{
+			Data:       responses,
+			Total:      result.Total,
+			Page:       result.Page,
+			TotalPages: result.TotalPages,
+		}
+		return paginatedResponse, nil
+	})
 }
 
-func (u *inventoryUseCase) Update(id uint, request *model.UpdateInventoryRequest) (*model.InventoryResponse, error) {
-	existing, err := u.repo.GetByID(id)
+func (u *inventoryUseCase) Update(ctx context.Context, id uint, request *model.UpdateInventoryRequest) (*model.InventoryResponse, error) {
+	existing, err := u.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -182,21 +162,19 @@ func (u *inventoryUseCase) Update(id uint, request *model.UpdateInventoryRequest
 		existing.UnitPrice = request.UnitPrice
 	}
 
-	if err := u.repo.Update(existing); err != nil {
+	if err := u.repo.Update(ctx, existing); err != nil {
 		return nil, err
 	}
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("inventory:%d", id)
-	if err := u.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := u.cache.Delete(ctx, cacheKey); err != nil {
 		u.logger.Errorf("Error deleting cache for ingredient ID %d: %v", id, err)
 	}
-	if err := u.cache.Delete(context.Background(), "inventory:all:*"); err != nil {
+	// The low stock list is tagged with the other lists
+	if err := u.cache.InvalidateTags(ctx, inventoryListTag); err != nil {
 		u.logger.Errorf("Error deleting cache for all ingredients: %v", err)
 	}
-	if err := u.cache.Delete(context.Background(), "low_stock_ingredients"); err != nil {
-		u.logger.Errorf("Error deleting cache for low stock ingredients: %v", err)
-	}
 
 	return &model.InventoryResponse{
 		ID:              existing.ID,
@@ -212,28 +190,26 @@ func (u *inventoryUseCase) Update(id uint, request *model.UpdateInventoryRequest
 	}, nil
 }
 
-func (u *inventoryUseCase) Delete(id uint) error {
-	if err := u.repo.Delete(id); err != nil {
+func (u *inventoryUseCase) Delete(ctx context.Context, id uint) error {
+	if err := u.repo.Delete(ctx, id); err != nil {
 		return err
 	}
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("inventory:%d", id)
-	if err := u.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := u.cache.Delete(ctx, cacheKey); err != nil {
 		u.logger.Errorf("Error deleting cache for ingredient ID %d: %v", id, err)
 	}
-	if err := u.cache.Delete(context.Background(), "inventory:all:*"); err != nil {
+	// The low stock list is tagged with the other lists
+	if err := u.cache.InvalidateTags(ctx, inventoryListTag); err != nil {
 		u.logger.Errorf("Error deleting cache for all ingredients: %v", err)
 	}
-	if err := u.cache.Delete(context.Background(), "low_stock_ingredients"); err != nil {
-		u.logger.Errorf("Error deleting cache for low stock ingredients: %v", err)
-	}
 
 	return nil
 }
 
-func (u *inventoryUseCase) UpdateStock(id uint, quantity float64) error {
-	ingredient, err := u.repo.GetByID(id)
+func (u *inventoryUseCase) UpdateStock(ctx context.Context, id uint, quantity float64) error {
+	ingredient, err := u.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -242,65 +218,58 @@ func (u *inventoryUseCase) UpdateStock(id uint, quantity float64) error {
 		return errors.New("insufficient stock")
 	}
 
-	if err := u.repo.UpdateStock(id, quantity); err != nil {
+	if err := u.repo.UpdateStock(ctx, id, quantity); err != nil {
 		return err
 	}
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("inventory:%d", id)
-	if err := u.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := u.cache.Delete(ctx, cacheKey); err != nil {
 		u.logger.Errorf("Error deleting cache for ingredient ID %d: %v", id, err)
 	}
-	if err := u.cache.Delete(context.Background(), "inventory:all:*"); err != nil {
+	// The low stock list is tagged with the other lists
+	if err := u.cache.InvalidateTags(ctx, inventoryListTag); err != nil {
 		u.logger.Errorf("Error deleting cache for all ingredients: %v", err)
 	}
-	if err := u.cache.Delete(context.Background(), "low_stock_ingredients"); err != nil {
-		u.logger.Errorf("Error deleting cache for low stock ingredients: %v", err)
-	}
 
 	return nil
 }
 
-func (u *inventoryUseCase) GetLowStockIngredients() ([]model.InventoryResponse, error) {
-	start := time.Now()
-	defer func() {
-		u.logger.Infof("GetLowStockIngredients took %v", time.Since(start))
-	}()
+func (u *inventoryUseCase) GetLowStockIngredients(ctx context.Context) ([]model.InventoryResponse, error) {
+	ctx, span := tracer.Start(ctx, "InventoryUseCase.GetLowStockIngredients")
+	defer span.End()
 
-	// Try to get the ingredients from the cache first
 	cacheKey := "low_stock_ingredients"
-	var ingredients []model.InventoryResponse
-	if err := u.cache.Get(context.Background(), cacheKey, &ingredients); err == nil {
-		u.logger.Info("Low stock ingredients fetched from cache")
-		return ingredients, nil
-	}
-
-	// If not in cache, get from the database
-	ingredientEntities, err := u.repo.GetLowStockIngredients()
-	if err != nil {
-		return nil, err
-	}
+	return database.GetOrLoad(ctx, u.cache, cacheKey, listLoad(inventoryListTag), func() ([]model.InventoryResponse, error) {
+		ingredientEntities, err := u.repo.GetLowStockIngredients(ctx)
+		if err != nil {
+			return nil, err
+		}
 
-	responses := make([]model.InventoryResponse, len(ingredientEntities))
-	for i, ingredient := range ingredientEntities {
-		responses[i] = model.InventoryResponse{
-			ID:              ingredient.ID,
-			Name:            ingredient.Name,
-			Quantity:        ingredient.Quantity,
-			Unit:            ingredient.Unit,
-			MinimumStock:    ingredient.MinimumStock,
-			ReorderPoint:    ingredient.ReorderPoint,
-			UnitPrice:       ingredient.UnitPrice,
-			LastRestockDate: ingredient.LastRestockDate,
-			CreatedAt:       ingredient.CreatedAt,
-			UpdatedAt:       ingredient.UpdatedAt,
+		responses := make([]model.InventoryResponse, len(ingredientEntities))
+		for i, ingredient := range ingredientEntities {
+			responses[i] = model.InventoryResponse{
+				ID:              ingredient.ID,
+				Name:            ingredient.Name,
+				Quantity:        ingredient.Quantity,
+				Unit:            ingredient.Unit,
+				MinimumStock:    ingredient.MinimumStock,
+				ReorderPoint:    ingredient.ReorderPoint,
+				UnitPrice:       ingredient.UnitPrice,
+				LastRestockDate: ingredient.LastRestockDate,
+				CreatedAt:       ingredient.CreatedAt,
+				UpdatedAt:       ingredient.UpdatedAt,
+			}
 		}
-	}
+		return responses, nil
+	})
+}
 
-	// Store the ingredients in the cache for future requests
-	if err := u.cache.Set(context.Background(), cacheKey, responses, 5*time.Minute); err != nil {
-		u.logger.Errorf("Error setting cache for low stock ingredients: %v", err)
+func (u *inventoryUseCase) RecordLowStock(ctx context.Context) error {
+	ingredients, err := u.repo.GetLowStockIngredients(ctx)
+	if err != nil {
+		return err
 	}
-
-	return responses, nil
+	u.metrics.LowStockIngredients(len(ingredients))
+	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
 	"errors"
 	"testing"
 
@@ -16,12 +18,12 @@ type MockInventoryRepository struct {
 	mock.Mock
 }
 
-func (m *MockInventoryRepository) Create(ingredient *entity.Inventory) error {
+func (m *MockInventoryRepository) Create(ctx context.Context, ingredient *entity.Inventory) error {
 	args := m.Called(ingredient)
 	return args.Error(0)
 }
 
-func (m *MockInventoryRepository) GetByID(id uint) (*entity.Inventory, error) {
+func (m *MockInventoryRepository) GetByID(ctx context.Context, id uint) (*entity.Inventory, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -29,7 +31,7 @@ func (m *MockInventoryRepository) GetByID(id uint) (*entity.Inventory, error) {
 	return args.Get(0).(*entity.Inventory), args.Error(1)
 }
 
-func (m *MockInventoryRepository) GetAll(params *model.InventoryQueryParams) (*model.PaginationResponse[[]entity.Inventory], error) {
+func (m *MockInventoryRepository) GetAll(ctx context.Context, params *model.InventoryQueryParams) (*model.PaginationResponse[[]entity.Inventory], error) {
 	args := m.Called(params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -37,22 +39,22 @@ func (m *MockInventoryRepository) GetAll(params *model.InventoryQueryParams) (*m
 	return args.Get(0).(*model.PaginationResponse[[]entity.Inventory]), args.Error(1)
 }
 
-func (m *MockInventoryRepository) Update(ingredient *entity.Inventory) error {
+func (m *MockInventoryRepository) Update(ctx context.Context, ingredient *entity.Inventory) error {
 	args := m.Called(ingredient)
 	return args.Error(0)
 }
 
-func (m *MockInventoryRepository) Delete(id uint) error {
+func (m *MockInventoryRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockInventoryRepository) UpdateStock(id uint, quantity float64) error {
+func (m *MockInventoryRepository) UpdateStock(ctx context.Context, id uint, quantity float64) error {
 	args := m.Called(id, quantity)
 	return args.Error(0)
 }
 
-func (m *MockInventoryRepository) GetLowStockIngredients() ([]entity.Inventory, error) {
+func (m *MockInventoryRepository) GetLowStockIngredients(ctx context.Context) ([]entity.Inventory, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -60,7 +62,7 @@ func (m *MockInventoryRepository) GetLowStockIngredients() ([]entity.Inventory,
 	return args.Get(0).([]entity.Inventory), args.Error(1)
 }
 
-func (m *MockInventoryRepository) Count() (int64, error) {
+func (m *MockInventoryRepository) Count(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
@@ -69,7 +71,7 @@ func TestInventoryUseCase_GetByID(t *testing.T) {
 	logger := logrus.New()
 	mockInventoryRepo := new(MockInventoryRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewInventoryUseCase(mockInventoryRepo, logger, mockCache)
+	useCase := NewInventoryUseCase(mockInventoryRepo, logger, mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedInventory := &entity.Inventory{
@@ -83,7 +85,7 @@ func TestInventoryUseCase_GetByID(t *testing.T) {
 		mockInventoryRepo.On("GetByID", uint(1)).Return(expectedInventory, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		inventory, err := useCase.GetByID(1)
+		inventory, err := useCase.GetByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, inventory)
@@ -95,7 +97,7 @@ func TestInventoryUseCase_GetByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockInventoryRepo.On("GetByID", uint(1)).Return(nil, errors.New("not found")).Once()
 
-		inventory, err := useCase.GetByID(1)
+		inventory, err := useCase.GetByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, inventory)
@@ -107,7 +109,7 @@ func TestInventoryUseCase_GetAll(t *testing.T) {
 	logger := logrus.New()
 	mockInventoryRepo := new(MockInventoryRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewInventoryUseCase(mockInventoryRepo, logger, mockCache)
+	useCase := NewInventoryUseCase(mockInventoryRepo, logger, mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedResponse := &model.PaginationResponse[[]entity.Inventory]{
@@ -126,9 +128,9 @@ func TestInventoryUseCase_GetAll(t *testing.T) {
 		params := &model.InventoryQueryParams{Page: 1, Limit: 10}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockInventoryRepo.On("GetAll", params).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "inventory:all:page:1:limit:10:search:", mock.Anything, mock.Anything, []string{inventoryListTag}).Return(nil)
 
-		inventories, err := useCase.GetAll(params)
+		inventories, err := useCase.GetAll(context.Background(), params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, inventories)
@@ -141,7 +143,7 @@ func TestInventoryUseCase_GetAll(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockInventoryRepo.On("GetAll", params).Return(nil, errors.New("error")).Once()
 
-		inventories, err := useCase.GetAll(params)
+		inventories, err := useCase.GetAll(context.Background(), params)
 
 		assert.Error(t, err)
 		assert.Nil(t, inventories)
@@ -153,7 +155,7 @@ func TestInventoryUseCase_GetLowStockIngredients(t *testing.T) {
 	logger := logrus.New()
 	mockInventoryRepo := new(MockInventoryRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewInventoryUseCase(mockInventoryRepo, logger, mockCache)
+	useCase := NewInventoryUseCase(mockInventoryRepo, logger, mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedIngredients := []entity.Inventory{
@@ -167,9 +169,9 @@ func TestInventoryUseCase_GetLowStockIngredients(t *testing.T) {
 		}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockInventoryRepo.On("GetLowStockIngredients").Return(expectedIngredients, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "low_stock_ingredients", mock.Anything, mock.Anything, []string{inventoryListTag}).Return(nil)
 
-		ingredients, err := useCase.GetLowStockIngredients()
+		ingredients, err := useCase.GetLowStockIngredients(context.Background())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, ingredients)
@@ -181,7 +183,7 @@ func TestInventoryUseCase_GetLowStockIngredients(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockInventoryRepo.On("GetLowStockIngredients").Return(nil, errors.New("error")).Once()
 
-		ingredients, err := useCase.GetLowStockIngredients()
+		ingredients, err := useCase.GetLowStockIngredients(context.Background())
 
 		assert.Error(t, err)
 		assert.Nil(t, ingredients)
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LoginPolicy sets how failed logins are throttled. Failures are counted per
+// email and per IP address within Window. From the DelayAfter-th failure on,
+// the email has to wait BaseDelay, doubling with every further failure up to
+// MaxDelay. MaxAttempts failures lock the email for LockoutDuration, and
+// IPMaxAttempts failures from one address lock that address.
+type LoginPolicy struct {
+	MaxAttempts     int
+	IPMaxAttempts   int
+	Window          time.Duration
+	LockoutDuration time.Duration
+	DelayAfter      int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+}
+
+// LoginBlockedError is returned while an email or address may not try to log
+// in. It wraps constants.ErrLoginLocked or constants.ErrLoginThrottled.
+type LoginBlockedError struct {
+	Reason     error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Reason.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Reason
+}
+
+// LoginAttemptUseCase keeps the failed login counters in Redis. When Redis is
+// unreachable logins are let through, so an outage never locks everyone out.
+type LoginAttemptUseCase interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string)
+	RecordSuccess(ctx context.Context, email string)
+	Unlock(ctx context.Context, request *model.UnlockLoginRequest) error
+}
+
+type loginAttemptUseCase struct {
+	cache  database.RedisCache
+	log    *logrus.Logger
+	policy LoginPolicy
+	now    func() time.Time
+}
+
+func NewLoginAttemptUseCase(cache database.RedisCache, log *logrus.Logger, policy LoginPolicy) LoginAttemptUseCase {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.IPMaxAttempts <= 0 {
+		policy.IPMaxAttempts = 50
+	}
+	if policy.Window <= 0 {
+		policy.Window = 15 * time.Minute
+	}
+	if policy.LockoutDuration <= 0 {
+		policy.LockoutDuration = 15 * time.Minute
+	}
+	if policy.DelayAfter <= 0 {
+		policy.DelayAfter = 3
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = time.Second
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = 30 * time.Second
+	}
+	return &loginAttemptUseCase{cache: cache, log: log, policy: policy, now: time.Now}
+}
+
+func (uc *loginAttemptUseCase) Check(ctx context.Context, email, ip string) error {
+	email = normalizeLoginEmail(email)
+
+	if retryAfter, blocked := uc.blockedFor(ctx, lockKey("email", email)); blocked {
+		metrics.LoginBlocked.WithLabelValues("locked").Inc()
+		return &LoginBlockedError{Reason: constants.ErrLoginLocked, RetryAfter: retryAfter}
+	}
+	if retryAfter, blocked := uc.blockedFor(ctx, lockKey("ip", ip)); blocked {
+		metrics.LoginBlocked.WithLabelValues("locked").Inc()
+		return &LoginBlockedError{Reason: constants.ErrLoginLocked, RetryAfter: retryAfter}
+	}
+	if retryAfter, blocked := uc.blockedFor(ctx, delayKey(email)); blocked {
+		metrics.LoginBlocked.WithLabelValues("throttled").Inc()
+		return &LoginBlockedError{Reason: constants.ErrLoginThrottled, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (uc *loginAttemptUseCase) RecordFailure(ctx context.Context, email, ip string) {
+	email = normalizeLoginEmail(email)
+	metrics.LoginFailures.Inc()
+
+	failures, err := uc.cache.Increment(ctx, failureKey("email", email), uc.policy.Window)
+	if err != nil {
+		uc.log.Errorf("Error counting failed login for %s: %v", email, err)
+	} else if failures >= int64(uc.policy.MaxAttempts) {
+		uc.lock(ctx, "email", email, failures)
+	} else if failures >= int64(uc.policy.DelayAfter) {
+		uc.block(ctx, delayKey(email), uc.delay(failures))
+	}
+
+	failures, err = uc.cache.Increment(ctx, failureKey("ip", ip), uc.policy.Window)
+	if err != nil {
+		uc.log.Errorf("Error counting failed login from %s: %v", ip, err)
+	} else if failures >= int64(uc.policy.IPMaxAttempts) {
+		uc.lock(ctx, "ip", ip, failures)
+	}
+}
+
+// RecordSuccess clears the email's failures. The address keeps its count, or
+// an attacker holding one valid account could reset it at will.
+func (uc *loginAttemptUseCase) RecordSuccess(ctx context.Context, email string) {
+	email = normalizeLoginEmail(email)
+	uc.clear(ctx, failureKey("email", email), delayKey(email))
+}
+
+func (uc *loginAttemptUseCase) Unlock(ctx context.Context, request *model.UnlockLoginRequest) error {
+	if request.Email != "" {
+		email := normalizeLoginEmail(request.Email)
+		if err := uc.clear(ctx, lockKey("email", email), failureKey("email", email), delayKey(email)); err != nil {
+			return err
+		}
+		uc.log.Infof("Login unlocked for %s", email)
+	}
+	if request.IP != "" {
+		if err := uc.clear(ctx, lockKey("ip", request.IP), failureKey("ip", request.IP)); err != nil {
+			return err
+		}
+		uc.log.Infof("Login unlocked for address %s", request.IP)
+	}
+	return nil
+}
+
+// delay doubles with every failure past DelayAfter
+func (uc *loginAttemptUseCase) delay(failures int64) time.Duration {
+	delay := uc.policy.BaseDelay
+	for i := int64(uc.policy.DelayAfter); i < failures && delay < uc.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > uc.policy.MaxDelay {
+		delay = uc.policy.MaxDelay
+	}
+	return delay
+}
+
+func (uc *loginAttemptUseCase) lock(ctx context.Context, scope, value string, failures int64) {
+	uc.block(ctx, lockKey(scope, value), uc.policy.LockoutDuration)
+	// Start counting afresh once the lockout ends
+	uc.clear(ctx, failureKey(scope, value))
+
+	metrics.LoginLockouts.WithLabelValues(scope).Inc()
+	uc.log.Warnf("Login locked for %s %s after %d failed attempts, for %v", scope, value, failures, uc.policy.LockoutDuration)
+}
+
+// block stores when the block ends, so Check can answer with Retry-After
+func (uc *loginAttemptUseCase) block(ctx context.Context, key string, duration time.Duration) {
+	until := uc.now().Add(duration).Unix()
+	if err := uc.cache.Set(ctx, key, until, duration); err != nil {
+		uc.log.Errorf("Error setting %s: %v", key, err)
+	}
+}
+
+func (uc *loginAttemptUseCase) blockedFor(ctx context.Context, key string) (time.Duration, bool) {
+	var until int64
+	if err := uc.cache.Get(ctx, key, &until); err != nil {
+		return 0, false
+	}
+	retryAfter := time.Unix(until, 0).Sub(uc.now())
+	if retryAfter <= 0 {
+		return 0, false
+	}
+	return retryAfter, true
+}
+
+func (uc *loginAttemptUseCase) clear(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if err := uc.cache.Delete(ctx, key); err != nil {
+			uc.log.Errorf("Error deleting %s: %v", key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failureKey(scope, value string) string {
+	return fmt.Sprintf("auth:login:failures:%s:%s", scope, value)
+}
+
+func lockKey(scope, value string) string {
+	return fmt.Sprintf("auth:login:locked:%s:%s", scope, value)
+}
+
+func delayKey(email string) string {
+	return fmt.Sprintf("auth:login:delay:email:%s", email)
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockLoginAttemptUseCase struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptUseCase) Check(ctx context.Context, email, ip string) error {
+	args := m.Called(email, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptUseCase) RecordFailure(ctx context.Context, email, ip string) {
+	m.Called(email, ip)
+}
+
+func (m *MockLoginAttemptUseCase) RecordSuccess(ctx context.Context, email string) {
+	m.Called(email)
+}
+
+func (m *MockLoginAttemptUseCase) Unlock(ctx context.Context, request *model.UnlockLoginRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+func TestLoginAttemptUseCase_Check(t *testing.T) {
+	logger := logrus.New()
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+
+	t.Run("locked email", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewLoginAttemptUseCase(mockCache, logger, LoginPolicy{}).(*loginAttemptUseCase)
+		useCase.now = func() time.Time { return now }
+
+		mockCache.On("Get", mock.Anything, "auth:login:locked:email:a@example.com", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*int64) = now.Add(10 * time.Minute).Unix()
+		}).Return(nil).Once()
+
+		err := useCase.Check(context.Background(), " A@example.com", "10.0.0.1")
+
+		var blocked *LoginBlockedError
+		assert.ErrorAs(t, err, &blocked)
+		assert.ErrorIs(t, err, constants.ErrLoginLocked)
+		assert.Equal(t, 10*time.Minute, blocked.RetryAfter)
+	})
+
+	t.Run("nothing stored", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewLoginAttemptUseCase(mockCache, logger, LoginPolicy{})
+
+		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("key not found"))
+
+		assert.NoError(t, useCase.Check(context.Background(), "a@example.com", "10.0.0.1"))
+	})
+}
+
+func TestLoginAttemptUseCase_RecordFailure(t *testing.T) {
+	logger := logrus.New()
+	now := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+
+	t.Run("progressive delay", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewLoginAttemptUseCase(mockCache, logger, LoginPolicy{}).(*loginAttemptUseCase)
+		useCase.now = func() time.Time { return now }
+
+		// Fourth failure: one past DelayAfter, so the base delay doubles once
+		mockCache.On("Increment", mock.Anything, "auth:login:failures:email:a@example.com", 15*time.Minute).Return(int64(4), nil).Once()
+		mockCache.On("Set", mock.Anything, "auth:login:delay:email:a@example.com", now.Add(2*time.Second).Unix(), 2*time.Second).Return(nil).Once()
+		mockCache.On("Increment", mock.Anything, "auth:login:failures:ip:10.0.0.1", 15*time.Minute).Return(int64(4), nil).Once()
+
+		useCase.RecordFailure(context.Background(), "a@example.com", "10.0.0.1")
+
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("locks after max attempts", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewLoginAttemptUseCase(mockCache, logger, LoginPolicy{MaxAttempts: 5, LockoutDuration: time.Hour})
+
+		mockCache.On("Increment", mock.Anything, "auth:login:failures:email:a@example.com", mock.Anything).Return(int64(5), nil).Once()
+		mockCache.On("Set", mock.Anything, "auth:login:locked:email:a@example.com", mock.Anything, time.Hour).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "auth:login:failures:email:a@example.com").Return(nil).Once()
+		mockCache.On("Increment", mock.Anything, "auth:login:failures:ip:10.0.0.1", mock.Anything).Return(int64(5), nil).Once()
+
+		useCase.RecordFailure(context.Background(), "a@example.com", "10.0.0.1")
+
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("redis unavailable", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewLoginAttemptUseCase(mockCache, logger, LoginPolicy{})
+
+		mockCache.On("Increment", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("connection refused"))
+
+		useCase.RecordFailure(context.Background(), "a@example.com", "10.0.0.1")
+
+		mockCache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginAttemptUseCase_Unlock(t *testing.T) {
+	logger := logrus.New()
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewLoginAttemptUseCase(mockCache, logger, LoginPolicy{})
+
+	mockCache.On("Delete", mock.Anything, "auth:login:locked:email:a@example.com").Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, "auth:login:failures:email:a@example.com").Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, "auth:login:delay:email:a@example.com").Return(nil).Once()
+
+	err := useCase.Unlock(context.Background(), &model.UnlockLoginRequest{Email: "A@example.com"})
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+}
+
+func TestCustomerUseCase_Login(t *testing.T) {
+	logger := logrus.New()
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	t.Run("wrong password counts as a failure", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockAttempts := new(MockLoginAttemptUseCase)
+		useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, mockAttempts, nil, nil, nil)
+
+		mockAttempts.On("Check", "a@example.com", "10.0.0.1").Return(nil).Once()
+		mockCustomerRepo.On("GetByEmail", "a@example.com").Return(&entity.Customer{ID: 7, Password: string(hashed)}, nil).Once()
+		mockAttempts.On("RecordFailure", "a@example.com", "10.0.0.1").Once()
+
+		result, err := useCase.Login(context.Background(), &model.LoginRequest{Email: "a@example.com", Password: "wrong"}, "10.0.0.1")
+
+		assert.ErrorIs(t, err, constants.ErrInvalidCredentials)
+		assert.Nil(t, result)
+		mockAttempts.AssertExpectations(t)
+	})
+
+	t.Run("blocked before the password is checked", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockAttempts := new(MockLoginAttemptUseCase)
+		useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, mockAttempts, nil, nil, nil)
+
+		mockAttempts.On("Check", "a@example.com", "10.0.0.1").Return(&LoginBlockedError{Reason: constants.ErrLoginLocked, RetryAfter: time.Minute}).Once()
+
+		_, err := useCase.Login(context.Background(), &model.LoginRequest{Email: "a@example.com", Password: "password"}, "10.0.0.1")
+
+		assert.ErrorIs(t, err, constants.ErrLoginLocked)
+		mockCustomerRepo.AssertNotCalled(t, "GetByEmail", mock.Anything)
+	})
+
+	t.Run("success clears failures", func(t *testing.T) {
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockAttempts := new(MockLoginAttemptUseCase)
+		mockSessions := new(MockSessionUseCase)
+		mockTwoFactor := new(MockTwoFactorUseCase)
+		useCase := NewCustomerUseCase(mockCustomerRepo, logger, mockSessions, nil, mockAttempts, mockTwoFactor, nil, nil)
+
+		customer := &entity.Customer{ID: 7, Password: string(hashed)}
+		mockAttempts.On("Check", "a@example.com", "10.0.0.1").Return(nil).Once()
+		mockCustomerRepo.On("GetByEmail", "a@example.com").Return(customer, nil).Once()
+		mockTwoFactor.On("Challenge", customer).Return(nil, nil).Once()
+		mockAttempts.On("RecordSuccess", "a@example.com").Once()
+		mockSessions.On("Issue", customer).Return(&model.TokenResponse{AccessToken: "access"}, nil).Once()
+
+		result, err := useCase.Login(context.Background(), &model.LoginRequest{Email: "a@example.com", Password: "password"}, "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Nil(t, result.Challenge)
+		assert.Equal(t, "access", result.Tokens.AccessToken)
+		mockAttempts.AssertExpectations(t)
+	})
+}
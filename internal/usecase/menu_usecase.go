@@ -16,11 +16,11 @@ import (
 )
 
 type MenuUseCase interface {
-	GetAllMenus(params *model.MenuQueryParams) (*model.PaginationResponse[[]entity.Menu], error)
-	GetMenuByID(id int64) (*entity.Menu, error)
-	CreateMenu(menu *entity.Menu) error
-	UpdateMenu(menu *entity.Menu) error
-	SoftDeleteMenu(id int64) error
+	GetAllMenus(ctx context.Context, params *model.MenuQueryParams) (*model.PaginationResponse[[]entity.Menu], error)
+	GetMenuByID(ctx context.Context, id int64) (*entity.Menu, error)
+	CreateMenu(ctx context.Context, menu *entity.Menu) error
+	UpdateMenu(ctx context.Context, menu *entity.Menu) error
+	SoftDeleteMenu(ctx context.Context, id int64) error
 }
 
 type menuUseCase struct {
@@ -39,55 +39,39 @@ func NewMenuUseCase(repo repository.MenuRepository, logger *logrus.Logger, cache
 	}
 }
 
-func (uc *menuUseCase) GetAllMenus(params *model.MenuQueryParams) (*model.PaginationResponse[[]entity.Menu], error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetAllMenus took %v", time.Since(start))
-	}()
+func (uc *menuUseCase) GetAllMenus(ctx context.Context, params *model.MenuQueryParams) (*model.PaginationResponse[[]entity.Menu], error) {
+	ctx, span := tracer.Start(ctx, "MenuUseCase.GetAllMenus")
+	defer span.End()
 
 	if params == nil {
 		params = &model.MenuQueryParams{}
 	}
 
-	// Try to get the menus from the cache first
-	cacheKey := fmt.Sprintf("menus:all:page:%d:limit:%d", params.Page, params.Limit)
-	var cachedData model.PaginationResponse[[]entity.Menu]
-	if err := uc.cache.Get(context.Background(), cacheKey, &cachedData); err == nil {
-		uc.logger.Info("Menus fetched from cache")
-		return &cachedData, nil
-	}
-
-	// If not in cache, get from the database
-	response, err := uc.repo.GetAll(params)
+	cacheKey := fmt.Sprintf("menus:all:page:%d:limit:%d:title:%s:price:%g-%g:category:%s",
+		params.Page, params.Limit, params.Title, params.MinPrice, params.MaxPrice, params.Category)
+	opts := listLoad(menuListTag)
+	// The public menu is the hottest list, so an expired page is still served
+	// for a minute while it reloads in the background
+	opts.StaleFor = time.Minute
+	response, err := database.GetOrLoad(ctx, uc.cache, cacheKey, opts, func() (*model.PaginationResponse[[]entity.Menu], error) {
+		return uc.repo.GetAll(ctx, params)
+	})
 	if err != nil {
 		uc.logger.Errorf("Error fetching menus with params: %v, error: %v", params, err)
 		return nil, err
 	}
 
-	// Store the menus in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, response, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for all menus: %v", err)
-	}
-
 	return response, nil
 }
 
-func (uc *menuUseCase) GetMenuByID(id int64) (*entity.Menu, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetMenuByID took %v", time.Since(start))
-	}()
+func (uc *menuUseCase) GetMenuByID(ctx context.Context, id int64) (*entity.Menu, error) {
+	ctx, span := tracer.Start(ctx, "MenuUseCase.GetMenuByID")
+	defer span.End()
 
-	// Try to get the menu from the cache first
 	cacheKey := fmt.Sprintf("menu:%d", id)
-	var menu entity.Menu
-	if err := uc.cache.Get(context.Background(), cacheKey, &menu); err == nil {
-		uc.logger.Info("Menu fetched from cache")
-		return &menu, nil
-	}
-
-	// If not in cache, get from the database
-	menuEntity, err := uc.repo.GetByID(id)
+	menuEntity, err := database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(constants.ErrNotFound), func() (*entity.Menu, error) {
+		return uc.repo.GetByID(ctx, id)
+	})
 	if err != nil {
 		if errors.Is(err, constants.ErrNotFound) {
 			return nil, constants.ErrNotFound
@@ -96,36 +80,38 @@ func (uc *menuUseCase) GetMenuByID(id int64) (*entity.Menu, error) {
 		return nil, err
 	}
 
-	// Store the menu in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, menuEntity, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for menu ID %d: %v", id, err)
-	}
-
 	uc.logger.Infof("Successfully fetched menu with ID %d", id)
 	return menuEntity, nil
 }
 
-func (uc *menuUseCase) CreateMenu(menu *entity.Menu) error {
+func (uc *menuUseCase) CreateMenu(ctx context.Context, menu *entity.Menu) error {
 	if err := uc.validate.Struct(menu); err != nil {
 		uc.logger.Errorf("Validation failed for menu: %v", err)
 		return err
 	}
 
-	if err := uc.repo.Create(menu); err != nil {
+	if err := uc.repo.Create(ctx, menu); err != nil {
 		uc.logger.Errorf("Error creating menu: %v", err)
 		return err
 	}
+	// A lookup of the new ID may have been cached as not found
+	if err := uc.cache.Delete(ctx, fmt.Sprintf("menu:%d", menu.ID)); err != nil {
+		uc.logger.Errorf("Error deleting cache for menu ID %d: %v", menu.ID, err)
+	}
+	if err := uc.cache.InvalidateTags(ctx, menuListTag); err != nil {
+		uc.logger.Errorf("Error deleting cache for all menus: %v", err)
+	}
 	uc.logger.Infof("Successfully created a new menu: %s", menu.Title)
 	return nil
 }
 
-func (uc *menuUseCase) UpdateMenu(menu *entity.Menu) error {
+func (uc *menuUseCase) UpdateMenu(ctx context.Context, menu *entity.Menu) error {
 	if err := uc.validate.Struct(menu); err != nil {
 		uc.logger.Errorf("Validation failed for menu: %v", err)
 		return err
 	}
 
-	if err := uc.repo.UpdateMenu(menu); err != nil {
+	if err := uc.repo.UpdateMenu(ctx, menu); err != nil {
 		if errors.Is(err, constants.ErrNotFound) {
 			return constants.ErrNotFound
 		}
@@ -135,10 +121,10 @@ func (uc *menuUseCase) UpdateMenu(menu *entity.Menu) error {
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("menu:%d", menu.ID)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := uc.cache.Delete(ctx, cacheKey); err != nil {
 		uc.logger.Errorf("Error deleting cache for menu ID %d: %v", menu.ID, err)
 	}
-	if err := uc.cache.Delete(context.Background(), "menus:all:*"); err != nil {
+	if err := uc.cache.InvalidateTags(ctx, menuListTag); err != nil {
 		uc.logger.Errorf("Error deleting cache for all menus: %v", err)
 	}
 
@@ -146,18 +132,18 @@ func (uc *menuUseCase) UpdateMenu(menu *entity.Menu) error {
 	return nil
 }
 
-func (uc *menuUseCase) SoftDeleteMenu(id int64) error {
-	if err := uc.repo.SoftDelete(id); err != nil {
+func (uc *menuUseCase) SoftDeleteMenu(ctx context.Context, id int64) error {
+	if err := uc.repo.SoftDelete(ctx, id); err != nil {
 		uc.logger.Errorf("Error deleting menu with ID %d: %v", id, err)
 		return err
 	}
 
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("menu:%d", id)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
+	if err := uc.cache.Delete(ctx, cacheKey); err != nil {
 		uc.logger.Errorf("Error deleting cache for menu ID %d: %v", id, err)
 	}
-	if err := uc.cache.Delete(context.Background(), "menus:all:*"); err != nil {
+	if err := uc.cache.InvalidateTags(ctx, menuListTag); err != nil {
 		uc.logger.Errorf("Error deleting cache for all menus: %v", err)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
 	"errors"
 	"testing"
 
@@ -16,7 +17,7 @@ type MockMenuRepository struct {
 	mock.Mock
 }
 
-func (m *MockMenuRepository) GetAll(params *model.MenuQueryParams) (*model.PaginationResponse[[]entity.Menu], error) {
+func (m *MockMenuRepository) GetAll(ctx context.Context, params *model.MenuQueryParams) (*model.PaginationResponse[[]entity.Menu], error) {
 	args := m.Called(params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -24,7 +25,7 @@ func (m *MockMenuRepository) GetAll(params *model.MenuQueryParams) (*model.Pagin
 	return args.Get(0).(*model.PaginationResponse[[]entity.Menu]), args.Error(1)
 }
 
-func (m *MockMenuRepository) GetByID(id int64) (*entity.Menu, error) {
+func (m *MockMenuRepository) GetByID(ctx context.Context, id int64) (*entity.Menu, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -32,17 +33,17 @@ func (m *MockMenuRepository) GetByID(id int64) (*entity.Menu, error) {
 	return args.Get(0).(*entity.Menu), args.Error(1)
 }
 
-func (m *MockMenuRepository) Create(menu *entity.Menu) error {
+func (m *MockMenuRepository) Create(ctx context.Context, menu *entity.Menu) error {
 	args := m.Called(menu)
 	return args.Error(0)
 }
 
-func (m *MockMenuRepository) UpdateMenu(menu *entity.Menu) error {
+func (m *MockMenuRepository) UpdateMenu(ctx context.Context, menu *entity.Menu) error {
 	args := m.Called(menu)
 	return args.Error(0)
 }
 
-func (m *MockMenuRepository) SoftDelete(id int64) error {
+func (m *MockMenuRepository) SoftDelete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
@@ -67,9 +68,9 @@ func TestMenuUseCase_GetAllMenus(t *testing.T) {
 		params := &model.MenuQueryParams{Page: 1, Limit: 10}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockMenuRepo.On("GetAll", params).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "menus:all:page:1:limit:10:title::price:0-0:category:", mock.Anything, mock.Anything, []string{menuListTag}).Return(nil)
 
-		menus, err := useCase.GetAllMenus(params)
+		menus, err := useCase.GetAllMenus(context.Background(), params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, menus)
@@ -82,7 +83,7 @@ func TestMenuUseCase_GetAllMenus(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockMenuRepo.On("GetAll", params).Return(nil, errors.New("error")).Once()
 
-		menus, err := useCase.GetAllMenus(params)
+		menus, err := useCase.GetAllMenus(context.Background(), params)
 
 		assert.Error(t, err)
 		assert.Nil(t, menus)
@@ -105,7 +106,7 @@ func TestMenuUseCase_GetMenuByID(t *testing.T) {
 		mockMenuRepo.On("GetByID", int64(1)).Return(expectedMenu, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		menu, err := useCase.GetMenuByID(1)
+		menu, err := useCase.GetMenuByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, menu)
@@ -117,7 +118,7 @@ func TestMenuUseCase_GetMenuByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockMenuRepo.On("GetByID", int64(1)).Return(nil, errors.New("not found")).Once()
 
-		menu, err := useCase.GetMenuByID(1)
+		menu, err := useCase.GetMenuByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, menu)
@@ -1,9 +1,11 @@
 package usecase
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
 	"cakestore/internal/repository"
 	"context"
 	"errors"
@@ -15,14 +17,21 @@ import (
 )
 
 type OrderUseCase interface {
-	CreateOrder(customerID int64, request *model.CreateOrderRequest) (*entity.Order, error)
-	GetOrderByID(id int64) (*model.OrderResponse, error)
-	GetPendingOrder(customerID int64, orderID int64) (*model.OrderResponse, error)
-	GetAllOrders(params *model.PaginationQuery) (*[]model.OrderResponse, *model.PaginatedMeta, error)
-	GetCustomerOrders(customerID int64) ([]model.OrderResponse, error)
-	UpdateOrderStatus(id string, status string) error
-	DeleteOrder(id int64) error
-	UpdateFoodStatus(orderID int64, foodStatus entity.FoodStatus) error
+	CreateOrder(ctx context.Context, customerID int64, request *model.CreateOrderRequest) (*entity.Order, error)
+	CreateTableOrder(ctx context.Context, session *entity.TableSession, request *model.CreateOrderRequest) (*entity.Order, error)
+	GetTableSessionOrders(ctx context.Context, sessionID int64) ([]model.OrderResponse, error)
+	GetOrderByID(ctx context.Context, id int64) (*model.OrderResponse, error)
+	GetPendingOrder(ctx context.Context, customerID int64, orderID int64) (*model.OrderResponse, error)
+	GetAllOrders(ctx context.Context, params *model.PaginationQuery) (*[]model.OrderResponse, *model.PaginatedMeta, error)
+	GetCustomerOrders(ctx context.Context, customerID int64) ([]model.OrderResponse, error)
+	UpdateOrderStatus(ctx context.Context, id string, status string) error
+	DeleteOrder(ctx context.Context, id int64) error
+	UpdateFoodStatus(ctx context.Context, orderID int64, foodStatus entity.FoodStatus) error
+	// ExpirePending cancels unpaid orders created before the cutoff and
+	// returns how many were cancelled.
+	ExpirePending(ctx context.Context, before time.Time) (int, error)
+	// RecordKitchenQueue refreshes the kitchen queue metric.
+	RecordKitchenQueue(ctx context.Context) error
 }
 
 type orderUseCaseImpl struct {
@@ -32,6 +41,7 @@ type orderUseCaseImpl struct {
 	logger       *logrus.Logger
 	env          string
 	cache        database.RedisCache
+	metrics      metrics.Business
 }
 
 func NewOrderUseCase(
@@ -41,6 +51,7 @@ func NewOrderUseCase(
 	logger *logrus.Logger,
 	env string,
 	cache database.RedisCache,
+	recorder metrics.Business,
 ) OrderUseCase {
 	return &orderUseCaseImpl{
 		orderRepo:    orderRepo,
@@ -49,79 +60,78 @@ func NewOrderUseCase(
 		logger:       logger,
 		env:          env,
 		cache:        cache,
+		metrics:      recorder,
 	}
 }
 
-func (uc *orderUseCaseImpl) UpdateFoodStatus(orderID int64, foodStatus entity.FoodStatus) error {
-	if err := uc.orderRepo.UpdateFoodStatus(orderID, foodStatus); err != nil {
+func (uc *orderUseCaseImpl) UpdateFoodStatus(ctx context.Context, orderID int64, foodStatus entity.FoodStatus) error {
+	if err := uc.orderRepo.UpdateFoodStatus(ctx, orderID, foodStatus); err != nil {
 		return err
 	}
 
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("order:%d", orderID)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
-		uc.logger.Errorf("Error deleting cache for order ID %d: %v", orderID, err)
-	}
-	if err := uc.cache.Delete(context.Background(), "orders:all:*"); err != nil {
-		uc.logger.Errorf("Error deleting cache for all orders: %v", err)
-	}
+	invalidateOrderViews(ctx, uc.cache, uc.logger, orderID)
 
 	return nil
 }
 
-func (uc *orderUseCaseImpl) GetPendingOrder(customerID int64, orderID int64) (*model.OrderResponse, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetPendingOrder took %v", time.Since(start))
-	}()
+func (uc *orderUseCaseImpl) ExpirePending(ctx context.Context, before time.Time) (int, error) {
+	ids, err := uc.orderRepo.ExpirePending(ctx, before)
+	if err != nil {
+		return 0, err
+	}
 
-	// Try to get the order from the cache first
-	cacheKey := fmt.Sprintf("order:pending:%d:%d", customerID, orderID)
-	var order model.OrderResponse
-	if err := uc.cache.Get(context.Background(), cacheKey, &order); err == nil {
-		uc.logger.Info("Pending order fetched from cache")
-		return &order, nil
+	for _, id := range ids {
+		invalidateOrderCache(ctx, uc.cache, uc.logger, &entity.Order{ID: id})
+		// Only online orders expire, and their items are not loaded here
+		uc.metrics.OrderCancelled(metrics.Order{Channel: metrics.ChannelOnline})
+	}
+	if len(ids) > 0 {
+		uc.logger.Infof("Cancelled %d unpaid orders created before %s", len(ids), before.Format(time.RFC3339))
 	}
+	return len(ids), nil
+}
 
-	// If not in cache, get from the database
-	orderEntity, err := uc.orderRepo.GetPendingPaymentByOrderID(customerID, orderID)
+func (uc *orderUseCaseImpl) RecordKitchenQueue(ctx context.Context) error {
+	counts, err := uc.orderRepo.CountKitchenQueue(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	response := model.ToOrderResponse(&orderEntity)
 
-	// Store the order in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, response, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for pending order: %v", err)
+	depth := make(map[string]int64, len(counts))
+	for status, count := range counts {
+		depth[string(status)] = count
 	}
+	uc.metrics.KitchenQueue(depth)
+	return nil
+}
+
+func (uc *orderUseCaseImpl) GetPendingOrder(ctx context.Context, customerID int64, orderID int64) (*model.OrderResponse, error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.GetPendingOrder")
+	defer span.End()
 
-	return response, nil
+	cacheKey := fmt.Sprintf("order:pending:%d:%d", customerID, orderID)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(nil, orderTag(orderID)), func() (*model.OrderResponse, error) {
+		orderEntity, err := uc.orderRepo.GetPendingPaymentByOrderID(ctx, customerID, orderID)
+		if err != nil {
+			return nil, err
+		}
+		return model.ToOrderResponse(&orderEntity), nil
+	})
 }
 
-func (uc *orderUseCaseImpl) CreateOrder(customerID int64, request *model.CreateOrderRequest) (*entity.Order, error) {
-	customer, err := uc.customerRepo.GetByID(customerID)
+func (uc *orderUseCaseImpl) CreateOrder(ctx context.Context, customerID int64, request *model.CreateOrderRequest) (*entity.Order, error) {
+	customer, err := uc.customerRepo.GetByID(ctx, customerID)
 	if err != nil {
 		return nil, errors.New("customer not found")
 	}
+	if customer.Role == constants.RoleCustomer && !customer.EmailVerifiedAt.Valid {
+		return nil, constants.ErrEmailNotVerified
+	}
 
-	// Create order items and calculate total price
-	var orderItems []entity.OrderItem
-	var totalPrice float64
-
-	for _, item := range request.Items {
-		// Validate menu exists
-		_, err := uc.menuRepo.GetByID(item.MenuID)
-		if err != nil {
-			return nil, errors.New("menu not found")
-		}
-
-		orderItem := entity.OrderItem{
-			MenuID:   item.MenuID,
-			Quantity: item.Quantity,
-			Price:    item.Price,
-		}
-		orderItems = append(orderItems, orderItem)
-		totalPrice += item.Price * float64(item.Quantity)
+	orderItems, totalPrice, categories, err := uc.buildOrderItems(ctx, request)
+	if err != nil {
+		return nil, err
 	}
 
 	order := &entity.Order{
@@ -136,77 +146,131 @@ func (uc *orderUseCaseImpl) CreateOrder(customerID int64, request *model.CreateO
 		UpdatedAt:  time.Now(),
 	}
 
-	if err := uc.orderRepo.Create(order); err != nil {
+	if err := uc.orderRepo.Create(ctx, order); err != nil {
 		uc.logger.Errorf("Error creating order: %v", err)
 		return nil, err
 	}
+	invalidateOrderViews(ctx, uc.cache, uc.logger, order.ID)
+	uc.metrics.OrderCreated(metrics.Order{Channel: metrics.ChannelOnline, Total: totalPrice, Items: categories})
 
 	return order, nil
 }
 
-func (uc *orderUseCaseImpl) GetOrderByID(id int64) (*model.OrderResponse, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetOrderByID took %v", time.Since(start))
-	}()
-
-	// Try to get the order from the cache first
-	cacheKey := fmt.Sprintf("order:%d", id)
-	var order model.OrderResponse
-	if err := uc.cache.Get(context.Background(), cacheKey, &order); err == nil {
-		uc.logger.Info("Order fetched from cache")
-		return &order, nil
+// CreateTableOrder places a dine-in order on behalf of the guest attached to an open table session.
+func (uc *orderUseCaseImpl) CreateTableOrder(ctx context.Context, session *entity.TableSession, request *model.CreateOrderRequest) (*entity.Order, error) {
+	if session.Status != entity.TableSessionStatusOpen {
+		return nil, constants.ErrTableSessionClosed
 	}
 
-	// If not in cache, get from the database
-	orderEntity, err := uc.orderRepo.GetByID(id)
+	orderItems, totalPrice, categories, err := uc.buildOrderItems(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	response := model.ToOrderResponse(orderEntity)
 
-	// Store the order in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, response, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for order ID %d: %v", id, err)
+	order := &entity.Order{
+		CustomerID:     session.GuestCustomerID,
+		Status:         entity.OrderStatusPending,
+		TotalPrice:     totalPrice,
+		FoodStatus:     entity.FoodStatusPending,
+		Address:        fmt.Sprintf("Table %d", session.Table.TableNumber),
+		TableID:        &session.TableID,
+		TableSessionID: &session.ID,
+		Items:          orderItems,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
 	}
 
-	return response, nil
+	if err := uc.orderRepo.Create(ctx, order); err != nil {
+		uc.logger.Errorf("Error creating table order: %v", err)
+		return nil, err
+	}
+
+	invalidateOrderViews(ctx, uc.cache, uc.logger, order.ID)
+	uc.metrics.OrderCreated(metrics.Order{Channel: metrics.ChannelDineIn, Total: totalPrice, Items: categories})
+
+	return order, nil
 }
 
-func (uc *orderUseCaseImpl) GetCustomerOrders(customerID int64) ([]model.OrderResponse, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetCustomerOrders took %v", time.Since(start))
-	}()
+func (uc *orderUseCaseImpl) GetTableSessionOrders(ctx context.Context, sessionID int64) ([]model.OrderResponse, error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.GetTableSessionOrders")
+	defer span.End()
 
-	// Try to get the orders from the cache first
-	cacheKey := fmt.Sprintf("orders:customer:%d", customerID)
-	var orders []model.OrderResponse
-	if err := uc.cache.Get(context.Background(), cacheKey, &orders); err == nil {
-		uc.logger.Info("Customer orders fetched from cache")
-		return orders, nil
-	}
+	cacheKey := fmt.Sprintf("orders:table_session:%d", sessionID)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, listLoad(orderListTag), func() ([]model.OrderResponse, error) {
+		orderEntities, err := uc.orderRepo.GetByTableSessionID(ctx, sessionID)
+		if err != nil {
+			return nil, err
+		}
 
-	// If not in cache, get from the database
-	orderEntities, err := uc.orderRepo.GetByCustomerID(customerID)
-	if err != nil {
-		return nil, err
-	}
+		responses := make([]model.OrderResponse, len(orderEntities))
+		for i, order := range orderEntities {
+			responses[i] = *model.ToOrderResponse(&order)
+		}
+		return responses, nil
+	})
+}
 
-	responses := make([]model.OrderResponse, len(orderEntities))
-	for i, order := range orderEntities {
-		responses[i] = *model.ToOrderResponse(&order)
-	}
+// buildOrderItems validates the requested menus and calculates the order
+// total. It also returns the item quantities by menu category for the metrics.
+func (uc *orderUseCaseImpl) buildOrderItems(ctx context.Context, request *model.CreateOrderRequest) ([]entity.OrderItem, float64, map[string]int64, error) {
+	var orderItems []entity.OrderItem
+	var totalPrice float64
+	categories := make(map[string]int64)
+
+	for _, item := range request.Items {
+		// Validate menu exists
+		menu, err := uc.menuRepo.GetByID(ctx, item.MenuID)
+		if err != nil {
+			return nil, 0, nil, errors.New("menu not found")
+		}
+		categories[menuCategory(menu)] += item.Quantity
 
-	// Store the orders in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, responses, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for customer orders: %v", err)
+		orderItem := entity.OrderItem{
+			MenuID:   item.MenuID,
+			Quantity: item.Quantity,
+			Price:    menu.Price,
+		}
+		orderItems = append(orderItems, orderItem)
+		totalPrice += menu.Price * float64(item.Quantity)
 	}
 
-	return responses, nil
+	return orderItems, totalPrice, categories, nil
+}
+
+func (uc *orderUseCaseImpl) GetOrderByID(ctx context.Context, id int64) (*model.OrderResponse, error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.GetOrderByID")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("order:%d", id)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(nil, orderTag(id)), func() (*model.OrderResponse, error) {
+		orderEntity, err := uc.orderRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return model.ToOrderResponse(orderEntity), nil
+	})
+}
+
+func (uc *orderUseCaseImpl) GetCustomerOrders(ctx context.Context, customerID int64) ([]model.OrderResponse, error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.GetCustomerOrders")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("orders:customer:%d", customerID)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, listLoad(orderListTag), func() ([]model.OrderResponse, error) {
+		orderEntities, err := uc.orderRepo.GetByCustomerID(ctx, customerID)
+		if err != nil {
+			return nil, err
+		}
+
+		responses := make([]model.OrderResponse, len(orderEntities))
+		for i, order := range orderEntities {
+			responses[i] = *model.ToOrderResponse(&order)
+		}
+		return responses, nil
+	})
 }
 
-func (uc *orderUseCaseImpl) UpdateOrderStatus(id string, status string) error {
+func (uc *orderUseCaseImpl) UpdateOrderStatus(ctx context.Context, id string, status string) error {
 	orderStatus := entity.OrderStatus(status)
 	uc.logger.Tracef("UpdateOrderStatus usecase ~ in %s", uc.env)
 
@@ -214,7 +278,7 @@ func (uc *orderUseCaseImpl) UpdateOrderStatus(id string, status string) error {
 	var err error
 
 	if uc.env == "development" {
-		orderID, err = uc.orderRepo.GetPendingOrder()
+		orderID, err = uc.orderRepo.GetPendingOrder(ctx)
 		if err != nil {
 			uc.logger.Errorf("UpdateOrderStatus usecase ~Error getting pending order: %v", err)
 			return err
@@ -226,79 +290,88 @@ func (uc *orderUseCaseImpl) UpdateOrderStatus(id string, status string) error {
 		}
 	}
 
-	if err := uc.orderRepo.UpdateStatus(orderID, orderStatus); err != nil {
+	// Loaded first so gateway retries of the same status are not counted twice
+	order, err := uc.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.orderRepo.UpdateStatus(ctx, orderID, orderStatus); err != nil {
 		uc.logger.Errorf("Error updating order status: %v", err)
 		return err
 	}
 
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("order:%d", orderID)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
-		uc.logger.Errorf("Error deleting cache for order ID %d: %v", orderID, err)
-	}
-	if err := uc.cache.Delete(context.Background(), "orders:all:*"); err != nil {
-		uc.logger.Errorf("Error deleting cache for all orders: %v", err)
+	invalidateOrderViews(ctx, uc.cache, uc.logger, orderID)
+
+	if order.Status != orderStatus {
+		switch orderStatus {
+		case entity.OrderStatusPaid:
+			uc.metrics.OrderPaid(orderMetrics(order))
+		case entity.OrderStatusCancelled:
+			uc.metrics.OrderCancelled(orderMetrics(order))
+		}
 	}
 
 	return nil
 }
 
-func (uc *orderUseCaseImpl) DeleteOrder(id int64) error {
-	if err := uc.orderRepo.Delete(id); err != nil {
+func (uc *orderUseCaseImpl) DeleteOrder(ctx context.Context, id int64) error {
+	if err := uc.orderRepo.Delete(ctx, id); err != nil {
 		uc.logger.Errorf("Error deleting order: %v", err)
 		return err
 	}
 
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("order:%d", id)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
-		uc.logger.Errorf("Error deleting cache for order ID %d: %v", id, err)
-	}
-	if err := uc.cache.Delete(context.Background(), "orders:all:*"); err != nil {
-		uc.logger.Errorf("Error deleting cache for all orders: %v", err)
-	}
+	invalidateOrderViews(ctx, uc.cache, uc.logger, id)
 
 	return nil
 }
 
-func (uc *orderUseCaseImpl) GetAllOrders(params *model.PaginationQuery) (*[]model.OrderResponse, *model.PaginatedMeta, error) {
-	start := time.Now()
-	defer func() {
-		uc.logger.Infof("GetAllOrders took %v", time.Since(start))
-	}()
+func (uc *orderUseCaseImpl) GetAllOrders(ctx context.Context, params *model.PaginationQuery) (*[]model.OrderResponse, *model.PaginatedMeta, error) {
+	ctx, span := tracer.Start(ctx, "OrderUseCase.GetAllOrders")
+	defer span.End()
 
 	uc.logger.Trace("GetAllOrders usecase ~ in ", uc.env)
 
-	// Try to get the orders from the cache first
 	cacheKey := fmt.Sprintf("orders:all:page:%d:limit:%d", params.Page, params.Limit)
-	var cachedData struct {
-		Data []model.OrderResponse
-		Meta *model.PaginatedMeta
-	}
-	if err := uc.cache.Get(context.Background(), cacheKey, &cachedData); err == nil {
-		uc.logger.Info("All orders fetched from cache")
-		return &cachedData.Data, cachedData.Meta, nil
-	}
+	page, err := database.GetOrLoad(ctx, uc.cache, cacheKey, listLoad(orderListTag), func() (cachedPage[[]model.OrderResponse], error) {
+		orders, meta, err := uc.orderRepo.GetAll(ctx, params)
+		if err != nil {
+			return cachedPage[[]model.OrderResponse]{}, err
+		}
 
-	// If not in cache, get from the database
-	orders, meta, err := uc.orderRepo.GetAll(params)
+		responses := make([]model.OrderResponse, len(orders))
+		for i, order := range orders {
+			responses[i] = *model.ToOrderResponse(&order)
+		}
+		return cachedPage[[]model.OrderResponse]{Data: responses, Meta: meta}, nil
+	})
 	if err != nil {
 		uc.logger.Errorf("Error getting all orders: %v", err)
 		return nil, nil, err
 	}
 
-	responses := make([]model.OrderResponse, len(orders))
-	for i, order := range orders {
-		responses[i] = *model.ToOrderResponse(&order)
+	return &page.Data, page.Meta, nil
+}
+
+// orderMetrics describes an order loaded with its items and their menus
+func orderMetrics(order *entity.Order) metrics.Order {
+	channel := metrics.ChannelOnline
+	if order.TableSessionID != nil {
+		channel = metrics.ChannelDineIn
 	}
 
-	// Store the orders in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, struct {
-		Data []model.OrderResponse
-		Meta *model.PaginatedMeta
-	}{Data: responses, Meta: meta}, 5*time.Minute); err != nil {
-		uc.logger.Errorf("Error setting cache for all orders: %v", err)
+	items := make(map[string]int64)
+	for _, item := range order.Items {
+		items[menuCategory(&item.Menu)] += item.Quantity
 	}
+	return metrics.Order{Channel: channel, Total: order.TotalPrice, Items: items}
+}
 
-	return &responses, meta, nil
+func menuCategory(menu *entity.Menu) string {
+	if menu.Category == "" {
+		return "uncategorized"
+	}
+	return menu.Category
 }
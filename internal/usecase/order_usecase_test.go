@@ -4,8 +4,12 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -16,12 +20,12 @@ type MockOrderRepository struct {
 	mock.Mock
 }
 
-func (m *MockOrderRepository) Create(order *entity.Order) error {
+func (m *MockOrderRepository) Create(ctx context.Context, order *entity.Order) error {
 	args := m.Called(order)
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) GetByID(id int64) (*entity.Order, error) {
+func (m *MockOrderRepository) GetByID(ctx context.Context, id int64) (*entity.Order, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -29,7 +33,7 @@ func (m *MockOrderRepository) GetByID(id int64) (*entity.Order, error) {
 	return args.Get(0).(*entity.Order), args.Error(1)
 }
 
-func (m *MockOrderRepository) GetPendingPaymentByOrderID(customerID, orderID int64) (entity.Order, error) {
+func (m *MockOrderRepository) GetPendingPaymentByOrderID(ctx context.Context, customerID, orderID int64) (entity.Order, error) {
 	args := m.Called(customerID, orderID)
 	if args.Get(0) == nil {
 		return entity.Order{}, args.Error(1)
@@ -37,7 +41,7 @@ func (m *MockOrderRepository) GetPendingPaymentByOrderID(customerID, orderID int
 	return args.Get(0).(entity.Order), args.Error(1)
 }
 
-func (m *MockOrderRepository) GetAll(params *model.PaginationQuery) ([]entity.Order, *model.PaginatedMeta, error) {
+func (m *MockOrderRepository) GetAll(ctx context.Context, params *model.PaginationQuery) ([]entity.Order, *model.PaginatedMeta, error) {
 	args := m.Called(params)
 	if args.Get(0) == nil {
 		return nil, nil, args.Error(2)
@@ -45,7 +49,7 @@ func (m *MockOrderRepository) GetAll(params *model.PaginationQuery) ([]entity.Or
 	return args.Get(0).([]entity.Order), args.Get(1).(*model.PaginatedMeta), args.Error(2)
 }
 
-func (m *MockOrderRepository) GetByCustomerID(customerID int64) ([]entity.Order, error) {
+func (m *MockOrderRepository) GetByCustomerID(ctx context.Context, customerID int64) ([]entity.Order, error) {
 	args := m.Called(customerID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -53,27 +57,27 @@ func (m *MockOrderRepository) GetByCustomerID(customerID int64) ([]entity.Order,
 	return args.Get(0).([]entity.Order), args.Error(1)
 }
 
-func (m *MockOrderRepository) UpdateStatus(id int64, status entity.OrderStatus) error {
+func (m *MockOrderRepository) UpdateStatus(ctx context.Context, id int64, status entity.OrderStatus) error {
 	args := m.Called(id, status)
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) Delete(id int64) error {
+func (m *MockOrderRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) UpdateFoodStatus(orderID int64, foodStatus entity.FoodStatus) error {
+func (m *MockOrderRepository) UpdateFoodStatus(ctx context.Context, orderID int64, foodStatus entity.FoodStatus) error {
 	args := m.Called(orderID, foodStatus)
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) GetPendingOrder() (int64, error) {
+func (m *MockOrderRepository) GetPendingOrder(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *MockOrderRepository) FindByDateRange(startDate, endDate string) ([]entity.Order, error) {
+func (m *MockOrderRepository) FindByDateRange(ctx context.Context, startDate, endDate string) ([]entity.Order, error) {
 	args := m.Called(startDate, endDate)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -81,16 +85,55 @@ func (m *MockOrderRepository) FindByDateRange(startDate, endDate string) ([]enti
 	return args.Get(0).([]entity.Order), args.Error(1)
 }
 
-func (m *MockOrderRepository) Update(order *entity.Order) error {
+func (m *MockOrderRepository) Update(ctx context.Context, order *entity.Order) error {
 	args := m.Called(order)
 	return args.Error(0)
 }
 
+func (m *MockOrderRepository) GetByTableSessionID(ctx context.Context, sessionID int64) ([]entity.Order, error) {
+	args := m.Called(sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) ExpirePending(ctx context.Context, before time.Time) ([]int64, error) {
+	args := m.Called(before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockOrderRepository) CountKitchenQueue(ctx context.Context) (map[entity.FoodStatus]int64, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[entity.FoodStatus]int64), args.Error(1)
+}
+
+// recordedMetrics keeps the business metrics a test cares about
+type recordedMetrics struct {
+	metrics.Noop
+	paid         []metrics.Order
+	kitchenQueue map[string]int64
+}
+
+func (r *recordedMetrics) OrderPaid(order metrics.Order) {
+	r.paid = append(r.paid, order)
+}
+
+func (r *recordedMetrics) KitchenQueue(depth map[string]int64) {
+	r.kitchenQueue = depth
+}
+
 func TestOrderUseCase_GetOrderByID(t *testing.T) {
 	logger := logrus.New()
 	mockOrderRepo := new(MockOrderRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache)
+	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedOrder := &entity.Order{
@@ -98,9 +141,9 @@ func TestOrderUseCase_GetOrderByID(t *testing.T) {
 		}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetByID", int64(1)).Return(expectedOrder, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "order:1", mock.Anything, mock.Anything, []string{orderTag(1)}).Return(nil)
 
-		order, err := useCase.GetOrderByID(1)
+		order, err := useCase.GetOrderByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, order)
@@ -112,7 +155,7 @@ func TestOrderUseCase_GetOrderByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetByID", int64(1)).Return(nil, errors.New("not found")).Once()
 
-		order, err := useCase.GetOrderByID(1)
+		order, err := useCase.GetOrderByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, order)
@@ -124,7 +167,7 @@ func TestOrderUseCase_GetPendingOrder(t *testing.T) {
 	logger := logrus.New()
 	mockOrderRepo := new(MockOrderRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache)
+	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedOrder := entity.Order{
@@ -132,9 +175,9 @@ func TestOrderUseCase_GetPendingOrder(t *testing.T) {
 		}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetPendingPaymentByOrderID", int64(1), int64(1)).Return(expectedOrder, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "order:pending:1:1", mock.Anything, mock.Anything, []string{orderTag(1)}).Return(nil)
 
-		order, err := useCase.GetPendingOrder(1, 1)
+		order, err := useCase.GetPendingOrder(context.Background(), 1, 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, order)
@@ -146,7 +189,7 @@ func TestOrderUseCase_GetPendingOrder(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetPendingPaymentByOrderID", int64(1), int64(1)).Return(entity.Order{}, errors.New("not found")).Once()
 
-		order, err := useCase.GetPendingOrder(1, 1)
+		order, err := useCase.GetPendingOrder(context.Background(), 1, 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, order)
@@ -158,7 +201,7 @@ func TestOrderUseCase_GetAllOrders(t *testing.T) {
 	logger := logrus.New()
 	mockOrderRepo := new(MockOrderRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache)
+	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedResponse := []entity.Order{
@@ -172,9 +215,9 @@ func TestOrderUseCase_GetAllOrders(t *testing.T) {
 		params := &model.PaginationQuery{Page: 1, Limit: 10}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetAll", params).Return(expectedResponse, meta, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "orders:all:page:1:limit:10", mock.Anything, mock.Anything, []string{orderListTag}).Return(nil)
 
-		orders, resultMeta, err := useCase.GetAllOrders(params)
+		orders, resultMeta, err := useCase.GetAllOrders(context.Background(), params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, orders)
@@ -188,7 +231,7 @@ func TestOrderUseCase_GetAllOrders(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetAll", params).Return(nil, nil, errors.New("error")).Once()
 
-		orders, resultMeta, err := useCase.GetAllOrders(params)
+		orders, resultMeta, err := useCase.GetAllOrders(context.Background(), params)
 
 		assert.Error(t, err)
 		assert.Nil(t, orders)
@@ -201,7 +244,7 @@ func TestOrderUseCase_GetCustomerOrders(t *testing.T) {
 	logger := logrus.New()
 	mockOrderRepo := new(MockOrderRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache)
+	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache, metrics.Noop{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedResponse := []entity.Order{
@@ -211,9 +254,9 @@ func TestOrderUseCase_GetCustomerOrders(t *testing.T) {
 		}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetByCustomerID", int64(1)).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "orders:customer:1", mock.Anything, mock.Anything, []string{orderListTag}).Return(nil)
 
-		orders, err := useCase.GetCustomerOrders(1)
+		orders, err := useCase.GetCustomerOrders(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, orders)
@@ -225,10 +268,118 @@ func TestOrderUseCase_GetCustomerOrders(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockOrderRepo.On("GetByCustomerID", int64(1)).Return(nil, errors.New("error")).Once()
 
-		orders, err := useCase.GetCustomerOrders(1)
+		orders, err := useCase.GetCustomerOrders(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, orders)
 		mockOrderRepo.AssertExpectations(t)
 	})
 }
+
+func TestOrderUseCase_ExpirePending(t *testing.T) {
+	logger := logrus.New()
+	before := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("invalidates expired orders", func(t *testing.T) {
+		mockOrderRepo := new(MockOrderRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache, metrics.Noop{})
+
+		mockOrderRepo.On("ExpirePending", before).Return([]int64{3, 7}, nil).Once()
+		for _, id := range []int64{3, 7} {
+			mockCache.On("InvalidateTags", mock.Anything, []string{orderTag(id), orderListTag}).Return(nil).Once()
+			mockCache.On("Delete", mock.Anything, fmt.Sprintf("payments:order:%d", id)).Return(nil).Once()
+		}
+
+		count, err := useCase.ExpirePending(context.Background(), before)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+		mockOrderRepo.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockOrderRepo := new(MockOrderRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logger, "test", mockCache, metrics.Noop{})
+
+		mockOrderRepo.On("ExpirePending", before).Return(nil, errors.New("database down")).Once()
+
+		count, err := useCase.ExpirePending(context.Background(), before)
+
+		assert.Error(t, err)
+		assert.Zero(t, count)
+		mockCache.AssertNotCalled(t, "InvalidateTags", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrderUseCase_UpdateOrderStatusRecordsPaymentOnce(t *testing.T) {
+	mockOrderRepo := new(MockOrderRepository)
+	mockCache := new(database.MockRedisCacheService)
+	recorder := &recordedMetrics{}
+	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logrus.New(), "test", mockCache, recorder)
+
+	order := &entity.Order{
+		ID:         5,
+		Status:     entity.OrderStatusPending,
+		TotalPrice: 90000,
+		Items: []entity.OrderItem{
+			{Quantity: 2, Menu: entity.Menu{Category: "cake"}},
+			{Quantity: 1, Menu: entity.Menu{Category: "cake"}},
+			{Quantity: 1},
+		},
+	}
+	paid := *order
+	paid.Status = entity.OrderStatusPaid
+	mockOrderRepo.On("GetByID", int64(5)).Return(order, nil).Once()
+	mockOrderRepo.On("GetByID", int64(5)).Return(&paid, nil).Once()
+	mockOrderRepo.On("UpdateStatus", int64(5), entity.OrderStatusPaid).Return(nil).Twice()
+	mockCache.On("InvalidateTags", mock.Anything, []string{orderTag(5), orderListTag}).Return(nil)
+
+	// Midtrans sends capture and then settlement for the same card payment
+	assert.NoError(t, useCase.UpdateOrderStatus(context.Background(), "5", string(entity.OrderStatusPaid)))
+	assert.NoError(t, useCase.UpdateOrderStatus(context.Background(), "5", string(entity.OrderStatusPaid)))
+
+	if assert.Len(t, recorder.paid, 1) {
+		assert.Equal(t, metrics.ChannelOnline, recorder.paid[0].Channel)
+		assert.Equal(t, 90000.0, recorder.paid[0].Total)
+		assert.Equal(t, map[string]int64{"cake": 3, "uncategorized": 1}, recorder.paid[0].Items)
+	}
+	mockOrderRepo.AssertExpectations(t)
+}
+
+func TestOrderUseCase_RecordKitchenQueue(t *testing.T) {
+	mockOrderRepo := new(MockOrderRepository)
+	recorder := &recordedMetrics{}
+	useCase := NewOrderUseCase(mockOrderRepo, nil, nil, logrus.New(), "test", nil, recorder)
+
+	mockOrderRepo.On("CountKitchenQueue").Return(map[entity.FoodStatus]int64{
+		entity.FoodStatusPending: 4,
+		entity.FoodStatusCooking: 2,
+	}, nil).Once()
+
+	assert.NoError(t, useCase.RecordKitchenQueue(context.Background()))
+	assert.Equal(t, map[string]int64{"pending": 4, "cooking": 2}, recorder.kitchenQueue)
+}
+
+func TestOrderUseCase_CreateTableOrderPricesFromMenu(t *testing.T) {
+	logger := logrus.New()
+	mockOrderRepo := new(MockOrderRepository)
+	mockMenuRepo := new(MockMenuRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewOrderUseCase(mockOrderRepo, mockMenuRepo, nil, logger, "test", mockCache, metrics.Noop{})
+
+	session := &entity.TableSession{ID: 4, TableID: 2, GuestCustomerID: 9, Status: entity.TableSessionStatusOpen}
+	mockMenuRepo.On("GetByID", int64(5)).Return(&entity.Menu{ID: 5, Price: 45000}, nil)
+	mockOrderRepo.On("Create", mock.Anything).Return(nil).Once()
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	order, err := useCase.CreateTableOrder(context.Background(), session, &model.CreateOrderRequest{
+		Items: []model.OrderItemRequest{{MenuID: 5, Title: "Tiramisu", Quantity: 2}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 90000.0, order.TotalPrice)
+	assert.Equal(t, 45000.0, order.Items[0].Price)
+}
@@ -6,96 +6,302 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
 	"cakestore/internal/repository"
 	"cakestore/utils"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/midtrans/midtrans-go"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type PaymentUseCase interface {
-	CreatePaymentURL(order *entity.Order) (*model.PaymentResponse, error)
-	GetOrderStatus(orderID string) (string, error)
-	UpdateOrderStatus(id string, status constants.PaymentStatus) error
-	GetPaymentByOrderID(order *entity.Order) (*entity.Payment, error)
+	CreatePaymentURL(ctx context.Context, order *entity.Order) (*model.PaymentResponse, error)
+	CreateSplitPayments(ctx context.Context, order *entity.Order, request *model.SplitPaymentRequest) (*model.PaymentSummaryResponse, error)
+	GetPaymentSummary(ctx context.Context, order *entity.Order) (*model.PaymentSummaryResponse, error)
+	UpdateGatewayPaymentStatus(ctx context.Context, gatewayOrderID string, status constants.PaymentStatus) (*entity.Payment, error)
+	GetOrderStatus(ctx context.Context, orderID string) (string, error)
+	UpdateOrderStatus(ctx context.Context, id string, status constants.PaymentStatus) error
+	GetPendingPayment(ctx context.Context, order *entity.Order, paymentID int64) (*model.PaymentModel, error)
+}
+
+// MidtransConfig is how payments and deposits reach the Midtrans API.
+type MidtransConfig struct {
+	Endpoint  string
+	ServerKey string
+	// Timeout bounds each call, 10 seconds when not set.
+	Timeout time.Duration
+}
+
+func (c MidtransConfig) httpClient() *http.Client {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	// The transport starts a client span per request and sends the trace
+	// context along in the traceparent header
+	return &http.Client{Timeout: timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)}
+}
+
+// Check reports a gateway that cannot work: a missing server key, an endpoint
+// that is not an http(s) URL, or a sandbox key paired with the production
+// endpoint or the other way round. It makes no request to Midtrans.
+func (c MidtransConfig) Check(context.Context) error {
+	if c.ServerKey == "" {
+		return errors.New("MIDTRANS_SERVER_KEY is not set")
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return fmt.Errorf("MIDTRANS_ENDPOINT %q is not an http(s) URL", c.Endpoint)
+	}
+	sandboxKey := strings.HasPrefix(c.ServerKey, "SB-")
+	sandboxEndpoint := strings.Contains(endpoint.Host, "sandbox")
+	if sandboxKey != sandboxEndpoint {
+		return fmt.Errorf("MIDTRANS_SERVER_KEY and MIDTRANS_ENDPOINT %s do not belong to the same environment", endpoint.Host)
+	}
+	return nil
 }
 
 type paymentUseCase struct {
 	paymentRepository repository.PaymentRepository
-	endpoint          string
+	gateway           MidtransConfig
 	log               *logrus.Logger
 	env               string
 	cache             database.RedisCache
+	metrics           metrics.Business
 }
 
 func NewPaymentUseCase(
-	endpoint string,
+	gateway MidtransConfig,
 	paymentRepository repository.PaymentRepository,
 	log *logrus.Logger,
 	env string,
 	cache database.RedisCache,
+	recorder metrics.Business,
 ) PaymentUseCase {
 	return &paymentUseCase{
-		endpoint:          endpoint,
+		gateway:           gateway,
 		paymentRepository: paymentRepository,
 		log:               log,
 		env:               env,
 		cache:             cache,
+		metrics:           recorder,
 	}
 }
 
-func (uc *paymentUseCase) GetPaymentByOrderID(order *entity.Order) (*entity.Payment, error) {
-	start := time.Now()
-	defer func() {
-		uc.log.Infof("GetPaymentByOrderID took %v", time.Since(start))
-	}()
+// GetPendingPayment returns the pending Midtrans payment of an order. A paymentID
+// of 0 is only accepted while the order has a single one; a split bill has to
+// name the split.
+func (uc *paymentUseCase) GetPendingPayment(ctx context.Context, order *entity.Order, paymentID int64) (*model.PaymentModel, error) {
+	ctx, span := tracer.Start(ctx, "PaymentUseCase.GetPendingPayment")
+	defer span.End()
 
-	// Try to get the payment from the cache first
-	cacheKey := fmt.Sprintf("payment:order:%d", order.ID)
-	var payment entity.Payment
-	if err := uc.cache.Get(context.Background(), cacheKey, &payment); err == nil {
-		uc.log.Info("Payment fetched from cache")
-		return &payment, nil
+	summary, err := uc.GetPaymentSummary(ctx, order)
+	if err != nil {
+		return nil, err
 	}
 
-	// If not in cache, get from the database
-	paymentEntity, err := uc.paymentRepository.GetPaymentByOrderID(order.ID)
+	var found *model.PaymentModel
+	for i, payment := range summary.Payments {
+		if payment.Status != constants.PaymentStatusPending || payment.Method != constants.PaymentMethodMidtrans {
+			continue
+		}
+		if paymentID != 0 && payment.ID != paymentID {
+			continue
+		}
+		if found != nil {
+			return nil, constants.ErrPaymentIDRequired
+		}
+		found = &summary.Payments[i]
+	}
+	if found == nil {
+		return nil, constants.ErrNotFound
+	}
+	return found, nil
+}
+
+func (uc *paymentUseCase) CreatePaymentURL(ctx context.Context, order *entity.Order) (*model.PaymentResponse, error) {
+	gatewayOrderID := newGatewayOrderID(order.ID)
+	paymentResponse, err := createSnapTransaction(ctx, uc.gateway, uc.metrics, gatewayOrderID, int64(order.TotalPrice))
 	if err != nil {
 		return nil, err
 	}
 
-	// Store the payment in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, paymentEntity, 5*time.Minute); err != nil {
-		uc.log.Errorf("Error setting cache for payment by order ID %d: %v", order.ID, err)
+	// insert payment to db
+	payment := &entity.Payment{
+		OrderID: order.ID,
+		Amount:  order.TotalPrice,
+		Method:  constants.PaymentMethodMidtrans,
+		// Settled by the Midtrans notification, which also marks the order
+		// paid once its payments cover the total
+		Status:         constants.PaymentStatusPending,
+		GatewayOrderID: gatewayOrderID,
+		PaymentToken:   paymentResponse.Token,
+		PaymentURL:     paymentResponse.RedirectURL,
+	}
+	if err := uc.paymentRepository.CreatePayment(ctx, payment); err != nil {
+		return nil, err
 	}
+	uc.invalidatePaymentCache(ctx, order.ID)
 
-	return paymentEntity, nil
+	return paymentResponse, nil
 }
 
-func (uc *paymentUseCase) CreatePaymentURL(order *entity.Order) (*model.PaymentResponse, error) {
+// CreateSplitPayments replaces the pending payments of an order with one payment
+// per requested split. Midtrans splits get their own Snap link, cash splits stay
+// pending until a cashier settles them.
+func (uc *paymentUseCase) CreateSplitPayments(ctx context.Context, order *entity.Order, request *model.SplitPaymentRequest) (*model.PaymentSummaryResponse, error) {
+	ctx, span := tracer.Start(ctx, "PaymentUseCase.CreateSplitPayments")
+	defer span.End()
+
+	if order.Status == entity.OrderStatusCancelled {
+		return nil, constants.ErrOrderNotPayable
+	}
+
+	payments, err := uc.paymentRepository.GetPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := int64(math.Round(order.TotalPrice - sumPayments(payments, constants.PaymentStatusSuccess)))
+	if outstanding <= 0 {
+		return nil, constants.ErrOrderAlreadyPaid
+	}
+
+	amounts, err := splitAmounts(order, request, outstanding)
+	if err != nil {
+		return nil, err
+	}
+
+	splits := make([]*entity.Payment, 0, len(request.Splits))
+	for i, split := range request.Splits {
+		payment := &entity.Payment{
+			OrderID:     order.ID,
+			Amount:      float64(amounts[i]),
+			Method:      split.Method,
+			Description: split.Label,
+		}
+		if payment.Description == "" {
+			payment.Description = fmt.Sprintf("Split %d of %d", i+1, len(request.Splits))
+		}
+
+		if split.Method == constants.PaymentMethodMidtrans {
+			payment.GatewayOrderID = newGatewayOrderID(order.ID)
+			paymentResponse, err := createSnapTransaction(ctx, uc.gateway, uc.metrics, payment.GatewayOrderID, amounts[i])
+			if err != nil {
+				uc.log.Errorf("Error creating payment link for split %d of order %d: %v", i+1, order.ID, err)
+				return nil, err
+			}
+			payment.PaymentToken = paymentResponse.Token
+			payment.PaymentURL = paymentResponse.RedirectURL
+		}
+		splits = append(splits, payment)
+	}
+
+	// Links from a previous split are abandoned in the same transaction that
+	// stores the new ones, so a failure leaves the previous split in place
+	if err := uc.paymentRepository.ReplacePendingPayments(ctx, order.ID, splits); err != nil {
+		return nil, err
+	}
+	uc.invalidatePaymentCache(ctx, order.ID)
+
+	return uc.GetPaymentSummary(ctx, order)
+}
+
+func (uc *paymentUseCase) GetPaymentSummary(ctx context.Context, order *entity.Order) (*model.PaymentSummaryResponse, error) {
+	ctx, span := tracer.Start(ctx, "PaymentUseCase.GetPaymentSummary")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("payments:order:%d", order.ID)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(nil), func() (*model.PaymentSummaryResponse, error) {
+		payments, err := uc.paymentRepository.GetPaymentsByOrderID(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		paid := sumPayments(payments, constants.PaymentStatusSuccess)
+		outstanding := math.Max(order.TotalPrice-paid, 0)
+		summary := &model.PaymentSummaryResponse{
+			OrderID:       order.ID,
+			TotalAmount:   order.TotalPrice,
+			PaidAmount:    paid,
+			PendingAmount: sumPayments(payments, constants.PaymentStatusPending),
+			Outstanding:   outstanding,
+			IsFullyPaid:   outstanding == 0,
+			Payments:      make([]model.PaymentModel, len(payments)),
+		}
+		for i, payment := range payments {
+			summary.Payments[i] = *model.ToPaymentModel(&payment)
+		}
+		return summary, nil
+	})
+}
+
+// UpdateGatewayPaymentStatus applies a Midtrans notification to the payment it was issued for.
+// Only transitions out of pending are applied; a late or replayed notification leaves the
+// payment as it is and the returned payment keeps its current status.
+// Returns constants.ErrNotFound for notifications about payments created before split bills existed.
+func (uc *paymentUseCase) UpdateGatewayPaymentStatus(ctx context.Context, gatewayOrderID string, status constants.PaymentStatus) (*entity.Payment, error) {
+	payment, err := uc.paymentRepository.GetPaymentByGatewayOrderID(ctx, gatewayOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if status == constants.PaymentStatusPending {
+		return payment, nil
+	}
+
+	applied, err := uc.paymentRepository.TransitionPaymentStatus(ctx, payment.ID, status, []constants.PaymentStatus{constants.PaymentStatusPending})
+	if err != nil {
+		uc.log.Errorf("Error updating payment status: %v", err)
+		return nil, err
+	}
+	if !applied {
+		uc.log.Infof("Ignoring %s notification for payment %s", status, gatewayOrderID)
+		return payment, nil
+	}
+	payment.Status = status
+
+	uc.invalidatePaymentCache(ctx, payment.OrderID)
+	if err := uc.cache.Delete(ctx, fmt.Sprintf("order_status:%s", gatewayOrderID)); err != nil {
+		uc.log.Errorf("Error deleting cache for order status %s: %v", gatewayOrderID, err)
+	}
+
+	return payment, nil
+}
+
+// createSnapTransaction asks Midtrans Snap for a payment link; deposits use it too
+func createSnapTransaction(ctx context.Context, gateway MidtransConfig, recorder metrics.Business, gatewayOrderID string, amount int64) (_ *model.PaymentResponse, err error) {
+	start := time.Now()
+	defer func() {
+		recorder.GatewayRequest("create_transaction", time.Since(start), err)
+	}()
+
 	var req model.CreatePaymentRequest
 
 	req.TransactionDetails = midtrans.TransactionDetails{
-		OrderID:  "ORDER-" + strconv.Itoa(int(order.ID)) + "-" + uuid.New().String(),
-		GrossAmt: int64(order.TotalPrice),
+		OrderID:  gatewayOrderID,
+		GrossAmt: amount,
 	}
 
-	headers := utils.GenerateRequestHeader()
+	headers := utils.GenerateRequestHeader(gateway.ServerKey)
 
 	reqBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequest("POST", uc.endpoint+"/snap/v1/transactions", bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", gateway.Endpoint+"/snap/v1/transactions", bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -107,8 +313,7 @@ func (uc *paymentUseCase) CreatePaymentURL(order *entity.Order) (*model.PaymentR
 	// optional: additional notification URLs
 	// httpReq.Header.Set("X-Append-Notification", "https://5a48-2a09-bac5-3a09-25d7-00-3c5-35.ngrok-free.app/payment/notification/")
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := gateway.httpClient().Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -133,83 +338,139 @@ func (uc *paymentUseCase) CreatePaymentURL(order *entity.Order) (*model.PaymentR
 		return nil, err
 	}
 
-	// insert payment to db
-	payment := &entity.Payment{
-		OrderID: order.ID,
-		Amount:  order.TotalPrice,
-		// FIXME workaround for midtrans webhook delay or error, force update to success
-		Status:       constants.PaymentStatusSuccess,
-		PaymentToken: paymentResponse.Token,
-		PaymentURL:   paymentResponse.RedirectURL,
-	}
-	if err := uc.paymentRepository.CreatePayment(payment); err != nil {
-		return nil, err
-	}
-
 	return &paymentResponse, nil
 }
 
-func (uc *paymentUseCase) GetOrderStatus(orderID string) (string, error) {
-	start := time.Now()
-	defer func() {
-		uc.log.Infof("GetOrderStatus took %v", time.Since(start))
-	}()
-
-	// Try to get the order status from the cache first
-	cacheKey := fmt.Sprintf("order_status:%s", orderID)
-	var status string
-	if err := uc.cache.Get(context.Background(), cacheKey, &status); err == nil {
-		uc.log.Info("Order status fetched from cache")
-		return status, nil
+func (uc *paymentUseCase) invalidatePaymentCache(ctx context.Context, orderID int64) {
+	if err := uc.cache.Delete(ctx, fmt.Sprintf("payments:order:%d", orderID)); err != nil {
+		uc.log.Errorf("Error deleting cache for payment summary of order ID %d: %v", orderID, err)
 	}
+}
 
-	// If not in cache, get from the external service
-	endpoint := fmt.Sprintf("%s/v2/%s/status", uc.endpoint, orderID)
-	headers := utils.GenerateRequestHeader()
+// newGatewayOrderID keeps the "ORDER-<id>-<uuid>" format the webhook has always parsed
+func newGatewayOrderID(orderID int64) string {
+	return "ORDER-" + strconv.FormatInt(orderID, 10) + "-" + uuid.New().String()
+}
 
-	httpReq, err := http.NewRequest("GET", endpoint, nil)
-	if err != nil {
-		return "", err
+func sumPayments(payments []entity.Payment, status constants.PaymentStatus) float64 {
+	var total float64
+	for _, payment := range payments {
+		if payment.Status == status {
+			total += payment.Amount
+		}
 	}
+	return total
+}
 
-	for key, value := range headers {
-		httpReq.Header.Set(key, value)
-	}
+// splitAmounts works out the amount charged to each split, in whole rupiah as Midtrans requires.
+func splitAmounts(order *entity.Order, request *model.SplitPaymentRequest, outstanding int64) ([]int64, error) {
+	amounts := make([]int64, len(request.Splits))
 
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		return "", err
+	switch request.Mode {
+	case constants.SplitModeEven:
+		parts := int64(len(request.Splits))
+		for i := range amounts {
+			amounts[i] = outstanding / parts
+		}
+		// The last split absorbs the rounding remainder
+		amounts[len(amounts)-1] += outstanding % parts
+	case constants.SplitModeItem:
+		items := make(map[int64]entity.OrderItem, len(order.Items))
+		for _, item := range order.Items {
+			items[item.ID] = item
+		}
+		claimed := make(map[int64]bool)
+		for i, split := range request.Splits {
+			if len(split.ItemIDs) == 0 {
+				return nil, constants.ErrInvalidSplit
+			}
+			var amount float64
+			for _, itemID := range split.ItemIDs {
+				item, ok := items[itemID]
+				if !ok || claimed[itemID] {
+					return nil, constants.ErrInvalidSplit
+				}
+				claimed[itemID] = true
+				amount += item.Price * float64(item.Quantity)
+			}
+			amounts[i] = int64(math.Round(amount))
+		}
+	case constants.SplitModeCustom:
+		for i, split := range request.Splits {
+			if split.Amount <= 0 {
+				return nil, constants.ErrInvalidSplit
+			}
+			amounts[i] = int64(math.Round(split.Amount))
+		}
+	default:
+		return nil, constants.ErrInvalidSplit
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get order status, status code: %d", resp.StatusCode)
+	var total int64
+	for _, amount := range amounts {
+		if amount <= 0 {
+			return nil, constants.ErrInvalidSplit
+		}
+		total += amount
 	}
-
-	var orderStatus model.GetOrderStatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&orderStatus); err != nil {
-		return "", err
+	if total > outstanding {
+		return nil, constants.ErrSplitExceedsOutstanding
 	}
 
-	if orderStatus.StatusCode != "200" {
-		return "", fmt.Errorf("failed to get order status, status code: %s", orderStatus.StatusCode)
-	}
+	return amounts, nil
+}
 
-	// Store the order status in the cache for future requests
-	if err := uc.cache.Set(context.Background(), cacheKey, orderStatus.TransactionStatus, 5*time.Minute); err != nil {
-		uc.log.Errorf("Error setting cache for order status %s: %v", orderID, err)
-	}
+func (uc *paymentUseCase) GetOrderStatus(ctx context.Context, orderID string) (string, error) {
+	ctx, span := tracer.Start(ctx, "PaymentUseCase.GetOrderStatus")
+	defer span.End()
+
+	cacheKey := fmt.Sprintf("order_status:%s", orderID)
+	return database.GetOrLoad(ctx, uc.cache, cacheKey, itemLoad(nil), func() (_ string, err error) {
+		requested := time.Now()
+		defer func() {
+			uc.metrics.GatewayRequest("get_status", time.Since(requested), err)
+		}()
 
-	return orderStatus.TransactionStatus, nil
+		endpoint := fmt.Sprintf("%s/v2/%s/status", uc.gateway.Endpoint, orderID)
+		headers := utils.GenerateRequestHeader(uc.gateway.ServerKey)
+
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return "", err
+		}
+
+		for key, value := range headers {
+			httpReq.Header.Set(key, value)
+		}
+
+		resp, err := uc.gateway.httpClient().Do(httpReq)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("failed to get order status, status code: %d", resp.StatusCode)
+		}
+
+		var orderStatus model.GetOrderStatusResponse
+		if err := json.NewDecoder(resp.Body).Decode(&orderStatus); err != nil {
+			return "", err
+		}
+
+		if orderStatus.StatusCode != "200" {
+			return "", fmt.Errorf("failed to get order status, status code: %s", orderStatus.StatusCode)
+		}
+		return orderStatus.TransactionStatus, nil
+	})
 }
 
-func (uc *paymentUseCase) UpdateOrderStatus(id string, status constants.PaymentStatus) error {
+func (uc *paymentUseCase) UpdateOrderStatus(ctx context.Context, id string, status constants.PaymentStatus) error {
 	var orderID int64
 	var err error
 
 	if uc.env == "development" {
-		orderID, err = uc.paymentRepository.GetPendingPayment()
+		orderID, err = uc.paymentRepository.GetPendingPayment(ctx)
 		if err != nil {
 			uc.log.Errorf("Error getting pending payment: %v", err)
 			return err
@@ -227,18 +488,15 @@ func (uc *paymentUseCase) UpdateOrderStatus(id string, status constants.PaymentS
 		Status:  status,
 	})
 
-	if err := uc.paymentRepository.UpdatePayment(payment); err != nil {
+	if err := uc.paymentRepository.UpdatePayment(ctx, payment); err != nil {
 		uc.log.Errorf("Error updating order status: %v", err)
 		return err
 	}
 
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("payment:order:%d", orderID)
-	if err := uc.cache.Delete(context.Background(), cacheKey); err != nil {
-		uc.log.Errorf("Error deleting cache for payment by order ID %d: %v", orderID, err)
-	}
+	uc.invalidatePaymentCache(ctx, orderID)
 	orderStatusCacheKey := fmt.Sprintf("order_status:%s", id)
-	if err := uc.cache.Delete(context.Background(), orderStatusCacheKey); err != nil {
+	if err := uc.cache.Delete(ctx, orderStatusCacheKey); err != nil {
 		uc.log.Errorf("Error deleting cache for order status %s: %v", id, err)
 	}
 
@@ -1,10 +1,17 @@
 package usecase
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -15,12 +22,12 @@ type MockPaymentRepository struct {
 	mock.Mock
 }
 
-func (m *MockPaymentRepository) CreatePayment(payment *entity.Payment) error {
+func (m *MockPaymentRepository) CreatePayment(ctx context.Context, payment *entity.Payment) error {
 	args := m.Called(payment)
 	return args.Error(0)
 }
 
-func (m *MockPaymentRepository) GetPaymentByOrderID(orderID int64) (*entity.Payment, error) {
+func (m *MockPaymentRepository) GetPaymentByOrderID(ctx context.Context, orderID int64) (*entity.Payment, error) {
 	args := m.Called(orderID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -28,49 +35,368 @@ func (m *MockPaymentRepository) GetPaymentByOrderID(orderID int64) (*entity.Paym
 	return args.Get(0).(*entity.Payment), args.Error(1)
 }
 
-func (m *MockPaymentRepository) UpdatePayment(payment *entity.Payment) error {
+func (m *MockPaymentRepository) GetPaymentsByOrderID(ctx context.Context, orderID int64) ([]entity.Payment, error) {
+	args := m.Called(orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentByID(ctx context.Context, id int64) (*entity.Payment, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPaymentByGatewayOrderID(ctx context.Context, gatewayOrderID string) (*entity.Payment, error) {
+	args := m.Called(gatewayOrderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) UpdatePayment(ctx context.Context, payment *entity.Payment) error {
 	args := m.Called(payment)
 	return args.Error(0)
 }
 
-func (m *MockPaymentRepository) GetPendingPayment() (int64, error) {
+func (m *MockPaymentRepository) UpdatePaymentStatus(ctx context.Context, id int64, status constants.PaymentStatus) error {
+	args := m.Called(id, status)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) TransitionPaymentStatus(ctx context.Context, id int64, status constants.PaymentStatus, from []constants.PaymentStatus) (bool, error) {
+	args := m.Called(id, status, from)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPaymentRepository) CancelPendingPayments(ctx context.Context, orderID int64) error {
+	args := m.Called(orderID)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) ReplacePendingPayments(ctx context.Context, orderID int64, payments []*entity.Payment) error {
+	args := m.Called(orderID, payments)
+	return args.Error(0)
+}
+
+func (m *MockPaymentRepository) GetSuccessfulPaymentsByDateRange(ctx context.Context, start, end time.Time) ([]entity.Payment, error) {
+	args := m.Called(start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.Payment), args.Error(1)
+}
+
+func (m *MockPaymentRepository) GetPendingPayment(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func TestPaymentUseCase_GetPaymentByOrderID(t *testing.T) {
+func TestPaymentUseCase_GetPendingPayment(t *testing.T) {
 	logger := logrus.New()
-	mockPaymentRepo := new(MockPaymentRepository)
-	mockCache := new(database.MockRedisCacheService)
-	useCase := NewPaymentUseCase("http://test.com", mockPaymentRepo, logger, "test", mockCache)
+	order := &entity.Order{ID: 1, TotalPrice: 100000}
+	payments := []entity.Payment{
+		{ID: 1, OrderID: 1, Amount: 100000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusCancelled, PaymentURL: "https://pay.example/checkout"},
+		{ID: 2, OrderID: 1, Amount: 50000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusPending, PaymentURL: "https://pay.example/split-1"},
+		{ID: 3, OrderID: 1, Amount: 50000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusPending, PaymentURL: "https://pay.example/split-2"},
+	}
 
-	t.Run("success", func(t *testing.T) {
-		expectedPayment := &entity.Payment{
-			ID:      1,
-			OrderID: 1,
-		}
-		order := &entity.Order{ID: 1}
+	newUseCase := func(payments []entity.Payment) PaymentUseCase {
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
-		mockPaymentRepo.On("GetPaymentByOrderID", order.ID).Return(expectedPayment, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return(payments, nil)
+		return NewPaymentUseCase(MidtransConfig{Endpoint: "http://test.com"}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+	}
+
+	t.Run("returns the requested split", func(t *testing.T) {
+		payment, err := newUseCase(payments).GetPendingPayment(context.Background(), order, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://pay.example/split-2", payment.PaymentURL)
+	})
+
+	t.Run("split bill needs a payment id", func(t *testing.T) {
+		payment, err := newUseCase(payments).GetPendingPayment(context.Background(), order, 0)
+
+		assert.ErrorIs(t, err, constants.ErrPaymentIDRequired)
+		assert.Nil(t, payment)
+	})
+
+	t.Run("single pending payment needs no id", func(t *testing.T) {
+		payment, err := newUseCase(payments[:2]).GetPendingPayment(context.Background(), order, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), payment.ID)
+	})
+
+	t.Run("settled split is not found", func(t *testing.T) {
+		payment, err := newUseCase(payments).GetPendingPayment(context.Background(), order, 1)
+
+		assert.ErrorIs(t, err, constants.ErrNotFound)
+		assert.Nil(t, payment)
+	})
+}
+
+func TestPaymentUseCase_UpdateGatewayPaymentStatus(t *testing.T) {
+	logger := logrus.New()
+	pendingOnly := []constants.PaymentStatus{constants.PaymentStatusPending}
+
+	t.Run("settles a pending payment", func(t *testing.T) {
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: "http://test.com"}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		mockPaymentRepo.On("GetPaymentByGatewayOrderID", "ORDER-1-a").Return(&entity.Payment{ID: 4, OrderID: 1, Status: constants.PaymentStatusPending}, nil).Once()
+		mockPaymentRepo.On("TransitionPaymentStatus", int64(4), constants.PaymentStatusSuccess, pendingOnly).Return(true, nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+		payment, err := useCase.UpdateGatewayPaymentStatus(context.Background(), "ORDER-1-a", constants.PaymentStatusSuccess)
 
-		payment, err := useCase.GetPaymentByOrderID(order)
+		assert.NoError(t, err)
+		assert.Equal(t, constants.PaymentStatusSuccess, payment.Status)
+		mockPaymentRepo.AssertExpectations(t)
+	})
+
+	t.Run("late notification does not undo a settled payment", func(t *testing.T) {
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: "http://test.com"}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		mockPaymentRepo.On("GetPaymentByGatewayOrderID", "ORDER-1-a").Return(&entity.Payment{ID: 4, OrderID: 1, Status: constants.PaymentStatusSuccess}, nil).Once()
+		mockPaymentRepo.On("TransitionPaymentStatus", int64(4), constants.PaymentStatusExpired, pendingOnly).Return(false, nil).Once()
+
+		payment, err := useCase.UpdateGatewayPaymentStatus(context.Background(), "ORDER-1-a", constants.PaymentStatusExpired)
 
 		assert.NoError(t, err)
-		assert.NotNil(t, payment)
-		assert.Equal(t, expectedPayment.ID, payment.ID)
+		assert.Equal(t, constants.PaymentStatusSuccess, payment.Status)
 		mockPaymentRepo.AssertExpectations(t)
+		mockCache.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("pending notification is ignored", func(t *testing.T) {
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: "http://test.com"}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		mockPaymentRepo.On("GetPaymentByGatewayOrderID", "ORDER-1-a").Return(&entity.Payment{ID: 4, OrderID: 1, Status: constants.PaymentStatusSuccess}, nil).Once()
+
+		payment, err := useCase.UpdateGatewayPaymentStatus(context.Background(), "ORDER-1-a", constants.PaymentStatusPending)
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.PaymentStatusSuccess, payment.Status)
+		mockPaymentRepo.AssertNotCalled(t, "TransitionPaymentStatus", mock.Anything, mock.Anything, mock.Anything)
 	})
+}
+
+func TestPaymentUseCase_CreateSplitPayments(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("even split gives the remainder to the last payment", func(t *testing.T) {
+		snap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"token":"snap-token","redirect_url":"https://pay.example/snap"}`))
+		}))
+		defer snap.Close()
 
-	t.Run("not found", func(t *testing.T) {
-		order := &entity.Order{ID: 1}
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: snap.URL}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 100000}
+		var created []*entity.Payment
+		mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{}, nil)
+		mockPaymentRepo.On("ReplacePendingPayments", order.ID, mock.Anything).Run(func(args mock.Arguments) {
+			created = args.Get(1).([]*entity.Payment)
+		}).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
-		mockPaymentRepo.On("GetPaymentByOrderID", order.ID).Return(nil, errors.New("not found")).Once()
+		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		payment, err := useCase.GetPaymentByOrderID(order)
+		request := &model.SplitPaymentRequest{
+			Mode: constants.SplitModeEven,
+			Splits: []model.PaymentSplitRequest{
+				{Method: constants.PaymentMethodMidtrans},
+				{Method: constants.PaymentMethodMidtrans},
+				{Method: constants.PaymentMethodCash},
+			},
+		}
+		summary, err := useCase.CreateSplitPayments(context.Background(), order, request)
 
-		assert.Error(t, err)
-		assert.Nil(t, payment)
+		assert.NoError(t, err)
+		assert.Equal(t, order.TotalPrice, summary.TotalAmount)
+		assert.Len(t, created, 3)
+		assert.Equal(t, 33333.0, created[0].Amount)
+		assert.Equal(t, 33334.0, created[2].Amount)
+		assert.Equal(t, "https://pay.example/snap", created[0].PaymentURL)
+		assert.Contains(t, created[1].GatewayOrderID, "ORDER-1-")
+		assert.Empty(t, created[2].PaymentURL)
 		mockPaymentRepo.AssertExpectations(t)
 	})
+
+	t.Run("custom amounts cannot exceed the outstanding balance", func(t *testing.T) {
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: "http://test.com"}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 2, Status: entity.OrderStatusPending, TotalPrice: 50000}
+		mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{
+			{ID: 1, OrderID: 2, Amount: 20000, Status: constants.PaymentStatusSuccess},
+		}, nil).Once()
+
+		request := &model.SplitPaymentRequest{
+			Mode: constants.SplitModeCustom,
+			Splits: []model.PaymentSplitRequest{
+				{Method: constants.PaymentMethodCash, Amount: 20000},
+				{Method: constants.PaymentMethodCash, Amount: 15000},
+			},
+		}
+		summary, err := useCase.CreateSplitPayments(context.Background(), order, request)
+
+		assert.ErrorIs(t, err, constants.ErrSplitExceedsOutstanding)
+		assert.Nil(t, summary)
+		mockPaymentRepo.AssertNotCalled(t, "ReplacePendingPayments", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed payment link keeps the previous split", func(t *testing.T) {
+		snap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer snap.Close()
+
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: snap.URL}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 4, Status: entity.OrderStatusPending, TotalPrice: 60000}
+		mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{}, nil).Once()
+
+		request := &model.SplitPaymentRequest{
+			Mode: constants.SplitModeEven,
+			Splits: []model.PaymentSplitRequest{
+				{Method: constants.PaymentMethodCash},
+				{Method: constants.PaymentMethodMidtrans},
+			},
+		}
+		summary, err := useCase.CreateSplitPayments(context.Background(), order, request)
+
+		assert.Error(t, err)
+		assert.Nil(t, summary)
+		mockPaymentRepo.AssertNotCalled(t, "ReplacePendingPayments", mock.Anything, mock.Anything)
+	})
+
+	t.Run("item split rejects items claimed twice", func(t *testing.T) {
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPaymentUseCase(MidtransConfig{Endpoint: "http://test.com"}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+		order := &entity.Order{
+			ID:         3,
+			Status:     entity.OrderStatusPending,
+			TotalPrice: 70000,
+			Items: []entity.OrderItem{
+				{ID: 10, Quantity: 2, Price: 20000},
+				{ID: 11, Quantity: 1, Price: 30000},
+			},
+		}
+		mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{}, nil)
+
+		request := &model.SplitPaymentRequest{
+			Mode: constants.SplitModeItem,
+			Splits: []model.PaymentSplitRequest{
+				{Method: constants.PaymentMethodCash, ItemIDs: []int64{10, 11}},
+				{Method: constants.PaymentMethodCash, ItemIDs: []int64{11}},
+			},
+		}
+		_, err := useCase.CreateSplitPayments(context.Background(), order, request)
+		assert.ErrorIs(t, err, constants.ErrInvalidSplit)
+
+		amounts, err := splitAmounts(order, &model.SplitPaymentRequest{
+			Mode: constants.SplitModeItem,
+			Splits: []model.PaymentSplitRequest{
+				{Method: constants.PaymentMethodCash, ItemIDs: []int64{10}},
+				{Method: constants.PaymentMethodMidtrans, ItemIDs: []int64{11}},
+			},
+		}, 70000)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{40000, 30000}, amounts)
+	})
+}
+
+func TestPaymentUseCase_SplitOrderAfterCheckout(t *testing.T) {
+	logger := logrus.New()
+	snap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":"snap-token","redirect_url":"https://pay.example/snap"}`))
+	}))
+	defer snap.Close()
+
+	mockOrderRepo := new(MockOrderRepository)
+	mockMenuRepo := new(MockMenuRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockCache := new(database.MockRedisCacheService)
+	orderUseCase := NewOrderUseCase(mockOrderRepo, mockMenuRepo, mockCustomerRepo, logger, "test", mockCache, metrics.Noop{})
+	paymentUseCase := NewPaymentUseCase(MidtransConfig{Endpoint: snap.URL}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+
+	mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7, Role: constants.RoleAdmin}, nil)
+	mockMenuRepo.On("GetByID", int64(5)).Return(&entity.Menu{ID: 5, Price: 45000}, nil)
+	mockOrderRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*entity.Order).ID = 11
+	}).Return(nil).Once()
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
+	mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var checkout *entity.Payment
+	mockPaymentRepo.On("CreatePayment", mock.Anything).Run(func(args mock.Arguments) {
+		checkout = args.Get(0).(*entity.Payment)
+		checkout.ID = 1
+	}).Return(nil).Once()
+
+	order, err := orderUseCase.CreateOrder(context.Background(), 7, &model.CreateOrderRequest{
+		Items: []model.OrderItemRequest{{MenuID: 5, Title: "Tiramisu", Quantity: 2}},
+	})
+	assert.NoError(t, err)
+	_, err = paymentUseCase.CreatePaymentURL(context.Background(), order)
+	assert.NoError(t, err)
+	assert.Equal(t, constants.PaymentStatusPending, checkout.Status)
+
+	// The unpaid checkout link is replaced by the split
+	mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{*checkout}, nil).Once()
+	var splits []*entity.Payment
+	mockPaymentRepo.On("ReplacePendingPayments", order.ID, mock.Anything).Run(func(args mock.Arguments) {
+		splits = args.Get(1).([]*entity.Payment)
+	}).Return(nil).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{*checkout}, nil)
+
+	_, err = paymentUseCase.CreateSplitPayments(context.Background(), order, &model.SplitPaymentRequest{
+		Mode:   constants.SplitModeEven,
+		Splits: []model.PaymentSplitRequest{{Method: constants.PaymentMethodCash}, {Method: constants.PaymentMethodCash}},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, splits, 2)
+	assert.Equal(t, 45000.0, splits[0].Amount)
+	assert.Equal(t, 45000.0, splits[1].Amount)
+	mockPaymentRepo.AssertExpectations(t)
+}
+
+func TestMidtransConfig_Check(t *testing.T) {
+	sandbox := "https://app.sandbox.midtrans.com/snap/v1/transactions"
+	production := "https://app.midtrans.com/snap/v1/transactions"
+
+	assert.NoError(t, MidtransConfig{Endpoint: sandbox, ServerKey: "SB-Mid-server-abc"}.Check(context.Background()))
+	assert.NoError(t, MidtransConfig{Endpoint: production, ServerKey: "Mid-server-abc"}.Check(context.Background()))
+
+	assert.ErrorContains(t, MidtransConfig{Endpoint: sandbox}.Check(context.Background()), "MIDTRANS_SERVER_KEY is not set")
+	assert.ErrorContains(t, MidtransConfig{Endpoint: "app.midtrans.com", ServerKey: "Mid-server-abc"}.Check(context.Background()), "not an http(s) URL")
+	assert.ErrorContains(t, MidtransConfig{Endpoint: production, ServerKey: "SB-Mid-server-abc"}.Check(context.Background()), "same environment")
 }
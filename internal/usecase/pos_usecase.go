@@ -0,0 +1,349 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"cakestore/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// POSUseCase records payments a cashier takes in person. Nothing here talks
+// to Midtrans, so the till keeps working when the gateway is down. Cash can
+// only be taken while the cashier has an open drawer shift.
+type POSUseCase interface {
+	PayOrder(ctx context.Context, cashierID int64, orderID int64, request *model.POSPaymentRequest) (*model.ReceiptResponse, error)
+	PayTableSession(ctx context.Context, cashierID int64, sessionID int64, request *model.POSPaymentRequest) (*model.ReceiptResponse, error)
+	// SettlePayment records a pending cash split as collected by the cashier,
+	// on a receipt of their open shift.
+	SettlePayment(ctx context.Context, cashierID int64, paymentID int64) (*model.ReceiptResponse, error)
+	GetReceipt(ctx context.Context, number string) (*model.ReceiptResponse, error)
+}
+
+type posUseCase struct {
+	receiptRepo  repository.ReceiptRepository
+	shiftRepo    repository.ShiftRepository
+	paymentRepo  repository.PaymentRepository
+	orderRepo    repository.OrderRepository
+	customerRepo repository.CustomerRepository
+	log          *logrus.Logger
+	cache        database.RedisCache
+	metrics      metrics.Business
+}
+
+func NewPOSUseCase(
+	receiptRepo repository.ReceiptRepository,
+	shiftRepo repository.ShiftRepository,
+	paymentRepo repository.PaymentRepository,
+	orderRepo repository.OrderRepository,
+	customerRepo repository.CustomerRepository,
+	log *logrus.Logger,
+	cache database.RedisCache,
+	recorder metrics.Business,
+) POSUseCase {
+	return &posUseCase{
+		receiptRepo:  receiptRepo,
+		shiftRepo:    shiftRepo,
+		paymentRepo:  paymentRepo,
+		orderRepo:    orderRepo,
+		customerRepo: customerRepo,
+		log:          log,
+		cache:        cache,
+		metrics:      recorder,
+	}
+}
+
+func (u *posUseCase) PayOrder(ctx context.Context, cashierID int64, orderID int64, request *model.POSPaymentRequest) (*model.ReceiptResponse, error) {
+	ctx, span := tracer.Start(ctx, "POSUseCase.PayOrder")
+	defer span.End()
+
+	order, err := u.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == entity.OrderStatusCancelled {
+		return nil, constants.ErrOrderNotPayable
+	}
+
+	return u.collect(ctx, cashierID, nil, []entity.Order{*order}, request)
+}
+
+// PayTableSession settles the orders of a table session oldest first, so a
+// partial payment covers whole orders before it starts on the next one.
+func (u *posUseCase) PayTableSession(ctx context.Context, cashierID int64, sessionID int64, request *model.POSPaymentRequest) (*model.ReceiptResponse, error) {
+	ctx, span := tracer.Start(ctx, "POSUseCase.PayTableSession")
+	defer span.End()
+
+	orders, err := u.orderRepo.GetByTableSessionID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	payable := make([]entity.Order, 0, len(orders))
+	for _, order := range orders {
+		if order.Status != entity.OrderStatusCancelled {
+			payable = append(payable, order)
+		}
+	}
+	if len(payable) == 0 {
+		return nil, constants.ErrNotFound
+	}
+
+	return u.collect(ctx, cashierID, &sessionID, payable, request)
+}
+
+func (u *posUseCase) SettlePayment(ctx context.Context, cashierID int64, paymentID int64) (*model.ReceiptResponse, error) {
+	ctx, span := tracer.Start(ctx, "POSUseCase.SettlePayment")
+	defer span.End()
+
+	payment, err := u.paymentRepo.GetPaymentByID(ctx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.Method != constants.PaymentMethodCash || payment.Status != constants.PaymentStatusPending {
+		return nil, constants.ErrInvalidPaymentStatus
+	}
+
+	cashier, err := u.customerRepo.GetByID(ctx, cashierID)
+	if err != nil {
+		return nil, err
+	}
+	shift, err := u.shiftRepo.GetOpenByCashierID(ctx, cashierID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := u.orderRepo.GetByID(ctx, payment.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := u.paymentRepo.GetPaymentsByOrderID(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	var settledIDs []int64
+	if sumPayments(payments, constants.PaymentStatusSuccess)+payment.Amount >= math.Round(order.TotalPrice) {
+		settledIDs = []int64{order.ID}
+	}
+
+	payment.Status = constants.PaymentStatusSuccess
+	receipt := &entity.Receipt{
+		Number:         newReceiptNumber(),
+		CashierID:      cashier.ID,
+		Cashier:        *cashier,
+		ShiftID:        &shift.ID,
+		TableSessionID: order.TableSessionID,
+		Method:         constants.PaymentMethodCash,
+		Amount:         payment.Amount,
+		AmountTendered: payment.Amount,
+		Payments:       []entity.Payment{*payment},
+	}
+	if err := u.receiptRepo.SettlePayment(ctx, receipt, payment.ID, settledIDs); err != nil {
+		u.log.Errorf("Error settling payment ID %d: %v", payment.ID, err)
+		return nil, err
+	}
+
+	if len(settledIDs) > 0 && order.Status != entity.OrderStatusPaid {
+		u.metrics.OrderPaid(orderMetrics(order))
+	}
+	invalidateOrderCache(ctx, u.cache, u.log, order)
+
+	u.log.Infof("Receipt %s: cash split %d of %.0f settled by cashier %d", receipt.Number, payment.ID, payment.Amount, cashier.ID)
+	return model.ToReceiptResponse(receipt, []entity.Order{*order}), nil
+}
+
+func (u *posUseCase) GetReceipt(ctx context.Context, number string) (*model.ReceiptResponse, error) {
+	ctx, span := tracer.Start(ctx, "POSUseCase.GetReceipt")
+	defer span.End()
+
+	// Receipts never change, so they can be cached for longer than usual
+	cacheKey := fmt.Sprintf("receipt:%s", number)
+	opts := database.LoadOptions{TTL: time.Hour, Jitter: cacheJitter}
+	return database.GetOrLoad(ctx, u.cache, cacheKey, opts, func() (*model.ReceiptResponse, error) {
+		receipt, err := u.receiptRepo.GetByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+
+		orders, err := u.receiptOrders(ctx, receipt)
+		if err != nil {
+			return nil, err
+		}
+		return model.ToReceiptResponse(receipt, orders), nil
+	})
+}
+
+func (u *posUseCase) collect(ctx context.Context, cashierID int64, sessionID *int64, orders []entity.Order, request *model.POSPaymentRequest) (*model.ReceiptResponse, error) {
+	cashier, err := u.customerRepo.GetByID(ctx, cashierID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shiftID *int64
+	shift, err := u.shiftRepo.GetOpenByCashierID(ctx, cashierID)
+	switch {
+	case err == nil:
+		shiftID = &shift.ID
+	case errors.Is(err, constants.ErrNoOpenShift):
+		if request.Method == constants.PaymentMethodCash {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	outstanding := make([]float64, len(orders))
+	reserved := make([]float64, len(orders))
+	var totalOutstanding, totalReserved float64
+	for i, order := range orders {
+		payments, err := u.paymentRepo.GetPaymentsByOrderID(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		balance := math.Max(math.Round(order.TotalPrice-sumPayments(payments, constants.PaymentStatusSuccess)), 0)
+		reserved[i] = math.Min(reservedBySplits(payments, balance), balance)
+		outstanding[i] = balance - reserved[i]
+		totalOutstanding += outstanding[i]
+		totalReserved += reserved[i]
+	}
+	if totalOutstanding == 0 {
+		if totalReserved > 0 {
+			return nil, constants.ErrBalanceAwaitingSplits
+		}
+		return nil, constants.ErrOrderAlreadyPaid
+	}
+
+	amount := math.Round(request.Amount)
+	if amount == 0 {
+		amount = totalOutstanding
+	}
+	if amount > totalOutstanding {
+		return nil, constants.ErrAmountExceedsOutstanding
+	}
+
+	receipt := &entity.Receipt{
+		Number:         newReceiptNumber(),
+		CashierID:      cashier.ID,
+		Cashier:        *cashier,
+		ShiftID:        shiftID,
+		TableSessionID: sessionID,
+		Method:         request.Method,
+		Amount:         amount,
+		AmountTendered: amount,
+		Reference:      request.Reference,
+	}
+	if request.Method == constants.PaymentMethodCash {
+		if request.AmountTendered < amount {
+			return nil, constants.ErrInsufficientTender
+		}
+		receipt.AmountTendered = request.AmountTendered
+		receipt.ChangeGiven = request.AmountTendered - amount
+	}
+
+	settled := make([]*entity.Order, 0, len(orders))
+	remaining := amount
+	for i, order := range orders {
+		if remaining == 0 {
+			break
+		}
+		part := math.Min(remaining, outstanding[i])
+		if part == 0 {
+			continue
+		}
+		receipt.Payments = append(receipt.Payments, entity.Payment{
+			OrderID:     order.ID,
+			Amount:      part,
+			Method:      request.Method,
+			Status:      constants.PaymentStatusSuccess,
+			Reference:   request.Reference,
+			Description: "Paid at till, receipt " + receipt.Number,
+		})
+		remaining -= part
+		// An order with splits still pending is paid once they settle
+		if part == outstanding[i] && reserved[i] == 0 {
+			settled = append(settled, &orders[i])
+		}
+	}
+
+	settledIDs := make([]int64, 0, len(settled))
+	for _, order := range settled {
+		settledIDs = append(settledIDs, order.ID)
+	}
+	// Gateway links issued for the bill are no longer needed once the till
+	// has covered it; the repository cancels them with the receipt
+	if err := u.receiptRepo.Create(ctx, receipt, settledIDs); err != nil {
+		u.log.Errorf("Error recording receipt %s: %v", receipt.Number, err)
+		return nil, err
+	}
+
+	for _, order := range settled {
+		if order.Status != entity.OrderStatusPaid {
+			u.metrics.OrderPaid(orderMetrics(order))
+		}
+	}
+	for _, order := range orders {
+		invalidateOrderCache(ctx, u.cache, u.log, &order)
+	}
+
+	u.log.Infof("Receipt %s: %s payment of %.0f by cashier %d", receipt.Number, receipt.Method, receipt.Amount, cashier.ID)
+	return model.ToReceiptResponse(receipt, orders), nil
+}
+
+func (u *posUseCase) receiptOrders(ctx context.Context, receipt *entity.Receipt) ([]entity.Order, error) {
+	orders := make([]entity.Order, 0, len(receipt.Payments))
+	seen := make(map[int64]bool, len(receipt.Payments))
+	for _, payment := range receipt.Payments {
+		if seen[payment.OrderID] {
+			continue
+		}
+		seen[payment.OrderID] = true
+
+		order, err := u.orderRepo.GetByID(ctx, payment.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	return orders, nil
+}
+
+// reservedBySplits is the part of the balance held by pending split payments,
+// which the till must not collect a second time. A single pending link for the
+// whole balance is the online checkout, which the till replaces instead.
+func reservedBySplits(payments []entity.Payment, balance float64) float64 {
+	var pending []entity.Payment
+	for _, payment := range payments {
+		if payment.Status == constants.PaymentStatusPending {
+			pending = append(pending, payment)
+		}
+	}
+	if len(pending) == 1 && math.Round(pending[0].Amount) >= balance {
+		return 0
+	}
+	return sumPayments(pending, constants.PaymentStatusPending)
+}
+
+// invalidateOrderCache drops every cached view of an order whose payments changed
+func invalidateOrderCache(ctx context.Context, cache database.RedisCache, log *logrus.Logger, order *entity.Order) {
+	invalidateOrderViews(ctx, cache, log, order.ID)
+	key := fmt.Sprintf("payments:order:%d", order.ID)
+	if err := cache.Delete(ctx, key); err != nil {
+		log.Errorf("Error deleting cache %s: %v", key, err)
+	}
+}
+
+// newReceiptNumber returns a human friendly number such as RCPT-20250612-3F2A9C1B
+func newReceiptNumber() string {
+	suffix := strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:8])
+	return fmt.Sprintf("RCPT-%s-%s", time.Now().Format("20060102"), suffix)
+}
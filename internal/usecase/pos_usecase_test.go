@@ -0,0 +1,374 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockReceiptRepository struct {
+	mock.Mock
+}
+
+func (m *MockReceiptRepository) Create(ctx context.Context, receipt *entity.Receipt, settledOrderIDs []int64) error {
+	args := m.Called(receipt, settledOrderIDs)
+	return args.Error(0)
+}
+
+func (m *MockReceiptRepository) SettlePayment(ctx context.Context, receipt *entity.Receipt, paymentID int64, settledOrderIDs []int64) error {
+	args := m.Called(receipt, paymentID, settledOrderIDs)
+	return args.Error(0)
+}
+
+func (m *MockReceiptRepository) GetByNumber(ctx context.Context, number string) (*entity.Receipt, error) {
+	args := m.Called(number)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Receipt), args.Error(1)
+}
+
+func (m *MockReceiptRepository) GetByShiftID(ctx context.Context, shiftID int64) ([]entity.Receipt, error) {
+	args := m.Called(shiftID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.Receipt), args.Error(1)
+}
+
+func TestPOSUseCase_PayOrder(t *testing.T) {
+	logger := logrus.New()
+	cashier := &entity.Customer{ID: 9, Name: "Cashier", Role: constants.RoleCashier}
+
+	t.Run("cash payment returns change and marks the order paid", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockOrderRepo := new(MockOrderRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 1, CustomerID: 2, Status: entity.OrderStatusPending, TotalPrice: 45000}
+		mockOrderRepo.On("GetByID", int64(1)).Return(order, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 3}, nil).Once()
+		mockPaymentRepo.On("GetPaymentsByOrderID", int64(1)).Return([]entity.Payment{}, nil).Once()
+		mockReceiptRepo.On("Create", mock.MatchedBy(func(r *entity.Receipt) bool {
+			return len(r.Payments) == 1 && r.Payments[0].Amount == 45000 && r.Payments[0].Status == constants.PaymentStatusSuccess &&
+				r.ShiftID != nil && *r.ShiftID == 3
+		}), []int64{1}).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+		receipt, err := useCase.PayOrder(context.Background(), 9, 1, &model.POSPaymentRequest{
+			Method:         constants.PaymentMethodCash,
+			AmountTendered: 50000,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 45000.0, receipt.Amount)
+		assert.Equal(t, 5000.0, receipt.ChangeGiven)
+		assert.Equal(t, "Cashier", receipt.Cashier)
+		mockReceiptRepo.AssertExpectations(t)
+		mockOrderRepo.AssertExpectations(t)
+		mockPaymentRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects insufficient cash", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockOrderRepo := new(MockOrderRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 45000}
+		mockOrderRepo.On("GetByID", int64(1)).Return(order, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 3}, nil).Once()
+		mockPaymentRepo.On("GetPaymentsByOrderID", int64(1)).Return([]entity.Payment{}, nil).Once()
+
+		receipt, err := useCase.PayOrder(context.Background(), 9, 1, &model.POSPaymentRequest{
+			Method:         constants.PaymentMethodCash,
+			AmountTendered: 40000,
+		})
+
+		assert.ErrorIs(t, err, constants.ErrInsufficientTender)
+		assert.Nil(t, receipt)
+		mockReceiptRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cash needs an open shift", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockOrderRepo := new(MockOrderRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 45000}
+		mockOrderRepo.On("GetByID", int64(1)).Return(order, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(nil, constants.ErrNoOpenShift).Once()
+
+		receipt, err := useCase.PayOrder(context.Background(), 9, 1, &model.POSPaymentRequest{
+			Method:         constants.PaymentMethodCash,
+			AmountTendered: 50000,
+		})
+
+		assert.ErrorIs(t, err, constants.ErrNoOpenShift)
+		assert.Nil(t, receipt)
+	})
+
+	t.Run("pending splits are left to their payers", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockOrderRepo := new(MockOrderRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 60000}
+		mockOrderRepo.On("GetByID", int64(1)).Return(order, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 3}, nil).Once()
+		mockPaymentRepo.On("GetPaymentsByOrderID", int64(1)).Return([]entity.Payment{
+			{ID: 2, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusSuccess},
+			{ID: 3, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusPending},
+			{ID: 4, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodCash, Status: constants.PaymentStatusPending},
+		}, nil).Once()
+
+		receipt, err := useCase.PayOrder(context.Background(), 9, 1, &model.POSPaymentRequest{
+			Method:         constants.PaymentMethodCash,
+			AmountTendered: 50000,
+		})
+
+		assert.ErrorIs(t, err, constants.ErrBalanceAwaitingSplits)
+		assert.Nil(t, receipt)
+		mockReceiptRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("collects only what pending splits do not cover", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockOrderRepo := new(MockOrderRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+		order := &entity.Order{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 60000}
+		mockOrderRepo.On("GetByID", int64(1)).Return(order, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 3}, nil).Once()
+		mockPaymentRepo.On("GetPaymentsByOrderID", int64(1)).Return([]entity.Payment{
+			{ID: 3, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusPending},
+			{ID: 4, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusPending},
+		}, nil).Once()
+		// The order stays open until the remaining split is paid
+		mockReceiptRepo.On("Create", mock.MatchedBy(func(r *entity.Receipt) bool {
+			return r.Amount == 20000 && len(r.Payments) == 1
+		}), []int64{}).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+		receipt, err := useCase.PayOrder(context.Background(), 9, 1, &model.POSPaymentRequest{
+			Method:         constants.PaymentMethodCash,
+			AmountTendered: 20000,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 20000.0, receipt.Amount)
+		mockReceiptRepo.AssertExpectations(t)
+	})
+}
+
+func TestPOSUseCase_PayTableSession(t *testing.T) {
+	logger := logrus.New()
+	mockReceiptRepo := new(MockReceiptRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockOrderRepo := new(MockOrderRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockShiftRepo := new(MockShiftRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+	sessionID := int64(4)
+	orders := []entity.Order{
+		{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 30000, TableSessionID: &sessionID},
+		{ID: 2, Status: entity.OrderStatusPending, TotalPrice: 20000, TableSessionID: &sessionID},
+	}
+	mockOrderRepo.On("GetByTableSessionID", sessionID).Return(orders, nil).Once()
+	mockCustomerRepo.On("GetByID", int64(9)).Return(&entity.Customer{ID: 9}, nil).Once()
+	mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(nil, constants.ErrNoOpenShift).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", int64(1)).Return([]entity.Payment{
+		{OrderID: 1, Amount: 10000, Status: constants.PaymentStatusSuccess},
+	}, nil).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", int64(2)).Return([]entity.Payment{}, nil).Once()
+	var created *entity.Receipt
+	mockReceiptRepo.On("Create", mock.Anything, []int64{1}).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*entity.Receipt)
+	}).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	// 25000 covers the 20000 left on the first order and part of the second
+	receipt, err := useCase.PayTableSession(context.Background(), 9, sessionID, &model.POSPaymentRequest{
+		Method:    constants.PaymentMethodCard,
+		Amount:    25000,
+		Reference: "TERM-0001",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 25000.0, receipt.Amount)
+	assert.Equal(t, 0.0, receipt.ChangeGiven)
+	assert.Len(t, created.Payments, 2)
+	assert.Equal(t, 20000.0, created.Payments[0].Amount)
+	assert.Equal(t, 5000.0, created.Payments[1].Amount)
+	assert.Equal(t, &sessionID, created.TableSessionID)
+	mockReceiptRepo.AssertExpectations(t)
+	mockPaymentRepo.AssertExpectations(t)
+}
+
+func TestPOSUseCase_SettlePayment(t *testing.T) {
+	logger := logrus.New()
+	cashier := &entity.Customer{ID: 9, Name: "Cashier", Role: constants.RoleCashier}
+
+	t.Run("the last cash split settles the order on the open shift", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockOrderRepo := new(MockOrderRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+		split := &entity.Payment{ID: 5, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodCash, Status: constants.PaymentStatusPending}
+		mockPaymentRepo.On("GetPaymentByID", int64(5)).Return(split, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 3}, nil).Once()
+		mockOrderRepo.On("GetByID", int64(1)).Return(&entity.Order{ID: 1, Status: entity.OrderStatusPending, TotalPrice: 45000}, nil).Once()
+		mockPaymentRepo.On("GetPaymentsByOrderID", int64(1)).Return([]entity.Payment{
+			{ID: 4, OrderID: 1, Amount: 25000, Status: constants.PaymentStatusSuccess},
+			*split,
+		}, nil).Once()
+		mockReceiptRepo.On("SettlePayment", mock.MatchedBy(func(r *entity.Receipt) bool {
+			return r.ShiftID != nil && *r.ShiftID == 3 && r.Method == constants.PaymentMethodCash && r.Amount == 20000
+		}), int64(5), []int64{1}).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+		receipt, err := useCase.SettlePayment(context.Background(), 9, 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 20000.0, receipt.Amount)
+		assert.Equal(t, 20000.0, receipt.Orders[0].AmountPaid)
+		mockReceiptRepo.AssertExpectations(t)
+	})
+
+	t.Run("needs an open shift", func(t *testing.T) {
+		mockReceiptRepo := new(MockReceiptRepository)
+		mockPaymentRepo := new(MockPaymentRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockShiftRepo := new(MockShiftRepository)
+		useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, nil, mockCustomerRepo, logger, nil, metrics.Noop{})
+
+		mockPaymentRepo.On("GetPaymentByID", int64(5)).Return(&entity.Payment{
+			ID: 5, OrderID: 1, Amount: 20000, Method: constants.PaymentMethodCash, Status: constants.PaymentStatusPending,
+		}, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(9)).Return(cashier, nil).Once()
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(nil, constants.ErrNoOpenShift).Once()
+
+		receipt, err := useCase.SettlePayment(context.Background(), 9, 5)
+
+		assert.ErrorIs(t, err, constants.ErrNoOpenShift)
+		assert.Nil(t, receipt)
+		mockReceiptRepo.AssertNotCalled(t, "SettlePayment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("refuses gateway payments", func(t *testing.T) {
+		mockPaymentRepo := new(MockPaymentRepository)
+		useCase := NewPOSUseCase(nil, nil, mockPaymentRepo, nil, nil, logger, nil, metrics.Noop{})
+
+		mockPaymentRepo.On("GetPaymentByID", int64(6)).Return(&entity.Payment{
+			ID: 6, OrderID: 1, Method: constants.PaymentMethodMidtrans, Status: constants.PaymentStatusPending,
+		}, nil).Once()
+
+		receipt, err := useCase.SettlePayment(context.Background(), 9, 6)
+
+		assert.ErrorIs(t, err, constants.ErrInvalidPaymentStatus)
+		assert.Nil(t, receipt)
+	})
+}
+
+func TestPOSUseCase_PayOrderAfterCheckout(t *testing.T) {
+	logger := logrus.New()
+	snap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":"snap-token","redirect_url":"https://pay.example/snap"}`))
+	}))
+	defer snap.Close()
+
+	mockReceiptRepo := new(MockReceiptRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	mockOrderRepo := new(MockOrderRepository)
+	mockMenuRepo := new(MockMenuRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockShiftRepo := new(MockShiftRepository)
+	mockCache := new(database.MockRedisCacheService)
+	orderUseCase := NewOrderUseCase(mockOrderRepo, mockMenuRepo, mockCustomerRepo, logger, "test", mockCache, metrics.Noop{})
+	paymentUseCase := NewPaymentUseCase(MidtransConfig{Endpoint: snap.URL}, mockPaymentRepo, logger, "test", mockCache, metrics.Noop{})
+	useCase := NewPOSUseCase(mockReceiptRepo, mockShiftRepo, mockPaymentRepo, mockOrderRepo, mockCustomerRepo, logger, mockCache, metrics.Noop{})
+
+	mockCustomerRepo.On("GetByID", int64(2)).Return(&entity.Customer{ID: 2, Role: constants.RoleAdmin}, nil)
+	mockCustomerRepo.On("GetByID", int64(9)).Return(&entity.Customer{ID: 9, Name: "Cashier", Role: constants.RoleCashier}, nil)
+	mockMenuRepo.On("GetByID", int64(5)).Return(&entity.Menu{ID: 5, Price: 45000}, nil)
+	mockOrderRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*entity.Order).ID = 11
+	}).Return(nil).Once()
+	var checkout *entity.Payment
+	mockPaymentRepo.On("CreatePayment", mock.Anything).Run(func(args mock.Arguments) {
+		checkout = args.Get(0).(*entity.Payment)
+	}).Return(nil).Once()
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	order, err := orderUseCase.CreateOrder(context.Background(), 2, &model.CreateOrderRequest{
+		Items: []model.OrderItemRequest{{MenuID: 5, Title: "Tiramisu", Quantity: 1}},
+	})
+	assert.NoError(t, err)
+	_, err = paymentUseCase.CreatePaymentURL(context.Background(), order)
+	assert.NoError(t, err)
+
+	// The guest abandons the payment link and pays at the till instead
+	mockOrderRepo.On("GetByID", order.ID).Return(order, nil).Once()
+	mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 3}, nil).Once()
+	mockPaymentRepo.On("GetPaymentsByOrderID", order.ID).Return([]entity.Payment{*checkout}, nil).Once()
+	mockReceiptRepo.On("Create", mock.MatchedBy(func(r *entity.Receipt) bool {
+		return len(r.Payments) == 1 && r.Payments[0].Amount == 45000
+	}), []int64{order.ID}).Return(nil).Once()
+
+	receipt, err := useCase.PayOrder(context.Background(), 9, order.ID, &model.POSPaymentRequest{
+		Method:         constants.PaymentMethodCash,
+		AmountTendered: 50000,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 45000.0, receipt.Amount)
+	assert.Equal(t, 5000.0, receipt.ChangeGiven)
+	mockReceiptRepo.AssertExpectations(t)
+}
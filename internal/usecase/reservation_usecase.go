@@ -1,25 +1,66 @@
 package usecase
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"cakestore/internal/notification"
 	"cakestore/internal/repository"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 type ReservationUseCase interface {
-	Create(customerID uint, request *model.CreateReservationRequest) (*model.ReservationResponse, error)
-	GetByID(id uint) (*model.ReservationResponse, error)
-	GetAll(params *model.ReservationQueryParams) (*model.PaginationResponse[[]model.ReservationResponse], error)
-	AdminGetAllCustomerReservations(params *model.PaginationQuery) (*model.PaginationResponse[[]model.ReservationResponse], error)
-	Update(id uint, request *model.UpdateReservationRequest) (*model.ReservationResponse, error)
-	Delete(id uint) error
+	Create(ctx context.Context, customerID uint, request *model.CreateReservationRequest) (*model.ReservationResponse, error)
+	GetByID(ctx context.Context, id uint) (*model.ReservationResponse, error)
+	GetAll(ctx context.Context, params *model.ReservationQueryParams) (*model.PaginationResponse[[]model.ReservationResponse], error)
+	AdminGetAllCustomerReservations(ctx context.Context, params *model.PaginationQuery) (*model.PaginationResponse[[]model.ReservationResponse], error)
+	Update(ctx context.Context, id uint, request *model.UpdateReservationRequest) (*model.ReservationResponse, error)
+	Delete(ctx context.Context, id uint) error
+	// GetByToken returns the reservation a confirm or cancel link is for
+	// without changing it, so the link can show what it is about to do.
+	GetByToken(ctx context.Context, id uint, action ReservationLinkAction, token string) (*model.ReservationResponse, error)
+	ConfirmByToken(ctx context.Context, id uint, token string) (*model.ReservationResponse, error)
+	CancelByToken(ctx context.Context, id uint, token string) (*model.ReservationResponse, error)
+	// CheckIn records that the party has arrived, which keeps the reservation
+	// from being marked as a no-show.
+	CheckIn(ctx context.Context, id uint) (*model.ReservationResponse, error)
+	GetNoShowSummary(ctx context.Context, customerID uint) (*model.NoShowSummaryResponse, error)
+	ApplyDeposit(ctx context.Context, id uint, request *model.ApplyDepositRequest) (*model.DepositResponse, error)
+	SendReminders(ctx context.Context, now time.Time) (int, error)
+	MarkNoShows(ctx context.Context, now time.Time) (int, error)
+}
+
+// ReservationLinkAction is what a signed reservation link does. Each link is
+// signed for one action, so a confirm link cannot be used to cancel.
+type ReservationLinkAction string
+
+const (
+	ReservationLinkConfirm ReservationLinkAction = "confirm"
+	ReservationLinkCancel  ReservationLinkAction = "cancel"
+)
+
+// ReservationPolicy configures reminders, no-show tracking and the signed
+// confirm/cancel links sent to customers.
+type ReservationPolicy struct {
+	ReminderLead time.Duration
+	NoShowGrace  time.Duration
+	// NoShowLimit blocks new bookings once a customer reaches it; 0 disables the block
+	NoShowLimit int
+	// LinkURL is the public base URL of the API used to build confirm/cancel links
+	LinkURL string
+	Secret  string
 }
 
 type reservationUseCase struct {
@@ -27,6 +68,10 @@ type reservationUseCase struct {
 	tableRepository repository.TableRepository
 	logger          *logrus.Logger
 	cache           database.RedisCache
+	sender          notification.Sender
+	deposits        DepositUseCase
+	metrics         metrics.Business
+	policy          ReservationPolicy
 }
 
 func NewReservationUseCase(
@@ -34,86 +79,86 @@ func NewReservationUseCase(
 	logger *logrus.Logger,
 	tableRepository repository.TableRepository,
 	cache database.RedisCache,
+	sender notification.Sender,
+	deposits DepositUseCase,
+	recorder metrics.Business,
+	policy ReservationPolicy,
 ) ReservationUseCase {
+	if policy.ReminderLead <= 0 {
+		policy.ReminderLead = 24 * time.Hour
+	}
+	if policy.NoShowGrace <= 0 {
+		policy.NoShowGrace = 30 * time.Minute
+	}
+
 	return &reservationUseCase{
 		repo:            repo,
 		logger:          logger,
 		tableRepository: tableRepository,
 		cache:           cache,
+		sender:          sender,
+		deposits:        deposits,
+		metrics:         recorder,
+		policy:          policy,
 	}
 }
 
-func (u *reservationUseCase) AdminGetAllCustomerReservations(params *model.PaginationQuery) (*model.PaginationResponse[[]model.ReservationResponse], error) {
-	start := time.Now()
-	defer func() {
-		u.logger.Infof("AdminGetAllCustomerReservations took %v", time.Since(start))
-	}()
+func (u *reservationUseCase) AdminGetAllCustomerReservations(ctx context.Context, params *model.PaginationQuery) (*model.PaginationResponse[[]model.ReservationResponse], error) {
+	ctx, span := tracer.Start(ctx, "ReservationUseCase.AdminGetAllCustomerReservations")
+	defer span.End()
 
-	// Try to get the reservations from the cache first
 	cacheKey := fmt.Sprintf("reservations:admin:all:page:%d:limit:%d", params.Page, params.Limit)
-	var cachedData model.PaginationResponse[[]model.ReservationResponse]
-	if err := u.cache.Get(context.Background(), cacheKey, &cachedData); err == nil {
-		u.logger.Info("Admin reservations fetched from cache")
-		return &cachedData, nil
-	}
-
-	// If not in cache, get from the database
-	result, err := u.repo.AdminGetAllCustomerReservations(params)
-	if err != nil {
-		return nil, err
-	}
-
-	responses := make([]model.ReservationResponse, len(result.Data))
-	for i, reservation := range result.Data {
-		responses[i] = model.ReservationResponse{
-			ID:           reservation.ID,
-			CustomerID:   reservation.CustomerID,
-			Customer:     *model.ToCustomerResponse(&reservation.Customer),
-			TableNumber:  reservation.TableNumber,
-			GuestCount:   reservation.GuestCount,
-			ReserveDate:  reservation.ReserveDate,
-			Status:       string(reservation.Status),
-			SpecialNotes: reservation.SpecialNotes,
-			CreatedAt:    reservation.CreatedAt,
-			UpdatedAt:    reservation.UpdatedAt,
+	return database.GetOrLoad(ctx, u.cache, cacheKey, listLoad(adminReservationListTag), func() (*model.PaginationResponse[[]model.ReservationResponse], error) {
+		result, err := u.repo.AdminGetAllCustomerReservations(ctx, params)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	paginatedResponse := &model.PaginationResponse[[]model.ReservationResponse]{
-		Data:       responses,
-		Total:      result.Total,
-		Page:       result.Page,
-		PageSize:   result.PageSize,
-		TotalPages: result.TotalPages,
-	}
 
-	// Store the reservations in the cache for future requests
-	if err := u.cache.Set(context.Background(), cacheKey, paginatedResponse, 5*time.Minute); err != nil {
-		u.logger.Errorf("Error setting cache for admin reservations: %v", err)
-	}
+		responses := make([]model.ReservationResponse, len(result.Data))
+		for i, reservation := range result.Data {
+			responses[i] = *model.ToReservationResponse(&reservation)
+		}
 
-	return paginatedResponse, nil
+		paginatedResponse := &model.PaginationResponse[[]model.ReservationResponse]{
+			Data:       responses,
+			Total:      result.Total,
+			Page:       result.Page,
+			PageSize:   result.PageSize,
+			TotalPages: result.TotalPages,
+		}
+		return paginatedResponse, nil
+	})
 }
 
-func (u *reservationUseCase) Create(customerID uint, request *model.CreateReservationRequest) (*model.ReservationResponse, error) {
+func (u *reservationUseCase) Create(ctx context.Context, customerID uint, request *model.CreateReservationRequest) (*model.ReservationResponse, error) {
 	if err := request.Validate(); err != nil {
 		return nil, err
 	}
 
+	if u.policy.NoShowLimit > 0 {
+		noShows, err := u.repo.CountNoShowsByCustomer(ctx, customerID)
+		if err != nil {
+			return nil, err
+		}
+		if noShows >= int64(u.policy.NoShowLimit) {
+			return nil, constants.ErrReservationBlocked
+		}
+	}
+
 	var table *entity.Table
 	var tableNumber int
 
 	// Only get table and set relation if TableID is provided (not zero)
 	if request.TableID != 0 {
 		var err error
-		table, err = u.tableRepository.GetByID(request.TableID)
+		table, err = u.tableRepository.GetByID(ctx, request.TableID)
 		if err != nil {
 			u.logger.Errorf("Error getting table: %v", err)
 			return nil, err
 		}
 
 		// Check table availability
-		isAvailable, err := u.repo.CheckTableAvailability(request.TableID, request.ReserveDate)
+		isAvailable, err := u.repo.CheckTableAvailability(ctx, request.TableID, request.ReserveDate)
 		if err != nil {
 			return nil, err
 		}
@@ -138,124 +183,89 @@ func (u *reservationUseCase) Create(customerID uint, request *model.CreateReserv
 		reservation.TableNumber = tableNumber
 	}
 
-	if err := u.repo.Create(reservation); err != nil {
+	// Large parties and flagged dates stay pending until a deposit is paid.
+	// Decided before the insert so a failed check leaves nothing behind.
+	depositRequired, err := u.deposits.IsRequired(ctx, reservation)
+	if err != nil {
 		return nil, err
 	}
 
-	// Get the created reservation with customer details
-	createdReservation, err := u.repo.GetByID(reservation.ID)
-	if err != nil {
+	if err := u.repo.Create(ctx, reservation); err != nil {
 		return nil, err
 	}
-
-	return &model.ReservationResponse{
-		ID:           createdReservation.ID,
-		CustomerID:   createdReservation.CustomerID,
-		Customer:     *model.ToCustomerResponse(&createdReservation.Customer),
-		TableNumber:  createdReservation.TableNumber,
-		GuestCount:   createdReservation.GuestCount,
-		ReserveDate:  createdReservation.ReserveDate,
-		Status:       string(createdReservation.Status),
-		SpecialNotes: createdReservation.SpecialNotes,
-		CreatedAt:    createdReservation.CreatedAt,
-		UpdatedAt:    createdReservation.UpdatedAt,
-	}, nil
-}
-
-func (u *reservationUseCase) GetByID(id uint) (*model.ReservationResponse, error) {
-	start := time.Now()
-	defer func() {
-		u.logger.Infof("GetByID took %v", time.Since(start))
-	}()
-
-	// Try to get the reservation from the cache first
-	cacheKey := fmt.Sprintf("reservation:%d", id)
-	var reservation model.ReservationResponse
-	if err := u.cache.Get(context.Background(), cacheKey, &reservation); err == nil {
-		u.logger.Info("Reservation fetched from cache")
-		return &reservation, nil
+	if depositRequired {
+		if _, err := u.deposits.Create(ctx, reservation); err != nil {
+			if deleteErr := u.repo.Delete(ctx, reservation.ID); deleteErr != nil {
+				u.logger.Errorf("Error removing reservation ID %d without deposit: %v", reservation.ID, deleteErr)
+			}
+			return nil, err
+		}
 	}
 
-	// If not in cache, get from the database
-	reservationEntity, err := u.repo.GetByID(id)
+	invalidateReservationCache(ctx, u.cache, u.logger, reservation.ID)
+	u.metrics.Reservation(metrics.ReservationBooked)
+
+	// Get the created reservation with customer details
+	createdReservation, err := u.repo.GetByID(ctx, reservation.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Store the reservation in the cache for future requests
-	reservationModel := &model.ReservationResponse{
-		ID:           reservationEntity.ID,
-		CustomerID:   reservationEntity.CustomerID,
-		Customer:     *model.ToCustomerResponse(&reservationEntity.Customer),
-		TableNumber:  reservationEntity.TableNumber,
-		GuestCount:   reservationEntity.GuestCount,
-		ReserveDate:  reservationEntity.ReserveDate,
-		Status:       string(reservationEntity.Status),
-		SpecialNotes: reservationEntity.SpecialNotes,
-		CreatedAt:    reservationEntity.CreatedAt,
-		UpdatedAt:    reservationEntity.UpdatedAt,
-	}
-	if err := u.cache.Set(context.Background(), cacheKey, reservationModel, 5*time.Minute); err != nil {
-		u.logger.Errorf("Error setting cache for reservation ID %d: %v", id, err)
+	intro := fmt.Sprintf("We have received your reservation for %d guests on %s.", createdReservation.GuestCount, formatReserveDate(createdReservation))
+	if deposit := createdReservation.Deposit; deposit != nil {
+		intro += fmt.Sprintf("\nA deposit of %.0f is required to hold it. Please pay by %s: %s",
+			deposit.Amount, deposit.DueAt.Format("Monday, 02 Jan 2006 15:04"), deposit.PaymentURL)
 	}
+	u.notify(ctx, createdReservation, "Your reservation has been received", intro)
 
-	return reservationModel, nil
+	return model.ToReservationResponse(createdReservation), nil
 }
 
-func (u *reservationUseCase) GetAll(params *model.ReservationQueryParams) (*model.PaginationResponse[[]model.ReservationResponse], error) {
-	start := time.Now()
-	defer func() {
-		u.logger.Infof("GetAll took %v", time.Since(start))
-	}()
+func (u *reservationUseCase) GetByID(ctx context.Context, id uint) (*model.ReservationResponse, error) {
+	ctx, span := tracer.Start(ctx, "ReservationUseCase.GetByID")
+	defer span.End()
 
-	// Try to get the reservations from the cache first
-	cacheKey := fmt.Sprintf("reservations:all:page:%d:limit:%d", params.Page, params.Limit)
-	var cachedData model.PaginationResponse[[]model.ReservationResponse]
-	if err := u.cache.Get(context.Background(), cacheKey, &cachedData); err == nil {
-		u.logger.Info("Reservations fetched from cache")
-		return &cachedData, nil
-	}
+	cacheKey := fmt.Sprintf("reservation:%d", id)
+	return database.GetOrLoad(ctx, u.cache, cacheKey, itemLoad(constants.ErrNotFound), func() (*model.ReservationResponse, error) {
+		reservationEntity, err := u.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 
-	// If not in cache, get from the database
-	result, err := u.repo.GetAll(params)
-	if err != nil {
-		return nil, err
-	}
+		return model.ToReservationResponse(reservationEntity), nil
+	})
+}
 
-	responses := make([]model.ReservationResponse, len(result.Data))
-	for i, reservation := range result.Data {
-		responses[i] = model.ReservationResponse{
-			ID:           reservation.ID,
-			CustomerID:   reservation.CustomerID,
-			Customer:     *model.ToCustomerResponse(&reservation.Customer),
-			TableNumber:  reservation.TableNumber,
-			GuestCount:   reservation.GuestCount,
-			ReserveDate:  reservation.ReserveDate,
-			Status:       string(reservation.Status),
-			SpecialNotes: reservation.SpecialNotes,
-			CreatedAt:    reservation.CreatedAt,
-			UpdatedAt:    reservation.UpdatedAt,
-		}
-	}
+func (u *reservationUseCase) GetAll(ctx context.Context, params *model.ReservationQueryParams) (*model.PaginationResponse[[]model.ReservationResponse], error) {
+	ctx, span := tracer.Start(ctx, "ReservationUseCase.GetAll")
+	defer span.End()
 
-	paginatedResponse := &model.PaginationResponse[[]model.ReservationResponse]{
-		Data:       responses,
-		Total:      result.Total,
-		Page:       result.Page,
-		PageSize:   result.PageSize,
-		TotalPages: result.TotalPages,
-	}
+	cacheKey := fmt.Sprintf("reservations:all:page:%d:limit:%d:customer:%d:status:%s:date:%s:table:%d",
+		params.Page, params.Limit, params.CustomerID, params.Status, params.ReserveDate.Format(time.RFC3339), params.TableNumber)
+	return database.GetOrLoad(ctx, u.cache, cacheKey, listLoad(reservationListTag), func() (*model.PaginationResponse[[]model.ReservationResponse], error) {
+		result, err := u.repo.GetAll(ctx, params)
+		if err != nil {
+			return nil, err
+		}
 
-	// Store the reservations in the cache for future requests
-	if err := u.cache.Set(context.Background(), cacheKey, paginatedResponse, 5*time.Minute); err != nil {
-		u.logger.Errorf("Error setting cache for all reservations: %v", err)
-	}
+		responses := make([]model.ReservationResponse, len(result.Data))
+		for i, reservation := range result.Data {
+			responses[i] = *model.ToReservationResponse(&reservation)
+		}
 
-	return paginatedResponse, nil
+		paginatedResponse := &model.PaginationResponse[[]model.ReservationResponse]{
+			Data:       responses,
+			Total:      result.Total,
+			Page:       result.Page,
+			PageSize:   result.PageSize,
+			TotalPages: result.TotalPages,
+		}
+		return paginatedResponse, nil
+	})
 }
 
-func (u *reservationUseCase) Update(id uint, request *model.UpdateReservationRequest) (*model.ReservationResponse, error) {
-	existing, err := u.repo.GetByID(id)
+func (u *reservationUseCase) Update(ctx context.Context, id uint, request *model.UpdateReservationRequest) (*model.ReservationResponse, error) {
+	existing, err := u.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -272,7 +282,7 @@ func (u *reservationUseCase) Update(id uint, request *model.UpdateReservationReq
 			checkTable = existing.TableNumber
 		}
 
-		available, err := u.repo.CheckTableAvailability(uint(checkTable), checkDate)
+		available, err := u.repo.CheckTableAvailability(ctx, uint(checkTable), checkDate)
 		if err != nil {
 			return nil, err
 		}
@@ -290,6 +300,7 @@ func (u *reservationUseCase) Update(id uint, request *model.UpdateReservationReq
 	if !request.ReserveDate.IsZero() {
 		existing.ReserveDate = request.ReserveDate
 	}
+	previousStatus := existing.Status
 	if request.Status != "" {
 		existing.Status = entity.ReservationStatus(request.Status)
 	}
@@ -297,57 +308,297 @@ func (u *reservationUseCase) Update(id uint, request *model.UpdateReservationReq
 		existing.SpecialNotes = request.SpecialNotes
 	}
 
-	if err := u.repo.Update(existing); err != nil {
+	if err := u.repo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+	if existing.Status != previousStatus {
+		switch existing.Status {
+		case entity.ReservationStatusCancelled:
+			err = u.deposits.Release(ctx, existing, time.Now())
+		case entity.ReservationStatusNoShow:
+			err = u.deposits.Forfeit(ctx, existing.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		u.metrics.Reservation(string(existing.Status))
+	}
+
+	// Invalidate cache
+	invalidateReservationCache(ctx, u.cache, u.logger, id)
+
+	updated, err := u.repo.GetByID(ctx, id)
+	if err != nil {
 		return nil, err
 	}
 
+	return model.ToReservationResponse(updated), nil
+}
+
+func (u *reservationUseCase) Delete(ctx context.Context, id uint) error {
+	if err := u.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
 	// Invalidate cache
-	cacheKey := fmt.Sprintf("reservation:%d", id)
-	if err := u.cache.Delete(context.Background(), cacheKey); err != nil {
-		u.logger.Errorf("Error deleting cache for reservation ID %d: %v", id, err)
+	invalidateReservationCache(ctx, u.cache, u.logger, id)
+
+	return nil
+}
+
+func (u *reservationUseCase) GetByToken(ctx context.Context, id uint, action ReservationLinkAction, token string) (*model.ReservationResponse, error) {
+	reservation, err := u.getByToken(ctx, id, action, token)
+	if err != nil {
+		return nil, err
+	}
+	return model.ToReservationResponse(reservation), nil
+}
+
+// ConfirmByToken confirms a pending reservation from the link in a confirmation or reminder message
+func (u *reservationUseCase) ConfirmByToken(ctx context.Context, id uint, token string) (*model.ReservationResponse, error) {
+	reservation, err := u.getByToken(ctx, id, ReservationLinkConfirm, token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch reservation.Status {
+	case entity.ReservationStatusConfirmed:
+		return model.ToReservationResponse(reservation), nil
+	case entity.ReservationStatusPending:
+	default:
+		return nil, constants.ErrReservationNotChangeable
+	}
+	if reservation.Deposit != nil && reservation.Deposit.Status == entity.DepositStatusPending {
+		return nil, constants.ErrDepositUnpaid
+	}
+
+	now := time.Now()
+	reservation.Status = entity.ReservationStatusConfirmed
+	reservation.ConfirmedAt = &now
+	if err := u.repo.Update(ctx, reservation); err != nil {
+		return nil, err
+	}
+	invalidateReservationCache(ctx, u.cache, u.logger, id)
+	u.metrics.Reservation(string(entity.ReservationStatusConfirmed))
+
+	u.logger.Infof("Reservation %d confirmed by customer %d", id, reservation.CustomerID)
+	return model.ToReservationResponse(reservation), nil
+}
+
+// CancelByToken cancels an upcoming reservation from the link in a confirmation or reminder message
+func (u *reservationUseCase) CancelByToken(ctx context.Context, id uint, token string) (*model.ReservationResponse, error) {
+	reservation, err := u.getByToken(ctx, id, ReservationLinkCancel, token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch reservation.Status {
+	case entity.ReservationStatusCancelled:
+		return model.ToReservationResponse(reservation), nil
+	case entity.ReservationStatusPending, entity.ReservationStatusConfirmed:
+	default:
+		return nil, constants.ErrReservationNotChangeable
+	}
+
+	reservation.Status = entity.ReservationStatusCancelled
+	if err := u.repo.Update(ctx, reservation); err != nil {
+		return nil, err
 	}
-	if err := u.cache.Delete(context.Background(), "reservations:all:*"); err != nil {
-		u.logger.Errorf("Error deleting cache for all reservations: %v", err)
+	if err := u.deposits.Release(ctx, reservation, time.Now()); err != nil {
+		return nil, err
 	}
-	if err := u.cache.Delete(context.Background(), "reservations:admin:all:*"); err != nil {
-		u.logger.Errorf("Error deleting cache for admin reservations: %v", err)
+	invalidateReservationCache(ctx, u.cache, u.logger, id)
+	u.metrics.Reservation(string(entity.ReservationStatusCancelled))
+
+	// Fetch again so the response shows what happens to the deposit
+	reservation, err = u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	updated, err := u.repo.GetByID(id)
+	u.logger.Infof("Reservation %d cancelled by customer %d", id, reservation.CustomerID)
+	return model.ToReservationResponse(reservation), nil
+}
+
+func (u *reservationUseCase) CheckIn(ctx context.Context, id uint) (*model.ReservationResponse, error) {
+	reservation, err := u.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.ReservationResponse{
-		ID:           updated.ID,
-		CustomerID:   updated.CustomerID,
-		Customer:     *model.ToCustomerResponse(&updated.Customer),
-		TableNumber:  updated.TableNumber,
-		GuestCount:   updated.GuestCount,
-		ReserveDate:  updated.ReserveDate,
-		Status:       string(updated.Status),
-		SpecialNotes: updated.SpecialNotes,
-		CreatedAt:    updated.CreatedAt,
-		UpdatedAt:    updated.UpdatedAt,
+	switch reservation.Status {
+	case entity.ReservationStatusPending, entity.ReservationStatusConfirmed:
+	default:
+		return nil, constants.ErrReservationNotChangeable
+	}
+	if reservation.CheckedInAt != nil {
+		return model.ToReservationResponse(reservation), nil
+	}
+
+	// A party that turns up without confirming the link is confirmed at the door
+	now := time.Now()
+	if reservation.Status == entity.ReservationStatusPending {
+		reservation.Status = entity.ReservationStatusConfirmed
+		reservation.ConfirmedAt = &now
+	}
+	reservation.CheckedInAt = &now
+	if err := u.repo.Update(ctx, reservation); err != nil {
+		return nil, err
+	}
+	invalidateReservationCache(ctx, u.cache, u.logger, id)
+
+	u.logger.Infof("Reservation %d checked in", id)
+	return model.ToReservationResponse(reservation), nil
+}
+
+func (u *reservationUseCase) GetNoShowSummary(ctx context.Context, customerID uint) (*model.NoShowSummaryResponse, error) {
+	count, err := u.repo.CountNoShowsByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NoShowSummaryResponse{
+		CustomerID:  customerID,
+		NoShowCount: count,
+		Blocked:     u.policy.NoShowLimit > 0 && count >= int64(u.policy.NoShowLimit),
 	}, nil
 }
 
-func (u *reservationUseCase) Delete(id uint) error {
-	if err := u.repo.Delete(id); err != nil {
-		return err
+// ApplyDeposit credits the reservation's paid deposit to the party's bill
+func (u *reservationUseCase) ApplyDeposit(ctx context.Context, id uint, request *model.ApplyDepositRequest) (*model.DepositResponse, error) {
+	return u.deposits.ApplyToOrder(ctx, id, request.OrderID)
+}
+
+// SendReminders messages every customer whose reservation starts within the
+// reminder lead time and returns how many reminders were sent.
+func (u *reservationUseCase) SendReminders(ctx context.Context, now time.Time) (int, error) {
+	reservations, err := u.repo.GetDueForReminder(ctx, now, now.Add(u.policy.ReminderLead))
+	if err != nil {
+		return 0, err
 	}
 
-	// Invalidate cache
-	cacheKey := fmt.Sprintf("reservation:%d", id)
-	if err := u.cache.Delete(context.Background(), cacheKey); err != nil {
-		u.logger.Errorf("Error deleting cache for reservation ID %d: %v", id, err)
+	sent := 0
+	for i := range reservations {
+		reservation := &reservations[i]
+		intro := fmt.Sprintf("This is a reminder of your reservation for %d guests on %s.", reservation.GuestCount, formatReserveDate(reservation))
+		if !u.notify(ctx, reservation, "Reminder: your upcoming reservation", intro) {
+			continue
+		}
+		if err := u.repo.MarkReminderSent(ctx, reservation.ID, now); err != nil {
+			return sent, err
+		}
+		invalidateReservationCache(ctx, u.cache, u.logger, reservation.ID)
+		sent++
+	}
+
+	if sent > 0 {
+		u.logger.Infof("Sent %d reservation reminders", sent)
+	}
+	return sent, nil
+}
+
+// MarkNoShows flags reservations that were not checked in, completed or
+// cancelled within the grace period after their start time, and returns how
+// many were marked.
+func (u *reservationUseCase) MarkNoShows(ctx context.Context, now time.Time) (int, error) {
+	reservations, err := u.repo.GetOverdue(ctx, now.Add(-u.policy.NoShowGrace))
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range reservations {
+		reservation := &reservations[i]
+		reservation.Status = entity.ReservationStatusNoShow
+		if err := u.repo.Update(ctx, reservation); err != nil {
+			return i, err
+		}
+		if err := u.deposits.Forfeit(ctx, reservation.ID); err != nil {
+			return i, err
+		}
+		invalidateReservationCache(ctx, u.cache, u.logger, reservation.ID)
+		u.metrics.Reservation(string(entity.ReservationStatusNoShow))
+		u.logger.Warnf("Reservation %d for customer %d marked as no-show", reservation.ID, reservation.CustomerID)
+	}
+
+	return len(reservations), nil
+}
+
+func (u *reservationUseCase) getByToken(ctx context.Context, id uint, action ReservationLinkAction, token string) (*entity.Reservation, error) {
+	if err := u.verifyToken(id, action, token, time.Now()); err != nil {
+		return nil, err
 	}
-	if err := u.cache.Delete(context.Background(), "reservations:all:*"); err != nil {
-		u.logger.Errorf("Error deleting cache for all reservations: %v", err)
+
+	reservation, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if err := u.cache.Delete(context.Background(), "reservations:admin:all:*"); err != nil {
-		u.logger.Errorf("Error deleting cache for admin reservations: %v", err)
+	if !reservation.ReserveDate.After(time.Now()) {
+		return nil, constants.ErrReservationNotChangeable
 	}
 
+	return reservation, nil
+}
+
+// notify sends a message with the reservation details and its confirm/cancel
+// links. Delivery failures are logged rather than returned so a broken sender
+// never blocks a booking.
+func (u *reservationUseCase) notify(ctx context.Context, reservation *entity.Reservation, subject, intro string) bool {
+	// Links stop working once the reservation starts
+	baseURL := strings.TrimRight(u.policy.LinkURL, "/")
+	body := intro +
+		fmt.Sprintf("\nConfirm: %s/reservations/%d/confirm?token=%s", baseURL, reservation.ID,
+			u.signToken(reservation.ID, ReservationLinkConfirm, reservation.ReserveDate)) +
+		fmt.Sprintf("\nCancel: %s/reservations/%d/cancel?token=%s", baseURL, reservation.ID,
+			u.signToken(reservation.ID, ReservationLinkCancel, reservation.ReserveDate))
+
+	if err := u.sender.Send(ctx, notification.Message{
+		To:      reservation.Customer.Email,
+		Subject: subject,
+		Body:    body,
+	}); err != nil {
+		u.logger.Errorf("Error sending notification for reservation ID %d: %v", reservation.ID, err)
+		return false
+	}
+	return true
+}
+
+func formatReserveDate(reservation *entity.Reservation) string {
+	return reservation.ReserveDate.Format("Monday, 02 Jan 2006 15:04")
+}
+
+// signToken produces "<expiry>.<signature>" for a confirm or cancel link, where the
+// signature is the base64 HMAC-SHA256 of "reservation:<id>:<action>:<expiry>"
+func (u *reservationUseCase) signToken(id uint, action ReservationLinkAction, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expires + "." + u.tokenSignature(id, action, expires)
+}
+
+func (u *reservationUseCase) verifyToken(id uint, action ReservationLinkAction, token string, now time.Time) error {
+	expires, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(u.tokenSignature(id, action, expires))) {
+		return constants.ErrInvalidReservationToken
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !now.Before(time.Unix(expiresAt, 0)) {
+		return constants.ErrInvalidReservationToken
+	}
 	return nil
 }
+
+func (u *reservationUseCase) tokenSignature(id uint, action ReservationLinkAction, expires string) string {
+	mac := hmac.New(sha256.New, []byte(u.policy.Secret))
+	mac.Write([]byte(fmt.Sprintf("reservation:%d:%s:%s", id, action, expires)))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func invalidateReservationCache(ctx context.Context, cache database.RedisCache, log *logrus.Logger, id uint) {
+	cacheKey := fmt.Sprintf("reservation:%d", id)
+	if err := cache.Delete(ctx, cacheKey); err != nil {
+		log.Errorf("Error deleting cache for reservation ID %d: %v", id, err)
+	}
+	// Available tables are derived from reservations, so they go stale too
+	if err := cache.InvalidateTags(ctx, reservationListTag, adminReservationListTag, availableTablesTag); err != nil {
+		log.Errorf("Error deleting cache for reservation lists: %v", err)
+	}
+}
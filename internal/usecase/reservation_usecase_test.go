@@ -1,10 +1,16 @@
 package usecase
 
 import (
+	"cakestore/internal/constants"
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"cakestore/internal/metrics"
+	"cakestore/internal/notification"
+	"context"
 	"errors"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,12 +23,12 @@ type MockReservationRepository struct {
 	mock.Mock
 }
 
-func (m *MockReservationRepository) Create(reservation *entity.Reservation) error {
+func (m *MockReservationRepository) Create(ctx context.Context, reservation *entity.Reservation) error {
 	args := m.Called(reservation)
 	return args.Error(0)
 }
 
-func (m *MockReservationRepository) GetByID(id uint) (*entity.Reservation, error) {
+func (m *MockReservationRepository) GetByID(ctx context.Context, id uint) (*entity.Reservation, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -30,7 +36,7 @@ func (m *MockReservationRepository) GetByID(id uint) (*entity.Reservation, error
 	return args.Get(0).(*entity.Reservation), args.Error(1)
 }
 
-func (m *MockReservationRepository) GetAll(params *model.ReservationQueryParams) (*model.PaginationResponse[[]entity.Reservation], error) {
+func (m *MockReservationRepository) GetAll(ctx context.Context, params *model.ReservationQueryParams) (*model.PaginationResponse[[]entity.Reservation], error) {
 	args := m.Called(params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,7 +44,7 @@ func (m *MockReservationRepository) GetAll(params *model.ReservationQueryParams)
 	return args.Get(0).(*model.PaginationResponse[[]entity.Reservation]), args.Error(1)
 }
 
-func (m *MockReservationRepository) AdminGetAllCustomerReservations(params *model.PaginationQuery) (*model.PaginationResponse[[]entity.Reservation], error) {
+func (m *MockReservationRepository) AdminGetAllCustomerReservations(ctx context.Context, params *model.PaginationQuery) (*model.PaginationResponse[[]entity.Reservation], error) {
 	args := m.Called(params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -46,26 +52,61 @@ func (m *MockReservationRepository) AdminGetAllCustomerReservations(params *mode
 	return args.Get(0).(*model.PaginationResponse[[]entity.Reservation]), args.Error(1)
 }
 
-func (m *MockReservationRepository) Update(reservation *entity.Reservation) error {
+func (m *MockReservationRepository) Update(ctx context.Context, reservation *entity.Reservation) error {
 	args := m.Called(reservation)
 	return args.Error(0)
 }
 
-func (m *MockReservationRepository) Delete(id uint) error {
+func (m *MockReservationRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockReservationRepository) CheckTableAvailability(tableID uint, reserveDate time.Time) (bool, error) {
+func (m *MockReservationRepository) CheckTableAvailability(ctx context.Context, tableID uint, reserveDate time.Time) (bool, error) {
 	args := m.Called(tableID, reserveDate)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockReservationRepository) GetDueForReminder(ctx context.Context, from, to time.Time) ([]entity.Reservation, error) {
+	args := m.Called(from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.Reservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) GetOverdue(ctx context.Context, before time.Time) ([]entity.Reservation, error) {
+	args := m.Called(before)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.Reservation), args.Error(1)
+}
+
+func (m *MockReservationRepository) MarkReminderSent(ctx context.Context, id uint, sentAt time.Time) error {
+	args := m.Called(id, sentAt)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) CountNoShowsByCustomer(ctx context.Context, customerID uint) (int64, error) {
+	args := m.Called(customerID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockNotificationSender struct {
+	mock.Mock
+}
+
+func (m *MockNotificationSender) Send(ctx context.Context, message notification.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
 func TestReservationUseCase_GetByID(t *testing.T) {
 	logger := logrus.New()
 	mockReservationRepo := new(MockReservationRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, nil, nil, metrics.Noop{}, ReservationPolicy{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedReservation := &entity.Reservation{
@@ -75,7 +116,7 @@ func TestReservationUseCase_GetByID(t *testing.T) {
 		mockReservationRepo.On("GetByID", uint(1)).Return(expectedReservation, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		reservation, err := useCase.GetByID(1)
+		reservation, err := useCase.GetByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, reservation)
@@ -87,7 +128,7 @@ func TestReservationUseCase_GetByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockReservationRepo.On("GetByID", uint(1)).Return(nil, errors.New("not found")).Once()
 
-		reservation, err := useCase.GetByID(1)
+		reservation, err := useCase.GetByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, reservation)
@@ -99,7 +140,7 @@ func TestReservationUseCase_GetAll(t *testing.T) {
 	logger := logrus.New()
 	mockReservationRepo := new(MockReservationRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, nil, nil, metrics.Noop{}, ReservationPolicy{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedResponse := &model.PaginationResponse[[]entity.Reservation]{
@@ -110,12 +151,14 @@ func TestReservationUseCase_GetAll(t *testing.T) {
 			},
 			Total: 1,
 		}
-		params := &model.ReservationQueryParams{}
+		params := &model.ReservationQueryParams{CustomerID: 7, Status: "pending"}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockReservationRepo.On("GetAll", params).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		// The key carries the filters so one customer's page is never served to another
+		mockCache.On("SetWithTags", mock.Anything, "reservations:all:page:0:limit:0:customer:7:status:pending:date:0001-01-01T00:00:00Z:table:0",
+			mock.Anything, mock.Anything, []string{reservationListTag}).Return(nil)
 
-		reservations, err := useCase.GetAll(params)
+		reservations, err := useCase.GetAll(context.Background(), params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, reservations)
@@ -128,7 +171,7 @@ func TestReservationUseCase_GetAll(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockReservationRepo.On("GetAll", params).Return(nil, errors.New("error")).Once()
 
-		reservations, err := useCase.GetAll(params)
+		reservations, err := useCase.GetAll(context.Background(), params)
 
 		assert.Error(t, err)
 		assert.Nil(t, reservations)
@@ -140,7 +183,7 @@ func TestReservationUseCase_AdminGetAllCustomerReservations(t *testing.T) {
 	logger := logrus.New()
 	mockReservationRepo := new(MockReservationRepository)
 	mockCache := new(database.MockRedisCacheService)
-	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, nil, nil, metrics.Noop{}, ReservationPolicy{})
 
 	t.Run("success", func(t *testing.T) {
 		expectedResponse := &model.PaginationResponse[[]entity.Reservation]{
@@ -154,9 +197,9 @@ func TestReservationUseCase_AdminGetAllCustomerReservations(t *testing.T) {
 		params := &model.PaginationQuery{}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockReservationRepo.On("AdminGetAllCustomerReservations", params).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "reservations:admin:all:page:0:limit:0", mock.Anything, mock.Anything, []string{adminReservationListTag}).Return(nil)
 
-		reservations, err := useCase.AdminGetAllCustomerReservations(params)
+		reservations, err := useCase.AdminGetAllCustomerReservations(context.Background(), params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, reservations)
@@ -169,10 +212,247 @@ func TestReservationUseCase_AdminGetAllCustomerReservations(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockReservationRepo.On("AdminGetAllCustomerReservations", params).Return(nil, errors.New("error")).Once()
 
-		reservations, err := useCase.AdminGetAllCustomerReservations(params)
+		reservations, err := useCase.AdminGetAllCustomerReservations(context.Background(), params)
 
 		assert.Error(t, err)
 		assert.Nil(t, reservations)
 		mockReservationRepo.AssertExpectations(t)
 	})
 }
+
+func TestReservationUseCase_Create_BlockedByNoShows(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, nil, nil, nil, metrics.Noop{}, ReservationPolicy{NoShowLimit: 2})
+
+	mockReservationRepo.On("CountNoShowsByCustomer", uint(7)).Return(int64(2), nil).Once()
+
+	reservation, err := useCase.Create(context.Background(), 7, &model.CreateReservationRequest{
+		GuestCount:  2,
+		ReserveDate: time.Now().Add(48 * time.Hour),
+	})
+
+	assert.ErrorIs(t, err, constants.ErrReservationBlocked)
+	assert.Nil(t, reservation)
+	mockReservationRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestReservationUseCase_Create_DepositCheckFails(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	deposits := NewDepositUseCase(nil, mockReservationRepo, nil, nil, MidtransConfig{}, logger, nil, metrics.Noop{}, DepositPolicy{
+		AmountPerGuest:  50000,
+		NoShowThreshold: 1,
+	})
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, nil, nil, deposits, metrics.Noop{}, ReservationPolicy{})
+
+	mockReservationRepo.On("CountNoShowsByCustomer", uint(7)).Return(int64(0), errors.New("connection reset")).Once()
+
+	reservation, err := useCase.Create(context.Background(), 7, &model.CreateReservationRequest{
+		GuestCount:  2,
+		ReserveDate: time.Now().Add(48 * time.Hour),
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, reservation)
+	mockReservationRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestReservationUseCase_SendReminders(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	mockCache := new(database.MockRedisCacheService)
+	mockSender := new(MockNotificationSender)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, mockSender, nil, metrics.Noop{}, ReservationPolicy{
+		ReminderLead: 2 * time.Hour,
+		LinkURL:      "https://api.example.com/",
+		Secret:       "secret",
+	})
+
+	now := time.Date(2025, 6, 12, 15, 0, 0, 0, time.UTC)
+	mockReservationRepo.On("GetDueForReminder", now, now.Add(2*time.Hour)).Return([]entity.Reservation{
+		{ID: 1, GuestCount: 4, ReserveDate: now.Add(time.Hour), Customer: entity.Customer{Email: "a@example.com"}},
+		{ID: 2, GuestCount: 2, ReserveDate: now.Add(90 * time.Minute), Customer: entity.Customer{Email: "b@example.com"}},
+	}, nil).Once()
+	mockSender.On("Send", mock.Anything, mock.MatchedBy(func(message notification.Message) bool {
+		return message.To == "a@example.com" &&
+			strings.Contains(message.Body, "https://api.example.com/reservations/1/confirm?token=") &&
+			strings.Contains(message.Body, "https://api.example.com/reservations/1/cancel?token=")
+	})).Return(nil).Once()
+	mockSender.On("Send", mock.Anything, mock.MatchedBy(func(message notification.Message) bool {
+		return message.To == "b@example.com"
+	})).Return(errors.New("smtp down")).Once()
+	mockReservationRepo.On("MarkReminderSent", uint(1), now).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	sent, err := useCase.SendReminders(context.Background(), now)
+
+	assert.NoError(t, err)
+	// the failed reminder is left unmarked so the next run retries it
+	assert.Equal(t, 1, sent)
+	mockReservationRepo.AssertNotCalled(t, "MarkReminderSent", uint(2), mock.Anything)
+	mockSender.AssertExpectations(t)
+}
+
+func TestReservationUseCase_MarkNoShows(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	mockCache := new(database.MockRedisCacheService)
+	mockDepositRepo := new(MockDepositRepository)
+	deposits := NewDepositUseCase(mockDepositRepo, mockReservationRepo, nil, nil, MidtransConfig{}, logger, mockCache, metrics.Noop{}, DepositPolicy{})
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, nil, deposits, metrics.Noop{}, ReservationPolicy{NoShowGrace: 15 * time.Minute})
+
+	now := time.Date(2025, 6, 12, 21, 0, 0, 0, time.UTC)
+	mockReservationRepo.On("GetOverdue", now.Add(-15*time.Minute)).Return([]entity.Reservation{
+		{ID: 1, Status: entity.ReservationStatusPending},
+		{ID: 2, Status: entity.ReservationStatusConfirmed},
+	}, nil).Once()
+	mockReservationRepo.On("Update", mock.MatchedBy(func(r *entity.Reservation) bool {
+		return r.Status == entity.ReservationStatusNoShow
+	})).Return(nil).Twice()
+	mockDepositRepo.On("GetByReservationID", uint(1)).Return(nil, constants.ErrNotFound).Once()
+	mockDepositRepo.On("GetByReservationID", uint(2)).Return(&entity.ReservationDeposit{
+		ReservationID: 2,
+		Amount:        200000,
+		Status:        entity.DepositStatusPaid,
+	}, nil).Once()
+	mockDepositRepo.On("Update", mock.MatchedBy(func(d *entity.ReservationDeposit) bool {
+		return d.ReservationID == 2 && d.Status == entity.DepositStatusForfeited
+	})).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+	marked, err := useCase.MarkNoShows(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, marked)
+	mockReservationRepo.AssertExpectations(t)
+	mockDepositRepo.AssertExpectations(t)
+}
+
+func TestReservationUseCase_CheckIn(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, nil, nil, metrics.Noop{}, ReservationPolicy{})
+
+	t.Run("confirms a pending reservation at the door", func(t *testing.T) {
+		mockReservationRepo.On("GetByID", uint(1)).Return(&entity.Reservation{
+			ID:     1,
+			Status: entity.ReservationStatusPending,
+		}, nil).Once()
+		mockReservationRepo.On("Update", mock.MatchedBy(func(r *entity.Reservation) bool {
+			return r.Status == entity.ReservationStatusConfirmed && r.ConfirmedAt != nil && r.CheckedInAt != nil
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+		reservation, err := useCase.CheckIn(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, reservation.CheckedInAt)
+		mockReservationRepo.AssertExpectations(t)
+	})
+
+	t.Run("a no-show cannot be checked in", func(t *testing.T) {
+		mockReservationRepo.On("GetByID", uint(2)).Return(&entity.Reservation{
+			ID:     2,
+			Status: entity.ReservationStatusNoShow,
+		}, nil).Once()
+
+		reservation, err := useCase.CheckIn(context.Background(), 2)
+
+		assert.ErrorIs(t, err, constants.ErrReservationNotChangeable)
+		assert.Nil(t, reservation)
+	})
+}
+
+func TestReservationUseCase_ConfirmByToken(t *testing.T) {
+	logger := logrus.New()
+	mockReservationRepo := new(MockReservationRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewReservationUseCase(mockReservationRepo, logger, nil, mockCache, nil, nil, metrics.Noop{}, ReservationPolicy{Secret: "secret"})
+	signToken := useCase.(*reservationUseCase).signToken
+	token := signToken(1, ReservationLinkConfirm, time.Now().Add(time.Hour))
+
+	t.Run("invalid token", func(t *testing.T) {
+		reservation, err := useCase.ConfirmByToken(context.Background(), 1, signToken(2, ReservationLinkConfirm, time.Now().Add(time.Hour)))
+
+		assert.ErrorIs(t, err, constants.ErrInvalidReservationToken)
+		assert.Nil(t, reservation)
+		mockReservationRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("cancel link cannot confirm", func(t *testing.T) {
+		reservation, err := useCase.ConfirmByToken(context.Background(), 1, signToken(1, ReservationLinkCancel, time.Now().Add(time.Hour)))
+
+		assert.ErrorIs(t, err, constants.ErrInvalidReservationToken)
+		assert.Nil(t, reservation)
+		mockReservationRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		reservation, err := useCase.ConfirmByToken(context.Background(), 1, signToken(1, ReservationLinkConfirm, time.Now().Add(-time.Minute)))
+
+		assert.ErrorIs(t, err, constants.ErrInvalidReservationToken)
+		assert.Nil(t, reservation)
+		mockReservationRepo.AssertNotCalled(t, "GetByID", mock.Anything)
+	})
+
+	t.Run("tampered expiry", func(t *testing.T) {
+		_, signature, _ := strings.Cut(signToken(1, ReservationLinkConfirm, time.Now().Add(-time.Minute)), ".")
+		forged := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "." + signature
+
+		reservation, err := useCase.ConfirmByToken(context.Background(), 1, forged)
+
+		assert.ErrorIs(t, err, constants.ErrInvalidReservationToken)
+		assert.Nil(t, reservation)
+	})
+
+	t.Run("viewing the link changes nothing", func(t *testing.T) {
+		mockReservationRepo.On("GetByID", uint(1)).Return(&entity.Reservation{
+			ID:          1,
+			Status:      entity.ReservationStatusPending,
+			ReserveDate: time.Now().Add(time.Hour),
+		}, nil).Once()
+
+		reservation, err := useCase.GetByToken(context.Background(), 1, ReservationLinkConfirm, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, string(entity.ReservationStatusPending), reservation.Status)
+		mockReservationRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		mockReservationRepo.On("GetByID", uint(1)).Return(&entity.Reservation{
+			ID:          1,
+			Status:      entity.ReservationStatusPending,
+			ReserveDate: time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockReservationRepo.On("Update", mock.MatchedBy(func(r *entity.Reservation) bool {
+			return r.Status == entity.ReservationStatusConfirmed && r.ConfirmedAt != nil
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("InvalidateTags", mock.Anything, mock.Anything).Return(nil)
+
+		reservation, err := useCase.ConfirmByToken(context.Background(), 1, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, string(entity.ReservationStatusConfirmed), reservation.Status)
+		mockReservationRepo.AssertExpectations(t)
+	})
+
+	t.Run("reservation already started", func(t *testing.T) {
+		mockReservationRepo.On("GetByID", uint(1)).Return(&entity.Reservation{
+			ID:          1,
+			Status:      entity.ReservationStatusPending,
+			ReserveDate: time.Now().Add(-time.Hour),
+		}, nil).Once()
+
+		reservation, err := useCase.ConfirmByToken(context.Background(), 1, token)
+
+		assert.ErrorIs(t, err, constants.ErrReservationNotChangeable)
+		assert.Nil(t, reservation)
+	})
+}
@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rolePermissionsTTL bounds how long a permission change can take to reach
+// other instances if the cache delete after an update is lost
+const rolePermissionsTTL = 5 * time.Minute
+
+type RoleUseCase interface {
+	// HasPermissions reports whether the role grants every one of the permissions
+	HasPermissions(ctx context.Context, role string, permissions ...string) (bool, error)
+	// Exists returns constants.ErrUnknownRole for roles that are not defined
+	Exists(ctx context.Context, role string) error
+	GetAll(ctx context.Context) ([]model.RoleResponse, error)
+	GetByName(ctx context.Context, name string) (*model.RoleResponse, error)
+	Create(ctx context.Context, request *model.CreateRoleRequest) (*model.RoleResponse, error)
+	Update(ctx context.Context, name string, request *model.UpdateRoleRequest) (*model.RoleResponse, error)
+	Delete(ctx context.Context, name string) error
+}
+
+type roleUseCase struct {
+	repo   repository.RoleRepository
+	logger *logrus.Logger
+	cache  database.RedisCache
+}
+
+func NewRoleUseCase(repo repository.RoleRepository, logger *logrus.Logger, cache database.RedisCache) RoleUseCase {
+	return &roleUseCase{
+		repo:   repo,
+		logger: logger,
+		cache:  cache,
+	}
+}
+
+func rolePermissionsKey(role string) string {
+	return fmt.Sprintf("role:%s:permissions", role)
+}
+
+// permissions is called on every guarded request, so the permission set is
+// cached per role and dropped whenever the role changes
+func (u *roleUseCase) permissions(ctx context.Context, role string) ([]string, error) {
+	key := rolePermissionsKey(role)
+	var permissions []string
+	if err := u.cache.Get(ctx, key, &permissions); err == nil {
+		return permissions, nil
+	}
+
+	stored, err := u.repo.GetByName(ctx, role)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrUnknownRole
+		}
+		return nil, err
+	}
+
+	permissions = stored.PermissionNames()
+	if err := u.cache.Set(ctx, key, permissions, rolePermissionsTTL); err != nil {
+		u.logger.Errorf("Error caching permissions for role %s: %v", role, err)
+	}
+	return permissions, nil
+}
+
+func (u *roleUseCase) HasPermissions(ctx context.Context, role string, permissions ...string) (bool, error) {
+	if role == "" {
+		return false, nil
+	}
+
+	granted, err := u.permissions(ctx, role)
+	if err != nil {
+		if errors.Is(err, constants.ErrUnknownRole) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (u *roleUseCase) Exists(ctx context.Context, role string) error {
+	_, err := u.permissions(ctx, role)
+	return err
+}
+
+func (u *roleUseCase) GetAll(ctx context.Context) ([]model.RoleResponse, error) {
+	roles, err := u.repo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.RoleResponse, 0, len(roles))
+	for i := range roles {
+		responses = append(responses, *model.ToRoleResponse(&roles[i]))
+	}
+	return responses, nil
+}
+
+func (u *roleUseCase) GetByName(ctx context.Context, name string) (*model.RoleResponse, error) {
+	role, err := u.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return model.ToRoleResponse(role), nil
+}
+
+func (u *roleUseCase) Create(ctx context.Context, request *model.CreateRoleRequest) (*model.RoleResponse, error) {
+	permissions, err := rolePermissions(request.Name, request.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := u.repo.GetByName(ctx, request.Name); err == nil {
+		return nil, constants.ErrRoleAlreadyExists
+	} else if !errors.Is(err, constants.ErrNotFound) {
+		return nil, err
+	}
+
+	now := time.Now()
+	role := &entity.Role{
+		Name:        request.Name,
+		Description: request.Description,
+		Permissions: permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := u.repo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	// An earlier lookup may have found the role missing
+	u.invalidate(ctx, role.Name)
+	return model.ToRoleResponse(role), nil
+}
+
+func (u *roleUseCase) Update(ctx context.Context, name string, request *model.UpdateRoleRequest) (*model.RoleResponse, error) {
+	// Otherwise an admin could lock everyone out of role management
+	if name == constants.RoleAdmin {
+		return nil, constants.ErrAdminRoleFixed
+	}
+
+	role, err := u.repo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := rolePermissions(name, request.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = request.Description
+	role.Permissions = permissions
+	role.UpdatedAt = time.Now()
+	if err := u.repo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	u.invalidate(ctx, name)
+	return model.ToRoleResponse(role), nil
+}
+
+func (u *roleUseCase) Delete(ctx context.Context, name string) error {
+	if slices.Contains(constants.BuiltInRoles, name) {
+		return constants.ErrBuiltInRole
+	}
+
+	members, err := u.repo.CountMembers(ctx, name)
+	if err != nil {
+		return err
+	}
+	if members > 0 {
+		return constants.ErrRoleInUse
+	}
+
+	if err := u.repo.Delete(ctx, name); err != nil {
+		return err
+	}
+
+	u.invalidate(ctx, name)
+	return nil
+}
+
+func (u *roleUseCase) invalidate(ctx context.Context, role string) {
+	if err := u.cache.Delete(ctx, rolePermissionsKey(role)); err != nil {
+		u.logger.Errorf("Error deleting cached permissions for role %s: %v", role, err)
+	}
+}
+
+// rolePermissions validates the requested permissions and drops duplicates
+func rolePermissions(role string, requested []string) ([]entity.RolePermission, error) {
+	permissions := make([]entity.RolePermission, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for _, permission := range requested {
+		if !slices.Contains(constants.Permissions, permission) {
+			return nil, fmt.Errorf("%w: %s", constants.ErrUnknownPermission, permission)
+		}
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		permissions = append(permissions, entity.RolePermission{RoleName: role, Permission: permission})
+	}
+	return permissions, nil
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) GetAll(ctx context.Context) ([]entity.Role, error) {
+	args := m.Called()
+	return args.Get(0).([]entity.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) GetByName(ctx context.Context, name string) (*entity.Role, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) Create(ctx context.Context, role *entity.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) Update(ctx context.Context, role *entity.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) Delete(ctx context.Context, name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) CountMembers(ctx context.Context, name string) (int64, error) {
+	args := m.Called(name)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockRoleUseCase struct {
+	mock.Mock
+}
+
+func (m *MockRoleUseCase) HasPermissions(ctx context.Context, role string, permissions ...string) (bool, error) {
+	args := m.Called(role, permissions)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoleUseCase) Exists(ctx context.Context, role string) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockRoleUseCase) GetAll(ctx context.Context) ([]model.RoleResponse, error) {
+	args := m.Called()
+	return args.Get(0).([]model.RoleResponse), args.Error(1)
+}
+
+func (m *MockRoleUseCase) GetByName(ctx context.Context, name string) (*model.RoleResponse, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RoleResponse), args.Error(1)
+}
+
+func (m *MockRoleUseCase) Create(ctx context.Context, request *model.CreateRoleRequest) (*model.RoleResponse, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RoleResponse), args.Error(1)
+}
+
+func (m *MockRoleUseCase) Update(ctx context.Context, name string, request *model.UpdateRoleRequest) (*model.RoleResponse, error) {
+	args := m.Called(name, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RoleResponse), args.Error(1)
+}
+
+func (m *MockRoleUseCase) Delete(ctx context.Context, name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func TestRoleUseCase_HasPermissions(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("from cache", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewRoleUseCase(mockRepo, logger, mockCache)
+
+		mockCache.On("Get", mock.Anything, "role:cashier:permissions", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]string) = []string{constants.PermissionPOSOperate, constants.PermissionPaymentSettle}
+		}).Return(nil)
+
+		allowed, err := useCase.HasPermissions(context.Background(), constants.RoleCashier, constants.PermissionPOSOperate)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+
+		allowed, err = useCase.HasPermissions(context.Background(), constants.RoleCashier, constants.PermissionPOSOperate, constants.PermissionMenuWrite)
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		mockRepo.AssertNotCalled(t, "GetByName", mock.Anything)
+	})
+
+	t.Run("cache miss loads the role", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewRoleUseCase(mockRepo, logger, mockCache)
+
+		mockCache.On("Get", mock.Anything, "role:kitchen_staff:permissions", mock.Anything).Return(errors.New("key not found")).Once()
+		mockRepo.On("GetByName", constants.RoleKitchen).Return(&entity.Role{
+			Name:        constants.RoleKitchen,
+			Permissions: []entity.RolePermission{{RoleName: constants.RoleKitchen, Permission: constants.PermissionMenuWrite}},
+		}, nil).Once()
+		mockCache.On("Set", mock.Anything, "role:kitchen_staff:permissions", []string{constants.PermissionMenuWrite}, rolePermissionsTTL).Return(nil).Once()
+
+		allowed, err := useCase.HasPermissions(context.Background(), constants.RoleKitchen, constants.PermissionMenuWrite)
+
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewRoleUseCase(mockRepo, logger, mockCache)
+
+		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("key not found"))
+		mockRepo.On("GetByName", "intern").Return(nil, constants.ErrNotFound).Once()
+
+		allowed, err := useCase.HasPermissions(context.Background(), "intern", constants.PermissionMenuWrite)
+
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+	})
+}
+
+func TestRoleUseCase_Update(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("replaces permissions and drops the cache", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewRoleUseCase(mockRepo, logger, mockCache)
+
+		mockRepo.On("GetByName", constants.RoleWaitress).Return(&entity.Role{Name: constants.RoleWaitress}, nil).Once()
+		mockRepo.On("Update", mock.MatchedBy(func(role *entity.Role) bool {
+			return len(role.Permissions) == 1 && role.Permissions[0].Permission == constants.PermissionTableSessionClose
+		})).Return(nil).Once()
+		mockCache.On("Delete", mock.Anything, "role:waitress:permissions").Return(nil).Once()
+
+		role, err := useCase.Update(context.Background(), constants.RoleWaitress, &model.UpdateRoleRequest{
+			Permissions: []string{constants.PermissionTableSessionClose, constants.PermissionTableSessionClose},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{constants.PermissionTableSessionClose}, role.Permissions)
+		mockRepo.AssertExpectations(t)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("unknown permission", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		useCase := NewRoleUseCase(mockRepo, logger, nil)
+
+		mockRepo.On("GetByName", constants.RoleWaitress).Return(&entity.Role{Name: constants.RoleWaitress}, nil).Once()
+
+		_, err := useCase.Update(context.Background(), constants.RoleWaitress, &model.UpdateRoleRequest{Permissions: []string{"menu:everything"}})
+
+		assert.ErrorIs(t, err, constants.ErrUnknownPermission)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+
+	t.Run("admin role is fixed", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		useCase := NewRoleUseCase(mockRepo, logger, nil)
+
+		_, err := useCase.Update(context.Background(), constants.RoleAdmin, &model.UpdateRoleRequest{Permissions: []string{constants.PermissionMenuWrite}})
+
+		assert.ErrorIs(t, err, constants.ErrAdminRoleFixed)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+	})
+}
+
+func TestRoleUseCase_Delete(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("built-in role", func(t *testing.T) {
+		useCase := NewRoleUseCase(new(MockRoleRepository), logger, nil)
+
+		assert.ErrorIs(t, useCase.Delete(context.Background(), constants.RoleCustomer), constants.ErrBuiltInRole)
+	})
+
+	t.Run("role still assigned", func(t *testing.T) {
+		mockRepo := new(MockRoleRepository)
+		useCase := NewRoleUseCase(mockRepo, logger, nil)
+
+		mockRepo.On("CountMembers", "barista").Return(int64(2), nil).Once()
+
+		assert.ErrorIs(t, useCase.Delete(context.Background(), "barista"), constants.ErrRoleInUse)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+}
+
+func TestCustomerUseCase_UpdateEmployeeUnknownRole(t *testing.T) {
+	logger := logrus.New()
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockRoles := new(MockRoleUseCase)
+	useCase := NewCustomerUseCase(mockCustomerRepo, logger, nil, nil, nil, nil, mockRoles, nil)
+
+	mockCustomerRepo.On("GetEmployeeByID", int64(7)).Return(&entity.Customer{ID: 7, Role: constants.RoleCashier}, nil).Once()
+	mockRoles.On("Exists", "superuser").Return(constants.ErrUnknownRole).Once()
+
+	err := useCase.UpdateEmployee(context.Background(), 7, &model.UpdateUserRequest{Name: "Bob"}, "superuser")
+
+	assert.ErrorIs(t, err, constants.ErrUnknownRole)
+	mockCustomerRepo.AssertNotCalled(t, "UpdateEmployee", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,264 @@
+package usecase
+
+import (
+	"cakestore/internal/auth"
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// SessionPolicy sets the lifetime of refresh tokens. The access token
+// lifetime belongs to the auth.TokenIssuer.
+type SessionPolicy struct {
+	RefreshTTL time.Duration
+}
+
+// SessionUseCase issues access/refresh token pairs and keeps the revocation
+// list that AuthMiddleware consults on every request.
+type SessionUseCase interface {
+	Issue(ctx context.Context, customer *entity.Customer) (*model.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error)
+	Logout(ctx context.Context, customerID int64, tokenID string, expiresAt time.Time, request *model.LogoutRequest) error
+	RevokeAll(ctx context.Context, customerID int64) error
+	IsRevoked(ctx context.Context, customerID int64, tokenID string, issuedAt time.Time) (bool, error)
+}
+
+type sessionUseCase struct {
+	refreshTokenRepo repository.RefreshTokenRepository
+	customerRepo     repository.CustomerRepository
+	log              *logrus.Logger
+	cache            database.RedisCache
+	issuer           auth.TokenIssuer
+	policy           SessionPolicy
+}
+
+func NewSessionUseCase(
+	refreshTokenRepo repository.RefreshTokenRepository,
+	customerRepo repository.CustomerRepository,
+	log *logrus.Logger,
+	cache database.RedisCache,
+	issuer auth.TokenIssuer,
+	policy SessionPolicy,
+) SessionUseCase {
+	if policy.RefreshTTL <= 0 {
+		policy.RefreshTTL = 30 * 24 * time.Hour
+	}
+	return &sessionUseCase{
+		refreshTokenRepo: refreshTokenRepo,
+		customerRepo:     customerRepo,
+		log:              log,
+		cache:            cache,
+		issuer:           issuer,
+		policy:           policy,
+	}
+}
+
+func (uc *sessionUseCase) Issue(ctx context.Context, customer *entity.Customer) (*model.TokenResponse, error) {
+	tokens, _, err := uc.issueWithRecord(ctx, customer, uuid.NewString())
+	return tokens, err
+}
+
+func (uc *sessionUseCase) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "SessionUseCase.Refresh")
+	defer span.End()
+
+	stored, err := uc.refreshTokenRepo.GetByHash(ctx, hashOpaqueToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.RevokedAt.Valid {
+		return nil, uc.revokeReusedFamily(ctx, stored)
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, constants.ErrInvalidRefreshToken
+	}
+
+	// Deleted employees and customers cannot keep their sessions alive
+	customer, err := uc.customerRepo.GetByID(ctx, stored.CustomerID)
+	if err != nil {
+		uc.log.Warnf("Refresh token for missing customer %d: %v", stored.CustomerID, err)
+		return nil, constants.ErrInvalidRefreshToken
+	}
+
+	tokens, next, err := uc.issueWithRecord(ctx, customer, stored.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := uc.refreshTokenRepo.Revoke(ctx, stored.ID, &next.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another request swapped the same token first
+		return nil, uc.revokeReusedFamily(ctx, stored)
+	}
+
+	return tokens, nil
+}
+
+// revokeReusedFamily ends every session descended from the same login. A
+// rotated refresh token is only ever presented again if it was copied.
+func (uc *sessionUseCase) revokeReusedFamily(ctx context.Context, stored *entity.RefreshToken) error {
+	uc.log.Warnf("Refresh token reuse detected for customer %d, revoking family %s", stored.CustomerID, stored.FamilyID)
+	if err := uc.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+		return err
+	}
+	return constants.ErrInvalidRefreshToken
+}
+
+func (uc *sessionUseCase) Logout(ctx context.Context, customerID int64, tokenID string, expiresAt time.Time, request *model.LogoutRequest) error {
+	if request.AllSessions {
+		return uc.RevokeAll(ctx, customerID)
+	}
+
+	if request.RefreshToken != "" {
+		stored, err := uc.refreshTokenRepo.GetByHash(ctx, hashOpaqueToken(request.RefreshToken))
+		if err != nil && !errors.Is(err, constants.ErrNotFound) {
+			return err
+		}
+		// Never let one account end another account's session
+		if stored != nil && stored.CustomerID == customerID {
+			if err := uc.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if tokenID == "" {
+		return nil
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := uc.cache.Set(ctx, revokedTokenKey(tokenID), true, ttl); err != nil {
+		uc.log.Errorf("Error revoking access token %s: %v", tokenID, err)
+		return err
+	}
+	return nil
+}
+
+func (uc *sessionUseCase) RevokeAll(ctx context.Context, customerID int64) error {
+	if err := uc.refreshTokenRepo.RevokeAllByCustomer(ctx, customerID); err != nil {
+		return err
+	}
+
+	// Access tokens issued before now are rejected until the last of them
+	// would have expired anyway. Tokens carry their issue time in milliseconds,
+	// so one issued straight after this call, such as the one returned by a
+	// password change, stays valid even within the same millisecond.
+	revokedBefore := time.Now().Truncate(time.Millisecond).UnixMilli()
+	if err := uc.cache.Set(ctx, revokedBeforeKey(customerID), revokedBefore, uc.issuer.TTL()); err != nil {
+		uc.log.Errorf("Error revoking access tokens for customer %d: %v", customerID, err)
+		return err
+	}
+	uc.log.Infof("Revoked all sessions for customer %d", customerID)
+	return nil
+}
+
+// IsRevoked reports whether an otherwise valid access token was revoked. It
+// fails closed: when the cache cannot be read it returns the error, so a
+// revoked token is never accepted during a Redis outage.
+func (uc *sessionUseCase) IsRevoked(ctx context.Context, customerID int64, tokenID string, issuedAt time.Time) (bool, error) {
+	if tokenID != "" {
+		var revoked bool
+		err := uc.cache.Get(ctx, revokedTokenKey(tokenID), &revoked)
+		if err != nil && !errors.Is(err, database.ErrCacheMiss) {
+			uc.log.Errorf("Error checking revocation of access token %s: %v", tokenID, err)
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	var revokedBefore int64
+	err := uc.cache.Get(ctx, revokedBeforeKey(customerID), &revokedBefore)
+	if errors.Is(err, database.ErrCacheMiss) {
+		return false, nil
+	}
+	if err != nil {
+		uc.log.Errorf("Error checking revocation for customer %d: %v", customerID, err)
+		return false, err
+	}
+	return issuedAt.UnixMilli() < revokedBefore, nil
+}
+
+func (uc *sessionUseCase) issueWithRecord(ctx context.Context, customer *entity.Customer, familyID string) (*model.TokenResponse, *entity.RefreshToken, error) {
+	now := time.Now()
+	accessToken, _, err := uc.issuer.Issue(auth.Subject{
+		CustomerID: customer.ID,
+		Email:      customer.Email,
+		Name:       customer.Name,
+		Role:       customer.Role,
+	})
+	if err != nil {
+		uc.log.Errorf("Error generating token: %v", err)
+		return nil, nil, err
+	}
+
+	refreshToken, err := newOpaqueToken()
+	if err != nil {
+		uc.log.Errorf("Error generating refresh token: %v", err)
+		return nil, nil, err
+	}
+
+	record := &entity.RefreshToken{
+		CustomerID: customer.ID,
+		FamilyID:   familyID,
+		TokenHash:  hashOpaqueToken(refreshToken),
+		ExpiresAt:  now.Add(uc.policy.RefreshTTL),
+		CreatedAt:  now,
+	}
+	if err := uc.refreshTokenRepo.Create(ctx, record); err != nil {
+		return nil, nil, err
+	}
+
+	return &model.TokenResponse{
+		AccessToken:           accessToken,
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(uc.issuer.TTL().Seconds()),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: record.ExpiresAt,
+	}, record, nil
+}
+
+// newOpaqueToken returns a random URL-safe token for refresh tokens and
+// emailed links. Only hashOpaqueToken of it is ever stored.
+func newOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func revokedTokenKey(tokenID string) string {
+	return fmt.Sprintf("auth:revoked:%s", tokenID)
+}
+
+func revokedBeforeKey(customerID int64) string {
+	return fmt.Sprintf("auth:revoked_before:%d", customerID)
+}
@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"cakestore/internal/auth"
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, token *entity.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entity.RefreshToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) Revoke(ctx context.Context, id int64, replacedByID *int64) (bool, error) {
+	args := m.Called(id, replacedByID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	args := m.Called(familyID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeAllByCustomer(ctx context.Context, customerID int64) error {
+	args := m.Called(customerID)
+	return args.Error(0)
+}
+
+type MockSessionUseCase struct {
+	mock.Mock
+}
+
+func (m *MockSessionUseCase) Issue(ctx context.Context, customer *entity.Customer) (*model.TokenResponse, error) {
+	args := m.Called(customer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TokenResponse), args.Error(1)
+}
+
+func (m *MockSessionUseCase) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	args := m.Called(refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TokenResponse), args.Error(1)
+}
+
+func (m *MockSessionUseCase) Logout(ctx context.Context, customerID int64, tokenID string, expiresAt time.Time, request *model.LogoutRequest) error {
+	args := m.Called(customerID, tokenID, expiresAt, request)
+	return args.Error(0)
+}
+
+func (m *MockSessionUseCase) RevokeAll(ctx context.Context, customerID int64) error {
+	args := m.Called(customerID)
+	return args.Error(0)
+}
+
+func (m *MockSessionUseCase) IsRevoked(ctx context.Context, customerID int64, tokenID string, issuedAt time.Time) (bool, error) {
+	args := m.Called(customerID, tokenID, issuedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func newTestTokens(t *testing.T) *auth.JWTService {
+	key, err := auth.NewHMACKey("test", []byte("secret"))
+	assert.NoError(t, err)
+	keys, err := auth.NewKeySet(key)
+	assert.NoError(t, err)
+	return auth.NewJWTService(keys, auth.Options{})
+}
+
+func TestSessionUseCase_Refresh(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("rotates the refresh token", func(t *testing.T) {
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		useCase := NewSessionUseCase(mockTokenRepo, mockCustomerRepo, logger, nil, newTestTokens(t), SessionPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("old-token")).Return(&entity.RefreshToken{
+			ID:         1,
+			CustomerID: 7,
+			FamilyID:   "family",
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7, Email: "a@example.com", Role: constants.RoleCustomer}, nil).Once()
+		mockTokenRepo.On("Create", mock.MatchedBy(func(token *entity.RefreshToken) bool {
+			token.ID = 2
+			return token.CustomerID == 7 && token.FamilyID == "family" && token.TokenHash != hashOpaqueToken("old-token")
+		})).Return(nil).Once()
+		mockTokenRepo.On("Revoke", int64(1), mock.MatchedBy(func(replacedBy *int64) bool {
+			return replacedBy != nil && *replacedBy == 2
+		})).Return(true, nil).Once()
+
+		tokens, err := useCase.Refresh(context.Background(), "old-token")
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEqual(t, "old-token", tokens.RefreshToken)
+		assert.Equal(t, int64(15*60), tokens.ExpiresIn)
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("reused token revokes the family", func(t *testing.T) {
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		useCase := NewSessionUseCase(mockTokenRepo, nil, logger, nil, newTestTokens(t), SessionPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("stolen")).Return(&entity.RefreshToken{
+			ID:         1,
+			CustomerID: 7,
+			FamilyID:   "family",
+			ExpiresAt:  time.Now().Add(time.Hour),
+			RevokedAt:  sql.NullTime{Time: time.Now(), Valid: true},
+		}, nil).Once()
+		mockTokenRepo.On("RevokeFamily", "family").Return(nil).Once()
+
+		tokens, err := useCase.Refresh(context.Background(), "stolen")
+
+		assert.ErrorIs(t, err, constants.ErrInvalidRefreshToken)
+		assert.Nil(t, tokens)
+		mockTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("deleted customer", func(t *testing.T) {
+		mockTokenRepo := new(MockRefreshTokenRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		useCase := NewSessionUseCase(mockTokenRepo, mockCustomerRepo, logger, nil, newTestTokens(t), SessionPolicy{})
+
+		mockTokenRepo.On("GetByHash", hashOpaqueToken("token")).Return(&entity.RefreshToken{
+			ID:         1,
+			CustomerID: 7,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}, nil).Once()
+		mockCustomerRepo.On("GetByID", int64(7)).Return(nil, errors.New("customer not found")).Once()
+
+		_, err := useCase.Refresh(context.Background(), "token")
+
+		assert.ErrorIs(t, err, constants.ErrInvalidRefreshToken)
+	})
+}
+
+func TestSessionUseCase_Logout(t *testing.T) {
+	logger := logrus.New()
+	mockTokenRepo := new(MockRefreshTokenRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewSessionUseCase(mockTokenRepo, nil, logger, mockCache, newTestTokens(t), SessionPolicy{})
+
+	mockTokenRepo.On("GetByHash", hashOpaqueToken("refresh")).Return(&entity.RefreshToken{ID: 1, CustomerID: 7, FamilyID: "family"}, nil).Once()
+	mockTokenRepo.On("RevokeFamily", "family").Return(nil).Once()
+	mockCache.On("Set", mock.Anything, "auth:revoked:jti-1", true, mock.Anything).Return(nil).Once()
+
+	err := useCase.Logout(context.Background(), 7, "jti-1", time.Now().Add(10*time.Minute), &model.LogoutRequest{RefreshToken: "refresh"})
+
+	assert.NoError(t, err)
+	mockTokenRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestSessionUseCase_IsRevoked(t *testing.T) {
+	logger := logrus.New()
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewSessionUseCase(nil, nil, logger, mockCache, newTestTokens(t), SessionPolicy{})
+
+	revokedAt := time.Date(2025, 6, 12, 12, 0, 0, 0, time.UTC)
+	mockCache.On("Get", mock.Anything, "auth:revoked:jti-1", mock.Anything).Return(database.ErrCacheMiss)
+	mockCache.On("Get", mock.Anything, "auth:revoked_before:7", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(2).(*int64) = revokedAt.UnixMilli()
+	}).Return(nil)
+
+	isRevoked := func(issuedAt time.Time) bool {
+		revoked, err := useCase.IsRevoked(context.Background(), 7, "jti-1", issuedAt)
+		assert.NoError(t, err)
+		return revoked
+	}
+
+	assert.True(t, isRevoked(revokedAt.Add(-time.Minute)))
+	assert.False(t, isRevoked(revokedAt.Add(time.Minute)))
+	// a token issued in the same second, after the revocation, stays valid
+	assert.True(t, isRevoked(revokedAt.Add(-300*time.Millisecond)))
+	assert.False(t, isRevoked(revokedAt.Add(300*time.Millisecond)))
+	// so does one issued in the same millisecond, such as the token returned
+	// by a password change, while the millisecond before is revoked
+	assert.False(t, isRevoked(revokedAt))
+	assert.False(t, isRevoked(revokedAt.Add(999*time.Microsecond)))
+	assert.True(t, isRevoked(revokedAt.Add(-time.Millisecond)))
+}
+
+func TestSessionUseCase_IsRevokedFailsClosed(t *testing.T) {
+	logger := logrus.New()
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewSessionUseCase(nil, nil, logger, mockCache, newTestTokens(t), SessionPolicy{})
+
+	mockCache.On("Get", mock.Anything, "auth:revoked:jti-1", mock.Anything).Return(database.ErrCacheUnavailable).Once()
+
+	_, err := useCase.IsRevoked(context.Background(), 7, "jti-1", time.Now())
+
+	assert.ErrorIs(t, err, database.ErrCacheUnavailable)
+	mockCache.AssertExpectations(t)
+}
+
+func TestCustomerUseCase_ChangePassword(t *testing.T) {
+	logger := logrus.New()
+	mockCustomerRepo := new(MockCustomerRepository)
+	mockSessions := new(MockSessionUseCase)
+	useCase := NewCustomerUseCase(mockCustomerRepo, logger, mockSessions, nil, nil, nil, nil, nil)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+
+	t.Run("wrong current password", func(t *testing.T) {
+		mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7, Password: string(hashed)}, nil).Once()
+
+		err := useCase.ChangePassword(context.Background(), 7, &model.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "new-password"})
+
+		assert.ErrorIs(t, err, constants.ErrInvalidPassword)
+		mockSessions.AssertNotCalled(t, "RevokeAll", mock.Anything)
+	})
+
+	t.Run("revokes every session", func(t *testing.T) {
+		mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7, Password: string(hashed)}, nil).Once()
+		mockCustomerRepo.On("Update", mock.MatchedBy(func(c *entity.Customer) bool {
+			return bcrypt.CompareHashAndPassword([]byte(c.Password), []byte("new-password")) == nil
+		})).Return(nil).Once()
+		mockSessions.On("RevokeAll", int64(7)).Return(nil).Once()
+
+		err := useCase.ChangePassword(context.Background(), 7, &model.ChangePasswordRequest{CurrentPassword: "old-password", NewPassword: "new-password"})
+
+		assert.NoError(t, err)
+		mockSessions.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,273 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ShiftUseCase manages the cash drawer: opening a shift with a float, recording
+// refunds and payouts against it, and reconciling the counted cash at close.
+type ShiftUseCase interface {
+	OpenShift(ctx context.Context, cashierID int64, request *model.OpenShiftRequest) (*model.ShiftResponse, error)
+	GetCurrentShift(ctx context.Context, cashierID int64) (*model.ShiftResponse, error)
+	RecordTransaction(ctx context.Context, cashierID int64, request *model.ShiftTransactionRequest) (*model.ShiftResponse, error)
+	CloseShift(ctx context.Context, cashierID int64, request *model.CloseShiftRequest) (*model.ShiftResponse, error)
+	GetZReport(ctx context.Context, date time.Time) (*model.ZReportResponse, error)
+}
+
+type shiftUseCase struct {
+	shiftRepo   repository.ShiftRepository
+	receiptRepo repository.ReceiptRepository
+	paymentRepo repository.PaymentRepository
+	log         *logrus.Logger
+	taxRate     float64
+}
+
+func NewShiftUseCase(
+	shiftRepo repository.ShiftRepository,
+	receiptRepo repository.ReceiptRepository,
+	paymentRepo repository.PaymentRepository,
+	log *logrus.Logger,
+	taxRate float64,
+) ShiftUseCase {
+	return &shiftUseCase{
+		shiftRepo:   shiftRepo,
+		receiptRepo: receiptRepo,
+		paymentRepo: paymentRepo,
+		log:         log,
+		taxRate:     taxRate,
+	}
+}
+
+func (u *shiftUseCase) OpenShift(ctx context.Context, cashierID int64, request *model.OpenShiftRequest) (*model.ShiftResponse, error) {
+	if _, err := u.shiftRepo.GetOpenByCashierID(ctx, cashierID); err == nil {
+		return nil, constants.ErrShiftAlreadyOpen
+	} else if !errors.Is(err, constants.ErrNoOpenShift) {
+		return nil, err
+	}
+
+	shift := &entity.CashierShift{
+		CashierID:    cashierID,
+		Status:       entity.ShiftStatusOpen,
+		OpeningFloat: request.OpeningFloat,
+		OpenedAt:     time.Now(),
+	}
+	if err := u.shiftRepo.Create(ctx, shift); err != nil {
+		return nil, err
+	}
+
+	u.log.Infof("Cashier %d opened shift %d with a float of %.0f", cashierID, shift.ID, shift.OpeningFloat)
+	return u.GetCurrentShift(ctx, cashierID)
+}
+
+func (u *shiftUseCase) GetCurrentShift(ctx context.Context, cashierID int64) (*model.ShiftResponse, error) {
+	shift, err := u.shiftRepo.GetOpenByCashierID(ctx, cashierID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.buildShiftResponse(ctx, shift, true)
+}
+
+func (u *shiftUseCase) RecordTransaction(ctx context.Context, cashierID int64, request *model.ShiftTransactionRequest) (*model.ShiftResponse, error) {
+	shift, err := u.shiftRepo.GetOpenByCashierID(ctx, cashierID)
+	if err != nil {
+		return nil, err
+	}
+
+	method := request.Method
+	if method == "" || request.Type == entity.ShiftTransactionPayout {
+		method = constants.PaymentMethodCash
+	}
+
+	transaction := &entity.ShiftTransaction{
+		ShiftID: shift.ID,
+		Type:    request.Type,
+		Method:  method,
+		Amount:  request.Amount,
+		OrderID: request.OrderID,
+		Reason:  request.Reason,
+	}
+	if err := u.shiftRepo.CreateTransaction(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	return u.buildShiftResponse(ctx, shift, true)
+}
+
+// CloseShift compares the cash counted in the drawer with what the shift's
+// transactions say should be there; a negative variance means cash is missing.
+func (u *shiftUseCase) CloseShift(ctx context.Context, cashierID int64, request *model.CloseShiftRequest) (*model.ShiftResponse, error) {
+	shift, err := u.shiftRepo.GetOpenByCashierID(ctx, cashierID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := u.buildShiftResponse(ctx, shift, true)
+	if err != nil {
+		return nil, err
+	}
+
+	shift.Status = entity.ShiftStatusClosed
+	shift.ExpectedAmount = response.ExpectedAmount
+	shift.CountedAmount = request.CountedAmount
+	shift.Variance = request.CountedAmount - response.ExpectedAmount
+	shift.Notes = request.Notes
+	shift.ClosedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := u.shiftRepo.Close(ctx, shift); err != nil {
+		return nil, err
+	}
+
+	if shift.Variance != 0 {
+		u.log.Warnf("Shift %d closed with a variance of %.0f", shift.ID, shift.Variance)
+	}
+	return u.buildShiftResponse(ctx, shift, true)
+}
+
+func (u *shiftUseCase) GetZReport(ctx context.Context, date time.Time) (*model.ZReportResponse, error) {
+	ctx, span := tracer.Start(ctx, "ShiftUseCase.GetZReport")
+	defer span.End()
+
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	payments, err := u.paymentRepo.GetSuccessfulPaymentsByDateRange(ctx, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := u.shiftRepo.GetTransactionsByDateRange(ctx, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+	shifts, err := u.shiftRepo.GetByDateRange(ctx, dayStart, dayEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ZReportResponse{
+		Date:    dayStart.Format("2006-01-02"),
+		TaxRate: u.taxRate,
+		Shifts:  make([]model.ShiftResponse, 0, len(shifts)),
+	}
+
+	sales := make(map[constants.PaymentMethod]*model.MethodTotal)
+	for _, payment := range payments {
+		addMethodTotal(sales, payment.Method, payment.Amount)
+		report.GrossSales += payment.Amount
+	}
+	refunds := make(map[constants.PaymentMethod]*model.MethodTotal)
+	for _, transaction := range transactions {
+		switch transaction.Type {
+		case entity.ShiftTransactionRefund:
+			addMethodTotal(refunds, transaction.Method, transaction.Amount)
+			report.Refunds += transaction.Amount
+		case entity.ShiftTransactionPayout:
+			report.Payouts += transaction.Amount
+		}
+	}
+	report.SalesByMethod = sortedMethodTotals(sales)
+	report.RefundsByMethod = sortedMethodTotals(refunds)
+
+	report.NetSales = report.GrossSales - report.Refunds
+	report.Tax = math.Round(report.NetSales * u.taxRate / (100 + u.taxRate))
+
+	for _, shift := range shifts {
+		response, err := u.buildShiftResponse(ctx, &shift, false)
+		if err != nil {
+			return nil, err
+		}
+		report.Shifts = append(report.Shifts, *response)
+		if response.Variance != nil {
+			report.CashVariance += *response.Variance
+		}
+	}
+
+	return report, nil
+}
+
+func (u *shiftUseCase) buildShiftResponse(ctx context.Context, shift *entity.CashierShift, withTransactions bool) (*model.ShiftResponse, error) {
+	receipts, err := u.receiptRepo.GetByShiftID(ctx, shift.ID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := u.shiftRepo.GetTransactions(ctx, shift.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &model.ShiftResponse{
+		ID:           shift.ID,
+		CashierID:    shift.CashierID,
+		Cashier:      shift.Cashier.Name,
+		Status:       shift.Status,
+		OpeningFloat: shift.OpeningFloat,
+		Notes:        shift.Notes,
+		OpenedAt:     shift.OpenedAt.Format(time.RFC3339),
+	}
+
+	for _, receipt := range receipts {
+		switch receipt.Method {
+		case constants.PaymentMethodCash:
+			response.CashSales += receipt.Amount
+		case constants.PaymentMethodCard:
+			response.CardSales += receipt.Amount
+		}
+	}
+	for _, transaction := range transactions {
+		switch {
+		case transaction.Type == entity.ShiftTransactionPayout:
+			response.Payouts += transaction.Amount
+		case transaction.Method == constants.PaymentMethodCard:
+			response.CardRefunds += transaction.Amount
+		default:
+			response.CashRefunds += transaction.Amount
+		}
+		if withTransactions {
+			response.Transactions = append(response.Transactions, model.ToShiftTransactionResponse(&transaction))
+		}
+	}
+	response.ExpectedAmount = shift.OpeningFloat + response.CashSales - response.CashRefunds - response.Payouts
+
+	if shift.Status == entity.ShiftStatusClosed {
+		counted, variance := shift.CountedAmount, shift.Variance
+		response.CountedAmount = &counted
+		response.Variance = &variance
+		response.ClosedAt = shift.ClosedAt.Time.Format(time.RFC3339)
+	}
+
+	return response, nil
+}
+
+func addMethodTotal(totals map[constants.PaymentMethod]*model.MethodTotal, method constants.PaymentMethod, amount float64) {
+	if method == "" {
+		method = constants.PaymentMethodMidtrans
+	}
+	total, ok := totals[method]
+	if !ok {
+		total = &model.MethodTotal{Method: method}
+		totals[method] = total
+	}
+	total.Count++
+	total.Amount += amount
+}
+
+func sortedMethodTotals(totals map[constants.PaymentMethod]*model.MethodTotal) []model.MethodTotal {
+	result := make([]model.MethodTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockShiftRepository struct {
+	mock.Mock
+}
+
+func (m *MockShiftRepository) Create(ctx context.Context, shift *entity.CashierShift) error {
+	args := m.Called(shift)
+	return args.Error(0)
+}
+
+func (m *MockShiftRepository) GetOpenByCashierID(ctx context.Context, cashierID int64) (*entity.CashierShift, error) {
+	args := m.Called(cashierID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.CashierShift), args.Error(1)
+}
+
+func (m *MockShiftRepository) GetByDateRange(ctx context.Context, start, end time.Time) ([]entity.CashierShift, error) {
+	args := m.Called(start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.CashierShift), args.Error(1)
+}
+
+func (m *MockShiftRepository) Close(ctx context.Context, shift *entity.CashierShift) error {
+	args := m.Called(shift)
+	return args.Error(0)
+}
+
+func (m *MockShiftRepository) CreateTransaction(ctx context.Context, transaction *entity.ShiftTransaction) error {
+	args := m.Called(transaction)
+	return args.Error(0)
+}
+
+func (m *MockShiftRepository) GetTransactions(ctx context.Context, shiftID int64) ([]entity.ShiftTransaction, error) {
+	args := m.Called(shiftID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.ShiftTransaction), args.Error(1)
+}
+
+func (m *MockShiftRepository) GetTransactionsByDateRange(ctx context.Context, start, end time.Time) ([]entity.ShiftTransaction, error) {
+	args := m.Called(start, end)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]entity.ShiftTransaction), args.Error(1)
+}
+
+func TestShiftUseCase_OpenShift(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("rejects a second open shift", func(t *testing.T) {
+		mockShiftRepo := new(MockShiftRepository)
+		useCase := NewShiftUseCase(mockShiftRepo, nil, nil, logger, 0)
+
+		mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(&entity.CashierShift{ID: 1}, nil).Once()
+
+		shift, err := useCase.OpenShift(context.Background(), 9, &model.OpenShiftRequest{OpeningFloat: 200000})
+
+		assert.ErrorIs(t, err, constants.ErrShiftAlreadyOpen)
+		assert.Nil(t, shift)
+		mockShiftRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestShiftUseCase_CloseShift(t *testing.T) {
+	logger := logrus.New()
+	mockShiftRepo := new(MockShiftRepository)
+	mockReceiptRepo := new(MockReceiptRepository)
+	useCase := NewShiftUseCase(mockShiftRepo, mockReceiptRepo, nil, logger, 0)
+
+	shift := &entity.CashierShift{ID: 1, CashierID: 9, Status: entity.ShiftStatusOpen, OpeningFloat: 200000, OpenedAt: time.Now()}
+	mockShiftRepo.On("GetOpenByCashierID", int64(9)).Return(shift, nil).Once()
+	mockReceiptRepo.On("GetByShiftID", int64(1)).Return([]entity.Receipt{
+		{Method: constants.PaymentMethodCash, Amount: 150000},
+		{Method: constants.PaymentMethodCard, Amount: 80000},
+	}, nil)
+	mockShiftRepo.On("GetTransactions", int64(1)).Return([]entity.ShiftTransaction{
+		{Type: entity.ShiftTransactionRefund, Method: constants.PaymentMethodCash, Amount: 20000},
+		{Type: entity.ShiftTransactionRefund, Method: constants.PaymentMethodCard, Amount: 10000},
+		{Type: entity.ShiftTransactionPayout, Method: constants.PaymentMethodCash, Amount: 30000},
+	}, nil)
+	mockShiftRepo.On("Close", mock.MatchedBy(func(s *entity.CashierShift) bool {
+		return s.ExpectedAmount == 300000 && s.Variance == -5000
+	})).Return(nil).Once()
+
+	response, err := useCase.CloseShift(context.Background(), 9, &model.CloseShiftRequest{CountedAmount: 295000})
+
+	assert.NoError(t, err)
+	// 200000 float + 150000 cash sales - 20000 cash refund - 30000 payout
+	assert.Equal(t, 300000.0, response.ExpectedAmount)
+	assert.Equal(t, 10000.0, response.CardRefunds)
+	assert.Equal(t, -5000.0, *response.Variance)
+	assert.Equal(t, entity.ShiftStatusClosed, response.Status)
+	mockShiftRepo.AssertExpectations(t)
+}
+
+func TestShiftUseCase_GetZReport(t *testing.T) {
+	logger := logrus.New()
+	mockShiftRepo := new(MockShiftRepository)
+	mockReceiptRepo := new(MockReceiptRepository)
+	mockPaymentRepo := new(MockPaymentRepository)
+	useCase := NewShiftUseCase(mockShiftRepo, mockReceiptRepo, mockPaymentRepo, logger, 10)
+
+	date := time.Date(2025, 6, 12, 15, 0, 0, 0, time.UTC)
+	dayStart := time.Date(2025, 6, 12, 0, 0, 0, 0, time.UTC)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	mockPaymentRepo.On("GetSuccessfulPaymentsByDateRange", dayStart, dayEnd).Return([]entity.Payment{
+		{Method: constants.PaymentMethodCash, Amount: 100000},
+		{Method: constants.PaymentMethodCash, Amount: 50000},
+		{Method: constants.PaymentMethodMidtrans, Amount: 70000},
+	}, nil).Once()
+	mockShiftRepo.On("GetTransactionsByDateRange", dayStart, dayEnd).Return([]entity.ShiftTransaction{
+		{Type: entity.ShiftTransactionRefund, Method: constants.PaymentMethodCash, Amount: 55000},
+		{Type: entity.ShiftTransactionPayout, Method: constants.PaymentMethodCash, Amount: 5000},
+	}, nil).Once()
+	mockShiftRepo.On("GetByDateRange", dayStart, dayEnd).Return([]entity.CashierShift{
+		{ID: 1, Status: entity.ShiftStatusClosed, CountedAmount: 100000, Variance: -2000},
+	}, nil).Once()
+	mockReceiptRepo.On("GetByShiftID", int64(1)).Return([]entity.Receipt{}, nil)
+	mockShiftRepo.On("GetTransactions", int64(1)).Return([]entity.ShiftTransaction{}, nil)
+
+	report, err := useCase.GetZReport(context.Background(), date)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-06-12", report.Date)
+	assert.Equal(t, 220000.0, report.GrossSales)
+	assert.Equal(t, []model.MethodTotal{
+		{Method: constants.PaymentMethodCash, Count: 2, Amount: 150000},
+		{Method: constants.PaymentMethodMidtrans, Count: 1, Amount: 70000},
+	}, report.SalesByMethod)
+	assert.Equal(t, 55000.0, report.Refunds)
+	assert.Equal(t, 5000.0, report.Payouts)
+	assert.Equal(t, 165000.0, report.NetSales)
+	assert.Equal(t, 15000.0, report.Tax)
+	assert.Equal(t, -2000.0, report.CashVariance)
+}
@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type TableSessionUseCase interface {
+	GetQRCode(ctx context.Context, tableID uint) (*model.TableQRCodeResponse, error)
+	RotateQRCode(ctx context.Context, tableID uint) (*model.TableQRCodeResponse, error)
+	ResolveToken(ctx context.Context, token string) (*entity.TableSession, error)
+	CloseSession(ctx context.Context, tableID uint) error
+}
+
+type tableSessionUseCase struct {
+	sessionRepo   repository.TableSessionRepository
+	tableRepo     repository.TableRepository
+	customerRepo  repository.CustomerRepository
+	log           *logrus.Logger
+	cache         database.RedisCache
+	secret        string
+	guestOrderURL string
+}
+
+func NewTableSessionUseCase(
+	sessionRepo repository.TableSessionRepository,
+	tableRepo repository.TableRepository,
+	customerRepo repository.CustomerRepository,
+	log *logrus.Logger,
+	cache database.RedisCache,
+	secret string,
+	guestOrderURL string,
+) TableSessionUseCase {
+	return &tableSessionUseCase{
+		sessionRepo:   sessionRepo,
+		tableRepo:     tableRepo,
+		customerRepo:  customerRepo,
+		log:           log,
+		cache:         cache,
+		secret:        secret,
+		guestOrderURL: guestOrderURL,
+	}
+}
+
+func (u *tableSessionUseCase) GetQRCode(ctx context.Context, tableID uint) (*model.TableQRCodeResponse, error) {
+	table, err := u.tableRepo.GetByID(ctx, tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.toQRCodeResponse(table.ID, table.TableNumber, table.QRTokenVersion), nil
+}
+
+// RotateQRCode invalidates every previously printed QR code for the table and
+// closes the table's open session, if any.
+func (u *tableSessionUseCase) RotateQRCode(ctx context.Context, tableID uint) (*model.TableQRCodeResponse, error) {
+	table, err := u.tableRepo.GetByID(ctx, tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := u.tableRepo.IncrementQRTokenVersion(ctx, tableID)
+	if err != nil {
+		u.log.Errorf("Error rotating QR token for table ID %d: %v", tableID, err)
+		return nil, err
+	}
+
+	if err := u.closeOpenSession(ctx, tableID); err != nil && !errors.Is(err, constants.ErrNotFound) {
+		u.log.Errorf("Error closing session for table ID %d: %v", tableID, err)
+		return nil, err
+	}
+
+	return u.toQRCodeResponse(table.ID, table.TableNumber, version), nil
+}
+
+// ResolveToken verifies a QR token and returns the table's open session,
+// opening a new one with its own guest account on the first scan.
+func (u *tableSessionUseCase) ResolveToken(ctx context.Context, token string) (*entity.TableSession, error) {
+	tableID, version, err := u.parseToken(token)
+	if err != nil {
+		return nil, constants.ErrInvalidTableToken
+	}
+
+	// Try to get the open session from the cache first
+	cacheKey := fmt.Sprintf("table_session:open:%d", tableID)
+	var cached entity.TableSession
+	if err := u.cache.Get(ctx, cacheKey, &cached); err == nil {
+		if cached.TokenVersion != version {
+			return nil, constants.ErrInvalidTableToken
+		}
+		return &cached, nil
+	}
+
+	table, err := u.tableRepo.GetByID(ctx, uint(tableID))
+	if err != nil {
+		return nil, constants.ErrInvalidTableToken
+	}
+	if table.QRTokenVersion != version {
+		return nil, constants.ErrInvalidTableToken
+	}
+
+	session, err := u.sessionRepo.GetOpenByTableID(ctx, tableID)
+	if err != nil {
+		if !errors.Is(err, constants.ErrNotFound) {
+			return nil, err
+		}
+		session, err = u.openSession(ctx, table, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if session.TokenVersion != version {
+		return nil, constants.ErrInvalidTableToken
+	}
+
+	if err := u.cache.Set(ctx, cacheKey, session, 5*time.Minute); err != nil {
+		u.log.Errorf("Error setting cache for table session: %v", err)
+	}
+
+	return session, nil
+}
+
+// CloseSession ends the table's open session and rotates its QR token, so
+// the code the leaving party scanned cannot reopen the table and show the
+// next party's orders. Staff show or print the new code from GetQRCode when
+// seating the next party.
+func (u *tableSessionUseCase) CloseSession(ctx context.Context, tableID uint) error {
+	session, err := u.sessionRepo.GetOpenByTableID(ctx, int64(tableID))
+	if err != nil {
+		return err
+	}
+
+	// Rotating first means a failed close can be retried, while the old
+	// token already stops working
+	if _, err := u.tableRepo.IncrementQRTokenVersion(ctx, tableID); err != nil {
+		u.log.Errorf("Error rotating QR token for table ID %d: %v", tableID, err)
+		return err
+	}
+
+	return u.close(ctx, tableID, session)
+}
+
+// closeOpenSession closes the table's open session without rotating its token.
+func (u *tableSessionUseCase) closeOpenSession(ctx context.Context, tableID uint) error {
+	session, err := u.sessionRepo.GetOpenByTableID(ctx, int64(tableID))
+	if err != nil {
+		return err
+	}
+	return u.close(ctx, tableID, session)
+}
+
+func (u *tableSessionUseCase) close(ctx context.Context, tableID uint, session *entity.TableSession) error {
+	if err := u.sessionRepo.Close(ctx, session.ID); err != nil {
+		return err
+	}
+
+	// Invalidate cache
+	cacheKey := fmt.Sprintf("table_session:open:%d", tableID)
+	if err := u.cache.Delete(ctx, cacheKey); err != nil {
+		u.log.Errorf("Error deleting cache for table session: %v", err)
+	}
+
+	return nil
+}
+
+func (u *tableSessionUseCase) openSession(ctx context.Context, table *entity.Table, version int) (*entity.TableSession, error) {
+	// Guests never log in; the account only exists so orders and payments
+	// can reference a customer like any other order.
+	guest := &entity.Customer{
+		Name:      fmt.Sprintf("Table %d Guest", table.TableNumber),
+		Email:     fmt.Sprintf("guest-%s@table-%d.guest", uuid.New().String(), table.TableNumber),
+		Role:      constants.RoleGuest,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := u.customerRepo.Create(ctx, guest); err != nil {
+		u.log.Errorf("Error creating guest customer: %v", err)
+		return nil, err
+	}
+
+	session := &entity.TableSession{
+		TableID:         table.ID,
+		Table:           *table,
+		GuestCustomerID: guest.ID,
+		GuestCustomer:   *guest,
+		TokenVersion:    version,
+		Status:          entity.TableSessionStatusOpen,
+		OpenedAt:        time.Now(),
+	}
+	if err := u.sessionRepo.Create(ctx, session); err != nil {
+		// A concurrent scan may have opened the session first
+		if existing, getErr := u.sessionRepo.GetOpenByTableID(ctx, table.ID); getErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	u.log.Infof("Opened session %d for table %d", session.ID, table.TableNumber)
+	return session, nil
+}
+
+func (u *tableSessionUseCase) toQRCodeResponse(tableID int64, tableNumber, version int) *model.TableQRCodeResponse {
+	token := u.signToken(tableID, version)
+
+	var qrURL string
+	if u.guestOrderURL != "" {
+		qrURL = u.guestOrderURL + "?" + constants.TableTokenQuery + "=" + url.QueryEscape(token)
+	}
+
+	return &model.TableQRCodeResponse{
+		TableID:     tableID,
+		TableNumber: tableNumber,
+		Version:     version,
+		Token:       token,
+		URL:         qrURL,
+	}
+}
+
+// signToken produces "<base64 payload>.<base64 HMAC-SHA256>" where the payload is "table:<id>:<version>"
+func (u *tableSessionUseCase) signToken(tableID int64, version int) string {
+	payload := fmt.Sprintf("table:%d:%d", tableID, version)
+	mac := hmac.New(sha256.New, []byte(u.secret))
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (u *tableSessionUseCase) parseToken(token string) (int64, int, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, 0, constants.ErrInvalidTableToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, 0, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(u.secret))
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return 0, 0, constants.ErrInvalidTableToken
+	}
+
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 || parts[0] != "table" {
+		return 0, 0, constants.ErrInvalidTableToken
+	}
+	tableID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	version, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tableID, version, nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTableSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockTableSessionRepository) Create(ctx context.Context, session *entity.TableSession) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *MockTableSessionRepository) GetByID(ctx context.Context, id int64) (*entity.TableSession, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TableSession), args.Error(1)
+}
+
+func (m *MockTableSessionRepository) GetOpenByTableID(ctx context.Context, tableID int64) (*entity.TableSession, error) {
+	args := m.Called(tableID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TableSession), args.Error(1)
+}
+
+func (m *MockTableSessionRepository) Close(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func TestTableSessionUseCase_ResolveToken(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("opens a session on first scan", func(t *testing.T) {
+		mockSessionRepo := new(MockTableSessionRepository)
+		mockTableRepo := new(MockTableRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewTableSessionUseCase(mockSessionRepo, mockTableRepo, mockCustomerRepo, logger, mockCache, "secret", "")
+
+		table := &entity.Table{ID: 3, TableNumber: 7, QRTokenVersion: 1}
+		mockTableRepo.On("GetByID", uint(3)).Return(table, nil)
+		qr, err := useCase.GetQRCode(context.Background(), 3)
+		assert.NoError(t, err)
+
+		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
+		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockSessionRepo.On("GetOpenByTableID", int64(3)).Return(nil, constants.ErrNotFound).Once()
+		mockCustomerRepo.On("Create", mock.MatchedBy(func(c *entity.Customer) bool {
+			return c.Role == constants.RoleGuest
+		})).Return(nil).Once()
+		mockSessionRepo.On("Create", mock.Anything).Return(nil).Once()
+
+		session, err := useCase.ResolveToken(context.Background(), qr.Token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), session.TableID)
+		assert.Equal(t, entity.TableSessionStatusOpen, session.Status)
+		mockCustomerRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejects a tampered token", func(t *testing.T) {
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewTableSessionUseCase(nil, nil, nil, logger, mockCache, "secret", "")
+		other := NewTableSessionUseCase(nil, nil, nil, logger, mockCache, "other-secret", "")
+
+		token := other.(*tableSessionUseCase).signToken(3, 1)
+		session, err := useCase.ResolveToken(context.Background(), token)
+
+		assert.ErrorIs(t, err, constants.ErrInvalidTableToken)
+		assert.Nil(t, session)
+	})
+
+	t.Run("rejects a token from before rotation", func(t *testing.T) {
+		mockTableRepo := new(MockTableRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewTableSessionUseCase(nil, mockTableRepo, nil, logger, mockCache, "secret", "")
+
+		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
+		mockTableRepo.On("GetByID", uint(3)).Return(&entity.Table{ID: 3, QRTokenVersion: 2}, nil)
+
+		token := useCase.(*tableSessionUseCase).signToken(3, 1)
+		session, err := useCase.ResolveToken(context.Background(), token)
+
+		assert.ErrorIs(t, err, constants.ErrInvalidTableToken)
+		assert.Nil(t, session)
+	})
+}
+
+func TestTableSessionUseCase_RotateQRCode(t *testing.T) {
+	logger := logrus.New()
+	mockSessionRepo := new(MockTableSessionRepository)
+	mockTableRepo := new(MockTableRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewTableSessionUseCase(mockSessionRepo, mockTableRepo, nil, logger, mockCache, "secret", "https://example.com/table")
+
+	mockTableRepo.On("GetByID", uint(3)).Return(&entity.Table{ID: 3, TableNumber: 7, QRTokenVersion: 1}, nil)
+	mockTableRepo.On("IncrementQRTokenVersion", uint(3)).Return(2, nil).Once()
+	mockSessionRepo.On("GetOpenByTableID", int64(3)).Return(&entity.TableSession{ID: 11, TableID: 3}, nil).Once()
+	mockSessionRepo.On("Close", int64(11)).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, "table_session:open:3").Return(nil).Once()
+
+	qr, err := useCase.RotateQRCode(context.Background(), 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, qr.Version)
+	assert.Contains(t, qr.URL, "https://example.com/table?token=")
+	mockSessionRepo.AssertExpectations(t)
+	mockTableRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestTableSessionUseCase_CloseSessionRotatesToken(t *testing.T) {
+	logger := logrus.New()
+	mockSessionRepo := new(MockTableSessionRepository)
+	mockTableRepo := new(MockTableRepository)
+	mockCache := new(database.MockRedisCacheService)
+	useCase := NewTableSessionUseCase(mockSessionRepo, mockTableRepo, nil, logger, mockCache, "secret", "")
+
+	mockSessionRepo.On("GetOpenByTableID", int64(3)).Return(&entity.TableSession{ID: 11, TableID: 3, TokenVersion: 1}, nil).Once()
+	mockTableRepo.On("IncrementQRTokenVersion", uint(3)).Return(2, nil).Once()
+	mockSessionRepo.On("Close", int64(11)).Return(nil).Once()
+	mockCache.On("Delete", mock.Anything, "table_session:open:3").Return(nil).Once()
+
+	err := useCase.CloseSession(context.Background(), 3)
+	assert.NoError(t, err)
+
+	// The leaving party's token no longer opens the table
+	mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
+	mockTableRepo.On("GetByID", uint(3)).Return(&entity.Table{ID: 3, QRTokenVersion: 2}, nil)
+	session, err := useCase.ResolveToken(context.Background(), useCase.(*tableSessionUseCase).signToken(3, 1))
+
+	assert.ErrorIs(t, err, constants.ErrInvalidTableToken)
+	assert.Nil(t, session)
+	mockSessionRepo.AssertExpectations(t)
+	mockTableRepo.AssertExpectations(t)
+}
@@ -4,6 +4,7 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -17,12 +18,12 @@ type MockTableRepository struct {
 	mock.Mock
 }
 
-func (m *MockTableRepository) Create(table *entity.Table) error {
+func (m *MockTableRepository) Create(ctx context.Context, table *entity.Table) error {
 	args := m.Called(table)
 	return args.Error(0)
 }
 
-func (m *MockTableRepository) GetByID(id uint) (*entity.Table, error) {
+func (m *MockTableRepository) GetByID(ctx context.Context, id uint) (*entity.Table, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -30,7 +31,7 @@ func (m *MockTableRepository) GetByID(id uint) (*entity.Table, error) {
 	return args.Get(0).(*entity.Table), args.Error(1)
 }
 
-func (m *MockTableRepository) GetAll() ([]entity.Table, error) {
+func (m *MockTableRepository) GetAll(ctx context.Context) ([]entity.Table, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -38,17 +39,17 @@ func (m *MockTableRepository) GetAll() ([]entity.Table, error) {
 	return args.Get(0).([]entity.Table), args.Error(1)
 }
 
-func (m *MockTableRepository) Update(table *entity.Table) error {
+func (m *MockTableRepository) Update(ctx context.Context, table *entity.Table) error {
 	args := m.Called(table)
 	return args.Error(0)
 }
 
-func (m *MockTableRepository) Delete(id uint) error {
+func (m *MockTableRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
-func (m *MockTableRepository) GetAvailableTables(reserveTime time.Time, duration time.Duration) ([]entity.Table, error) {
+func (m *MockTableRepository) GetAvailableTables(ctx context.Context, reserveTime time.Time, duration time.Duration) ([]entity.Table, error) {
 	args := m.Called(reserveTime, duration)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -56,16 +57,21 @@ func (m *MockTableRepository) GetAvailableTables(reserveTime time.Time, duration
 	return args.Get(0).([]entity.Table), args.Error(1)
 }
 
-func (m *MockTableRepository) UpdateAvailability(id uint, isAvailable bool) error {
+func (m *MockTableRepository) UpdateAvailability(ctx context.Context, id uint, isAvailable bool) error {
 	args := m.Called(id, isAvailable)
 	return args.Error(0)
 }
 
-func (m *MockTableRepository) Count() (int64, error) {
+func (m *MockTableRepository) Count(ctx context.Context) (int64, error) {
 	args := m.Called()
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockTableRepository) IncrementQRTokenVersion(ctx context.Context, id uint) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
 func TestTableUseCase_GetByID(t *testing.T) {
 	logger := logrus.New()
 	mockTableRepo := new(MockTableRepository)
@@ -80,7 +86,7 @@ func TestTableUseCase_GetByID(t *testing.T) {
 		mockTableRepo.On("GetByID", uint(1)).Return(expectedTable, nil).Once()
 		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
-		table, err := useCase.GetByID(1)
+		table, err := useCase.GetByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, table)
@@ -92,7 +98,7 @@ func TestTableUseCase_GetByID(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockTableRepo.On("GetByID", uint(1)).Return(nil, errors.New("not found")).Once()
 
-		table, err := useCase.GetByID(1)
+		table, err := useCase.GetByID(context.Background(), 1)
 
 		assert.Error(t, err)
 		assert.Nil(t, table)
@@ -115,9 +121,9 @@ func TestTableUseCase_GetAll(t *testing.T) {
 		params := &model.TableQueryParams{}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockTableRepo.On("GetAll").Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "tables:all:page:0:limit:0:capacity:0:available:any", mock.Anything, mock.Anything, []string{tableListTag}).Return(nil)
 
-		tables, err := useCase.GetAll(params)
+		tables, err := useCase.GetAll(context.Background(), params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, tables)
@@ -130,7 +136,7 @@ func TestTableUseCase_GetAll(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockTableRepo.On("GetAll").Return(nil, errors.New("error")).Once()
 
-		tables, err := useCase.GetAll(params)
+		tables, err := useCase.GetAll(context.Background(), params)
 
 		assert.Error(t, err)
 		assert.Nil(t, tables)
@@ -154,9 +160,9 @@ func TestTableUseCase_GetAvailableTables(t *testing.T) {
 		duration := time.Hour
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockTableRepo.On("GetAvailableTables", reserveTime, duration).Return(expectedResponse, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, mock.Anything, mock.Anything, mock.Anything, []string{availableTablesTag}).Return(nil)
 
-		tables, err := useCase.GetAvailableTables(reserveTime, duration)
+		tables, err := useCase.GetAvailableTables(context.Background(), reserveTime, duration)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, tables)
@@ -170,7 +176,7 @@ func TestTableUseCase_GetAvailableTables(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockTableRepo.On("GetAvailableTables", reserveTime, duration).Return(nil, errors.New("error")).Once()
 
-		tables, err := useCase.GetAvailableTables(reserveTime, duration)
+		tables, err := useCase.GetAvailableTables(context.Background(), reserveTime, duration)
 
 		assert.Error(t, err)
 		assert.Nil(t, tables)
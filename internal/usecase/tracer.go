@@ -0,0 +1,8 @@
+package usecase
+
+import "go.opentelemetry.io/otel"
+
+// tracer starts the use case spans, named after the interface method, such
+// as OrderUseCase.GetOrderByID. The HTTP, GORM and Redis spans nest under
+// them through the context passed down.
+var tracer = otel.Tracer("cakestore/internal/usecase")
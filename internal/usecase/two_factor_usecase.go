@@ -0,0 +1,467 @@
+package usecase
+
+import (
+	"cakestore/internal/auth"
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/internal/repository"
+	"cakestore/utils"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorPolicy configures TOTP two-factor authentication. Secrets are
+// encrypted with EncryptionKey (32 bytes); without a key nobody can enrol.
+// Accounts in RequiredRoles must set it up at their next login.
+type TwoFactorPolicy struct {
+	Issuer        string
+	EncryptionKey []byte
+	RequiredRoles []string
+	ChallengeTTL  time.Duration
+}
+
+// TwoFactorUseCase handles TOTP enrolment and the second login step.
+type TwoFactorUseCase interface {
+	// Challenge returns nil when the customer can be logged in right away
+	Challenge(ctx context.Context, customer *entity.Customer) (*model.TwoFactorChallenge, error)
+	VerifyLogin(ctx context.Context, request *model.TwoFactorLoginRequest, clientIP string) (*model.TokenResponse, error)
+	EnrollWithChallenge(ctx context.Context, request *model.TwoFactorTokenRequest) (*model.TwoFactorEnrollment, error)
+	ActivateWithChallenge(ctx context.Context, request *model.TwoFactorLoginRequest, clientIP string) (*model.TwoFactorActivation, error)
+
+	Status(ctx context.Context, customerID int64) (*model.TwoFactorStatus, error)
+	Enroll(ctx context.Context, customerID int64) (*model.TwoFactorEnrollment, error)
+	Activate(ctx context.Context, customerID int64, request *model.TwoFactorCodeRequest) (*model.TwoFactorActivation, error)
+	Disable(ctx context.Context, customerID int64, request *model.DisableTwoFactorRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, customerID int64, request *model.TwoFactorCodeRequest) (*model.TwoFactorActivation, error)
+}
+
+type twoFactorUseCase struct {
+	repo         repository.TwoFactorRepository
+	customerRepo repository.CustomerRepository
+	sessions     SessionUseCase
+	attempts     LoginAttemptUseCase
+	log          *logrus.Logger
+	cache        database.RedisCache
+	policy       TwoFactorPolicy
+	now          func() time.Time
+}
+
+// twoFactorChallenge is kept in Redis under the hash of the intermediate token
+type twoFactorChallenge struct {
+	CustomerID int64 `json:"customer_id"`
+	Enroll     bool  `json:"enroll"`
+}
+
+func NewTwoFactorUseCase(
+	repo repository.TwoFactorRepository,
+	customerRepo repository.CustomerRepository,
+	sessions SessionUseCase,
+	attempts LoginAttemptUseCase,
+	log *logrus.Logger,
+	cache database.RedisCache,
+	policy TwoFactorPolicy,
+) TwoFactorUseCase {
+	if policy.Issuer == "" {
+		policy.Issuer = "CakeStore"
+	}
+	if policy.ChallengeTTL <= 0 {
+		policy.ChallengeTTL = 5 * time.Minute
+	}
+	return &twoFactorUseCase{
+		repo:         repo,
+		customerRepo: customerRepo,
+		sessions:     sessions,
+		attempts:     attempts,
+		log:          log,
+		cache:        cache,
+		policy:       policy,
+		now:          time.Now,
+	}
+}
+
+func (uc *twoFactorUseCase) Challenge(ctx context.Context, customer *entity.Customer) (*model.TwoFactorChallenge, error) {
+	enabled, err := uc.enabled(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled && !uc.required(customer.Role) {
+		return nil, nil
+	}
+
+	raw, err := newOpaqueToken()
+	if err != nil {
+		uc.log.Errorf("Error generating two-factor token: %v", err)
+		return nil, err
+	}
+	// Without Redis the second step cannot be completed, so this fails closed
+	challenge := twoFactorChallenge{CustomerID: customer.ID, Enroll: !enabled}
+	if err := uc.cache.Set(ctx, challengeKey(raw), challenge, uc.policy.ChallengeTTL); err != nil {
+		uc.log.Errorf("Error storing two-factor challenge for customer %d: %v", customer.ID, err)
+		return nil, err
+	}
+
+	return &model.TwoFactorChallenge{
+		TwoFactorToken:     raw,
+		ExpiresIn:          int64(uc.policy.ChallengeTTL.Seconds()),
+		EnrollmentRequired: !enabled,
+	}, nil
+}
+
+func (uc *twoFactorUseCase) VerifyLogin(ctx context.Context, request *model.TwoFactorLoginRequest, clientIP string) (*model.TokenResponse, error) {
+	challenge, err := uc.loadChallenge(ctx, request.TwoFactorToken, false)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := uc.customerRepo.GetByID(ctx, challenge.CustomerID)
+	if err != nil {
+		return nil, constants.ErrInvalidTwoFactorToken
+	}
+	// Wrong codes count as failed logins, so they lock the account like wrong passwords do
+	if err := uc.attempts.Check(ctx, customer.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	valid, err := uc.checkCode(ctx, customer.ID, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		uc.attempts.RecordFailure(ctx, customer.Email, clientIP)
+		return nil, constants.ErrInvalidTwoFactorCode
+	}
+
+	uc.attempts.RecordSuccess(ctx, customer.Email)
+	uc.deleteChallenge(ctx, request.TwoFactorToken)
+	return uc.sessions.Issue(ctx, customer)
+}
+
+func (uc *twoFactorUseCase) EnrollWithChallenge(ctx context.Context, request *model.TwoFactorTokenRequest) (*model.TwoFactorEnrollment, error) {
+	challenge, err := uc.loadChallenge(ctx, request.TwoFactorToken, true)
+	if err != nil {
+		return nil, err
+	}
+	return uc.Enroll(ctx, challenge.CustomerID)
+}
+
+func (uc *twoFactorUseCase) ActivateWithChallenge(ctx context.Context, request *model.TwoFactorLoginRequest, clientIP string) (*model.TwoFactorActivation, error) {
+	challenge, err := uc.loadChallenge(ctx, request.TwoFactorToken, true)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := uc.customerRepo.GetByID(ctx, challenge.CustomerID)
+	if err != nil {
+		return nil, constants.ErrInvalidTwoFactorToken
+	}
+	if err := uc.attempts.Check(ctx, customer.Email, clientIP); err != nil {
+		return nil, err
+	}
+
+	activation, err := uc.Activate(ctx, customer.ID, &model.TwoFactorCodeRequest{Code: request.Code})
+	if err != nil {
+		if errors.Is(err, constants.ErrInvalidTwoFactorCode) {
+			uc.attempts.RecordFailure(ctx, customer.Email, clientIP)
+		}
+		return nil, err
+	}
+
+	uc.attempts.RecordSuccess(ctx, customer.Email)
+	uc.deleteChallenge(ctx, request.TwoFactorToken)
+	activation.Session, err = uc.sessions.Issue(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	return activation, nil
+}
+
+func (uc *twoFactorUseCase) Status(ctx context.Context, customerID int64) (*model.TwoFactorStatus, error) {
+	customer, err := uc.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := uc.enabled(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TwoFactorStatus{Enabled: enabled, Required: uc.required(customer.Role)}, nil
+}
+
+// Enroll starts (or restarts) setting up an authenticator. Two-factor
+// authentication is only switched on once Activate confirms a code.
+func (uc *twoFactorUseCase) Enroll(ctx context.Context, customerID int64) (*model.TwoFactorEnrollment, error) {
+	if len(uc.policy.EncryptionKey) == 0 {
+		return nil, constants.ErrTwoFactorNotConfigured
+	}
+
+	enabled, err := uc.enabled(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, constants.ErrTwoFactorAlreadyEnabled
+	}
+
+	customer, err := uc.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		uc.log.Errorf("Error generating TOTP secret: %v", err)
+		return nil, err
+	}
+	encrypted, err := utils.EncryptAES(secret, uc.policy.EncryptionKey)
+	if err != nil {
+		uc.log.Errorf("Error encrypting TOTP secret: %v", err)
+		return nil, err
+	}
+
+	now := uc.now()
+	if err := uc.repo.Save(ctx, &entity.TwoFactor{
+		CustomerID: customerID,
+		Secret:     encrypted,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}); err != nil {
+		return nil, err
+	}
+
+	uri := auth.TOTPURI(uc.policy.Issuer, customer.Email, secret)
+	png, err := utils.GenerateQRCodePNG(uri, 256)
+	if err != nil {
+		uc.log.Errorf("Error generating QR code: %v", err)
+		return nil, err
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+func (uc *twoFactorUseCase) Activate(ctx context.Context, customerID int64, request *model.TwoFactorCodeRequest) (*model.TwoFactorActivation, error) {
+	twoFactor, err := uc.repo.GetByCustomer(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return nil, constants.ErrTwoFactorNotSetUp
+		}
+		return nil, err
+	}
+	if twoFactor.EnabledAt.Valid {
+		return nil, constants.ErrTwoFactorAlreadyEnabled
+	}
+
+	step, valid, err := uc.validateTOTP(twoFactor, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, constants.ErrInvalidTwoFactorCode
+	}
+
+	twoFactor.EnabledAt = sql.NullTime{Time: uc.now(), Valid: true}
+	twoFactor.LastUsedStep = step
+	twoFactor.UpdatedAt = uc.now()
+	if err := uc.repo.Save(ctx, twoFactor); err != nil {
+		return nil, err
+	}
+
+	codes, err := uc.newRecoveryCodes(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	uc.log.Infof("Customer %d enabled two-factor authentication", customerID)
+	return &model.TwoFactorActivation{RecoveryCodes: codes}, nil
+}
+
+func (uc *twoFactorUseCase) Disable(ctx context.Context, customerID int64, request *model.DisableTwoFactorRequest) error {
+	customer, err := uc.customerRepo.GetByID(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if uc.required(customer.Role) {
+		return constants.ErrTwoFactorRequired
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(customer.Password), []byte(request.Password)); err != nil {
+		return constants.ErrInvalidPassword
+	}
+
+	valid, err := uc.checkCode(ctx, customerID, request.Code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return constants.ErrInvalidTwoFactorCode
+	}
+
+	if err := uc.repo.Delete(ctx, customerID); err != nil {
+		return err
+	}
+
+	uc.log.Infof("Customer %d disabled two-factor authentication", customerID)
+	return nil
+}
+
+func (uc *twoFactorUseCase) RegenerateRecoveryCodes(ctx context.Context, customerID int64, request *model.TwoFactorCodeRequest) (*model.TwoFactorActivation, error) {
+	enabled, err := uc.enabled(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, constants.ErrTwoFactorNotSetUp
+	}
+
+	valid, err := uc.checkCode(ctx, customerID, request.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, constants.ErrInvalidTwoFactorCode
+	}
+
+	codes, err := uc.newRecoveryCodes(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TwoFactorActivation{RecoveryCodes: codes}, nil
+}
+
+// checkCode accepts a current authenticator code or an unused recovery code.
+// Either only works once.
+func (uc *twoFactorUseCase) checkCode(ctx context.Context, customerID int64, code string) (bool, error) {
+	twoFactor, err := uc.repo.GetByCustomer(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !twoFactor.EnabledAt.Valid {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		step, valid, err := uc.validateTOTP(twoFactor, code)
+		if err != nil || !valid {
+			return false, err
+		}
+		return uc.repo.MarkStepUsed(ctx, customerID, step)
+	}
+
+	used, err := uc.repo.UseRecoveryCode(ctx, customerID, hashOpaqueToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		uc.log.Warnf("Customer %d used a two-factor recovery code", customerID)
+	}
+	return used, nil
+}
+
+func (uc *twoFactorUseCase) validateTOTP(twoFactor *entity.TwoFactor, code string) (int64, bool, error) {
+	secret, err := utils.DecryptAES(twoFactor.Secret, uc.policy.EncryptionKey)
+	if err != nil {
+		uc.log.Errorf("Error decrypting TOTP secret of customer %d: %v", twoFactor.CustomerID, err)
+		return 0, false, err
+	}
+	// One step either way allows for clock drift on the phone
+	step, valid := auth.ValidateTOTP(secret, strings.TrimSpace(code), uc.now(), 1)
+	if valid && step <= twoFactor.LastUsedStep {
+		return 0, false, nil
+	}
+	return step, valid, nil
+}
+
+func (uc *twoFactorUseCase) newRecoveryCodes(ctx context.Context, customerID int64) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			uc.log.Errorf("Error generating recovery code: %v", err)
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashOpaqueToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := uc.repo.ReplaceRecoveryCodes(ctx, customerID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (uc *twoFactorUseCase) enabled(ctx context.Context, customerID int64) (bool, error) {
+	twoFactor, err := uc.repo.GetByCustomer(ctx, customerID)
+	if err != nil {
+		if errors.Is(err, constants.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return twoFactor.EnabledAt.Valid, nil
+}
+
+func (uc *twoFactorUseCase) required(role string) bool {
+	for _, required := range uc.policy.RequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (uc *twoFactorUseCase) loadChallenge(ctx context.Context, raw string, enroll bool) (*twoFactorChallenge, error) {
+	var challenge twoFactorChallenge
+	if err := uc.cache.Get(ctx, challengeKey(raw), &challenge); err != nil {
+		return nil, constants.ErrInvalidTwoFactorToken
+	}
+	if challenge.Enroll != enroll {
+		return nil, constants.ErrInvalidTwoFactorToken
+	}
+	return &challenge, nil
+}
+
+func (uc *twoFactorUseCase) deleteChallenge(ctx context.Context, raw string) {
+	if err := uc.cache.Delete(ctx, challengeKey(raw)); err != nil {
+		uc.log.Errorf("Error deleting two-factor challenge: %v", err)
+	}
+}
+
+func challengeKey(raw string) string {
+	return fmt.Sprintf("auth:2fa:challenge:%s", hashOpaqueToken(raw))
+}
+
+// newRecoveryCode returns a code such as "k3v9q-7mx2a"
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,312 @@
+package usecase
+
+import (
+	"cakestore/internal/auth"
+	"cakestore/internal/constants"
+	"cakestore/internal/database"
+	"cakestore/internal/domain/entity"
+	"cakestore/internal/domain/model"
+	"cakestore/utils"
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) GetByCustomer(ctx context.Context, customerID int64) (*entity.TwoFactor, error) {
+	args := m.Called(customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.TwoFactor), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) Save(ctx context.Context, twoFactor *entity.TwoFactor) error {
+	args := m.Called(twoFactor)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) Delete(ctx context.Context, customerID int64) error {
+	args := m.Called(customerID)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) MarkStepUsed(ctx context.Context, customerID int64, step int64) (bool, error) {
+	args := m.Called(customerID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, customerID int64, codeHashes []string) error {
+	args := m.Called(customerID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, customerID int64, codeHash string) (bool, error) {
+	args := m.Called(customerID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockTwoFactorUseCase struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorUseCase) Challenge(ctx context.Context, customer *entity.Customer) (*model.TwoFactorChallenge, error) {
+	args := m.Called(customer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorChallenge), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) VerifyLogin(ctx context.Context, request *model.TwoFactorLoginRequest, clientIP string) (*model.TokenResponse, error) {
+	args := m.Called(request, clientIP)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TokenResponse), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) EnrollWithChallenge(ctx context.Context, request *model.TwoFactorTokenRequest) (*model.TwoFactorEnrollment, error) {
+	args := m.Called(request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorEnrollment), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) ActivateWithChallenge(ctx context.Context, request *model.TwoFactorLoginRequest, clientIP string) (*model.TwoFactorActivation, error) {
+	args := m.Called(request, clientIP)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorActivation), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) Status(ctx context.Context, customerID int64) (*model.TwoFactorStatus, error) {
+	args := m.Called(customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorStatus), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) Enroll(ctx context.Context, customerID int64) (*model.TwoFactorEnrollment, error) {
+	args := m.Called(customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorEnrollment), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) Activate(ctx context.Context, customerID int64, request *model.TwoFactorCodeRequest) (*model.TwoFactorActivation, error) {
+	args := m.Called(customerID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorActivation), args.Error(1)
+}
+
+func (m *MockTwoFactorUseCase) Disable(ctx context.Context, customerID int64, request *model.DisableTwoFactorRequest) error {
+	args := m.Called(customerID, request)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorUseCase) RegenerateRecoveryCodes(ctx context.Context, customerID int64, request *model.TwoFactorCodeRequest) (*model.TwoFactorActivation, error) {
+	args := m.Called(customerID, request)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TwoFactorActivation), args.Error(1)
+}
+
+var testTwoFactorKey = []byte("0123456789abcdef0123456789abcdef")
+
+// enabledTwoFactor returns an active enrolment and the plain secret behind it
+func enabledTwoFactor(t *testing.T) (*entity.TwoFactor, string) {
+	secret, err := auth.NewTOTPSecret()
+	require.NoError(t, err)
+	encrypted, err := utils.EncryptAES(secret, testTwoFactorKey)
+	require.NoError(t, err)
+	return &entity.TwoFactor{
+		CustomerID: 7,
+		Secret:     encrypted,
+		EnabledAt:  sql.NullTime{Time: time.Now(), Valid: true},
+	}, secret
+}
+
+func TestTwoFactorUseCase_Challenge(t *testing.T) {
+	logger := logrus.New()
+
+	t.Run("not enrolled and not required", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		useCase := NewTwoFactorUseCase(mockRepo, nil, nil, nil, logger, nil, TwoFactorPolicy{})
+
+		mockRepo.On("GetByCustomer", int64(7)).Return(nil, constants.ErrNotFound).Once()
+
+		challenge, err := useCase.Challenge(context.Background(), &entity.Customer{ID: 7, Role: constants.RoleCustomer})
+
+		assert.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	t.Run("required role must enrol", func(t *testing.T) {
+		mockRepo := new(MockTwoFactorRepository)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewTwoFactorUseCase(mockRepo, nil, nil, nil, logger, mockCache, TwoFactorPolicy{
+			RequiredRoles: []string{constants.RoleAdmin},
+		})
+
+		mockRepo.On("GetByCustomer", int64(7)).Return(nil, constants.ErrNotFound).Once()
+		mockCache.On("Set", mock.Anything, mock.MatchedBy(func(key string) bool {
+			return strings.HasPrefix(key, "auth:2fa:challenge:")
+		}), twoFactorChallenge{CustomerID: 7, Enroll: true}, 5*time.Minute).Return(nil).Once()
+
+		challenge, err := useCase.Challenge(context.Background(), &entity.Customer{ID: 7, Role: constants.RoleAdmin})
+
+		assert.NoError(t, err)
+		assert.True(t, challenge.EnrollmentRequired)
+		assert.NotEmpty(t, challenge.TwoFactorToken)
+		mockCache.AssertExpectations(t)
+	})
+}
+
+func TestTwoFactorUseCase_EnrollAndActivate(t *testing.T) {
+	logger := logrus.New()
+	mockRepo := new(MockTwoFactorRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	useCase := NewTwoFactorUseCase(mockRepo, mockCustomerRepo, nil, nil, logger, nil, TwoFactorPolicy{EncryptionKey: testTwoFactorKey})
+
+	var saved *entity.TwoFactor
+	mockRepo.On("GetByCustomer", int64(7)).Return(nil, constants.ErrNotFound).Once()
+	mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7, Email: "a@example.com"}, nil).Once()
+	mockRepo.On("Save", mock.AnythingOfType("*entity.TwoFactor")).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(*entity.TwoFactor)
+	}).Return(nil).Once()
+
+	enrollment, err := useCase.Enroll(context.Background(), 7)
+
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/CakeStore:a@example.com")
+	assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+	assert.NotEqual(t, enrollment.Secret, saved.Secret, "the secret is stored encrypted")
+	decrypted, err := utils.DecryptAES(saved.Secret, testTwoFactorKey)
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, decrypted)
+	assert.False(t, saved.EnabledAt.Valid)
+
+	code, err := auth.TOTPCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+	mockRepo.On("GetByCustomer", int64(7)).Return(saved, nil).Once()
+	mockRepo.On("Save", mock.MatchedBy(func(twoFactor *entity.TwoFactor) bool {
+		return twoFactor.EnabledAt.Valid && twoFactor.LastUsedStep > 0
+	})).Return(nil).Once()
+	mockRepo.On("ReplaceRecoveryCodes", int64(7), mock.MatchedBy(func(hashes []string) bool {
+		return len(hashes) == recoveryCodeCount
+	})).Return(nil).Once()
+
+	activation, err := useCase.Activate(context.Background(), 7, &model.TwoFactorCodeRequest{Code: code})
+
+	require.NoError(t, err)
+	assert.Len(t, activation.RecoveryCodes, recoveryCodeCount)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTwoFactorUseCase_VerifyLogin(t *testing.T) {
+	logger := logrus.New()
+	customer := &entity.Customer{ID: 7, Email: "a@example.com"}
+
+	setup := func() (TwoFactorUseCase, *MockTwoFactorRepository, *MockLoginAttemptUseCase, *MockSessionUseCase, *database.MockRedisCacheService) {
+		mockRepo := new(MockTwoFactorRepository)
+		mockCustomerRepo := new(MockCustomerRepository)
+		mockAttempts := new(MockLoginAttemptUseCase)
+		mockSessions := new(MockSessionUseCase)
+		mockCache := new(database.MockRedisCacheService)
+		useCase := NewTwoFactorUseCase(mockRepo, mockCustomerRepo, mockSessions, mockAttempts, logger, mockCache, TwoFactorPolicy{EncryptionKey: testTwoFactorKey})
+
+		mockCache.On("Get", mock.Anything, challengeKey("challenge"), mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*twoFactorChallenge) = twoFactorChallenge{CustomerID: 7}
+		}).Return(nil).Once()
+		mockCustomerRepo.On("GetByID", int64(7)).Return(customer, nil).Once()
+		mockAttempts.On("Check", "a@example.com", "10.0.0.1").Return(nil).Once()
+		return useCase, mockRepo, mockAttempts, mockSessions, mockCache
+	}
+
+	t.Run("authenticator code", func(t *testing.T) {
+		useCase, mockRepo, mockAttempts, mockSessions, mockCache := setup()
+		twoFactor, secret := enabledTwoFactor(t)
+		code, err := auth.TOTPCode(secret, time.Now())
+		require.NoError(t, err)
+
+		mockRepo.On("GetByCustomer", int64(7)).Return(twoFactor, nil).Once()
+		mockRepo.On("MarkStepUsed", int64(7), mock.Anything).Return(true, nil).Once()
+		mockAttempts.On("RecordSuccess", "a@example.com").Once()
+		mockCache.On("Delete", mock.Anything, challengeKey("challenge")).Return(nil).Once()
+		mockSessions.On("Issue", customer).Return(&model.TokenResponse{AccessToken: "access"}, nil).Once()
+
+		tokens, err := useCase.VerifyLogin(context.Background(), &model.TwoFactorLoginRequest{TwoFactorToken: "challenge", Code: code}, "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "access", tokens.AccessToken)
+		mockCache.AssertExpectations(t)
+	})
+
+	t.Run("replayed code", func(t *testing.T) {
+		useCase, mockRepo, mockAttempts, mockSessions, _ := setup()
+		twoFactor, secret := enabledTwoFactor(t)
+		code, err := auth.TOTPCode(secret, time.Now())
+		require.NoError(t, err)
+
+		mockRepo.On("GetByCustomer", int64(7)).Return(twoFactor, nil).Once()
+		mockRepo.On("MarkStepUsed", int64(7), mock.Anything).Return(false, nil).Once()
+		mockAttempts.On("RecordFailure", "a@example.com", "10.0.0.1").Once()
+
+		_, err = useCase.VerifyLogin(context.Background(), &model.TwoFactorLoginRequest{TwoFactorToken: "challenge", Code: code}, "10.0.0.1")
+
+		assert.ErrorIs(t, err, constants.ErrInvalidTwoFactorCode)
+		mockAttempts.AssertExpectations(t)
+		mockSessions.AssertNotCalled(t, "Issue", mock.Anything)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		useCase, mockRepo, mockAttempts, mockSessions, mockCache := setup()
+		twoFactor, _ := enabledTwoFactor(t)
+
+		mockRepo.On("GetByCustomer", int64(7)).Return(twoFactor, nil).Once()
+		mockRepo.On("UseRecoveryCode", int64(7), hashOpaqueToken("k3v9q7mx2a")).Return(true, nil).Once()
+		mockAttempts.On("RecordSuccess", "a@example.com").Once()
+		mockCache.On("Delete", mock.Anything, challengeKey("challenge")).Return(nil).Once()
+		mockSessions.On("Issue", customer).Return(&model.TokenResponse{AccessToken: "access"}, nil).Once()
+
+		_, err := useCase.VerifyLogin(context.Background(), &model.TwoFactorLoginRequest{TwoFactorToken: "challenge", Code: "K3V9Q-7MX2A"}, "10.0.0.1")
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTwoFactorUseCase_Disable(t *testing.T) {
+	logger := logrus.New()
+	mockRepo := new(MockTwoFactorRepository)
+	mockCustomerRepo := new(MockCustomerRepository)
+	useCase := NewTwoFactorUseCase(mockRepo, mockCustomerRepo, nil, nil, logger, nil, TwoFactorPolicy{
+		EncryptionKey: testTwoFactorKey,
+		RequiredRoles: []string{constants.RoleAdmin, constants.RoleCashier},
+	})
+
+	mockCustomerRepo.On("GetByID", int64(7)).Return(&entity.Customer{ID: 7, Role: constants.RoleCashier}, nil).Once()
+
+	err := useCase.Disable(context.Background(), 7, &model.DisableTwoFactorRequest{Password: "password", Code: "123456"})
+
+	assert.ErrorIs(t, err, constants.ErrTwoFactorRequired)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything)
+}
@@ -4,6 +4,7 @@ import (
 	"cakestore/internal/database"
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
+	"context"
 	"errors"
 	"testing"
 
@@ -16,12 +17,12 @@ type MockWishListRepository struct {
 	mock.Mock
 }
 
-func (m *MockWishListRepository) Create(wishlist *entity.WishList) error {
+func (m *MockWishListRepository) Create(ctx context.Context, wishlist *entity.WishList) error {
 	args := m.Called(wishlist)
 	return args.Error(0)
 }
 
-func (m *MockWishListRepository) GetByCustomerID(customerID int64, params *model.PaginationQuery) ([]entity.Menu, *model.PaginatedMeta, error) {
+func (m *MockWishListRepository) GetByCustomerID(ctx context.Context, customerID int64, params *model.PaginationQuery) ([]entity.Menu, *model.PaginatedMeta, error) {
 	args := m.Called(customerID, params)
 	if args.Get(0) == nil {
 		return nil, nil, args.Error(2)
@@ -29,7 +30,7 @@ func (m *MockWishListRepository) GetByCustomerID(customerID int64, params *model
 	return args.Get(0).([]entity.Menu), args.Get(1).(*model.PaginatedMeta), args.Error(2)
 }
 
-func (m *MockWishListRepository) GetByCustomerIDAndMenuID(customerID, menuID int64) (*entity.WishList, error) {
+func (m *MockWishListRepository) GetByCustomerIDAndMenuID(ctx context.Context, customerID, menuID int64) (*entity.WishList, error) {
 	args := m.Called(customerID, menuID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -37,7 +38,7 @@ func (m *MockWishListRepository) GetByCustomerIDAndMenuID(customerID, menuID int
 	return args.Get(0).(*entity.WishList), args.Error(1)
 }
 
-func (m *MockWishListRepository) Delete(customerID, menuID int64) error {
+func (m *MockWishListRepository) Delete(ctx context.Context, customerID, menuID int64) error {
 	args := m.Called(customerID, menuID)
 	return args.Error(0)
 }
@@ -61,9 +62,9 @@ func TestWishListUseCase_GetWishList(t *testing.T) {
 		params := &model.PaginationQuery{Page: 1, Limit: 10}
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockWishListRepo.On("GetByCustomerID", int64(1), params).Return(expectedResponse, meta, nil).Once()
-		mockCache.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		mockCache.On("SetWithTags", mock.Anything, "wishlist:1:page:1:limit:10", mock.Anything, mock.Anything, []string{wishlistTag(1)}).Return(nil)
 
-		menus, resultMeta, err := useCase.GetWishList(1, params)
+		menus, resultMeta, err := useCase.GetWishList(context.Background(), 1, params)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, menus)
@@ -77,7 +78,7 @@ func TestWishListUseCase_GetWishList(t *testing.T) {
 		mockCache.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("not found"))
 		mockWishListRepo.On("GetByCustomerID", int64(1), params).Return(nil, nil, errors.New("error")).Once()
 
-		menus, resultMeta, err := useCase.GetWishList(1, params)
+		menus, resultMeta, err := useCase.GetWishList(context.Background(), 1, params)
 
 		assert.Error(t, err)
 		assert.Nil(t, menus)
@@ -8,6 +8,7 @@ import (
 	"cakestore/internal/domain/entity"
 	"cakestore/internal/domain/model"
 	"cakestore/internal/middleware"
+	"cakestore/internal/notification"
 	"cakestore/internal/repository"
 	"cakestore/internal/usecase"
 	"cakestore/utils"
@@ -37,33 +38,42 @@ type AuthTestSuite struct {
 }
 
 func (suite *AuthTestSuite) SetupTest() {
-	cfg := configs.LoadConfig()
+	cfg, _, err := configs.Load(nil)
+	suite.Require().NoError(err)
 	db := database.ConnectPostgres(cfg)
 	// Run migrations
-	err := db.AutoMigrate(&entity.Customer{})
+	err = db.AutoMigrate(&entity.Customer{}, &entity.Role{}, &entity.RolePermission{}, &entity.RefreshToken{}, &entity.AccountToken{}, &entity.TwoFactor{})
 	assert.NoError(suite.T(), err)
 	ctx := context.Background()
-	redis := database.NewRedisCacheService(ctx, "")
+	redis, err := database.NewCache(ctx, database.CacheConfig{Driver: database.CacheDriverMemory})
+	suite.Require().NoError(err)
 
 	suite.db = db
 	suite.logger = utils.NewLogger()
 	suite.repo = repository.NewCustomerRepository(db, suite.logger)
-	suite.useCase = usecase.NewCustomerUseCase(suite.repo, suite.logger, cfg.JWT_SECRET, redis)
-	suite.handler = controller.NewCustomerController(suite.useCase, suite.logger)
+	tokens, err := NewTokenService(cfg)
+	suite.Require().NoError(err)
+	sessions := usecase.NewSessionUseCase(repository.NewRefreshTokenRepository(db, suite.logger), suite.repo, suite.logger, redis, tokens, usecase.SessionPolicy{})
+	accounts := usecase.NewAccountUseCase(repository.NewAccountTokenRepository(db, suite.logger), suite.repo, sessions, notification.NewLogSender(suite.logger), suite.logger, redis, usecase.AccountPolicy{})
+	attempts := usecase.NewLoginAttemptUseCase(redis, suite.logger, usecase.LoginPolicy{})
+	twoFactor := usecase.NewTwoFactorUseCase(repository.NewTwoFactorRepository(db, suite.logger), suite.repo, sessions, attempts, suite.logger, redis, usecase.TwoFactorPolicy{})
+	roles := usecase.NewRoleUseCase(repository.NewRoleRepository(db, suite.logger), suite.logger, redis)
+	suite.useCase = usecase.NewCustomerUseCase(suite.repo, suite.logger, sessions, accounts, attempts, twoFactor, roles, redis)
+	suite.handler = controller.NewCustomerController(suite.useCase, nil, sessions, suite.logger)
 
 	suite.app = fiber.New()
 
 	// Generate a test token
-	token, err := utils.GenerateToken(123, "test@example.com", "Test User", "customer")
+	token, err := GenerateToken(tokens, 123, "test@example.com", "Test User", "customer")
 	suite.Require().NoError(err)
 	suite.token = token
 
 	// Setup routes
 	suite.app.Post("/register", suite.handler.Register)
 	suite.app.Post("/login", suite.handler.Login)
-	suite.app.Get("/authorize", middleware.AuthMiddleware(cfg.JWT_SECRET), suite.handler.Authorize)
-	suite.app.Get("/customers/me", middleware.AuthMiddleware(cfg.JWT_SECRET), suite.handler.GetCustomerByID)
-	suite.app.Put("/customers/:id", middleware.AuthMiddleware(cfg.JWT_SECRET), suite.handler.UpdateProfile)
+	suite.app.Get("/authorize", middleware.AuthMiddleware(tokens, sessions, nil), suite.handler.Authorize)
+	suite.app.Get("/customers/me", middleware.AuthMiddleware(tokens, sessions, nil), suite.handler.GetCustomerByID)
+	suite.app.Put("/customers/:id", middleware.AuthMiddleware(tokens, sessions, nil), suite.handler.UpdateProfile)
 }
 
 func (suite *AuthTestSuite) TestRegister() {
@@ -124,7 +134,9 @@ func (suite *AuthTestSuite) TestGetCustomerByID() {
 	_ = json.Unmarshal(respBody, &response)
 
 	// parse token
-	token, ok := response.Data.(string)
+	tokens, ok := response.Data.(map[string]interface{})
+	suite.Require().True(ok)
+	token, ok := tokens["access_token"].(string)
 	suite.Require().True(ok)
 
 	// Fetch the customer by assumed ID
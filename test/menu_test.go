@@ -36,16 +36,18 @@ type MenuHandlerTestSuite struct {
 }
 
 func (suite *MenuHandlerTestSuite) SetupTest() {
-	cfg := configs.LoadConfig()
+	cfg, _, err := configs.Load(nil)
+	suite.Require().NoError(err)
 
 	db := database.ConnectPostgres(cfg)
 
 	// Run migrations
-	err := db.AutoMigrate(&entity.Menu{})
+	err = db.AutoMigrate(&entity.Menu{})
 	assert.NoError(suite.T(), err)
 
 	ctx := context.Background()
-	redis := database.NewRedisCacheService(ctx, "")
+	redis, err := database.NewCache(ctx, database.CacheConfig{Driver: database.CacheDriverMemory})
+	suite.Require().NoError(err)
 
 	suite.db = db
 	suite.logger = utils.NewLogger()
@@ -56,8 +58,11 @@ func (suite *MenuHandlerTestSuite) SetupTest() {
 	// Initialize Fiber app
 	suite.app = fiber.New()
 
+	tokens, err := NewTokenService(cfg)
+	suite.Require().NoError(err)
+
 	// Generate a test token
-	token, err := utils.GenerateToken(123, "test@example.com", "Test User", "customer")
+	token, err := GenerateToken(tokens, 123, "test@example.com", "Test User", "customer")
 	suite.Require().NoError(err)
 	suite.token = token
 
@@ -2,6 +2,8 @@ package test
 
 import (
 	"bytes"
+	"cakestore/internal/auth"
+	configs "cakestore/internal/config"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -45,3 +47,18 @@ func ParseResponse(resp *http.Response, v interface{}) error {
 
 	return json.Unmarshal(body, v)
 }
+
+// NewTokenService builds the same HS256 token service the application uses
+func NewTokenService(cfg *configs.Config) (*auth.JWTService, error) {
+	keys, err := auth.LoadKeySet(auth.KeyConfig{Secret: cfg.JWT_SECRET})
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewJWTService(keys, auth.Options{}), nil
+}
+
+// GenerateToken is a helper function to sign an access token for a test account
+func GenerateToken(tokens auth.TokenIssuer, customerID int64, email, name, role string) (string, error) {
+	token, _, err := tokens.Issue(auth.Subject{CustomerID: customerID, Email: email, Name: name, Role: role})
+	return token, err
+}
@@ -1,13 +1,9 @@
 package utils
 
-import (
-	configs "cakestore/internal/config"
-)
-
-func GenerateRequestHeader() map[string]string {
-	cfg := configs.LoadConfig()
-	SERVER_KEY := cfg.MIDTRANS_SERVER_KEY
-	base64ServerKey := EncodeToBase64(SERVER_KEY)
+// GenerateRequestHeader returns the headers for a Midtrans API call
+// authenticated with serverKey.
+func GenerateRequestHeader(serverKey string) map[string]string {
+	base64ServerKey := EncodeToBase64(serverKey)
 
 	return map[string]string{
 		"Authorization": "Basic " + base64ServerKey,